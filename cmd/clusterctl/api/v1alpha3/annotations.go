@@ -19,4 +19,23 @@ package v1alpha3
 const (
 	// CertManagerVersionAnnotation reports the cert manager version installed by clusterctl.
 	CertManagerVersionAnnotation = "cert-manager.clusterctl.cluster.x-k8s.io/version"
+
+	// ProviderURLAnnotation is applied by clusterctl to the inventory object of the providers installed from a local path
+	// instead of the provider repository defined in the clusterctl configuration, and it reports the URL of the components YAML.
+	ProviderURLAnnotation = "provider.clusterctl.cluster.x-k8s.io/url"
+
+	// BackupRedactedAnnotation is applied by clusterctl to the Secrets whose data have been removed while backing up Cluster API objects to a directory.
+	BackupRedactedAnnotation = "backup.clusterctl.cluster.x-k8s.io/redacted"
+
+	// BackupEncryptedAnnotation is applied by clusterctl to the Secrets whose data have been encrypted while backing up Cluster API objects to a directory.
+	BackupEncryptedAnnotation = "backup.clusterctl.cluster.x-k8s.io/encrypted"
+
+	// PausedByClusterctlAnnotation is applied by clusterctl to the objects it paused when pausing a Cluster, so resuming
+	// the Cluster does not resume objects paused by other means, e.g. by a user.
+	PausedByClusterctlAnnotation = "pause.clusterctl.cluster.x-k8s.io/paused"
+
+	// KubeconfigRegenerationRequestedAnnotation is applied by clusterctl to the KubeadmControlPlane of a Cluster after deleting
+	// the Cluster's kubeconfig Secret, so the control plane is reconciled and the Secret is created again; it reports the time
+	// of the request.
+	KubeconfigRegenerationRequestedAnnotation = "kubeconfig.clusterctl.cluster.x-k8s.io/regeneration-requested"
 )
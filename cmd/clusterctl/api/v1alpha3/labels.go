@@ -36,6 +36,13 @@ const (
 
 	// ClusterctlMoveHierarchyLabelName can be set on CRDs that providers wish to move with their entire hierarchy, but that are not part of a Cluster.
 	ClusterctlMoveHierarchyLabelName = "clusterctl.cluster.x-k8s.io/move-hierarchy"
+
+	// ClusterctlMoveCheckpointLabelName is applied to the ConfigMaps used by clusterctl for tracking the progress of a move operation.
+	ClusterctlMoveCheckpointLabelName = "clusterctl.cluster.x-k8s.io/move-checkpoint"
+
+	// ClusterctlUpgradeSnapshotLabelName is applied to the Secrets used by clusterctl for storing the provider components
+	// before an upgrade; the label value is the ID of the upgrade attempt.
+	ClusterctlUpgradeSnapshotLabelName = "clusterctl.cluster.x-k8s.io/upgrade-snapshot"
 )
 
 // ManifestLabel returns the cluster.x-k8s.io/provider label value for a provider/type.
@@ -18,6 +18,7 @@ package v1alpha3
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/version"
 )
 
@@ -30,6 +31,30 @@ type Metadata struct {
 
 	// +optional
 	ReleaseSeries []ReleaseSeries `json:"releaseSeries"`
+
+	// DefaultTargetNamespace is the namespace where the provider components are installed when a target
+	// namespace is not specified; if empty, the namespace defined in the components YAML is used.
+	// +optional
+	DefaultTargetNamespace string `json:"defaultTargetNamespace,omitempty"`
+
+	// InstallHooks defines the objects required by the provider, but not part of the provider components, that
+	// are created when installing the provider.
+	// +optional
+	InstallHooks *InstallHooks `json:"installHooks,omitempty"`
+}
+
+// InstallHooks defines the objects created before and after installing the provider components, e.g. a ConfigMap
+// the provider controllers expect to exist. Objects are processed with the same variables of the provider components,
+// namespaced objects without a namespace are created in the target namespace, and objects already existing are not changed;
+// objects are not deleted when deleting the provider.
+type InstallHooks struct {
+	// PreInstall lists the objects created before the provider components.
+	// +optional
+	PreInstall []runtime.RawExtension `json:"preInstall,omitempty"`
+
+	// PostInstall lists the objects created after the provider components, before adding the provider to the inventory.
+	// +optional
+	PostInstall []runtime.RawExtension `json:"postInstall,omitempty"`
 }
 
 // ReleaseSeries maps a provider release series (major/minor) with a API Version of Cluster API (contract).
@@ -24,6 +24,35 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallHooks) DeepCopyInto(out *InstallHooks) {
+	*out = *in
+	if in.PreInstall != nil {
+		in, out := &in.PreInstall, &out.PreInstall
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostInstall != nil {
+		in, out := &in.PostInstall, &out.PostInstall
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallHooks.
+func (in *InstallHooks) DeepCopy() *InstallHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Metadata) DeepCopyInto(out *Metadata) {
 	*out = *in
@@ -34,6 +63,11 @@ func (in *Metadata) DeepCopyInto(out *Metadata) {
 		*out = make([]ReleaseSeries, len(*in))
 		copy(*out, *in)
 	}
+	if in.InstallHooks != nil {
+		in, out := &in.InstallHooks, &out.InstallHooks
+		*out = new(InstallHooks)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Metadata.
@@ -41,13 +41,134 @@ type Template repository.Template
 // UpgradePlan defines a list of possible upgrade targets for a management cluster.
 type UpgradePlan cluster.UpgradePlan
 
+// VersionSkew describes a provider whose version violates the version skew policy relative to the core provider.
+type VersionSkew cluster.VersionSkew
+
+// UpgradeDiff describes the changes an upgrade is going to apply to the provider components in a management cluster.
+type UpgradeDiff cluster.UpgradeDiff
+
 // CertManagerUpgradePlan defines the upgrade plan if cert-manager needs to be
 // upgraded to a different version.
 type CertManagerUpgradePlan cluster.CertManagerUpgradePlan
 
+// MoveReport describes the objects a move operation is going to transfer to the target management cluster.
+type MoveReport cluster.MoveReport
+
+// MovePlan estimates the number of objects, their size and the time a move operation is going to require.
+type MovePlan cluster.MovePlan
+
+// MoveError is returned by Move when some objects fail in a phase of the move operation.
+// Nb. MoveError is a type alias, so it can be used with errors.As and it retains the FrozenObjects method.
+type MoveError = cluster.MoveError
+
+// ConnectionOptions defines settings for connecting to the management and workload clusters applied on top of the kubeconfig.
+type ConnectionOptions = cluster.ConnectionOptions
+
+// MoveObjectError describes the failure of a move operation on a single object.
+type MoveObjectError = cluster.MoveObjectError
+
+// MoveScope defines which of the objects belonging to a Cluster are moved.
+type MoveScope = cluster.MoveScope
+
+const (
+	// AllMoveScope moves all the objects belonging to the Clusters; this is the default.
+	AllMoveScope = cluster.AllMoveScope
+
+	// ControlPlaneMoveScope moves only the control plane of the Clusters.
+	ControlPlaneMoveScope = cluster.ControlPlaneMoveScope
+)
+
+// MovePhase defines a phase of a move operation.
+type MovePhase = cluster.MovePhase
+
+const (
+	// MovePausePhase is the phase pausing the Clusters in the source management cluster.
+	MovePausePhase = cluster.MovePausePhase
+
+	// MoveCreatePhase is the phase creating the objects in the target management cluster.
+	MoveCreatePhase = cluster.MoveCreatePhase
+
+	// MoveDeletePhase is the phase deleting the objects from the source management cluster.
+	MoveDeletePhase = cluster.MoveDeletePhase
+
+	// MoveUnpausePhase is the phase resuming the Clusters in the target management cluster.
+	MoveUnpausePhase = cluster.MoveUnpausePhase
+)
+
+// ObjectGraph is a read-only view of the graph of the Cluster API objects discovered in a management cluster.
+type ObjectGraph cluster.ObjectGraph
+
+// DeleteReport describes the objects a delete operation is going to remove from the management cluster.
+type DeleteReport cluster.DeleteReport
+
+// OrphanedComponent is an object labeled as a component of a provider which is not installed in the management cluster.
+type OrphanedComponent = cluster.OrphanedComponent
+
 // Kubeconfig is a type that specifies inputs related to the actual kubeconfig.
 type Kubeconfig cluster.Kubeconfig
 
+// ConfigValidationFinding is a problem found when validating the clusterctl configuration.
+type ConfigValidationFinding config.ValidationFinding
+
 // Processor defines the methods necessary for creating a specific yaml
 // processor.
 type Processor yaml.Processor
+
+// ComponentsTransformer mutates the objects of the provider components, e.g. for adding sidecars.
+type ComponentsTransformer = repository.ComponentsTransformer
+
+// ComponentsTransformerFunc is an adapter for using a function as a ComponentsTransformer.
+type ComponentsTransformerFunc = repository.ComponentsTransformerFunc
+
+// InstallHook defines an action executed by Init when installing a provider, e.g. for creating a ConfigMap required by the provider.
+type InstallHook = cluster.InstallHook
+
+// InstallHookFunc is an adapter for using a function as an InstallHook.
+type InstallHookFunc = cluster.InstallHookFunc
+
+// Event describes a step of an Init or an ApplyUpgrade operation, e.g. fetching the components of a provider.
+type Event = cluster.Event
+
+// EventType identifies the step of an Init or an ApplyUpgrade operation described by an Event.
+type EventType = cluster.EventType
+
+// EventFunc is invoked for streaming the events of an Init or an ApplyUpgrade operation.
+type EventFunc = cluster.EventFunc
+
+const (
+	// FetchingComponentsEvent is streamed before reading the components of a provider from its repository.
+	FetchingComponentsEvent = cluster.FetchingComponentsEvent
+
+	// InstallingCertManagerEvent is streamed before installing or upgrading cert-manager, if required.
+	InstallingCertManagerEvent = cluster.InstallingCertManagerEvent
+
+	// DeletingComponentsEvent is streamed before deleting the components of the current version of a provider being upgraded.
+	DeletingComponentsEvent = cluster.DeletingComponentsEvent
+
+	// ApplyingComponentsEvent is streamed before creating the components of a provider, including its CRDs.
+	ApplyingComponentsEvent = cluster.ApplyingComponentsEvent
+
+	// CreatingInventoryEvent is streamed before creating the inventory entry for a provider.
+	CreatingInventoryEvent = cluster.CreatingInventoryEvent
+
+	// ProviderInstalledEvent is streamed when the components and the inventory entry of a provider are created.
+	ProviderInstalledEvent = cluster.ProviderInstalledEvent
+
+	// WaitingForProvidersEvent is streamed before waiting for the provider controllers to be ready.
+	WaitingForProvidersEvent = cluster.WaitingForProvidersEvent
+
+	// ProviderReadyEvent is streamed when the controllers of a provider are ready.
+	ProviderReadyEvent = cluster.ProviderReadyEvent
+)
+
+// ReleaseNotes defines the release notes of a provider version.
+type ReleaseNotes = repository.ReleaseNotes
+
+// VersionInfo defines the contract and the cluster template flavors of a provider version.
+type VersionInfo = repository.VersionInfo
+
+// ProviderVersion defines a version published by a provider, together with the Cluster API contract it supports.
+type ProviderVersion = repository.ProviderVersion
+
+// ManagementClusterHealth describes the health of the providers and of cert-manager installed in a management cluster.
+type ManagementClusterHealth cluster.ManagementClusterHealth
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sort"
+
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+// CheckManagementClusterOptions carries the options supported by CheckManagementCluster.
+type CheckManagementClusterOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+}
+
+func (c *clusterctlClient) CheckManagementCluster(options CheckManagementClusterOptions) (*ManagementClusterHealth, error) {
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, err
+	}
+
+	providerList, err := clusterClient.ProviderInventory().List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &cluster.ManagementClusterHealth{
+		Providers: []cluster.ComponentsHealth{},
+	}
+
+	certManagerHealth, err := clusterClient.CertManager().CheckHealth()
+	if err != nil {
+		return nil, err
+	}
+	report.CertManager = certManagerHealth
+	report.Healthy = certManagerHealth.Healthy
+
+	for _, provider := range providerList.Items {
+		providerHealth, err := clusterClient.ProviderComponents().CheckHealth(provider)
+		if err != nil {
+			return nil, err
+		}
+		report.Providers = append(report.Providers, *providerHealth)
+		report.Healthy = report.Healthy && providerHealth.Healthy
+	}
+	sortComponentsHealth(report.Providers)
+
+	return (*ManagementClusterHealth)(report), nil
+}
+
+// sortComponentsHealth sorts the health of the providers by instance name.
+func sortComponentsHealth(providers []cluster.ComponentsHealth) {
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+}
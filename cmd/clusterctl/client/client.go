@@ -17,6 +17,9 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"time"
+
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/alpha"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
@@ -30,45 +33,205 @@ type Client interface {
 	// GetProvidersConfig returns the list of providers configured for this instance of clusterctl.
 	GetProvidersConfig() ([]Provider, error)
 
+	// GetProvidersConfigByType returns the list of providers of the given type configured for this instance of clusterctl.
+	GetProvidersConfigByType(providerType clusterctlv1.ProviderType) ([]Provider, error)
+
+	// GetInstalledProviders returns the inventory of the provider instances installed in a management cluster, including
+	// their version, namespace and watched namespace; ErrInventoryNotInstalled is returned if the management cluster
+	// does not have the clusterctl inventory CRD. If a namespace is set in the options, only the provider instances
+	// installed in the namespace are returned.
+	GetInstalledProviders(options GetInstalledProvidersOptions) ([]clusterctlv1.Provider, error)
+
+	// GetInventoryReport returns the provider instances installed in a management cluster, or in a namespace of it, e.g.
+	// for auditing the providers installed for each tenant of a multi-tenant management cluster, together with the
+	// cluster-scoped CRDs shared with the other instances of the same providers.
+	GetInventoryReport(options GetInstalledProvidersOptions) (*InventoryReport, error)
+
+	// ValidateClusterTemplate validates the objects of a cluster template against the OpenAPI schemas of the provider
+	// CRDs, without accessing a management cluster, e.g. for validating cluster definitions in CI; the invalid fields
+	// of each object are reported with their path.
+	ValidateClusterTemplate(options ValidateClusterTemplateOptions) ([]TemplateValidationError, error)
+
+	// DiffProviders compares the providers configured for this instance of clusterctl with the providers installed in
+	// a management cluster, returning the providers to be added, the ones to be removed and the version mismatches,
+	// together with the clusterctl operation reconciling each difference; differences are sorted by type and name.
+	DiffProviders(kubeconfig Kubeconfig) ([]ProviderDiff, error)
+
+	// ValidateConfig checks the clusterctl configuration, including the reachability of the provider repositories
+	// and the overrides layer, returning the list of problems found, if any.
+	ValidateConfig(options ValidateConfigOptions) ([]ConfigValidationFinding, error)
+
 	// GetProviderComponents returns the provider components for a given provider with options including targetNamespace.
 	GetProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) (Components, error)
 
-	// Init initializes a management cluster by adding the requested list of providers.
+	// GetProviderVersions returns all the versions published by a given provider, sorted from the oldest to the newest,
+	// with the contract of each version, e.g. for selecting a version interactively or for planning upgrades; prerelease
+	// versions are included only if enabled in the clusterctl configuration. An error is returned if the provider does not
+	// have any published release.
+	GetProviderVersions(provider string, providerType clusterctlv1.ProviderType) ([]ProviderVersion, error)
+
+	// GetProviderVariables returns the variables used by the components of a given provider, sorted by name, without
+	// processing the components; variables without a default value are reported as required, and the value each
+	// variable currently resolves to is reported for helping to set the missing variables before Init.
+	GetProviderVariables(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ProviderVariable, error)
+
+	// LintProviderComponents checks the components of a given provider, in the name[:version] format, for common
+	// misconfigurations, e.g. a missing controller Deployment, webhooks and CRD conversions referring to Services not
+	// included in the components, or RBAC bindings referring to missing ServiceAccounts, without installing them.
+	// Findings are sorted by object; options.SkipTemplateProcess allows to check the components without setting the variables.
+	LintProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ComponentsLintFinding, error)
+
+	// Init initializes a management cluster by adding the requested list of providers; providers already installed at
+	// the requested version are skipped, so Init can be executed many times.
 	Init(options InitOptions) ([]Components, error)
 
+	// InitContext is the same as Init, but the operation is interrupted when the context is cancelled or its deadline expires.
+	InitContext(ctx context.Context, options InitOptions) ([]Components, error)
+
 	// InitImages returns the list of images required for executing the init command.
 	InitImages(options InitOptions) ([]string, error)
 
+	// InitImagesDetailed returns the list of images required for executing the init command, with the details
+	// about the provider requiring each image and the manifest the image is read from.
+	InitImagesDetailed(options InitOptions) ([]ImageReference, error)
+
+	// PrepareOfflineInit downloads into a folder the components and the metadata of the providers required for executing
+	// the init command, together with the cert-manager manifest, so a later Init using the clusterctl config file generated
+	// in the folder can run without accessing the provider repositories, e.g. in air-gapped environments; the list of
+	// images to be mirrored, as returned by InitImages, is written in the folder as well and returned.
+	PrepareOfflineInit(options InitOptions, dir string) ([]string, error)
+
 	// GetClusterTemplate returns a workload cluster template.
 	GetClusterTemplate(options GetClusterTemplateOptions) (Template, error)
 
 	// GetKubeconfig returns the kubeconfig of the workload cluster.
 	GetKubeconfig(options GetKubeconfigOptions) (string, error)
 
+	// GetKubeconfigs returns the kubeconfig of all the workload clusters in a namespace, indexed by cluster name.
+	// Workload clusters without a kubeconfig, e.g. because it is not yet generated, are reported in a returned
+	// aggregate error, while the kubeconfig of the other workload clusters is returned anyway.
+	GetKubeconfigs(options GetKubeconfigsOptions) (map[string]string, error)
+
+	// RegenerateKubeconfig deletes the kubeconfig Secret of a workload cluster, so the control plane provider creates it again
+	// with new credentials, and returns the new kubeconfig. Only workload clusters with a KubeadmControlPlane are supported.
+	RegenerateKubeconfig(options RegenerateKubeconfigOptions) (string, error)
+
 	// Delete deletes providers from a management cluster.
 	Delete(options DeleteOptions) error
 
+	// DeleteContext is the same as Delete, but the operation is interrupted when the context is cancelled or its deadline expires.
+	DeleteContext(ctx context.Context, options DeleteOptions) error
+
+	// DeleteProvider deletes a single provider from a management cluster, together with its components and, if requested,
+	// its CRDs, and removes it from the clusterctl inventory; the other providers are left untouched.
+	DeleteProvider(options DeleteProviderOptions) error
+
+	// FindOrphans returns the objects labeled as components of a provider which is not installed in a management cluster,
+	// e.g. the CRDs left behind by a failed delete, so they can be cleaned up.
+	FindOrphans(kubeconfig Kubeconfig) ([]OrphanedComponent, error)
+
+	// DeleteOrphans deletes the objects returned by FindOrphans, and it returns the deleted objects.
+	DeleteOrphans(options DeleteOrphansOptions) ([]OrphanedComponent, error)
+
+	// DeleteDryRun returns a report describing the objects Delete would remove from the management cluster,
+	// including the workload Clusters still depending on the providers being deleted, without deleting anything.
+	DeleteDryRun(options DeleteOptions) (*DeleteReport, error)
+
 	// Move moves all the Cluster API objects existing in a namespace (or from all the namespaces if empty) to a target management cluster.
 	Move(options MoveOptions) error
 
+	// MoveContext is the same as Move, but the operation is interrupted when the context is cancelled or its deadline expires;
+	// an interrupted move can be completed later using MoveOptions.Resume.
+	MoveContext(ctx context.Context, options MoveOptions) error
+
+	// MoveDryRun returns a report describing the objects that Move would transfer to the target management cluster,
+	// without changing either the source or the target management cluster.
+	MoveDryRun(options MoveOptions) (*MoveReport, error)
+
+	// MoveDryRunContext is the same as MoveDryRun, but the operation is interrupted when the context is cancelled or its deadline expires.
+	MoveDryRunContext(ctx context.Context, options MoveOptions) (*MoveReport, error)
+
+	// MovePlan estimates the number of objects by kind, the size of the Secrets and the duration of the move operation,
+	// measuring the latency of the target management cluster, if specified, without changing either the source or the
+	// target management cluster; objects unusually large are reported as warnings.
+	MovePlan(options MoveOptions) (*MovePlan, error)
+
+	// MovePlanContext is the same as MovePlan, but the operation is interrupted when the context is cancelled or its deadline expires.
+	MovePlanContext(ctx context.Context, options MoveOptions) (*MovePlan, error)
+
+	// CanMove checks that the target management cluster has all the providers and the types required for moving the Cluster API objects
+	// existing in a namespace (or from all the namespaces if empty), without changing either the source or the target management cluster.
+	CanMove(options MoveOptions) error
+
+	// CanMoveContext is the same as CanMove, but the operation is interrupted when the context is cancelled or its deadline expires.
+	CanMoveContext(ctx context.Context, options MoveOptions) error
+
+	// Backup saves all the Cluster API objects existing in a namespace (or from all the namespaces if empty) to a directory.
+	Backup(options BackupOptions) error
+
+	// BackupContext is the same as Backup, but the operation is interrupted when the context is cancelled or its deadline expires.
+	BackupContext(ctx context.Context, options BackupOptions) error
+
+	// Restore creates all the Cluster API objects previously saved in a directory into a target management cluster.
+	Restore(options RestoreOptions) error
+
+	// RestoreContext is the same as Restore, but the operation is interrupted when the context is cancelled or its deadline expires.
+	RestoreContext(ctx context.Context, options RestoreOptions) error
+
 	// PlanUpgrade returns a set of suggested Upgrade plans for the cluster, and more specifically:
 	// - Upgrade to the latest version in the the v1alpha3 series: ....
 	// - Upgrade to the latest version in the the v1alpha4 series: ....
 	PlanUpgrade(options PlanUpgradeOptions) ([]UpgradePlan, error)
 
+	// PlanUpgradeContext is the same as PlanUpgrade, but the operation is interrupted when the context is cancelled or its deadline expires.
+	PlanUpgradeContext(ctx context.Context, options PlanUpgradeOptions) ([]UpgradePlan, error)
+
 	// PlanCertManagerUpgrade returns a CertManagerUpgradePlan.
 	PlanCertManagerUpgrade(options PlanUpgradeOptions) (CertManagerUpgradePlan, error)
 
+	// CheckVersionSkew returns the providers in a management cluster supporting an API Version of Cluster API (contract)
+	// different from the one supported by the core provider, with the recommended target versions for fixing the skew.
+	CheckVersionSkew(options CheckVersionSkewOptions) ([]VersionSkew, error)
+
 	// ApplyUpgrade executes an upgrade plan.
 	ApplyUpgrade(options ApplyUpgradeOptions) error
 
+	// ApplyUpgradeContext is the same as ApplyUpgrade, but the operation is interrupted when the context is cancelled or its deadline expires.
+	ApplyUpgradeContext(ctx context.Context, options ApplyUpgradeOptions) error
+
+	// ApplyUpgradeDryRun returns the changes ApplyUpgrade would apply to the CustomResourceDefinitions and Deployments
+	// of the providers in the management cluster, without applying them.
+	ApplyUpgradeDryRun(options ApplyUpgradeOptions) (*UpgradeDiff, error)
+
+	// RollbackUpgrade restores the provider components as they were before an upgrade attempt.
+	RollbackUpgrade(options RollbackUpgradeOptions) error
+
 	// ProcessYAML provides a direct way to process a yaml and inspect its
 	// variables.
 	ProcessYAML(options ProcessYAMLOptions) (YamlPrinter, error)
 
+	// ResolveYAMLVariables returns the variables used by a yaml together with the value and the source
+	// each variable resolves to; values of sensitive variables are masked unless ShowSecrets is set.
+	ResolveYAMLVariables(options ProcessYAMLOptions) ([]ResolvedVariable, error)
+
 	// DescribeCluster returns the object tree representing the status of a Cluster API cluster.
 	DescribeCluster(options DescribeClusterOptions) (*tree.ObjectTree, error)
 
+	// PauseCluster pauses the reconciliation of a workload cluster and of all the objects belonging to it, e.g. for maintenance.
+	PauseCluster(options PauseClusterOptions) error
+
+	// ResumeCluster resumes the reconciliation of a workload cluster paused by PauseCluster; objects paused by other means,
+	// e.g. by a user, are not resumed.
+	ResumeCluster(options ResumeClusterOptions) error
+
+	// CheckManagementCluster checks the health of the providers and of cert-manager installed in a management cluster,
+	// i.e. if the controllers are available, the CRDs established and the webhooks reachable, returning a report for each component.
+	CheckManagementCluster(options CheckManagementClusterOptions) (*ManagementClusterHealth, error)
+
+	// GetObjectGraph returns the graph of the Cluster API objects existing in a management cluster, including the
+	// ownership relationships inferred by clusterctl for moving objects, e.g. Secrets linked to a Cluster by a naming convention.
+	GetObjectGraph(options GetObjectGraphOptions) (*ObjectGraph, error)
+
 	// Interface for alpha features in clusterctl
 	AlphaClient
 }
@@ -91,6 +254,10 @@ type YamlPrinter interface {
 	// Variables required by the template.
 	Variables() []string
 
+	// VariableMap used by the template with their default values, as defined using the ${VAR:=default} syntax.
+	// If the value is `nil`, there is no default and the variable is required.
+	VariableMap() map[string]*string
+
 	// Yaml returns yaml defining all the cluster template objects as a byte array.
 	Yaml() ([]byte, error)
 }
@@ -101,12 +268,20 @@ type clusterctlClient struct {
 	repositoryClientFactory RepositoryClientFactory
 	clusterClientFactory    ClusterClientFactory
 	alphaClient             alpha.Client
+	metricsRecorder         MetricsRecorder
+	transformers            []ComponentsTransformer
+	fieldManager            string
+	connection              *ConnectionOptions
 }
 
 // RepositoryClientFactoryInput represents the inputs required by the factory.
 type RepositoryClientFactoryInput struct {
 	Provider  Provider
 	Processor Processor
+	// Context, if set, binds the requests to the provider repository to a context.
+	Context context.Context
+	// IncludePrereleases makes prerelease versions eligible when resolving the latest version or a version range.
+	IncludePrereleases bool
 }
 
 // RepositoryClientFactory is a factory of repository.Client from a given input.
@@ -116,6 +291,12 @@ type RepositoryClientFactory func(RepositoryClientFactoryInput) (repository.Clie
 type ClusterClientFactoryInput struct {
 	Kubeconfig Kubeconfig
 	Processor  Processor
+	// Context, if set, binds the operations on the management cluster to a context.
+	Context context.Context
+	// IncludePrereleases makes prerelease versions eligible as upgrade targets.
+	IncludePrereleases bool
+	// EventFunc, if set, streams the events of the operations installing or upgrading providers.
+	EventFunc EventFunc
 }
 
 // ClusterClientFactory is a factory of cluster.Client from a given input.
@@ -150,7 +331,35 @@ func InjectClusterClientFactory(factory ClusterClientFactory) Option {
 	}
 }
 
+// WithComponentsTransformers adds transformers to be applied, in order, to the provider components read by the
+// default repository and cluster client factories, not by injected ones; see ComponentsTransformer for more details.
+func WithComponentsTransformers(transformers ...ComponentsTransformer) Option {
+	return func(c *clusterctlClient) {
+		c.transformers = append(c.transformers, transformers...)
+	}
+}
+
+// WithFieldManager sets the field manager of the objects created or updated by the default cluster client factory, not by
+// an injected one, e.g. when installing or upgrading providers, so the ownership of the fields of objects co-managed with
+// other tools, like GitOps controllers, is predictable. If not set, the field manager is derived from the clusterctl user agent.
+func WithFieldManager(fieldManager string) Option {
+	return func(c *clusterctlClient) {
+		c.fieldManager = fieldManager
+	}
+}
+
+// WithConnectionOptions sets the connection options used by the default cluster client factory, not by an injected one,
+// e.g. for trusting a custom CA, for authenticating with a client certificate or for impersonating a user in all the
+// requests to the management and workload clusters, in addition to the settings defined in the kubeconfig.
+func WithConnectionOptions(options ConnectionOptions) Option {
+	return func(c *clusterctlClient) {
+		c.connection = &options
+	}
+}
+
 // New returns a configClient.
+// NB. The clusterctl library logs using the process-global logger; programs embedding clusterctl can route the library
+// logs into their own structured logging pipeline with log.SetLogger from sigs.k8s.io/cluster-api/cmd/clusterctl/log.
 func New(path string, options ...Option) (Client, error) {
 	return newClusterctlClient(path, options...)
 }
@@ -173,12 +382,12 @@ func newClusterctlClient(path string, options ...Option) (*clusterctlClient, err
 
 	// if there is an injected RepositoryFactory, use it, otherwise use a default one.
 	if client.repositoryClientFactory == nil {
-		client.repositoryClientFactory = defaultRepositoryFactory(client.configClient)
+		client.repositoryClientFactory = defaultRepositoryFactory(client.configClient, client.transformers...)
 	}
 
 	// if there is an injected ClusterFactory, use it, otherwise use a default one.
 	if client.clusterClientFactory == nil {
-		client.clusterClientFactory = defaultClusterFactory(client.configClient)
+		client.clusterClientFactory = defaultClusterFactory(client.configClient, client.fieldManager, client.connection, client.transformers...)
 	}
 
 	// if there is an injected alphaClient, use it, otherwise use a default one.
@@ -191,24 +400,70 @@ func newClusterctlClient(path string, options ...Option) (*clusterctlClient, err
 }
 
 // defaultRepositoryFactory is a RepositoryClientFactory func the uses the default client provided by the repository low level library.
-func defaultRepositoryFactory(configClient config.Client) RepositoryClientFactory {
+func defaultRepositoryFactory(configClient config.Client, transformers ...ComponentsTransformer) RepositoryClientFactory {
 	return func(input RepositoryClientFactoryInput) (repository.Client, error) {
 		return repository.New(
 			input.Provider,
 			configClient,
 			repository.InjectYamlProcessor(input.Processor),
+			repository.InjectContext(input.Context),
+			repository.InjectIncludePrereleases(input.IncludePrereleases),
+			repository.InjectComponentsTransformers(transformers...),
 		)
 	}
 }
 
 // defaultClusterFactory is a ClusterClientFactory func the uses the default client provided by the cluster low level library.
-func defaultClusterFactory(configClient config.Client) ClusterClientFactory {
+func defaultClusterFactory(configClient config.Client, fieldManager string, connection *ConnectionOptions, transformers ...ComponentsTransformer) ClusterClientFactory {
 	return func(input ClusterClientFactoryInput) (cluster.Client, error) {
+		// Fails early if the kubeconfig context does not exist, instead of connecting to the current context.
+		if err := cluster.ValidateKubeconfigContext(cluster.Kubeconfig(input.Kubeconfig)); err != nil {
+			return nil, err
+		}
+		options := []cluster.Option{
+			cluster.InjectYamlProcessor(input.Processor),
+			cluster.InjectContext(input.Context),
+			cluster.InjectIncludePrereleases(input.IncludePrereleases),
+			cluster.InjectEventFunc(input.EventFunc),
+			cluster.InjectComponentsTransformers(transformers...),
+			cluster.InjectFieldManager(fieldManager),
+		}
+		if connection != nil {
+			options = append(options, cluster.InjectConnectionOptions(*connection))
+		}
 		return cluster.New(
 			// Kubeconfig is a type alias to cluster.Kubeconfig
 			cluster.Kubeconfig(input.Kubeconfig),
 			configClient,
-			cluster.InjectYamlProcessor(input.Processor),
+			options...,
 		), nil
 	}
 }
+
+// withContext returns a copy of the client whose cluster and repository clients are bound to ctx, so the operations
+// executed by the copy are interrupted when ctx is cancelled or its deadline expires.
+func (c *clusterctlClient) withContext(ctx context.Context) *clusterctlClient {
+	contextClient := *c
+	contextClient.repositoryClientFactory = func(input RepositoryClientFactoryInput) (repository.Client, error) {
+		if input.Context == nil {
+			input.Context = ctx
+		}
+		return c.repositoryClientFactory(input)
+	}
+	contextClient.clusterClientFactory = func(input ClusterClientFactoryInput) (cluster.Client, error) {
+		if input.Context == nil {
+			input.Context = ctx
+		}
+		return c.clusterClientFactory(input)
+	}
+	return &contextClient
+}
+
+// newOperationContext returns the context for an operation with the given timeout; a zero timeout means no timeout
+// other than the deadline of the parent context, if any.
+func newOperationContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
+	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -62,6 +64,87 @@ func TestNewFakeClient(t *testing.T) {
 		WithCluster(cluster1)
 }
 
+func Test_clusterctlClient_ContextVariants(t *testing.T) {
+	type contextKey string
+	const key = contextKey("operation")
+
+	tests := []struct {
+		name         string
+		run          func(c Client, ctx context.Context) error
+		wantDeadline bool
+	}{
+		{
+			name: "InitContext binds the cluster client to the context, with the timeout",
+			run: func(c Client, ctx context.Context) error {
+				_, err := c.InitContext(ctx, InitOptions{Timeout: time.Minute})
+				return err
+			},
+			wantDeadline: true,
+		},
+		{
+			name: "DeleteContext binds the cluster client to the context",
+			run: func(c Client, ctx context.Context) error {
+				return c.DeleteContext(ctx, DeleteOptions{})
+			},
+		},
+		{
+			name: "MoveContext binds the cluster client to the context",
+			run: func(c Client, ctx context.Context) error {
+				return c.MoveContext(ctx, MoveOptions{})
+			},
+		},
+		{
+			name: "MoveDryRunContext binds the cluster client to the context, with the timeout",
+			run: func(c Client, ctx context.Context) error {
+				_, err := c.MoveDryRunContext(ctx, MoveOptions{Timeout: time.Minute})
+				return err
+			},
+			wantDeadline: true,
+		},
+		{
+			name: "MovePlanContext binds the cluster client to the context",
+			run: func(c Client, ctx context.Context) error {
+				_, err := c.MovePlanContext(ctx, MoveOptions{})
+				return err
+			},
+		},
+		{
+			name: "CanMoveContext binds the cluster client to the context",
+			run: func(c Client, ctx context.Context) error {
+				return c.CanMoveContext(ctx, MoveOptions{})
+			},
+		},
+		{
+			name: "ApplyUpgradeContext binds the cluster client to the context, with the timeout",
+			run: func(c Client, ctx context.Context) error {
+				return c.ApplyUpgradeContext(ctx, ApplyUpgradeOptions{Timeout: time.Minute})
+			},
+			wantDeadline: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var got context.Context
+			c, err := newClusterctlClient("fake-config",
+				InjectConfig(newFakeConfig()),
+				InjectClusterClientFactory(func(input ClusterClientFactoryInput) (cluster.Client, error) {
+					got = input.Context
+					return nil, errors.New("no cluster")
+				}),
+			)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(tt.run(c, context.WithValue(context.Background(), key, "value"))).NotTo(Succeed())
+			g.Expect(got).NotTo(BeNil())
+			g.Expect(got.Value(key)).To(Equal("value"))
+			_, hasDeadline := got.Deadline()
+			g.Expect(hasDeadline).To(Equal(tt.wantDeadline))
+		})
+	}
+}
+
 type fakeClient struct {
 	configClient config.Client
 	// mapping between kubeconfigPath/context with cluster client
@@ -76,6 +159,14 @@ func (f fakeClient) GetProvidersConfig() ([]Provider, error) {
 	return f.internalClient.GetProvidersConfig()
 }
 
+func (f fakeClient) GetProvidersConfigByType(providerType clusterctlv1.ProviderType) ([]Provider, error) {
+	return f.internalClient.GetProvidersConfigByType(providerType)
+}
+
+func (f fakeClient) ValidateConfig(options ValidateConfigOptions) ([]ConfigValidationFinding, error) {
+	return f.internalClient.ValidateConfig(options)
+}
+
 func (f fakeClient) GetProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) (Components, error) {
 	return f.internalClient.GetProviderComponents(provider, providerType, options)
 }
@@ -88,6 +179,22 @@ func (f fakeClient) GetKubeconfig(options GetKubeconfigOptions) (string, error)
 	return f.internalClient.GetKubeconfig(options)
 }
 
+func (f fakeClient) GetKubeconfigs(options GetKubeconfigsOptions) (map[string]string, error) {
+	return f.internalClient.GetKubeconfigs(options)
+}
+
+func (f fakeClient) FindOrphans(kubeconfig Kubeconfig) ([]OrphanedComponent, error) {
+	return f.internalClient.FindOrphans(kubeconfig)
+}
+
+func (f fakeClient) DeleteOrphans(options DeleteOrphansOptions) ([]OrphanedComponent, error) {
+	return f.internalClient.DeleteOrphans(options)
+}
+
+func (f fakeClient) RegenerateKubeconfig(options RegenerateKubeconfigOptions) (string, error) {
+	return f.internalClient.RegenerateKubeconfig(options)
+}
+
 func (f fakeClient) Init(options InitOptions) ([]Components, error) {
 	return f.internalClient.Init(options)
 }
@@ -96,18 +203,58 @@ func (f fakeClient) InitImages(options InitOptions) ([]string, error) {
 	return f.internalClient.InitImages(options)
 }
 
+func (f fakeClient) PrepareOfflineInit(options InitOptions, dir string) ([]string, error) {
+	return f.internalClient.PrepareOfflineInit(options, dir)
+}
+
+func (f fakeClient) InitImagesDetailed(options InitOptions) ([]ImageReference, error) {
+	return f.internalClient.InitImagesDetailed(options)
+}
+
 func (f fakeClient) Delete(options DeleteOptions) error {
 	return f.internalClient.Delete(options)
 }
 
+func (f fakeClient) DeleteProvider(options DeleteProviderOptions) error {
+	return f.internalClient.DeleteProvider(options)
+}
+
+func (f fakeClient) DeleteDryRun(options DeleteOptions) (*DeleteReport, error) {
+	return f.internalClient.DeleteDryRun(options)
+}
+
 func (f fakeClient) Move(options MoveOptions) error {
 	return f.internalClient.Move(options)
 }
 
+func (f fakeClient) MoveDryRun(options MoveOptions) (*MoveReport, error) {
+	return f.internalClient.MoveDryRun(options)
+}
+
+func (f fakeClient) MovePlan(options MoveOptions) (*MovePlan, error) {
+	return f.internalClient.MovePlan(options)
+}
+
+func (f fakeClient) CanMove(options MoveOptions) error {
+	return f.internalClient.CanMove(options)
+}
+
+func (f fakeClient) Backup(options BackupOptions) error {
+	return f.internalClient.Backup(options)
+}
+
+func (f fakeClient) Restore(options RestoreOptions) error {
+	return f.internalClient.Restore(options)
+}
+
 func (f fakeClient) PlanUpgrade(options PlanUpgradeOptions) ([]UpgradePlan, error) {
 	return f.internalClient.PlanUpgrade(options)
 }
 
+func (f fakeClient) CheckVersionSkew(options CheckVersionSkewOptions) ([]VersionSkew, error) {
+	return f.internalClient.CheckVersionSkew(options)
+}
+
 func (f fakeClient) PlanCertManagerUpgrade(options PlanUpgradeOptions) (CertManagerUpgradePlan, error) {
 	return f.internalClient.PlanCertManagerUpgrade(options)
 }
@@ -116,10 +263,34 @@ func (f fakeClient) ApplyUpgrade(options ApplyUpgradeOptions) error {
 	return f.internalClient.ApplyUpgrade(options)
 }
 
+func (f fakeClient) ApplyUpgradeDryRun(options ApplyUpgradeOptions) (*UpgradeDiff, error) {
+	return f.internalClient.ApplyUpgradeDryRun(options)
+}
+
+func (f fakeClient) RollbackUpgrade(options RollbackUpgradeOptions) error {
+	return f.internalClient.RollbackUpgrade(options)
+}
+
 func (f fakeClient) ProcessYAML(options ProcessYAMLOptions) (YamlPrinter, error) {
 	return f.internalClient.ProcessYAML(options)
 }
 
+func (f fakeClient) GetProviderVersions(provider string, providerType clusterctlv1.ProviderType) ([]ProviderVersion, error) {
+	return f.internalClient.GetProviderVersions(provider, providerType)
+}
+
+func (f fakeClient) GetProviderVariables(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ProviderVariable, error) {
+	return f.internalClient.GetProviderVariables(provider, providerType, options)
+}
+
+func (f fakeClient) LintProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ComponentsLintFinding, error) {
+	return f.internalClient.LintProviderComponents(provider, providerType, options)
+}
+
+func (f fakeClient) ResolveYAMLVariables(options ProcessYAMLOptions) ([]ResolvedVariable, error) {
+	return f.internalClient.ResolveYAMLVariables(options)
+}
+
 func (f fakeClient) RolloutRestart(options RolloutOptions) error {
 	return f.internalClient.RolloutRestart(options)
 }
@@ -128,10 +299,82 @@ func (f fakeClient) DescribeCluster(options DescribeClusterOptions) (*tree.Objec
 	return f.internalClient.DescribeCluster(options)
 }
 
+func (f fakeClient) PauseCluster(options PauseClusterOptions) error {
+	return f.internalClient.PauseCluster(options)
+}
+
+func (f fakeClient) ResumeCluster(options ResumeClusterOptions) error {
+	return f.internalClient.ResumeCluster(options)
+}
+
+func (f fakeClient) CheckManagementCluster(options CheckManagementClusterOptions) (*ManagementClusterHealth, error) {
+	return f.internalClient.CheckManagementCluster(options)
+}
+
+func (f fakeClient) InitContext(ctx context.Context, options InitOptions) ([]Components, error) {
+	return f.internalClient.InitContext(ctx, options)
+}
+
+func (f fakeClient) DeleteContext(ctx context.Context, options DeleteOptions) error {
+	return f.internalClient.DeleteContext(ctx, options)
+}
+
+func (f fakeClient) MoveContext(ctx context.Context, options MoveOptions) error {
+	return f.internalClient.MoveContext(ctx, options)
+}
+
+func (f fakeClient) MoveDryRunContext(ctx context.Context, options MoveOptions) (*MoveReport, error) {
+	return f.internalClient.MoveDryRunContext(ctx, options)
+}
+
+func (f fakeClient) MovePlanContext(ctx context.Context, options MoveOptions) (*MovePlan, error) {
+	return f.internalClient.MovePlanContext(ctx, options)
+}
+
+func (f fakeClient) CanMoveContext(ctx context.Context, options MoveOptions) error {
+	return f.internalClient.CanMoveContext(ctx, options)
+}
+
+func (f fakeClient) BackupContext(ctx context.Context, options BackupOptions) error {
+	return f.internalClient.BackupContext(ctx, options)
+}
+
+func (f fakeClient) RestoreContext(ctx context.Context, options RestoreOptions) error {
+	return f.internalClient.RestoreContext(ctx, options)
+}
+
+func (f fakeClient) PlanUpgradeContext(ctx context.Context, options PlanUpgradeOptions) ([]UpgradePlan, error) {
+	return f.internalClient.PlanUpgradeContext(ctx, options)
+}
+
+func (f fakeClient) ApplyUpgradeContext(ctx context.Context, options ApplyUpgradeOptions) error {
+	return f.internalClient.ApplyUpgradeContext(ctx, options)
+}
+
+func (f fakeClient) GetObjectGraph(options GetObjectGraphOptions) (*ObjectGraph, error) {
+	return f.internalClient.GetObjectGraph(options)
+}
+
 func (f fakeClient) RolloutPause(options RolloutOptions) error {
 	return f.internalClient.RolloutPause(options)
 }
 
+func (f fakeClient) GetInstalledProviders(options GetInstalledProvidersOptions) ([]clusterctlv1.Provider, error) {
+	return f.internalClient.GetInstalledProviders(options)
+}
+
+func (f fakeClient) ValidateClusterTemplate(options ValidateClusterTemplateOptions) ([]TemplateValidationError, error) {
+	return f.internalClient.ValidateClusterTemplate(options)
+}
+
+func (f fakeClient) DiffProviders(kubeconfig Kubeconfig) ([]ProviderDiff, error) {
+	return f.internalClient.DiffProviders(kubeconfig)
+}
+
+func (f fakeClient) GetInventoryReport(options GetInstalledProvidersOptions) (*InventoryReport, error) {
+	return f.internalClient.GetInventoryReport(options)
+}
+
 func (f fakeClient) RolloutResume(options RolloutOptions) error {
 	return f.internalClient.RolloutResume(options)
 }
@@ -228,17 +471,32 @@ func newFakeCertManagerClient(imagesReturnImages []string, imagesReturnError err
 }
 
 type fakeCertManagerClient struct {
-	images          []string
-	imagesError     error
-	certManagerPlan cluster.CertManagerUpgradePlan
+	images                []string
+	imagesError           error
+	certManagerPlan       cluster.CertManagerUpgradePlan
+	checkReadyError       error
+	ensureInstalledCalled bool
 }
 
 var _ cluster.CertManagerClient = &fakeCertManagerClient{}
 
 func (p *fakeCertManagerClient) EnsureInstalled() error {
+	p.ensureInstalledCalled = true
 	return nil
 }
 
+func (p *fakeCertManagerClient) CheckReady() error {
+	return p.checkReadyError
+}
+
+func (p *fakeCertManagerClient) CheckHealth() (*cluster.ComponentsHealth, error) {
+	return &cluster.ComponentsHealth{
+		Name:    "cert-manager",
+		Healthy: p.checkReadyError == nil,
+		Checks:  []cluster.HealthCheck{},
+	}, nil
+}
+
 func (p *fakeCertManagerClient) EnsureLatestVersion() error {
 	return nil
 }
@@ -275,7 +533,7 @@ func (f fakeClusterClient) Proxy() cluster.Proxy {
 	return f.fakeProxy
 }
 
-func (f *fakeClusterClient) CertManager() cluster.CertManagerClient {
+func (f *fakeClusterClient) CertManager(options ...cluster.CertManagerOption) cluster.CertManagerClient {
 	return f.certManager
 }
 
@@ -338,10 +596,10 @@ func (f *fakeClusterClient) WithCertManagerClient(client cluster.CertManagerClie
 // newFakeConfig return a fake implementation of the client for low-level config library.
 // The implementation uses a FakeReader that stores configuration settings in a map; you can use
 // the WithVar or WithProvider methods to set the map values.
-func newFakeConfig() *fakeConfigClient {
+func newFakeConfig(options ...config.Option) *fakeConfigClient {
 	fakeReader := test.NewFakeReader()
 
-	client, _ := config.New("fake-config", config.InjectReader(fakeReader))
+	client, _ := config.New("fake-config", append([]config.Option{config.InjectReader(fakeReader)}, options...)...)
 
 	return &fakeConfigClient{
 		fakeReader:     fakeReader,
@@ -372,6 +630,10 @@ func (f fakeConfigClient) ImageMeta() config.ImageMetaClient {
 	return f.internalclient.ImageMeta()
 }
 
+func (f fakeConfigClient) Validate() []config.ValidationFinding {
+	return f.internalclient.Validate()
+}
+
 func (f *fakeConfigClient) WithVar(key, value string) *fakeConfigClient {
 	f.fakeReader.WithVar(key, value)
 	return f
@@ -382,6 +644,11 @@ func (f *fakeConfigClient) WithProvider(provider config.Provider) *fakeConfigCli
 	return f
 }
 
+func (f *fakeConfigClient) WithProviderAlias(alias, provider string) *fakeConfigClient {
+	f.fakeReader.WithProviderAlias(alias, provider)
+	return f
+}
+
 // newFakeRepository return a fake implementation of the client for low-level repository library.
 // The implementation stores configuration settings in a map; you can use
 // the WithPaths or WithDefaultVersion methods to configure the repository and WithFile to set the map values.
@@ -417,6 +684,30 @@ func (f fakeRepositoryClient) GetVersions() ([]string, error) {
 	return f.fakeRepository.GetVersions()
 }
 
+func (f fakeRepositoryClient) GetChangelog(fromVersion, toVersion string) ([]repository.ReleaseNotes, error) {
+	repo, err := repository.New(f.Provider, f.configClient, repository.InjectRepository(f.fakeRepository))
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetChangelog(fromVersion, toVersion)
+}
+
+func (f fakeRepositoryClient) GetVersionInfo(version string) (*repository.VersionInfo, error) {
+	repo, err := repository.New(f.Provider, f.configClient, repository.InjectRepository(f.fakeRepository))
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetVersionInfo(version)
+}
+
+func (f fakeRepositoryClient) GetPublishedVersions() ([]repository.ProviderVersion, error) {
+	repo, err := repository.New(f.Provider, f.configClient, repository.InjectRepository(f.fakeRepository))
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetPublishedVersions()
+}
+
 func (f fakeRepositoryClient) Components() repository.ComponentsClient {
 	// use a fakeComponentClient (instead of the internal client used in other fake objects) we can de deterministic on what is returned (e.g. avoid interferences from overrides)
 	return &fakeComponentClient{
@@ -519,6 +810,14 @@ func (f *fakeMetadataClient) Get() (*clusterctlv1.Metadata, error) {
 	return obj, nil
 }
 
+func (f *fakeMetadataClient) ValidateContract() (*clusterctlv1.ReleaseSeries, error) {
+	metadata, err := f.Get()
+	if err != nil {
+		return nil, err
+	}
+	return repository.ValidateMetadataContract(metadata, f.version)
+}
+
 // fakeComponentClient provides a super simple ComponentClient (e.g. without support for local overrides).
 type fakeComponentClient struct {
 	provider       config.Provider
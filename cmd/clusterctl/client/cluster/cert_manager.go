@@ -19,6 +19,8 @@ package cluster
 import (
 	"context"
 	_ "embed"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -33,6 +35,7 @@ import (
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
 	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/cluster-api/util/container"
 	utilresource "sigs.k8s.io/cluster-api/util/resource"
 	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
 )
@@ -42,6 +45,10 @@ const (
 
 	certManagerNamespace = "cert-manager"
 
+	// minimumCertManagerVersion is the oldest cert-manager version clusterctl can manage, which is the first one
+	// supporting the cert-manager.io/v1 API used by the clusterctl test resources.
+	minimumCertManagerVersion = "v1.0.0"
+
 	// Deprecated: Use clusterctlv1.CertManagerVersionAnnotation instead.
 	// This is maintained only for supporting upgrades from cluster created with clusterctl v1alpha3.
 	certManagerVersionAnnotation = "certmanager.clusterctl.cluster.x-k8s.io/version"
@@ -55,9 +62,17 @@ var (
 // CertManagerUpgradePlan defines the upgrade plan if cert-manager needs to be
 // upgraded to a different version.
 type CertManagerUpgradePlan struct {
-	ExternallyManaged bool
-	From, To          string
-	ShouldUpgrade     bool
+	// ExternallyManaged is true if cert-manager is not managed by clusterctl.
+	ExternallyManaged bool `json:"externallyManaged"`
+
+	// From is the version of cert-manager installed in the management cluster.
+	From string `json:"from"`
+
+	// To is the version of cert-manager supported by clusterctl.
+	To string `json:"to"`
+
+	// ShouldUpgrade is true if cert-manager has to be upgraded.
+	ShouldUpgrade bool `json:"shouldUpgrade"`
 }
 
 // CertManagerClient has methods to work with cert-manager components in the cluster.
@@ -66,6 +81,14 @@ type CertManagerClient interface {
 	// This is required to install a new provider.
 	EnsureInstalled() error
 
+	// CheckReady checks cert-manager is running and its API is available, without installing it.
+	// This is required to install a new provider when cert-manager is managed outside of clusterctl.
+	CheckReady() error
+
+	// CheckHealth checks the health of the cert-manager components installed by clusterctl, and if the cert-manager
+	// API is available; if cert-manager is managed outside of clusterctl only the API is checked.
+	CheckHealth() (*ComponentsHealth, error)
+
 	// EnsureLatestVersion checks the cert-manager version currently installed, and if it is
 	// older than the version currently suggested by clusterctl, upgrades it.
 	EnsureLatestVersion() error
@@ -78,25 +101,82 @@ type CertManagerClient interface {
 	Images() ([]string, error)
 }
 
+// CertManagerOption is a configuration option supplied to CertManager.
+type CertManagerOption func(*certManagerClient)
+
+// WithCertManagerVersion pins the cert-manager version to install or to upgrade to, overriding the version
+// defined in the clusterctl configuration (or the default version embedded in clusterctl).
+func WithCertManagerVersion(version string) CertManagerOption {
+	return func(cm *certManagerClient) {
+		cm.version = version
+	}
+}
+
+// WithCertManagerImageRegistry replaces the registry of all the cert-manager images, i.e. the controller, the cainjector
+// and the webhook images, e.g. with the registry of an internal mirror, preserving the repository path, the tag and the
+// digest of each image; the image overrides defined in the clusterctl configuration, if any, are applied before.
+func WithCertManagerImageRegistry(registry string) CertManagerOption {
+	return func(cm *certManagerClient) {
+		cm.imageRegistry = registry
+	}
+}
+
 // certManagerClient implements CertManagerClient .
 type certManagerClient struct {
 	configClient            config.Client
 	repositoryClientFactory RepositoryClientFactory
 	proxy                   Proxy
 	pollImmediateWaiter     PollImmediateWaiter
+	version                 string
+	imageRegistry           string
 }
 
 // Ensure certManagerClient implements the CertManagerClient interface.
 var _ CertManagerClient = &certManagerClient{}
 
 // newCertManagerClient returns a certManagerClient.
-func newCertManagerClient(configClient config.Client, repositoryClientFactory RepositoryClientFactory, proxy Proxy, pollImmediateWaiter PollImmediateWaiter) *certManagerClient {
-	return &certManagerClient{
+func newCertManagerClient(configClient config.Client, repositoryClientFactory RepositoryClientFactory, proxy Proxy, pollImmediateWaiter PollImmediateWaiter, options ...CertManagerOption) *certManagerClient {
+	cm := &certManagerClient{
 		configClient:            configClient,
 		repositoryClientFactory: repositoryClientFactory,
 		proxy:                   proxy,
 		pollImmediateWaiter:     pollImmediateWaiter,
 	}
+	for _, o := range options {
+		o(cm)
+	}
+	return cm
+}
+
+// getConfig returns the cert-manager configuration, with the version pinned by WithCertManagerVersion, if any.
+func (cm *certManagerClient) getConfig() (config.CertManager, error) {
+	certManagerConfig, err := cm.configClient.CertManager().Get()
+	if err != nil {
+		return nil, err
+	}
+	if cm.version == "" {
+		return certManagerConfig, nil
+	}
+
+	if err := validateCertManagerVersion(cm.version); err != nil {
+		return nil, err
+	}
+	return config.NewCertManager(certManagerConfig.URL(), cm.version, certManagerConfig.Timeout()), nil
+}
+
+// validateCertManagerVersion checks a cert-manager version is a valid semantic version that clusterctl can manage.
+func validateCertManagerVersion(v string) error {
+	semVersion, err := version.ParseSemantic(v)
+	if err != nil {
+		return errors.Wrapf(err, "invalid cert-manager version %q", v)
+	}
+	if !strings.HasPrefix(v, "v") {
+		return errors.Errorf("invalid cert-manager version %q: the version must start with v, e.g. %s", v, config.CertManagerDefaultVersion)
+	}
+	if semVersion.LessThan(version.MustParseSemantic(minimumCertManagerVersion)) {
+		return errors.Errorf("invalid cert-manager version %q: clusterctl supports cert-manager %s or newer", v, minimumCertManagerVersion)
+	}
+	return nil
 }
 
 // Images return the list of images required for installing the cert-manager.
@@ -111,7 +191,7 @@ func (cm *certManagerClient) Images() ([]string, error) {
 	}
 
 	// Otherwise, retrieve the images from the cert-manager manifest.
-	config, err := cm.configClient.CertManager().Get()
+	config, err := cm.getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -162,10 +242,39 @@ func (cm *certManagerClient) EnsureInstalled() error {
 	return cm.install()
 }
 
+func (cm *certManagerClient) CheckReady() error {
+	// Checking if a version of cert manager supporting cert-manager-test-resources.yaml is installed and properly working,
+	// which implies also the cert-manager webhook is reachable.
+	if err := cm.waitForAPIReady(ctx, false); err != nil {
+		return errors.Wrap(err, "cert-manager is not installed or it is not working properly; installing providers requires a working cert-manager")
+	}
+	return nil
+}
+
+func (cm *certManagerClient) CheckHealth() (*ComponentsHealth, error) {
+	health := &ComponentsHealth{
+		Name:   clusterctlv1.ClusterctlCoreLabelCertManagerValue,
+		Checks: []HealthCheck{},
+	}
+	labels := client.MatchingLabels{clusterctlv1.ClusterctlCoreLabelName: clusterctlv1.ClusterctlCoreLabelCertManagerValue}
+	if err := checkComponentsHealth(cm.proxy, health, certManagerNamespace, labels); err != nil {
+		return nil, errors.Wrap(err, "failed to check the health of cert-manager")
+	}
+
+	message := ""
+	if err := cm.waitForAPIReady(ctx, false); err != nil {
+		message = fmt.Sprintf("cert-manager API is not available: %v", err)
+	}
+	health.addCheck(apiKind, "cert-manager.io", message)
+
+	health.complete()
+	return health, nil
+}
+
 func (cm *certManagerClient) install() error {
 	log := logf.Log
 
-	config, err := cm.configClient.CertManager().Get()
+	config, err := cm.getConfig()
 	if err != nil {
 		return err
 	}
@@ -294,7 +403,7 @@ func (cm *certManagerClient) deleteObjs(objs []unstructured.Unstructured) error
 }
 
 func (cm *certManagerClient) shouldUpgrade(objs []unstructured.Unstructured) (string, string, bool, error) {
-	config, err := cm.configClient.CertManager().Get()
+	config, err := cm.getConfig()
 	if err != nil {
 		return "", "", false, err
 	}
@@ -351,7 +460,7 @@ func (cm *certManagerClient) shouldUpgrade(objs []unstructured.Unstructured) (st
 func (cm *certManagerClient) getWaitTimeout() time.Duration {
 	log := logf.Log
 
-	certManagerConfig, err := cm.configClient.CertManager().Get()
+	certManagerConfig, err := cm.getConfig()
 	if err != nil {
 		return config.CertManagerDefaultTimeout
 	}
@@ -395,6 +504,16 @@ func (cm *certManagerClient) getManifestObjs(certManagerConfig config.CertManage
 		return nil, errors.Wrap(err, "failed to apply image override to the cert-manager manifest")
 	}
 
+	// Apply the image registry override, if defined.
+	if cm.imageRegistry != "" {
+		objs, err = util.FixImages(objs, func(image string) (string, error) {
+			return container.ModifyImageRegistry(image, cm.imageRegistry)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to apply the image registry override to the cert-manager manifest")
+		}
+	}
+
 	// Add cert manager labels and annotations.
 	objs = addCerManagerLabel(objs)
 	objs = addCerManagerAnnotations(objs, certManagerConfig.Version())
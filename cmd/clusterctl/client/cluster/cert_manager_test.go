@@ -36,6 +36,7 @@ import (
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
 	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -163,6 +164,44 @@ func Test_getManifestObjs(t *testing.T) {
 	}
 }
 
+func Test_getManifestObjs_withImageRegistry(t *testing.T) {
+	g := NewWithT(t)
+
+	configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cainjectorDeploymentYaml := []byte("apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: cert-manager-cainjector\n" +
+		"spec:\n" +
+		"  template:\n" +
+		"    spec:\n" +
+		"      containers:\n" +
+		"      - name: cainjector\n" +
+		"        image: quay.io/jetstack/cert-manager-cainjector:v1.1.0\n")
+	repository1 := test.NewFakeRepository().
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion(config.CertManagerDefaultVersion).
+		WithFile(config.CertManagerDefaultVersion, "components.yaml", utilyaml.JoinYaml(certManagerNamespaceYaml, certManagerDeploymentYaml, cainjectorDeploymentYaml))
+
+	cm := newCertManagerClient(configClient, func(provider config.Provider, configClient config.Client, options ...repository.Option) (repository.Client, error) {
+		return repository.New(provider, configClient, repository.InjectRepository(repository1))
+	}, nil, nil, WithCertManagerImageRegistry("mirror.example.com"))
+
+	certManagerConfig, err := cm.getConfig()
+	g.Expect(err).NotTo(HaveOccurred())
+	objs, err := cm.getManifestObjs(certManagerConfig)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	images, err := util.InspectImages(objs)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(images).To(ConsistOf(
+		"mirror.example.com/jetstack/cert-manager:v1.1.0",
+		"mirror.example.com/jetstack/cert-manager-cainjector:v1.1.0",
+	))
+}
+
 func Test_GetTimeout(t *testing.T) {
 	pollImmediateWaiter := func(interval, timeout time.Duration, condition wait.ConditionFunc) error {
 		return nil
@@ -499,6 +538,7 @@ func Test_certManagerClient_PlanUpgrade(t *testing.T) {
 	tests := []struct {
 		name         string
 		objs         []client.Object
+		version      string
 		expectErr    bool
 		expectedPlan CertManagerUpgradePlan
 	}{
@@ -591,6 +631,47 @@ func Test_certManagerClient_PlanUpgrade(t *testing.T) {
 				ShouldUpgrade: false,
 			},
 		},
+		{
+			name: "returns the upgrade plan for cert-manager to the pinned version",
+			objs: []client.Object{
+				&appsv1.Deployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Deployment",
+						APIVersion: appsv1.SchemeGroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cert-manager",
+						Labels:      map[string]string{clusterctlv1.ClusterctlCoreLabelName: clusterctlv1.ClusterctlCoreLabelCertManagerValue},
+						Annotations: map[string]string{clusterctlv1.CertManagerVersionAnnotation: config.CertManagerDefaultVersion},
+					},
+				},
+			},
+			version:   "v1.5.0",
+			expectErr: false,
+			expectedPlan: CertManagerUpgradePlan{
+				From:          config.CertManagerDefaultVersion,
+				To:            "v1.5.0",
+				ShouldUpgrade: true,
+			},
+		},
+		{
+			name: "returns empty plan and error if the pinned version is not supported",
+			objs: []client.Object{
+				&appsv1.Deployment{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Deployment",
+						APIVersion: appsv1.SchemeGroupVersion.String(),
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cert-manager",
+						Labels:      map[string]string{clusterctlv1.ClusterctlCoreLabelName: clusterctlv1.ClusterctlCoreLabelCertManagerValue},
+						Annotations: map[string]string{clusterctlv1.CertManagerVersionAnnotation: config.CertManagerDefaultVersion},
+					},
+				},
+			},
+			version:   "v0.16.1",
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -602,7 +683,7 @@ func Test_certManagerClient_PlanUpgrade(t *testing.T) {
 			pollImmediateWaiter := func(interval, timeout time.Duration, condition wait.ConditionFunc) error {
 				return nil
 			}
-			cm := newCertManagerClient(fakeConfigClient, nil, proxy, pollImmediateWaiter)
+			cm := newCertManagerClient(fakeConfigClient, nil, proxy, pollImmediateWaiter, WithCertManagerVersion(tt.version))
 
 			actualPlan, err := cm.PlanUpgrade()
 			if tt.expectErr {
@@ -690,6 +771,10 @@ func (f fakeConfigClient) ImageMeta() config.ImageMetaClient {
 	return f.internalclient.ImageMeta()
 }
 
+func (f fakeConfigClient) Validate() []config.ValidationFinding {
+	return f.internalclient.Validate()
+}
+
 func (f *fakeConfigClient) WithVar(key, value string) *fakeConfigClient {
 	f.fakeReader.WithVar(key, value)
 	return f
@@ -704,3 +789,83 @@ func (f *fakeConfigClient) WithCertManager(url, version, timeout string) *fakeCo
 	f.fakeReader.WithCertManager(url, version, timeout)
 	return f
 }
+
+func Test_certManagerClient_CheckReady(t *testing.T) {
+	g := NewWithT(t)
+
+	pollImmediateWaiter := func(interval, timeout time.Duration, condition wait.ConditionFunc) error {
+		ok, err := condition()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return wait.ErrWaitTimeout
+		}
+		return nil
+	}
+
+	// NB. The fake client accepts the cert-manager test resources, so this simulates a cluster with a working cert-manager.
+	proxy := test.NewFakeProxy()
+	cm := newCertManagerClient(newFakeConfig(), nil, proxy, pollImmediateWaiter)
+	g.Expect(cm.CheckReady()).To(Succeed())
+
+	// Check the test resources are removed.
+	testObjs, err := getTestResourcesManifestObjs()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	cl, err := proxy.NewClient()
+	g.Expect(err).ToNot(HaveOccurred())
+	for i := range testObjs {
+		obj := testObjs[i].DeepCopy()
+		err := cl.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	}
+}
+
+func Test_validateCertManagerVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		wantErr bool
+	}{
+		{
+			name:    "default version",
+			version: config.CertManagerDefaultVersion,
+		},
+		{
+			name:    "minimum supported version",
+			version: "v1.0.0",
+		},
+		{
+			name:    "newer version",
+			version: "v1.5.3",
+		},
+		{
+			name:    "version older than the minimum supported version",
+			version: "v0.16.1",
+			wantErr: true,
+		},
+		{
+			name:    "version without the v prefix",
+			version: "1.5.3",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			version: "latest",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateCertManagerVersion(tt.version)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
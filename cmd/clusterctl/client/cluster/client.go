@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
 	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -63,7 +64,7 @@ type Client interface {
 
 	// CertManager returns a CertManagerClient that can be user for
 	// operating the cert-manager components in the cluster.
-	CertManager() CertManagerClient
+	CertManager(options ...CertManagerOption) CertManagerClient
 
 	// ProviderComponents returns a ComponentsClient object that can be user for
 	// operating provider components objects in the management cluster (e.g. the CRDs, controllers, RBAC).
@@ -102,6 +103,12 @@ type clusterClient struct {
 	repositoryClientFactory RepositoryClientFactory
 	pollImmediateWaiter     PollImmediateWaiter
 	processor               yaml.Processor
+	ctx                     context.Context
+	includePrereleases      bool
+	eventFunc               EventFunc
+	transformers            []repository.ComponentsTransformer
+	fieldManager            string
+	connection              *ConnectionOptions
 }
 
 // RepositoryClientFactory defines a function that returns a new repository.Client.
@@ -118,8 +125,8 @@ func (c *clusterClient) Proxy() Proxy {
 	return c.proxy
 }
 
-func (c *clusterClient) CertManager() CertManagerClient {
-	return newCertManagerClient(c.configClient, c.repositoryClientFactory, c.proxy, c.pollImmediateWaiter)
+func (c *clusterClient) CertManager(options ...CertManagerOption) CertManagerClient {
+	return newCertManagerClient(c.configClient, c.repositoryClientFactory, c.proxy, c.pollImmediateWaiter, options...)
 }
 
 func (c *clusterClient) ProviderComponents() ComponentsClient {
@@ -131,7 +138,9 @@ func (c *clusterClient) ProviderInventory() InventoryClient {
 }
 
 func (c *clusterClient) ProviderInstaller() ProviderInstaller {
-	return newProviderInstaller(c.configClient, c.repositoryClientFactory, c.proxy, c.ProviderInventory(), c.ProviderComponents())
+	installer := newProviderInstaller(c.configClient, c.repositoryClientFactory, c.proxy, c.ProviderInventory(), c.ProviderComponents())
+	installer.eventFunc = c.eventFunc
+	return installer
 }
 
 func (c *clusterClient) ObjectMover() ObjectMover {
@@ -139,7 +148,10 @@ func (c *clusterClient) ObjectMover() ObjectMover {
 }
 
 func (c *clusterClient) ProviderUpgrader() ProviderUpgrader {
-	return newProviderUpgrader(c.configClient, c.repositoryClientFactory, c.ProviderInventory(), c.ProviderComponents())
+	upgrader := newProviderUpgrader(c.configClient, c.proxy, c.repositoryClientFactory, c.ProviderInventory(), c.ProviderComponents())
+	upgrader.includePrereleases = c.includePrereleases
+	upgrader.eventFunc = c.eventFunc
+	return upgrader
 }
 
 func (c *clusterClient) Template() TemplateClient {
@@ -147,7 +159,7 @@ func (c *clusterClient) Template() TemplateClient {
 }
 
 func (c *clusterClient) WorkloadCluster() WorkloadCluster {
-	return newWorkloadCluster(c.proxy)
+	return newWorkloadCluster(c.proxy, c.pollImmediateWaiter)
 }
 
 // Option is a configuration option supplied to New.
@@ -186,6 +198,54 @@ func InjectYamlProcessor(p yaml.Processor) Option {
 	}
 }
 
+// InjectContext binds the operations of the client to a context, so they are interrupted when the context is
+// cancelled or its deadline expires; the context applies to the default proxy, PollImmediateWaiter and
+// RepositoryClientFactory, not to injected ones.
+func InjectContext(ctx context.Context) Option {
+	return func(c *clusterClient) {
+		c.ctx = ctx
+	}
+}
+
+// InjectIncludePrereleases makes prerelease provider versions, e.g. release candidates, eligible as upgrade targets,
+// as if the repository-include-prereleases variable was set to true.
+func InjectIncludePrereleases(include bool) Option {
+	return func(c *clusterClient) {
+		c.includePrereleases = c.includePrereleases || include
+	}
+}
+
+// InjectEventFunc sets a function streaming the events of the ProviderInstaller and the ProviderUpgrader, e.g. for
+// showing the progress of the installation of each provider.
+func InjectEventFunc(f EventFunc) Option {
+	return func(c *clusterClient) {
+		c.eventFunc = f
+	}
+}
+
+// InjectComponentsTransformers adds transformers to be applied, in order, to the provider components read by the
+// default RepositoryClientFactory, not by injected ones.
+func InjectComponentsTransformers(transformers ...repository.ComponentsTransformer) Option {
+	return func(c *clusterClient) {
+		c.transformers = append(c.transformers, transformers...)
+	}
+}
+
+// InjectFieldManager sets the field manager of the objects created or updated by the default proxy, not by an injected one.
+func InjectFieldManager(fieldManager string) Option {
+	return func(c *clusterClient) {
+		c.fieldManager = fieldManager
+	}
+}
+
+// InjectConnectionOptions sets the connection options, e.g. a custom CA or the user to impersonate, used by the default proxy,
+// not by an injected one.
+func InjectConnectionOptions(options ConnectionOptions) Option {
+	return func(c *clusterClient) {
+		c.connection = &options
+	}
+}
+
 // New returns a cluster.Client.
 func New(kubeconfig Kubeconfig, configClient config.Client, options ...Option) Client {
 	return newClusterClient(kubeconfig, configClient, options...)
@@ -203,17 +263,42 @@ func newClusterClient(kubeconfig Kubeconfig, configClient config.Client, options
 
 	// if there is an injected proxy, use it, otherwise use a default one
 	if client.proxy == nil {
-		client.proxy = newProxy(client.kubeconfig)
+		proxyOptions := []ProxyOption{}
+		if client.ctx != nil {
+			proxyOptions = append(proxyOptions, InjectProxyContext(client.ctx))
+		}
+		if client.fieldManager != "" {
+			proxyOptions = append(proxyOptions, InjectProxyFieldManager(client.fieldManager))
+		}
+		if client.connection != nil {
+			proxyOptions = append(proxyOptions, InjectProxyConnectionOptions(*client.connection))
+		}
+		client.proxy = newProxy(client.kubeconfig, proxyOptions...)
 	}
 
 	// if there is an injected repositoryClientFactory, use it, otherwise use the default one
 	if client.repositoryClientFactory == nil {
 		client.repositoryClientFactory = repository.New
+		defaultOptions := []repository.Option{}
+		if client.ctx != nil {
+			defaultOptions = append(defaultOptions, repository.InjectContext(client.ctx))
+		}
+		if len(client.transformers) > 0 {
+			defaultOptions = append(defaultOptions, repository.InjectComponentsTransformers(client.transformers...))
+		}
+		if len(defaultOptions) > 0 {
+			client.repositoryClientFactory = func(provider config.Provider, configClient config.Client, options ...repository.Option) (repository.Client, error) {
+				return repository.New(provider, configClient, append(append([]repository.Option{}, defaultOptions...), options...)...)
+			}
+		}
 	}
 
 	// if there is an injected PollImmediateWaiter, use it, otherwise use the default one
 	if client.pollImmediateWaiter == nil {
 		client.pollImmediateWaiter = wait.PollImmediate
+		if client.ctx != nil {
+			client.pollImmediateWaiter = newContextPollImmediateWaiter(client.ctx)
+		}
 	}
 
 	return client
@@ -237,7 +322,17 @@ type Proxy interface {
 	ListResources(labels map[string]string, namespaces ...string) ([]unstructured.Unstructured, error)
 }
 
-// retryWithExponentialBackoff repeats an operation until it passes or the exponential backoff times out.
+// newContextPollImmediateWaiter returns a PollImmediateWaiter that stops polling when the context is done.
+func newContextPollImmediateWaiter(ctx context.Context) PollImmediateWaiter {
+	return func(interval, timeout time.Duration, condition wait.ConditionFunc) error {
+		pollCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return wait.PollImmediateUntil(interval, condition, pollCtx.Done())
+	}
+}
+
+// retryWithExponentialBackoff repeats an operation until it passes or the exponential backoff times out;
+// errors caused by a cancelled context or by an expired deadline are not retried.
 func retryWithExponentialBackoff(opts wait.Backoff, operation func() error) error {
 	log := logf.Log
 
@@ -245,7 +340,7 @@ func retryWithExponentialBackoff(opts wait.Backoff, operation func() error) erro
 	err := wait.ExponentialBackoff(opts, func() (bool, error) {
 		i++
 		if err := operation(); err != nil {
-			if i < opts.Steps {
+			if i < opts.Steps && !util.IsContextError(err) {
 				log.V(5).Info("Operation failed, retrying with backoff", "Cause", err.Error())
 				return false, nil
 			}
@@ -18,6 +18,7 @@ package cluster
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -59,9 +60,36 @@ type ComponentsClient interface {
 	// and for the deletion of the provider's CRDs.
 	Delete(options DeleteOptions) error
 
+	// DeleteDryRun returns a report describing the provider components Delete would remove from the management cluster,
+	// and the workload Clusters still depending on the provider, without deleting anything.
+	DeleteDryRun(options DeleteOptions) (*DeleteReport, error)
+
+	// GetDependentClusters returns the workload Clusters existing in the management cluster that depend on a provider.
+	GetDependentClusters(provider clusterctlv1.Provider) ([]corev1.ObjectReference, error)
+
+	// ListLeftovers returns the objects existing in a namespace which are not labeled as provider components, and that
+	// are left behind when deleting providers while preserving the namespace where they are hosted.
+	ListLeftovers(namespace string) ([]corev1.ObjectReference, error)
+
+	// ListOrphans returns the objects labeled as components of a provider which is not in the given list of installed
+	// providers, e.g. the leftovers of a failed delete; namespaced objects are orphaned also if the provider is installed
+	// only in other namespaces. The objects managed by clusterctl itself, e.g. cert-manager, are never reported.
+	ListOrphans(providers []clusterctlv1.Provider) ([]OrphanedComponent, error)
+
+	// DeleteOrphans deletes the given orphaned objects from the management cluster; objects already deleted are ignored.
+	DeleteOrphans(orphans []OrphanedComponent) error
+
 	// DeleteWebhookNamespace deletes the core provider webhook namespace (eg. capi-webhook-system).
 	// This is required when upgrading to v1alpha4 where webhooks are included in the controller itself.
 	DeleteWebhookNamespace() error
+
+	// Diff returns the changes to the CustomResourceDefinitions and Deployments of a provider that installing the
+	// given objects is going to apply, without actually applying them.
+	Diff(provider clusterctlv1.Provider, objs []unstructured.Unstructured) ([]ComponentChange, error)
+
+	// CheckHealth checks the provider controllers Deployments are available, the provider CRDs are established and
+	// the provider webhooks are reachable.
+	CheckHealth(provider clusterctlv1.Provider) (*ComponentsHealth, error)
 }
 
 // providerComponents implements ComponentsClient.
@@ -128,6 +156,45 @@ func (p *providerComponents) Delete(options DeleteOptions) error {
 	log := logf.Log
 	log.Info("Deleting", "Provider", options.Provider.Name, "Version", options.Provider.Version, "TargetNamespace", options.Provider.Namespace)
 
+	resourcesToDelete, namespacesToDelete, err := p.getResourcesToDelete(options)
+	if err != nil {
+		return err
+	}
+
+	// Delete all the provider components.
+	cs, err := p.proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	errList := []error{}
+	for i := range resourcesToDelete {
+		obj := resourcesToDelete[i]
+
+		// if the objects is in a namespace that is going to be deleted, skip deletion
+		// because everything that is contained in the namespace will be deleted by the Namespace controller
+		if namespacesToDelete.Has(obj.GetNamespace()) {
+			continue
+		}
+
+		// Otherwise delete the object
+		log.V(5).Info("Deleting", logf.UnstructuredToValues(obj)...)
+		if err := cs.Delete(ctx, &obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				// Tolerate IsNotFound error that might happen because we are not enforcing a deletion order
+				// that considers relation across objects (e.g. Deployments -> ReplicaSets -> Pods)
+				continue
+			}
+			errList = append(errList, errors.Wrapf(err, "Error deleting object %s, %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName()))
+		}
+	}
+
+	return kerrors.NewAggregate(errList)
+}
+
+// getResourcesToDelete returns the provider components to be deleted according to the delete options, and the
+// namespaces to be deleted.
+func (p *providerComponents) getResourcesToDelete(options DeleteOptions) ([]unstructured.Unstructured, sets.String, error) {
 	// Fetch all the components belonging to a provider.
 	// We want that the delete operation is able to clean-up everything.
 	labels := map[string]string{
@@ -138,7 +205,7 @@ func (p *providerComponents) Delete(options DeleteOptions) error {
 	namespaces := []string{options.Provider.Namespace}
 	resources, err := p.proxy.ListResources(labels, namespaces...)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Filter the resources according to the delete options
@@ -184,35 +251,36 @@ func (p *providerComponents) Delete(options DeleteOptions) error {
 		resourcesToDelete = append(resourcesToDelete, obj)
 	}
 
-	// Delete all the provider components.
-	cs, err := p.proxy.NewClient()
+	return resourcesToDelete, namespacesToDelete, nil
+}
+
+func (p *providerComponents) ListLeftovers(namespace string) ([]corev1.ObjectReference, error) {
+	// NOTE: Listing without labels returns all the objects in the namespace, and all the cluster-scoped objects.
+	resources, err := p.proxy.ListResources(map[string]string{}, namespace)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	errList := []error{}
-	for i := range resourcesToDelete {
-		obj := resourcesToDelete[i]
-
-		// if the objects is in a namespace that is going to be deleted, skip deletion
-		// because everything that is contained in the namespace will be deleted by the Namespace controller
-		if namespacesToDelete.Has(obj.GetNamespace()) {
+	leftovers := []corev1.ObjectReference{}
+	for i := range resources {
+		obj := resources[i]
+		if obj.GetNamespace() != namespace {
 			continue
 		}
-
-		// Otherwise delete the object
-		log.V(5).Info("Deleting", logf.UnstructuredToValues(obj)...)
-		if err := cs.Delete(ctx, &obj); err != nil {
-			if apierrors.IsNotFound(err) {
-				// Tolerate IsNotFound error that might happen because we are not enforcing a deletion order
-				// that considers relation across objects (e.g. Deployments -> ReplicaSets -> Pods)
-				continue
-			}
-			errList = append(errList, errors.Wrapf(err, "Error deleting object %s, %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName()))
+		if _, ok := obj.GetLabels()[clusterctlv1.ClusterctlLabelName]; ok {
+			continue
 		}
+		// Skip objects having an owner, e.g. the Pods generated by a Deployment, because they are deleted by
+		// the garbage collector together with their owner; also Events are not relevant for this check.
+		if len(obj.GetOwnerReferences()) > 0 || obj.GetKind() == "Event" {
+			continue
+		}
+		leftovers = append(leftovers, objectReference(&obj))
 	}
-
-	return kerrors.NewAggregate(errList)
+	sort.Slice(leftovers, func(i, j int) bool {
+		return objectReferenceSortKey(leftovers[i]) < objectReferenceSortKey(leftovers[j])
+	})
+	return leftovers, nil
 }
 
 func (p *providerComponents) DeleteWebhookNamespace() error {
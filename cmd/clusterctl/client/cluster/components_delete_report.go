@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeleteReport describes the objects a delete operation is going to remove from the management cluster.
+type DeleteReport struct {
+	// Providers lists the instance names of the providers being deleted.
+	Providers []string `json:"providers,omitempty"`
+
+	// Namespaces to be deleted, together with all the objects they contain.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// CustomResourceDefinitions to be deleted, together with all the objects of the kinds they define.
+	CustomResourceDefinitions []string `json:"customResourceDefinitions,omitempty"`
+
+	// ClusterResources lists the other cluster-scoped objects to be deleted, e.g. ClusterRoles or webhook configurations.
+	ClusterResources []corev1.ObjectReference `json:"clusterResources,omitempty"`
+
+	// Objects lists the namespaced objects to be deleted, excluding the objects hosted in the namespaces to be deleted.
+	Objects []corev1.ObjectReference `json:"objects,omitempty"`
+
+	// DependentClusters lists the workload Clusters still existing in the management cluster that depend on the
+	// providers being deleted; deleting providers in use by a Cluster leaves the Cluster without the controllers
+	// reconciling it.
+	DependentClusters []DependentCluster `json:"dependentClusters,omitempty"`
+}
+
+// DependentCluster describes a workload Cluster depending on the providers being deleted.
+type DependentCluster struct {
+	// Cluster is the reference to the workload Cluster.
+	Cluster corev1.ObjectReference `json:"cluster"`
+
+	// Providers lists the instance names of the providers being deleted the Cluster depends on.
+	Providers []string `json:"providers"`
+}
+
+// HasDependentClusters returns true if any workload Cluster depends on the providers being deleted.
+func (r *DeleteReport) HasDependentClusters() bool {
+	return len(r.DependentClusters) > 0
+}
+
+// Merge adds the content of another DeleteReport to this report.
+func (r *DeleteReport) Merge(other *DeleteReport) {
+	r.Providers = append(r.Providers, other.Providers...)
+	r.Namespaces = append(r.Namespaces, other.Namespaces...)
+	r.CustomResourceDefinitions = append(r.CustomResourceDefinitions, other.CustomResourceDefinitions...)
+	r.ClusterResources = append(r.ClusterResources, other.ClusterResources...)
+	r.Objects = append(r.Objects, other.Objects...)
+
+	for _, d := range other.DependentClusters {
+		found := false
+		for i := range r.DependentClusters {
+			if r.DependentClusters[i].Cluster == d.Cluster {
+				r.DependentClusters[i].Providers = append(r.DependentClusters[i].Providers, d.Providers...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.DependentClusters = append(r.DependentClusters, d)
+		}
+	}
+	sort.Slice(r.DependentClusters, func(i, j int) bool {
+		return objectReferenceSortKey(r.DependentClusters[i].Cluster) < objectReferenceSortKey(r.DependentClusters[j].Cluster)
+	})
+}
+
+func (p *providerComponents) DeleteDryRun(options DeleteOptions) (*DeleteReport, error) {
+	resourcesToDelete, namespacesToDelete, err := p.getResourcesToDelete(options)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DeleteReport{
+		Providers:  []string{options.Provider.InstanceName()},
+		Namespaces: namespacesToDelete.List(),
+	}
+	for i := range resourcesToDelete {
+		obj := resourcesToDelete[i]
+		switch {
+		case obj.GetKind() == namespaceKind:
+			continue
+		case obj.GetKind() == customResourceDefinitionKind:
+			report.CustomResourceDefinitions = append(report.CustomResourceDefinitions, obj.GetName())
+		case util.IsClusterResource(obj.GetKind()):
+			report.ClusterResources = append(report.ClusterResources, objectReference(&obj))
+		case !namespacesToDelete.Has(obj.GetNamespace()):
+			report.Objects = append(report.Objects, objectReference(&obj))
+		}
+	}
+	sort.Strings(report.CustomResourceDefinitions)
+	sort.Slice(report.ClusterResources, func(i, j int) bool {
+		return objectReferenceSortKey(report.ClusterResources[i]) < objectReferenceSortKey(report.ClusterResources[j])
+	})
+	sort.Slice(report.Objects, func(i, j int) bool {
+		return objectReferenceSortKey(report.Objects[i]) < objectReferenceSortKey(report.Objects[j])
+	})
+
+	clusters, err := p.GetDependentClusters(options.Provider)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clusters {
+		report.DependentClusters = append(report.DependentClusters, DependentCluster{
+			Cluster:   c,
+			Providers: []string{options.Provider.InstanceName()},
+		})
+	}
+
+	return report, nil
+}
+
+// GetDependentClusters returns the workload Clusters depending on a provider.
+// All the Clusters depend on the core provider, while a Cluster depends on another provider if any object of a kind
+// defined by the provider's CRDs belongs to the Cluster, either because it is owned by the Cluster or because it has
+// the cluster name label.
+func (p *providerComponents) GetDependentClusters(provider clusterctlv1.Provider) ([]corev1.ObjectReference, error) {
+	c, err := p.proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterList := &clusterv1.ClusterList{}
+	if err := c.List(ctx, clusterList); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list Clusters")
+	}
+
+	existingClusters := map[string]corev1.ObjectReference{}
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		existingClusters[cluster.Namespace+"/"+cluster.Name] = corev1.ObjectReference{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "Cluster",
+			Namespace:  cluster.Namespace,
+			Name:       cluster.Name,
+		}
+	}
+
+	dependentClusters := map[string]corev1.ObjectReference{}
+	if provider.GetProviderType() == clusterctlv1.CoreProviderType {
+		dependentClusters = existingClusters
+	} else if len(existingClusters) > 0 {
+		crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+		if err := c.List(ctx, crdList, client.MatchingLabels{
+			clusterctlv1.ClusterctlLabelName: "",
+			clusterv1.ProviderLabelName:      provider.ManifestLabel(),
+		}); err != nil {
+			return nil, errors.Wrapf(err, "failed to list CustomResourceDefinitions for the %s provider", provider.InstanceName())
+		}
+
+		for i := range crdList.Items {
+			crd := &crdList.Items[i]
+			gvk, ok := crdStorageGroupVersionKind(crd)
+			if !ok {
+				continue
+			}
+
+			objList := &unstructured.UnstructuredList{}
+			objList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			if err := c.List(ctx, objList); err != nil {
+				if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, errors.Wrapf(err, "failed to list %s objects", gvk.Kind)
+			}
+
+			for _, obj := range objList.Items {
+				for _, clusterName := range ownerClusterNames(obj) {
+					key := obj.GetNamespace() + "/" + clusterName
+					if ref, ok := existingClusters[key]; ok {
+						dependentClusters[key] = ref
+					}
+				}
+			}
+		}
+	}
+
+	ret := make([]corev1.ObjectReference, 0, len(dependentClusters))
+	for _, ref := range dependentClusters {
+		ret = append(ret, ref)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return objectReferenceSortKey(ret[i]) < objectReferenceSortKey(ret[j])
+	})
+	return ret, nil
+}
+
+// crdStorageGroupVersionKind returns the GroupVersionKind objects defined by a CRD are stored with.
+func crdStorageGroupVersionKind(crd *apiextensionsv1.CustomResourceDefinition) (schema.GroupVersionKind, bool) {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}, true
+		}
+	}
+	return schema.GroupVersionKind{}, false
+}
+
+// ownerClusterNames returns the names of the Clusters an object belongs to, according to the cluster name label
+// and to the owner references.
+func ownerClusterNames(obj unstructured.Unstructured) []string {
+	names := []string{}
+	if name, ok := obj.GetLabels()[clusterv1.ClusterLabelName]; ok && name != "" {
+		names = append(names, name)
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if ref.Kind == "Cluster" && gv.Group == clusterv1.GroupVersion.Group {
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
+
+func objectReferenceSortKey(ref corev1.ObjectReference) string {
+	return ref.Kind + "/" + ref.Namespace + "/" + ref.Name
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	fakeinfrastructure "sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test/providers/infrastructure"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_providerComponents_DeleteDryRun(t *testing.T) {
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      "infrastructure-infra",
+	}
+
+	infraCRD := test.FakeNamespacedCustomResourceDefinition(fakeinfrastructure.GroupVersion.Group, "GenericInfrastructureCluster", fakeinfrastructure.GroupVersion.Version)
+	infraCRD.TypeMeta = metav1.TypeMeta{
+		APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+		Kind:       "CustomResourceDefinition",
+	}
+	infraCRD.Labels = labels
+
+	fakeCluster := func(name string) *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+			},
+		}
+	}
+
+	initObjs := []client.Object{
+		&corev1.Namespace{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Namespace",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "ns1",
+				Labels: labels,
+			},
+		},
+		&corev1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1",
+				Name:      "pod1",
+				Labels:    labels,
+			},
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ClusterRole",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "ns1-cluster-role",
+				Labels: labels,
+			},
+		},
+		infraCRD,
+		// cluster1 depends on the infra provider, because it has an infrastructure object with the cluster name label.
+		fakeCluster("cluster1"),
+		&fakeinfrastructure.GenericInfrastructureCluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: fakeinfrastructure.GroupVersion.String(),
+				Kind:       "GenericInfrastructureCluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "cluster1",
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName: "cluster1",
+				},
+			},
+		},
+		// cluster2 does not use objects of the infra provider.
+		fakeCluster("cluster2"),
+	}
+
+	infra := clusterctlv1.Provider{ObjectMeta: metav1.ObjectMeta{Name: "infrastructure-infra", Namespace: "ns1"}, ProviderName: "infra", Type: string(clusterctlv1.InfrastructureProviderType)}
+	core := clusterctlv1.Provider{ObjectMeta: metav1.ObjectMeta{Name: "cluster-api", Namespace: "capi-system"}, ProviderName: "cluster-api", Type: string(clusterctlv1.CoreProviderType)}
+
+	tests := []struct {
+		name    string
+		options DeleteOptions
+		want    *DeleteReport
+	}{
+		{
+			name:    "Preserving Namespace and CRDs",
+			options: DeleteOptions{Provider: infra},
+			want: &DeleteReport{
+				Providers:        []string{"ns1/infrastructure-infra"},
+				Namespaces:       []string{},
+				ClusterResources: []corev1.ObjectReference{{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "ns1-cluster-role"}},
+				Objects:          []corev1.ObjectReference{{APIVersion: "v1", Kind: "Pod", Namespace: "ns1", Name: "pod1"}},
+				DependentClusters: []DependentCluster{
+					{
+						Cluster:   corev1.ObjectReference{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Namespace: "default", Name: "cluster1"},
+						Providers: []string{"ns1/infrastructure-infra"},
+					},
+				},
+			},
+		},
+		{
+			name:    "Including Namespace and CRDs",
+			options: DeleteOptions{Provider: infra, IncludeNamespace: true, IncludeCRDs: true},
+			want: &DeleteReport{
+				Providers:                 []string{"ns1/infrastructure-infra"},
+				Namespaces:                []string{"ns1"},
+				CustomResourceDefinitions: []string{infraCRD.Name},
+				ClusterResources:          []corev1.ObjectReference{{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "ns1-cluster-role"}},
+				DependentClusters: []DependentCluster{
+					{
+						Cluster:   corev1.ObjectReference{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Namespace: "default", Name: "cluster1"},
+						Providers: []string{"ns1/infrastructure-infra"},
+					},
+				},
+			},
+		},
+		{
+			name:    "All the Clusters depend on the core provider",
+			options: DeleteOptions{Provider: core},
+			want: &DeleteReport{
+				Providers:  []string{"capi-system/cluster-api"},
+				Namespaces: []string{},
+				DependentClusters: []DependentCluster{
+					{
+						Cluster:   corev1.ObjectReference{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Namespace: "default", Name: "cluster1"},
+						Providers: []string{"capi-system/cluster-api"},
+					},
+					{
+						Cluster:   corev1.ObjectReference{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Namespace: "default", Name: "cluster2"},
+						Providers: []string{"capi-system/cluster-api"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			proxy := test.NewFakeProxy().WithObjs(initObjs...)
+			c := newComponentsClient(proxy)
+
+			got, err := c.DeleteDryRun(tt.options)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+
+			// Check nothing has been deleted.
+			cs, err := proxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, obj := range initObjs {
+				g.Expect(cs.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))).To(Succeed())
+			}
+		})
+	}
+}
+
+func Test_DeleteReport_Merge(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster1 := corev1.ObjectReference{Kind: "Cluster", Namespace: "default", Name: "cluster1"}
+	cluster2 := corev1.ObjectReference{Kind: "Cluster", Namespace: "default", Name: "cluster2"}
+
+	report := &DeleteReport{
+		Providers:         []string{"capi-system/cluster-api"},
+		DependentClusters: []DependentCluster{{Cluster: cluster2, Providers: []string{"capi-system/cluster-api"}}},
+	}
+	report.Merge(&DeleteReport{
+		Providers:  []string{"infra-system/infrastructure-infra"},
+		Namespaces: []string{"infra-system"},
+		DependentClusters: []DependentCluster{
+			{Cluster: cluster1, Providers: []string{"infra-system/infrastructure-infra"}},
+			{Cluster: cluster2, Providers: []string{"infra-system/infrastructure-infra"}},
+		},
+	})
+
+	g.Expect(report.Providers).To(Equal([]string{"capi-system/cluster-api", "infra-system/infrastructure-infra"}))
+	g.Expect(report.Namespaces).To(Equal([]string{"infra-system"}))
+	g.Expect(report.HasDependentClusters()).To(BeTrue())
+	g.Expect(report.DependentClusters).To(Equal([]DependentCluster{
+		{Cluster: cluster1, Providers: []string{"infra-system/infrastructure-infra"}},
+		{Cluster: cluster2, Providers: []string{"capi-system/cluster-api", "infra-system/infrastructure-infra"}},
+	}))
+}
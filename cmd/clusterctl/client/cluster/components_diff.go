@@ -0,0 +1,264 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const deploymentKind = "Deployment"
+
+// ComponentChangeType defines the type of change an upgrade is going to apply to a provider component.
+type ComponentChangeType string
+
+const (
+	// ComponentCreated identifies a component which does not exist in the management cluster and will be created.
+	ComponentCreated ComponentChangeType = "Create"
+
+	// ComponentUpdated identifies a component which exists in the management cluster and will be updated.
+	ComponentUpdated ComponentChangeType = "Update"
+
+	// ComponentDeleted identifies a component which exists in the management cluster and will be deleted.
+	ComponentDeleted ComponentChangeType = "Delete"
+)
+
+// UpgradeDiff describes the changes an upgrade is going to apply to the provider components in a management cluster.
+type UpgradeDiff struct {
+	// Changes lists the changes to CustomResourceDefinitions and Deployments, grouped by provider.
+	Changes []ComponentChange `json:"changes,omitempty"`
+}
+
+// HasStorageVersionChanges returns true if the upgrade changes the storage version of at least one CustomResourceDefinition.
+func (d *UpgradeDiff) HasStorageVersionChanges() bool {
+	for _, c := range d.Changes {
+		if c.StorageVersionChange != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ComponentChange describes a change to a provider component.
+type ComponentChange struct {
+	// Provider is the instance name of the provider the component belongs to.
+	Provider string `json:"provider"`
+
+	// Object is the reference to the component.
+	Object corev1.ObjectReference `json:"object"`
+
+	// Type of the change.
+	Type ComponentChangeType `json:"type"`
+
+	// Diff is a human readable diff between the component in the management cluster and
+	// the component after the upgrade; it is empty for components being created or deleted.
+	Diff string `json:"diff,omitempty"`
+
+	// StorageVersionChange is set when the change modifies the storage version of a CustomResourceDefinition.
+	StorageVersionChange *StorageVersionChange `json:"storageVersionChange,omitempty"`
+}
+
+// StorageVersionChange describes a change of the storage version of a CustomResourceDefinition.
+type StorageVersionChange struct {
+	// From is the storage version currently defined in the management cluster.
+	From string `json:"from"`
+
+	// To is the storage version defined after the upgrade.
+	To string `json:"to"`
+
+	// StoredVersions are the versions that objects of this type were ever persisted with in the management cluster.
+	StoredVersions []string `json:"storedVersions,omitempty"`
+}
+
+func (p *providerComponents) Diff(provider clusterctlv1.Provider, objs []unstructured.Unstructured) ([]ComponentChange, error) {
+	c, err := p.proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ComponentChange{}
+	desiredDeployments := sets.NewString()
+	readComponentObjectBackoff := newReadBackoff()
+	for i := range objs {
+		obj := objs[i].DeepCopy()
+		kind := obj.GetKind()
+		if kind != customResourceDefinitionKind && kind != deploymentKind {
+			continue
+		}
+		if kind == deploymentKind {
+			desiredDeployments.Insert(obj.GetNamespace() + "/" + obj.GetName())
+		}
+
+		var change *ComponentChange
+		if err := retryWithExponentialBackoff(readComponentObjectBackoff, func() error {
+			change, err = diffObj(c, obj)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if change != nil {
+			change.Provider = provider.InstanceName()
+			changes = append(changes, *change)
+		}
+	}
+
+	// Deployments of the current version of the provider not included in the new version are going to be deleted.
+	// NB. CRDs are never deleted during upgrades, so they are not considered here.
+	currentDeployments := &unstructured.UnstructuredList{}
+	currentDeployments.SetAPIVersion("apps/v1")
+	currentDeployments.SetKind("DeploymentList")
+	if err := retryWithExponentialBackoff(readComponentObjectBackoff, func() error {
+		return c.List(ctx, currentDeployments,
+			client.InNamespace(provider.Namespace),
+			client.MatchingLabels{
+				clusterctlv1.ClusterctlLabelName: "",
+				clusterv1.ProviderLabelName:      provider.ManifestLabel(),
+			},
+		)
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list Deployments for the %s provider", provider.InstanceName())
+	}
+	for _, d := range currentDeployments.Items {
+		if desiredDeployments.Has(d.GetNamespace() + "/" + d.GetName()) {
+			continue
+		}
+		changes = append(changes, ComponentChange{
+			Provider: provider.InstanceName(),
+			Object:   objectReference(&d),
+			Type:     ComponentDeleted,
+		})
+	}
+
+	return changes, nil
+}
+
+// diffObj computes the change to a component by running the same operation used during upgrades in dry-run mode;
+// CRDs are patched, while all the other components are re-created (and thus fully replaced).
+// It returns nil if the component is not going to change.
+func diffObj(c client.Client, obj *unstructured.Unstructured) (*ComponentChange, error) {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	key := client.ObjectKey{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	if err := c.Get(ctx, key, current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get current provider object %s, %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+		}
+		return &ComponentChange{
+			Object: objectReference(obj),
+			Type:   ComponentCreated,
+		}, nil
+	}
+
+	obj.SetResourceVersion(current.GetResourceVersion())
+	if obj.GetKind() == customResourceDefinitionKind {
+		if err := c.Patch(ctx, obj, client.Merge, client.DryRunAll); err != nil {
+			return nil, errors.Wrapf(err, "failed to dry-run patch provider object %s, %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+		}
+	} else {
+		if err := c.Update(ctx, obj, client.DryRunAll); err != nil {
+			return nil, errors.Wrapf(err, "failed to dry-run update provider object %s, %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+		}
+	}
+
+	diff := cmp.Diff(normalizeForDiff(current), normalizeForDiff(obj))
+	if diff == "" {
+		return nil, nil
+	}
+
+	change := &ComponentChange{
+		Object: objectReference(obj),
+		Type:   ComponentUpdated,
+		Diff:   diff,
+	}
+	if obj.GetKind() == customResourceDefinitionKind {
+		from, to := getCRDStorageVersion(current), getCRDStorageVersion(obj)
+		if from != to {
+			storedVersions, _, _ := unstructured.NestedStringSlice(current.Object, "status", "storedVersions")
+			change.StorageVersionChange = &StorageVersionChange{
+				From:           from,
+				To:             to,
+				StoredVersions: storedVersions,
+			}
+		}
+	}
+	return change, nil
+}
+
+// normalizeForDiff drops from an object the status and the metadata fields managed by the API server, which
+// are not relevant when comparing the component in the management cluster with the component after the upgrade.
+func normalizeForDiff(obj *unstructured.Unstructured) map[string]interface{} {
+	ret := obj.DeepCopy()
+	delete(ret.Object, "status")
+	for _, f := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(ret.Object, "metadata", f)
+	}
+	return ret.Object
+}
+
+// getCRDStorageVersion returns the version flagged as storage version in a CustomResourceDefinition.
+func getCRDStorageVersion(crd *unstructured.Unstructured) string {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if storage, _, _ := unstructured.NestedBool(version, "storage"); storage {
+			name, _, _ := unstructured.NestedString(version, "name")
+			return name
+		}
+	}
+	return ""
+}
+
+func objectReference(obj *unstructured.Unstructured) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// sortComponentChanges sorts changes by provider, kind, namespace and name.
+func sortComponentChanges(changes []ComponentChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Object.Kind != b.Object.Kind {
+			return a.Object.Kind < b.Object.Kind
+		}
+		if a.Object.Namespace != b.Object.Namespace {
+			return a.Object.Namespace < b.Object.Namespace
+		}
+		return a.Object.Name < b.Object.Name
+	})
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_providerComponents_Diff(t *testing.T) {
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      "infrastructure-infra",
+	}
+
+	provider := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v1.0.0", "ns1")
+
+	fakeCRD := func(storageVersion string, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+		crd := &apiextensionsv1.CustomResourceDefinition{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+				Kind:       "CustomResourceDefinition",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "infraclusters.infrastructure.cluster.x-k8s.io",
+				Labels: labels,
+			},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: "infrastructure.cluster.x-k8s.io",
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:   "InfraCluster",
+					Plural: "infraclusters",
+				},
+				Scope: apiextensionsv1.NamespaceScoped,
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				StoredVersions: []string{storageVersion},
+			},
+		}
+		for _, v := range versions {
+			crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+				Name:    v,
+				Served:  true,
+				Storage: v == storageVersion,
+			})
+		}
+		return crd
+	}
+
+	fakeDeployment := func(name, image string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1",
+				Name:      name,
+				Labels:    labels,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "manager", Image: image}},
+					},
+				},
+			},
+		}
+	}
+
+	toUnstructured := func(g *WithT, objs ...client.Object) []unstructured.Unstructured {
+		ret := []unstructured.Unstructured{}
+		for _, o := range objs {
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+			g.Expect(err).NotTo(HaveOccurred())
+			ret = append(ret, unstructured.Unstructured{Object: u})
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name        string
+		initObjs    []client.Object
+		objs        []client.Object
+		wantChanges map[string]ComponentChangeType
+		wantStorage *StorageVersionChange
+	}{
+		{
+			name:        "no changes",
+			initObjs:    []client.Object{fakeCRD("v1alpha3", "v1alpha3"), fakeDeployment("manager", "infra:v1.0.0")},
+			objs:        []client.Object{fakeCRD("v1alpha3", "v1alpha3"), fakeDeployment("manager", "infra:v1.0.0")},
+			wantChanges: map[string]ComponentChangeType{},
+		},
+		{
+			name:     "components to be created, updated and deleted",
+			initObjs: []client.Object{fakeDeployment("manager", "infra:v1.0.0"), fakeDeployment("old-manager", "infra:v1.0.0")},
+			objs: []client.Object{
+				fakeCRD("v1alpha3", "v1alpha3"),
+				fakeDeployment("manager", "infra:v1.1.0"),
+				&corev1.ServiceAccount{ // only CRDs and Deployments are considered
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "manager"},
+				},
+			},
+			wantChanges: map[string]ComponentChangeType{
+				"CustomResourceDefinition/infraclusters.infrastructure.cluster.x-k8s.io": ComponentCreated,
+				"Deployment/manager":     ComponentUpdated,
+				"Deployment/old-manager": ComponentDeleted,
+			},
+		},
+		{
+			name:     "CRD with a storage version change",
+			initObjs: []client.Object{fakeCRD("v1alpha3", "v1alpha3")},
+			objs:     []client.Object{fakeCRD("v1alpha4", "v1alpha3", "v1alpha4")},
+			wantChanges: map[string]ComponentChangeType{
+				"CustomResourceDefinition/infraclusters.infrastructure.cluster.x-k8s.io": ComponentUpdated,
+			},
+			wantStorage: &StorageVersionChange{
+				From:           "v1alpha3",
+				To:             "v1alpha4",
+				StoredVersions: []string{"v1alpha3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			proxy := test.NewFakeProxy().WithObjs(tt.initObjs...)
+			c := newComponentsClient(proxy)
+
+			changes, err := c.Diff(provider, toUnstructured(g, tt.objs...))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got := map[string]ComponentChangeType{}
+			for _, change := range changes {
+				g.Expect(change.Provider).To(Equal(provider.InstanceName()))
+				got[change.Object.Kind+"/"+change.Object.Name] = change.Type
+				if change.Type == ComponentUpdated {
+					g.Expect(change.Diff).ToNot(BeEmpty())
+				}
+				if change.Object.Kind == customResourceDefinitionKind {
+					g.Expect(change.StorageVersionChange).To(Equal(tt.wantStorage))
+				}
+			}
+			g.Expect(got).To(Equal(tt.wantChanges))
+
+			// Check the components in the cluster are not changed.
+			cs, err := proxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, o := range tt.initObjs {
+				d, ok := o.(*appsv1.Deployment)
+				if !ok {
+					continue
+				}
+				current := &appsv1.Deployment{}
+				g.Expect(cs.Get(ctx, client.ObjectKeyFromObject(d), current)).To(Succeed())
+				g.Expect(current.Spec).To(Equal(d.Spec))
+			}
+		})
+	}
+}
+
+func Test_UpgradeDiff_HasStorageVersionChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	diff := &UpgradeDiff{
+		Changes: []ComponentChange{
+			{Type: ComponentUpdated},
+		},
+	}
+	g.Expect(diff.HasStorageVersionChanges()).To(BeFalse())
+
+	diff.Changes = append(diff.Changes, ComponentChange{Type: ComponentUpdated, StorageVersionChange: &StorageVersionChange{From: "v1alpha3", To: "v1alpha4"}})
+	g.Expect(diff.HasStorageVersionChanges()).To(BeTrue())
+}
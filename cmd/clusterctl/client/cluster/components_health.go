@@ -0,0 +1,243 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	webhookKind = "Webhook"
+	apiKind     = "API"
+)
+
+// ManagementClusterHealth describes the health of the providers and of cert-manager installed in a management cluster.
+type ManagementClusterHealth struct {
+	// Healthy is true if all the components in the report are healthy.
+	Healthy bool `json:"healthy"`
+
+	// CertManager describes the health of cert-manager.
+	CertManager *ComponentsHealth `json:"certManager,omitempty"`
+
+	// Providers describes the health of the providers, sorted by instance name.
+	Providers []ComponentsHealth `json:"providers"`
+}
+
+// ComponentsHealth describes the health of the components of a provider, or of cert-manager.
+type ComponentsHealth struct {
+	// Name of the component, i.e. the instance name for providers, or cert-manager.
+	Name string `json:"name"`
+
+	// Type of the provider; empty for cert-manager.
+	Type string `json:"type,omitempty"`
+
+	// Version of the component, if known.
+	Version string `json:"version,omitempty"`
+
+	// Healthy is true if all the checks succeeded.
+	Healthy bool `json:"healthy"`
+
+	// Checks lists the results of the checks on the component's objects, sorted by kind and name.
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the result of a check on an object belonging to a component.
+type HealthCheck struct {
+	// Kind of the object being checked, i.e. Deployment, CustomResourceDefinition or Webhook, or API for the
+	// check on the cert-manager API.
+	Kind string `json:"kind"`
+
+	// Name of the object; webhooks are identified by the name of the webhook configuration and of the webhook.
+	Name string `json:"name"`
+
+	// Healthy is true if the check succeeded.
+	Healthy bool `json:"healthy"`
+
+	// Message describes why the check failed.
+	Message string `json:"message,omitempty"`
+}
+
+func (h *ComponentsHealth) addCheck(kind, name, failureMessage string) {
+	h.Checks = append(h.Checks, HealthCheck{
+		Kind:    kind,
+		Name:    name,
+		Healthy: failureMessage == "",
+		Message: failureMessage,
+	})
+}
+
+func (h *ComponentsHealth) complete() {
+	sort.Slice(h.Checks, func(i, j int) bool {
+		if h.Checks[i].Kind != h.Checks[j].Kind {
+			return h.Checks[i].Kind < h.Checks[j].Kind
+		}
+		return h.Checks[i].Name < h.Checks[j].Name
+	})
+
+	h.Healthy = true
+	for _, c := range h.Checks {
+		h.Healthy = h.Healthy && c.Healthy
+	}
+}
+
+func (p *providerComponents) CheckHealth(provider clusterctlv1.Provider) (*ComponentsHealth, error) {
+	labels := client.MatchingLabels{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      provider.ManifestLabel(),
+	}
+
+	health := &ComponentsHealth{
+		Name:    provider.InstanceName(),
+		Type:    provider.Type,
+		Version: provider.Version,
+		Checks:  []HealthCheck{},
+	}
+	if err := checkComponentsHealth(p.proxy, health, provider.Namespace, labels); err != nil {
+		return nil, errors.Wrapf(err, "failed to check the health of provider %q", provider.InstanceName())
+	}
+	health.complete()
+	return health, nil
+}
+
+// checkComponentsHealth checks the Deployments in the given namespace, and the CustomResourceDefinitions and the
+// webhooks matching the given labels, adding the results to health.
+func checkComponentsHealth(proxy Proxy, health *ComponentsHealth, namespace string, labels client.MatchingLabels) error {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	deploymentList := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deploymentList, client.InNamespace(namespace), labels); err != nil {
+		return errors.Wrap(err, "failed to list Deployments")
+	}
+	for i := range deploymentList.Items {
+		d := &deploymentList.Items[i]
+		health.addCheck(deploymentKind, fmt.Sprintf("%s/%s", d.Namespace, d.Name), deploymentFailureMessage(d))
+	}
+
+	crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := c.List(ctx, crdList, labels); err != nil {
+		return errors.Wrap(err, "failed to list CustomResourceDefinitions")
+	}
+	for i := range crdList.Items {
+		crd := &crdList.Items[i]
+		health.addCheck(customResourceDefinitionKind, crd.Name, crdFailureMessage(crd))
+	}
+
+	validatingWebhookList := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := c.List(ctx, validatingWebhookList, labels); err != nil {
+		return errors.Wrap(err, "failed to list ValidatingWebhookConfigurations")
+	}
+	for _, w := range validatingWebhookList.Items {
+		for _, webhook := range w.Webhooks {
+			message, err := webhookFailureMessage(c, webhook.ClientConfig)
+			if err != nil {
+				return err
+			}
+			health.addCheck(webhookKind, fmt.Sprintf("%s/%s", w.Name, webhook.Name), message)
+		}
+	}
+
+	mutatingWebhookList := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := c.List(ctx, mutatingWebhookList, labels); err != nil {
+		return errors.Wrap(err, "failed to list MutatingWebhookConfigurations")
+	}
+	for _, w := range mutatingWebhookList.Items {
+		for _, webhook := range w.Webhooks {
+			message, err := webhookFailureMessage(c, webhook.ClientConfig)
+			if err != nil {
+				return err
+			}
+			health.addCheck(webhookKind, fmt.Sprintf("%s/%s", w.Name, webhook.Name), message)
+		}
+	}
+	return nil
+}
+
+// deploymentFailureMessage returns why a Deployment is not available, or an empty string if it is available.
+func deploymentFailureMessage(d *appsv1.Deployment) string {
+	for _, c := range d.Status.Conditions {
+		if c.Type != appsv1.DeploymentAvailable {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			return ""
+		}
+		if c.Message != "" {
+			return c.Message
+		}
+		return "Deployment is not available"
+	}
+	return "Deployment does not report the Available condition"
+}
+
+// crdFailureMessage returns why a CustomResourceDefinition is not established, or an empty string if it is established.
+func crdFailureMessage(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, c := range crd.Status.Conditions {
+		if c.Type != apiextensionsv1.Established {
+			continue
+		}
+		if c.Status == apiextensionsv1.ConditionTrue {
+			return ""
+		}
+		if c.Message != "" {
+			return c.Message
+		}
+		return "CustomResourceDefinition is not established"
+	}
+	return "CustomResourceDefinition does not report the Established condition"
+}
+
+// webhookFailureMessage returns why a webhook is not reachable, or an empty string if it is reachable.
+// Webhooks backed by a Service are considered reachable if the Service has at least one ready endpoint;
+// webhooks using an URL are not checked.
+func webhookFailureMessage(c client.Client, config admissionregistrationv1.WebhookClientConfig) (string, error) {
+	if config.Service == nil {
+		return "", nil
+	}
+
+	key := client.ObjectKey{
+		Namespace: config.Service.Namespace,
+		Name:      config.Service.Name,
+	}
+	endpoints := &corev1.Endpoints{}
+	if err := c.Get(ctx, key, endpoints); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("Service %s does not have endpoints", key), nil
+		}
+		return "", errors.Wrapf(err, "failed to get the endpoints of Service %s", key)
+	}
+
+	for _, s := range endpoints.Subsets {
+		if len(s.Addresses) > 0 {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("Service %s does not have ready endpoints", key), nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_providerComponents_CheckHealth(t *testing.T) {
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      "infrastructure-infra",
+	}
+
+	provider := clusterctlv1.Provider{
+		ObjectMeta:   metav1.ObjectMeta{Namespace: "ns1", Name: "infrastructure-infra"},
+		ProviderName: "infra",
+		Type:         string(clusterctlv1.InfrastructureProviderType),
+		Version:      "v1.0.0",
+	}
+
+	deployment := func(name string, available corev1.ConditionStatus) client.Object {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name, Labels: labels},
+			Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: available}},
+			},
+		}
+	}
+	crd := func(name string, established apiextensionsv1.ConditionStatus) client.Object {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: established}},
+			},
+		}
+	}
+	webhook := func(serviceName string) client.Object {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "vwh1", Labels: labels},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name: "validation.infra.io",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{Namespace: "ns1", Name: serviceName},
+				},
+			}},
+		}
+	}
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "webhook-service"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		objs        []client.Object
+		wantHealthy bool
+		wantFailing []string
+	}{
+		{
+			name: "healthy provider",
+			objs: []client.Object{
+				deployment("controller-manager", corev1.ConditionTrue),
+				crd("infraclusters.infrastructure.cluster.x-k8s.io", apiextensionsv1.ConditionTrue),
+				webhook("webhook-service"),
+				endpoints,
+			},
+			wantHealthy: true,
+			wantFailing: []string{},
+		},
+		{
+			name: "unhealthy provider",
+			objs: []client.Object{
+				deployment("controller-manager", corev1.ConditionFalse),
+				crd("infraclusters.infrastructure.cluster.x-k8s.io", apiextensionsv1.ConditionFalse),
+				webhook("missing-service"),
+				endpoints,
+			},
+			wantHealthy: false,
+			wantFailing: []string{
+				"CustomResourceDefinition/infraclusters.infrastructure.cluster.x-k8s.io",
+				"Deployment/ns1/controller-manager",
+				"Webhook/vwh1/validation.infra.io",
+			},
+		},
+		{
+			name: "objects not belonging to the provider are ignored",
+			objs: []client.Object{
+				deployment("controller-manager", corev1.ConditionTrue),
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "other"}},
+			},
+			wantHealthy: true,
+			wantFailing: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := newComponentsClient(test.NewFakeProxy().WithObjs(tt.objs...))
+			got, err := p.CheckHealth(provider)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(got.Name).To(Equal("ns1/infrastructure-infra"))
+			g.Expect(got.Healthy).To(Equal(tt.wantHealthy))
+			g.Expect(got.Checks).NotTo(BeEmpty())
+
+			failing := []string{}
+			for _, c := range got.Checks {
+				if !c.Healthy {
+					g.Expect(c.Message).NotTo(BeEmpty())
+					failing = append(failing, c.Kind+"/"+c.Name)
+				}
+			}
+			g.Expect(failing).To(Equal(tt.wantFailing))
+		})
+	}
+}
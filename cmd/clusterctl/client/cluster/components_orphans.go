@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+// OrphanedComponent is an object labeled as a component of a provider which is not installed in the management cluster,
+// e.g. a CRD left behind by a failed delete.
+type OrphanedComponent struct {
+	corev1.ObjectReference
+
+	// Provider is the value of the cluster.x-k8s.io/provider label of the object, e.g. infrastructure-aws.
+	Provider string
+}
+
+func (p *providerComponents) ListOrphans(providers []clusterctlv1.Provider) ([]OrphanedComponent, error) {
+	c, err := p.proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceList := &corev1.NamespaceList{}
+	listNamespacesBackoff := newReadBackoff()
+	if err := retryWithExponentialBackoff(listNamespacesBackoff, func() error {
+		return c.List(ctx, namespaceList)
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list namespaces")
+	}
+	namespaces := make([]string, 0, len(namespaceList.Items))
+	for _, ns := range namespaceList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+
+	resources, err := p.proxy.ListResources(map[string]string{clusterctlv1.ClusterctlLabelName: ""}, namespaces...)
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. All the namespaced components of a provider are installed in the provider namespace, so namespaced objects are
+	// orphaned also if the provider is installed only in other namespaces; instead cluster-scoped objects are orphaned only
+	// if the provider is not installed at all.
+	installed := sets.NewString()
+	installedInNamespace := sets.NewString()
+	for _, provider := range providers {
+		installed.Insert(provider.ManifestLabel())
+		installedInNamespace.Insert(provider.Namespace + "/" + provider.ManifestLabel())
+	}
+
+	orphans := []OrphanedComponent{}
+	for i := range resources {
+		obj := resources[i]
+		labels := obj.GetLabels()
+		if _, ok := labels[clusterctlv1.ClusterctlLabelName]; !ok {
+			continue
+		}
+		// Cert-manager and the inventory are managed by clusterctl itself, not by a provider.
+		if _, ok := labels[clusterctlv1.ClusterctlCoreLabelName]; ok {
+			continue
+		}
+		// Skip objects having an owner, because they are deleted by the garbage collector together with their owner.
+		if len(obj.GetOwnerReferences()) > 0 {
+			continue
+		}
+
+		provider := labels[clusterv1.ProviderLabelName]
+		if obj.GetNamespace() == "" {
+			if installed.Has(provider) {
+				continue
+			}
+		} else if installedInNamespace.Has(obj.GetNamespace() + "/" + provider) {
+			continue
+		}
+		orphans = append(orphans, OrphanedComponent{ObjectReference: objectReference(&obj), Provider: provider})
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Provider != orphans[j].Provider {
+			return orphans[i].Provider < orphans[j].Provider
+		}
+		return objectReferenceSortKey(orphans[i].ObjectReference) < objectReferenceSortKey(orphans[j].ObjectReference)
+	})
+	return orphans, nil
+}
+
+func (p *providerComponents) DeleteOrphans(orphans []OrphanedComponent) error {
+	log := logf.Log
+
+	c, err := p.proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	errList := []error{}
+	deleteOrphanBackoff := newWriteBackoff()
+	for _, orphan := range orphans {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(orphan.APIVersion)
+		obj.SetKind(orphan.Kind)
+		obj.SetNamespace(orphan.Namespace)
+		obj.SetName(orphan.Name)
+
+		log.V(5).Info("Deleting orphaned", logf.UnstructuredToValues(*obj)...)
+		if err := retryWithExponentialBackoff(deleteOrphanBackoff, func() error {
+			if err := c.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			return nil
+		}); err != nil {
+			errList = append(errList, errors.Wrapf(err, "failed to delete the orphaned object %s", objectReferenceSortKey(orphan.ObjectReference)))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_providerComponents_ListOrphans(t *testing.T) {
+	g := NewWithT(t)
+
+	providerLabels := func(provider string) map[string]string {
+		return map[string]string{
+			clusterctlv1.ClusterctlLabelName: "",
+			clusterv1.ProviderLabelName:      provider,
+		}
+	}
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		}
+	}
+
+	initObjs := []client.Object{
+		namespace("capi-system", providerLabels("cluster-api")),
+		namespace("capa-system", providerLabels("infrastructure-aws")),
+		namespace("other", nil),
+		namespace("cert-manager", map[string]string{
+			clusterctlv1.ClusterctlLabelName:     "",
+			clusterctlv1.ClusterctlCoreLabelName: clusterctlv1.ClusterctlCoreLabelCertManagerValue,
+		}),
+		// The components of an installed provider (should not be reported)
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "capi-system", Name: "capi-controller-manager", Labels: providerLabels("cluster-api")},
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: "capi-system-capi-manager-role", Labels: providerLabels("cluster-api")},
+		},
+		// The components of a provider which is not installed (should be reported)
+		&apiextensionsv1.CustomResourceDefinition{
+			TypeMeta:   metav1.TypeMeta{Kind: "CustomResourceDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "awsclusters.infrastructure.cluster.x-k8s.io", Labels: providerLabels("infrastructure-aws")},
+		},
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "capa-system", Name: "capa-manager", Labels: providerLabels("infrastructure-aws")},
+		},
+		// A component of an installed provider in a namespace without an instance of the provider (should be reported)
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "capi-config", Labels: providerLabels("cluster-api")},
+		},
+		// A component with an owner (should not be reported)
+		&corev1.Pod{
+			TypeMeta: metav1.TypeMeta{Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "capa-system",
+				Name:            "capa-manager-pod",
+				Labels:          providerLabels("infrastructure-aws"),
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1"}},
+			},
+		},
+		// An object managed by clusterctl itself (should not be reported)
+		&appsv1.Deployment{
+			TypeMeta: metav1.TypeMeta{Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "cert-manager", Labels: map[string]string{
+				clusterctlv1.ClusterctlLabelName:     "",
+				clusterctlv1.ClusterctlCoreLabelName: clusterctlv1.ClusterctlCoreLabelCertManagerValue,
+			}},
+		},
+		// An object not managed by clusterctl (should not be reported)
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "capa-system", Name: "user-config"},
+		},
+	}
+	providers := []clusterctlv1.Provider{
+		fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "capi-system"),
+	}
+
+	proxy := test.NewFakeProxy().WithObjs(initObjs...)
+	c := newComponentsClient(proxy)
+
+	got, err := c.ListOrphans(providers)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]OrphanedComponent{
+		{ObjectReference: corev1.ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "other", Name: "capi-config"}, Provider: "cluster-api"},
+		{ObjectReference: corev1.ObjectReference{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "awsclusters.infrastructure.cluster.x-k8s.io"}, Provider: "infrastructure-aws"},
+		{ObjectReference: corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "capa-system"}, Provider: "infrastructure-aws"},
+		{ObjectReference: corev1.ObjectReference{APIVersion: "v1", Kind: "ServiceAccount", Namespace: "capa-system", Name: "capa-manager"}, Provider: "infrastructure-aws"},
+	}))
+
+	// Deleting the orphans removes them from the cluster; orphans already deleted are ignored.
+	g.Expect(c.DeleteOrphans(got)).To(Succeed())
+	g.Expect(c.DeleteOrphans(got)).To(Succeed())
+
+	cs, err := proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	err = cs.Get(ctx, client.ObjectKey{Name: "awsclusters.infrastructure.cluster.x-k8s.io"}, &apiextensionsv1.CustomResourceDefinition{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	err = cs.Get(ctx, client.ObjectKey{Namespace: "capi-system", Name: "capi-controller-manager"}, &appsv1.Deployment{})
+	g.Expect(err).NotTo(HaveOccurred())
+}
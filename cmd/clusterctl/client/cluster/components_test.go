@@ -295,3 +295,82 @@ func Test_providerComponents_DeleteCoreProviderWebhookNamespace(t *testing.T) {
 		g.Expect(len(nsList.Items)).Should(Equal(0))
 	})
 }
+
+func Test_providerComponents_ListLeftovers(t *testing.T) {
+	g := NewWithT(t)
+
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      "infrastructure-infra",
+	}
+
+	initObjs := []client.Object{
+		&corev1.Namespace{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Namespace",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "ns1",
+				Labels: labels,
+			},
+		},
+		// A provider component (should not be reported)
+		&corev1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1",
+				Name:      "pod1",
+				Labels:    labels,
+			},
+		},
+		// An object in the namespace not belonging to the provider (should be reported)
+		&corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ConfigMap",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1",
+				Name:      "cm1",
+			},
+		},
+		// An object with an owner (should not be reported)
+		&corev1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "ns1",
+				Name:            "pod2",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1"}},
+			},
+		},
+		// An object in another namespace (should not be reported)
+		&corev1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns2",
+				Name:      "pod3",
+			},
+		},
+		// A cluster-wide object (should not be reported)
+		&rbacv1.ClusterRole{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "ClusterRole",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-cluster-role",
+			},
+		},
+	}
+
+	proxy := test.NewFakeProxy().WithObjs(initObjs...)
+	c := newComponentsClient(proxy)
+
+	got, err := c.ListLeftovers("ns1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]corev1.ObjectReference{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns1", Name: "cm1"}}))
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sync"
+)
+
+// EventType identifies a step of an operation installing or upgrading providers.
+type EventType string
+
+const (
+	// FetchingComponentsEvent is streamed before reading the components of a provider from its repository.
+	FetchingComponentsEvent EventType = "FetchingComponents"
+
+	// InstallingCertManagerEvent is streamed before installing or upgrading cert-manager, if required.
+	InstallingCertManagerEvent EventType = "InstallingCertManager"
+
+	// DeletingComponentsEvent is streamed before deleting the components of the current version of a provider being upgraded.
+	DeletingComponentsEvent EventType = "DeletingComponents"
+
+	// ApplyingComponentsEvent is streamed before creating the components of a provider, including its CRDs.
+	ApplyingComponentsEvent EventType = "ApplyingComponents"
+
+	// CreatingInventoryEvent is streamed before creating the inventory entry for a provider.
+	CreatingInventoryEvent EventType = "CreatingInventory"
+
+	// ProviderInstalledEvent is streamed when the components and the inventory entry of a provider are created.
+	ProviderInstalledEvent EventType = "ProviderInstalled"
+
+	// WaitingForProvidersEvent is streamed before waiting for the provider controllers to be ready.
+	WaitingForProvidersEvent EventType = "WaitingForProviders"
+
+	// ProviderReadyEvent is streamed when the controllers of a provider are ready.
+	ProviderReadyEvent EventType = "ProviderReady"
+)
+
+// Event describes a step of an operation installing or upgrading providers.
+type Event struct {
+	// Type identifies the step.
+	Type EventType
+
+	// Provider is the provider the step applies to, e.g. infrastructure-aws; it is empty for the steps not related
+	// to a single provider.
+	Provider string
+
+	// Message is a human readable description of the step, e.g. "Fetching the components for version v0.6.0".
+	Message string
+}
+
+// EventFunc is invoked for streaming the events of an operation installing or upgrading providers.
+type EventFunc func(event Event)
+
+// Notify streams an event; it is a no-op for a nil EventFunc.
+func (f EventFunc) Notify(eventType EventType, provider, format string, args ...interface{}) {
+	if f == nil {
+		return
+	}
+	f(Event{Type: eventType, Provider: provider, Message: fmt.Sprintf(format, args...)})
+}
+
+// NewEventStream returns an EventFunc streaming the events to f, together with a function closing the stream.
+// The events are streamed one at a time, also if they are notified from many goroutines, and the events notified
+// after closing the stream are dropped, so f is never invoked concurrently nor after the operation returns.
+func NewEventStream(f EventFunc) (EventFunc, func()) {
+	if f == nil {
+		return nil, func() {}
+	}
+
+	var lock sync.Mutex
+	closed := false
+	stream := func(event Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !closed {
+			f(event)
+		}
+	}
+	closeStream := func() {
+		lock.Lock()
+		defer lock.Unlock()
+		closed = true
+	}
+	return stream, closeStream
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_NewEventStream(t *testing.T) {
+	g := NewWithT(t)
+
+	stream, closeStream := NewEventStream(nil)
+	g.Expect(stream).To(BeNil())
+	// Notifying a nil EventFunc and closing its stream are no-ops.
+	stream.Notify(FetchingComponentsEvent, "infrastructure-infra", "Fetching")
+	closeStream()
+
+	var events []Event
+	stream, closeStream = NewEventStream(func(event Event) {
+		// NB. appending without a lock fails with the race detector if the events are not serialized.
+		events = append(events, event)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.Notify(ApplyingComponentsEvent, "infrastructure-infra", "Applying %d objects", 3)
+		}()
+	}
+	wg.Wait()
+	g.Expect(events).To(HaveLen(10))
+	g.Expect(events[0]).To(Equal(Event{Type: ApplyingComponentsEvent, Provider: "infrastructure-infra", Message: "Applying 3 objects"}))
+
+	// The events notified after closing the stream are dropped.
+	closeStream()
+	stream.Notify(ProviderInstalledEvent, "infrastructure-infra", "Installed")
+	g.Expect(events).To(HaveLen(10))
+}
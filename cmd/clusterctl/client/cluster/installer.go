@@ -17,7 +17,11 @@ limitations under the License.
 package cluster
 
 import (
+	"sort"
+	"sync"
+
 	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/version"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
@@ -35,7 +39,10 @@ type ProviderInstaller interface {
 	Add(repository.Components)
 
 	// Install performs the installation of the providers ready in the install queue.
-	Install() ([]repository.Components, error)
+	// Providers are installed in order of type, i.e. core, bootstrap, control-plane and then infrastructure providers;
+	// providers of the same type can be installed concurrently using WithInstallConcurrency. Errors installing
+	// providers of the same type are aggregated, and providers of the following types are not installed.
+	Install(options ...InstallOption) ([]repository.Components, error)
 
 	// Validate performs steps to validate a management cluster by looking at the current state and the providers in the queue.
 	// The following checks are performed in order to ensure a fully operational cluster:
@@ -43,8 +50,27 @@ type ProviderInstaller interface {
 	// - All the providers in must support the same API Version of Cluster API (contract)
 	Validate() error
 
+	// SkipInstalled removes from the install queue the providers already installed in the management cluster at the same
+	// version and in the same namespace, and returns them, so the installation can be repeated safely; an error is returned
+	// if a provider in the queue is already installed at a different version, because this requires an upgrade.
+	SkipInstalled() ([]clusterctlv1.Provider, error)
+
 	// Images returns the list of images required for installing the providers ready in the install queue.
 	Images() []string
+
+	// InstallQueue returns the list of provider components ready in the install queue.
+	InstallQueue() []repository.Components
+}
+
+// InstallOption is a configuration option supplied to Install.
+type InstallOption func(*providerInstaller)
+
+// WithInstallConcurrency sets the maximum number of providers of the same type installed concurrently;
+// values lower than 1 are ignored, and providers are installed one at a time.
+func WithInstallConcurrency(concurrency int) InstallOption {
+	return func(i *providerInstaller) {
+		i.concurrency = concurrency
+	}
 }
 
 // providerInstaller implements ProviderInstaller.
@@ -55,6 +81,10 @@ type providerInstaller struct {
 	providerComponents      ComponentsClient
 	providerInventory       InventoryClient
 	installQueue            []repository.Components
+	concurrency             int
+	preInstallHooks         []InstallHook
+	postInstallHooks        []InstallHook
+	eventFunc               EventFunc
 }
 
 var _ ProviderInstaller = &providerInstaller{}
@@ -63,31 +93,106 @@ func (i *providerInstaller) Add(components repository.Components) {
 	i.installQueue = append(i.installQueue, components)
 }
 
-func (i *providerInstaller) Install() ([]repository.Components, error) {
-	ret := make([]repository.Components, 0, len(i.installQueue))
+func (i *providerInstaller) Install(options ...InstallOption) ([]repository.Components, error) {
+	for _, o := range options {
+		o(i)
+	}
+
+	// Groups the providers in the install queue by type, so all the providers of a type are installed before
+	// starting with the providers of the next type, e.g. the core provider before the bootstrap providers.
+	groups := map[int][]repository.Components{}
+	orders := []int{}
 	for _, components := range i.installQueue {
-		if err := installComponentsAndUpdateInventory(components, i.providerComponents, i.providerInventory); err != nil {
-			return nil, err
+		order := components.Type().Order()
+		if _, ok := groups[order]; !ok {
+			orders = append(orders, order)
 		}
+		groups[order] = append(groups[order], components)
+	}
+	sort.Ints(orders)
 
-		ret = append(ret, components)
+	ret := make([]repository.Components, 0, len(i.installQueue))
+	for _, order := range orders {
+		if err := i.installGroup(groups[order]); err != nil {
+			return nil, err
+		}
+		ret = append(ret, groups[order]...)
 	}
 	return ret, nil
 }
 
-func installComponentsAndUpdateInventory(components repository.Components, providerComponents ComponentsClient, providerInventory InventoryClient) error {
+// installGroup installs a group of providers using at most i.concurrency workers, returning the aggregate of the
+// errors installing each provider.
+func (i *providerInstaller) installGroup(group []repository.Components) error {
+	workers := i.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	hooks := &installHooks{
+		proxy:       i.proxy,
+		preInstall:  i.preInstallHooks,
+		postInstall: i.postInstallHooks,
+	}
+
+	// NB. Each provider creates its own components and its own inventory entry, so providers in the same group
+	// don't share any object and they can be safely installed concurrently.
+	errList := make([]error, len(group))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for idx := range group {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := installComponentsAndUpdateInventory(group[idx], i.providerComponents, i.providerInventory, hooks, i.eventFunc)
+			errList[idx] = errors.Wrapf(err, "failed to install provider %q", group[idx].ManifestLabel())
+		}(idx)
+	}
+	wg.Wait()
+
+	return kerrors.NewAggregate(errList)
+}
+
+// installComponentsAndUpdateInventory creates the provider components and the inventory entry for a provider, running
+// the install hooks, if any, before creating the components and before creating the inventory entry; the progress
+// of the installation is streamed to eventFunc, if set.
+func installComponentsAndUpdateInventory(components repository.Components, providerComponents ComponentsClient, providerInventory InventoryClient, hooks *installHooks, eventFunc EventFunc) error {
 	log := logf.Log
 	log.Info("Installing", "Provider", components.ManifestLabel(), "Version", components.Version(), "TargetNamespace", components.TargetNamespace())
 
 	inventoryObject := components.InventoryObject()
 
+	if err := hooks.runPreInstall(components); err != nil {
+		return err
+	}
+
 	log.V(1).Info("Creating objects", "Provider", components.ManifestLabel(), "Version", components.Version(), "TargetNamespace", components.TargetNamespace())
+	crds := 0
+	for _, obj := range components.Objs() {
+		if obj.GetKind() == customResourceDefinitionKind {
+			crds++
+		}
+	}
+	eventFunc.Notify(ApplyingComponentsEvent, components.ManifestLabel(), "Applying %d objects, including %d CustomResourceDefinitions, for version %s in namespace %s",
+		len(components.Objs()), crds, components.Version(), components.TargetNamespace())
 	if err := providerComponents.Create(components.Objs()); err != nil {
 		return err
 	}
 
+	if err := hooks.runPostInstall(components); err != nil {
+		return err
+	}
+
 	log.V(1).Info("Creating inventory entry", "Provider", components.ManifestLabel(), "Version", components.Version(), "TargetNamespace", components.TargetNamespace())
-	return providerInventory.Create(inventoryObject)
+	eventFunc.Notify(CreatingInventoryEvent, components.ManifestLabel(), "Creating the inventory entry for version %s", components.Version())
+	if err := providerInventory.Create(inventoryObject); err != nil {
+		return err
+	}
+
+	eventFunc.Notify(ProviderInstalledEvent, components.ManifestLabel(), "Installed version %s in namespace %s", components.Version(), components.TargetNamespace())
+	return nil
 }
 
 func (i *providerInstaller) Validate() error {
@@ -147,9 +252,22 @@ func (i *providerInstaller) getProviderContract(providerInstanceContracts map[st
 	// Otherwise get the contract for the providers instance.
 
 	// Gets the providers metadata.
-	configRepository, err := i.configClient.Providers().Get(provider.ProviderName, provider.GetProviderType())
-	if err != nil {
-		return "", err
+	// NB. For the providers in the install queue, metadata are read from the same repository used for reading components,
+	// which could be different from the repository defined in the clusterctl configuration (e.g. a local path).
+	var configRepository config.Provider
+	for _, components := range i.installQueue {
+		queuedProvider := components.InventoryObject()
+		if queuedProvider.InstanceName() == provider.InstanceName() {
+			configRepository = components
+			break
+		}
+	}
+	if configRepository == nil {
+		var err error
+		configRepository, err = i.configClient.Providers().Get(provider.ProviderName, provider.GetProviderType())
+		if err != nil {
+			return "", err
+		}
 	}
 
 	providerRepository, err := i.repositoryClientFactory(configRepository, i.configClient)
@@ -194,6 +312,42 @@ func simulateInstall(providerList *clusterctlv1.ProviderList, components reposit
 	return providerList, nil
 }
 
+func (i *providerInstaller) SkipInstalled() ([]clusterctlv1.Provider, error) {
+	providerList, err := i.providerInventory.List()
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := []clusterctlv1.Provider{}
+	queue := make([]repository.Components, 0, len(i.installQueue))
+	errList := []error{}
+	for _, components := range i.installQueue {
+		provider := components.InventoryObject()
+
+		// NB. Instances installed in a different namespace are left in the queue, so they are reported by Validate.
+		installed := false
+		for _, existing := range providerList.FilterByProviderNameAndType(provider.ProviderName, provider.GetProviderType()) {
+			if existing.Namespace != provider.Namespace {
+				continue
+			}
+			if existing.Version != provider.Version {
+				errList = append(errList, errors.Errorf("the %q provider is already installed in the %q namespace with version %s, while version %s is requested; please use clusterctl upgrade for changing the provider version", provider.ManifestLabel(), existing.Namespace, existing.Version, provider.Version))
+			}
+			installed = true
+			skipped = append(skipped, existing)
+		}
+		if !installed {
+			queue = append(queue, components)
+		}
+	}
+	if len(errList) > 0 {
+		return nil, kerrors.NewAggregate(errList)
+	}
+
+	i.installQueue = queue
+	return skipped, nil
+}
+
 func (i *providerInstaller) Images() []string {
 	ret := sets.NewString()
 	for _, components := range i.installQueue {
@@ -202,6 +356,10 @@ func (i *providerInstaller) Images() []string {
 	return ret.List()
 }
 
+func (i *providerInstaller) InstallQueue() []repository.Components {
+	return i.installQueue
+}
+
 func newProviderInstaller(configClient config.Client, repositoryClientFactory RepositoryClientFactory, proxy Proxy, providerMetadata InventoryClient, providerComponents ComponentsClient) *providerInstaller {
 	return &providerInstaller{
 		configClient:            configClient,
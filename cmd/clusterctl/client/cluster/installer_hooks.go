@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InstallHook defines an action executed when installing a provider, e.g. for creating a Namespace or a ConfigMap required
+// by the provider. Hooks are executed for all the providers, so they should check the provider of the components they
+// receive; hooks must be idempotent, because the installation can be repeated after a failure.
+type InstallHook interface {
+	Run(proxy Proxy, components repository.Components) error
+}
+
+// InstallHookFunc is an adapter for using a function as an InstallHook.
+type InstallHookFunc func(proxy Proxy, components repository.Components) error
+
+// Run calls f(proxy, components).
+func (f InstallHookFunc) Run(proxy Proxy, components repository.Components) error {
+	return f(proxy, components)
+}
+
+// WithPreInstallHooks adds hooks executed, in order, before creating the components of each provider; they are executed
+// after creating the objects declared by the pre-install hooks in the provider's metadata.
+// If a hook fails, the provider is not installed.
+func WithPreInstallHooks(hooks ...InstallHook) InstallOption {
+	return func(i *providerInstaller) {
+		i.preInstallHooks = append(i.preInstallHooks, hooks...)
+	}
+}
+
+// WithPostInstallHooks adds hooks executed, in order, after creating the components of each provider and before adding
+// the provider to the inventory; they are executed after creating the objects declared by the post-install hooks in the
+// provider's metadata. If a hook fails, the provider is not added to the inventory, so the installation can be repeated.
+func WithPostInstallHooks(hooks ...InstallHook) InstallOption {
+	return func(i *providerInstaller) {
+		i.postInstallHooks = append(i.postInstallHooks, hooks...)
+	}
+}
+
+// installHooks groups the hooks executed when installing a provider.
+type installHooks struct {
+	proxy       Proxy
+	preInstall  []InstallHook
+	postInstall []InstallHook
+}
+
+func (h *installHooks) runPreInstall(components repository.Components) error {
+	if h == nil {
+		return nil
+	}
+	return errors.Wrap(h.run(components, components.PreInstallObjs(), h.preInstall), "pre-install hook failed")
+}
+
+func (h *installHooks) runPostInstall(components repository.Components) error {
+	if h == nil {
+		return nil
+	}
+	return errors.Wrap(h.run(components, components.PostInstallObjs(), h.postInstall), "post-install hook failed")
+}
+
+func (h *installHooks) run(components repository.Components, objs []unstructured.Unstructured, hooks []InstallHook) error {
+	if len(objs) > 0 {
+		if err := createHookObjs(h.proxy, objs); err != nil {
+			return errors.Wrap(err, "failed to create the objects declared in the provider metadata")
+		}
+	}
+	for idx, hook := range hooks {
+		if err := hook.Run(h.proxy, components); err != nil {
+			return errors.Wrapf(err, "hook %d returned an error", idx)
+		}
+	}
+	return nil
+}
+
+// createHookObjs creates the objects declared by the install hooks in the provider's metadata; objects already existing
+// are not changed, so that the objects can be customized after the installation.
+func createHookObjs(proxy Proxy, objs []unstructured.Unstructured) error {
+	log := logf.Log
+	c, err := proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	createHookObjectBackoff := newWriteBackoff()
+	for i := range objs {
+		obj := objs[i].DeepCopy()
+		log.V(5).Info("Creating", obj.GetKind(), obj.GetName(), "Namespace", obj.GetNamespace())
+		if err := retryWithExponentialBackoff(createHookObjectBackoff, func() error {
+			if err := c.Create(ctx, obj); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					log.V(5).Info("Object already exists, skipping", obj.GetKind(), obj.GetName(), "Namespace", obj.GetNamespace())
+					return nil
+				}
+				return errors.Wrapf(err, "failed to create %q %s", obj.GroupVersionKind(), client.ObjectKeyFromObject(obj))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_providerInstaller_Install_withHooks(t *testing.T) {
+	g := NewWithT(t)
+
+	settings := unstructured.Unstructured{}
+	settings.SetAPIVersion("v1")
+	settings.SetKind("ConfigMap")
+	settings.SetNamespace("cluster-api-system")
+	settings.SetName("settings")
+
+	core := newFakeComponents("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system")
+	core.(*fakeComponents).preInstallObjs = []unstructured.Unstructured{settings}
+	infra := newFakeComponents("infra1", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra1-system")
+
+	var lock sync.Mutex
+	calls := []string{}
+	record := func(phase string) InstallHook {
+		return InstallHookFunc(func(proxy Proxy, components repository.Components) error {
+			lock.Lock()
+			defer lock.Unlock()
+			calls = append(calls, phase+"/"+components.ManifestLabel())
+			return nil
+		})
+	}
+	failInfra := InstallHookFunc(func(proxy Proxy, components repository.Components) error {
+		if components.ManifestLabel() == "infrastructure-infra1" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	proxy := test.NewFakeProxy()
+	inventory := &fakeRecordingInventoryClient{
+		InventoryClient: newInventoryClient(proxy, fakePollImmediateWaiter),
+	}
+	i := newProviderInstaller(nil, nil, proxy, inventory, newComponentsClient(proxy))
+	i.Add(core)
+	i.Add(infra)
+
+	_, err := i.Install(
+		WithPreInstallHooks(record("pre")),
+		WithPostInstallHooks(record("post"), failInfra),
+	)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring(`failed to install provider "infrastructure-infra1": post-install hook failed: hook 1 returned an error: failed`))
+
+	// check the hooks are executed for all the providers, and the provider with a failing hook is not added to the inventory.
+	g.Expect(calls).To(Equal([]string{"pre/cluster-api", "post/cluster-api", "pre/infrastructure-infra1", "post/infrastructure-infra1"}))
+	g.Expect(inventory.created).To(ConsistOf("cluster-api"))
+
+	// check the objects declared in the provider metadata are created.
+	c, err := proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "cluster-api-system", Name: "settings"}, &corev1.ConfigMap{})).To(Succeed())
+
+	// check creating the objects declared in the provider metadata is idempotent.
+	g.Expect(createHookObjs(proxy, core.PreInstallObjs())).To(Succeed())
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package cluster
 
 import (
+	"sync"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
@@ -257,25 +261,222 @@ func Test_providerInstaller_Validate(t *testing.T) {
 	}
 }
 
+func Test_providerInstaller_SkipInstalled(t *testing.T) {
+	queue := []repository.Components{
+		newFakeComponents("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "ns1"),
+		newFakeComponents("infra1", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra1-system"),
+	}
+
+	tests := []struct {
+		name        string
+		proxy       Proxy
+		wantSkipped []string
+		wantQueue   []string
+		wantErr     bool
+	}{
+		{
+			name:        "nothing is skipped on an empty cluster",
+			proxy:       test.NewFakeProxy(),
+			wantSkipped: []string{},
+			wantQueue:   []string{"cluster-api", "infrastructure-infra1"},
+		},
+		{
+			name: "providers installed at the same version are skipped",
+			proxy: test.NewFakeProxy().
+				WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "ns1"),
+			wantSkipped: []string{"cluster-api"},
+			wantQueue:   []string{"infrastructure-infra1"},
+		},
+		{
+			name: "providers installed in a different namespace are not skipped",
+			proxy: test.NewFakeProxy().
+				WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "capi-system"),
+			wantSkipped: []string{},
+			wantQueue:   []string{"cluster-api", "infrastructure-infra1"},
+		},
+		{
+			name: "fails for providers installed at a different version",
+			proxy: test.NewFakeProxy().
+				WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "ns1").
+				WithProviderInventory("infra1", clusterctlv1.InfrastructureProviderType, "v0.9.0", "infra1-system"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			i := &providerInstaller{
+				proxy:             tt.proxy,
+				providerInventory: newInventoryClient(tt.proxy, nil),
+				installQueue:      append([]repository.Components{}, queue...),
+			}
+
+			skipped, err := i.SkipInstalled()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotSkipped := []string{}
+			for _, p := range skipped {
+				gotSkipped = append(gotSkipped, p.ManifestLabel())
+			}
+			g.Expect(gotSkipped).To(Equal(tt.wantSkipped))
+
+			gotQueue := []string{}
+			for _, c := range i.InstallQueue() {
+				gotQueue = append(gotQueue, c.ManifestLabel())
+			}
+			g.Expect(gotQueue).To(Equal(tt.wantQueue))
+		})
+	}
+}
+
+func Test_providerInstaller_Install(t *testing.T) {
+	queue := []repository.Components{
+		newFakeComponents("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"),
+		newFakeComponents("kubeadm", clusterctlv1.BootstrapProviderType, "v1.0.0", "kubeadm-system"),
+		newFakeComponents("infra1", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra1-system"),
+		newFakeComponents("infra2", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra2-system"),
+		newFakeComponents("infra3", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra3-system"),
+	}
+
+	tests := []struct {
+		name        string
+		concurrency int
+		failing     []string
+		wantCreated []string
+		wantErrs    int
+	}{
+		{
+			name:        "install providers one at a time",
+			concurrency: 0,
+			wantCreated: []string{"cluster-api", "bootstrap-kubeadm", "infrastructure-infra1", "infrastructure-infra2", "infrastructure-infra3"},
+		},
+		{
+			name:        "install providers of the same type concurrently",
+			concurrency: 3,
+			wantCreated: []string{"cluster-api", "bootstrap-kubeadm", "infrastructure-infra1", "infrastructure-infra2", "infrastructure-infra3"},
+		},
+		{
+			name:        "errors installing providers of the same type are aggregated",
+			concurrency: 2,
+			failing:     []string{"infrastructure-infra1", "infrastructure-infra3"},
+			wantCreated: []string{"cluster-api", "bootstrap-kubeadm", "infrastructure-infra2"},
+			wantErrs:    2,
+		},
+		{
+			name:        "providers of the following types are not installed after an error",
+			concurrency: 2,
+			failing:     []string{"bootstrap-kubeadm"},
+			wantCreated: []string{"cluster-api"},
+			wantErrs:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			proxy := test.NewFakeProxy()
+			inventory := &fakeRecordingInventoryClient{
+				InventoryClient: newInventoryClient(proxy, fakePollImmediateWaiter),
+				failing:         sets.NewString(tt.failing...),
+			}
+			i := newProviderInstaller(nil, nil, proxy, inventory, newComponentsClient(proxy))
+			for _, components := range queue {
+				i.Add(components)
+			}
+
+			got, err := i.Install(WithInstallConcurrency(tt.concurrency))
+			g.Expect(inventory.created).To(ConsistOf(tt.wantCreated))
+			if tt.wantErrs > 0 {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.(kerrors.Aggregate).Errors()).To(HaveLen(tt.wantErrs))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(queue))
+		})
+	}
+}
+
+func Test_providerInstaller_Install_withEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	proxy := test.NewFakeProxy()
+	inventory := &fakeRecordingInventoryClient{
+		InventoryClient: newInventoryClient(proxy, fakePollImmediateWaiter),
+		failing:         sets.NewString(),
+	}
+	i := newProviderInstaller(nil, nil, proxy, inventory, newComponentsClient(proxy))
+	i.Add(newFakeComponents("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"))
+	i.Add(newFakeComponents("infra1", clusterctlv1.InfrastructureProviderType, "v1.0.0", "infra1-system"))
+
+	var events []Event
+	i.eventFunc = func(event Event) {
+		events = append(events, event)
+	}
+	_, err := i.Install()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	gotEvents := []string{}
+	for _, e := range events {
+		gotEvents = append(gotEvents, string(e.Type)+" "+e.Provider)
+	}
+	g.Expect(gotEvents).To(Equal([]string{
+		"ApplyingComponents cluster-api",
+		"CreatingInventory cluster-api",
+		"ProviderInstalled cluster-api",
+		"ApplyingComponents infrastructure-infra1",
+		"CreatingInventory infrastructure-infra1",
+		"ProviderInstalled infrastructure-infra1",
+	}))
+}
+
+// fakeRecordingInventoryClient records the inventory entries created, failing for the given providers.
+type fakeRecordingInventoryClient struct {
+	InventoryClient
+	lock    sync.Mutex
+	failing sets.String
+	created []string
+}
+
+func (c *fakeRecordingInventoryClient) Create(provider clusterctlv1.Provider) error {
+	if c.failing.Has(provider.Name) {
+		return errors.Errorf("failed to create %s", provider.Name)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.created = append(c.created, provider.Name)
+	return nil
+}
+
 type fakeComponents struct {
 	config.Provider
 	inventoryObject clusterctlv1.Provider
+	preInstallObjs  []unstructured.Unstructured
 }
 
 func (c *fakeComponents) Version() string {
-	panic("not implemented")
+	return c.inventoryObject.Version
 }
 
 func (c *fakeComponents) Variables() []string {
 	panic("not implemented")
 }
 
+func (c *fakeComponents) VariableMap() map[string]*string {
+	panic("not implemented")
+}
+
 func (c *fakeComponents) Images() []string {
 	panic("not implemented")
 }
 
 func (c *fakeComponents) TargetNamespace() string {
-	panic("not implemented")
+	return c.inventoryObject.Namespace
 }
 
 func (c *fakeComponents) InventoryObject() clusterctlv1.Provider {
@@ -283,13 +484,25 @@ func (c *fakeComponents) InventoryObject() clusterctlv1.Provider {
 }
 
 func (c *fakeComponents) Objs() []unstructured.Unstructured {
-	panic("not implemented")
+	return nil
+}
+
+func (c *fakeComponents) PreInstallObjs() []unstructured.Unstructured {
+	return c.preInstallObjs
+}
+
+func (c *fakeComponents) PostInstallObjs() []unstructured.Unstructured {
+	return nil
 }
 
 func (c *fakeComponents) Yaml() ([]byte, error) {
 	panic("not implemented")
 }
 
+func (c *fakeComponents) SplitByKind() []repository.ComponentsSubset {
+	panic("not implemented")
+}
+
 func newFakeComponents(name string, providerType clusterctlv1.ProviderType, version, targetNamespace string) repository.Components {
 	inventoryObject := fakeProvider(name, providerType, version, targetNamespace)
 	return &fakeComponents{
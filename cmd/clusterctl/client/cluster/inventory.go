@@ -82,12 +82,21 @@ type InventoryClient interface {
 	// is embedded in the clusterctl binary.
 	EnsureCustomResourceDefinitions() error
 
+	// HasCustomResourceDefinitions returns true if the CRD required for creating inventory items is installed.
+	HasCustomResourceDefinitions() (bool, error)
+
 	// Create an inventory item for a provider instance installed in the cluster.
 	Create(clusterctlv1.Provider) error
 
+	// Delete deletes the inventory item for a provider instance; it is a no-op if the inventory item does not exist.
+	Delete(clusterctlv1.Provider) error
+
 	// List returns the inventory items for all the provider instances installed in the cluster.
 	List() (*clusterctlv1.ProviderList, error)
 
+	// ListInNamespace returns the inventory items for the provider instances installed in a namespace of the cluster.
+	ListInNamespace(namespace string) (*clusterctlv1.ProviderList, error)
+
 	// GetDefaultProviderName returns the default provider for a given ProviderType.
 	// In case there is only a single provider for a given type, e.g. only the AWS infrastructure Provider, it returns
 	// this as the default provider; In case there are more provider of the same type, there is no default provider.
@@ -207,6 +216,10 @@ func (p *inventoryClient) EnsureCustomResourceDefinitions() error {
 	return nil
 }
 
+func (p *inventoryClient) HasCustomResourceDefinitions() (bool, error) {
+	return checkInventoryCRDs(p.proxy)
+}
+
 // checkInventoryCRDs checks if the inventory CRDs are installed in the cluster.
 func checkInventoryCRDs(proxy Proxy) (bool, error) {
 	c, err := proxy.NewClient()
@@ -289,6 +302,27 @@ func (p *inventoryClient) Create(m clusterctlv1.Provider) error {
 	})
 }
 
+func (p *inventoryClient) Delete(m clusterctlv1.Provider) error {
+	deleteInventoryObjectBackoff := newWriteBackoff()
+	return retryWithExponentialBackoff(deleteInventoryObjectBackoff, func() error {
+		cl, err := p.proxy.NewClient()
+		if err != nil {
+			return err
+		}
+
+		provider := &clusterctlv1.Provider{}
+		provider.SetNamespace(m.Namespace)
+		provider.SetName(m.Name)
+		if err := cl.Delete(ctx, provider); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to delete provider object")
+		}
+		return nil
+	})
+}
+
 func (p *inventoryClient) List() (*clusterctlv1.ProviderList, error) {
 	providerList := &clusterctlv1.ProviderList{}
 
@@ -302,14 +336,27 @@ func (p *inventoryClient) List() (*clusterctlv1.ProviderList, error) {
 	return providerList, nil
 }
 
+func (p *inventoryClient) ListInNamespace(namespace string) (*clusterctlv1.ProviderList, error) {
+	providerList := &clusterctlv1.ProviderList{}
+
+	listProvidersBackoff := newReadBackoff()
+	if err := retryWithExponentialBackoff(listProvidersBackoff, func() error {
+		return listProviders(p.proxy, providerList, client.InNamespace(namespace))
+	}); err != nil {
+		return nil, err
+	}
+
+	return providerList, nil
+}
+
 // listProviders retrieves the list of provider inventory objects.
-func listProviders(proxy Proxy, providerList *clusterctlv1.ProviderList) error {
+func listProviders(proxy Proxy, providerList *clusterctlv1.ProviderList, options ...client.ListOption) error {
 	cl, err := proxy.NewClient()
 	if err != nil {
 		return err
 	}
 
-	if err := cl.List(ctx, providerList); err != nil {
+	if err := cl.List(ctx, providerList, options...); err != nil {
 		return errors.Wrap(err, "failed get providers")
 	}
 	return nil
@@ -202,6 +202,46 @@ func Test_inventoryClient_Create(t *testing.T) {
 	}
 }
 
+func Test_inventoryClient_Delete(t *testing.T) {
+	infra := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v0.2.0", "ns1")
+	bootstrap := fakeProvider("bootstrap", clusterctlv1.BootstrapProviderType, "v0.2.0", "ns1")
+
+	tests := []struct {
+		name          string
+		proxy         Proxy
+		wantProviders []string
+	}{
+		{
+			name:          "Deletes a provider",
+			proxy:         test.NewFakeProxy().WithObjs(&infra, &bootstrap),
+			wantProviders: []string{bootstrap.Name},
+		},
+		{
+			name:          "Does not fail if the provider does not exist",
+			proxy:         test.NewFakeProxy().WithObjs(&bootstrap),
+			wantProviders: []string{bootstrap.Name},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := &inventoryClient{
+				proxy: tt.proxy,
+			}
+			g.Expect(p.Delete(infra)).To(Succeed())
+
+			got, err := p.List()
+			g.Expect(err).NotTo(HaveOccurred())
+			gotProviders := []string{}
+			for _, provider := range got.Items {
+				gotProviders = append(gotProviders, provider.Name)
+			}
+			g.Expect(gotProviders).To(Equal(tt.wantProviders))
+		})
+	}
+}
+
 func Test_CheckCAPIContract(t *testing.T) {
 	type args struct {
 		options []CheckCAPIContractOption
@@ -18,12 +18,18 @@ package cluster
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -37,7 +43,154 @@ import (
 // ObjectMover defines methods for moving Cluster API objects to another management cluster.
 type ObjectMover interface {
 	// Move moves all the Cluster API objects existing in a namespace (or from all the namespaces if empty) to a target management cluster.
-	Move(namespace string, toCluster Client, dryRun bool) error
+	// It returns the number of objects created in the target management cluster, also if the move fails.
+	Move(namespace string, toCluster Client, dryRun bool, options ...MoveOption) (int, error)
+
+	// DryRun returns a report describing all the Cluster API objects existing in a namespace (or from all the namespaces if empty)
+	// that would be moved to a target management cluster, without changing either the source or the target management cluster.
+	// If toCluster is nil, checking for conflicts with objects already existing in the target management cluster is skipped.
+	DryRun(namespace string, toCluster Client, options ...MoveOption) (*MoveReport, error)
+
+	// Plan returns an estimate of the number of objects, of their size and of the duration of moving all the Cluster API objects
+	// existing in a namespace (or from all the namespaces if empty) to a target management cluster, without changing either
+	// the source or the target management cluster. If toCluster is nil, the latency of the source management cluster is used.
+	Plan(namespace string, toCluster Client, options ...MoveOption) (*MovePlan, error)
+
+	// CanMove checks that a target management cluster is ready for moving all the Cluster API objects existing in a namespace
+	// (or from all the namespaces if empty), without changing either the source or the target management cluster.
+	CanMove(namespace string, toCluster Client, options ...MoveOption) error
+
+	// Backup saves all the Cluster API objects existing in a namespace (or from all the namespaces if empty) to a directory.
+	// It returns the number of objects saved, also if the backup fails.
+	Backup(namespace string, directory string, options ...MoveOption) (int, error)
+
+	// Restore creates all the Cluster API objects saved in a directory into a target management cluster.
+	// It returns the number of objects created in the target management cluster, also if the restore fails.
+	Restore(toCluster Client, directory string, options ...MoveOption) (int, error)
+
+	// Pause pauses the reconciliation of a Cluster and of all the objects belonging to it.
+	Pause(namespace, clusterName string) error
+
+	// Resume resumes the reconciliation of a Cluster and of all the objects belonging to it previously paused by Pause;
+	// objects paused by other means, e.g. by a user, are not resumed.
+	Resume(namespace, clusterName string) error
+
+	// ObjectGraph returns the graph of the Cluster API objects existing in a namespace (or from all the namespaces if empty),
+	// i.e. the graph used for moving objects; if clusterName is not empty, the graph is restricted to the Cluster with
+	// the given name and to the objects belonging to it.
+	ObjectGraph(namespace, clusterName string) (*ObjectGraph, error)
+}
+
+// MoveOption is a configuration option supplied to ObjectMover.Move and ObjectMover.DryRun.
+type MoveOption func(*objectMover)
+
+// WithResume allows to resume a move operation previously failed; objects already created in the
+// target management cluster are skipped, if they match with the corresponding objects in the source management cluster.
+func WithResume(resume bool) MoveOption {
+	return func(o *objectMover) {
+		o.resume = resume
+	}
+}
+
+// WithLabelSelector restricts the move operation to the objects matching the label selector, e.g. a subset of the Clusters
+// existing in a namespace, together with all the objects belonging to them.
+func WithLabelSelector(selector labels.Selector) MoveOption {
+	return func(o *objectMover) {
+		o.selector = selector
+	}
+}
+
+// WithExcludedKinds excludes the objects of the given kinds from the move operation, e.g. objects managed separately
+// in each management cluster; kinds without a version match all the versions. The move operation fails if an
+// excluded object owns any of the objects to be moved.
+func WithExcludedKinds(kinds ...schema.GroupVersionKind) MoveOption {
+	return func(o *objectMover) {
+		o.excludedKinds = append(o.excludedKinds, kinds...)
+	}
+}
+
+// WithScope defines which of the objects belonging to the Clusters are moved, e.g. only the control plane objects.
+func WithScope(scope MoveScope) MoveOption {
+	return func(o *objectMover) {
+		o.scope = scope
+	}
+}
+
+// WithRedactSecrets removes the data of the Secrets when backing up Cluster API objects to a directory;
+// redacted Secrets are not restored.
+func WithRedactSecrets(redact bool) MoveOption {
+	return func(o *objectMover) {
+		o.redactSecrets = redact
+	}
+}
+
+// WithPreserveStatus restores the status of the objects after creating them in the target management cluster, so the
+// target controllers can resume from the known state; objects whose status can't be restored are moved without status.
+// NB. Creation timestamps and other metadata assigned by the API server can't be preserved.
+func WithPreserveStatus(preserve bool) MoveOption {
+	return func(o *objectMover) {
+		o.preserveStatus = preserve
+	}
+}
+
+// WithConcurrency sets the maximum number of objects of the same move group created concurrently in the target
+// management cluster; values lower than 1 are ignored, and objects are created one at a time.
+func WithConcurrency(concurrency int) MoveOption {
+	return func(o *objectMover) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithDiscoveryPageSize sets the maximum number of objects read by each list request when discovering the objects to be
+// moved; values lower than 1 are ignored, and the default page size is used.
+func WithDiscoveryPageSize(pageSize int64) MoveOption {
+	return func(o *objectMover) {
+		o.discoveryPageSize = pageSize
+	}
+}
+
+// WithDiscoveryConcurrency sets the maximum number of types listed concurrently when discovering the objects to be
+// moved; values lower than 1 are ignored, and types are listed one at a time.
+func WithDiscoveryConcurrency(concurrency int) MoveOption {
+	return func(o *objectMover) {
+		o.discoveryConcurrency = concurrency
+	}
+}
+
+// WithEncryptionKey sets the key used for encrypting the data of the Secrets when backing up Cluster API objects to a directory,
+// and for decrypting them when restoring.
+func WithEncryptionKey(key string) MoveOption {
+	return func(o *objectMover) {
+		o.encryptionKey = key
+	}
+}
+
+// WithToNamespace moves all the namespaced objects to a namespace in the target management cluster, no matter of the namespace
+// they have in the source management cluster. References between the moved objects are updated accordingly.
+func WithToNamespace(namespace string) MoveOption {
+	return func(o *objectMover) {
+		o.toNamespace = namespace
+	}
+}
+
+// WithAPIGroupMappings maps API groups of the source management cluster to equivalent API groups of the target management
+// cluster, e.g. the API groups renamed by a provider migration; objects of the mapped API groups, and the references to them,
+// are created in the target management cluster using the target API group and the same API version.
+func WithAPIGroupMappings(mappings map[string]string) MoveOption {
+	return func(o *objectMover) {
+		o.apiGroupMappings = mappings
+	}
+}
+
+// MoveProgressFunc is invoked each time an object is created in the target management cluster or deleted from the
+// source management cluster; done and total are the number of operations completed and the overall number of operations.
+type MoveProgressFunc func(done, total int, currentObject string)
+
+// WithProgress sets a function to be notified about the progress of a move operation.
+func WithProgress(progressFunc MoveProgressFunc) MoveOption {
+	return func(o *objectMover) {
+		o.progressFunc = progressFunc
+	}
 }
 
 // objectMover implements the ObjectMover interface.
@@ -45,41 +198,52 @@ type objectMover struct {
 	fromProxy             Proxy
 	fromProviderInventory InventoryClient
 	dryRun                bool
+	resume                bool
+	selector              labels.Selector
+	excludedKinds         []schema.GroupVersionKind
+	scope                 MoveScope
+	redactSecrets         bool
+	preserveStatus        bool
+	concurrency           int
+	discoveryPageSize     int64
+	discoveryConcurrency  int
+	encryptionKey         string
+	toNamespace           string
+	apiGroupMappings      map[string]string
+	movedNamespaces       sets.String
+	progressFunc          MoveProgressFunc
+	progress              *moveProgress
+	movedObjects          int
+	checkpoint            *moveCheckpoint
 }
 
 // ensure objectMover implements the ObjectMover interface.
 var _ ObjectMover = &objectMover{}
 
-func (o *objectMover) Move(namespace string, toCluster Client, dryRun bool) error {
+func (o *objectMover) Move(namespace string, toCluster Client, dryRun bool, options ...MoveOption) (int, error) {
 	log := logf.Log
 	log.Info("Performing move...")
+	for _, opt := range options {
+		opt(o)
+	}
 	o.dryRun = dryRun
+	o.movedObjects = 0
 	if o.dryRun {
 		log.Info("********************************************************")
 		log.Info("This is a dry-run move, will not perform any real action")
 		log.Info("********************************************************")
 	}
 
-	objectGraph := newObjectGraph(o.fromProxy, o.fromProviderInventory)
-
-	// checks that all the required providers in place in the target cluster.
-	if !o.dryRun {
-		if err := o.checkTargetProviders(toCluster.ProviderInventory()); err != nil {
-			return errors.Wrap(err, "failed to check providers in target cluster")
-		}
-	}
-
-	// Gets all the types defines by the CRDs installed by clusterctl plus the ConfigMap/Secret core types.
-	err := objectGraph.getDiscoveryTypes()
+	objectGraph, err := o.getObjectGraph(namespace)
 	if err != nil {
-		return errors.Wrap(err, "failed to retrieve discovery types")
+		return 0, err
 	}
 
-	// Discovery the object graph for the selected types:
-	// - Nodes are defined the Kubernetes objects (Clusters, Machines etc.) identified during the discovery process.
-	// - Edges are derived by the OwnerReferences between nodes.
-	if err := objectGraph.Discovery(namespace); err != nil {
-		return errors.Wrap(err, "failed to discover the object graph")
+	// checks that all the required providers and types are in place in the target cluster.
+	if !o.dryRun {
+		if err := o.checkTarget(objectGraph, toCluster); err != nil {
+			return 0, err
+		}
 	}
 
 	// Checks if Cluster API has already completed the provisioning of the infrastructure for the objects involved in the move operation.
@@ -87,7 +251,7 @@ func (o *objectMover) Move(namespace string, toCluster Client, dryRun bool) erro
 	// not currently waiting for long-running reconciliation loops, and so we can safely rely on the pause field on the Cluster object
 	// for blocking any further object reconciliation on the source objects.
 	if err := o.checkProvisioningCompleted(objectGraph); err != nil {
-		return errors.Wrap(err, "failed to check for provisioned infrastructure")
+		return 0, errors.Wrap(err, "failed to check for provisioned infrastructure")
 	}
 
 	// Check whether nodes are not included in GVK considered for move
@@ -97,9 +261,159 @@ func (o *objectMover) Move(namespace string, toCluster Client, dryRun bool) erro
 	var proxy Proxy
 	if !o.dryRun {
 		proxy = toCluster.Proxy()
+
+		// Gets the checkpoint keeping track of the move progress, so the operation can be resumed in case of failures.
+		if err := o.initCheckpoint(namespace); err != nil {
+			return 0, err
+		}
+
+		// Ensures objects moved to the target namespace do not collide.
+		if err := o.checkToNamespaceCollisions(objectGraph, proxy); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := o.move(objectGraph, proxy); err != nil {
+		return o.movedObjects, err
+	}
+
+	// The move is completed, so the checkpoint is not required anymore.
+	if o.checkpoint != nil {
+		return o.movedObjects, o.checkpoint.delete()
+	}
+	return o.movedObjects, nil
+}
+
+func (o *objectMover) DryRun(namespace string, toCluster Client, options ...MoveOption) (*MoveReport, error) {
+	log := logf.Log
+	log.Info("Performing move dry-run...")
+	for _, opt := range options {
+		opt(o)
+	}
+
+	objectGraph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensures objects moved to the target namespace do not collide.
+	// NB. Objects already existing in the target management cluster are reported as conflicts.
+	if err := o.checkToNamespaceCollisions(objectGraph, nil); err != nil {
+		return nil, err
+	}
+
+	var proxy Proxy
+	if toCluster != nil {
+		proxy = toCluster.Proxy()
 	}
 
-	return o.move(objectGraph, proxy)
+	return o.buildMoveReport(objectGraph, proxy)
+}
+
+func (o *objectMover) CanMove(namespace string, toCluster Client, options ...MoveOption) error {
+	log := logf.Log
+	log.Info("Checking the target management cluster...")
+	for _, opt := range options {
+		opt(o)
+	}
+
+	objectGraph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return err
+	}
+
+	return o.checkTarget(objectGraph, toCluster)
+}
+
+func (o *objectMover) ObjectGraph(namespace, clusterName string) (*ObjectGraph, error) {
+	objectGraph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if clusterName == "" {
+		return newObjectGraphView(objectGraph, nil), nil
+	}
+
+	cluster, err := objectGraph.getCluster(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectGraphView(objectGraph, cluster), nil
+}
+
+// getObjectGraph returns the object graph for all the Cluster API objects existing in a namespace (or from all the namespaces if empty).
+func (o *objectMover) getObjectGraph(namespace string) (*objectGraph, error) {
+	objectGraph := newObjectGraph(o.fromProxy, o.fromProviderInventory)
+	objectGraph.selector = o.selector
+	objectGraph.excludedKinds = o.excludedKinds
+	objectGraph.scope = o.scope
+	objectGraph.discoveryPageSize = o.discoveryPageSize
+	objectGraph.discoveryConcurrency = o.discoveryConcurrency
+	if o.scope != "" && o.scope != AllMoveScope && o.scope != ControlPlaneMoveScope {
+		return nil, errors.Errorf("invalid move scope %q, it must be one of %q or %q", o.scope, AllMoveScope, ControlPlaneMoveScope)
+	}
+	if err := validateAPIGroupMappings(o.apiGroupMappings); err != nil {
+		return nil, err
+	}
+
+	// Gets all the types defines by the CRDs installed by clusterctl plus the ConfigMap/Secret core types.
+	err := objectGraph.getDiscoveryTypes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve discovery types")
+	}
+
+	// Discovery the object graph for the selected types:
+	// - Nodes are defined the Kubernetes objects (Clusters, Machines etc.) identified during the discovery process.
+	// - Edges are derived by the OwnerReferences between nodes.
+	if err := objectGraph.Discovery(namespace); err != nil {
+		return nil, errors.Wrap(err, "failed to discover the object graph")
+	}
+
+	// Ensures the label selector, if any, does not split an ownership hierarchy across management clusters.
+	if err := objectGraph.checkSelection(); err != nil {
+		return nil, err
+	}
+
+	// Restricts the move operation to the control plane of the Clusters, if required, ensuring the objects split across the source and
+	// the target management cluster can be reconciled.
+	if o.scope == ControlPlaneMoveScope {
+		if err := objectGraph.setControlPlaneScope(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ensures the excluded kinds, if any, do not own any of the objects to be moved.
+	if err := objectGraph.checkExclusion(); err != nil {
+		return nil, err
+	}
+
+	return objectGraph, nil
+}
+
+// initCheckpoint reads the checkpoint of a previous move operation for the namespace, if any.
+// When not resuming, a checkpoint left by a previous move operation that did not complete blocks the move, because the source and
+// the target management clusters could be in an inconsistent state.
+func (o *objectMover) initCheckpoint(namespace string) error {
+	log := logf.Log
+
+	checkpoint := newMoveCheckpoint(o.fromProxy, namespace)
+	if err := checkpoint.load(); err != nil {
+		return err
+	}
+
+	if checkpoint.exists && !o.resume {
+		return errors.Errorf("a previous move operation for the %q namespace did not complete (see ConfigMap %s/%s); please resume the move operation", namespace, checkpoint.namespace, checkpoint.name)
+	}
+	if !checkpoint.exists && o.resume {
+		log.Info("No checkpoint found for a previous move operation, starting a new move")
+	}
+	if checkpoint.exists && o.resume {
+		log.Info("Resuming a previous move operation", "Moved objects", len(checkpoint.entries))
+	}
+
+	o.checkpoint = checkpoint
+	return nil
 }
 
 func newObjectMover(fromProxy Proxy, fromProviderInventory InventoryClient) *objectMover {
@@ -118,7 +432,7 @@ func (o *objectMover) checkProvisioningCompleted(graph *objectGraph) error {
 
 	// Checking all the clusters have infrastructure is ready
 	readClusterBackoff := newReadBackoff()
-	clusters := graph.getClusters()
+	clusters := graph.getMoveClusters()
 	for i := range clusters {
 		cluster := clusters[i]
 		clusterObj := &clusterv1.Cluster{}
@@ -148,7 +462,7 @@ func (o *objectMover) checkProvisioningCompleted(graph *objectGraph) error {
 	// Checking all the machine have a NodeRef
 	// Nb. NodeRef is considered a better signal than InfrastructureReady, because it ensures the node in the workload cluster is up and running.
 	readMachinesBackoff := newReadBackoff()
-	machines := graph.getMachines()
+	machines := graph.getMoveMachines()
 	for i := range machines {
 		machine := machines[i]
 		machineObj := &clusterv1.Machine{}
@@ -206,13 +520,16 @@ func getMachineObj(proxy Proxy, machine *node, machineObj *clusterv1.Machine) er
 func (o *objectMover) move(graph *objectGraph, toProxy Proxy) error {
 	log := logf.Log
 
-	clusters := graph.getClusters()
+	clusters := graph.getMoveClusters()
 	log.Info("Moving Cluster API objects", "Clusters", len(clusters))
 
+	// Each object is created in the target management cluster first, and then deleted from the source management cluster.
+	o.progress = newMoveProgress(o.progressFunc, 2*len(graph.getMoveNodes()))
+
 	// Sets the pause field on the Cluster object in the source management cluster, so the controllers stop reconciling it.
 	log.V(1).Info("Pausing the source cluster")
 	if err := setClusterPause(o.fromProxy, clusters, true, o.dryRun); err != nil {
-		return err
+		return newMoveError(err)
 	}
 
 	// Ensure all the expected target namespaces are in place before creating objects.
@@ -220,6 +537,7 @@ func (o *objectMover) move(graph *objectGraph, toProxy Proxy) error {
 	if err := o.ensureNamespaces(graph, toProxy); err != nil {
 		return err
 	}
+	o.movedNamespaces = o.getMovedNamespaces(graph)
 
 	// Define the move sequence by processing the ownerReference chain, so we ensure that a Kubernetes object is moved only after its owners.
 	// The sequence is bases on object graph nodes, each one representing a Kubernetes object; nodes are grouped, so bulk of nodes can be moved in parallel. e.g.
@@ -231,8 +549,17 @@ func (o *objectMover) move(graph *objectGraph, toProxy Proxy) error {
 	// Create all objects group by group, ensuring all the ownerReferences are re-created.
 	log.Info("Creating objects in the target cluster")
 	for groupIndex := 0; groupIndex < len(moveSequence.groups); groupIndex++ {
-		if err := o.createGroup(moveSequence.getGroup(groupIndex), toProxy); err != nil {
-			return err
+		err := o.createGroup(moveSequence.getGroup(groupIndex), toProxy)
+
+		// Persist the progress of the move operation, so it can be resumed in case of failures.
+		if o.checkpoint != nil {
+			if saveErr := o.checkpoint.save(); saveErr != nil {
+				return kerrors.NewAggregate([]error{err, saveErr})
+			}
+		}
+
+		if err != nil {
+			return newMoveError(err)
 		}
 	}
 
@@ -240,13 +567,170 @@ func (o *objectMover) move(graph *objectGraph, toProxy Proxy) error {
 	log.Info("Deleting objects from the source cluster")
 	for groupIndex := len(moveSequence.groups) - 1; groupIndex >= 0; groupIndex-- {
 		if err := o.deleteGroup(moveSequence.getGroup(groupIndex)); err != nil {
-			return err
+			return newMoveError(err)
 		}
 	}
 
 	// Reset the pause field on the Cluster object in the target management cluster, so the controllers start reconciling it.
+	// Nb. Clusters failing to be resumed are left paused in the target management cluster, and they are reported
+	// by MoveError.FrozenObjects.
 	log.V(1).Info("Resuming the target cluster")
-	return setClusterPause(toProxy, clusters, false, o.dryRun)
+	return newMoveError(setClusterPause(toProxy, o.toTargetNodes(clusters), false, o.dryRun))
+}
+
+// targetNamespace returns the namespace of the object corresponding to a node in the target management cluster.
+// Objects belonging to a global hierarchy keep their namespace, because it is usually defined by the providers (e.g. identity Secrets).
+func (o *objectMover) targetNamespace(n *node) string {
+	if o.toNamespace == "" || n.isGlobal || n.isGlobalHierarchy || n.identity.Namespace == "" {
+		return n.identity.Namespace
+	}
+	return o.toNamespace
+}
+
+// toTargetNodes returns a copy of the nodes, referring to the corresponding objects in the target management cluster.
+func (o *objectMover) toTargetNodes(nodes []*node) []*node {
+	if o.toNamespace == "" && len(o.apiGroupMappings) == 0 {
+		return nodes
+	}
+
+	targetNodes := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		targetNode := *n
+		targetNode.identity.Namespace = o.targetNamespace(n)
+		targetNode.identity.APIVersion = o.targetAPIVersion(n.identity.APIVersion)
+		targetNodes = append(targetNodes, &targetNode)
+	}
+	return targetNodes
+}
+
+// checkToNamespaceCollisions checks that objects moved to the target namespace do not collide on name; if toProxy is set,
+// objects already existing in the target namespace are considered collisions too, unless they are created by
+// the move operation being resumed.
+func (o *objectMover) checkToNamespaceCollisions(graph *objectGraph, toProxy Proxy) error {
+	if o.toNamespace == "" {
+		return nil
+	}
+
+	errList := []error{}
+	moved := map[string]*node{}
+	nodes := graph.getMoveNodes()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeSortKey(nodes[i]) < nodeSortKey(nodes[j])
+	})
+	for _, n := range nodes {
+		if o.targetNamespace(n) != o.toNamespace {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", n.identity.GroupVersionKind().GroupKind(), n.identity.Name)
+		if other, ok := moved[key]; ok {
+			errList = append(errList, errors.Errorf("%s %s and %s %s would collide in the target namespace %q",
+				other.identity.Kind, namespacedName(other.identity), n.identity.Kind, namespacedName(n.identity), o.toNamespace))
+			continue
+		}
+		moved[key] = n
+	}
+
+	if toProxy != nil && len(errList) == 0 {
+		candidates := []*node{}
+		for _, n := range moved {
+			if o.checkpoint != nil {
+				if _, ok := o.checkpoint.get(n.identity.UID); ok {
+					continue
+				}
+			}
+			candidates = append(candidates, n)
+		}
+
+		conflicts, err := o.checkTargetConflicts(candidates, toProxy)
+		if err != nil {
+			return err
+		}
+		for _, ref := range conflicts {
+			errList = append(errList, errors.Errorf("%s %s/%s already exists in the target management cluster", ref.Kind, ref.Namespace, ref.Name))
+		}
+	}
+
+	if len(errList) > 0 {
+		return errors.Wrapf(kerrors.NewAggregate(errList), "failed to move objects to the namespace %q", o.toNamespace)
+	}
+	return nil
+}
+
+// getMovedNamespaces returns the namespaces whose objects are moved to the target namespace.
+func (o *objectMover) getMovedNamespaces(graph *objectGraph) sets.String {
+	namespaces := sets.NewString()
+	if o.toNamespace == "" {
+		return namespaces
+	}
+	for _, n := range graph.getMoveNodes() {
+		if o.targetNamespace(n) == o.toNamespace {
+			namespaces.Insert(n.identity.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// setTargetNamespace moves an object to the target namespace, updating all the references to objects in the namespaces being
+// moved to the target namespace as well.
+// NB. references are detected by looking for fields having both a name and a namespace, like in corev1.ObjectReference or corev1.SecretReference.
+func (o *objectMover) setTargetNamespace(obj *unstructured.Unstructured, nodeToCreate *node) {
+	if o.toNamespace == "" || o.targetNamespace(nodeToCreate) != o.toNamespace {
+		return
+	}
+
+	setReferencesNamespace(obj.Object, o.movedNamespaces, o.toNamespace)
+	obj.SetNamespace(o.toNamespace)
+}
+
+func setReferencesNamespace(value interface{}, movedNamespaces sets.String, toNamespace string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v["name"].(string); ok {
+			if namespace, ok := v["namespace"].(string); ok && movedNamespaces.Has(namespace) {
+				v["namespace"] = toNamespace
+			}
+		}
+		for _, item := range v {
+			setReferencesNamespace(item, movedNamespaces, toNamespace)
+		}
+	case []interface{}:
+		for _, item := range v {
+			setReferencesNamespace(item, movedNamespaces, toNamespace)
+		}
+	}
+}
+
+// moveProgress keeps track of the operations completed during a move operation, notifying a MoveProgressFunc.
+// NB. moveProgress can be safely used from many goroutines, and a nil moveProgress does nothing.
+type moveProgress struct {
+	lock         sync.Mutex
+	progressFunc MoveProgressFunc
+	done         int
+	total        int
+}
+
+func newMoveProgress(progressFunc MoveProgressFunc, total int) *moveProgress {
+	if progressFunc == nil {
+		return nil
+	}
+	return &moveProgress{
+		progressFunc: progressFunc,
+		total:        total,
+	}
+}
+
+// inc records an operation completed for a node.
+func (p *moveProgress) inc(n *node) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.done++
+	p.progressFunc(p.done, p.total, fmt.Sprintf("%s %s", n.identity.Kind, namespacedName(n.identity)))
 }
 
 // moveSequence defines a list of group of moveGroups.
@@ -324,7 +808,8 @@ func getMoveSequence(graph *objectGraph) *moveSequence {
 	return moveSequence
 }
 
-// setClusterPause sets the paused field on nodes referring to Cluster objects.
+// setClusterPause sets the paused field on nodes referring to Cluster objects; all the Clusters are processed, and
+// the Clusters that failed are reported as an aggregate of MoveObjectErrors.
 func setClusterPause(proxy Proxy, clusters []*node, value bool, dryRun bool) error {
 	if dryRun {
 		return nil
@@ -333,7 +818,13 @@ func setClusterPause(proxy Proxy, clusters []*node, value bool, dryRun bool) err
 	log := logf.Log
 	patch := client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf("{\"spec\":{\"paused\":%t}}", value)))
 
+	phase := MoveUnpausePhase
+	if value {
+		phase = MovePausePhase
+	}
+
 	setClusterPauseBackoff := newWriteBackoff()
+	errList := []error{}
 	for i := range clusters {
 		cluster := clusters[i]
 		log.V(5).Info("Set Cluster.Spec.Paused", "Paused", value, "Cluster", cluster.identity.Name, "Namespace", cluster.identity.Namespace)
@@ -342,10 +833,14 @@ func setClusterPause(proxy Proxy, clusters []*node, value bool, dryRun bool) err
 		if err := retryWithExponentialBackoff(setClusterPauseBackoff, func() error {
 			return patchCluster(proxy, cluster, patch)
 		}); err != nil {
-			return errors.Wrapf(err, "error setting Cluster.Spec.Paused=%t", value)
+			errList = append(errList, &MoveObjectError{
+				Phase:  phase,
+				Object: cluster.identity,
+				Err:    errors.Wrapf(err, "error setting Cluster.Spec.Paused=%t", value),
+			})
 		}
 	}
-	return nil
+	return kerrors.NewAggregate(errList)
 }
 
 // patchCluster applies a patch to a node referring to a Cluster object.
@@ -388,7 +883,7 @@ func (o *objectMover) ensureNamespaces(graph *objectGraph, toProxy Proxy) error
 			continue
 		}
 
-		namespace := node.identity.Namespace
+		namespace := o.targetNamespace(node)
 
 		// If the namespace was already processed, skip it.
 		if namespaces.Has(namespace) {
@@ -471,26 +966,47 @@ func (o *objectMover) ensureNamespace(toProxy Proxy, namespace string) error {
 
 // createGroup creates all the Kubernetes objects into the target management cluster corresponding to the object graph nodes in a moveGroup.
 func (o *objectMover) createGroup(group moveGroup, toProxy Proxy) error {
+	workers := o.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	// NB. The owners of the objects in a group belong to the previous groups, so they are already created, and the
+	// objects in the same group can be safely created concurrently.
 	createTargetObjectBackoff := newWriteBackoff()
-	errList := []error{}
+	errList := make([]error, len(group))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
 	for i := range group {
-		nodeToCreate := group[i]
-
-		// Creates the Kubernetes object corresponding to the nodeToCreate.
-		// Nb. The operation is wrapped in a retry loop to make move more resilient to unexpected conditions.
-		err := retryWithExponentialBackoff(createTargetObjectBackoff, func() error {
-			return o.createTargetObject(nodeToCreate, toProxy)
-		})
-		if err != nil {
-			errList = append(errList, err)
-		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(nodeToCreate *node, i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Creates the Kubernetes object corresponding to the nodeToCreate.
+			// Nb. The operation is wrapped in a retry loop to make move more resilient to unexpected conditions.
+			err := retryWithExponentialBackoff(createTargetObjectBackoff, func() error {
+				return o.createTargetObject(nodeToCreate, toProxy)
+			})
+			if err != nil {
+				errList[i] = &MoveObjectError{Phase: MoveCreatePhase, Object: nodeToCreate.identity, Err: err}
+				return
+			}
+			o.progress.inc(nodeToCreate)
+		}(group[i], i)
 	}
+	wg.Wait()
 
-	if len(errList) > 0 {
-		return kerrors.NewAggregate(errList)
+	// NB. The objects are counted once all the workers are done, so the counter is not accessed concurrently; objects
+	// created by a previous move operation are not counted.
+	for i, err := range errList {
+		if err == nil && !group[i].alreadyMoved {
+			o.movedObjects++
+		}
 	}
 
-	return nil
+	return kerrors.NewAggregate(errList)
 }
 
 // createTargetObject creates the Kubernetes object in the target Management cluster corresponding to the object graph node, taking care of restoring the OwnerReference with the owner nodes, if any.
@@ -502,11 +1018,6 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		return nil
 	}
 
-	cFrom, err := o.fromProxy.NewClient()
-	if err != nil {
-		return err
-	}
-
 	// Get the source object
 	obj := &unstructured.Unstructured{}
 	obj.SetAPIVersion(nodeToCreate.identity.APIVersion)
@@ -516,9 +1027,40 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		Name:      nodeToCreate.identity.Name,
 	}
 
-	if err := cFrom.Get(ctx, objKey, obj); err != nil {
-		return errors.Wrapf(err, "error reading %q %s/%s",
-			obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+	if nodeToCreate.restoreObject != nil {
+		// When restoring, the source object is the one read from the backup directory.
+		obj = nodeToCreate.restoreObject.DeepCopy()
+	} else {
+		cFrom, err := o.fromProxy.NewClient()
+		if err != nil {
+			return err
+		}
+
+		if err := cFrom.Get(ctx, objKey, obj); err != nil {
+			return errors.Wrapf(err, "error reading %q %s/%s",
+				obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+		}
+	}
+
+	// Moves the object to the target namespace and to the target API groups, if required.
+	o.setTargetNamespace(obj, nodeToCreate)
+	o.setTargetAPIGroups(obj)
+
+	// Computes the hash of the source object, so it could be verified against the object in the target cluster when resuming a move operation.
+	specHash, err := getSpecHash(obj)
+	if err != nil {
+		return err
+	}
+
+	// If resuming a move operation, skip objects already created in the target cluster.
+	if o.resume && o.checkpoint != nil {
+		moved, err := o.checkAlreadyMoved(nodeToCreate, specHash, toProxy)
+		if err != nil {
+			return err
+		}
+		if moved {
+			return nil
+		}
 	}
 
 	// New objects cannot have a specified resource version. Clear it out.
@@ -532,7 +1074,7 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		ownerRefs := []metav1.OwnerReference{}
 		for ownerNode := range nodeToCreate.owners {
 			ownerRef := metav1.OwnerReference{
-				APIVersion: ownerNode.identity.APIVersion,
+				APIVersion: o.targetAPIVersion(ownerNode.identity.APIVersion),
 				Kind:       ownerNode.identity.Kind,
 				Name:       ownerNode.identity.Name,
 				UID:        ownerNode.newUID, // Use the owner's newUID read from the target management cluster (instead of the UID read during discovery).
@@ -549,6 +1091,14 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		obj.SetOwnerReferences(ownerRefs)
 	}
 
+	// Saves the status, because the status subresource is ignored when creating or updating objects.
+	status, hasStatus, err := unstructured.NestedFieldCopy(obj.Object, "status")
+	if err != nil {
+		return errors.Wrapf(err, "error reading the status of %q %s/%s",
+			obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+	}
+	restoreStatus := o.preserveStatus && hasStatus
+
 	// Creates the targetObj into the target management cluster.
 	cTo, err := toProxy.NewClient()
 	if err != nil {
@@ -564,15 +1114,21 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		// If the object already exists, try to update it if it is node a global object / something belonging to a global object hierarchy (e.g. a secrets owned by a global identity object).
 		if nodeToCreate.isGlobal || nodeToCreate.isGlobalHierarchy {
 			log.V(5).Info("Object already exists, skipping upgrade because it is global/it is owned by a global object", nodeToCreate.identity.Kind, nodeToCreate.identity.Name, "Namespace", nodeToCreate.identity.Namespace)
+			restoreStatus = false
 		} else {
 			// Nb. This should not happen, but it is supported to make move more resilient to unexpected interrupt/restarts of the move process.
+			// ClusterClasses instead could already exist in the target cluster, and they are reconciled with the source ones.
 			log.V(5).Info("Object already exists, updating", nodeToCreate.identity.Kind, nodeToCreate.identity.Name, "Namespace", nodeToCreate.identity.Namespace)
 
 			// Retrieve the UID and the resource version for the update.
 			existingTargetObj := &unstructured.Unstructured{}
 			existingTargetObj.SetAPIVersion(obj.GetAPIVersion())
 			existingTargetObj.SetKind(obj.GetKind())
-			if err := cTo.Get(ctx, objKey, existingTargetObj); err != nil {
+			existingTargetObjKey := client.ObjectKey{
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+			}
+			if err := cTo.Get(ctx, existingTargetObjKey, existingTargetObj); err != nil {
 				return errors.Wrapf(err, "error reading resource for %q %s/%s",
 					existingTargetObj.GroupVersionKind(), existingTargetObj.GetNamespace(), existingTargetObj.GetName())
 			}
@@ -586,12 +1142,86 @@ func (o *objectMover) createTargetObject(nodeToCreate *node, toProxy Proxy) erro
 		}
 	}
 
+	if restoreStatus {
+		o.restoreTargetStatus(cTo, obj, status)
+	}
+
 	// Stores the newUID assigned to the newly created object.
 	nodeToCreate.newUID = obj.GetUID()
 
+	// Keeps track of the object created in the target cluster.
+	if o.checkpoint != nil {
+		o.checkpoint.record(nodeToCreate.identity.UID, nodeToCreate.newUID, specHash)
+	}
+
 	return nil
 }
 
+// restoreTargetStatus restores the status of an object created in the target management cluster using the status
+// subresource; failures are logged and ignored, because the target controllers can still recompute the status.
+func (o *objectMover) restoreTargetStatus(cTo client.Client, obj *unstructured.Unstructured, status interface{}) {
+	log := logf.Log
+
+	if err := unstructured.SetNestedField(obj.Object, status, "status"); err != nil {
+		log.V(5).Info("Failed to restore the status", obj.GetKind(), obj.GetName(), "Namespace", obj.GetNamespace(), "Reason", err.Error())
+		return
+	}
+	if err := cTo.Status().Update(ctx, obj); err != nil {
+		// NB. Objects without a status subresource, e.g. Secrets, already got the status, if any, when created.
+		if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+			log.V(5).Info("Skipping status restore, the object does not have a status subresource", obj.GetKind(), obj.GetName(), "Namespace", obj.GetNamespace())
+			return
+		}
+		log.Info("Failed to restore the status, the target controllers will recompute it", obj.GetKind(), obj.GetName(), "Namespace", obj.GetNamespace(), "Reason", err.Error())
+	}
+}
+
+// checkAlreadyMoved checks if the object corresponding to the node was already created in the target Management cluster by a previous move operation,
+// and if the object in the target cluster still matches the object in the source cluster.
+func (o *objectMover) checkAlreadyMoved(nodeToCreate *node, specHash string, toProxy Proxy) (bool, error) {
+	log := logf.Log
+
+	entry, ok := o.checkpoint.get(nodeToCreate.identity.UID)
+	if !ok {
+		return false, nil
+	}
+
+	cTo, err := toProxy.NewClient()
+	if err != nil {
+		return false, err
+	}
+
+	targetObj := &unstructured.Unstructured{}
+	targetObj.SetAPIVersion(o.targetAPIVersion(nodeToCreate.identity.APIVersion))
+	targetObj.SetKind(nodeToCreate.identity.Kind)
+	targetObjKey := client.ObjectKey{
+		Namespace: o.targetNamespace(nodeToCreate),
+		Name:      nodeToCreate.identity.Name,
+	}
+	if err := cTo.Get(ctx, targetObjKey, targetObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error reading %q %s/%s from the target cluster",
+			targetObj.GroupVersionKind(), targetObj.GetNamespace(), targetObj.GetName())
+	}
+
+	targetSpecHash, err := getSpecHash(targetObj)
+	if err != nil {
+		return false, err
+	}
+
+	if targetObj.GetUID() != entry.NewUID || targetSpecHash != specHash {
+		log.V(5).Info("Object already exists but it does not match the source object, re-applying", nodeToCreate.identity.Kind, nodeToCreate.identity.Name, "Namespace", nodeToCreate.identity.Namespace)
+		return false, nil
+	}
+
+	log.V(5).Info("Object already moved, skipping", nodeToCreate.identity.Kind, nodeToCreate.identity.Name, "Namespace", nodeToCreate.identity.Namespace)
+	nodeToCreate.newUID = entry.NewUID
+	nodeToCreate.alreadyMoved = true
+	return true, nil
+}
+
 // deleteGroup deletes all the Kubernetes objects from the source management cluster corresponding to the object graph nodes in a moveGroup.
 func (o *objectMover) deleteGroup(group moveGroup) error {
 	deleteSourceObjectBackoff := newWriteBackoff()
@@ -606,8 +1236,10 @@ func (o *objectMover) deleteGroup(group moveGroup) error {
 		})
 
 		if err != nil {
-			errList = append(errList, err)
+			errList = append(errList, &MoveObjectError{Phase: MoveDeletePhase, Object: nodeToDelete.identity, Err: err})
+			continue
 		}
+		o.progress.inc(nodeToDelete)
 	}
 
 	return kerrors.NewAggregate(errList)
@@ -625,6 +1257,11 @@ func (o *objectMover) deleteSourceObject(nodeToDelete *node) error {
 		return nil
 	}
 
+	// Don't delete nodes shared between the control plane moved to the target cluster and the objects left in the source cluster.
+	if nodeToDelete.keepInSource {
+		return nil
+	}
+
 	log := logf.Log
 	log.V(1).Info("Deleting", nodeToDelete.identity.Kind, nodeToDelete.identity.Name, "Namespace", nodeToDelete.identity.Namespace)
 
@@ -671,6 +1308,87 @@ func (o *objectMover) deleteSourceObject(nodeToDelete *node) error {
 	return nil
 }
 
+// checkTarget checks that the target management cluster has all the providers installed in the source management cluster,
+// that it serves all the types of the objects to be moved, and that it has the ClusterClasses used by the Clusters not moved with them.
+func (o *objectMover) checkTarget(graph *objectGraph, toCluster Client) error {
+	errList := []error{}
+	if err := o.checkTargetProviders(toCluster.ProviderInventory()); err != nil {
+		errList = append(errList, errors.Wrap(err, "failed to check providers in target cluster"))
+	}
+	if err := o.checkTargetTypes(graph, toCluster.Proxy()); err != nil {
+		errList = append(errList, errors.Wrap(err, "failed to check types in target cluster"))
+	}
+	if err := o.checkTargetClusterClasses(graph, toCluster.Proxy()); err != nil {
+		errList = append(errList, errors.Wrap(err, "failed to check ClusterClasses in target cluster"))
+	}
+	if err := o.checkTargetContract(toCluster.Proxy()); err != nil {
+		errList = append(errList, errors.Wrap(err, "failed to check the contract of the target cluster"))
+	}
+	return kerrors.NewAggregate(errList)
+}
+
+// checkTargetTypes checks that the CRDs for all the types of the objects to be moved are installed in the target management cluster,
+// serving the same API version used in the source management cluster, after mapping the API groups, if required. Types installed
+// in the target management cluster only under other API groups are reported together with those API groups.
+func (o *objectMover) checkTargetTypes(graph *objectGraph, toProxy Proxy) error {
+	if o.dryRun {
+		return nil
+	}
+
+	crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		return getCRDList(toProxy, crdList)
+	}); err != nil {
+		return err
+	}
+
+	targetKinds := sets.NewString()
+	targetServedVersions := sets.NewString()
+	targetKindGroups := map[string]sets.String{}
+	for _, crd := range crdList.Items {
+		groupKind := schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}
+		targetKinds.Insert(groupKind.String())
+		if _, ok := targetKindGroups[groupKind.Kind]; !ok {
+			targetKindGroups[groupKind.Kind] = sets.NewString()
+		}
+		targetKindGroups[groupKind.Kind].Insert(groupKind.Group)
+		for _, version := range crd.Spec.Versions {
+			if version.Served {
+				targetServedVersions.Insert(groupKind.WithVersion(version.Name).String())
+			}
+		}
+	}
+
+	requiredTypes := map[string]schema.GroupVersionKind{}
+	for _, n := range graph.getMoveNodes() {
+		gvk := n.identity.GroupVersionKind()
+		// Skips core types, which are always available, and nodes not observed during discovery.
+		if n.virtual || gvk.Group == "" {
+			continue
+		}
+		gvk.Group = o.targetAPIGroup(gvk.Group)
+		requiredTypes[gvk.String()] = gvk
+	}
+
+	errList := []error{}
+	for _, key := range sets.StringKeySet(requiredTypes).List() {
+		gvk := requiredTypes[key]
+		if !targetKinds.Has(gvk.GroupKind().String()) {
+			if otherGroups, ok := targetKindGroups[gvk.Kind]; ok {
+				errList = append(errList, errors.Errorf("the CRD for %s is not installed in the target cluster, which defines %s in the API groups %s instead; "+
+					"if the API groups are equivalent, please map them using the API group mappings", gvk.GroupKind(), gvk.Kind, strings.Join(otherGroups.List(), ", ")))
+				continue
+			}
+			errList = append(errList, errors.Errorf("the CRD for %s is not installed in the target cluster", gvk.GroupKind()))
+			continue
+		}
+		if !targetServedVersions.Has(gvk.String()) {
+			errList = append(errList, errors.Errorf("the CRD for %s in the target cluster does not serve the API version %s", gvk.GroupKind(), gvk.GroupVersion()))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
 // checkTargetProviders checks that all the providers installed in the source cluster exists in the target cluster as well (with a version >= of the current version).
 func (o *objectMover) checkTargetProviders(toInventory InventoryClient) error {
 	if o.dryRun {
@@ -713,12 +1431,12 @@ func (o *objectMover) checkTargetProviders(toInventory InventoryClient) error {
 			}
 		}
 		if maxTargetVersion == nil {
-			errList = append(errList, errors.Errorf("provider %s not found in the target cluster", sourceProvider.Name))
+			errList = append(errList, errors.Errorf("provider %s not found in the target cluster", sourceProvider.ManifestLabel()))
 			continue
 		}
 
 		if !maxTargetVersion.AtLeast(sourceVersion) {
-			errList = append(errList, errors.Errorf("provider %s in the target cluster is older than in the source cluster (source: %s, target: %s)", sourceProvider.Name, sourceVersion.String(), maxTargetVersion.String()))
+			errList = append(errList, errors.Errorf("provider %s in the target cluster is older than in the source cluster (source: %s, target: %s)", sourceProvider.ManifestLabel(), sourceVersion.String(), maxTargetVersion.String()))
 		}
 	}
 
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// validateAPIGroupMappings checks that the API group mappings do not involve the core API group, which can't be renamed.
+func validateAPIGroupMappings(mappings map[string]string) error {
+	errList := []error{}
+	for _, from := range sets.StringKeySet(mappings).List() {
+		if from == "" || mappings[from] == "" {
+			errList = append(errList, errors.Errorf("invalid API group mapping %q to %q, the core API group can't be mapped", from, mappings[from]))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
+// targetAPIGroup returns the API group of the objects in the target management cluster corresponding to the
+// objects of an API group in the source management cluster.
+func (o *objectMover) targetAPIGroup(group string) string {
+	if targetGroup, ok := o.apiGroupMappings[group]; ok {
+		return targetGroup
+	}
+	return group
+}
+
+// targetAPIVersion returns the API version of the objects in the target management cluster corresponding to the
+// objects of an API version in the source management cluster; the version is preserved, only the API group is mapped.
+func (o *objectMover) targetAPIVersion(apiVersion string) string {
+	if len(o.apiGroupMappings) == 0 {
+		return apiVersion
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return apiVersion
+	}
+	gv.Group = o.targetAPIGroup(gv.Group)
+	return gv.String()
+}
+
+// setTargetAPIGroups rewrites an object using the API groups of the target management cluster, updating all the
+// references to objects of the mapped API groups as well.
+// NB. references are detected by looking for fields having a kind together with an apiVersion or an apiGroup,
+// like in corev1.ObjectReference or corev1.TypedLocalObjectReference.
+func (o *objectMover) setTargetAPIGroups(obj *unstructured.Unstructured) {
+	if len(o.apiGroupMappings) == 0 {
+		return
+	}
+
+	setReferencesAPIGroup(obj.Object, o)
+	obj.SetAPIVersion(o.targetAPIVersion(obj.GetAPIVersion()))
+}
+
+func setReferencesAPIGroup(value interface{}, o *objectMover) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v["kind"].(string); ok {
+			if apiVersion, ok := v["apiVersion"].(string); ok {
+				v["apiVersion"] = o.targetAPIVersion(apiVersion)
+			}
+			if apiGroup, ok := v["apiGroup"].(string); ok {
+				v["apiGroup"] = o.targetAPIGroup(apiGroup)
+			}
+		}
+		for _, item := range v {
+			setReferencesAPIGroup(item, o)
+		}
+	case []interface{}:
+		for _, item := range v {
+			setReferencesAPIGroup(item, o)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test/providers/infrastructure"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const renamedInfrastructureGroup = "infrastructure.example.com"
+
+// getFakeProxyWithRenamedInfrastructureCRDs returns a fakeProxy with all the CRDs for the types involved in the tests,
+// defining the infrastructure types in the renamed infrastructure API group.
+func getFakeProxyWithRenamedInfrastructureCRDs() *test.FakeProxy {
+	proxy := test.NewFakeProxy()
+	for _, crd := range test.FakeCRDList() {
+		if crd.Spec.Group == infrastructure.GroupVersion.Group {
+			crd.Spec.Group = renamedInfrastructureGroup
+			crd.Name = strings.ToLower(crd.Spec.Names.Kind) + "." + renamedInfrastructureGroup
+		}
+		proxy.WithObjs(crd)
+	}
+	return proxy
+}
+
+func Test_objectMover_checkTargetTypes_withAPIGroupMappings(t *testing.T) {
+	tests := []struct {
+		name             string
+		apiGroupMappings map[string]string
+		wantErr          string
+	}{
+		{
+			name:             "fails reporting the renamed API group if the API groups are not mapped",
+			apiGroupMappings: nil,
+			wantErr:          "in the API groups infrastructure.example.com instead",
+		},
+		{
+			name:             "all the types are served by the target cluster under the mapped API groups",
+			apiGroupMappings: map[string]string{infrastructure.GroupVersion.Group: renamedInfrastructureGroup},
+		},
+		{
+			name:             "fails if the target cluster does not define the types under the mapped API groups",
+			apiGroupMappings: map[string]string{infrastructure.GroupVersion.Group: "infrastructure.other.com"},
+			wantErr:          "the CRD for GenericInfrastructureCluster.infrastructure.other.com is not installed in the target cluster, which defines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			graph := getObjectGraphWithObjs(test.NewFakeCluster("ns1", "foo").Objs())
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			o := &objectMover{
+				fromProxy:        graph.proxy,
+				apiGroupMappings: tt.apiGroupMappings,
+			}
+			err := o.checkTargetTypes(graph, getFakeProxyWithRenamedInfrastructureCRDs())
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func Test_objectMover_move_withAPIGroupMappings(t *testing.T) {
+	g := NewWithT(t)
+
+	graph := getObjectGraphWithObjs(test.NewFakeCluster("ns1", "foo").Objs())
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+	g.Expect(graph.Discovery("")).To(Succeed())
+
+	toProxy := getFakeProxyWithRenamedInfrastructureCRDs()
+	mover := objectMover{
+		fromProxy:        graph.proxy,
+		apiGroupMappings: map[string]string{infrastructure.GroupVersion.Group: renamedInfrastructureGroup},
+	}
+	g.Expect(mover.move(graph, toProxy)).To(Succeed())
+
+	// check that the objects of the mapped API group are created using the target API group, and references are updated accordingly
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	renamedAPIVersion := renamedInfrastructureGroup + "/" + infrastructure.GroupVersion.Version
+	for _, node := range graph.getMoveNodes() {
+		oTo := &unstructured.Unstructured{}
+		oTo.SetAPIVersion(mover.targetAPIVersion(node.identity.APIVersion))
+		oTo.SetKind(node.identity.Kind)
+		g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: node.identity.Namespace, Name: node.identity.Name}, oTo)).To(Succeed())
+
+		if node.identity.GroupVersionKind().Group == infrastructure.GroupVersion.Group {
+			g.Expect(oTo.GetAPIVersion()).To(Equal(renamedAPIVersion))
+		}
+		if node.identity.Kind == "Cluster" {
+			apiVersion, _, err := unstructured.NestedString(oTo.Object, "spec", "infrastructureRef", "apiVersion")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(apiVersion).To(Equal(renamedAPIVersion))
+		}
+		for _, ref := range oTo.GetOwnerReferences() {
+			owner := &unstructured.Unstructured{}
+			owner.SetAPIVersion(ref.APIVersion)
+			owner.SetKind(ref.Kind)
+			g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: node.identity.Namespace, Name: ref.Name}, owner)).To(Succeed())
+			g.Expect(ref.UID).To(Equal(owner.GetUID()))
+		}
+	}
+}
+
+func Test_objectMover_setTargetAPIGroups(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+		"kind":       "GenericInfrastructureMachineTemplate",
+		"spec": map[string]interface{}{
+			"identityRef": map[string]interface{}{
+				"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+				"kind":       "GenericClusterInfrastructureIdentity",
+				"name":       "foo",
+			},
+			"refs": []interface{}{
+				map[string]interface{}{
+					"apiGroup": "infrastructure.cluster.x-k8s.io",
+					"kind":     "GenericInfrastructureCluster",
+					"name":     "foo",
+				},
+				map[string]interface{}{
+					"apiVersion": "cluster.x-k8s.io/v1alpha4",
+					"kind":       "Cluster",
+					"name":       "foo",
+				},
+			},
+			// Not a reference (should not be changed)
+			"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+		},
+	}}
+
+	o := &objectMover{apiGroupMappings: map[string]string{"infrastructure.cluster.x-k8s.io": "infrastructure.example.com"}}
+	o.setTargetAPIGroups(obj)
+
+	g.Expect(obj.Object).To(Equal(map[string]interface{}{
+		"apiVersion": "infrastructure.example.com/v1alpha4",
+		"kind":       "GenericInfrastructureMachineTemplate",
+		"spec": map[string]interface{}{
+			"identityRef": map[string]interface{}{
+				"apiVersion": "infrastructure.example.com/v1alpha4",
+				"kind":       "GenericClusterInfrastructureIdentity",
+				"name":       "foo",
+			},
+			"refs": []interface{}{
+				map[string]interface{}{
+					"apiGroup": "infrastructure.example.com",
+					"kind":     "GenericInfrastructureCluster",
+					"name":     "foo",
+				},
+				map[string]interface{}{
+					"apiVersion": "cluster.x-k8s.io/v1alpha4",
+					"kind":       "Cluster",
+					"name":       "foo",
+				},
+			},
+			"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+		},
+	}))
+}
+
+func Test_validateAPIGroupMappings(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(validateAPIGroupMappings(nil)).To(Succeed())
+	g.Expect(validateAPIGroupMappings(map[string]string{"infrastructure.cluster.x-k8s.io": "infrastructure.example.com"})).To(Succeed())
+	g.Expect(validateAPIGroupMappings(map[string]string{"": "infrastructure.example.com"})).NotTo(Succeed())
+	g.Expect(validateAPIGroupMappings(map[string]string{"infrastructure.cluster.x-k8s.io": ""})).NotTo(Succeed())
+}
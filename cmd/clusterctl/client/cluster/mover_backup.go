@@ -0,0 +1,570 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// backupHeaderFile is the name of the file describing a backup, saved in the backup directory together with the objects.
+	backupHeaderFile = "clusterctl-backup.json"
+
+	// argon2idKDF identifies the argon2id key derivation function.
+	argon2idKDF = "argon2id"
+
+	// backupSaltSize is the size of the random salt generated for each encrypted backup.
+	backupSaltSize = 16
+)
+
+// backupHeader describes a backup; it is saved in the backup directory, and it is required for restoring encrypted backups.
+type backupHeader struct {
+	// Encryption defines how the key for encrypting Secrets is derived from the encryption key, if Secrets are encrypted.
+	Encryption *backupEncryption `json:"encryption,omitempty"`
+}
+
+// backupEncryption defines the parameters of the key derivation function used for deriving the key for encrypting Secrets
+// from the encryption key; a random salt is generated for each backup, so passphrases can't be attacked using precomputed keys.
+type backupEncryption struct {
+	KDF  string `json:"kdf"`
+	Salt []byte `json:"salt"`
+	// Time is the number of passes over the memory.
+	Time uint32 `json:"time"`
+	// Memory is the size of the memory, in KiB.
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// newBackupEncryption returns the key derivation parameters for a new backup, using the argon2id parameters
+// recommended by RFC 9106 for memory-constrained environments.
+func newBackupEncryption() (*backupEncryption, error) {
+	salt := make([]byte, backupSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate the salt for encrypting Secrets")
+	}
+	return &backupEncryption{
+		KDF:     argon2idKDF,
+		Salt:    salt,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}, nil
+}
+
+// validate checks the key derivation parameters read from a backup, bounding the resources required for deriving the key.
+func (e *backupEncryption) validate() error {
+	switch {
+	case e.KDF != argon2idKDF:
+		return errors.Errorf("unsupported key derivation function %q", e.KDF)
+	case len(e.Salt) < backupSaltSize:
+		return errors.Errorf("the salt must be at least %d bytes", backupSaltSize)
+	case e.Time < 1 || e.Time > 100:
+		return errors.Errorf("invalid time parameter %d", e.Time)
+	case e.Memory < 8*uint32(e.Threads) || e.Memory > 4*1024*1024:
+		return errors.Errorf("invalid memory parameter %d", e.Memory)
+	case e.Threads < 1:
+		return errors.Errorf("invalid threads parameter %d", e.Threads)
+	}
+	return nil
+}
+
+// Backup saves all the Cluster API objects existing in a namespace (or from all the namespaces if empty) to a directory,
+// one YAML file for each object.
+func (o *objectMover) Backup(namespace string, directory string, options ...MoveOption) (int, error) {
+	log := logf.Log
+	log.Info("Performing backup...")
+	for _, opt := range options {
+		opt(o)
+	}
+	o.movedObjects = 0
+
+	if o.redactSecrets && o.encryptionKey != "" {
+		return 0, errors.New("secrets can't be redacted and encrypted at the same time")
+	}
+
+	objectGraph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	// Check whether nodes are not included in GVK considered for backup.
+	objectGraph.checkVirtualNode()
+
+	err = o.backup(objectGraph, directory)
+	return o.movedObjects, err
+}
+
+// Restore creates in the target management cluster all the Cluster API objects previously saved in a directory.
+func (o *objectMover) Restore(toCluster Client, directory string, options ...MoveOption) (int, error) {
+	log := logf.Log
+	log.Info("Performing restore...")
+	for _, opt := range options {
+		opt(o)
+	}
+	o.movedObjects = 0
+
+	objectGraph := newObjectGraph(toCluster.Proxy(), toCluster.ProviderInventory())
+
+	// Gets all the types defined by the CRDs installed by clusterctl in the target management cluster plus the ConfigMap/Secret core types.
+	if err := objectGraph.getDiscoveryTypes(); err != nil {
+		return 0, errors.Wrap(err, "failed to retrieve discovery types")
+	}
+
+	// Builds the object graph from the objects saved in the directory.
+	if err := o.readBackup(objectGraph, directory); err != nil {
+		return 0, err
+	}
+
+	// Check whether nodes are not included in GVK considered for restore.
+	objectGraph.checkVirtualNode()
+
+	err := o.restore(objectGraph, toCluster.Proxy())
+	return o.movedObjects, err
+}
+
+// backup saves the objects in the object graph to a directory.
+func (o *objectMover) backup(graph *objectGraph, directory string) error {
+	log := logf.Log
+
+	clusters := graph.getMoveClusters()
+	log.Info("Saving Cluster API objects", "Clusters", len(clusters), "Directory", directory)
+
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create the backup directory %q", directory)
+	}
+
+	// Sets the pause field on the Cluster object in the source management cluster, so the objects are saved in a consistent state.
+	// NB. Clusters already paused are left as they are, so they are saved, and then restored, paused.
+	unpausedClusters, err := o.getUnpausedClusters(clusters)
+	if err != nil {
+		return err
+	}
+	log.V(1).Info("Pausing the source cluster")
+	if err := setClusterPause(o.fromProxy, unpausedClusters, true, false); err != nil {
+		return err
+	}
+
+	err = o.writeBackup(graph, directory, unpausedClusters)
+
+	// Reset the pause field on the Cluster object in the source management cluster, no matter if saving the objects failed or not.
+	log.V(1).Info("Resuming the source cluster")
+	if resumeErr := setClusterPause(o.fromProxy, unpausedClusters, false, false); resumeErr != nil {
+		return kerrors.NewAggregate([]error{err, resumeErr})
+	}
+	return err
+}
+
+// getUnpausedClusters returns the Clusters which are not paused in the source management cluster.
+func (o *objectMover) getUnpausedClusters(clusters []*node) ([]*node, error) {
+	unpausedClusters := []*node{}
+	readClusterBackoff := newReadBackoff()
+	for i := range clusters {
+		cluster := clusters[i]
+		clusterObj := &clusterv1.Cluster{}
+		if err := retryWithExponentialBackoff(readClusterBackoff, func() error {
+			return getClusterObj(o.fromProxy, cluster, clusterObj)
+		}); err != nil {
+			return nil, err
+		}
+		if !clusterObj.Spec.Paused {
+			unpausedClusters = append(unpausedClusters, cluster)
+		}
+	}
+	return unpausedClusters, nil
+}
+
+// writeBackup writes a YAML file in the directory for each object in the object graph; pausedClusters are the Clusters
+// paused by the backup, which are saved unpaused.
+func (o *objectMover) writeBackup(graph *objectGraph, directory string, pausedClusters []*node) error {
+	log := logf.Log
+
+	pausedByBackup := map[*node]bool{}
+	for _, cluster := range pausedClusters {
+		pausedByBackup[cluster] = true
+	}
+
+	cFrom, err := o.fromProxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	header := &backupHeader{}
+	var gcm cipher.AEAD
+	if o.encryptionKey != "" && !o.redactSecrets {
+		header.Encryption, err = newBackupEncryption()
+		if err != nil {
+			return err
+		}
+		gcm, err = newBackupCipher(o.encryptionKey, header.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeBackupHeader(directory, header); err != nil {
+		return err
+	}
+
+	readObjectBackoff := newReadBackoff()
+	for _, n := range graph.getMoveNodes() {
+		log.V(1).Info("Saving", n.identity.Kind, n.identity.Name, "Namespace", n.identity.Namespace)
+
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(n.identity.APIVersion)
+		obj.SetKind(n.identity.Kind)
+		objKey := client.ObjectKey{
+			Namespace: n.identity.Namespace,
+			Name:      n.identity.Name,
+		}
+		if err := retryWithExponentialBackoff(readObjectBackoff, func() error {
+			if err := cFrom.Get(ctx, objKey, obj); err != nil {
+				return errors.Wrapf(err, "error reading %q %s/%s",
+					obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// Drops the fields that can't be restored.
+		// NB. the UID is preserved, because it is required for rebuilding the object graph from OwnerReferences when restoring.
+		obj.SetResourceVersion("")
+		obj.SetManagedFields(nil)
+
+		// Saves the Clusters paused by the backup as they were before.
+		if pausedByBackup[n] {
+			if err := unstructured.SetNestedField(obj.Object, false, "spec", "paused"); err != nil {
+				return errors.Wrapf(err, "failed to unpause Cluster %s", namespacedName(n.identity))
+			}
+		}
+
+		if isSecret(obj) {
+			if err := o.protectSecret(obj, gcm); err != nil {
+				return err
+			}
+		}
+
+		data, err := utilyaml.FromUnstructured([]unstructured.Unstructured{*obj})
+		if err != nil {
+			return errors.Wrapf(err, "failed to serialize %q %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+		}
+
+		path := filepath.Join(directory, backupFileName(n.identity))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return errors.Wrapf(err, "failed to write %q", path)
+		}
+		o.movedObjects++
+	}
+	return nil
+}
+
+// readBackup adds to the object graph all the objects saved in a directory.
+func (o *objectMover) readBackup(graph *objectGraph, directory string) error {
+	log := logf.Log
+
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the backup directory %q", directory)
+	}
+
+	header, err := readBackupHeader(directory)
+	if err != nil {
+		return err
+	}
+	var gcm cipher.AEAD
+	if header.Encryption != nil && o.encryptionKey != "" {
+		if err := header.Encryption.validate(); err != nil {
+			return errors.Wrapf(err, "invalid encryption parameters in %q", filepath.Join(directory, backupHeaderFile))
+		}
+		gcm, err = newBackupCipher(o.encryptionKey, header.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".yaml" {
+			continue
+		}
+
+		path := filepath.Join(directory, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %q", path)
+		}
+
+		objs, err := utilyaml.ToUnstructured(data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse %q", path)
+		}
+
+		for i := range objs {
+			obj := objs[i]
+			if obj.GetUID() == "" {
+				return errors.Errorf("%q %s/%s in %q does not have a UID; only objects saved by clusterctl can be restored", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName(), path)
+			}
+
+			if isSecret(&obj) {
+				skip, err := o.unprotectSecret(&obj, gcm)
+				if err != nil {
+					return err
+				}
+				if skip {
+					log.Info("Skipping redacted Secret, it should be re-created manually", "Secret", obj.GetName(), "Namespace", obj.GetNamespace())
+					continue
+				}
+			}
+
+			graph.addRestoredObj(&obj)
+		}
+	}
+
+	log.V(1).Info("Total objects", "Count", len(graph.uidToNode))
+
+	// Completes the graph by searching for soft ownership relations such as secrets linked to the cluster
+	// by a naming convention (without any explicit OwnerReference).
+	graph.setSoftOwnership()
+
+	// Completes the graph by setting for each node the list of tenants the node belongs to.
+	graph.setTenants()
+
+	return nil
+}
+
+// restore creates the objects in the object graph, as read from a backup directory, in the target management cluster.
+func (o *objectMover) restore(graph *objectGraph, toProxy Proxy) error {
+	log := logf.Log
+
+	clusters := graph.getMoveClusters()
+	log.Info("Restoring Cluster API objects", "Clusters", len(clusters))
+
+	// Ensures Clusters are created paused, so the controllers in the target management cluster do not start reconciling
+	// them before all the objects are restored.
+	// NB. Clusters saved paused are restored paused.
+	unpausedClusters := []*node{}
+	for _, cluster := range clusters {
+		if cluster.restoreObject == nil {
+			continue
+		}
+		paused, _, err := unstructured.NestedBool(cluster.restoreObject.Object, "spec", "paused")
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the pause field of Cluster %s", namespacedName(cluster.identity))
+		}
+		if !paused {
+			unpausedClusters = append(unpausedClusters, cluster)
+		}
+		if err := unstructured.SetNestedField(cluster.restoreObject.Object, true, "spec", "paused"); err != nil {
+			return errors.Wrapf(err, "failed to pause Cluster %s", namespacedName(cluster.identity))
+		}
+	}
+
+	// Each object is created in the target management cluster.
+	o.progress = newMoveProgress(o.progressFunc, len(graph.getMoveNodes()))
+
+	// Ensure all the expected target namespaces are in place before creating objects.
+	log.V(1).Info("Creating target namespaces, if missing")
+	if err := o.ensureNamespaces(graph, toProxy); err != nil {
+		return err
+	}
+
+	// Create all objects group by group, ensuring all the ownerReferences are re-created.
+	log.Info("Creating objects in the target cluster")
+	moveSequence := getMoveSequence(graph)
+	for groupIndex := 0; groupIndex < len(moveSequence.groups); groupIndex++ {
+		if err := o.createGroup(moveSequence.getGroup(groupIndex), toProxy); err != nil {
+			return err
+		}
+	}
+
+	// Reset the pause field on the Cluster object in the target management cluster, so the controllers start reconciling it.
+	log.V(1).Info("Resuming the target cluster")
+	return setClusterPause(toProxy, unpausedClusters, false, false)
+}
+
+// protectSecret redacts or encrypts the data of a Secret, according to the backup options; gcm is the cipher
+// for encrypting Secrets, if Secrets are encrypted.
+func (o *objectMover) protectSecret(obj *unstructured.Unstructured, gcm cipher.AEAD) error {
+	if o.redactSecrets {
+		unstructured.RemoveNestedField(obj.Object, "data")
+		unstructured.RemoveNestedField(obj.Object, "stringData")
+		setAnnotation(obj, clusterctlv1.BackupRedactedAnnotation)
+		return nil
+	}
+
+	if gcm == nil {
+		return nil
+	}
+
+	data, _, err := unstructured.NestedStringMap(obj.Object, "data")
+	if err != nil {
+		return errors.Wrapf(err, "failed to read data from Secret %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+	for k, v := range data {
+		value, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode key %q of Secret %s/%s", k, obj.GetNamespace(), obj.GetName())
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return errors.Wrap(err, "failed to generate a nonce")
+		}
+		data[k] = base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, value, nil))
+	}
+	if err := unstructured.SetNestedStringMap(obj.Object, data, "data"); err != nil {
+		return errors.Wrapf(err, "failed to set data for Secret %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+	setAnnotation(obj, clusterctlv1.BackupEncryptedAnnotation)
+	return nil
+}
+
+// unprotectSecret decrypts the data of a Secret read from a backup directory, if encrypted.
+// It returns true if the Secret was redacted, and thus it can't be restored; gcm is the cipher for decrypting Secrets,
+// if the encryption key is provided.
+func (o *objectMover) unprotectSecret(obj *unstructured.Unstructured, gcm cipher.AEAD) (bool, error) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[clusterctlv1.BackupRedactedAnnotation]; ok {
+		return true, nil
+	}
+
+	if _, ok := annotations[clusterctlv1.BackupEncryptedAnnotation]; !ok {
+		return false, nil
+	}
+
+	if o.encryptionKey == "" {
+		return false, errors.Errorf("Secret %s/%s is encrypted, please provide the encryption key used for the backup", obj.GetNamespace(), obj.GetName())
+	}
+	if gcm == nil {
+		return false, errors.Errorf("Secret %s/%s is encrypted, but the backup does not define the encryption parameters in %s", obj.GetNamespace(), obj.GetName(), backupHeaderFile)
+	}
+
+	data, _, err := unstructured.NestedStringMap(obj.Object, "data")
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read data from Secret %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+	for k, v := range data {
+		encrypted, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(encrypted) < gcm.NonceSize() {
+			return false, errors.Errorf("failed to decode key %q of Secret %s/%s", k, obj.GetNamespace(), obj.GetName())
+		}
+
+		nonce, ciphertext := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+		value, err := gcm.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return false, errors.Errorf("failed to decrypt key %q of Secret %s/%s, please check the encryption key", k, obj.GetNamespace(), obj.GetName())
+		}
+		data[k] = base64.StdEncoding.EncodeToString(value)
+	}
+	if err := unstructured.SetNestedStringMap(obj.Object, data, "data"); err != nil {
+		return false, errors.Wrapf(err, "failed to set data for Secret %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+
+	delete(annotations, clusterctlv1.BackupEncryptedAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	return false, nil
+}
+
+// newBackupCipher returns the AES-GCM cipher used for encrypting Secrets, using a 256-bit key derived from the encryption key
+// with the key derivation parameters of the backup.
+func newBackupCipher(encryptionKey string, encryption *backupEncryption) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(encryptionKey), encryption.Salt, encryption.Time, encryption.Memory, encryption.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the cipher for encrypting Secrets")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the cipher for encrypting Secrets")
+	}
+	return gcm, nil
+}
+
+// writeBackupHeader saves the header of a backup in the backup directory.
+func writeBackupHeader(directory string, header *backupHeader) error {
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the backup header")
+	}
+	path := filepath.Join(directory, backupHeaderFile)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return errors.Wrapf(err, "failed to write %q", path)
+	}
+	return nil
+}
+
+// readBackupHeader reads the header of a backup from the backup directory; an empty header is returned for backups
+// without a header, which can be restored only if Secrets are not encrypted.
+func readBackupHeader(directory string) (*backupHeader, error) {
+	header := &backupHeader{}
+	path := filepath.Join(directory, backupHeaderFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return header, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read %q", path)
+	}
+	if err := json.Unmarshal(data, header); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q", path)
+	}
+	return header, nil
+}
+
+// backupFileName returns the name of the file an object is saved to, e.g. cluster.cluster.x-k8s.io_ns1_foo.yaml.
+// NB. object names can't contain underscores, so the file name is unique for each object.
+func backupFileName(ref corev1.ObjectReference) string {
+	parts := []string{strings.ToLower(ref.GroupVersionKind().GroupKind().String())}
+	if ref.Namespace != "" {
+		parts = append(parts, ref.Namespace)
+	}
+	parts = append(parts, ref.Name)
+	return strings.Join(parts, "_") + ".yaml"
+}
+
+func isSecret(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == corev1.SchemeGroupVersion.WithKind("Secret").GroupKind()
+}
+
+func setAnnotation(obj *unstructured.Unstructured, annotation string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotation] = ""
+	obj.SetAnnotations(annotations)
+}
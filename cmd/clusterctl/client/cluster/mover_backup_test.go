@@ -0,0 +1,356 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_objectMover_backupAndRestore(t *testing.T) {
+	type fields struct {
+		redactSecrets bool
+		encryptionKey string
+	}
+	tests := []struct {
+		name              string
+		fields            fields
+		restoreKey        string
+		wantRestoreErr    bool
+		wantSecretsMasked bool
+		wantSecrets       bool
+	}{
+		{
+			name:              "Secrets saved in clear text",
+			fields:            fields{},
+			wantSecretsMasked: false,
+			wantSecrets:       true,
+		},
+		{
+			name: "Secrets encrypted",
+			fields: fields{
+				encryptionKey: "secret-key",
+			},
+			restoreKey:        "secret-key",
+			wantSecretsMasked: true,
+			wantSecrets:       true,
+		},
+		{
+			name: "Secrets encrypted, restore with the wrong key",
+			fields: fields{
+				encryptionKey: "secret-key",
+			},
+			restoreKey:        "wrong-key",
+			wantSecretsMasked: true,
+			wantRestoreErr:    true,
+		},
+		{
+			name: "Secrets encrypted, restore without a key",
+			fields: fields{
+				encryptionKey: "secret-key",
+			},
+			wantSecretsMasked: true,
+			wantRestoreErr:    true,
+		},
+		{
+			name: "Secrets redacted",
+			fields: fields{
+				redactSecrets: true,
+			},
+			wantSecretsMasked: true,
+			wantSecrets:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			objs := test.NewFakeCluster("ns1", "foo").Objs()
+
+			// Adds some data to the Secrets, so it is possible to check they are protected as expected.
+			for _, o := range objs {
+				if secret, ok := o.(*corev1.Secret); ok {
+					secret.Data = map[string][]byte{
+						"tls.crt": []byte("cert-" + secret.Name),
+						"tls.key": []byte("key-" + secret.Name),
+					}
+				}
+			}
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(objs)
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			dir := t.TempDir()
+			mover := objectMover{
+				fromProxy:     graph.proxy,
+				redactSecrets: tt.fields.redactSecrets,
+				encryptionKey: tt.fields.encryptionKey,
+			}
+			g.Expect(mover.backup(graph, dir)).To(Succeed())
+			g.Expect(mover.movedObjects).To(Equal(len(graph.getMoveNodes())))
+
+			// Check the header stores the key derivation parameters, if Secrets are encrypted.
+			header, err := readBackupHeader(dir)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.fields.encryptionKey != "" {
+				g.Expect(header.Encryption).NotTo(BeNil())
+				g.Expect(header.Encryption.KDF).To(Equal(argon2idKDF))
+				g.Expect(header.Encryption.Salt).To(HaveLen(backupSaltSize))
+				g.Expect(header.Encryption.validate()).To(Succeed())
+			} else {
+				g.Expect(header.Encryption).To(BeNil())
+			}
+
+			// Check a file exists for each object, and the Secrets are protected as expected.
+			csFrom, err := graph.proxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for _, n := range graph.getMoveNodes() {
+				data, err := os.ReadFile(filepath.Join(dir, backupFileName(n.identity)))
+				g.Expect(err).NotTo(HaveOccurred())
+
+				if n.identity.Kind != "Secret" {
+					continue
+				}
+
+				secret := &corev1.Secret{}
+				g.Expect(csFrom.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}, secret)).To(Succeed())
+				for _, v := range secret.Data {
+					if tt.wantSecretsMasked {
+						g.Expect(string(data)).ToNot(ContainSubstring(base64.StdEncoding.EncodeToString(v)))
+						continue
+					}
+					g.Expect(string(data)).To(ContainSubstring(base64.StdEncoding.EncodeToString(v)))
+				}
+			}
+
+			// Check the source Clusters are not left paused.
+			for _, cluster := range graph.getClusters() {
+				obj := &unstructured.Unstructured{}
+				obj.SetAPIVersion(cluster.identity.APIVersion)
+				obj.SetKind(cluster.identity.Kind)
+				g.Expect(csFrom.Get(ctx, client.ObjectKey{Namespace: cluster.identity.Namespace, Name: cluster.identity.Name}, obj)).To(Succeed())
+				paused, _, err := unstructured.NestedBool(obj.Object, "spec", "paused")
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(paused).To(BeFalse())
+			}
+
+			// Restore the objects into an empty cluster with all the required CRDs.
+			toProxy := getFakeProxyWithCRDs()
+			restoreGraph := newObjectGraph(toProxy, nil)
+			g.Expect(getFakeDiscoveryTypes(restoreGraph)).To(Succeed())
+
+			restoreMover := objectMover{
+				encryptionKey: tt.restoreKey,
+			}
+			err = restoreMover.readBackup(restoreGraph, dir)
+			if tt.wantRestoreErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(restoreMover.restore(restoreGraph, toProxy)).To(Succeed())
+			g.Expect(restoreMover.movedObjects).To(Equal(len(restoreGraph.getMoveNodes())))
+
+			// Check the objects are restored in the target cluster.
+			csTo, err := toProxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for _, n := range graph.getMoveNodes() {
+				key := client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}
+
+				if n.identity.Kind == "Secret" {
+					secret := &corev1.Secret{}
+					err := csTo.Get(ctx, key, secret)
+					if !tt.wantSecrets {
+						g.Expect(err).To(HaveOccurred())
+						continue
+					}
+					g.Expect(err).NotTo(HaveOccurred())
+
+					sourceSecret := &corev1.Secret{}
+					g.Expect(csFrom.Get(ctx, key, sourceSecret)).To(Succeed())
+					g.Expect(secret.Data).To(Equal(sourceSecret.Data))
+					g.Expect(secret.Annotations).To(Equal(sourceSecret.Annotations))
+					continue
+				}
+
+				obj := &unstructured.Unstructured{}
+				obj.SetAPIVersion(n.identity.APIVersion)
+				obj.SetKind(n.identity.Kind)
+				g.Expect(csTo.Get(ctx, key, obj)).To(Succeed())
+
+				// Check the Clusters are not left paused.
+				if n.identity.Kind == "Cluster" {
+					paused, _, err := unstructured.NestedBool(obj.Object, "spec", "paused")
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(paused).To(BeFalse())
+				}
+
+				// Check OwnerReferences are restored using the UIDs of the objects in the target cluster.
+				for _, ref := range obj.GetOwnerReferences() {
+					owner := &unstructured.Unstructured{}
+					owner.SetAPIVersion(ref.APIVersion)
+					owner.SetKind(ref.Kind)
+					g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: ref.Name}, owner)).To(Succeed())
+					g.Expect(ref.UID).To(Equal(owner.GetUID()))
+				}
+			}
+		})
+	}
+}
+
+func Test_objectMover_backupAndRestore_pausedClusters(t *testing.T) {
+	g := NewWithT(t)
+
+	// Create a source cluster with a Cluster already paused and a Cluster not paused.
+	objs := []client.Object{}
+	objs = append(objs, test.NewFakeCluster("ns1", "foo").Objs()...)
+	objs = append(objs, test.NewFakeCluster("ns1", "bar").Objs()...)
+	for _, o := range objs {
+		if c, ok := o.(*clusterv1.Cluster); ok && c.Name == "foo" {
+			c.Spec.Paused = true
+		}
+	}
+	graph := getObjectGraphWithObjs(objs)
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+	g.Expect(graph.Discovery("")).To(Succeed())
+
+	dir := t.TempDir()
+	mover := objectMover{
+		fromProxy: graph.proxy,
+	}
+	g.Expect(mover.backup(graph, dir)).To(Succeed())
+
+	// Check the Clusters are left in the source cluster, and saved, as they were before the backup.
+	wantPaused := map[string]bool{"foo": true, "bar": false}
+	csFrom, err := graph.proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	for name, paused := range wantPaused {
+		cluster := &clusterv1.Cluster{}
+		g.Expect(csFrom.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: name}, cluster)).To(Succeed())
+		g.Expect(cluster.Spec.Paused).To(Equal(paused), "Cluster %s in the source cluster", name)
+	}
+
+	// Restore the objects into an empty cluster with all the required CRDs.
+	toProxy := getFakeProxyWithCRDs()
+	restoreGraph := newObjectGraph(toProxy, nil)
+	g.Expect(getFakeDiscoveryTypes(restoreGraph)).To(Succeed())
+
+	restoreMover := objectMover{}
+	g.Expect(restoreMover.readBackup(restoreGraph, dir)).To(Succeed())
+	g.Expect(restoreMover.restore(restoreGraph, toProxy)).To(Succeed())
+
+	// Check only the Clusters not paused when saved are unpaused in the target cluster.
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	for name, paused := range wantPaused {
+		cluster := &clusterv1.Cluster{}
+		g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: name}, cluster)).To(Succeed())
+		g.Expect(cluster.Spec.Paused).To(Equal(paused), "Cluster %s in the target cluster", name)
+	}
+}
+
+func Test_backupFileName(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(backupFileName(corev1.ObjectReference{APIVersion: "cluster.x-k8s.io/v1alpha4", Kind: "Cluster", Namespace: "ns1", Name: "foo"})).To(Equal("cluster.cluster.x-k8s.io_ns1_foo.yaml"))
+	g.Expect(backupFileName(corev1.ObjectReference{APIVersion: "v1", Kind: "Secret", Namespace: "ns1", Name: "foo"})).To(Equal("secret_ns1_foo.yaml"))
+	g.Expect(backupFileName(corev1.ObjectReference{APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4", Kind: "GenericClusterIdentity", Name: "foo"})).To(Equal("genericclusteridentity.infrastructure.cluster.x-k8s.io_foo.yaml"))
+}
+
+func Test_newBackupCipher(t *testing.T) {
+	g := NewWithT(t)
+
+	encryption1, err := newBackupEncryption()
+	g.Expect(err).NotTo(HaveOccurred())
+	encryption2, err := newBackupEncryption()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Each backup uses a different salt, so the same passphrase derives different keys.
+	g.Expect(encryption1.Salt).NotTo(Equal(encryption2.Salt))
+
+	gcm1, err := newBackupCipher("secret-key", encryption1)
+	g.Expect(err).NotTo(HaveOccurred())
+	nonce := make([]byte, gcm1.NonceSize())
+	sealed := gcm1.Seal(nil, nonce, []byte("value"), nil)
+
+	// Round trip with the same passphrase and salt.
+	gcm, err := newBackupCipher("secret-key", encryption1)
+	g.Expect(err).NotTo(HaveOccurred())
+	value, err := gcm.Open(nil, nonce, sealed, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(value)).To(Equal("value"))
+
+	// A wrong passphrase, or the same passphrase with a different salt, fails.
+	gcm, err = newBackupCipher("wrong-key", encryption1)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = gcm.Open(nil, nonce, sealed, nil)
+	g.Expect(err).To(HaveOccurred())
+
+	gcm, err = newBackupCipher("secret-key", encryption2)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = gcm.Open(nil, nonce, sealed, nil)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_backupEncryption_validate(t *testing.T) {
+	valid := func() *backupEncryption {
+		return &backupEncryption{KDF: argon2idKDF, Salt: make([]byte, backupSaltSize), Time: 3, Memory: 64 * 1024, Threads: 4}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(e *backupEncryption)
+		wantErr bool
+	}{
+		{name: "valid parameters", mutate: func(e *backupEncryption) {}},
+		{name: "unsupported KDF", mutate: func(e *backupEncryption) { e.KDF = "sha256" }, wantErr: true},
+		{name: "short salt", mutate: func(e *backupEncryption) { e.Salt = []byte("salt") }, wantErr: true},
+		{name: "no passes", mutate: func(e *backupEncryption) { e.Time = 0 }, wantErr: true},
+		{name: "too much memory", mutate: func(e *backupEncryption) { e.Memory = 1 << 30 }, wantErr: true},
+		{name: "no threads", mutate: func(e *backupEncryption) { e.Threads = 0 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			e := valid()
+			tt.mutate(e)
+			err := e.validate()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
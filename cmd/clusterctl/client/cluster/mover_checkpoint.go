@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// moveCheckpointNamePrefix is the prefix of the name of the ConfigMap used for tracking the progress of a move operation.
+	moveCheckpointNamePrefix = "clusterctl-move-"
+
+	// moveCheckpointAllNamespacesID is the move ID used when moving objects from all the namespaces.
+	moveCheckpointAllNamespacesID = "all-namespaces"
+
+	// moveCheckpointDataKey is the key in the ConfigMap data storing the list of objects already moved.
+	moveCheckpointDataKey = "objects"
+)
+
+// moveCheckpointEntry records an object already created in the target management cluster.
+type moveCheckpointEntry struct {
+	// NewUID is the UID the object got once created in the target management cluster.
+	NewUID types.UID `json:"newUID"`
+
+	// SpecHash is the hash of the object's content at the time of the creation in the target management cluster.
+	SpecHash string `json:"specHash"`
+}
+
+// moveCheckpoint keeps track of the objects already created in the target management cluster during a move operation,
+// so the operation can be resumed in case of failures.
+// The checkpoint is persisted in a ConfigMap in the source management cluster, keyed by a move ID derived from the namespace being moved.
+type moveCheckpoint struct {
+	proxy     Proxy
+	namespace string
+	name      string
+	entries   map[types.UID]moveCheckpointEntry
+	exists    bool
+	// lock protects entries, that are recorded concurrently when creating the objects of a move group.
+	lock sync.Mutex
+}
+
+func newMoveCheckpoint(proxy Proxy, namespace string) *moveCheckpoint {
+	id := namespace
+	checkpointNamespace := namespace
+	if namespace == "" {
+		id = moveCheckpointAllNamespacesID
+		checkpointNamespace = metav1.NamespaceDefault
+	}
+	return &moveCheckpoint{
+		proxy:     proxy,
+		namespace: checkpointNamespace,
+		name:      moveCheckpointNamePrefix + id,
+		entries:   map[types.UID]moveCheckpointEntry{},
+	}
+}
+
+// load reads the checkpoint from the source management cluster, if it exists.
+func (c *moveCheckpoint) load() error {
+	cs, err := c.proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: c.namespace, Name: c.name}
+	if err := cs.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			c.exists = false
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read the move checkpoint %s/%s", c.namespace, c.name)
+	}
+
+	c.exists = true
+	c.entries = map[types.UID]moveCheckpointEntry{}
+	if data, ok := cm.Data[moveCheckpointDataKey]; ok {
+		if err := json.Unmarshal([]byte(data), &c.entries); err != nil {
+			return errors.Wrapf(err, "failed to parse the move checkpoint %s/%s", c.namespace, c.name)
+		}
+	}
+	return nil
+}
+
+// save persists the checkpoint into the source management cluster.
+func (c *moveCheckpoint) save() error {
+	cs, err := c.proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the move checkpoint")
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      c.name,
+			Labels: map[string]string{
+				clusterctlv1.ClusterctlMoveCheckpointLabelName: "",
+			},
+		},
+		Data: map[string]string{
+			moveCheckpointDataKey: string(data),
+		},
+	}
+
+	if !c.exists {
+		if err := cs.Create(ctx, cm); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return errors.Wrapf(err, "failed to create the move checkpoint %s/%s", c.namespace, c.name)
+			}
+		} else {
+			c.exists = true
+			return nil
+		}
+	}
+
+	existing := &corev1.ConfigMap{}
+	if err := cs.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: c.name}, existing); err != nil {
+		return errors.Wrapf(err, "failed to read the move checkpoint %s/%s", c.namespace, c.name)
+	}
+	existing.Labels = cm.Labels
+	existing.Data = cm.Data
+	if err := cs.Update(ctx, existing); err != nil {
+		return errors.Wrapf(err, "failed to update the move checkpoint %s/%s", c.namespace, c.name)
+	}
+	c.exists = true
+	return nil
+}
+
+// delete removes the checkpoint from the source management cluster.
+func (c *moveCheckpoint) delete() error {
+	cs, err := c.proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      c.name,
+		},
+	}
+	if err := cs.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete the move checkpoint %s/%s", c.namespace, c.name)
+	}
+	c.exists = false
+	return nil
+}
+
+// record adds an object created in the target management cluster to the checkpoint.
+func (c *moveCheckpoint) record(sourceUID, newUID types.UID, specHash string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[sourceUID] = moveCheckpointEntry{NewUID: newUID, SpecHash: specHash}
+}
+
+// get returns the checkpoint entry for an object, if any.
+func (c *moveCheckpoint) get(sourceUID types.UID) (moveCheckpointEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[sourceUID]
+	return entry, ok
+}
+
+// getSpecHash returns a hash of the content of an object, ignoring metadata and status, that can be used
+// for verifying the object in the target management cluster matches the object in the source management cluster.
+func getSpecHash(obj *unstructured.Unstructured) (string, error) {
+	content := map[string]interface{}{}
+	for k, v := range obj.Object {
+		if k == "metadata" || k == "status" || k == "apiVersion" {
+			continue
+		}
+		content[k] = v
+	}
+
+	// NB. encoding/json marshals maps with sorted keys, so the output is deterministic.
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to compute hash for %q %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_moveCheckpoint_saveAndLoad(t *testing.T) {
+	g := NewWithT(t)
+
+	proxy := test.NewFakeProxy()
+
+	checkpoint := newMoveCheckpoint(proxy, "ns1")
+	g.Expect(checkpoint.load()).To(Succeed())
+	g.Expect(checkpoint.exists).To(BeFalse())
+
+	checkpoint.record(types.UID("source-uid-1"), types.UID("target-uid-1"), "hash1")
+	g.Expect(checkpoint.save()).To(Succeed())
+
+	checkpoint.record(types.UID("source-uid-2"), types.UID("target-uid-2"), "hash2")
+	g.Expect(checkpoint.save()).To(Succeed())
+
+	loaded := newMoveCheckpoint(proxy, "ns1")
+	g.Expect(loaded.load()).To(Succeed())
+	g.Expect(loaded.exists).To(BeTrue())
+	g.Expect(loaded.entries).To(HaveLen(2))
+
+	entry, ok := loaded.get(types.UID("source-uid-2"))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(entry.NewUID).To(Equal(types.UID("target-uid-2")))
+	g.Expect(entry.SpecHash).To(Equal("hash2"))
+
+	// checkpoints are keyed by namespace.
+	other := newMoveCheckpoint(proxy, "ns2")
+	g.Expect(other.load()).To(Succeed())
+	g.Expect(other.exists).To(BeFalse())
+
+	g.Expect(loaded.delete()).To(Succeed())
+	g.Expect(checkpoint.load()).To(Succeed())
+	g.Expect(checkpoint.exists).To(BeFalse())
+}
+
+func Test_getSpecHash(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cluster.x-k8s.io/v1alpha4",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":            "foo",
+				"namespace":       "ns1",
+				"uid":             "source-uid",
+				"resourceVersion": "1",
+			},
+			"spec": map[string]interface{}{
+				"paused": true,
+				"foo":    "bar",
+			},
+			"status": map[string]interface{}{
+				"phase": "Provisioned",
+			},
+		},
+	}
+
+	hash, err := getSpecHash(obj)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Changes to metadata and status are ignored.
+	other := obj.DeepCopy()
+	other.SetUID("target-uid")
+	other.SetResourceVersion("42")
+	other.Object["status"] = map[string]interface{}{}
+	otherHash, err := getSpecHash(other)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(otherHash).To(Equal(hash))
+
+	// Changes to spec are detected.
+	other.Object["spec"] = map[string]interface{}{"paused": false, "foo": "bar"}
+	otherHash, err = getSpecHash(other)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(otherHash).ToNot(Equal(hash))
+}
+
+func Test_objectMover_move_withCheckpoint(t *testing.T) {
+	g := NewWithT(t)
+
+	objs := test.NewFakeCluster("ns1", "foo").Objs()
+
+	// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+	graph := getObjectGraphWithObjs(objs)
+
+	// Get all the types to be considered for discovery
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+	// trigger discovery the content of the source cluster
+	g.Expect(graph.Discovery("")).To(Succeed())
+
+	// gets a fakeProxy to an empty cluster with all the required CRDs
+	toProxy := getFakeProxyWithCRDs()
+
+	// Simulates a move operation failed after creating the first two groups of objects in the target cluster.
+	mover := objectMover{
+		fromProxy:  graph.proxy,
+		checkpoint: newMoveCheckpoint(graph.proxy, "ns1"),
+	}
+	moveSequence := getMoveSequence(graph)
+	g.Expect(setClusterPause(graph.proxy, graph.getClusters(), true, false)).To(Succeed())
+	movedNodes := moveGroup{}
+	for i := 0; i < 2; i++ {
+		g.Expect(mover.createGroup(moveSequence.getGroup(i), toProxy)).To(Succeed())
+		movedNodes = append(movedNodes, moveSequence.getGroup(i)...)
+	}
+	g.Expect(mover.checkpoint.save()).To(Succeed())
+
+	// Gets the UID of the objects already moved.
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	movedUIDs := map[types.UID]types.UID{}
+	movedResourceVersions := map[types.UID]string{}
+	for _, n := range movedNodes {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(n.identity.APIVersion)
+		obj.SetKind(n.identity.Kind)
+		g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}, obj)).To(Succeed())
+		movedUIDs[n.identity.UID] = obj.GetUID()
+		movedResourceVersions[n.identity.UID] = obj.GetResourceVersion()
+	}
+
+	// A new move without resume should fail because of the existing checkpoint.
+	newMover := objectMover{
+		fromProxy: graph.proxy,
+	}
+	g.Expect(newMover.initCheckpoint("ns1")).ToNot(Succeed())
+
+	// Resume the move.
+	resumeMover := objectMover{
+		fromProxy: graph.proxy,
+		resume:    true,
+	}
+	g.Expect(resumeMover.initCheckpoint("ns1")).To(Succeed())
+	g.Expect(resumeMover.checkpoint.entries).To(HaveLen(len(movedNodes)))
+
+	// Reset the UIDs computed by the first move, so we can check they are restored from the checkpoint.
+	for _, n := range graph.uidToNode {
+		n.newUID = ""
+	}
+
+	g.Expect(resumeMover.move(graph, toProxy)).To(Succeed())
+
+	// Check only the objects created while resuming are counted.
+	g.Expect(resumeMover.movedObjects).To(Equal(len(graph.getMoveNodes()) - len(movedNodes)))
+
+	// Check the objects already moved were not re-created nor updated.
+	for sourceUID, targetUID := range movedUIDs {
+		n := graph.uidToNode[sourceUID]
+		g.Expect(n.newUID).To(Equal(targetUID))
+
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(n.identity.APIVersion)
+		obj.SetKind(n.identity.Kind)
+		g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}, obj)).To(Succeed())
+
+		// NB. Clusters are patched at the end of move for resetting the paused field.
+		if obj.GetKind() == "Cluster" {
+			continue
+		}
+		g.Expect(obj.GetResourceVersion()).To(Equal(movedResourceVersions[sourceUID]))
+	}
+
+	// Check all the objects are created in the target cluster.
+	for _, n := range graph.getMoveNodes() {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(n.identity.APIVersion)
+		obj.SetKind(n.identity.Kind)
+		g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}, obj)).To(Succeed())
+	}
+
+	// Check the checkpoint tracks all the objects moved.
+	g.Expect(resumeMover.checkpoint.entries).To(HaveLen(len(graph.getMoveNodes())))
+}
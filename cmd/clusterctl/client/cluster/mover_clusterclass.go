@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterClassKind is the kind of the objects defining the topology of the Clusters with a managed topology.
+// NB. ClusterClasses are handled as unstructured objects, so Clusters and ClusterClasses served by a newer version
+// of the Cluster API CRDs can be moved as well.
+const clusterClassKind = "ClusterClass"
+
+// isClusterClass returns true if the node is a ClusterClass.
+func (n *node) isClusterClass() bool {
+	return n.identity.GroupVersionKind().GroupKind() == clusterv1.GroupVersion.WithKind(clusterClassKind).GroupKind()
+}
+
+// isClusterClassHierarchy returns true if the node is a ClusterClass or one of the templates referenced by a ClusterClass.
+func (n *node) isClusterClassHierarchy() bool {
+	if n.isClusterClass() {
+		return true
+	}
+	if n.identity.GroupVersionKind().GroupKind() == clusterv1.GroupVersion.WithKind("Cluster").GroupKind() {
+		return false
+	}
+	for owner := range n.softOwners {
+		if owner.isClusterClass() {
+			return true
+		}
+	}
+	return false
+}
+
+// setClusterClassInfo reads from a Cluster the name of the ClusterClass defining its topology, and from a ClusterClass
+// the references to its templates, so they can be linked in the object graph.
+func (o *objectGraph) setClusterClassInfo(obj *unstructured.Unstructured, n *node) {
+	switch {
+	case n.identity.GroupVersionKind().GroupKind() == clusterv1.GroupVersion.WithKind("Cluster").GroupKind():
+		n.clusterClass, _, _ = unstructured.NestedString(obj.Object, "spec", "topology", "class")
+	case n.isClusterClass():
+		n.clusterClassReferences = findObjectReferences(obj.Object["spec"])
+	}
+}
+
+// getClusterClasses returns the list of ClusterClasses existing in the object graph.
+func (o *objectGraph) getClusterClasses() []*node {
+	clusterClasses := []*node{}
+	for _, node := range o.uidToNode {
+		if !node.virtual && node.isClusterClass() {
+			clusterClasses = append(clusterClasses, node)
+		}
+	}
+	return clusterClasses
+}
+
+// getClusterClass returns the node for the ClusterClass used by a Cluster, if it exists in the object graph.
+func (o *objectGraph) getClusterClass(cluster *node) *node {
+	if cluster.clusterClass == "" {
+		return nil
+	}
+	return o.getNodeByReference(corev1.ObjectReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       clusterClassKind,
+		Name:       cluster.clusterClass,
+	}, cluster.identity.Namespace)
+}
+
+// setClusterClassSoftOwnership links the Clusters to the ClusterClass they use, and the ClusterClasses to the templates they
+// reference, so the ClusterClass is created in the target management cluster before the Clusters and the templates.
+func (o *objectGraph) setClusterClassSoftOwnership() {
+	for _, cluster := range o.getClusters() {
+		if clusterClass := o.getClusterClass(cluster); clusterClass != nil {
+			cluster.addSoftOwner(clusterClass)
+		}
+	}
+
+	for _, clusterClass := range o.getClusterClasses() {
+		for _, ref := range clusterClass.clusterClassReferences {
+			if template := o.getNodeByReference(ref, clusterClass.identity.Namespace); template != nil {
+				template.addSoftOwner(clusterClass)
+			}
+		}
+	}
+}
+
+// getClusterClassHierarchy returns the ClusterClass used by a Cluster together with the templates it references, if
+// the ClusterClass exists in the object graph.
+func (o *objectGraph) getClusterClassHierarchy(cluster *node) []*node {
+	clusterClass := o.getClusterClass(cluster)
+	if clusterClass == nil {
+		return nil
+	}
+	hierarchy := []*node{clusterClass}
+	for _, other := range o.getNodes() {
+		if other != cluster && other.isSoftOwnedBy(clusterClass) && other.isClusterClassHierarchy() {
+			hierarchy = append(hierarchy, other)
+		}
+	}
+	return hierarchy
+}
+
+// setClusterClassTenants adds the tenants of the Clusters to the ClusterClass they use and to its templates, so they are moved
+// together with the Clusters.
+// NB. The Clusters are soft-owned by the ClusterClass, but they are not part of its hierarchy, otherwise all the Clusters
+// using a ClusterClass would become tenants of each other.
+func (o *objectGraph) setClusterClassTenants() {
+	for _, cluster := range o.getClusters() {
+		for _, n := range o.getClusterClassHierarchy(cluster) {
+			for tenant := range cluster.tenant {
+				n.tenant[tenant] = empty{}
+			}
+		}
+	}
+}
+
+// checkTargetClusterClasses checks that the ClusterClass used by each of the Clusters to be moved is either moved together
+// with the Cluster or it already exists in the target management cluster; otherwise the Cluster can't be reconciled after the move.
+func (o *objectMover) checkTargetClusterClasses(graph *objectGraph, toProxy Proxy) error {
+	if o.dryRun {
+		return nil
+	}
+
+	clusters := []*node{}
+	for _, n := range graph.getMoveNodes() {
+		if n.clusterClass != "" && n.identity.GroupVersionKind().GroupKind() == clusterv1.GroupVersion.WithKind("Cluster").GroupKind() {
+			clusters = append(clusters, n)
+		}
+	}
+	if len(clusters) == 0 {
+		return nil
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return nodeSortKey(clusters[i]) < nodeSortKey(clusters[j])
+	})
+
+	cTo, err := toProxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	errList := []error{}
+	readTargetObjectBackoff := newReadBackoff()
+	for _, cluster := range clusters {
+		// NB. A ClusterClass existing in the source cluster is moved together with the Clusters using it.
+		if graph.getClusterClass(cluster) != nil {
+			continue
+		}
+
+		exists := false
+		key := client.ObjectKey{Namespace: o.targetNamespace(cluster), Name: cluster.clusterClass}
+		if err := retryWithExponentialBackoff(readTargetObjectBackoff, func() error {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(clusterv1.GroupVersion.WithKind(clusterClassKind))
+			if err := cTo.Get(ctx, key, obj); err != nil {
+				if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+					exists = false
+					return nil
+				}
+				return errors.Wrapf(err, "error reading ClusterClass %s/%s from the target cluster", key.Namespace, key.Name)
+			}
+			exists = true
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if !exists {
+			errList = append(errList, errors.Errorf("Cluster %s uses the ClusterClass %s, which exists neither in the source cluster nor in the target cluster",
+				namespacedName(cluster.identity), key.String()))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	fakeinfrastructure "sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test/providers/infrastructure"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	clusterClassUID = "cluster.x-k8s.io/v1alpha4, Kind=ClusterClass, ns1/class1"
+	templateUID     = "infrastructure.cluster.x-k8s.io/v1alpha4, Kind=GenericInfrastructureMachineTemplate, ns1/class1-workers"
+)
+
+// newFakeClusterClass returns a ClusterClass referencing a worker infrastructure template, together with the template.
+func newFakeClusterClass(namespace, name string) []client.Object {
+	template := newFakeUnstructured(fakeinfrastructure.GroupVersion.String(), "GenericInfrastructureMachineTemplate", namespace, fmt.Sprintf("%s-workers", name))
+
+	clusterClass := newFakeUnstructured(clusterv1.GroupVersion.String(), clusterClassKind, namespace, name)
+	clusterClass.Object["spec"] = map[string]interface{}{
+		"workers": map[string]interface{}{
+			"machineDeployments": []interface{}{
+				map[string]interface{}{
+					"class": "default-worker",
+					"template": map[string]interface{}{
+						"infrastructure": map[string]interface{}{
+							"ref": map[string]interface{}{
+								"apiVersion": template.GetAPIVersion(),
+								"kind":       template.GetKind(),
+								"name":       template.GetName(),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return []client.Object{clusterClass, template}
+}
+
+// newFakeTopologyCluster returns the objects for a Cluster with a managed topology defined by a ClusterClass.
+func newFakeTopologyCluster(namespace, name, clusterClass string, clusterLabels map[string]string) []client.Object {
+	objs := []client.Object{}
+	for _, o := range test.NewFakeCluster(namespace, name).WithLabels(clusterLabels).Objs() {
+		u := &unstructured.Unstructured{}
+		if err := test.FakeScheme.Convert(o, u, nil); err != nil {
+			panic(err)
+		}
+		if u.GetKind() == "Cluster" {
+			u.Object["spec"].(map[string]interface{})["topology"] = map[string]interface{}{
+				"class":   clusterClass,
+				"version": "v1.21.2",
+			}
+		}
+		objs = append(objs, u)
+	}
+	return objs
+}
+
+func newFakeUnstructured(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetUID(types.UID(fmt.Sprintf("%s, Kind=%s, %s/%s", apiVersion, kind, namespace, name)))
+	return u
+}
+
+func Test_objectGraph_clusterClass(t *testing.T) {
+	tests := []struct {
+		name             string
+		selector         labels.Selector
+		objs             []client.Object
+		wantMoved        bool
+		wantKeptInSource bool
+	}{
+		{
+			name:      "Moves the ClusterClass and its templates with the Cluster",
+			objs:      append(newFakeClusterClass("ns1", "class1"), newFakeTopologyCluster("ns1", "foo", "class1", nil)...),
+			wantMoved: true,
+		},
+		{
+			name: "Moves the ClusterClass shared by multiple Clusters",
+			objs: append(append(newFakeClusterClass("ns1", "class1"),
+				newFakeTopologyCluster("ns1", "foo", "class1", nil)...),
+				newFakeTopologyCluster("ns1", "bar", "class1", nil)...),
+			wantMoved: true,
+		},
+		{
+			name:     "Copies the ClusterClass shared by Clusters not matching the label selector",
+			selector: labels.SelectorFromSet(labels.Set{"move": "true"}),
+			objs: append(append(newFakeClusterClass("ns1", "class1"),
+				newFakeTopologyCluster("ns1", "foo", "class1", map[string]string{"move": "true"})...),
+				newFakeTopologyCluster("ns1", "bar", "class1", nil)...),
+			wantMoved:        true,
+			wantKeptInSource: true,
+		},
+		{
+			name:      "Does not move a ClusterClass not used by any Cluster",
+			objs:      append(newFakeClusterClass("ns1", "class1"), newFakeTopologyCluster("ns1", "foo", "class2", nil)...),
+			wantMoved: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			graph, err := getDetachedObjectGraphWihObjs(tt.objs)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.selector != nil {
+				graph.selector = tt.selector
+				for _, o := range tt.objs {
+					graph.uidToNode[o.GetUID()].selected = tt.selector.Matches(labels.Set(o.GetLabels()))
+				}
+			}
+
+			graph.setSoftOwnership()
+			graph.setTenants()
+			g.Expect(graph.checkSelection()).To(Succeed())
+
+			clusterClass := graph.uidToNode[clusterClassUID]
+			template := graph.uidToNode[templateUID]
+			g.Expect(template.isSoftOwnedBy(clusterClass)).To(BeTrue())
+
+			moved := map[types.UID]bool{}
+			for _, n := range graph.getMoveNodes() {
+				moved[n.identity.UID] = true
+			}
+			g.Expect(moved[clusterClassUID]).To(Equal(tt.wantMoved))
+			g.Expect(moved[templateUID]).To(Equal(tt.wantMoved))
+			g.Expect(clusterClass.keepInSource).To(Equal(tt.wantKeptInSource))
+
+			for _, cluster := range graph.getClusters() {
+				g.Expect(cluster.isSoftOwnedBy(clusterClass)).To(Equal(cluster.clusterClass == "class1"))
+			}
+
+			// All the nodes to be moved are in the move sequence, and the ClusterClass is created before the Clusters using it.
+			moveSequence := getMoveSequence(graph)
+			g.Expect(moveSequence.nodesMap).To(HaveLen(len(moved)))
+			if tt.wantMoved {
+				g.Expect(moveSequence.getGroup(0)).To(ContainElement(clusterClass))
+			}
+		})
+	}
+}
+
+func Test_objectMover_checkTargetClusterClasses(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		toProxy Proxy
+		wantErr bool
+	}{
+		{
+			name:    "Passes if the ClusterClass is moved with the Cluster",
+			objs:    append(newFakeClusterClass("ns1", "class1"), newFakeTopologyCluster("ns1", "foo", "class1", nil)...),
+			toProxy: test.NewFakeProxy(),
+			wantErr: false,
+		},
+		{
+			name:    "Passes if the ClusterClass already exists in the target cluster",
+			objs:    newFakeTopologyCluster("ns1", "foo", "class1", nil),
+			toProxy: test.NewFakeProxy().WithObjs(newFakeClusterClass("ns1", "class1")...),
+			wantErr: false,
+		},
+		{
+			name:    "Passes for Clusters without a managed topology",
+			objs:    test.NewFakeCluster("ns1", "foo").Objs(),
+			toProxy: test.NewFakeProxy(),
+			wantErr: false,
+		},
+		{
+			name:    "Fails if the ClusterClass exists neither in the source nor in the target cluster",
+			objs:    newFakeTopologyCluster("ns1", "foo", "class1", nil),
+			toProxy: test.NewFakeProxy(),
+			wantErr: true,
+		},
+		{
+			name:    "Fails if the ClusterClass exists in the target cluster in another namespace",
+			objs:    newFakeTopologyCluster("ns1", "foo", "class1", nil),
+			toProxy: test.NewFakeProxy().WithObjs(newFakeClusterClass("ns2", "class1")...),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			graph, err := getDetachedObjectGraphWihObjs(tt.objs)
+			g.Expect(err).NotTo(HaveOccurred())
+			graph.setSoftOwnership()
+			graph.setTenants()
+
+			o := &objectMover{}
+			err = o.checkTargetClusterClasses(graph, tt.toProxy)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kubeversion "k8s.io/apimachinery/pkg/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// checkTargetContract checks that the Cluster API contract implemented by the target management cluster is compatible
+// with the contract implemented by the source management cluster; the contract is defined by the storage version of
+// the Cluster CRD.
+// Supported transitions are:
+//   - moving to a target cluster implementing the same contract.
+//   - moving to a target cluster implementing a newer contract, as long as the target cluster still serves the API version
+//     used by the source cluster; in this case objects are created using this API version, and then they are converted
+//     to the newer one by the conversion webhooks installed in the target cluster.
+//
+// Moving to a target cluster implementing an older contract is not supported, because objects could use fields not
+// existing in the older API version.
+func (o *objectMover) checkTargetContract(toProxy Proxy) error {
+	if o.dryRun {
+		return nil
+	}
+
+	fromCRD, err := getClusterCRD(o.fromProxy)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the contract of the source cluster")
+	}
+	toCRD, err := getClusterCRD(toProxy)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the contract of the target cluster")
+	}
+
+	// If the Cluster CRD is missing, there is nothing to check here; a missing CRD in the target cluster is reported by checkTargetTypes.
+	if fromCRD == nil || toCRD == nil {
+		return nil
+	}
+
+	fromContract := storageVersion(fromCRD)
+	toContract := storageVersion(toCRD)
+	if fromContract == toContract {
+		return nil
+	}
+
+	if kubeversion.CompareKubeAwareVersionStrings(fromContract, toContract) > 0 {
+		return errors.Errorf("moving from the %s contract to the %s contract is not supported: the target cluster implements an older contract than the source cluster", fromContract, toContract)
+	}
+
+	for _, v := range toCRD.Spec.Versions {
+		if v.Name == fromContract && v.Served {
+			return nil
+		}
+	}
+	return errors.Errorf("moving from the %s contract to the %s contract is not supported: the target cluster does not serve the %s API version anymore, so there is no conversion path for the objects", fromContract, toContract, fromContract)
+}
+
+// getClusterCRD returns the Cluster CRD installed in a management cluster, if any.
+func getClusterCRD(proxy Proxy) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		return getCRDList(proxy, crdList)
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range crdList.Items {
+		crd := &crdList.Items[i]
+		if crd.Spec.Group == clusterv1.GroupVersion.Group && crd.Spec.Names.Kind == "Cluster" {
+			return crd, nil
+		}
+	}
+	return nil, nil
+}
+
+// storageVersion returns the storage version of a CRD.
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_objectMover_checkTargetContract(t *testing.T) {
+	proxyWithClusterCRD := func(versions ...string) Proxy {
+		return test.NewFakeProxy().WithObjs(test.FakeNamespacedCustomResourceDefinition(clusterv1.GroupVersion.Group, "Cluster", versions...))
+	}
+
+	tests := []struct {
+		name      string
+		fromProxy Proxy
+		toProxy   Proxy
+		wantErr   bool
+	}{
+		{
+			name:      "same contract",
+			fromProxy: proxyWithClusterCRD("v1alpha4"),
+			toProxy:   proxyWithClusterCRD("v1alpha4"),
+			wantErr:   false,
+		},
+		{
+			name:      "newer contract in the target cluster, serving the source API version",
+			fromProxy: proxyWithClusterCRD("v1alpha3"),
+			toProxy:   proxyWithClusterCRD("v1alpha4", "v1alpha3"),
+			wantErr:   false,
+		},
+		{
+			name:      "newer contract in the target cluster, not serving the source API version",
+			fromProxy: proxyWithClusterCRD("v1alpha3"),
+			toProxy:   proxyWithClusterCRD("v1beta1"),
+			wantErr:   true,
+		},
+		{
+			name:      "older contract in the target cluster",
+			fromProxy: proxyWithClusterCRD("v1alpha4", "v1alpha3"),
+			toProxy:   proxyWithClusterCRD("v1alpha3"),
+			wantErr:   true,
+		},
+		{
+			name:      "Cluster CRD missing in the target cluster",
+			fromProxy: proxyWithClusterCRD("v1alpha4"),
+			toProxy:   test.NewFakeProxy(),
+			wantErr:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			o := &objectMover{
+				fromProxy: tt.fromProxy,
+			}
+			err := o.checkTargetContract(tt.toProxy)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// MovePhase defines a phase of a move operation.
+type MovePhase string
+
+const (
+	// MovePausePhase is the phase pausing the Clusters in the source management cluster.
+	MovePausePhase MovePhase = "Pause"
+
+	// MoveCreatePhase is the phase creating the objects in the target management cluster.
+	MoveCreatePhase MovePhase = "CreateOnTarget"
+
+	// MoveDeletePhase is the phase deleting the objects from the source management cluster.
+	MoveDeletePhase MovePhase = "DeleteFromSource"
+
+	// MoveUnpausePhase is the phase resuming the Clusters in the target management cluster.
+	MoveUnpausePhase MovePhase = "Unpause"
+)
+
+// MoveObjectError describes the failure of a move operation on a single object.
+type MoveObjectError struct {
+	// Phase is the phase of the move operation that failed.
+	Phase MovePhase
+
+	// Object is the object that failed, as it is identified in the source management cluster; for the unpause phase,
+	// it is the Cluster in the target management cluster.
+	Object corev1.ObjectReference
+
+	// Err is the error reported for the object.
+	Err error
+}
+
+func (e *MoveObjectError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error reported for the object.
+func (e *MoveObjectError) Unwrap() error {
+	return e.Err
+}
+
+// MoveError is returned by Move when some objects fail in a phase of the move operation; it lists all the objects
+// that failed, so the operator can recover them one by one.
+type MoveError struct {
+	// Failures lists the objects that failed, in the order they were processed.
+	Failures []MoveObjectError
+}
+
+func (e *MoveError) Error() string {
+	errList := make([]error, 0, len(e.Failures))
+	for i := range e.Failures {
+		errList = append(errList, &e.Failures[i])
+	}
+	return kerrors.NewAggregate(errList).Error()
+}
+
+// FailuresInPhase returns the objects that failed in a phase of the move operation.
+func (e *MoveError) FailuresInPhase(phase MovePhase) []MoveObjectError {
+	failures := []MoveObjectError{}
+	for _, f := range e.Failures {
+		if f.Phase == phase {
+			failures = append(failures, f)
+		}
+	}
+	return failures
+}
+
+// FrozenObjects returns the Clusters that were moved to the target management cluster but failed to be resumed,
+// and so are still paused there; they must be resumed manually by setting Cluster.Spec.Paused=false.
+func (e *MoveError) FrozenObjects() []corev1.ObjectReference {
+	frozen := []corev1.ObjectReference{}
+	for _, f := range e.FailuresInPhase(MoveUnpausePhase) {
+		frozen = append(frozen, f.Object)
+	}
+	return frozen
+}
+
+// newMoveError returns a MoveError collecting the MoveObjectErrors in err, which can be a single MoveObjectError or
+// an aggregate of MoveObjectErrors; any other error is returned unchanged.
+func newMoveError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	errList := []error{err}
+	if agg, ok := err.(kerrors.Aggregate); ok {
+		errList = agg.Errors()
+	}
+
+	moveErr := &MoveError{}
+	for _, e := range errList {
+		objErr, ok := e.(*MoveObjectError)
+		if !ok {
+			return err
+		}
+		moveErr.Failures = append(moveErr.Failures, *objErr)
+	}
+	return moveErr
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func Test_newMoveError(t *testing.T) {
+	cluster1 := corev1.ObjectReference{Kind: "Cluster", Namespace: "ns1", Name: "cluster1"}
+	cluster2 := corev1.ObjectReference{Kind: "Cluster", Namespace: "ns1", Name: "cluster2"}
+
+	tests := []struct {
+		name          string
+		err           error
+		wantMoveError bool
+		wantFailures  int
+		wantFrozen    []corev1.ObjectReference
+	}{
+		{
+			name:          "nil error",
+			err:           nil,
+			wantMoveError: false,
+		},
+		{
+			name:          "errors not related to objects are returned unchanged",
+			err:           kerrors.NewAggregate([]error{errors.New("failed to save checkpoint")}),
+			wantMoveError: false,
+		},
+		{
+			name:          "single object error",
+			err:           &MoveObjectError{Phase: MoveCreatePhase, Object: cluster1, Err: errors.New("create failed")},
+			wantMoveError: true,
+			wantFailures:  1,
+			wantFrozen:    []corev1.ObjectReference{},
+		},
+		{
+			name: "partial unpause",
+			err: kerrors.NewAggregate([]error{
+				&MoveObjectError{Phase: MoveUnpausePhase, Object: cluster1, Err: errors.New("unpause failed")},
+				&MoveObjectError{Phase: MoveUnpausePhase, Object: cluster2, Err: errors.New("unpause failed")},
+			}),
+			wantMoveError: true,
+			wantFailures:  2,
+			wantFrozen:    []corev1.ObjectReference{cluster1, cluster2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := newMoveError(tt.err)
+			if tt.err == nil {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+
+			moveErr := &MoveError{}
+			if !tt.wantMoveError {
+				g.Expect(errors.As(err, &moveErr)).To(BeFalse())
+				g.Expect(err).To(Equal(tt.err))
+				return
+			}
+
+			g.Expect(errors.As(err, &moveErr)).To(BeTrue())
+			g.Expect(moveErr.Failures).To(HaveLen(tt.wantFailures))
+			g.Expect(moveErr.FrozenObjects()).To(Equal(tt.wantFrozen))
+			g.Expect(moveErr.Error()).To(Equal(tt.err.Error()))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (o *objectMover) Pause(namespace, clusterName string) error {
+	log := logf.Log
+	log.Info("Pausing Cluster", "Cluster", clusterName, "Namespace", namespace)
+
+	graph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return err
+	}
+	return o.pauseCluster(graph, namespace, clusterName)
+}
+
+func (o *objectMover) pauseCluster(graph *objectGraph, namespace, clusterName string) error {
+	log := logf.Log
+
+	nodes, err := getPauseNodes(graph, namespace, clusterName)
+	if err != nil {
+		return err
+	}
+
+	// Pauses the objects, starting from the Cluster; if pausing any of the objects fails, the objects already
+	// paused are resumed, so the Cluster is not left partially paused.
+	paused := []*node{}
+	for _, n := range nodes {
+		ok, err := pauseObject(o.fromProxy, n)
+		if err != nil {
+			errList := []error{err}
+			for i := len(paused) - 1; i >= 0; i-- {
+				if _, err := resumeObject(o.fromProxy, paused[i]); err != nil {
+					errList = append(errList, errors.Wrap(err, "failed to rollback pausing the Cluster"))
+				}
+			}
+			return kerrors.NewAggregate(errList)
+		}
+		if ok {
+			paused = append(paused, n)
+		}
+	}
+	log.V(1).Info("Paused objects", "Count", len(paused))
+	return nil
+}
+
+func (o *objectMover) Resume(namespace, clusterName string) error {
+	log := logf.Log
+	log.Info("Resuming Cluster", "Cluster", clusterName, "Namespace", namespace)
+
+	graph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return err
+	}
+	return o.resumeCluster(graph, namespace, clusterName)
+}
+
+func (o *objectMover) resumeCluster(graph *objectGraph, namespace, clusterName string) error {
+	log := logf.Log
+
+	nodes, err := getPauseNodes(graph, namespace, clusterName)
+	if err != nil {
+		return err
+	}
+
+	// Resumes the objects in reverse order, so the Cluster is resumed last.
+	errList := []error{}
+	resumed := 0
+	for i := len(nodes) - 1; i >= 0; i-- {
+		ok, err := resumeObject(o.fromProxy, nodes[i])
+		if err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		if ok {
+			resumed++
+		}
+	}
+	log.V(1).Info("Resumed objects", "Count", resumed)
+	return kerrors.NewAggregate(errList)
+}
+
+// getPauseNodes returns the Cluster with the given name and the objects belonging to it that can be paused, i.e.
+// excluding core objects like Secrets and ConfigMaps, with the Cluster first.
+func getPauseNodes(graph *objectGraph, namespace, clusterName string) ([]*node, error) {
+	cluster, err := graph.getCluster(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*node{}
+	for _, n := range graph.uidToNode {
+		if n == cluster || n.virtual || n.identity.GroupVersionKind().Group == "" {
+			continue
+		}
+		if _, ok := n.tenant[cluster]; ok {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeSortKey(nodes[i]) < nodeSortKey(nodes[j])
+	})
+	return append([]*node{cluster}, nodes...), nil
+}
+
+// pauseObject pauses an object, setting Cluster.Spec.Paused for Clusters and the paused annotation for the other objects,
+// and it marks the object as paused by clusterctl. Objects already paused are not changed, and false is returned.
+func pauseObject(proxy Proxy, n *node) (bool, error) {
+	obj, err := getPauseObject(proxy, n)
+	if err != nil {
+		return false, err
+	}
+
+	patch := map[string]interface{}{}
+	if isClusterNode(n) {
+		if paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused"); paused {
+			return false, nil
+		}
+		patch["spec"] = map[string]interface{}{"paused": true}
+		patch["metadata"] = map[string]interface{}{"annotations": map[string]interface{}{
+			clusterctlv1.PausedByClusterctlAnnotation: "",
+		}}
+	} else {
+		if _, paused := obj.GetAnnotations()[clusterv1.PausedAnnotation]; paused {
+			return false, nil
+		}
+		patch["metadata"] = map[string]interface{}{"annotations": map[string]interface{}{
+			clusterv1.PausedAnnotation:                "",
+			clusterctlv1.PausedByClusterctlAnnotation: "",
+		}}
+	}
+
+	logf.Log.V(5).Info("Pausing", "Kind", n.identity.Kind, "Name", n.identity.Name, "Namespace", n.identity.Namespace)
+	return true, patchPauseObject(proxy, obj, patch)
+}
+
+// resumeObject resumes an object previously paused by pauseObject; objects not paused by clusterctl are not changed, and false is returned.
+func resumeObject(proxy Proxy, n *node) (bool, error) {
+	obj, err := getPauseObject(proxy, n)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := obj.GetAnnotations()[clusterctlv1.PausedByClusterctlAnnotation]; !ok {
+		return false, nil
+	}
+
+	patch := map[string]interface{}{}
+	if isClusterNode(n) {
+		patch["spec"] = map[string]interface{}{"paused": false}
+		patch["metadata"] = map[string]interface{}{"annotations": map[string]interface{}{
+			clusterctlv1.PausedByClusterctlAnnotation: nil,
+		}}
+	} else {
+		patch["metadata"] = map[string]interface{}{"annotations": map[string]interface{}{
+			clusterv1.PausedAnnotation:                nil,
+			clusterctlv1.PausedByClusterctlAnnotation: nil,
+		}}
+	}
+
+	logf.Log.V(5).Info("Resuming", "Kind", n.identity.Kind, "Name", n.identity.Name, "Namespace", n.identity.Namespace)
+	return true, patchPauseObject(proxy, obj, patch)
+}
+
+func isClusterNode(n *node) bool {
+	return n.identity.GroupVersionKind().GroupKind() == clusterv1.GroupVersion.WithKind("Cluster").GroupKind()
+}
+
+// getPauseObject reads the object referred by a node.
+func getPauseObject(proxy Proxy, n *node) (*unstructured.Unstructured, error) {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(n.identity.APIVersion)
+	obj.SetKind(n.identity.Kind)
+	key := client.ObjectKey{
+		Namespace: n.identity.Namespace,
+		Name:      n.identity.Name,
+	}
+
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		return c.Get(ctx, key, obj)
+	}); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s %s", n.identity.Kind, namespacedName(n.identity))
+	}
+	return obj, nil
+}
+
+// patchPauseObject applies a merge patch to an object.
+func patchPauseObject(proxy Proxy, obj *unstructured.Unstructured, patch map[string]interface{}) error {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	rawPatch, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the patch")
+	}
+
+	if err := retryWithExponentialBackoff(newWriteBackoff(), func() error {
+		return c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, rawPatch))
+	}); err != nil {
+		return errors.Wrapf(err, "error patching %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	return nil
+}
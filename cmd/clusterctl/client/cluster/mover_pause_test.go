@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_objectMover_pauseCluster_resumeCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	objs := []client.Object{}
+	objs = append(objs, test.NewFakeCluster("ns1", "cluster1").WithMachines(test.NewFakeMachine("cluster1-m1")).Objs()...)
+	objs = append(objs, test.NewFakeCluster("ns1", "cluster2").Objs()...)
+
+	// Pauses an object belonging to cluster1 as a user would do, so it should not be resumed.
+	for _, o := range objs {
+		if o.GetObjectKind().GroupVersionKind().Kind == "GenericInfrastructureCluster" && o.GetName() == "cluster1" {
+			o.SetAnnotations(map[string]string{clusterv1.PausedAnnotation: ""})
+		}
+	}
+
+	graph := getObjectGraphWithObjs(objs)
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+	g.Expect(graph.Discovery("ns1")).To(Succeed())
+
+	mover := objectMover{
+		fromProxy: graph.proxy,
+	}
+
+	// getObjs returns the objects in the management cluster, indexed by kind and name.
+	getObjs := func() map[string]*unstructured.Unstructured {
+		ret := map[string]*unstructured.Unstructured{}
+		for _, n := range graph.uidToNode {
+			if n.virtual || n.identity.GroupVersionKind().Group == "" {
+				continue
+			}
+			obj, err := getPauseObject(graph.proxy, n)
+			g.Expect(err).NotTo(HaveOccurred())
+			ret[n.identity.Kind+"/"+n.identity.Name] = obj
+		}
+		return ret
+	}
+
+	g.Expect(mover.pauseCluster(graph, "ns1", "cluster1")).To(Succeed())
+	pausedMachine := false
+	for key, obj := range getObjs() {
+		_, pausedByClusterctl := obj.GetAnnotations()[clusterctlv1.PausedByClusterctlAnnotation]
+		_, pausedAnnotation := obj.GetAnnotations()[clusterv1.PausedAnnotation]
+		specPaused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused")
+
+		switch key {
+		case "Cluster/cluster1":
+			g.Expect(specPaused).To(BeTrue(), key)
+			g.Expect(pausedByClusterctl).To(BeTrue(), key)
+		case "GenericInfrastructureCluster/cluster1":
+			g.Expect(pausedAnnotation).To(BeTrue(), key)
+			g.Expect(pausedByClusterctl).To(BeFalse(), key)
+		case "Cluster/cluster2", "GenericInfrastructureCluster/cluster2":
+			g.Expect(specPaused).To(BeFalse(), key)
+			g.Expect(pausedAnnotation).To(BeFalse(), key)
+			g.Expect(pausedByClusterctl).To(BeFalse(), key)
+		case "Machine/cluster1-m1":
+			pausedMachine = pausedAnnotation && pausedByClusterctl
+		default:
+			g.Expect(pausedAnnotation).To(Equal(pausedByClusterctl), key)
+		}
+	}
+	g.Expect(pausedMachine).To(BeTrue())
+
+	g.Expect(mover.resumeCluster(graph, "ns1", "cluster1")).To(Succeed())
+	for key, obj := range getObjs() {
+		_, pausedByClusterctl := obj.GetAnnotations()[clusterctlv1.PausedByClusterctlAnnotation]
+		_, pausedAnnotation := obj.GetAnnotations()[clusterv1.PausedAnnotation]
+		specPaused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused")
+
+		g.Expect(pausedByClusterctl).To(BeFalse(), key)
+		g.Expect(specPaused).To(BeFalse(), key)
+		// The object paused by the user is not resumed.
+		g.Expect(pausedAnnotation).To(Equal(key == "GenericInfrastructureCluster/cluster1"), key)
+	}
+
+	g.Expect(mover.pauseCluster(graph, "ns1", "cluster3")).ToNot(Succeed())
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// largeObjectSize is the size above which an object to be moved is reported as unusually large; objects are still
+	// moved, but they are slow to transfer and they are close to the size limit of the requests accepted by etcd (1.5MiB).
+	largeObjectSize = 512 * 1024
+
+	// applyLatencySamples is the number of requests used for measuring the latency of the target management cluster.
+	applyLatencySamples = 3
+
+	// applyLatencyProbeName is the name of the Namespace used for measuring the latency of the target management cluster;
+	// the Namespace is never created, because it is sent with server-side dry-run requests.
+	applyLatencyProbeName = "clusterctl-move-plan-probe"
+)
+
+// MovePlan estimates the number of objects, their size and the time a move operation is going to require, e.g. for
+// sizing the maintenance window of a big migration.
+type MovePlan struct {
+	// ObjectsByKind is the number of objects to be moved, grouped by kind.
+	ObjectsByKind map[string]int
+
+	// ObjectsCount is the total number of objects to be moved.
+	ObjectsCount int
+
+	// SecretsSize is the total size, in bytes, of the Secrets to be moved, serialized as JSON.
+	SecretsSize int64
+
+	// ApplyLatency is the average latency of a request to the target management cluster, measured with server-side dry-run
+	// requests; if the target management cluster is not known, the latency of reading the objects from the source management
+	// cluster is used instead.
+	ApplyLatency time.Duration
+
+	// EstimatedDuration is the time the move operation is expected to require, derived from the number of requests required
+	// for moving each object and from ApplyLatency. Waiting for the objects to be ready for move is not included.
+	EstimatedDuration time.Duration
+
+	// Warnings contains the issues detected by the move dry-run, and the objects that are unusually large.
+	Warnings []string
+}
+
+func (o *objectMover) Plan(namespace string, toCluster Client, options ...MoveOption) (*MovePlan, error) {
+	log := logf.Log
+	log.Info("Performing move plan...")
+	for _, opt := range options {
+		opt(o)
+	}
+
+	objectGraph, err := o.getObjectGraph(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.checkToNamespaceCollisions(objectGraph, nil); err != nil {
+		return nil, err
+	}
+
+	// NB. Conflicts are not relevant for the estimate, so they are not checked.
+	report, err := o.buildMoveReport(objectGraph, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MovePlan{
+		ObjectsByKind: map[string]int{},
+		Warnings:      report.Warnings,
+	}
+	readLatency, err := o.measureObjects(objectGraph, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.ApplyLatency = readLatency
+	if toCluster != nil {
+		plan.ApplyLatency, err = measureApplyLatency(toCluster.Proxy())
+		if err != nil {
+			return nil, err
+		}
+	}
+	plan.EstimatedDuration = time.Duration(o.countMoveRequests(objectGraph)) * plan.ApplyLatency
+
+	return plan, nil
+}
+
+// measureObjects reads the objects to be moved from the source management cluster, adding their count and size to the plan;
+// it returns the average latency of the read requests.
+func (o *objectMover) measureObjects(graph *objectGraph, plan *MovePlan) (time.Duration, error) {
+	cFrom, err := o.fromProxy.NewClient()
+	if err != nil {
+		return 0, err
+	}
+
+	nodes := graph.getMoveNodes()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeSortKey(nodes[i]) < nodeSortKey(nodes[j])
+	})
+
+	var elapsed time.Duration
+	largeObjects := []string{}
+	readObjectBackoff := newReadBackoff()
+	for _, n := range nodes {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(n.identity.APIVersion)
+		obj.SetKind(n.identity.Kind)
+		key := client.ObjectKey{
+			Namespace: n.identity.Namespace,
+			Name:      n.identity.Name,
+		}
+
+		if err := retryWithExponentialBackoff(readObjectBackoff, func() error {
+			start := time.Now()
+			err := cFrom.Get(ctx, key, obj)
+			elapsed += time.Since(start)
+			if err != nil {
+				return errors.Wrapf(err, "error reading %q %s/%s", obj.GroupVersionKind(), key.Namespace, key.Name)
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to serialize %q %s/%s", obj.GroupVersionKind(), key.Namespace, key.Name)
+		}
+
+		plan.ObjectsByKind[n.identity.Kind]++
+		plan.ObjectsCount++
+		if n.identity.GroupVersionKind().GroupKind() == corev1.SchemeGroupVersion.WithKind("Secret").GroupKind() {
+			plan.SecretsSize += int64(len(data))
+		}
+		if len(data) > largeObjectSize {
+			largeObjects = append(largeObjects, fmt.Sprintf("%s %s is unusually large (%d bytes); it will slow down the move, and it is close to the size limit of the target management cluster",
+				n.identity.Kind, namespacedName(n.identity), len(data)))
+		}
+	}
+	plan.Warnings = append(plan.Warnings, largeObjects...)
+
+	if len(nodes) == 0 {
+		return 0, nil
+	}
+	return elapsed / time.Duration(len(nodes)), nil
+}
+
+// measureApplyLatency returns the average latency of creating an object in a management cluster, measured with server-side
+// dry-run requests, so nothing is created.
+func measureApplyLatency(proxy Proxy) (time.Duration, error) {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return 0, err
+	}
+
+	var elapsed time.Duration
+	for i := 0; i < applyLatencySamples; i++ {
+		probe := &corev1.Namespace{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: applyLatencyProbeName,
+			},
+		}
+		start := time.Now()
+		err := c.Create(ctx, probe, client.DryRunAll)
+		elapsed += time.Since(start)
+		// NB. A Namespace with the same name already existing does not affect the measure.
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return 0, errors.Wrap(err, "failed to measure the latency of the target management cluster")
+		}
+	}
+	return elapsed / applyLatencySamples, nil
+}
+
+// countMoveRequests returns the number of sequential requests required for moving the objects in the graph: each object is
+// read from the source and created in the target management cluster, with the objects in the same group created concurrently,
+// then each object to be deleted is read, its finalizers are removed, and it is deleted from the source management cluster.
+func (o *objectMover) countMoveRequests(graph *objectGraph) int {
+	workers := o.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	requests := 0
+	for _, group := range getMoveSequence(graph).groups {
+		rounds := (len(group) + workers - 1) / workers
+		requests += 2 * rounds
+		for _, n := range group {
+			if n.isGlobal || n.isGlobalHierarchy || n.keepInSource {
+				continue
+			}
+			requests += 3
+		}
+	}
+	return requests
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_objectMover_plan(t *testing.T) {
+	g := NewWithT(t)
+
+	objs := test.NewFakeCluster("ns1", "foo").Objs()
+	largeSecret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "foo-proxy",
+		},
+		Data: map[string][]byte{
+			"value": []byte(strings.Repeat("x", largeObjectSize)),
+		},
+	}
+	objs = append(objs, largeSecret)
+
+	// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+	graph := getObjectGraphWithObjs(objs)
+
+	// Get all the types to be considered for discovery
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+	// trigger discovery the content of the source cluster
+	g.Expect(graph.Discovery("")).To(Succeed())
+
+	mover := objectMover{
+		fromProxy: graph.proxy,
+	}
+
+	plan := &MovePlan{ObjectsByKind: map[string]int{}}
+	_, err := mover.measureObjects(graph, plan)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// check all the objects to be moved are counted by kind.
+	g.Expect(plan.ObjectsCount).To(Equal(len(graph.getMoveNodes())))
+	g.Expect(plan.ObjectsByKind).To(HaveKeyWithValue("Cluster", 1))
+	g.Expect(plan.ObjectsByKind).To(HaveKeyWithValue("Secret", 3))
+
+	// check the size of the Secrets includes the large one, which is reported as a warning.
+	g.Expect(plan.SecretsSize).To(BeNumerically(">", largeObjectSize))
+	g.Expect(plan.Warnings).To(HaveLen(1))
+	g.Expect(plan.Warnings[0]).To(ContainSubstring("Secret ns1/foo-proxy is unusually large"))
+
+	// check the latency of the target cluster is measured without creating objects.
+	toProxy := getFakeProxyWithCRDs()
+	_, err = measureApplyLatency(toProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	err = csTo.Get(ctx, client.ObjectKey{Name: applyLatencyProbeName}, &corev1.Namespace{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// check creating the objects concurrently requires less sequential requests.
+	requests := mover.countMoveRequests(graph)
+	g.Expect(requests).To(BeNumerically(">", 2*plan.ObjectsCount))
+
+	mover.concurrency = 10
+	g.Expect(mover.countMoveRequests(graph)).To(BeNumerically("<", requests))
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MoveReport describes the objects a move operation is going to transfer to the target management cluster.
+type MoveReport struct {
+	// Namespaces contains the objects to be moved, grouped by namespace.
+	// Global objects, if any, are grouped under an empty namespace.
+	Namespaces []MoveReportNamespace
+
+	// Conflicts contains the objects to be moved that already exist in the target management cluster.
+	Conflicts []corev1.ObjectReference
+
+	// Warnings contains the issues detected while validating the object graph that will likely
+	// prevent the move operation from succeeding or from leaving the objects in a consistent state.
+	Warnings []string
+}
+
+// MoveReportNamespace lists the objects to be moved from a namespace.
+type MoveReportNamespace struct {
+	// Namespace where the objects exist.
+	Namespace string
+
+	// Objects to be moved, listed in the same order they are going to be created in the target management cluster.
+	Objects []MoveReportObject
+}
+
+// MoveReportObject describes an object to be moved.
+type MoveReportObject struct {
+	// Object is the reference to the object to be moved.
+	Object corev1.ObjectReference
+
+	// OwnerChain lists the owners of the object, starting from the direct owner up to the
+	// root of the ownership hierarchy (e.g. Machine -> MachineSet -> MachineDeployment -> Cluster).
+	OwnerChain []corev1.ObjectReference
+
+	// KeptInSource is true if the object is copied to the target management cluster without being deleted from the source
+	// management cluster, e.g. the Cluster when moving only its control plane.
+	KeptInSource bool
+}
+
+// HasConflicts returns true if any of the objects to be moved already exists in the target management cluster.
+func (r *MoveReport) HasConflicts() bool {
+	return len(r.Conflicts) > 0
+}
+
+// ObjectsCount returns the total number of objects to be moved.
+func (r *MoveReport) ObjectsCount() int {
+	count := 0
+	for _, n := range r.Namespaces {
+		count += len(n.Objects)
+	}
+	return count
+}
+
+// buildMoveReport computes the MoveReport for the object graph. If toProxy is nil, checking for conflicts with
+// objects already existing in the target management cluster is skipped.
+func (o *objectMover) buildMoveReport(graph *objectGraph, toProxy Proxy) (*MoveReport, error) {
+	report := &MoveReport{}
+
+	// Group the objects by namespace, preserving the move sequence.
+	namespaces := map[string]*MoveReportNamespace{}
+	moveSequence := getMoveSequence(graph)
+	for _, group := range moveSequence.groups {
+		sortedGroup := make(moveGroup, len(group))
+		copy(sortedGroup, group)
+		sort.Slice(sortedGroup, func(i, j int) bool {
+			return nodeSortKey(sortedGroup[i]) < nodeSortKey(sortedGroup[j])
+		})
+
+		for _, n := range sortedGroup {
+			reportNamespace, ok := namespaces[n.identity.Namespace]
+			if !ok {
+				reportNamespace = &MoveReportNamespace{Namespace: n.identity.Namespace}
+				namespaces[n.identity.Namespace] = reportNamespace
+			}
+			reportNamespace.Objects = append(reportNamespace.Objects, MoveReportObject{
+				Object:       n.identity,
+				OwnerChain:   getOwnerChain(n),
+				KeptInSource: n.keepInSource,
+			})
+		}
+	}
+
+	namespaceNames := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		namespaceNames = append(namespaceNames, name)
+	}
+	sort.Strings(namespaceNames)
+	for _, name := range namespaceNames {
+		report.Namespaces = append(report.Namespaces, *namespaces[name])
+	}
+
+	// Validates ownership references.
+	for _, n := range graph.getMoveNodes() {
+		for owner := range n.owners {
+			if owner.virtual {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s %s has an OwnerReference to %s %s which is not included in the types considered for move",
+					n.identity.Kind, namespacedName(n.identity), owner.identity.Kind, owner.identity.Name))
+			}
+		}
+	}
+	sort.Strings(report.Warnings)
+
+	// Validates the objects are ready for move.
+	if err := o.checkProvisioningCompleted(graph); err != nil {
+		report.Warnings = append(report.Warnings, errorsToWarnings(err)...)
+	}
+
+	// Validates the clusters are not already paused; move is going to unpause them
+	// in the target management cluster.
+	warnings, err := o.checkClustersPause(graph)
+	if err != nil {
+		return nil, err
+	}
+	report.Warnings = append(report.Warnings, warnings...)
+
+	// Checks for conflicts with objects already existing in the target management cluster.
+	if toProxy != nil {
+		conflicts, err := o.checkTargetConflicts(graph.getMoveNodes(), toProxy)
+		if err != nil {
+			return nil, err
+		}
+		report.Conflicts = conflicts
+	}
+
+	return report, nil
+}
+
+// checkClustersPause returns a warning for each Cluster that is already paused in the source management cluster.
+func (o *objectMover) checkClustersPause(graph *objectGraph) ([]string, error) {
+	warnings := []string{}
+	readClusterBackoff := newReadBackoff()
+	clusters := graph.getMoveClusters()
+	sort.Slice(clusters, func(i, j int) bool {
+		return nodeSortKey(clusters[i]) < nodeSortKey(clusters[j])
+	})
+	for i := range clusters {
+		cluster := clusters[i]
+		clusterObj := &clusterv1.Cluster{}
+		if err := retryWithExponentialBackoff(readClusterBackoff, func() error {
+			return getClusterObj(o.fromProxy, cluster, clusterObj)
+		}); err != nil {
+			return nil, err
+		}
+
+		if clusterObj.Spec.Paused {
+			warnings = append(warnings, fmt.Sprintf("Cluster %s is already paused; it will be unpaused in the target management cluster at the end of the move", namespacedName(cluster.identity)))
+			continue
+		}
+
+		if _, ok := clusterObj.GetAnnotations()[clusterv1.PausedAnnotation]; ok {
+			warnings = append(warnings, fmt.Sprintf("Cluster %s has the %q annotation; the annotation will be moved to the target management cluster as well", namespacedName(cluster.identity), clusterv1.PausedAnnotation))
+		}
+	}
+	return warnings, nil
+}
+
+// checkTargetConflicts returns the objects corresponding to the nodes which already exist in the target management cluster.
+// Global objects and objects belonging to a global hierarchy are ignored, because move does not change them if they already exist.
+func (o *objectMover) checkTargetConflicts(nodes []*node, toProxy Proxy) ([]corev1.ObjectReference, error) {
+	cTo, err := toProxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	sortedNodes := make([]*node, len(nodes))
+	copy(sortedNodes, nodes)
+	sort.Slice(sortedNodes, func(i, j int) bool {
+		return nodeSortKey(sortedNodes[i]) < nodeSortKey(sortedNodes[j])
+	})
+
+	conflicts := []corev1.ObjectReference{}
+	readTargetObjectBackoff := newReadBackoff()
+	for _, n := range sortedNodes {
+		// NB. ClusterClasses already existing in the target management cluster are updated, because they can be shared
+		// with the Clusters already existing there.
+		if n.isGlobal || n.isGlobalHierarchy || n.isClusterClassHierarchy() {
+			continue
+		}
+
+		ref := n.identity
+		ref.Namespace = o.targetNamespace(n)
+		ref.APIVersion = o.targetAPIVersion(ref.APIVersion)
+
+		exists := false
+		if err := retryWithExponentialBackoff(readTargetObjectBackoff, func() error {
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion(ref.APIVersion)
+			obj.SetKind(ref.Kind)
+			key := client.ObjectKey{
+				Namespace: ref.Namespace,
+				Name:      ref.Name,
+			}
+			if err := cTo.Get(ctx, key, obj); err != nil {
+				// NB. the type might not be defined in the target cluster yet (e.g. a missing provider), and this is
+				// not a conflict; missing providers are reported by the target providers check.
+				if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+					exists = false
+					return nil
+				}
+				return errors.Wrapf(err, "error reading %q %s/%s from the target cluster", obj.GroupVersionKind(), ref.Namespace, ref.Name)
+			}
+			exists = true
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		if exists {
+			conflicts = append(conflicts, ref)
+		}
+	}
+	return conflicts, nil
+}
+
+// getOwnerChain returns the owners of a node, starting from the direct owner up to the root of the ownership hierarchy.
+// When a node has many owners, the controller owner is preferred, then the owner that sorts first.
+func getOwnerChain(n *node) []corev1.ObjectReference {
+	chain := []corev1.ObjectReference{}
+	visited := map[*node]bool{n: true}
+	current := n
+	for {
+		owner := getPreferredOwner(current)
+		if owner == nil || visited[owner] {
+			break
+		}
+		visited[owner] = true
+		chain = append(chain, owner.identity)
+		current = owner
+	}
+	return chain
+}
+
+func getPreferredOwner(n *node) *node {
+	var preferred *node
+	for owner, attributes := range n.owners {
+		if attributes.Controller != nil && *attributes.Controller {
+			return owner
+		}
+		if preferred == nil || nodeSortKey(owner) < nodeSortKey(preferred) {
+			preferred = owner
+		}
+	}
+	if preferred != nil {
+		return preferred
+	}
+	for owner := range n.softOwners {
+		if preferred == nil || nodeSortKey(owner) < nodeSortKey(preferred) {
+			preferred = owner
+		}
+	}
+	return preferred
+}
+
+func nodeSortKey(n *node) string {
+	return fmt.Sprintf("%s/%s/%s/%s", n.identity.Namespace, n.identity.GroupVersionKind().GroupKind(), n.identity.Name, n.identity.UID)
+}
+
+func namespacedName(ref corev1.ObjectReference) string {
+	if ref.Namespace == "" {
+		return ref.Name
+	}
+	return fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)
+}
+
+// errorsToWarnings flattens an aggregate error into a list of warnings.
+func errorsToWarnings(err error) []string {
+	var agg kerrors.Aggregate
+	if errors.As(err, &agg) {
+		warnings := []string{}
+		for _, e := range agg.Errors() {
+			warnings = append(warnings, e.Error())
+		}
+		return warnings
+	}
+	return []string{err.Error()}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MoveScope defines which of the objects belonging to a Cluster are moved.
+type MoveScope string
+
+const (
+	// AllMoveScope moves all the objects belonging to the Clusters; this is the default.
+	AllMoveScope MoveScope = "All"
+
+	// ControlPlaneMoveScope moves only the control plane of the Clusters, i.e. the object referenced by the Cluster's
+	// spec.controlPlaneRef with all the objects it owns, e.g. the control plane Machines, their infrastructure machines,
+	// bootstrap configs and Secrets. The Cluster, its infrastructure cluster, the Secrets linked to the Cluster by naming
+	// convention and the objects referenced by the control plane, e.g. its machine template, are copied to the target
+	// management cluster and kept, paused, in the source management cluster together with all the other objects, e.g. the
+	// MachineDeployments.
+	ControlPlaneMoveScope MoveScope = "ControlPlane"
+)
+
+// isInScope returns true if the node is in the scope of the move operation.
+// NB. Global objects are never deleted from the source management cluster, so they are always in scope.
+func (o *objectGraph) isInScope(n *node) bool {
+	return o.scope != ControlPlaneMoveScope || n.controlPlaneScope || n.isGlobal || n.isGlobalHierarchy
+}
+
+// setControlPlaneScope marks the nodes to be moved when moving only the control plane of the Clusters, and it ensures
+// the resulting split between the source and the target management cluster can be reconciled.
+func (o *objectGraph) setControlPlaneScope() error {
+	errList := []error{}
+	for _, cluster := range o.getClusters() {
+		if !cluster.selected {
+			continue
+		}
+		if err := o.setClusterControlPlaneScope(cluster); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if len(errList) > 0 {
+		sort.Slice(errList, func(i, j int) bool { return errList[i].Error() < errList[j].Error() })
+		return errors.Wrap(kerrors.NewAggregate(errList), "failed to get the control plane objects to be moved")
+	}
+	return o.checkControlPlaneScope()
+}
+
+// setClusterControlPlaneScope marks the control plane of a Cluster to be moved and the objects shared with the rest of the
+// Cluster to be copied.
+func (o *objectGraph) setClusterControlPlaneScope(cluster *node) error {
+	clusterObj := &clusterv1.Cluster{}
+	if err := getClusterObj(o.proxy, cluster, clusterObj); err != nil {
+		return err
+	}
+	if clusterObj.Spec.ControlPlaneRef == nil {
+		return errors.Errorf("Cluster %s does not have a control plane object", namespacedName(cluster.identity))
+	}
+
+	controlPlane := o.getNodeByReference(*clusterObj.Spec.ControlPlaneRef, cluster.identity.Namespace)
+	if controlPlane == nil {
+		return errors.Errorf("the control plane %s %s of Cluster %s is not included in the types considered for move",
+			clusterObj.Spec.ControlPlaneRef.Kind, clusterObj.Spec.ControlPlaneRef.Name, namespacedName(cluster.identity))
+	}
+	o.setControlPlaneHierarchy(controlPlane)
+
+	// The Cluster and the objects it requires for being reconciled in the target management cluster, including its ClusterClass, are copied, because
+	// they are required by the objects left in the source management cluster as well.
+	shared := []*node{cluster}
+	if ref := clusterObj.Spec.InfrastructureRef; ref != nil {
+		if infraCluster := o.getNodeByReference(*ref, cluster.identity.Namespace); infraCluster != nil {
+			shared = append(shared, infraCluster)
+		}
+	}
+	for _, secret := range o.getSecrets() {
+		if secret.isSoftOwnedBy(cluster) {
+			shared = append(shared, secret)
+		}
+	}
+	references, err := o.getSpecReferences(controlPlane)
+	if err != nil {
+		return err
+	}
+	shared = append(shared, references...)
+	shared = append(shared, o.getClusterClassHierarchy(cluster)...)
+
+	for _, n := range shared {
+		if !n.controlPlaneScope {
+			n.controlPlaneScope = true
+			n.keepInSource = true
+		}
+	}
+	return nil
+}
+
+// setControlPlaneHierarchy marks a node, and all the nodes it owns, as part of a control plane.
+func (o *objectGraph) setControlPlaneHierarchy(n *node) {
+	n.controlPlaneScope = true
+	for _, other := range o.getNodes() {
+		if !other.controlPlaneScope && (other.isOwnedBy(n) || other.isSoftOwnedBy(n)) {
+			o.setControlPlaneHierarchy(other)
+		}
+	}
+}
+
+// getNodeByReference returns the node corresponding to an object reference, if any; references without a namespace
+// default to the given namespace.
+func (o *objectGraph) getNodeByReference(ref corev1.ObjectReference, namespace string) *node {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	group := ""
+	if ref.APIVersion != "" {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil
+		}
+		group = gv.Group
+	}
+	for _, n := range o.uidToNode {
+		if n.virtual || n.identity.Kind != ref.Kind || n.identity.Name != ref.Name || (!n.isGlobal && n.identity.Namespace != namespace) {
+			continue
+		}
+		if ref.APIVersion != "" && n.identity.GroupVersionKind().Group != group {
+			continue
+		}
+		return n
+	}
+	return nil
+}
+
+// getSpecReferences returns the nodes referenced in the spec of the object corresponding to a node, e.g. the
+// infrastructure machine template of a control plane; references are detected as the fields with a kind and a name.
+func (o *objectGraph) getSpecReferences(n *node) ([]*node, error) {
+	c, err := o.proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(n.identity.APIVersion)
+	obj.SetKind(n.identity.Kind)
+	if err := c.Get(ctx, client.ObjectKey{Namespace: n.identity.Namespace, Name: n.identity.Name}, obj); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s %s", n.identity.Kind, namespacedName(n.identity))
+	}
+
+	references := []*node{}
+	for _, ref := range findObjectReferences(obj.Object["spec"]) {
+		if referenced := o.getNodeByReference(ref, n.identity.Namespace); referenced != nil {
+			references = append(references, referenced)
+		}
+	}
+	return references, nil
+}
+
+// findObjectReferences returns the object references nested in a value, i.e. the maps with a kind and a name.
+func findObjectReferences(value interface{}) []corev1.ObjectReference {
+	references := []corev1.ObjectReference{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		kind, _ := v["kind"].(string)
+		name, _ := v["name"].(string)
+		if kind != "" && name != "" {
+			apiVersion, _ := v["apiVersion"].(string)
+			namespace, _ := v["namespace"].(string)
+			references = append(references, corev1.ObjectReference{APIVersion: apiVersion, Kind: kind, Name: name, Namespace: namespace})
+		}
+		for _, nested := range v {
+			references = append(references, findObjectReferences(nested)...)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			references = append(references, findObjectReferences(nested)...)
+		}
+	}
+	return references
+}
+
+// checkControlPlaneScope ensures that moving only the control plane of the Clusters leaves both the source and the target
+// management cluster in a state that can be reconciled, i.e. that the objects moved to the target management cluster are
+// not owned by objects left in the source management cluster.
+// NB. Objects owned by the control plane are moved together with it, so the objects left in the source management cluster
+// are never garbage collected.
+func (o *objectGraph) checkControlPlaneScope() error {
+	errList := []error{}
+	for _, n := range o.uidToNode {
+		if !n.controlPlaneScope {
+			continue
+		}
+		ownersLeft := []string{}
+		for owner := range n.owners {
+			if !owner.virtual && !o.isInScope(owner) {
+				ownersLeft = append(ownersLeft, fmt.Sprintf("%s %s", owner.identity.Kind, namespacedName(owner.identity)))
+			}
+		}
+		if len(ownersLeft) > 0 {
+			sort.Strings(ownersLeft)
+			errList = append(errList, errors.Errorf("%s %s is moved with the control plane, but it is owned by objects left in the source management cluster (%s)",
+				n.identity.Kind, namespacedName(n.identity), strings.Join(ownersLeft, ", ")))
+		}
+	}
+
+	if len(errList) > 0 {
+		sort.Slice(errList, func(i, j int) bool { return errList[i].Error() < errList[j].Error() })
+		return errors.Wrap(kerrors.NewAggregate(errList), "moving only the control plane splits an ownership hierarchy across the source and the target management cluster")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneScopeObjs returns the objects for a Cluster with a control plane and a MachineDeployment.
+func controlPlaneScopeObjs() []client.Object {
+	return test.NewFakeCluster("ns1", "cluster1").
+		WithControlPlane(test.NewFakeControlPlane("cp1").WithMachines(test.NewFakeMachine("cp1-m1"))).
+		WithMachineDeployments(test.NewFakeMachineDeployment("md1").
+			WithMachineSets(test.NewFakeMachineSet("ms1").
+				WithMachines(test.NewFakeMachine("m1")))).
+		Objs()
+}
+
+// controlPlaneOnlyObjs returns the objects expected to be moved for the Cluster returned by controlPlaneScopeObjs.
+func controlPlaneOnlyObjs() []client.Object {
+	return test.NewFakeCluster("ns1", "cluster1").
+		WithControlPlane(test.NewFakeControlPlane("cp1").WithMachines(test.NewFakeMachine("cp1-m1"))).
+		Objs()
+}
+
+func Test_objectGraph_setControlPlaneScope(t *testing.T) {
+	tests := []struct {
+		name             string
+		objs             func() []client.Object
+		wantMoved        []client.Object
+		wantKeptInSource []string
+		wantErr          bool
+	}{
+		{
+			name:      "Moves the control plane hierarchy, keeping the objects shared with the workers in the source",
+			objs:      controlPlaneScopeObjs,
+			wantMoved: controlPlaneOnlyObjs(),
+			wantKeptInSource: []string{
+				"cluster.x-k8s.io/v1alpha4, Kind=Cluster, ns1/cluster1",
+				"infrastructure.cluster.x-k8s.io/v1alpha4, Kind=GenericInfrastructureCluster, ns1/cluster1",
+				"infrastructure.cluster.x-k8s.io/v1alpha4, Kind=GenericInfrastructureMachineTemplate, ns1/cp1",
+				"/v1, Kind=Secret, ns1/cluster1-ca",
+			},
+		},
+		{
+			name: "Fails if a Cluster does not have a control plane",
+			objs: func() []client.Object {
+				return test.NewFakeCluster("ns1", "cluster1").
+					WithMachines(test.NewFakeMachine("m1")).
+					Objs()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Fails if an object moved with the control plane is owned by an object left in the source",
+			objs: func() []client.Object {
+				objs := controlPlaneScopeObjs()
+				var machineDeployment client.Object
+				for _, o := range objs {
+					if o.GetObjectKind().GroupVersionKind().Kind == "MachineDeployment" {
+						machineDeployment = o
+					}
+				}
+				for _, o := range objs {
+					if o.GetName() == "cluster1-kubeconfig" {
+						o.SetOwnerReferences(append(o.GetOwnerReferences(), metav1.OwnerReference{
+							APIVersion: clusterv1.GroupVersion.String(),
+							Kind:       "MachineDeployment",
+							Name:       machineDeployment.GetName(),
+							UID:        machineDeployment.GetUID(),
+						}))
+					}
+				}
+				return objs
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound to a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.objs())
+			graph.scope = ControlPlaneMoveScope
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			err := graph.setControlPlaneScope()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotMoved := []string{}
+			gotKeptInSource := []string{}
+			for _, n := range graph.getMoveNodes() {
+				gotMoved = append(gotMoved, string(n.identity.UID))
+				if n.keepInSource {
+					gotKeptInSource = append(gotKeptInSource, string(n.identity.UID))
+				}
+			}
+
+			wantMoved := []string{}
+			for _, o := range tt.wantMoved {
+				wantMoved = append(wantMoved, string(o.GetUID()))
+			}
+			g.Expect(gotMoved).To(ConsistOf(wantMoved))
+			g.Expect(gotKeptInSource).To(ConsistOf(tt.wantKeptInSource))
+		})
+	}
+}
+
+func Test_objectMover_move_controlPlaneScope(t *testing.T) {
+	g := NewWithT(t)
+
+	// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+	graph := getObjectGraphWithObjs(controlPlaneScopeObjs())
+	graph.scope = ControlPlaneMoveScope
+
+	// Get all the types to be considered for discovery
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+	// trigger discovery the content of the source cluster
+	g.Expect(graph.Discovery("")).To(Succeed())
+	g.Expect(graph.setControlPlaneScope()).To(Succeed())
+
+	// gets a fakeProxy to an empty cluster with all the required CRDs
+	toProxy := getFakeProxyWithCRDs()
+
+	// Run move
+	mover := objectMover{
+		fromProxy: graph.proxy,
+	}
+	g.Expect(mover.move(graph, toProxy)).To(Succeed())
+
+	csFrom, err := graph.proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	moved := map[string]bool{}
+	for _, o := range controlPlaneOnlyObjs() {
+		moved[string(o.GetUID())] = true
+	}
+
+	for _, node := range graph.uidToNode {
+		key := client.ObjectKey{
+			Namespace: node.identity.Namespace,
+			Name:      node.identity.Name,
+		}
+
+		oFrom := &unstructured.Unstructured{}
+		oFrom.SetAPIVersion(node.identity.APIVersion)
+		oFrom.SetKind(node.identity.Kind)
+		errFrom := csFrom.Get(ctx, key, oFrom)
+
+		oTo := &unstructured.Unstructured{}
+		oTo.SetAPIVersion(node.identity.APIVersion)
+		oTo.SetKind(node.identity.Kind)
+		errTo := csTo.Get(ctx, key, oTo)
+
+		switch {
+		case node.keepInSource:
+			// objects shared with the objects left in the source cluster are copied
+			g.Expect(errFrom).NotTo(HaveOccurred(), "%v not kept in source cluster", key)
+			g.Expect(errTo).NotTo(HaveOccurred(), "%v not created in target cluster", key)
+		case moved[string(node.identity.UID)]:
+			// the control plane objects are moved
+			g.Expect(apierrors.IsNotFound(errFrom)).To(BeTrue(), "%v not deleted in source cluster", key)
+			g.Expect(errTo).NotTo(HaveOccurred(), "%v not created in target cluster", key)
+		default:
+			// the other objects are left in the source cluster
+			g.Expect(errFrom).NotTo(HaveOccurred(), "%v not left in source cluster", key)
+			g.Expect(apierrors.IsNotFound(errTo)).To(BeTrue(), "%v created in target cluster", key)
+		}
+	}
+
+	// the Cluster is left paused in the source cluster, and resumed in the target cluster
+	clusterFrom := &clusterv1.Cluster{}
+	g.Expect(csFrom.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "cluster1"}, clusterFrom)).To(Succeed())
+	g.Expect(clusterFrom.Spec.Paused).To(BeTrue())
+
+	clusterTo := &clusterv1.Cluster{}
+	g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "cluster1"}, clusterTo)).To(Succeed())
+	g.Expect(clusterTo.Spec.Paused).To(BeFalse())
+}
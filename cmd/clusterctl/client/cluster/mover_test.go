@@ -17,6 +17,7 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -24,6 +25,9 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
@@ -586,6 +590,119 @@ func Test_objectMover_move_dryRun(t *testing.T) {
 	}
 }
 
+func Test_objectMover_buildMoveReport(t *testing.T) {
+	// NB. we are testing the move and move sequence using the same set of moveTests, but checking the results at different stages of the move process
+	for _, tt := range moveTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.fields.objs)
+
+			// Get all the types to be considered for discovery
+			err := getFakeDiscoveryTypes(graph)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			// gets a fakeProxy to an empty cluster with all the required CRDs
+			toProxy := getFakeProxyWithCRDs()
+
+			mover := objectMover{
+				fromProxy: graph.proxy,
+			}
+
+			report, err := mover.buildMoveReport(graph, toProxy)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// check all the objects in the move sequence are reported, grouped by namespace.
+			wantObjects := []string{}
+			for _, group := range tt.wantMoveGroups {
+				wantObjects = append(wantObjects, group...)
+			}
+			gotObjects := []string{}
+			for _, n := range report.Namespaces {
+				for _, o := range n.Objects {
+					g.Expect(o.Object.Namespace).To(Equal(n.Namespace))
+					gotObjects = append(gotObjects, string(o.Object.UID))
+				}
+			}
+			g.Expect(gotObjects).To(ConsistOf(wantObjects))
+			g.Expect(report.ObjectsCount()).To(Equal(len(wantObjects)))
+
+			// check there are no conflicts given that the target cluster is empty.
+			g.Expect(report.HasConflicts()).To(BeFalse())
+
+			// check that the objects are not created in the target cluster.
+			csTo, err := toProxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, node := range graph.uidToNode {
+				if node.isGlobal {
+					continue
+				}
+				oTo := &unstructured.Unstructured{}
+				oTo.SetAPIVersion(node.identity.APIVersion)
+				oTo.SetKind(node.identity.Kind)
+				err := csTo.Get(ctx, client.ObjectKey{Namespace: node.identity.Namespace, Name: node.identity.Name}, oTo)
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			}
+		})
+	}
+}
+
+func Test_objectMover_buildMoveReport_conflicts(t *testing.T) {
+	g := NewWithT(t)
+
+	objs := test.NewFakeCluster("ns1", "foo").Objs()
+
+	// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+	graph := getObjectGraphWithObjs(objs)
+
+	// Get all the types to be considered for discovery
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+	// trigger discovery the content of the source cluster
+	g.Expect(graph.Discovery("")).To(Succeed())
+
+	// gets a fakeProxy to a cluster already containing the Cluster with the same name.
+	toProxy := getFakeProxyWithCRDs()
+	toProxy.WithObjs(&clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "foo",
+		},
+	})
+
+	mover := objectMover{
+		fromProxy: graph.proxy,
+	}
+
+	report, err := mover.buildMoveReport(graph, toProxy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.HasConflicts()).To(BeTrue())
+	g.Expect(report.Conflicts).To(HaveLen(1))
+	g.Expect(report.Conflicts[0].Kind).To(Equal("Cluster"))
+	g.Expect(report.Conflicts[0].Namespace).To(Equal("ns1"))
+	g.Expect(report.Conflicts[0].Name).To(Equal("foo"))
+
+	// check the objects owned by the Cluster report the Cluster as the root of their owner chain.
+	for _, n := range report.Namespaces {
+		for _, o := range n.Objects {
+			if o.Object.Kind == "Cluster" {
+				g.Expect(o.OwnerChain).To(BeEmpty())
+				continue
+			}
+			g.Expect(o.OwnerChain).NotTo(BeEmpty())
+			g.Expect(o.OwnerChain[len(o.OwnerChain)-1].Kind).To(Equal("Cluster"))
+		}
+	}
+}
+
 func Test_objectMover_move(t *testing.T) {
 	// NB. we are testing the move and move sequence using the same set of moveTests, but checking the results at different stages of the move process
 	for _, tt := range moveTests {
@@ -617,6 +734,7 @@ func Test_objectMover_move(t *testing.T) {
 			}
 
 			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mover.movedObjects).To(Equal(len(graph.getMoveNodes())))
 
 			// check that the objects are removed from the source cluster and are created in the target cluster
 			csFrom, err := graph.proxy.NewClient()
@@ -661,6 +779,291 @@ func Test_objectMover_move(t *testing.T) {
 	}
 }
 
+func Test_objectMover_Move_withLabelSelector(t *testing.T) {
+	g := NewWithT(t)
+
+	// Create a source cluster with a Cluster matching the label selector and a Cluster not matching it, both provisioned.
+	objs := []client.Object{}
+	objs = append(objs, test.NewFakeCluster("ns1", "foo").WithLabels(map[string]string{"env": "staging"}).Objs()...)
+	objs = append(objs, test.NewFakeCluster("ns1", "bar").Objs()...)
+	for _, o := range objs {
+		if c, ok := o.(*clusterv1.Cluster); ok {
+			c.Status.InfrastructureReady = true
+			conditions.MarkTrue(c, clusterv1.ControlPlaneInitializedCondition)
+		}
+	}
+	graph := getObjectGraphWithObjs(objs)
+
+	// gets a fakeProxy to an empty cluster with all the required CRDs and providers
+	toProxy := getFakeProxyWithCRDs()
+	toProxy.WithProviderInventory("infra1", clusterctlv1.InfrastructureProviderType, "v1.2.3", "infra1-system")
+	toCluster := newClusterClient(Kubeconfig{}, nil, InjectProxy(toProxy), InjectPollImmediateWaiter(fakePollImmediateWaiter))
+
+	mover := newObjectMover(graph.proxy, graph.providerInventory)
+	movedObjects, err := mover.Move("ns1", toCluster, false, WithLabelSelector(labels.SelectorFromSet(labels.Set{"env": "staging"})))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(movedObjects).To(BeNumerically(">", 0))
+
+	csFrom, err := graph.proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	csTo, err := toProxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// foo is moved to the target cluster and unpaused there.
+	foo := &clusterv1.Cluster{}
+	g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "foo"}, foo)).To(Succeed())
+	g.Expect(foo.Spec.Paused).To(BeFalse())
+	g.Expect(apierrors.IsNotFound(csFrom.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "foo"}, &clusterv1.Cluster{}))).To(BeTrue())
+
+	// bar is left untouched in the source cluster.
+	bar := &clusterv1.Cluster{}
+	g.Expect(csFrom.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "bar"}, bar)).To(Succeed())
+	g.Expect(bar.Spec.Paused).To(BeFalse())
+	g.Expect(apierrors.IsNotFound(csTo.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "bar"}, &clusterv1.Cluster{}))).To(BeTrue())
+}
+
+func Test_objectMover_move_withConcurrency(t *testing.T) {
+	for _, tt := range moveTests {
+		if tt.wantErr {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.fields.objs)
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			// gets a fakeProxy to an empty cluster with all the required CRDs
+			toProxy := getFakeProxyWithCRDs()
+
+			// Run move
+			mover := objectMover{
+				fromProxy:   graph.proxy,
+				concurrency: 4,
+			}
+			g.Expect(mover.move(graph, toProxy)).To(Succeed())
+
+			csTo, err := toProxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// objects are created in the target cluster, with owner references to the owners created before them
+			targetUIDs := map[types.UID]bool{}
+			targetObjs := []*unstructured.Unstructured{}
+			for _, node := range graph.uidToNode {
+				key := client.ObjectKey{
+					Namespace: node.identity.Namespace,
+					Name:      node.identity.Name,
+				}
+				oTo := &unstructured.Unstructured{}
+				oTo.SetAPIVersion(node.identity.APIVersion)
+				oTo.SetKind(node.identity.Kind)
+				g.Expect(csTo.Get(ctx, key, oTo)).To(Succeed())
+
+				targetUIDs[oTo.GetUID()] = true
+				targetObjs = append(targetObjs, oTo)
+			}
+			for _, o := range targetObjs {
+				for _, ref := range o.GetOwnerReferences() {
+					g.Expect(targetUIDs).To(HaveKey(ref.UID), "%s %s has an owner reference to a missing object", o.GetKind(), o.GetName())
+				}
+			}
+		})
+	}
+}
+
+func Test_objectMover_move_withProgress(t *testing.T) {
+	for _, tt := range moveTests {
+		if tt.wantErr {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.fields.objs)
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			// gets a fakeProxy to an empty cluster with all the required CRDs
+			toProxy := getFakeProxyWithCRDs()
+
+			// Run move, keeping track of the progress notifications.
+			done := []int{}
+			totals := map[int]bool{}
+			mover := objectMover{
+				fromProxy: graph.proxy,
+				progressFunc: func(d, total int, currentObject string) {
+					done = append(done, d)
+					totals[total] = true
+					g.Expect(currentObject).ToNot(BeEmpty())
+				},
+			}
+			g.Expect(mover.move(graph, toProxy)).To(Succeed())
+
+			// check a notification is received for each object created and deleted, and the total is known up front.
+			wantTotal := 2 * len(graph.getMoveNodes())
+			g.Expect(totals).To(Equal(map[int]bool{wantTotal: true}))
+			g.Expect(done).To(HaveLen(wantTotal))
+			for i := range done {
+				g.Expect(done[i]).To(Equal(i + 1))
+			}
+		})
+	}
+}
+
+func Test_objectMover_move_toNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		wantErr bool
+	}{
+		{
+			name: "Clusters from many namespaces are moved to the target namespace",
+			objs: func() []client.Object {
+				objs := []client.Object{}
+				objs = append(objs, test.NewFakeCluster("ns1", "foo").Objs()...)
+				objs = append(objs, test.NewFakeCluster("ns2", "bar").Objs()...)
+				return objs
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "Fails if objects from many namespaces collide in the target namespace",
+			objs: func() []client.Object {
+				objs := []client.Object{}
+				objs = append(objs, test.NewFakeCluster("ns1", "foo").Objs()...)
+				objs = append(objs, test.NewFakeCluster("ns2", "foo").Objs()...)
+				return objs
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.objs)
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			// gets a fakeProxy to an empty cluster with all the required CRDs
+			toProxy := getFakeProxyWithCRDs()
+
+			// Run move
+			mover := objectMover{
+				fromProxy:   graph.proxy,
+				toNamespace: "ns3",
+			}
+
+			err := mover.checkToNamespaceCollisions(graph, toProxy)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(mover.move(graph, toProxy)).To(Succeed())
+
+			// check that the objects are created in the target namespace, and references are updated accordingly
+			csTo, err := toProxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for _, node := range graph.getMoveNodes() {
+				key := client.ObjectKey{
+					Namespace: "ns3",
+					Name:      node.identity.Name,
+				}
+
+				oTo := &unstructured.Unstructured{}
+				oTo.SetAPIVersion(node.identity.APIVersion)
+				oTo.SetKind(node.identity.Kind)
+				g.Expect(csTo.Get(ctx, key, oTo)).To(Succeed())
+
+				if node.identity.Kind == "Cluster" {
+					namespace, _, err := unstructured.NestedString(oTo.Object, "spec", "infrastructureRef", "namespace")
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(namespace).To(Equal("ns3"))
+				}
+
+				for _, ref := range oTo.GetOwnerReferences() {
+					owner := &unstructured.Unstructured{}
+					owner.SetAPIVersion(ref.APIVersion)
+					owner.SetKind(ref.Kind)
+					g.Expect(csTo.Get(ctx, client.ObjectKey{Namespace: "ns3", Name: ref.Name}, owner)).To(Succeed())
+					g.Expect(ref.UID).To(Equal(owner.GetUID()))
+				}
+			}
+
+			// check that running again the move the target namespace fails because of collisions with the objects already moved.
+			g.Expect(mover.checkToNamespaceCollisions(graph, toProxy)).ToNot(Succeed())
+		})
+	}
+}
+
+func Test_setReferencesNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"infrastructureRef": map[string]interface{}{
+				"kind":      "GenericInfrastructureCluster",
+				"name":      "foo",
+				"namespace": "ns1",
+			},
+			"secretRefs": []interface{}{
+				map[string]interface{}{
+					"name":      "foo",
+					"namespace": "ns2",
+				},
+				map[string]interface{}{
+					"name":      "foo",
+					"namespace": "other",
+				},
+			},
+			"namespace": "ns1",
+		},
+	}
+
+	setReferencesNamespace(obj, sets.NewString("ns1", "ns2"), "ns3")
+
+	g.Expect(obj).To(Equal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"infrastructureRef": map[string]interface{}{
+				"kind":      "GenericInfrastructureCluster",
+				"name":      "foo",
+				"namespace": "ns3",
+			},
+			"secretRefs": []interface{}{
+				map[string]interface{}{
+					"name":      "foo",
+					"namespace": "ns3",
+				},
+				map[string]interface{}{
+					"name":      "foo",
+					"namespace": "other",
+				},
+			},
+			// NB. namespace fields not being part of a reference are not changed.
+			"namespace": "ns1",
+		},
+	}))
+}
+
 func Test_objectMover_checkProvisioningCompleted(t *testing.T) {
 	type fields struct {
 		objs []client.Object
@@ -969,6 +1372,67 @@ func Test_objectsMoverService_checkTargetProviders(t *testing.T) {
 	}
 }
 
+func Test_objectMover_checkTargetTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		toProxy func() Proxy
+		wantErr bool
+	}{
+		{
+			name: "all the types are served by the target cluster",
+			toProxy: func() Proxy {
+				return getFakeProxyWithCRDs()
+			},
+			wantErr: false,
+		},
+		{
+			name: "fails if CRDs are missing in the target cluster",
+			toProxy: func() Proxy {
+				return test.NewFakeProxy()
+			},
+			wantErr: true,
+		},
+		{
+			name: "fails if a CRD in the target cluster does not serve the required API version",
+			toProxy: func() Proxy {
+				proxy := test.NewFakeProxy()
+				for _, crd := range test.FakeCRDList() {
+					if crd.Spec.Group == clusterv1.GroupVersion.Group && crd.Spec.Names.Kind == "Cluster" {
+						crd.Spec.Versions[0].Served = false
+					}
+					proxy.WithObjs(crd)
+				}
+				return proxy
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(test.NewFakeCluster("ns1", "foo").Objs())
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			o := &objectMover{
+				fromProxy: graph.proxy,
+			}
+			err := o.checkTargetTypes(graph, tt.toProxy())
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
 func Test_objectMoverService_ensureNamespace(t *testing.T) {
 	type args struct {
 		toProxy   Proxy
@@ -1375,6 +1839,91 @@ func Test_createTargetObject(t *testing.T) {
 	}
 }
 
+// statusSubresourceProxy is a Proxy whose clients ignore the status when creating or updating objects, like the
+// API server does for resources with a status subresource.
+type statusSubresourceProxy struct {
+	Proxy
+}
+
+func (p *statusSubresourceProxy) NewClient() (client.Client, error) {
+	c, err := p.Proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return &statusSubresourceClient{Client: c}, nil
+}
+
+type statusSubresourceClient struct {
+	client.Client
+}
+
+func (c *statusSubresourceClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		unstructured.RemoveNestedField(u.Object, "status")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func Test_createTargetObject_preserveStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		preserveStatus bool
+		wantStatus     bool
+	}{
+		{
+			name:           "drops the status by default",
+			preserveStatus: false,
+			wantStatus:     false,
+		},
+		{
+			name:           "restores the status if requested",
+			preserveStatus: true,
+			wantStatus:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fromProxy := test.NewFakeProxy().WithObjs(
+				&clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "ns1",
+					},
+					Status: clusterv1.ClusterStatus{
+						InfrastructureReady: true,
+						Phase:               "Provisioned",
+					},
+				},
+			)
+			toProxy := &statusSubresourceProxy{Proxy: test.NewFakeProxy()}
+			node := &node{
+				identity: corev1.ObjectReference{
+					Kind:       "Cluster",
+					Namespace:  "ns1",
+					Name:       "foo",
+					APIVersion: "cluster.x-k8s.io/v1alpha4",
+				},
+			}
+
+			mover := objectMover{
+				fromProxy:      fromProxy,
+				preserveStatus: tt.preserveStatus,
+			}
+			g.Expect(mover.createTargetObject(node, toProxy)).To(Succeed())
+
+			toClient, err := toProxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			c := &clusterv1.Cluster{}
+			g.Expect(toClient.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "foo"}, c)).To(Succeed())
+			g.Expect(c.Status.InfrastructureReady).To(Equal(tt.wantStatus))
+			g.Expect(c.Status.Phase == "Provisioned").To(Equal(tt.wantStatus))
+		})
+	}
+}
+
 func Test_deleteSourceObject(t *testing.T) {
 	type args struct {
 		fromProxy Proxy
@@ -18,7 +18,10 @@ package cluster
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -26,7 +29,10 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
@@ -35,6 +41,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// defaultDiscoveryPageSize is the maximum number of objects read by each list request during discovery, if not
+// set using WithDiscoveryPageSize.
+const defaultDiscoveryPageSize int64 = 500
+
 type empty struct{}
 
 type ownerReferenceAttributes struct {
@@ -72,9 +82,33 @@ type node struct {
 	// virtual records if this node was discovered indirectly, e.g. by processing an OwnerRef, but not yet observed as a concrete object.
 	virtual bool
 
+	// restoreObject holds the object read from a backup directory, if the graph is built for restoring objects.
+	restoreObject *unstructured.Unstructured
+
+	// selected is set to true if the object matches the label selector used for the move operation (or if no label selector is set).
+	selected bool
+
+	// controlPlaneScope is set to true if the object is part of the control plane of a Cluster, or if it is shared between the
+	// control plane and the other objects of the Cluster, when moving only the control plane.
+	controlPlaneScope bool
+
+	// keepInSource is set to true if the object is shared between the control plane moved to the target management cluster and
+	// the objects left in the source management cluster, so it is copied but not deleted from the source management cluster.
+	keepInSource bool
+
+	// clusterClass is the name of the ClusterClass defining the topology of a Cluster, if any.
+	clusterClass string
+
+	// clusterClassReferences lists the references to the templates of a ClusterClass.
+	clusterClassReferences []corev1.ObjectReference
+
 	// newID stores the new UID the objects gets once created in the target cluster.
 	newUID types.UID
 
+	// alreadyMoved is set to true if the object was created in the target cluster by a previous move operation, and so it is
+	// skipped when resuming the move operation.
+	alreadyMoved bool
+
 	// tenant define the list of objects which are tenant for the node, no matter if the node has a direct OwnerReference to the object or if
 	// the node is linked to a object indirectly in the OwnerReference chain.
 	tenant map[*node]empty
@@ -116,6 +150,23 @@ type objectGraph struct {
 	providerInventory InventoryClient
 	uidToNode         map[types.UID]*node
 	types             map[string]*discoveryTypeInfo
+
+	// selector restricts the move operation to the objects matching it, together with their entire hierarchy; if nil, all the objects are moved.
+	selector labels.Selector
+
+	// excludedKinds lists the kinds of the objects not to be moved; kinds without a version match all the versions.
+	excludedKinds []schema.GroupVersionKind
+
+	// scope defines which of the objects belonging to the Clusters are moved; if empty, all the objects are moved.
+	scope MoveScope
+
+	// discoveryPageSize defines the maximum number of objects read by each list request during discovery; if lower
+	// than 1, defaultDiscoveryPageSize is used.
+	discoveryPageSize int64
+
+	// discoveryConcurrency defines the maximum number of types listed concurrently during discovery; if lower than 1,
+	// types are listed one at a time.
+	discoveryConcurrency int
 }
 
 func newObjectGraph(proxy Proxy, providerInventory InventoryClient) *objectGraph {
@@ -131,6 +182,7 @@ func newObjectGraph(proxy Proxy, providerInventory InventoryClient) *objectGraph
 func (o *objectGraph) addObj(obj *unstructured.Unstructured) {
 	// Adds the node to the Graph.
 	newNode := o.objToNode(obj)
+	o.setClusterClassInfo(obj, newNode)
 
 	// Process OwnerReferences; if the owner object doe not exists yet, create a virtual node as a placeholder for it.
 	for _, ownerReference := range obj.GetOwnerReferences() {
@@ -146,6 +198,13 @@ func (o *objectGraph) addObj(obj *unstructured.Unstructured) {
 	}
 }
 
+// addRestoredObj adds a Kubernetes object read from a backup directory to the object graph, keeping track of
+// the object so it can be created in the target management cluster.
+func (o *objectGraph) addRestoredObj(obj *unstructured.Unstructured) {
+	o.addObj(obj)
+	o.uidToNode[obj.GetUID()].restoreObject = obj
+}
+
 // ownerToVirtualNode creates a virtual node as a placeholder for the Kubernetes owner object received in input.
 // The virtual node will be eventually converted to an actual node when the node will be visited during discovery.
 func (o *objectGraph) ownerToVirtualNode(owner metav1.OwnerReference) *node {
@@ -204,6 +263,7 @@ func (o *objectGraph) objToNode(obj *unstructured.Unstructured) *node {
 
 func (o *objectGraph) objMetaToNode(obj *unstructured.Unstructured, n *node) {
 	n.identity.Namespace = obj.GetNamespace()
+	n.selected = o.selector == nil || o.selector.Matches(labels.Set(obj.GetLabels()))
 	if _, ok := obj.GetLabels()[clusterctlv1.ClusterctlMoveLabelName]; ok {
 		n.forceMove = true
 	}
@@ -323,44 +383,47 @@ func (o *objectGraph) Discovery(namespace string) error {
 		selectors = append(selectors, client.InNamespace(namespace))
 	}
 
-	discoveryBackoff := newReadBackoff()
-	for _, discoveryType := range o.types {
-		typeMeta := discoveryType.typeMeta
-		objList := new(unstructured.UnstructuredList)
+	workers := o.discoveryConcurrency
+	if workers < 1 {
+		workers = 1
+	}
 
-		if err := retryWithExponentialBackoff(discoveryBackoff, func() error {
-			return getObjList(o.proxy, typeMeta, selectors, objList)
-		}); err != nil {
-			return err
-		}
+	// NB. The types are listed concurrently, but the objects are added to the graph by a single goroutine, in the
+	// order of the types, once all the lists are completed.
+	typeNames := make([]string, 0, len(o.types))
+	for typeName := range o.types {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	objLists := make([]*unstructured.UnstructuredList, len(typeNames))
+	errList := make([]error, len(typeNames))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range typeNames {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(discoveryType *discoveryTypeInfo, i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			objLists[i], errList[i] = o.discoverType(discoveryType, selectors)
+		}(o.types[typeNames[i]], i)
+	}
+	wg.Wait()
 
-		// if we are discovering Secrets, also secrets from the providers namespace should be included.
-		if discoveryType.typeMeta.GetObjectKind().GroupVersionKind().GroupKind() == corev1.SchemeGroupVersion.WithKind("SecretList").GroupKind() {
-			providers, err := o.providerInventory.List()
-			if err != nil {
-				return err
-			}
-			for _, p := range providers.Items {
-				if p.Type == string(clusterctlv1.InfrastructureProviderType) {
-					providerNamespaceSelector := []client.ListOption{client.InNamespace(p.Namespace)}
-					providerNamespaceSecretList := new(unstructured.UnstructuredList)
-					if err := retryWithExponentialBackoff(discoveryBackoff, func() error {
-						return getObjList(o.proxy, typeMeta, providerNamespaceSelector, providerNamespaceSecretList)
-					}); err != nil {
-						return err
-					}
-					objList.Items = append(objList.Items, providerNamespaceSecretList.Items...)
-				}
-			}
-		}
+	if err := kerrors.NewAggregate(errList); err != nil {
+		return err
+	}
 
+	for i, objList := range objLists {
 		if len(objList.Items) == 0 {
 			continue
 		}
 
-		log.V(5).Info(typeMeta.Kind, "Count", len(objList.Items))
-		for i := range objList.Items {
-			obj := objList.Items[i]
+		log.V(5).Info(o.types[typeNames[i]].typeMeta.Kind, "Count", len(objList.Items))
+		for j := range objList.Items {
+			obj := objList.Items[j]
 			o.addObj(&obj)
 		}
 	}
@@ -377,7 +440,37 @@ func (o *objectGraph) Discovery(namespace string) error {
 	return nil
 }
 
-func getObjList(proxy Proxy, typeMeta metav1.TypeMeta, selectors []client.ListOption, objList *unstructured.UnstructuredList) error {
+// discoverType returns all the objects of a discovery type matching the selectors.
+func (o *objectGraph) discoverType(discoveryType *discoveryTypeInfo, selectors []client.ListOption) (*unstructured.UnstructuredList, error) {
+	typeMeta := discoveryType.typeMeta
+	objList := new(unstructured.UnstructuredList)
+	if err := getObjList(o.proxy, typeMeta, selectors, o.discoveryPageSize, objList); err != nil {
+		return nil, err
+	}
+
+	// if we are discovering Secrets, also secrets from the providers namespace should be included.
+	if typeMeta.GetObjectKind().GroupVersionKind().GroupKind() == corev1.SchemeGroupVersion.WithKind("SecretList").GroupKind() {
+		providers, err := o.providerInventory.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range providers.Items {
+			if p.Type == string(clusterctlv1.InfrastructureProviderType) {
+				providerNamespaceSelector := []client.ListOption{client.InNamespace(p.Namespace)}
+				providerNamespaceSecretList := new(unstructured.UnstructuredList)
+				if err := getObjList(o.proxy, typeMeta, providerNamespaceSelector, o.discoveryPageSize, providerNamespaceSecretList); err != nil {
+					return nil, err
+				}
+				objList.Items = append(objList.Items, providerNamespaceSecretList.Items...)
+			}
+		}
+	}
+	return objList, nil
+}
+
+// getObjList reads all the objects of a type matching the selectors, using list requests returning at most pageSize
+// objects each; if pageSize is lower than 1, defaultDiscoveryPageSize is used.
+func getObjList(proxy Proxy, typeMeta metav1.TypeMeta, selectors []client.ListOption, pageSize int64, objList *unstructured.UnstructuredList) error {
 	c, err := proxy.NewClient()
 	if err != nil {
 		return err
@@ -385,14 +478,46 @@ func getObjList(proxy Proxy, typeMeta metav1.TypeMeta, selectors []client.ListOp
 
 	objList.SetAPIVersion(typeMeta.APIVersion)
 	objList.SetKind(typeMeta.Kind)
+	return listPages(c, selectors, pageSize, objList)
+}
+
+// listPages reads all the objects matching the selectors into objList, one page at a time. Each page is read with
+// retries, so a failure does not require reading again the previous pages.
+func listPages(c client.Reader, selectors []client.ListOption, pageSize int64, objList *unstructured.UnstructuredList) error {
+	if pageSize < 1 {
+		pageSize = defaultDiscoveryPageSize
+	}
+
+	listPageBackoff := newReadBackoff()
+	continueToken := ""
+	for {
+		page := new(unstructured.UnstructuredList)
+		page.SetGroupVersionKind(objList.GroupVersionKind())
+		listOptions := append([]client.ListOption{client.Limit(pageSize), client.Continue(continueToken)}, selectors...)
+
+		if err := retryWithExponentialBackoff(listPageBackoff, func() error {
+			if err := c.List(ctx, page, listOptions...); err != nil {
+				if apierrors.IsNotFound(err) {
+					return nil
+				}
+				// Wait as suggested by the API server when throttling requests, e.g. by API Priority and Fairness,
+				// in addition to the backoff.
+				if delay, ok := apierrors.SuggestsClientDelay(err); ok {
+					time.Sleep(time.Duration(delay) * time.Second)
+				}
+				return errors.Wrapf(err, "failed to list %q resources", objList.GroupVersionKind())
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
 
-	if err := c.List(ctx, objList, selectors...); err != nil {
-		if apierrors.IsNotFound(err) {
+		objList.Items = append(objList.Items, page.Items...)
+		continueToken = page.GetContinue()
+		if continueToken == "" {
 			return nil
 		}
-		return errors.Wrapf(err, "failed to list %q resources", objList.GroupVersionKind())
 	}
-	return nil
 }
 
 // getClusters returns the list of Clusters existing in the object graph.
@@ -406,6 +531,16 @@ func (o *objectGraph) getClusters() []*node {
 	return clusters
 }
 
+// getCluster returns the node for the Cluster with the given namespace and name.
+func (o *objectGraph) getCluster(namespace, name string) (*node, error) {
+	for _, cluster := range o.getClusters() {
+		if cluster.identity.Name == name && (namespace == "" || cluster.identity.Namespace == namespace) {
+			return cluster, nil
+		}
+	}
+	return nil, errors.Errorf("Cluster %q not found in namespace %q", name, namespace)
+}
+
 // getClusters returns the list of Secrets existing in the object graph.
 func (o *objectGraph) getSecrets() []*node {
 	secrets := []*node{}
@@ -439,16 +574,169 @@ func (o *objectGraph) getCRSs() []*node {
 
 // getMoveNodes returns the list of nodes existing in the object graph that belong at least to one tenant (e.g Cluster or to a ClusterResourceSet)
 // or it is labeled for force move (at object level or at CRD level).
+// If a label selector is set, only nodes belonging to tenants matching the selector, or labeled for force move and matching the selector, are returned.
 func (o *objectGraph) getMoveNodes() []*node {
 	nodes := []*node{}
 	for _, node := range o.uidToNode {
-		if len(node.tenant) > 0 || node.forceMove {
+		if o.isExcluded(node) || !o.isInScope(node) {
+			continue
+		}
+
+		if o.selector == nil {
+			if len(node.tenant) > 0 || node.forceMove {
+				nodes = append(nodes, node)
+			}
+			continue
+		}
+
+		if node.hasSelectedTenant() || (node.forceMove && node.selected) {
 			nodes = append(nodes, node)
 		}
 	}
 	return nodes
 }
 
+// getMoveClusters returns the list of Clusters to be moved, i.e. the Clusters included in getMoveNodes.
+func (o *objectGraph) getMoveClusters() []*node {
+	return o.filterMoveNodes(clusterv1.GroupVersion.WithKind("Cluster").GroupKind())
+}
+
+// getMoveMachines returns the list of Machines to be moved, i.e. the Machines included in getMoveNodes.
+func (o *objectGraph) getMoveMachines() []*node {
+	return o.filterMoveNodes(clusterv1.GroupVersion.WithKind("Machine").GroupKind())
+}
+
+func (o *objectGraph) filterMoveNodes(groupKind schema.GroupKind) []*node {
+	nodes := []*node{}
+	for _, node := range o.getMoveNodes() {
+		if node.identity.GroupVersionKind().GroupKind() == groupKind {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// hasSelectedTenant returns true if the node belongs at least to one tenant matching the label selector.
+func (n *node) hasSelectedTenant() bool {
+	for tenant := range n.tenant {
+		if tenant.selected {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSelection ensures that the label selector does not split an ownership hierarchy across the source and the target management cluster,
+// e.g. an object shared between a Cluster matching the selector and a Cluster not matching it.
+func (o *objectGraph) checkSelection() error {
+	if o.selector == nil {
+		return nil
+	}
+
+	errList := []error{}
+	for _, node := range o.uidToNode {
+		if !node.hasSelectedTenant() {
+			continue
+		}
+
+		notSelected := []string{}
+		for tenant := range node.tenant {
+			if !tenant.selected {
+				notSelected = append(notSelected, fmt.Sprintf("%s %s", tenant.identity.Kind, namespacedName(tenant.identity)))
+			}
+		}
+		if len(notSelected) > 0 {
+			// A ClusterClass, and its templates, can be shared by Clusters matching the label selector and Clusters not matching it,
+			// so it is copied to the target management cluster and kept in the source one.
+			if node.isClusterClassHierarchy() {
+				node.keepInSource = true
+				continue
+			}
+			sort.Strings(notSelected)
+			errList = append(errList, errors.Errorf("%s %s belongs both to objects matching the label selector and to objects not matching it (%s)",
+				node.identity.Kind, namespacedName(node.identity), strings.Join(notSelected, ", ")))
+		}
+	}
+
+	if len(errList) > 0 {
+		sort.Slice(errList, func(i, j int) bool { return errList[i].Error() < errList[j].Error() })
+		return errors.Wrapf(kerrors.NewAggregate(errList), "the label selector %q splits an ownership hierarchy across the source and the target management cluster", o.selector.String())
+	}
+	return nil
+}
+
+// isExcluded returns true if the node is excluded from the move operation, i.e. if the kind of the node is excluded, or
+// if the node belongs only to excluded tenants, e.g. the Secrets referenced only by an excluded ClusterResourceSet.
+func (o *objectGraph) isExcluded(n *node) bool {
+	if len(o.excludedKinds) == 0 {
+		return false
+	}
+	if o.isExcludedKind(n) {
+		return true
+	}
+	if len(n.tenant) == 0 {
+		return false
+	}
+	for tenant := range n.tenant {
+		if !o.isExcludedKind(tenant) {
+			return false
+		}
+	}
+	return true
+}
+
+// isExcludedKind returns true if the kind of the node is excluded from the move operation.
+func (o *objectGraph) isExcludedKind(n *node) bool {
+	gvk := n.identity.GroupVersionKind()
+	for _, excluded := range o.excludedKinds {
+		if excluded.GroupKind() == gvk.GroupKind() && (excluded.Version == "" || excluded.Version == gvk.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExclusion ensures that the excluded kinds do not leave dangling references in the target management cluster,
+// i.e. that none of the objects to be moved is owned by an excluded object.
+func (o *objectGraph) checkExclusion() error {
+	if len(o.excludedKinds) == 0 {
+		return nil
+	}
+
+	clusterGK := clusterv1.GroupVersion.WithKind("Cluster").GroupKind()
+	for _, excluded := range o.excludedKinds {
+		if excluded.GroupKind() == clusterGK {
+			return errors.New("Clusters can't be excluded from the move operation")
+		}
+	}
+
+	errList := []error{}
+	for _, node := range o.getMoveNodes() {
+		excludedOwners := []string{}
+		for owner := range node.owners {
+			if o.isExcluded(owner) {
+				excludedOwners = append(excludedOwners, fmt.Sprintf("%s %s", owner.identity.Kind, namespacedName(owner.identity)))
+			}
+		}
+		for owner := range node.softOwners {
+			if o.isExcluded(owner) {
+				excludedOwners = append(excludedOwners, fmt.Sprintf("%s %s", owner.identity.Kind, namespacedName(owner.identity)))
+			}
+		}
+		if len(excludedOwners) > 0 {
+			sort.Strings(excludedOwners)
+			errList = append(errList, errors.Errorf("%s %s is owned by excluded objects (%s)",
+				node.identity.Kind, namespacedName(node.identity), strings.Join(excludedOwners, ", ")))
+		}
+	}
+
+	if len(errList) > 0 {
+		sort.Slice(errList, func(i, j int) bool { return errList[i].Error() < errList[j].Error() })
+		return errors.Wrap(kerrors.NewAggregate(errList), "the excluded kinds own objects to be moved; please exclude the owned objects as well")
+	}
+	return nil
+}
+
 // getMachines returns the list of Machine existing in the object graph.
 func (o *objectGraph) getMachines() []*node {
 	machines := []*node{}
@@ -485,6 +773,9 @@ func (o *objectGraph) setSoftOwnership() {
 			}
 		}
 	}
+
+	// Links the Clusters with a managed topology to their ClusterClass, and the ClusterClasses to their templates.
+	o.setClusterClassSoftOwnership()
 }
 
 // setTenants identifies all the nodes linked to a parent with forceMoveHierarchy = true (e.g. Clusters or ClusterResourceSet)
@@ -495,6 +786,9 @@ func (o *objectGraph) setTenants() {
 			o.setTenant(node, node, node.isGlobal)
 		}
 	}
+
+	// ClusterClasses are moved together with the Clusters using them.
+	o.setClusterClassTenants()
 }
 
 // setTenant sets a tenant for a node and for its own dependents/sofDependents.
@@ -17,17 +17,24 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"sort"
+	"strconv"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1alpha4"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -1424,6 +1431,106 @@ func TestObjectGraph_Discovery(t *testing.T) {
 	}
 }
 
+func TestObjectGraph_Discovery_withConcurrency(t *testing.T) {
+	// NB. Types listed concurrently, one object at a time, must result in the same graph of a sequential discovery.
+	for _, tt := range objectGraphsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			graph := getObjectGraphWithObjs(tt.args.objs)
+			graph.discoveryConcurrency = 4
+			graph.discoveryPageSize = 1
+
+			err := getFakeDiscoveryTypes(graph)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			err = graph.Discovery("")
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			assertGraph(t, graph, tt.want)
+		})
+	}
+}
+
+// pagedReader is a client.Reader returning the objects one page at a time, and throttling the first request.
+type pagedReader struct {
+	client.Reader
+	objs      []unstructured.Unstructured
+	throttled bool
+	limits    []int64
+}
+
+func (r *pagedReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if !r.throttled {
+		r.throttled = true
+		return apierrors.NewTooManyRequests("too many requests", 0)
+	}
+
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	r.limits = append(r.limits, listOptions.Limit)
+
+	start := 0
+	if listOptions.Continue != "" {
+		start, _ = strconv.Atoi(listOptions.Continue)
+	}
+	end := start + int(listOptions.Limit)
+	objList := list.(*unstructured.UnstructuredList)
+	objList.Items = nil
+	objList.SetContinue("")
+	if end < len(r.objs) {
+		objList.SetContinue(strconv.Itoa(end))
+	} else {
+		end = len(r.objs)
+	}
+	objList.Items = append(objList.Items, r.objs[start:end]...)
+	return nil
+}
+
+func Test_listPages(t *testing.T) {
+	objs := []unstructured.Unstructured{}
+	for i := 0; i < 5; i++ {
+		obj := unstructured.Unstructured{}
+		obj.SetName(fmt.Sprintf("obj%d", i))
+		objs = append(objs, obj)
+	}
+
+	tests := []struct {
+		name       string
+		pageSize   int64
+		wantLimits []int64
+	}{
+		{
+			name:       "reads all the pages",
+			pageSize:   2,
+			wantLimits: []int64{2, 2, 2},
+		},
+		{
+			name:       "reads all the objects with the default page size",
+			pageSize:   0,
+			wantLimits: []int64{defaultDiscoveryPageSize},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			reader := &pagedReader{objs: objs}
+			objList := &unstructured.UnstructuredList{}
+			g.Expect(listPages(reader, nil, tt.pageSize, objList)).To(Succeed())
+
+			// The throttled request is retried.
+			g.Expect(reader.throttled).To(BeTrue())
+			g.Expect(reader.limits).To(Equal(tt.wantLimits))
+			g.Expect(objList.Items).To(Equal(objs))
+		})
+	}
+}
+
 func TestObjectGraph_DiscoveryByNamespace(t *testing.T) {
 	type args struct {
 		namespace string
@@ -1969,6 +2076,181 @@ func Test_objectGraph_setCRSTenants(t *testing.T) {
 	}
 }
 
+func Test_objectGraph_getMoveNodes_withLabelSelector(t *testing.T) {
+	stagingLabels := map[string]string{"env": "staging"}
+
+	type args struct {
+		selector string
+		objs     []client.Object
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []client.Object
+		wantErr bool
+	}{
+		{
+			name: "Only the selected cluster and its objects are moved",
+			args: args{
+				selector: "env=staging",
+				objs: func() []client.Object {
+					objs := []client.Object{}
+					objs = append(objs, test.NewFakeCluster("ns1", "cluster1").WithLabels(stagingLabels).Objs()...)
+					objs = append(objs, test.NewFakeCluster("ns1", "cluster2").Objs()...)
+					return objs
+				}(),
+			},
+			want: test.NewFakeCluster("ns1", "cluster1").WithLabels(stagingLabels).Objs(),
+		},
+		{
+			name: "Nothing is moved if no cluster matches the selector",
+			args: args{
+				selector: "env=production",
+				objs: func() []client.Object {
+					objs := []client.Object{}
+					objs = append(objs, test.NewFakeCluster("ns1", "cluster1").WithLabels(stagingLabels).Objs()...)
+					objs = append(objs, test.NewFakeCluster("ns1", "cluster2").Objs()...)
+					return objs
+				}(),
+			},
+			want: []client.Object{},
+		},
+		{
+			name: "Fails when an object belongs both to selected and not selected objects",
+			args: args{
+				selector: "env=staging",
+				objs: func() []client.Object {
+					objs := []client.Object{}
+					objs = append(objs, test.NewFakeCluster("ns1", "cluster1").WithLabels(stagingLabels).Objs()...)
+					objs = append(objs, test.NewFakeClusterResourceSet("ns1", "crs1").
+						WithSecret("resource-s1").
+						ApplyToCluster(test.SelectClusterObj(objs, "ns1", "cluster1")).
+						Objs()...)
+					return objs
+				}(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			selector, err := labels.Parse(tt.args.selector)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// Create an objectGraph bound to a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(tt.args.objs)
+			graph.selector = selector
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			err = graph.checkSelection()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotUIDs := []string{}
+			for _, n := range graph.getMoveNodes() {
+				gotUIDs = append(gotUIDs, string(n.identity.UID))
+			}
+
+			wantUIDs := []string{}
+			for _, o := range tt.want {
+				wantUIDs = append(wantUIDs, string(o.GetUID()))
+			}
+			g.Expect(gotUIDs).To(ConsistOf(wantUIDs))
+		})
+	}
+}
+
+func Test_objectGraph_getMoveNodes_withExcludedKinds(t *testing.T) {
+	crsGVK := addonsv1.GroupVersion.WithKind("ClusterResourceSet")
+	crsBindingGVK := addonsv1.GroupVersion.WithKind("ClusterResourceSetBinding")
+
+	objs := func() []client.Object {
+		objs := []client.Object{}
+		objs = append(objs, test.NewFakeCluster("ns1", "cluster1").Objs()...)
+		objs = append(objs, test.NewFakeClusterResourceSet("ns1", "crs1").
+			WithSecret("resource-s1").
+			ApplyToCluster(test.SelectClusterObj(objs, "ns1", "cluster1")).
+			Objs()...)
+		return objs
+	}
+
+	tests := []struct {
+		name          string
+		excludedKinds []schema.GroupVersionKind
+		want          []client.Object
+		wantErr       bool
+	}{
+		{
+			name:          "Excluded objects and the objects belonging only to them are not moved",
+			excludedKinds: []schema.GroupVersionKind{crsGVK, crsBindingGVK},
+			want:          test.NewFakeCluster("ns1", "cluster1").Objs(),
+		},
+		{
+			name:          "Kinds without a version match all the versions",
+			excludedKinds: []schema.GroupVersionKind{crsGVK.GroupKind().WithVersion(""), crsBindingGVK.GroupKind().WithVersion("")},
+			want:          test.NewFakeCluster("ns1", "cluster1").Objs(),
+		},
+		{
+			name:          "Kinds with a different version do not match",
+			excludedKinds: []schema.GroupVersionKind{crsGVK.GroupKind().WithVersion("v1alpha1")},
+			want:          objs(),
+		},
+		{
+			name:          "Fails when an excluded object owns objects to be moved",
+			excludedKinds: []schema.GroupVersionKind{crsGVK},
+			wantErr:       true,
+		},
+		{
+			name:          "Fails when Clusters are excluded",
+			excludedKinds: []schema.GroupVersionKind{clusterv1.GroupVersion.WithKind("Cluster")},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// Create an objectGraph bound to a source cluster with all the CRDs for the types involved in the test.
+			graph := getObjectGraphWithObjs(objs())
+			graph.excludedKinds = tt.excludedKinds
+
+			// Get all the types to be considered for discovery
+			g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+
+			// trigger discovery the content of the source cluster
+			g.Expect(graph.Discovery("")).To(Succeed())
+
+			err := graph.checkExclusion()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotUIDs := []string{}
+			for _, n := range graph.getMoveNodes() {
+				gotUIDs = append(gotUIDs, string(n.identity.UID))
+			}
+
+			wantUIDs := []string{}
+			for _, o := range tt.want {
+				wantUIDs = append(wantUIDs, string(o.GetUID()))
+			}
+			g.Expect(gotUIDs).To(ConsistOf(wantUIDs))
+		})
+	}
+}
+
 func Test_objectGraph_setGlobalIdentityTenants(t *testing.T) {
 	type fields struct {
 		objs []client.Object
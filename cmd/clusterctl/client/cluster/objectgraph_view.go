@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ObjectGraph is a read-only view of the graph of the Cluster API objects discovered in a management cluster, i.e.
+// the same graph used by the move operation, including the ownership relationships inferred by clusterctl.
+type ObjectGraph struct {
+	// Nodes contains the objects in the graph, sorted by namespace, kind and name.
+	Nodes []*ObjectGraphNode
+
+	// Roots contains the nodes without owners, e.g. the Clusters, sorted by namespace, kind and name.
+	Roots []*ObjectGraphNode
+}
+
+// ObjectGraphNode is an object in the ObjectGraph.
+type ObjectGraphNode struct {
+	// Object is the reference to the object.
+	Object corev1.ObjectReference
+
+	// Owners contains the relationships to the owners of the object, sorted by namespace, kind and name of the owner.
+	Owners []ObjectGraphOwner
+
+	// Dependants contains the objects owned by this object, including soft ownership, sorted by namespace, kind and name.
+	Dependants []*ObjectGraphNode
+
+	// Virtual is true if the object was not observed, but it is referenced by an OwnerReference of another object;
+	// e.g. this happens for owners of a type not included in the types considered for move.
+	Virtual bool
+
+	// Global is true if the object is a global resource (no namespace).
+	Global bool
+
+	// ForceMove is true if the object is moved regardless of its owners, because its CRD has the move label.
+	ForceMove bool
+}
+
+// ObjectGraphOwner describes the relationship between an object and one of its owners.
+type ObjectGraphOwner struct {
+	// Owner of the object.
+	Owner *ObjectGraphNode
+
+	// Soft is true if the ownership is inferred by clusterctl without an explicit OwnerReference,
+	// e.g. a Secret linked to a Cluster by a naming convention.
+	Soft bool
+
+	// Controller is true if the OwnerReference is a controller reference.
+	Controller bool
+}
+
+// newObjectGraphView returns the ObjectGraph for an objectGraph; if tenant is not nil, the view is restricted to the
+// tenant itself and to the objects belonging to it, e.g. all the objects belonging to a Cluster.
+func newObjectGraphView(graph *objectGraph, tenant *node) *ObjectGraph {
+	included := func(n *node) bool {
+		if tenant == nil || n == tenant {
+			return true
+		}
+		_, ok := n.tenant[tenant]
+		return ok
+	}
+
+	nodes := []*node{}
+	for _, n := range graph.uidToNode {
+		if included(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeSortKey(nodes[i]) < nodeSortKey(nodes[j])
+	})
+
+	view := &ObjectGraph{
+		Nodes: []*ObjectGraphNode{},
+		Roots: []*ObjectGraphNode{},
+	}
+	viewNodes := make(map[*node]*ObjectGraphNode, len(nodes))
+	for _, n := range nodes {
+		viewNode := &ObjectGraphNode{
+			Object:     n.identity,
+			Owners:     []ObjectGraphOwner{},
+			Dependants: []*ObjectGraphNode{},
+			Virtual:    n.virtual,
+			Global:     n.isGlobal,
+			ForceMove:  n.forceMove || n.forceMoveHierarchy,
+		}
+		viewNodes[n] = viewNode
+		view.Nodes = append(view.Nodes, viewNode)
+	}
+
+	// NB. Nodes are processed in sorted order, so dependants are sorted as well.
+	for _, n := range nodes {
+		viewNode := viewNodes[n]
+		for _, owner := range sortedNodes(n.owners) {
+			viewOwner, ok := viewNodes[owner]
+			if !ok {
+				continue
+			}
+			attributes := n.owners[owner]
+			viewNode.Owners = append(viewNode.Owners, ObjectGraphOwner{
+				Owner:      viewOwner,
+				Controller: attributes.Controller != nil && *attributes.Controller,
+			})
+			viewOwner.Dependants = append(viewOwner.Dependants, viewNode)
+		}
+		for _, owner := range sortedNodes(n.softOwners) {
+			viewOwner, ok := viewNodes[owner]
+			if !ok {
+				continue
+			}
+			viewNode.Owners = append(viewNode.Owners, ObjectGraphOwner{
+				Owner: viewOwner,
+				Soft:  true,
+			})
+			viewOwner.Dependants = append(viewOwner.Dependants, viewNode)
+		}
+	}
+
+	for _, viewNode := range view.Nodes {
+		if len(viewNode.Owners) == 0 {
+			view.Roots = append(view.Roots, viewNode)
+		}
+	}
+	return view
+}
+
+// sortedNodes returns the keys of a map of nodes, sorted by namespace, kind and name.
+func sortedNodes(m interface{}) []*node {
+	nodes := []*node{}
+	switch m := m.(type) {
+	case map[*node]ownerReferenceAttributes:
+		for n := range m {
+			nodes = append(nodes, n)
+		}
+	case map[*node]empty:
+		for n := range m {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeSortKey(nodes[i]) < nodeSortKey(nodes[j])
+	})
+	return nodes
+}
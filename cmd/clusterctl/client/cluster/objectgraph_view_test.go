@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_newObjectGraphView(t *testing.T) {
+	g := NewWithT(t)
+
+	objs := []client.Object{}
+	objs = append(objs, test.NewFakeCluster("ns1", "cluster1").Objs()...)
+	objs = append(objs, test.NewFakeCluster("ns1", "cluster2").Objs()...)
+
+	graph := getObjectGraphWithObjs(objs)
+	g.Expect(getFakeDiscoveryTypes(graph)).To(Succeed())
+	g.Expect(graph.Discovery("ns1")).To(Succeed())
+
+	t.Run("returns all the objects", func(t *testing.T) {
+		g := NewWithT(t)
+
+		view := newObjectGraphView(graph, nil)
+		g.Expect(view.Nodes).To(HaveLen(len(graph.uidToNode)))
+
+		roots := []string{}
+		for _, n := range view.Roots {
+			roots = append(roots, n.Object.Kind+"/"+n.Object.Name)
+		}
+		g.Expect(roots).To(Equal([]string{"Cluster/cluster1", "Cluster/cluster2"}))
+
+		// Checks owners and dependants are consistent.
+		for _, n := range view.Nodes {
+			for _, owner := range n.Owners {
+				g.Expect(owner.Owner.Dependants).To(ContainElement(n))
+			}
+		}
+	})
+
+	t.Run("returns the objects belonging to a cluster, including soft ownership", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var cluster1 *node
+		for _, c := range graph.getClusters() {
+			if c.identity.Name == "cluster1" {
+				cluster1 = c
+			}
+		}
+		g.Expect(cluster1).NotTo(BeNil())
+
+		view := newObjectGraphView(graph, cluster1)
+		g.Expect(view.Roots).To(HaveLen(1))
+		g.Expect(view.Roots[0].Object.UID).To(Equal(cluster1.identity.UID))
+
+		softOwned := []string{}
+		for _, n := range view.Nodes {
+			g.Expect(strings.HasPrefix(n.Object.Name, "cluster1")).To(BeTrue(), "unexpected object %s/%s", n.Object.Kind, n.Object.Name)
+			for _, owner := range n.Owners {
+				if owner.Soft {
+					softOwned = append(softOwned, n.Object.Kind+"/"+n.Object.Name)
+				}
+			}
+		}
+		g.Expect(softOwned).To(ContainElement("Secret/cluster1-ca"))
+	})
+}
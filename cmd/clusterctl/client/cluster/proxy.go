@@ -17,7 +17,9 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -31,6 +33,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
 	"sigs.k8s.io/cluster-api/version"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -42,7 +45,10 @@ var (
 type proxy struct {
 	kubeconfig         Kubeconfig
 	timeout            time.Duration
+	ctx                context.Context
 	configLoadingRules *clientcmd.ClientConfigLoadingRules
+	fieldManager       string
+	connection         *ConnectionOptions
 }
 
 var _ Proxy = &proxy{}
@@ -120,10 +126,24 @@ func (k *proxy) GetConfig() (*rest.Config, error) {
 	}
 	restConfig.UserAgent = fmt.Sprintf("clusterctl/%s (%s)", version.Get().GitVersion, version.Get().Platform)
 
+	// Apply the connection options on top of the kubeconfig, so they are used by all the requests to the cluster.
+	if k.connection != nil {
+		if err := k.connection.apply(restConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	// Set QPS and Burst to a threshold that ensures the controller runtime client/client go does't generate throttling log messages
 	restConfig.QPS = 20
 	restConfig.Burst = 100
 
+	// Bind all the requests to the proxy context, if any, so cancelling it interrupts in-flight requests.
+	if k.ctx != nil {
+		restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return util.NewContextRoundTripper(k.ctx, rt)
+		})
+	}
+
 	return restConfig, nil
 }
 
@@ -147,9 +167,48 @@ func (k *proxy) NewClient() (client.Client, error) {
 		return nil, errors.Wrap(err, "failed to connect to the management cluster")
 	}
 
+	if k.fieldManager != "" {
+		return &fieldManagerClient{Client: c, fieldOwner: client.FieldOwner(k.fieldManager)}, nil
+	}
 	return c, nil
 }
 
+// fieldManagerClient sets the field manager of all the write requests, unless a request explicitly sets a different one.
+type fieldManagerClient struct {
+	client.Client
+	fieldOwner client.FieldOwner
+}
+
+func (c *fieldManagerClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.Client.Create(ctx, obj, append([]client.CreateOption{c.fieldOwner}, opts...)...)
+}
+
+func (c *fieldManagerClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.Client.Update(ctx, obj, append([]client.UpdateOption{c.fieldOwner}, opts...)...)
+}
+
+func (c *fieldManagerClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Patch(ctx, obj, patch, append([]client.PatchOption{c.fieldOwner}, opts...)...)
+}
+
+func (c *fieldManagerClient) Status() client.StatusWriter {
+	return &fieldManagerStatusWriter{StatusWriter: c.Client.Status(), fieldOwner: c.fieldOwner}
+}
+
+// fieldManagerStatusWriter sets the field manager of all the status write requests, unless a request explicitly sets a different one.
+type fieldManagerStatusWriter struct {
+	client.StatusWriter
+	fieldOwner client.FieldOwner
+}
+
+func (w *fieldManagerStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return w.StatusWriter.Update(ctx, obj, append([]client.UpdateOption{w.fieldOwner}, opts...)...)
+}
+
+func (w *fieldManagerStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return w.StatusWriter.Patch(ctx, obj, patch, append([]client.PatchOption{w.fieldOwner}, opts...)...)
+}
+
 func (k *proxy) ListResources(labels map[string]string, namespaces ...string) ([]unstructured.Unstructured, error) {
 	cs, err := k.newClientSet()
 	if err != nil {
@@ -227,6 +286,30 @@ func InjectProxyTimeout(t time.Duration) ProxyOption {
 	}
 }
 
+// InjectProxyContext binds all the requests to the management cluster to a context, so they are interrupted
+// when the context is cancelled or its deadline expires.
+func InjectProxyContext(ctx context.Context) ProxyOption {
+	return func(p *proxy) {
+		p.ctx = ctx
+	}
+}
+
+// InjectProxyFieldManager sets the field manager of the objects created or updated in the cluster, e.g. for avoiding field
+// ownership conflicts with other tools managing the same objects; if empty, the field manager is derived from the user agent.
+func InjectProxyFieldManager(fieldManager string) ProxyOption {
+	return func(p *proxy) {
+		p.fieldManager = fieldManager
+	}
+}
+
+// InjectProxyConnectionOptions sets the connection options applied on top of the kubeconfig, e.g. a custom CA or the user
+// to impersonate, to all the requests to the cluster.
+func InjectProxyConnectionOptions(options ConnectionOptions) ProxyOption {
+	return func(p *proxy) {
+		p.connection = &options
+	}
+}
+
 // InjectKubeconfigPaths sets the kubeconfig paths loading rules.
 func InjectKubeconfigPaths(paths []string) ProxyOption {
 	return func(p *proxy) {
@@ -234,16 +317,43 @@ func InjectKubeconfigPaths(paths []string) ProxyOption {
 	}
 }
 
-func newProxy(kubeconfig Kubeconfig, opts ...ProxyOption) Proxy {
-	// If a kubeconfig file isn't provided, find one in the standard locations.
+// ValidateKubeconfigContext returns an error if the context explicitly set for a kubeconfig does not exist, so
+// operations fail early instead of connecting to a different cluster.
+func ValidateKubeconfigContext(kubeconfig Kubeconfig) error {
+	if kubeconfig.Context == "" {
+		return nil
+	}
+
+	rules := newConfigLoadingRules(kubeconfig)
+	config, err := rules.Load()
+	if err != nil {
+		return errors.Wrap(err, "failed to load Kubeconfig")
+	}
+
+	if _, ok := config.Contexts[kubeconfig.Context]; !ok {
+		if kubeconfig.Path != "" {
+			return errors.Errorf("context %q does not exist in %q", kubeconfig.Context, rules.GetExplicitFile())
+		}
+		return errors.Errorf("context %q does not exist in %q", kubeconfig.Context, rules.GetLoadingPrecedence())
+	}
+	return nil
+}
+
+// newConfigLoadingRules returns the loading rules for a kubeconfig; if a kubeconfig file isn't provided, the rules
+// find one in the standard locations.
+func newConfigLoadingRules(kubeconfig Kubeconfig) *clientcmd.ClientConfigLoadingRules {
 	rules := clientcmd.NewDefaultClientConfigLoadingRules()
 	if kubeconfig.Path != "" {
 		rules.ExplicitPath = kubeconfig.Path
 	}
+	return rules
+}
+
+func newProxy(kubeconfig Kubeconfig, opts ...ProxyOption) Proxy {
 	p := &proxy{
 		kubeconfig:         kubeconfig,
 		timeout:            30 * time.Second,
-		configLoadingRules: rules,
+		configLoadingRules: newConfigLoadingRules(kubeconfig),
 	}
 
 	for _, o := range opts {
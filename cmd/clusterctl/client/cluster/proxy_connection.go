@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+// ConnectionOptions defines settings for connecting to a cluster that are applied on top of the kubeconfig, e.g. for
+// restricted environments or for audit requirements.
+type ConnectionOptions struct {
+	// CAFile is the path of a PEM file with CA certificates to be trusted in addition to the CA defined in the kubeconfig.
+	CAFile string
+
+	// CertFile and KeyFile are the paths of the client certificate and of the corresponding key used for authenticating
+	// to the cluster, instead of the credentials defined in the kubeconfig; both must be set.
+	CertFile string
+	KeyFile  string
+
+	// ImpersonateUser is the user to impersonate in all the requests to the cluster.
+	ImpersonateUser string
+
+	// ImpersonateGroups are the groups to impersonate in all the requests to the cluster; ImpersonateUser must be set.
+	ImpersonateGroups []string
+}
+
+// validate checks the connection options are consistent, and that the referenced files exist.
+func (o *ConnectionOptions) validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("invalid connection options: both the client certificate and the client key must be set")
+	}
+	if len(o.ImpersonateGroups) > 0 && o.ImpersonateUser == "" {
+		return errors.New("invalid connection options: the user to impersonate must be set when impersonating groups")
+	}
+	for _, f := range []string{o.CAFile, o.CertFile, o.KeyFile} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return errors.Wrapf(err, "invalid connection options: failed to read %q", f)
+		}
+	}
+	return nil
+}
+
+// apply applies the connection options to a rest config, so they are used by all the clients derived from it.
+func (o *ConnectionOptions) apply(config *rest.Config) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	if o.CAFile != "" {
+		ca, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the CA file %q", o.CAFile)
+		}
+		// NB. CAData takes precedence over CAFile, so the CA file defined in the kubeconfig, if any, is merged into CAData.
+		caData := config.TLSClientConfig.CAData
+		if len(caData) == 0 && config.TLSClientConfig.CAFile != "" {
+			caData, err = os.ReadFile(config.TLSClientConfig.CAFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read the CA file %q", config.TLSClientConfig.CAFile)
+			}
+		}
+		if len(caData) > 0 && caData[len(caData)-1] != '\n' {
+			caData = append(caData, '\n')
+		}
+		config.TLSClientConfig.CAData = append(caData, ca...)
+		config.TLSClientConfig.CAFile = ""
+		// NB. client-go does not allow root certificates together with the insecure flag.
+		config.TLSClientConfig.Insecure = false
+	}
+
+	if o.CertFile != "" {
+		// The client certificate replaces any other credential defined in the kubeconfig.
+		config.TLSClientConfig.CertFile = o.CertFile
+		config.TLSClientConfig.KeyFile = o.KeyFile
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyData = nil
+		config.BearerToken = ""
+		config.BearerTokenFile = ""
+		config.Username = ""
+		config.Password = ""
+		config.AuthProvider = nil
+		config.ExecProvider = nil
+	}
+
+	if o.ImpersonateUser != "" {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: o.ImpersonateUser,
+			Groups:   append([]string{}, o.ImpersonateGroups...),
+		}
+	}
+	return nil
+}
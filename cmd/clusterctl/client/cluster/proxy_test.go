@@ -17,6 +17,7 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,8 +25,12 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
 	"sigs.k8s.io/cluster-api/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ Proxy = &test.FakeProxy{}
@@ -100,11 +105,133 @@ func TestProxyGetConfig(t *testing.T) {
 		g.Expect(err).ToNot(HaveOccurred())
 		g.Expect(conf.Timeout.String()).To(Equal("23s"))
 	})
+
+	t.Run("configure connection options", func(t *testing.T) {
+		dir, err := os.MkdirTemp("", "clusterctl")
+		NewWithT(t).Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		configFile := filepath.Join(dir, ".test-kubeconfig.yaml")
+		caFile := filepath.Join(dir, "ca.crt")
+		certFile := filepath.Join(dir, "client.crt")
+		keyFile := filepath.Join(dir, "client.key")
+		for file, contents := range map[string]string{configFile: kubeconfig("management", "default"), caFile: "ca", certFile: "cert", keyFile: "key"} {
+			NewWithT(t).Expect(os.WriteFile(file, []byte(contents), 0600)).To(Succeed())
+		}
+
+		tests := []struct {
+			name      string
+			options   ConnectionOptions
+			expectErr bool
+			check     func(g *WithT, conf *rest.Config)
+		}{
+			{
+				name:    "custom CA",
+				options: ConnectionOptions{CAFile: caFile},
+				check: func(g *WithT, conf *rest.Config) {
+					g.Expect(string(conf.CAData)).To(Equal("ca"))
+					g.Expect(conf.CAFile).To(BeEmpty())
+					g.Expect(conf.Insecure).To(BeFalse())
+					// The credentials defined in the kubeconfig are preserved.
+					g.Expect(conf.CertData).ToNot(BeEmpty())
+				},
+			},
+			{
+				name:    "client certificate",
+				options: ConnectionOptions{CertFile: certFile, KeyFile: keyFile},
+				check: func(g *WithT, conf *rest.Config) {
+					g.Expect(conf.CertFile).To(Equal(certFile))
+					g.Expect(conf.KeyFile).To(Equal(keyFile))
+					g.Expect(conf.CertData).To(BeEmpty())
+					g.Expect(conf.KeyData).To(BeEmpty())
+				},
+			},
+			{
+				name:    "impersonation",
+				options: ConnectionOptions{ImpersonateUser: "jane", ImpersonateGroups: []string{"ops", "audit"}},
+				check: func(g *WithT, conf *rest.Config) {
+					g.Expect(conf.Impersonate.UserName).To(Equal("jane"))
+					g.Expect(conf.Impersonate.Groups).To(Equal([]string{"ops", "audit"}))
+				},
+			},
+			{
+				name:      "fails if the client key is missing",
+				options:   ConnectionOptions{CertFile: certFile},
+				expectErr: true,
+			},
+			{
+				name:      "fails if groups are impersonated without a user",
+				options:   ConnectionOptions{ImpersonateGroups: []string{"ops"}},
+				expectErr: true,
+			},
+			{
+				name:      "fails if the CA file does not exist",
+				options:   ConnectionOptions{CAFile: filepath.Join(dir, "does-not-exist")},
+				expectErr: true,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				g := NewWithT(t)
+
+				proxy := newProxy(Kubeconfig{Path: configFile, Context: "management"}, InjectProxyConnectionOptions(tt.options))
+				conf, err := proxy.GetConfig()
+				if tt.expectErr {
+					g.Expect(err).To(HaveOccurred())
+					return
+				}
+				g.Expect(err).ToNot(HaveOccurred())
+				tt.check(g, conf)
+			})
+		}
+	})
 }
 
 // These tests are emulating the files passed in via KUBECONFIG env var by
 // injecting the file paths into the ClientConfigLoadingRules.Precedence
 // chain.
+// fieldManagerRecorder is a client recording the field manager of the write requests.
+type fieldManagerRecorder struct {
+	client.Client
+	fieldManagers []string
+}
+
+func (r *fieldManagerRecorder) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	r.fieldManagers = append(r.fieldManagers, (&client.CreateOptions{}).ApplyOptions(opts).FieldManager)
+	return r.Client.Create(ctx, obj, opts...)
+}
+
+func (r *fieldManagerRecorder) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	r.fieldManagers = append(r.fieldManagers, (&client.UpdateOptions{}).ApplyOptions(opts).FieldManager)
+	return r.Client.Update(ctx, obj, opts...)
+}
+
+func (r *fieldManagerRecorder) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	r.fieldManagers = append(r.fieldManagers, (&client.PatchOptions{}).ApplyOptions(opts).FieldManager)
+	return r.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func Test_fieldManagerClient(t *testing.T) {
+	g := NewWithT(t)
+
+	c, err := test.NewFakeProxy().NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	recorder := &fieldManagerRecorder{Client: c}
+	fieldManagerClient := &fieldManagerClient{Client: recorder, fieldOwner: client.FieldOwner("gitops")}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cm1"},
+	}
+	g.Expect(fieldManagerClient.Create(ctx, cm)).To(Succeed())
+	g.Expect(fieldManagerClient.Update(ctx, cm)).To(Succeed())
+	g.Expect(fieldManagerClient.Patch(ctx, cm, client.Merge)).To(Succeed())
+
+	// Field managers explicitly set by a request take precedence.
+	g.Expect(fieldManagerClient.Patch(ctx, cm, client.Merge, client.FieldOwner("other"))).To(Succeed())
+
+	g.Expect(recorder.fieldManagers).To(Equal([]string{"gitops", "gitops", "gitops", "other"}))
+}
+
 func TestKUBECONFIGEnvVar(t *testing.T) {
 	t.Run("CurrentNamespace", func(t *testing.T) {
 		// KUBECONFIG can specify multiple config files. We should be able to
@@ -240,6 +367,60 @@ func TestProxyCurrentNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateKubeconfigContext(t *testing.T) {
+	tests := []struct {
+		name              string
+		kubeconfigPath    string
+		kubeconfigContext string
+		expectErr         bool
+	}{
+		{
+			name:              "pass if the context is not set",
+			kubeconfigPath:    "do-not-exist",
+			kubeconfigContext: "",
+			expectErr:         false,
+		},
+		{
+			name:              "pass if the context exists",
+			kubeconfigContext: "workload",
+			expectErr:         false,
+		},
+		{
+			name:              "return error if the context does not exist",
+			kubeconfigContext: "does-not-exist",
+			expectErr:         true,
+		},
+		{
+			name:              "return error for invalid kubeconfig path",
+			kubeconfigPath:    "do-not-exist",
+			kubeconfigContext: "workload",
+			expectErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var configFile string
+			if len(tt.kubeconfigPath) != 0 {
+				configFile = tt.kubeconfigPath
+			} else {
+				dir, err := os.MkdirTemp("", "clusterctl")
+				g.Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(dir)
+				configFile = filepath.Join(dir, ".test-kubeconfig.yaml")
+				g.Expect(os.WriteFile(configFile, []byte(kubeconfig("management", "")), 0600)).To(Succeed())
+			}
+
+			err := ValidateKubeconfigContext(Kubeconfig{Path: configFile, Context: tt.kubeconfigContext})
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
 func kubeconfig(currentContext, namespace string) string {
 	return fmt.Sprintf(`---
 apiVersion: v1
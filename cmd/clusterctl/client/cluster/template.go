@@ -19,6 +19,7 @@ package cluster
 import (
 	"context"
 	"encoding/base64"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -41,6 +42,9 @@ type TemplateClient interface {
 
 	// GetFromURL returns a workload cluster template from the given URL.
 	GetFromURL(templateURL, targetNamespace string, skipTemplateProcess bool) (repository.Template, error)
+
+	// GetFromRaw returns a workload cluster template from the given YAML content.
+	GetFromRaw(content []byte, targetNamespace string, skipTemplateProcess bool) (repository.Template, error)
 }
 
 // templateClient implements TemplateClient.
@@ -127,6 +131,20 @@ func (t *templateClient) GetFromURL(templateURL, targetNamespace string, skipTem
 	})
 }
 
+func (t *templateClient) GetFromRaw(content []byte, targetNamespace string, skipTemplateProcess bool) (repository.Template, error) {
+	if len(content) == 0 {
+		return nil, errors.New("invalid GetFromRaw operation: missing content value")
+	}
+
+	return repository.NewTemplate(repository.TemplateInput{
+		RawArtifact:           content,
+		ConfigVariablesClient: t.configClient.Variables(),
+		Processor:             t.processor,
+		TargetNamespace:       targetNamespace,
+		SkipTemplateProcess:   skipTemplateProcess,
+	})
+}
+
 func (t *templateClient) getURLContent(templateURL string) ([]byte, error) {
 	rURL, err := url.Parse(templateURL)
 	if err != nil {
@@ -137,11 +155,42 @@ func (t *templateClient) getURLContent(templateURL string) ([]byte, error) {
 		return t.getGitHubFileContent(rURL)
 	}
 
+	if rURL.Scheme == "http" || rURL.Scheme == "https" {
+		return t.getHTTPFileContent(rURL)
+	}
+
 	if rURL.Scheme == "file" || rURL.Scheme == "" {
 		return t.getLocalFileContent(rURL)
 	}
 
-	return nil, errors.Errorf("unable to read content from %q. Only reading from GitHub and local file system is supported", templateURL)
+	return nil, errors.Errorf("unable to read content from %q. Only reading from GitHub, HTTP(S) URLs and local file system is supported", templateURL)
+}
+
+func (t *templateClient) getHTTPFileContent(rURL *url.URL) ([]byte, error) {
+	httpClient, err := repository.NewHTTPClient(t.configClient.Variables())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rURL.String(), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the request for %q", rURL)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %q", rURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get %q: unexpected status %s", rURL, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", rURL)
+	}
+	return content, nil
 }
 
 func (t *templateClient) getLocalFileContent(rURL *url.URL) ([]byte, error) {
@@ -201,15 +250,19 @@ func (t *templateClient) getGitHubFileContent(rURL *url.URL) ([]byte, error) {
 }
 
 func getGitHubClient(configVariablesClient config.VariablesClient) (*github.Client, error) {
-	var authenticatingHTTPClient *http.Client
+	httpClient, err := repository.NewHTTPClient(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
 	if token, err := configVariablesClient.Get(config.GitHubTokenVariable); err == nil {
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
-		authenticatingHTTPClient = oauth2.NewClient(context.TODO(), ts)
+		httpClient = oauth2.NewClient(context.WithValue(context.TODO(), oauth2.HTTPClient, httpClient), ts)
 	}
 
-	return github.NewClient(authenticatingHTTPClient), nil
+	return github.NewClient(httpClient), nil
 }
 
 // handleGithubErr wraps error messages.
@@ -20,6 +20,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -307,6 +308,15 @@ func Test_templateClient_GetFromURL(t *testing.T) {
 	path := filepath.Join(tmpDir, "cluster-template.yaml")
 	g.Expect(os.WriteFile(path, []byte(template), 0600)).To(Succeed())
 
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cluster-template.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, template)
+	}))
+	defer server.Close()
+
 	type args struct {
 		templateURL         string
 		targetNamespace     string
@@ -338,6 +348,25 @@ func Test_templateClient_GetFromURL(t *testing.T) {
 			want:    template,
 			wantErr: false,
 		},
+		{
+			name: "Get from an HTTP URL",
+			args: args{
+				templateURL:         server.URL + "/cluster-template.yaml",
+				targetNamespace:     "",
+				skipTemplateProcess: false,
+			},
+			want:    template,
+			wantErr: false,
+		},
+		{
+			name: "Fails if the HTTP URL does not exist",
+			args: args{
+				templateURL:         server.URL + "/does-not-exist.yaml",
+				targetNamespace:     "",
+				skipTemplateProcess: false,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -372,6 +401,32 @@ func Test_templateClient_GetFromURL(t *testing.T) {
 	}
 }
 
+func Test_templateClient_GetFromRaw(t *testing.T) {
+	g := NewWithT(t)
+
+	configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	processor := yaml.NewSimpleProcessor()
+	c := newTemplateClient(TemplateClientInput{nil, configClient, processor})
+
+	_, err = c.GetFromRaw(nil, "ns1", false)
+	g.Expect(err).To(HaveOccurred())
+
+	got, err := c.GetFromRaw([]byte(template), "ns1", true)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	wantTemplate, err := repository.NewTemplate(repository.TemplateInput{
+		RawArtifact:           []byte(template),
+		ConfigVariablesClient: configClient.Variables(),
+		Processor:             processor,
+		TargetNamespace:       "ns1",
+		SkipTemplateProcess:   true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal(wantTemplate))
+}
+
 func mustParseURL(rawURL string) *url.URL {
 	rURL, err := url.Parse(rawURL)
 	if err != nil {
@@ -17,6 +17,10 @@ limitations under the License.
 package cluster
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/version"
@@ -39,12 +43,33 @@ type ProviderUpgrader interface {
 
 	// ApplyCustomPlan plan executes an upgrade using the UpgradeItems provided by the user.
 	ApplyCustomPlan(providersToUpgrade ...UpgradeItem) error
+
+	// DryRunPlan returns the changes ApplyPlan is going to apply to the provider components, without applying them.
+	DryRunPlan(clusterAPIVersion string) (*UpgradeDiff, error)
+
+	// DryRunCustomPlan returns the changes ApplyCustomPlan is going to apply to the provider components, without applying them.
+	DryRunCustomPlan(providersToUpgrade ...UpgradeItem) (*UpgradeDiff, error)
+
+	// Rollback restores the provider components as they were before an upgrade attempt.
+	// If the attempt ID is empty, the latest upgrade attempt is rolled back.
+	Rollback(attemptID string) error
+
+	// CheckVersionSkew returns the providers supporting an API Version of Cluster API (contract) different from the
+	// one supported by the core provider, with the recommended target versions for fixing the skew.
+	CheckVersionSkew() ([]VersionSkew, error)
 }
 
 // UpgradePlan defines a list of possible upgrade targets for a management cluster.
 type UpgradePlan struct {
-	Contract  string
-	Providers []UpgradeItem
+	// Contract is the API Version of Cluster API (contract) targeted by the upgrade plan.
+	Contract string `json:"contract"`
+
+	// Providers lists the upgrade targets for the providers in the management cluster.
+	Providers []UpgradeItem `json:"providers"`
+
+	// CrossesContract is true if the upgrade plan requires the management cluster to move to a different
+	// API Version of Cluster API (contract) than the one currently supported by the core provider.
+	CrossesContract bool `json:"crossesContract"`
 }
 
 // isPartialUpgrade returns true if at least one upgradeItem in the plan does not have a target version.
@@ -61,6 +86,10 @@ func (u *UpgradePlan) isPartialUpgrade() bool {
 type UpgradeItem struct {
 	clusterctlv1.Provider
 	NextVersion string
+
+	// Changelog lists the release notes of the versions between the current version and the next version, if requested
+	// when planning the upgrade and if published by the provider.
+	Changelog []repository.ReleaseNotes
 }
 
 // UpgradeRef returns a string identifying the upgrade item; this string is derived by the provider.
@@ -68,11 +97,36 @@ func (u *UpgradeItem) UpgradeRef() string {
 	return u.InstanceName()
 }
 
+// MarshalJSON serializes an UpgradeItem as the identity of the provider, its current version and the next
+// version, if any, omitting the other fields of the provider object, e.g. the managed fields.
+func (u UpgradeItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name         string                    `json:"name"`
+		Namespace    string                    `json:"namespace"`
+		ProviderName string                    `json:"providerName"`
+		Type         string                    `json:"type"`
+		Version      string                    `json:"version"`
+		NextVersion  string                    `json:"nextVersion"`
+		Changelog    []repository.ReleaseNotes `json:"changelog,omitempty"`
+	}{
+		Name:         u.Name,
+		Namespace:    u.Namespace,
+		ProviderName: u.ProviderName,
+		Type:         u.Type,
+		Version:      u.Version,
+		NextVersion:  u.NextVersion,
+		Changelog:    u.Changelog,
+	})
+}
+
 type providerUpgrader struct {
 	configClient            config.Client
+	proxy                   Proxy
 	repositoryClientFactory RepositoryClientFactory
 	providerInventory       InventoryClient
 	providerComponents      ComponentsClient
+	includePrereleases      bool
+	eventFunc               EventFunc
 }
 
 var _ ProviderUpgrader = &providerUpgrader{}
@@ -133,6 +187,7 @@ func (u *providerUpgrader) Plan() ([]UpgradePlan, error) {
 			continue
 		}
 
+		upgradePlan.CrossesContract = coreUpgradeInfo.currentContract != contract
 		ret = append(ret, *upgradePlan)
 	}
 
@@ -140,26 +195,42 @@ func (u *providerUpgrader) Plan() ([]UpgradePlan, error) {
 }
 
 func (u *providerUpgrader) ApplyPlan(contract string) error {
-	if contract != clusterv1.GroupVersion.Version {
-		return errors.Errorf("current version of clusterctl could only upgrade to %s contract, requested %s", clusterv1.GroupVersion.Version, contract)
-	}
-
 	log := logf.Log
 	log.Info("Performing upgrade...")
 
-	// Gets the upgrade plan for the selected API Version of Cluster API (contract).
-	providerList, err := u.providerInventory.List()
+	upgradePlan, err := u.getUpgradePlanForContract(contract)
 	if err != nil {
 		return err
 	}
 
-	upgradePlan, err := u.getUpgradePlan(providerList.Items, contract)
+	// Do the upgrade
+	return u.doUpgrade(upgradePlan)
+}
+
+func (u *providerUpgrader) DryRunPlan(contract string) (*UpgradeDiff, error) {
+	log := logf.Log
+	log.Info("Computing upgrade changes...")
+
+	upgradePlan, err := u.getUpgradePlanForContract(contract)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Do the upgrade
-	return u.doUpgrade(upgradePlan)
+	return u.diffUpgrade(upgradePlan)
+}
+
+// getUpgradePlanForContract returns the upgrade plan for the selected API Version of Cluster API (contract).
+func (u *providerUpgrader) getUpgradePlanForContract(contract string) (*UpgradePlan, error) {
+	if contract != clusterv1.GroupVersion.Version {
+		return nil, errors.Errorf("current version of clusterctl could only upgrade to %s contract, requested %s", clusterv1.GroupVersion.Version, contract)
+	}
+
+	providerList, err := u.providerInventory.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return u.getUpgradePlan(providerList.Items, contract)
 }
 
 func (u *providerUpgrader) ApplyCustomPlan(upgradeItems ...UpgradeItem) error {
@@ -177,6 +248,18 @@ func (u *providerUpgrader) ApplyCustomPlan(upgradeItems ...UpgradeItem) error {
 	return u.doUpgrade(upgradePlan)
 }
 
+func (u *providerUpgrader) DryRunCustomPlan(upgradeItems ...UpgradeItem) (*UpgradeDiff, error) {
+	log := logf.Log
+	log.Info("Computing upgrade changes...")
+
+	upgradePlan, err := u.createCustomPlan(upgradeItems)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.diffUpgrade(upgradePlan)
+}
+
 // getUpgradePlan returns the upgrade plan for a specific set of providers/contract
 // NB. this function is used both for upgrade plan and upgrade apply.
 func (u *providerUpgrader) getUpgradePlan(providers []clusterctlv1.Provider, contract string) (*UpgradePlan, error) {
@@ -206,7 +289,14 @@ func (u *providerUpgrader) getUpgradePlan(providers []clusterctlv1.Provider, con
 
 // createCustomPlan creates a custom upgrade plan from a set of upgrade items, taking care of ensuring all the providers
 // in a management cluster are consistent with the API Version of Cluster API (contract).
+// Upgrade items without a NextVersion are upgraded to the latest version available for the target contract; this allows
+// e.g. to upgrade a single provider independently from the others.
 func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*UpgradePlan, error) {
+	log := logf.Log
+
+	// Work on a copy of the upgrade items, given that missing target versions are going to be defaulted.
+	upgradeItems = append([]UpgradeItem{}, upgradeItems...)
+
 	// Gets the API Version of Cluster API (contract).
 	// The this is required to ensure all the providers in a management cluster are consistent with the contract supported by the core provider.
 	// e.g if the core provider is v1alpha3, all the provider should be v1alpha3 as well.
@@ -224,11 +314,23 @@ func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*Upgrad
 	coreProvider := coreProviders[0]
 
 	targetCoreProviderVersion := coreProvider.Version
-	for _, providerToUpgrade := range upgradeItems {
-		if providerToUpgrade.InstanceName() == coreProvider.InstanceName() {
-			targetCoreProviderVersion = providerToUpgrade.NextVersion
-			break
+	for i := range upgradeItems {
+		if upgradeItems[i].InstanceName() != coreProvider.InstanceName() {
+			continue
+		}
+
+		// If the target version for the core provider is not specified, use the latest version available for the current contract.
+		if upgradeItems[i].NextVersion == "" {
+			coreUpgradeInfo, err := u.getUpgradeInfo(coreProvider)
+			if err != nil {
+				return nil, err
+			}
+			upgradeItems[i].NextVersion = versionTag(coreUpgradeInfo.getLatestNextVersion(coreUpgradeInfo.currentContract))
+		}
+		if upgradeItems[i].NextVersion != "" {
+			targetCoreProviderVersion = upgradeItems[i].NextVersion
 		}
+		break
 	}
 
 	targetContract, err := u.getProviderContractByVersion(coreProvider, targetCoreProviderVersion)
@@ -246,6 +348,8 @@ func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*Upgrad
 		Contract: targetContract,
 	}
 
+	// Keeps track of the providers conflicting with the target contract, so all of them can be reported at once.
+	conflicts := []string{}
 	for _, upgradeItem := range upgradeItems {
 		// Match the upgrade item with the corresponding provider in the management cluster
 		var provider *clusterctlv1.Provider
@@ -259,6 +363,20 @@ func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*Upgrad
 			return nil, errors.Errorf("unable to complete that upgrade: the provider %s in not part of the management cluster", upgradeItem.InstanceName())
 		}
 
+		// If the target version is not specified, use the latest version available for the target contract.
+		if upgradeItem.NextVersion == "" {
+			providerUpgradeInfo, err := u.getUpgradeInfo(*provider)
+			if err != nil {
+				return nil, err
+			}
+			upgradeItem.NextVersion = versionTag(providerUpgradeInfo.getLatestNextVersion(targetContract))
+			if upgradeItem.NextVersion == "" {
+				// The provider is already up-to-date; it will be checked for consistency with the target contract below, like all the other providers not included in the upgrade plan.
+				log.Info("Provider already up-to-date", "Provider", upgradeItem.InstanceName(), "Version", provider.Version, "Contract", targetContract)
+				continue
+			}
+		}
+
 		// Retrieves the contract that is supported by the target version of the provider.
 		contract, err := u.getProviderContractByVersion(*provider, upgradeItem.NextVersion)
 		if err != nil {
@@ -266,7 +384,8 @@ func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*Upgrad
 		}
 
 		if contract != targetContract {
-			return nil, errors.Errorf("unable to complete that upgrade: the target version for the provider %s supports the %s API Version of Cluster API (contract), while the management cluster is using %s", upgradeItem.InstanceName(), contract, targetContract)
+			conflicts = append(conflicts, fmt.Sprintf("the target version %s for the provider %s supports the %s contract", upgradeItem.NextVersion, upgradeItem.InstanceName(), contract))
+			continue
 		}
 
 		upgradePlan.Providers = append(upgradePlan.Providers, upgradeItem)
@@ -287,9 +406,13 @@ func (u *providerUpgrader) createCustomPlan(upgradeItems []UpgradeItem) (*Upgrad
 		}
 
 		if contract != targetContract {
-			return nil, errors.Errorf("unable to complete that upgrade: the provider %s supports the %s API Version of Cluster API (contract), while the management cluster is being updated to %s. Please include the %[1]s provider in the upgrade", provider.InstanceName(), contract, targetContract)
+			conflicts = append(conflicts, fmt.Sprintf("the provider %s supports the %s contract, please include it in the upgrade", provider.InstanceName(), contract))
 		}
 	}
+
+	if len(conflicts) > 0 {
+		return nil, errors.Errorf("unable to complete that upgrade: the management cluster is using the %s API Version of Cluster API (contract), but %s", targetContract, strings.Join(conflicts, "; "))
+	}
 	return upgradePlan, nil
 }
 
@@ -344,6 +467,12 @@ func (u *providerUpgrader) doUpgrade(upgradePlan *UpgradePlan) error {
 		}
 	}
 
+	// Before doing any change, saves a snapshot of the current provider components, so it is possible to rollback in case of failures.
+	attemptID, err := u.snapshotUpgrade(upgradePlan)
+	if err != nil {
+		return err
+	}
+
 	for _, upgradeItem := range upgradePlan.Providers {
 		// If there is not a specified next version, skip it (we are already up-to-date).
 		if upgradeItem.NextVersion == "" {
@@ -351,23 +480,25 @@ func (u *providerUpgrader) doUpgrade(upgradePlan *UpgradePlan) error {
 		}
 
 		// Gets the provider components for the target version.
+		u.eventFunc.Notify(FetchingComponentsEvent, upgradeItem.ManifestLabel(), "Fetching the components for version %s", upgradeItem.NextVersion)
 		components, err := u.getUpgradeComponents(upgradeItem)
 		if err != nil {
 			return err
 		}
 
 		// Delete the provider, preserving CRD and namespace.
+		u.eventFunc.Notify(DeletingComponentsEvent, upgradeItem.ManifestLabel(), "Deleting the components of version %s", upgradeItem.Version)
 		if err := u.providerComponents.Delete(DeleteOptions{
 			Provider:         upgradeItem.Provider,
 			IncludeNamespace: false,
 			IncludeCRDs:      false,
 		}); err != nil {
-			return err
+			return errors.Wrapf(err, "upgrade attempt %s failed, it can be rolled back using the attempt ID", attemptID)
 		}
 
 		// Install the new version of the provider components.
-		if err := installComponentsAndUpdateInventory(components, u.providerComponents, u.providerInventory); err != nil {
-			return err
+		if err := installComponentsAndUpdateInventory(components, u.providerComponents, u.providerInventory, nil, u.eventFunc); err != nil {
+			return errors.Wrapf(err, "upgrade attempt %s failed, it can be rolled back using the attempt ID", attemptID)
 		}
 	}
 
@@ -378,12 +509,66 @@ func (u *providerUpgrader) doUpgrade(upgradePlan *UpgradePlan) error {
 		}
 	}
 
+	// Once the upgrade is completed, deletes the snapshots of the previous upgrade attempts.
+	if err := pruneUpgradeSnapshots(u.proxy, upgradeSnapshotsToKeep); err != nil {
+		return errors.Wrapf(err, "upgrade attempt %s completed, but failed to delete the snapshots of the previous upgrade attempts", attemptID)
+	}
 	return nil
 }
 
-func newProviderUpgrader(configClient config.Client, repositoryClientFactory RepositoryClientFactory, providerInventory InventoryClient, providerComponents ComponentsClient) *providerUpgrader {
+// diffUpgrade computes the changes doUpgrade is going to apply to the CRDs and Deployments of the providers in the upgrade plan.
+func (u *providerUpgrader) diffUpgrade(upgradePlan *UpgradePlan) (*UpgradeDiff, error) {
+	diff := &UpgradeDiff{}
+	for _, upgradeItem := range upgradePlan.Providers {
+		// If there is not a specified next version, skip it (we are already up-to-date).
+		if upgradeItem.NextVersion == "" {
+			continue
+		}
+
+		// Gets the provider components for the target version.
+		components, err := u.getUpgradeComponents(upgradeItem)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := u.providerComponents.Diff(upgradeItem.Provider, components.Objs())
+		if err != nil {
+			return nil, err
+		}
+		diff.Changes = append(diff.Changes, changes...)
+	}
+	sortComponentChanges(diff.Changes)
+	return diff, nil
+}
+
+// snapshotUpgrade saves the components of the providers to be upgraded, as installed in the management cluster, and returns the ID of the upgrade attempt.
+func (u *providerUpgrader) snapshotUpgrade(upgradePlan *UpgradePlan) (string, error) {
+	log := logf.Log
+
+	attemptID := newUpgradeAttemptID()
+	for _, upgradeItem := range upgradePlan.Providers {
+		if upgradeItem.NextVersion == "" {
+			continue
+		}
+
+		// Gets the provider components currently installed in the management cluster.
+		objs, err := getUpgradeSnapshotObjs(u.proxy, upgradeItem.Provider)
+		if err != nil {
+			return "", err
+		}
+
+		if err := saveUpgradeSnapshot(u.proxy, attemptID, upgradeItem.Provider, objs); err != nil {
+			return "", err
+		}
+	}
+	log.Info("Saved the provider components for rollback", "AttemptID", attemptID)
+	return attemptID, nil
+}
+
+func newProviderUpgrader(configClient config.Client, proxy Proxy, repositoryClientFactory RepositoryClientFactory, providerInventory InventoryClient, providerComponents ComponentsClient) *providerUpgrader {
 	return &providerUpgrader{
 		configClient:            configClient,
+		proxy:                   proxy,
 		repositoryClientFactory: repositoryClientFactory,
 		providerInventory:       providerInventory,
 		providerComponents:      providerComponents,
@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/version"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 )
 
 // upgradeInfo holds all the information required for taking upgrade decisions for a provider.
@@ -41,6 +42,9 @@ type upgradeInfo struct {
 	// nextVersions return the list of versions available for upgrades, defined as the list of version available in the provider repository
 	// greater than the currentVersion.
 	nextVersions []version.Version
+
+	// includePrereleases makes prerelease versions eligible as upgrade targets.
+	includePrereleases bool
 }
 
 // getUpgradeInfo returns all the info required for taking upgrade decisions for a provider.
@@ -116,7 +120,13 @@ func (u *providerUpgrader) getUpgradeInfo(provider clusterctlv1.Provider) (*upgr
 		nextVersions = append(nextVersions, *repositorySemVersion)
 	}
 
-	return newUpgradeInfo(latestMetadata, currentVersion, nextVersions), nil
+	upgradeInfo := newUpgradeInfo(latestMetadata, currentVersion, nextVersions)
+	upgradeInfo.includePrereleases, err = repository.IsPrereleaseIncluded(u.configClient.Variables())
+	if err != nil {
+		return nil, err
+	}
+	upgradeInfo.includePrereleases = upgradeInfo.includePrereleases || u.includePrereleases
+	return upgradeInfo, nil
 }
 
 func newUpgradeInfo(metadata *clusterctlv1.Metadata, currentVersion *version.Version, nextVersions []version.Version) *upgradeInfo {
@@ -174,10 +184,10 @@ func (i *upgradeInfo) getLatestNextVersion(contract string) *version.Version {
 			nextVersion := &i.nextVersions[j]
 
 			// Drop the nextVersion version if not linked with the current
-			// release series or if it is a pre-release.
+			// release series or if it is a pre-release, unless pre-releases are included.
 			if nextVersion.Major() != releaseSeries.Major ||
 				nextVersion.Minor() != releaseSeries.Minor ||
-				nextVersion.PreRelease() != "" {
+				(nextVersion.PreRelease() != "" && !i.includePrereleases) {
 				continue
 			}
 
@@ -332,9 +332,10 @@ func Test_upgradeInfo_getContractsForUpgrade(t *testing.T) {
 
 func Test_upgradeInfo_getLatestNextVersion(t *testing.T) {
 	type field struct {
-		currentVersion string
-		nextVersions   []string
-		metadata       *clusterctlv1.Metadata
+		currentVersion     string
+		nextVersions       []string
+		metadata           *clusterctlv1.Metadata
+		includePrereleases bool
 	}
 	type args struct {
 		contract string
@@ -413,12 +414,48 @@ func Test_upgradeInfo_getLatestNextVersion(t *testing.T) {
 			},
 			want: "v2.0.2", // skipping v2.0.1 because it is not the latest version available; ignoring v1.* because linked to a different contract
 		},
+		{
+			name: "Skip pre-releases by default",
+			field: field{
+				currentVersion: "v1.2.3",
+				nextVersions:   []string{"v1.2.4", "v1.3.0-rc.0"},
+				metadata: &clusterctlv1.Metadata{
+					ReleaseSeries: []clusterctlv1.ReleaseSeries{
+						{Major: 1, Minor: 2, Contract: test.CurrentCAPIContract},
+						{Major: 1, Minor: 3, Contract: test.CurrentCAPIContract},
+					},
+				},
+			},
+			args: args{
+				contract: test.CurrentCAPIContract,
+			},
+			want: "v1.2.4",
+		},
+		{
+			name: "Find a pre-release upgrade version if pre-releases are included",
+			field: field{
+				currentVersion: "v1.2.3",
+				nextVersions:   []string{"v1.2.4", "v1.3.0-rc.0", "v1.3.0-beta.1"},
+				metadata: &clusterctlv1.Metadata{
+					ReleaseSeries: []clusterctlv1.ReleaseSeries{
+						{Major: 1, Minor: 2, Contract: test.CurrentCAPIContract},
+						{Major: 1, Minor: 3, Contract: test.CurrentCAPIContract},
+					},
+				},
+				includePrereleases: true,
+			},
+			args: args{
+				contract: test.CurrentCAPIContract,
+			},
+			want: "v1.3.0-rc.0", // pre-releases are ordered according to semantic versioning
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
 
 			upgradeInfo := newUpgradeInfo(tt.field.metadata, version.MustParseSemantic(tt.field.currentVersion), toSemanticVersions(tt.field.nextVersions))
+			upgradeInfo.includePrereleases = tt.field.includePrereleases
 
 			got := upgradeInfo.getLatestNextVersion(tt.args.contract)
 			g.Expect(versionTag(got)).To(Equal(tt.want))
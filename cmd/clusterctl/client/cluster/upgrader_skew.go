@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+)
+
+// VersionSkew describes a provider whose version violates the version skew policy, that requires all the providers
+// in a management cluster to support the same API Version of Cluster API (contract) of the core provider.
+type VersionSkew struct {
+	// Provider is the inventory object of the provider.
+	Provider clusterctlv1.Provider `json:"provider"`
+
+	// Contract supported by the current version of the provider; it is empty if the contract can't be determined,
+	// e.g. because the provider repository is not defined in the clusterctl configuration.
+	Contract string `json:"contract"`
+
+	// CoreContract is the contract supported by the current version of the core provider.
+	CoreContract string `json:"coreContract"`
+
+	// RecommendedVersion is the latest version of the provider supporting CoreContract, if any.
+	RecommendedVersion string `json:"recommendedVersion,omitempty"`
+
+	// RecommendedCoreVersion is the latest version of the core provider supporting Contract, if any; this applies
+	// when the provider is ahead of the core provider.
+	RecommendedCoreVersion string `json:"recommendedCoreVersion,omitempty"`
+
+	// Message describes the version skew in a human readable form.
+	Message string `json:"message"`
+}
+
+func (u *providerUpgrader) CheckVersionSkew() ([]VersionSkew, error) {
+	providerList, err := u.providerInventory.List()
+	if err != nil {
+		return nil, err
+	}
+
+	coreProviders := providerList.FilterCore()
+	if len(coreProviders) != 1 {
+		return nil, errors.Errorf("invalid management cluster: there should a core provider, found %d", len(coreProviders))
+	}
+	coreProvider := coreProviders[0]
+
+	coreUpgradeInfo, err := u.getUpgradeInfo(coreProvider)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the contract for the %s provider", coreProvider.InstanceName())
+	}
+	coreContract := coreUpgradeInfo.currentContract
+
+	ret := []VersionSkew{}
+	for _, provider := range providerList.Items {
+		if provider.InstanceName() == coreProvider.InstanceName() {
+			continue
+		}
+
+		// NB. Providers installed outside of clusterctl could point to a repository not defined in the clusterctl
+		// configuration; in this case the version skew is reported without a recommendation, instead of failing.
+		providerUpgradeInfo, err := u.getUpgradeInfo(provider)
+		if err != nil {
+			ret = append(ret, VersionSkew{
+				Provider:     provider,
+				CoreContract: coreContract,
+				Message:      fmt.Sprintf("unable to determine the contract for the %s provider: %v", provider.InstanceName(), err),
+			})
+			continue
+		}
+
+		if providerUpgradeInfo.currentContract == coreContract {
+			continue
+		}
+
+		skew := VersionSkew{
+			Provider:               provider,
+			Contract:               providerUpgradeInfo.currentContract,
+			CoreContract:           coreContract,
+			RecommendedVersion:     versionTag(providerUpgradeInfo.getLatestNextVersion(coreContract)),
+			RecommendedCoreVersion: versionTag(coreUpgradeInfo.getLatestNextVersion(providerUpgradeInfo.currentContract)),
+		}
+		skew.Message = fmt.Sprintf("the %s provider version %s supports the %s contract, while the core provider %s version %s supports the %s contract",
+			provider.InstanceName(), provider.Version, skew.Contract, coreProvider.InstanceName(), coreProvider.Version, coreContract)
+		switch {
+		case skew.RecommendedVersion != "":
+			skew.Message += fmt.Sprintf("; upgrade the %s provider to %s", provider.InstanceName(), skew.RecommendedVersion)
+		case skew.RecommendedCoreVersion != "":
+			skew.Message += fmt.Sprintf("; upgrade the core provider to %s", skew.RecommendedCoreVersion)
+		}
+		ret = append(ret, skew)
+	}
+	return ret, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_providerUpgrader_CheckVersionSkew(t *testing.T) {
+	coreRepository := test.NewFakeRepository().
+		WithVersions("v1.0.0", "v2.0.0", "v2.0.1").
+		WithMetadata("v2.0.1", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 1, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+				{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+	infraRepository := test.NewFakeRepository().
+		WithVersions("v2.0.0", "v3.0.0", "v3.0.1").
+		WithMetadata("v3.0.1", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 2, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+				{Major: 3, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+
+	type fields struct {
+		reader config.Reader
+		proxy  Proxy
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    []VersionSkew
+		wantErr bool
+	}{
+		{
+			name: "No version skew",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v2.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+			},
+			want: []VersionSkew{},
+		},
+		{
+			name: "Provider behind the core provider",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v2.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+			},
+			want: []VersionSkew{
+				{
+					Provider:           fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+					Contract:           test.PreviousCAPIContractNotSupported,
+					CoreContract:       test.CurrentCAPIContract,
+					RecommendedVersion: "v3.0.1",
+				},
+			},
+		},
+		{
+			name: "Provider ahead of the core provider",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+			},
+			want: []VersionSkew{
+				{
+					Provider:               fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+					Contract:               test.CurrentCAPIContract,
+					CoreContract:           test.PreviousCAPIContractNotSupported,
+					RecommendedCoreVersion: "v2.0.1",
+				},
+			},
+		},
+		{
+			name: "Provider not defined in the clusterctl configuration",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com"),
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v2.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+			},
+			want: []VersionSkew{
+				{
+					Provider:     fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+					CoreContract: test.CurrentCAPIContract,
+				},
+			},
+		},
+		{
+			name: "Fails without a core provider",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v3.0.0", "infra-system"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient, _ := config.New("", config.InjectReader(tt.fields.reader))
+			repositories := map[string]repository.Repository{
+				"cluster-api":          coreRepository,
+				"infrastructure-infra": infraRepository,
+			}
+
+			u := &providerUpgrader{
+				configClient: configClient,
+				repositoryClientFactory: func(provider config.Provider, configClient config.Client, options ...repository.Option) (repository.Client, error) {
+					return repository.New(provider, configClient, repository.InjectRepository(repositories[provider.ManifestLabel()]))
+				},
+				providerInventory: newInventoryClient(tt.fields.proxy, nil),
+			}
+			got, err := u.CheckVersionSkew()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// Messages are checked separately, given that they are meant for humans.
+			for i := range got {
+				g.Expect(got[i].Message).NotTo(BeEmpty())
+				got[i].Message = ""
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
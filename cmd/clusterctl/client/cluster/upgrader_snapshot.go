@@ -0,0 +1,409 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	utilresource "sigs.k8s.io/cluster-api/util/resource"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// upgradeSnapshotNamePrefix is the prefix of the name of the Secrets storing the snapshots of the provider components.
+	upgradeSnapshotNamePrefix = "clusterctl-upgrade-"
+
+	// upgradeSnapshotProviderKey is the key in the Secret data storing the inventory object of the provider.
+	upgradeSnapshotProviderKey = "provider"
+
+	// upgradeSnapshotComponentsKey is the key in the Secret data storing the gzipped components YAML of the provider.
+	upgradeSnapshotComponentsKey = "components.yaml.gz"
+
+	// upgradeAttemptIDFormat is the time format used for generating upgrade attempt IDs.
+	upgradeAttemptIDFormat = "20060102-150405"
+
+	// upgradeSnapshotsToKeep is the number of upgrade attempts for which the snapshots are preserved after a successful
+	// upgrade, so it is still possible to rollback the latest upgrades if the new version does not work as expected.
+	upgradeSnapshotsToKeep = 1
+)
+
+// newUpgradeAttemptID returns the ID for a new upgrade attempt; IDs sort in chronological order.
+var newUpgradeAttemptID = func() string {
+	return time.Now().UTC().Format(upgradeAttemptIDFormat)
+}
+
+// upgradeSnapshot contains the components of a provider before an upgrade attempt.
+type upgradeSnapshot struct {
+	attemptID string
+	provider  clusterctlv1.Provider
+	objs      []unstructured.Unstructured
+}
+
+// getUpgradeSnapshotObjs returns the components of a provider installed in the management cluster.
+// NB. The components are read from the cluster instead of the provider repository, so it is possible to rollback also
+// providers installed from a local path, versions not available anymore in the repository, or offline clusters.
+func getUpgradeSnapshotObjs(proxy Proxy, provider clusterctlv1.Provider) ([]unstructured.Unstructured, error) {
+	// Selects the provider components like when deleting the provider, including the CRDs and the namespace.
+	objs, _, err := newComponentsClient(proxy).getResourcesToDelete(DeleteOptions{
+		Provider:         provider,
+		IncludeNamespace: true,
+		IncludeCRDs:      true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the components of the provider %s", provider.InstanceName())
+	}
+
+	snapshotObjs := []unstructured.Unstructured{}
+	for i := range objs {
+		obj := objs[i]
+
+		// Skip the inventory object, which is saved separately.
+		if obj.GetLabels()[clusterctlv1.ClusterctlCoreLabelName] == clusterctlv1.ClusterctlCoreLabelInventoryValue {
+			continue
+		}
+
+		// Skip the objects owned by other objects, e.g. ReplicaSets, because they are generated by controllers.
+		if len(obj.GetOwnerReferences()) > 0 {
+			continue
+		}
+
+		snapshotObjs = append(snapshotObjs, cleanupSnapshotObj(obj))
+	}
+	return utilresource.SortForCreate(snapshotObjs), nil
+}
+
+// cleanupSnapshotObj drops the fields set by the API server from an object read from the management cluster,
+// so the object can be created again when restoring the snapshot.
+func cleanupSnapshotObj(obj unstructured.Unstructured) unstructured.Unstructured {
+	obj = *obj.DeepCopy()
+	for _, field := range [][]string{
+		{"metadata", "resourceVersion"},
+		{"metadata", "uid"},
+		{"metadata", "creationTimestamp"},
+		{"metadata", "generation"},
+		{"metadata", "managedFields"},
+		{"metadata", "selfLink"},
+		{"status"},
+	} {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+
+	// NB. Cluster IPs are allocated by the API server; they are dropped, so restoring a Service does not fail if the
+	// IP is not released yet.
+	if obj.GetKind() == "Service" {
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	}
+	return obj
+}
+
+// saveUpgradeSnapshot stores the components of a provider into a Secret in the provider namespace, so they
+// can be restored in case the upgrade fails, even from another clusterctl process.
+// NB. The Secret is not labeled as a provider component, so it is preserved when deleting the provider during the upgrade.
+func saveUpgradeSnapshot(proxy Proxy, attemptID string, provider clusterctlv1.Provider, objs []unstructured.Unstructured) error {
+	// NB. Only the identity of the inventory object is preserved, so it can be created again when restoring the snapshot.
+	inventoryObject := provider.DeepCopy()
+	inventoryObject.TypeMeta = metav1.TypeMeta{
+		APIVersion: clusterctlv1.GroupVersion.String(),
+		Kind:       "Provider",
+	}
+	inventoryObject.ObjectMeta = metav1.ObjectMeta{
+		Namespace:   provider.Namespace,
+		Name:        provider.Name,
+		Labels:      provider.Labels,
+		Annotations: provider.Annotations,
+	}
+	providerData, err := json.Marshal(inventoryObject)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize the inventory object for the provider %s", provider.InstanceName())
+	}
+
+	rawYaml, err := utilyaml.FromUnstructured(objs)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize the components for the provider %s", provider.InstanceName())
+	}
+	var componentsData bytes.Buffer
+	w := gzip.NewWriter(&componentsData)
+	if _, err := w.Write(rawYaml); err != nil {
+		return errors.Wrapf(err, "failed to compress the components for the provider %s", provider.InstanceName())
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrapf(err, "failed to compress the components for the provider %s", provider.InstanceName())
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: provider.Namespace,
+			Name:      fmt.Sprintf("%s%s-%s", upgradeSnapshotNamePrefix, attemptID, provider.ManifestLabel()),
+			Labels: map[string]string{
+				clusterctlv1.ClusterctlUpgradeSnapshotLabelName: attemptID,
+			},
+		},
+		Data: map[string][]byte{
+			upgradeSnapshotProviderKey:   providerData,
+			upgradeSnapshotComponentsKey: componentsData.Bytes(),
+		},
+	}
+
+	return retryWithExponentialBackoff(newWriteBackoff(), func() error {
+		c, err := proxy.NewClient()
+		if err != nil {
+			return err
+		}
+		if err := c.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create the upgrade snapshot %s/%s", secret.Namespace, secret.Name)
+		}
+		return nil
+	})
+}
+
+// listUpgradeSnapshots returns the snapshots saved for an upgrade attempt; if the attempt ID is empty, the snapshots
+// for the latest upgrade attempt are returned.
+func listUpgradeSnapshots(proxy Proxy, attemptID string) ([]upgradeSnapshot, error) {
+	secrets := &corev1.SecretList{}
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		c, err := proxy.NewClient()
+		if err != nil {
+			return err
+		}
+		return c.List(ctx, secrets, client.HasLabels{clusterctlv1.ClusterctlUpgradeSnapshotLabelName})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list upgrade snapshots")
+	}
+
+	if attemptID == "" {
+		for _, s := range secrets.Items {
+			if id := s.Labels[clusterctlv1.ClusterctlUpgradeSnapshotLabelName]; id > attemptID {
+				attemptID = id
+			}
+		}
+		if attemptID == "" {
+			return nil, errors.New("failed to find an upgrade snapshot: no upgrade attempts recorded in the management cluster")
+		}
+	}
+
+	snapshots := []upgradeSnapshot{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Labels[clusterctlv1.ClusterctlUpgradeSnapshotLabelName] != attemptID {
+			continue
+		}
+
+		snapshot, err := readUpgradeSnapshot(secret)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	if len(snapshots) == 0 {
+		return nil, errors.Errorf("failed to find an upgrade snapshot for the upgrade attempt %s", attemptID)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].provider.InstanceName() < snapshots[j].provider.InstanceName()
+	})
+	return snapshots, nil
+}
+
+func readUpgradeSnapshot(secret *corev1.Secret) (*upgradeSnapshot, error) {
+	provider := clusterctlv1.Provider{}
+	if err := json.Unmarshal(secret.Data[upgradeSnapshotProviderKey], &provider); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the inventory object in the upgrade snapshot %s/%s", secret.Namespace, secret.Name)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(secret.Data[upgradeSnapshotComponentsKey]))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the components in the upgrade snapshot %s/%s", secret.Namespace, secret.Name)
+	}
+	rawYaml, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the components in the upgrade snapshot %s/%s", secret.Namespace, secret.Name)
+	}
+
+	// NB. The components in the snapshot were read from the management cluster, so they are restored as they are.
+	objs, err := utilyaml.ToUnstructured(rawYaml)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the components in the upgrade snapshot %s/%s", secret.Namespace, secret.Name)
+	}
+
+	return &upgradeSnapshot{
+		attemptID: secret.Labels[clusterctlv1.ClusterctlUpgradeSnapshotLabelName],
+		provider:  provider,
+		objs:      objs,
+	}, nil
+}
+
+// deleteUpgradeSnapshots deletes the snapshots for an upgrade attempt.
+func deleteUpgradeSnapshots(proxy Proxy, attemptID string) error {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.MatchingLabels{clusterctlv1.ClusterctlUpgradeSnapshotLabelName: attemptID}); err != nil {
+		return errors.Wrap(err, "failed to list upgrade snapshots")
+	}
+
+	errList := []error{}
+	for i := range secrets.Items {
+		if err := c.Delete(ctx, &secrets.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			errList = append(errList, errors.Wrapf(err, "failed to delete the upgrade snapshot %s/%s", secrets.Items[i].Namespace, secrets.Items[i].Name))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
+// pruneUpgradeSnapshots deletes the snapshots of all the upgrade attempts except the latest ones.
+func pruneUpgradeSnapshots(proxy Proxy, keep int) error {
+	secrets := &corev1.SecretList{}
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		c, err := proxy.NewClient()
+		if err != nil {
+			return err
+		}
+		return c.List(ctx, secrets, client.HasLabels{clusterctlv1.ClusterctlUpgradeSnapshotLabelName})
+	}); err != nil {
+		return errors.Wrap(err, "failed to list upgrade snapshots")
+	}
+
+	attemptIDs := sets.NewString()
+	for _, s := range secrets.Items {
+		attemptIDs.Insert(s.Labels[clusterctlv1.ClusterctlUpgradeSnapshotLabelName])
+	}
+
+	// NB. Attempt IDs sort in chronological order.
+	sortedIDs := attemptIDs.List()
+	errList := []error{}
+	for i := 0; i < len(sortedIDs)-keep; i++ {
+		if err := deleteUpgradeSnapshots(proxy, sortedIDs[i]); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
+// checkCRDsRollback checks that restoring the CRDs in the snapshot does not drop any version used for storing objects
+// in the management cluster; in this case the rollback must be refused, because those objects will become unreadable.
+func checkCRDsRollback(proxy Proxy, snapshot upgradeSnapshot) error {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return err
+	}
+
+	errList := []error{}
+	for _, obj := range snapshot.objs {
+		if obj.GetKind() != customResourceDefinitionKind {
+			continue
+		}
+
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName()}, current); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get the CustomResourceDefinition %s", obj.GetName())
+		}
+
+		restoredVersions := sets.NewString()
+		versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+		for _, v := range versions {
+			if version, ok := v.(map[string]interface{}); ok {
+				name, _, _ := unstructured.NestedString(version, "name")
+				restoredVersions.Insert(name)
+			}
+		}
+
+		storedVersions, _, _ := unstructured.NestedStringSlice(current.Object, "status", "storedVersions")
+		for _, v := range storedVersions {
+			if !restoredVersions.Has(v) {
+				errList = append(errList, errors.Errorf("objects of the CustomResourceDefinition %s are stored with version %s, which is not supported by the %s version of the provider %s", obj.GetName(), v, snapshot.provider.Version, snapshot.provider.InstanceName()))
+			}
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
+// Rollback restores the provider components saved before an upgrade attempt.
+func (u *providerUpgrader) Rollback(attemptID string) error {
+	log := logf.Log
+
+	snapshots, err := listUpgradeSnapshots(u.proxy, attemptID)
+	if err != nil {
+		return err
+	}
+	attemptID = snapshots[0].attemptID
+	log.Info("Performing rollback...", "AttemptID", attemptID)
+
+	// Before doing any change, checks none of the CRDs is going to be downgraded to a version not supporting the stored objects.
+	errList := []error{}
+	for _, snapshot := range snapshots {
+		if err := checkCRDsRollback(u.proxy, snapshot); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if len(errList) > 0 {
+		return errors.Wrapf(kerrors.NewAggregate(errList), "unable to rollback the upgrade attempt %s", attemptID)
+	}
+
+	for _, snapshot := range snapshots {
+		// Delete the provider, preserving CRD and namespace.
+		if err := u.providerComponents.Delete(DeleteOptions{
+			Provider:         snapshot.provider,
+			IncludeNamespace: false,
+			IncludeCRDs:      false,
+		}); err != nil {
+			return err
+		}
+
+		// Restore the provider components and the inventory entry from the snapshot.
+		u.eventFunc.Notify(ApplyingComponentsEvent, snapshot.provider.ManifestLabel(), "Restoring %d objects for version %s in namespace %s",
+			len(snapshot.objs), snapshot.provider.Version, snapshot.provider.Namespace)
+		if err := u.providerComponents.Create(snapshot.objs); err != nil {
+			return err
+		}
+
+		u.eventFunc.Notify(CreatingInventoryEvent, snapshot.provider.ManifestLabel(), "Restoring the inventory entry for version %s", snapshot.provider.Version)
+		if err := u.providerInventory.Create(snapshot.provider); err != nil {
+			return err
+		}
+	}
+
+	// Once the rollback is completed, the snapshots are not required anymore.
+	return deleteUpgradeSnapshots(u.proxy, attemptID)
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+func fakeSnapshotObjs(g *WithT, crdVersions ...string) []unstructured.Unstructured {
+	versions := []string{}
+	for i, v := range crdVersions {
+		versions = append(versions, fmt.Sprintf("  - name: %s\n    served: true\n    storage: %t", v, i == len(crdVersions)-1))
+	}
+	rawYaml := []byte("apiVersion: apiextensions.k8s.io/v1\n" +
+		"kind: CustomResourceDefinition\n" +
+		"metadata:\n" +
+		"  name: infraclusters.infrastructure.cluster.x-k8s.io\n" +
+		"spec:\n" +
+		"  group: infrastructure.cluster.x-k8s.io\n" +
+		"  versions:\n" +
+		strings.Join(versions, "\n") + "\n" +
+		"---\n" +
+		"apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: manager\n")
+
+	objs, err := utilyaml.ToUnstructured(rawYaml)
+	g.Expect(err).NotTo(HaveOccurred())
+	return objs
+}
+
+func Test_getUpgradeSnapshotObjs(t *testing.T) {
+	g := NewWithT(t)
+
+	infra := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system")
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      infra.ManifestLabel(),
+	}
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "infra-system",
+			Name:            "manager",
+			Labels:          labels,
+			UID:             "deployment-uid",
+			ResourceVersion: "999",
+			Generation:      2,
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas: 1,
+		},
+	}
+	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "infra-system",
+			Name:      "webhook-service",
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.96.0.10",
+			ClusterIPs: []string{"10.96.0.10"},
+		},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "ReplicaSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "infra-system",
+			Name:      "manager-12345",
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "manager", UID: "deployment-uid"},
+			},
+		},
+	}
+	namespace := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "infra-system",
+			Labels: labels,
+		},
+	}
+	otherDeployment := deployment.DeepCopy()
+	otherDeployment.Namespace = "other-system"
+
+	proxy := test.NewFakeProxy().
+		WithObjs(deployment, service, replicaSet, namespace, otherDeployment).
+		WithProviderInventory(infra.ProviderName, infra.GetProviderType(), infra.Version, infra.Namespace)
+
+	objs, err := getUpgradeSnapshotObjs(proxy, infra)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The inventory object, the objects owned by other objects and the objects in other namespaces are not included;
+	// The objects are sorted for creation.
+	g.Expect(objs).To(HaveLen(3))
+	g.Expect(objs[0].GetKind()).To(Equal("Namespace"))
+	for _, obj := range objs {
+		g.Expect(obj.GetNamespace()).To(Or(Equal("infra-system"), BeEmpty()))
+		g.Expect(obj.GetKind()).To(BeElementOf("Namespace", "Deployment", "Service"))
+
+		// The fields set by the API server are dropped.
+		g.Expect(obj.GetResourceVersion()).To(BeEmpty())
+		g.Expect(obj.GetUID()).To(BeEmpty())
+		g.Expect(obj.GetGeneration()).To(BeZero())
+		g.Expect(obj.Object).ToNot(HaveKey("status"))
+		if obj.GetKind() == "Service" {
+			g.Expect(obj.Object["spec"]).ToNot(HaveKey("clusterIP"))
+			g.Expect(obj.Object["spec"]).ToNot(HaveKey("clusterIPs"))
+		}
+	}
+}
+
+func Test_upgradeSnapshot_saveListAndDelete(t *testing.T) {
+	g := NewWithT(t)
+
+	proxy := test.NewFakeProxy()
+
+	core := fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system")
+	infra := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system")
+
+	// Saves snapshots for two different upgrade attempts.
+	g.Expect(saveUpgradeSnapshot(proxy, "20210101-000000", core, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+	g.Expect(saveUpgradeSnapshot(proxy, "20210102-000000", core, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+	g.Expect(saveUpgradeSnapshot(proxy, "20210102-000000", infra, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+
+	// If the attempt ID is not specified, the snapshots for the latest attempt are returned.
+	snapshots, err := listUpgradeSnapshots(proxy, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(2))
+	for _, s := range snapshots {
+		g.Expect(s.attemptID).To(Equal("20210102-000000"))
+	}
+	g.Expect(snapshots[0].provider.InstanceName()).To(Equal(core.InstanceName()))
+	g.Expect(snapshots[1].provider.InstanceName()).To(Equal(infra.InstanceName()))
+
+	// Check the components and the inventory object are restored from the snapshot.
+	g.Expect(snapshots[1].objs).To(Equal(fakeSnapshotObjs(g, "v1alpha3")))
+	inventoryObject := snapshots[1].provider
+	g.Expect(inventoryObject.Version).To(Equal("v2.0.0"))
+	g.Expect(inventoryObject.Namespace).To(Equal("infra-system"))
+	g.Expect(inventoryObject.Labels).To(Equal(infra.Labels))
+	g.Expect(inventoryObject.ResourceVersion).To(BeEmpty())
+
+	snapshots, err = listUpgradeSnapshots(proxy, "20210101-000000")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+
+	// Check the snapshots are not labeled as provider components, so they are preserved during upgrades.
+	cs, err := proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	secrets := &corev1.SecretList{}
+	g.Expect(cs.List(ctx, secrets)).To(Succeed())
+	g.Expect(secrets.Items).To(HaveLen(3))
+	for _, s := range secrets.Items {
+		g.Expect(s.Labels).ToNot(HaveKey(clusterctlv1.ClusterctlLabelName))
+	}
+
+	// Deletes the snapshots for an attempt.
+	g.Expect(deleteUpgradeSnapshots(proxy, "20210102-000000")).To(Succeed())
+	_, err = listUpgradeSnapshots(proxy, "20210102-000000")
+	g.Expect(err).To(HaveOccurred())
+
+	snapshots, err = listUpgradeSnapshots(proxy, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].attemptID).To(Equal("20210101-000000"))
+}
+
+func Test_pruneUpgradeSnapshots(t *testing.T) {
+	g := NewWithT(t)
+
+	proxy := test.NewFakeProxy()
+
+	core := fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system")
+	infra := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system")
+
+	g.Expect(saveUpgradeSnapshot(proxy, "20210101-000000", core, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+	g.Expect(saveUpgradeSnapshot(proxy, "20210102-000000", core, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+	g.Expect(saveUpgradeSnapshot(proxy, "20210102-000000", infra, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+	g.Expect(saveUpgradeSnapshot(proxy, "20210103-000000", infra, fakeSnapshotObjs(g, "v1alpha3"))).To(Succeed())
+
+	// Keeps the snapshots of the latest two upgrade attempts.
+	g.Expect(pruneUpgradeSnapshots(proxy, 2)).To(Succeed())
+	_, err := listUpgradeSnapshots(proxy, "20210101-000000")
+	g.Expect(err).To(HaveOccurred())
+	snapshots, err := listUpgradeSnapshots(proxy, "20210102-000000")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(2))
+
+	// Keeps the snapshots of the latest upgrade attempt only.
+	g.Expect(pruneUpgradeSnapshots(proxy, 1)).To(Succeed())
+	_, err = listUpgradeSnapshots(proxy, "20210102-000000")
+	g.Expect(err).To(HaveOccurred())
+	snapshots, err = listUpgradeSnapshots(proxy, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].attemptID).To(Equal("20210103-000000"))
+
+	// Pruning is a no-op if there are no more attempts than the ones to keep.
+	g.Expect(pruneUpgradeSnapshots(proxy, 1)).To(Succeed())
+	_, err = listUpgradeSnapshots(proxy, "20210103-000000")
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func Test_checkCRDsRollback(t *testing.T) {
+	infra := fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system")
+
+	currentCRD := &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "infraclusters.infrastructure.cluster.x-k8s.io",
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			StoredVersions: []string{"v1alpha3", "v1alpha4"},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		snapshotVersions  []string
+		withCurrentObject bool
+		wantErr           bool
+	}{
+		{
+			name:              "pass if the CRD does not exist",
+			snapshotVersions:  []string{"v1alpha3"},
+			withCurrentObject: false,
+			wantErr:           false,
+		},
+		{
+			name:              "pass if the restored CRD supports all the stored versions",
+			snapshotVersions:  []string{"v1alpha3", "v1alpha4"},
+			withCurrentObject: true,
+			wantErr:           false,
+		},
+		{
+			name:              "fail if the restored CRD does not support one of the stored versions",
+			snapshotVersions:  []string{"v1alpha3"},
+			withCurrentObject: true,
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			proxy := test.NewFakeProxy()
+			if tt.withCurrentObject {
+				proxy = proxy.WithObjs(currentCRD.DeepCopy())
+			}
+
+			snapshot := upgradeSnapshot{
+				provider: infra,
+				objs:     fakeSnapshotObjs(g, tt.snapshotVersions...),
+			}
+
+			err := checkCRDsRollback(proxy, snapshot)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("v1alpha4"))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
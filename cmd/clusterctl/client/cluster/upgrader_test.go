@@ -17,6 +17,7 @@ limitations under the License.
 package cluster
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -191,6 +192,7 @@ func Test_providerUpgrader_Plan(t *testing.T) {
 							NextVersion: "v3.0.0",
 						},
 					},
+					CrossesContract: true,
 				},
 			},
 			wantErr: false,
@@ -251,6 +253,7 @@ func Test_providerUpgrader_Plan(t *testing.T) {
 							NextVersion: "v3.0.0",
 						},
 					},
+					CrossesContract: true,
 				},
 			},
 			wantErr: false,
@@ -339,11 +342,12 @@ func Test_providerUpgrader_createCustomPlan(t *testing.T) {
 		providersToUpgrade []UpgradeItem
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    *UpgradePlan
-		wantErr bool
+		name            string
+		fields          fields
+		args            args
+		want            *UpgradePlan
+		wantErr         bool
+		wantErrContains []string
 	}{
 		{
 			name: "pass if upgrade infra provider, same contract",
@@ -771,6 +775,149 @@ func Test_providerUpgrader_createCustomPlan(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "pass if upgrade infra provider alone without a target version, the latest version for the current contract is used",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				repository: map[string]repository.Repository{
+					"cluster-api": test.NewFakeRepository().
+						WithVersions("v1.0.0", "v1.0.1").
+						WithMetadata("v1.0.1", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+							},
+						}),
+					"infra": test.NewFakeRepository().
+						WithVersions("v2.0.0", "v2.0.1", "v2.0.2", "v3.0.0").
+						WithMetadata("v3.0.0", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+								{Major: 3, Minor: 0, Contract: test.NextCAPIContractNotSupported},
+							},
+						}),
+				},
+				// two providers existing in the cluster
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+			},
+			args: args{
+				coreProvider: fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "", "cluster-api-system"),
+				providersToUpgrade: []UpgradeItem{
+					{
+						Provider:    fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+						NextVersion: "", // upgrade to the latest release in the current contract (v3.0.0 supports the next contract).
+					},
+				},
+			},
+			want: &UpgradePlan{
+				Contract: test.CurrentCAPIContract,
+				Providers: []UpgradeItem{
+					{
+						Provider:    fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+						NextVersion: "v2.0.2",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "pass if upgrade infra provider alone without a target version and already up-to-date",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				repository: map[string]repository.Repository{
+					"cluster-api": test.NewFakeRepository().
+						WithVersions("v1.0.0", "v1.0.1").
+						WithMetadata("v1.0.1", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+							},
+						}),
+					"infra": test.NewFakeRepository().
+						WithVersions("v2.0.0", "v2.0.1", "v2.0.2", "v3.0.0").
+						WithMetadata("v3.0.0", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+								{Major: 3, Minor: 0, Contract: test.NextCAPIContractNotSupported},
+							},
+						}),
+				},
+				// two providers existing in the cluster
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v2.0.2", "infra-system"),
+			},
+			args: args{
+				coreProvider: fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "", "cluster-api-system"),
+				providersToUpgrade: []UpgradeItem{
+					{
+						Provider:    fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.2", "infra-system"),
+						NextVersion: "",
+					},
+				},
+			},
+			want: &UpgradePlan{
+				Contract: test.CurrentCAPIContract,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail listing all the conflicting providers if upgrade core provider alone from previous to the current contract",
+			fields: fields{
+				// config for three providers
+				reader: test.NewFakeReader().
+					WithProvider("cluster-api", clusterctlv1.CoreProviderType, "https://somewhere.com").
+					WithProvider("kubeadm", clusterctlv1.BootstrapProviderType, "https://somewhere.com").
+					WithProvider("infra", clusterctlv1.InfrastructureProviderType, "https://somewhere.com"),
+				repository: map[string]repository.Repository{
+					"cluster-api": test.NewFakeRepository().
+						WithVersions("v1.0.0", "v2.0.0").
+						WithMetadata("v2.0.0", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 1, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+								{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+							},
+						}),
+					"kubeadm": test.NewFakeRepository().
+						WithVersions("v1.0.0", "v2.0.0").
+						WithMetadata("v2.0.0", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 1, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+								{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+							},
+						}),
+					"infra": test.NewFakeRepository().
+						WithVersions("v2.0.0", "v3.0.0").
+						WithMetadata("v3.0.0", &clusterctlv1.Metadata{
+							ReleaseSeries: []clusterctlv1.ReleaseSeries{
+								{Major: 2, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+								{Major: 3, Minor: 0, Contract: test.CurrentCAPIContract},
+							},
+						}),
+				},
+				// three providers existing in the cluster
+				proxy: test.NewFakeProxy().
+					WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system").
+					WithProviderInventory("kubeadm", clusterctlv1.BootstrapProviderType, "v1.0.0", "kubeadm-system").
+					WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+			},
+			args: args{
+				coreProvider: fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "", "cluster-api-system"),
+				providersToUpgrade: []UpgradeItem{
+					{
+						Provider:    fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"),
+						NextVersion: "v2.0.0", // upgrade to next release in the current contract.
+					},
+				},
+			},
+			want:            nil,
+			wantErr:         true,
+			wantErrContains: []string{"kubeadm-system/bootstrap-kubeadm", "infra-system/infrastructure-infra"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -788,6 +935,9 @@ func Test_providerUpgrader_createCustomPlan(t *testing.T) {
 			got, err := u.createCustomPlan(tt.args.providersToUpgrade)
 			if tt.wantErr {
 				g.Expect(err).To(HaveOccurred())
+				for _, s := range tt.wantErrContains {
+					g.Expect(err.Error()).To(ContainSubstring(s))
+				}
 				return
 			}
 
@@ -1046,3 +1196,23 @@ func Test_providerUpgrader_ApplyCustomPlan(t *testing.T) {
 		})
 	}
 }
+
+func Test_UpgradePlan_MarshalJSON(t *testing.T) {
+	g := NewWithT(t)
+
+	plan := UpgradePlan{
+		Contract: "v1alpha4",
+		Providers: []UpgradeItem{
+			{
+				Provider:    fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"),
+				NextVersion: "v1.0.1",
+			},
+		},
+		CrossesContract: false,
+	}
+
+	got, err := json.Marshal(plan)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(got)).To(Equal(`{"contract":"v1alpha4","providers":[{"name":"cluster-api","namespace":"cluster-api-system",` +
+		`"providerName":"cluster-api","type":"CoreProvider","version":"v1.0.0","nextVersion":"v1.0.1"}],"crossesContract":false}`))
+}
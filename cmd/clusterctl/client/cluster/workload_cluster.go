@@ -17,8 +17,22 @@ limitations under the License.
 package cluster
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/util/annotations"
 	utilkubeconfig "sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/cluster-api/util/secret"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -26,17 +40,40 @@ import (
 type WorkloadCluster interface {
 	// GetKubeconfig returns the kubeconfig of the workload cluster.
 	GetKubeconfig(workloadClusterName string, namespace string) (string, error)
+
+	// GetKubeconfigs returns the kubeconfig of all the workload clusters in a namespace, indexed by cluster name.
+	// Workload clusters for which the kubeconfig cannot be read, e.g. because the kubeconfig secret is not created yet,
+	// are not included in the result, and the corresponding errors are returned as an aggregate.
+	GetKubeconfigs(namespace string) (map[string]string, error)
+
+	// GetKubeconfigFromSecret returns the kubeconfig of a workload cluster stored in a Secret with a non-conventional
+	// name, e.g. by a managed control plane provider, under the given key ("value" if empty); it returns an error if
+	// the key does not exist or if it does not contain a valid kubeconfig.
+	GetKubeconfigFromSecret(namespace, name, key string) (string, error)
+
+	// RegenerateKubeconfig deletes the kubeconfig Secret of a workload cluster, so the control plane provider creates it
+	// again with new credentials, and returns the new kubeconfig once available, waiting at most for timeout.
+	// Only Clusters with a KubeadmControlPlane owning the kubeconfig Secret are supported, and neither the Cluster nor the
+	// KubeadmControlPlane can be paused, otherwise the kubeconfig Secret is not created again.
+	RegenerateKubeconfig(workloadClusterName string, namespace string, timeout time.Duration) (string, error)
 }
 
+const regenerateKubeconfigInterval = 5 * time.Second
+
+// kubeadmControlPlaneGroupKind is the GroupKind of the control plane provider supporting the regeneration of the kubeconfig Secret.
+var kubeadmControlPlaneGroupKind = schema.GroupKind{Group: "controlplane.cluster.x-k8s.io", Kind: "KubeadmControlPlane"}
+
 // workloadCluster implements WorkloadCluster.
 type workloadCluster struct {
-	proxy Proxy
+	proxy               Proxy
+	pollImmediateWaiter PollImmediateWaiter
 }
 
 // newWorkloadCluster returns a workloadCluster.
-func newWorkloadCluster(proxy Proxy) *workloadCluster {
+func newWorkloadCluster(proxy Proxy, pollImmediateWaiter PollImmediateWaiter) *workloadCluster {
 	return &workloadCluster{
-		proxy: proxy,
+		proxy:               proxy,
+		pollImmediateWaiter: pollImmediateWaiter,
 	}
 }
 
@@ -56,3 +93,168 @@ func (p *workloadCluster) GetKubeconfig(workloadClusterName string, namespace st
 	}
 	return string(dataBytes), nil
 }
+
+func (p *workloadCluster) GetKubeconfigFromSecret(namespace, name, key string) (string, error) {
+	if key == "" {
+		key = secret.KubeconfigDataName
+	}
+
+	cs, err := p.proxy.NewClient()
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfigSecret := &corev1.Secret{}
+	if err := cs.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, kubeconfigSecret); err != nil {
+		return "", errors.Wrapf(err, "failed to get the kubeconfig secret %s/%s", namespace, name)
+	}
+
+	data, ok := kubeconfigSecret.Data[key]
+	if !ok {
+		return "", errors.Errorf("the kubeconfig secret %s/%s does not have the %q key", namespace, name, key)
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return "", errors.Wrapf(err, "the %q key of the secret %s/%s does not contain a valid kubeconfig", key, namespace, name)
+	}
+	if err := clientcmd.Validate(*config); err != nil {
+		return "", errors.Wrapf(err, "the %q key of the secret %s/%s does not contain a valid kubeconfig", key, namespace, name)
+	}
+	return string(data), nil
+}
+
+func (p *workloadCluster) RegenerateKubeconfig(workloadClusterName string, namespace string, timeout time.Duration) (string, error) {
+	cs, err := p.proxy.NewClient()
+	if err != nil {
+		return "", err
+	}
+
+	cluster := &clusterv1.Cluster{}
+	if err := cs.Get(ctx, client.ObjectKey{Namespace: namespace, Name: workloadClusterName}, cluster); err != nil {
+		return "", errors.Wrapf(err, "failed to get Cluster %s/%s", namespace, workloadClusterName)
+	}
+
+	ref := cluster.Spec.ControlPlaneRef
+	if ref == nil {
+		return "", errors.Errorf("regenerating the kubeconfig of Cluster %s/%s is not supported: the Cluster does not have a control plane provider", namespace, workloadClusterName)
+	}
+	if ref.GroupVersionKind().GroupKind() != kubeadmControlPlaneGroupKind {
+		return "", errors.Errorf("regenerating the kubeconfig of Cluster %s/%s is not supported for the %s control plane provider", namespace, workloadClusterName, ref.Kind)
+	}
+
+	// Checks the KubeadmControlPlane exists and it is going to be reconciled before deleting anything, otherwise the kubeconfig
+	// Secret is not created again.
+	controlPlane := &unstructured.Unstructured{}
+	controlPlane.SetGroupVersionKind(ref.GroupVersionKind())
+	if err := retryWithExponentialBackoff(newReadBackoff(), func() error {
+		if err := cs.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, controlPlane); err != nil {
+			return errors.Wrapf(err, "failed to get the KubeadmControlPlane %s/%s", namespace, ref.Name)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	if annotations.IsPaused(cluster, controlPlane) || annotations.HasPausedAnnotation(cluster) {
+		return "", errors.Errorf("regenerating the kubeconfig of Cluster %s/%s is not supported while the Cluster or the KubeadmControlPlane %s is paused", namespace, workloadClusterName, ref.Name)
+	}
+
+	// Checks the kubeconfig Secret is owned by the KubeadmControlPlane, otherwise it is not created again after being deleted,
+	// e.g. if the Secret is provided by the user.
+	key := client.ObjectKey{Namespace: namespace, Name: secret.Name(workloadClusterName, secret.Kubeconfig)}
+	kubeconfigSecret := &corev1.Secret{}
+	var oldUID types.UID
+	if err := cs.Get(ctx, key, kubeconfigSecret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "failed to get the kubeconfig secret %s", key)
+		}
+	} else {
+		if !isControlledByKubeadmControlPlane(kubeconfigSecret, ref.Name) {
+			return "", errors.Errorf("regenerating the kubeconfig of Cluster %s/%s is not supported: the kubeconfig secret %s is not managed by the KubeadmControlPlane %s", namespace, workloadClusterName, key, ref.Name)
+		}
+		oldUID = kubeconfigSecret.UID
+
+		deleteSecretBackoff := newWriteBackoff()
+		if err := retryWithExponentialBackoff(deleteSecretBackoff, func() error {
+			if err := cs.Delete(ctx, kubeconfigSecret); err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete the kubeconfig secret %s", key)
+			}
+			return nil
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	// Annotates the KubeadmControlPlane, so it is reconciled without waiting for the resync period; the controller
+	// does not watch the kubeconfig Secret.
+	patchControlPlaneBackoff := newWriteBackoff()
+	if err := retryWithExponentialBackoff(patchControlPlaneBackoff, func() error {
+		if err := cs.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, controlPlane); err != nil {
+			return errors.Wrapf(err, "failed to get the KubeadmControlPlane %s/%s", namespace, ref.Name)
+		}
+		patch := client.MergeFrom(controlPlane.DeepCopy())
+		controlPlaneAnnotations := controlPlane.GetAnnotations()
+		if controlPlaneAnnotations == nil {
+			controlPlaneAnnotations = map[string]string{}
+		}
+		controlPlaneAnnotations[clusterctlv1.KubeconfigRegenerationRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		controlPlane.SetAnnotations(controlPlaneAnnotations)
+		if err := cs.Patch(ctx, controlPlane, patch); err != nil {
+			return errors.Wrapf(err, "failed to annotate the KubeadmControlPlane %s/%s", namespace, ref.Name)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	if err := p.pollImmediateWaiter(regenerateKubeconfigInterval, timeout, func() (bool, error) {
+		newSecret := &corev1.Secret{}
+		if err := cs.Get(ctx, key, newSecret); err != nil {
+			return false, nil
+		}
+		// The deleted Secret could still be there, e.g. if it has finalizers.
+		return newSecret.UID != oldUID && newSecret.DeletionTimestamp.IsZero(), nil
+	}); err != nil {
+		return "", errors.Wrapf(err, "failed to wait for the KubeadmControlPlane %s/%s to regenerate the kubeconfig secret %s", namespace, ref.Name, key)
+	}
+
+	return p.GetKubeconfig(workloadClusterName, namespace)
+}
+
+// isControlledByKubeadmControlPlane returns true if the controller of the object is the KubeadmControlPlane with the given name.
+func isControlledByKubeadmControlPlane(obj client.Object, name string) bool {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.WithKind(ref.Kind).GroupKind() == kubeadmControlPlaneGroupKind && ref.Name == name
+}
+
+func (p *workloadCluster) GetKubeconfigs(namespace string) (map[string]string, error) {
+	cs, err := p.proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterList := &clusterv1.ClusterList{}
+	if err := cs.List(ctx, clusterList, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrapf(err, "failed to list workload clusters in namespace %q", namespace)
+	}
+
+	kubeconfigs := map[string]string{}
+	var errList []error
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		kubeconfig, err := p.GetKubeconfig(cluster.Name, cluster.Namespace)
+		if err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		kubeconfigs[cluster.Name] = kubeconfig
+	}
+	return kubeconfigs, kerrors.NewAggregate(errList)
+}
@@ -18,13 +18,20 @@ package cluster
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
 	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func Test_WorkloadCluster_GetKubeconfig(t *testing.T) {
@@ -83,7 +90,7 @@ users:
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
 
-			wc := newWorkloadCluster(tt.proxy)
+			wc := newWorkloadCluster(tt.proxy, nil)
 			data, err := wc.GetKubeconfig("test1", "test")
 
 			if tt.expectErr {
@@ -96,3 +103,343 @@ users:
 		})
 	}
 }
+
+func Test_WorkloadCluster_GetKubeconfigFromSecret(t *testing.T) {
+	validKubeConfig := `
+clusters:
+- cluster:
+    server: https://test-cluster-api:6443
+  name: test1
+contexts:
+- context:
+    cluster: test1
+    user: test1-admin
+  name: test1-admin@test1
+current-context: test1-admin@test1
+kind: Config
+users:
+- name: test1-admin
+  user:
+    token: stuff
+`
+	kubeconfigSecret := func(key, data string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test1-user-kubeconfig",
+				Namespace: "test",
+			},
+			Data: map[string][]byte{
+				key: []byte(data),
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		proxy     Proxy
+		expectErr bool
+	}{
+		{
+			name:      "return the kubeconfig stored under the default key",
+			proxy:     test.NewFakeProxy().WithObjs(kubeconfigSecret(secret.KubeconfigDataName, validKubeConfig)),
+			expectErr: false,
+		},
+		{
+			name:      "return the kubeconfig stored under a custom key",
+			key:       "kubeconfig",
+			proxy:     test.NewFakeProxy().WithObjs(kubeconfigSecret("kubeconfig", validKubeConfig)),
+			expectErr: false,
+		},
+		{
+			name:      "return error if cannot find the secret",
+			proxy:     test.NewFakeProxy(),
+			expectErr: true,
+		},
+		{
+			name:      "return error if the secret does not have the key",
+			key:       "kubeconfig",
+			proxy:     test.NewFakeProxy().WithObjs(kubeconfigSecret(secret.KubeconfigDataName, validKubeConfig)),
+			expectErr: true,
+		},
+		{
+			name:      "return error if the key does not contain a kubeconfig",
+			proxy:     test.NewFakeProxy().WithObjs(kubeconfigSecret(secret.KubeconfigDataName, "not a kubeconfig")),
+			expectErr: true,
+		},
+		{
+			name:      "return error if the kubeconfig is not valid",
+			proxy:     test.NewFakeProxy().WithObjs(kubeconfigSecret(secret.KubeconfigDataName, "current-context: foo\nkind: Config\n")),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			wc := newWorkloadCluster(tt.proxy, nil)
+			data, err := wc.GetKubeconfigFromSecret("test", "test1-user-kubeconfig", tt.key)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal(validKubeConfig))
+		})
+	}
+}
+
+func Test_WorkloadCluster_GetKubeconfigs(t *testing.T) {
+	kubeconfigSecret := func(namespace, clusterName string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName + "-kubeconfig",
+				Namespace: namespace,
+				Labels:    map[string]string{clusterv1.ClusterLabelName: clusterName},
+			},
+			Data: map[string][]byte{
+				secret.KubeconfigDataName: []byte("kubeconfig-" + clusterName),
+			},
+		}
+	}
+	workloadCluster := func(namespace, clusterName string) *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName,
+				Namespace: namespace,
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		proxy     Proxy
+		want      map[string]string
+		expectErr bool
+	}{
+		{
+			name:  "no workload clusters",
+			proxy: test.NewFakeProxy(),
+			want:  map[string]string{},
+		},
+		{
+			name: "return the kubeconfig of all the workload clusters in the namespace",
+			proxy: test.NewFakeProxy().WithObjs(
+				workloadCluster("test", "test1"), kubeconfigSecret("test", "test1"),
+				workloadCluster("test", "test2"), kubeconfigSecret("test", "test2"),
+				workloadCluster("other", "test3"), kubeconfigSecret("other", "test3"),
+			),
+			want: map[string]string{
+				"test1": "kubeconfig-test1",
+				"test2": "kubeconfig-test2",
+			},
+		},
+		{
+			name: "return an error for workload clusters without a kubeconfig secret",
+			proxy: test.NewFakeProxy().WithObjs(
+				workloadCluster("test", "test1"), kubeconfigSecret("test", "test1"),
+				workloadCluster("test", "test2"),
+			),
+			want: map[string]string{
+				"test1": "kubeconfig-test1",
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			wc := newWorkloadCluster(tt.proxy, nil)
+			got, err := wc.GetKubeconfigs("test")
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("test2"))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_WorkloadCluster_RegenerateKubeconfig(t *testing.T) {
+	kcpRef := &corev1.ObjectReference{
+		APIVersion: "controlplane.cluster.x-k8s.io/v1alpha4",
+		Kind:       "KubeadmControlPlane",
+		Name:       "test1-control-plane",
+		Namespace:  "test",
+	}
+	workloadCluster := func(controlPlaneRef *corev1.ObjectReference) *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test1", Namespace: "test"},
+			Spec:       clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef},
+		}
+	}
+	pausedWorkloadCluster := func() *clusterv1.Cluster {
+		c := workloadCluster(kcpRef)
+		c.Spec.Paused = true
+		return c
+	}
+	pausedAnnotationWorkloadCluster := func() *clusterv1.Cluster {
+		c := workloadCluster(kcpRef)
+		c.Annotations = map[string]string{clusterv1.PausedAnnotation: ""}
+		return c
+	}
+	controlPlane := func() *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(kcpRef.APIVersion)
+		u.SetKind(kcpRef.Kind)
+		u.SetNamespace("test")
+		u.SetName(kcpRef.Name)
+		return u
+	}
+	pausedControlPlane := func() *unstructured.Unstructured {
+		u := controlPlane()
+		u.SetAnnotations(map[string]string{clusterv1.PausedAnnotation: ""})
+		return u
+	}
+	kubeconfigSecret := func(uid types.UID, data string, owner metav1.OwnerReference) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test1-kubeconfig", Namespace: "test", UID: uid},
+			Data:       map[string][]byte{secret.KubeconfigDataName: []byte(data)},
+		}
+		if owner.Name != "" {
+			s.OwnerReferences = []metav1.OwnerReference{owner}
+		}
+		return s
+	}
+	kcpOwner := metav1.OwnerReference{APIVersion: kcpRef.APIVersion, Kind: kcpRef.Kind, Name: kcpRef.Name, Controller: pointer.BoolPtr(true)}
+
+	tests := []struct {
+		name           string
+		objs           []client.Object
+		regenerated    *corev1.Secret
+		wantKubeconfig string
+		wantErr        bool
+		wantSecretKept bool
+	}{
+		{
+			name:           "regenerates the kubeconfig secret owned by the KubeadmControlPlane",
+			objs:           []client.Object{workloadCluster(kcpRef), controlPlane(), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			regenerated:    kubeconfigSecret("new", "new-kubeconfig", kcpOwner),
+			wantKubeconfig: "new-kubeconfig",
+		},
+		{
+			name:           "waits for the kubeconfig secret if it does not exist yet",
+			objs:           []client.Object{workloadCluster(kcpRef), controlPlane()},
+			regenerated:    kubeconfigSecret("new", "new-kubeconfig", kcpOwner),
+			wantKubeconfig: "new-kubeconfig",
+		},
+		{
+			name:        "fails if the kubeconfig secret is not regenerated",
+			objs:        []client.Object{workloadCluster(kcpRef), controlPlane(), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			regenerated: nil,
+			wantErr:     true,
+		},
+		{
+			name:    "fails if the Cluster does not exist",
+			objs:    []client.Object{controlPlane()},
+			wantErr: true,
+		},
+		{
+			name:    "fails if the Cluster does not have a control plane provider",
+			objs:    []client.Object{workloadCluster(nil), kubeconfigSecret("old", "old-kubeconfig", metav1.OwnerReference{})},
+			wantErr: true,
+		},
+		{
+			name: "fails for control plane providers other than KubeadmControlPlane",
+			objs: []client.Object{
+				workloadCluster(&corev1.ObjectReference{APIVersion: "controlplane.cluster.x-k8s.io/v1alpha4", Kind: "AWSManagedControlPlane", Name: "test1-control-plane"}),
+				kubeconfigSecret("old", "old-kubeconfig", metav1.OwnerReference{}),
+			},
+			wantErr: true,
+		},
+		{
+			name:           "fails if the kubeconfig secret is not owned by the KubeadmControlPlane",
+			objs:           []client.Object{workloadCluster(kcpRef), controlPlane(), kubeconfigSecret("old", "old-kubeconfig", metav1.OwnerReference{})},
+			wantErr:        true,
+			wantSecretKept: true,
+		},
+		{
+			name:           "fails without deleting the kubeconfig secret if the KubeadmControlPlane does not exist",
+			objs:           []client.Object{workloadCluster(kcpRef), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			wantErr:        true,
+			wantSecretKept: true,
+		},
+		{
+			name:           "fails without deleting the kubeconfig secret if the Cluster is paused",
+			objs:           []client.Object{pausedWorkloadCluster(), controlPlane(), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			regenerated:    kubeconfigSecret("new", "new-kubeconfig", kcpOwner),
+			wantErr:        true,
+			wantSecretKept: true,
+		},
+		{
+			name:           "fails without deleting the kubeconfig secret if the Cluster has the paused annotation",
+			objs:           []client.Object{pausedAnnotationWorkloadCluster(), controlPlane(), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			regenerated:    kubeconfigSecret("new", "new-kubeconfig", kcpOwner),
+			wantErr:        true,
+			wantSecretKept: true,
+		},
+		{
+			name:           "fails without deleting the kubeconfig secret if the KubeadmControlPlane has the paused annotation",
+			objs:           []client.Object{workloadCluster(kcpRef), pausedControlPlane(), kubeconfigSecret("old", "old-kubeconfig", kcpOwner)},
+			regenerated:    kubeconfigSecret("new", "new-kubeconfig", kcpOwner),
+			wantErr:        true,
+			wantSecretKept: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			proxy := test.NewFakeProxy().WithObjs(tt.objs...)
+			cs, err := proxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// Simulates the KubeadmControlPlane controller creating the kubeconfig secret again after it is deleted.
+			pollImmediateWaiter := func(interval, timeout time.Duration, condition wait.ConditionFunc) error {
+				if tt.regenerated != nil {
+					if err := cs.Create(ctx, tt.regenerated.DeepCopy()); err != nil {
+						return err
+					}
+				}
+				done, err := condition()
+				if err != nil {
+					return err
+				}
+				if !done {
+					return wait.ErrWaitTimeout
+				}
+				return nil
+			}
+
+			wc := newWorkloadCluster(proxy, pollImmediateWaiter)
+			got, err := wc.RegenerateKubeconfig("test1", "test", time.Minute)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				if tt.wantSecretKept {
+					s := &corev1.Secret{}
+					g.Expect(cs.Get(ctx, client.ObjectKey{Namespace: "test", Name: "test1-kubeconfig"}, s)).To(Succeed())
+					g.Expect(s.UID).To(Equal(types.UID("old")))
+				}
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.wantKubeconfig))
+
+			cp := controlPlane()
+			g.Expect(cs.Get(ctx, client.ObjectKeyFromObject(cp), cp)).To(Succeed())
+			g.Expect(cp.GetAnnotations()).To(HaveKey(clusterctlv1.KubeconfigRegenerationRequestedAnnotation))
+		})
+	}
+}
@@ -0,0 +1,383 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterClassVariable is a variable defined by a ClusterClass, i.e. an item of the ClusterClass spec.variables.
+type clusterClassVariable struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Schema   struct {
+		OpenAPIV3Schema struct {
+			Type string `json:"type,omitempty"`
+		} `json:"openAPIV3Schema"`
+	} `json:"schema"`
+}
+
+// templateVariableName returns the name of the template variable providing the value for a ClusterClass variable, i.e.
+// the name of the ClusterClass variable converted from camel case to upper snake case, e.g. IMAGE_REPOSITORY for imageRepository.
+func (v clusterClassVariable) templateVariableName() string {
+	runes := []rune(v.Name)
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			b.WriteRune('_')
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// parse converts the value of a template variable to the type defined by the ClusterClass variable schema.
+func (v clusterClassVariable) parse(value string) (interface{}, error) {
+	switch v.Schema.OpenAPIV3Schema.Type {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "object", "array":
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return nil, err
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// validate checks that a value matches the type defined by the ClusterClass variable schema.
+func (v clusterClassVariable) validate(value interface{}) error {
+	valid := true
+	switch v.Schema.OpenAPIV3Schema.Type {
+	case "string":
+		_, valid = value.(string)
+	case "integer":
+		switch n := value.(type) {
+		case int64, int32, int:
+		case float64:
+			valid = n == float64(int64(n))
+		default:
+			valid = false
+		}
+	case "number":
+		switch value.(type) {
+		case int64, int32, int, float64:
+		default:
+			valid = false
+		}
+	case "boolean":
+		_, valid = value.(bool)
+	case "object":
+		_, valid = value.(map[string]interface{})
+	case "array":
+		_, valid = value.([]interface{})
+	}
+	if !valid {
+		return errors.Errorf("the value of the variable %q must be of type %s", v.Name, v.Schema.OpenAPIV3Schema.Type)
+	}
+	return nil
+}
+
+// getClusterClassVariables reads the variables defined by a ClusterClass existing in the management cluster.
+func getClusterClassVariables(proxy cluster.Proxy, namespace, name string) ([]clusterClassVariable, error) {
+	c, err := proxy.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterClass := &unstructured.Unstructured{}
+	clusterClass.SetGroupVersionKind(clusterv1.GroupVersion.WithKind("ClusterClass"))
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, clusterClass); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("the ClusterClass %s/%s does not exist in the management cluster", namespace, name)
+		}
+		return nil, errors.Wrapf(err, "failed to read the ClusterClass %s/%s", namespace, name)
+	}
+
+	rawVariables, _, err := unstructured.NestedSlice(clusterClass.Object, "spec", "variables")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the variables of the ClusterClass %s/%s", namespace, name)
+	}
+	data, err := json.Marshal(rawVariables)
+	if err != nil {
+		return nil, err
+	}
+	variables := []clusterClassVariable{}
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the variables of the ClusterClass %s/%s", namespace, name)
+	}
+	return variables, nil
+}
+
+// clusterClassProcessor is a yaml.Processor generating a Cluster with a managed topology referencing a ClusterClass already
+// existing in the management cluster, instead of all the objects defined by the template: it drops from the template the
+// ClusterClasses and the templates they reference, and it sets the class and the variables of the topology of the Clusters.
+// Variables defined by the ClusterClass and not set in the template are read from the template variables using their upper
+// snake case name (see clusterClassVariable.templateVariableName), so the required ones are reported as template variables.
+// NB. Only templates defining a Cluster with a managed topology, i.e. with spec.topology, support this processor.
+type clusterClassProcessor struct {
+	yaml.Processor
+	clusterClass string
+	variables    []clusterClassVariable
+}
+
+var _ yaml.Processor = &clusterClassProcessor{}
+
+func newClusterClassProcessor(processor yaml.Processor, clusterClass string) *clusterClassProcessor {
+	if processor == nil {
+		processor = yaml.NewSimpleProcessor()
+	}
+	return &clusterClassProcessor{
+		Processor:    processor,
+		clusterClass: clusterClass,
+	}
+}
+
+func (p *clusterClassProcessor) GetVariables(rawArtifact []byte) ([]string, error) {
+	variableMap, err := p.GetVariableMap(rawArtifact)
+	if err != nil {
+		return nil, err
+	}
+	variables := make([]string, 0, len(variableMap))
+	for name := range variableMap {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables, nil
+}
+
+func (p *clusterClassProcessor) GetVariableMap(rawArtifact []byte) (map[string]*string, error) {
+	topologyYaml, topologyVariables, err := p.filter(rawArtifact)
+	if err != nil {
+		return nil, err
+	}
+	variableMap, err := p.Processor.GetVariableMap(topologyYaml)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range p.variables {
+		if v.Required && !topologyVariables.Has(v.Name) {
+			if _, ok := variableMap[v.templateVariableName()]; !ok {
+				variableMap[v.templateVariableName()] = nil
+			}
+		}
+	}
+	return variableMap, nil
+}
+
+func (p *clusterClassProcessor) Process(rawArtifact []byte, variablesClient func(string) (string, error)) ([]byte, error) {
+	topologyYaml, _, err := p.filter(rawArtifact)
+	if err != nil {
+		return rawArtifact, err
+	}
+	processed, err := p.Processor.Process(topologyYaml, variablesClient)
+	if err != nil {
+		return rawArtifact, err
+	}
+
+	objs, err := utilyaml.ToUnstructured(processed)
+	if err != nil {
+		return rawArtifact, errors.Wrap(err, "failed to parse the processed template")
+	}
+	for i := range objs {
+		if !isCluster(objs[i]) {
+			continue
+		}
+		if err := p.setTopology(&objs[i], variablesClient); err != nil {
+			return rawArtifact, err
+		}
+	}
+	return utilyaml.FromUnstructured(objs)
+}
+
+// filter drops from the template the ClusterClasses and the templates they reference, and it returns the names of the
+// variables already set in the topology of the Clusters.
+// NB. The template is filtered before processing the variables, so the objects are read without changing their YAML.
+func (p *clusterClassProcessor) filter(rawArtifact []byte) ([]byte, sets.String, error) {
+	docs, err := splitTemplateDocuments(rawArtifact)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse the template for generating a ClusterClass-based Cluster")
+	}
+
+	clusterClassRefs := sets.NewString()
+	topologyVariables := sets.NewString()
+	hasTopology := false
+	for _, d := range docs {
+		objs, err := utilyaml.ToUnstructured(d.raw)
+		if err != nil || len(objs) == 0 {
+			continue
+		}
+		obj := objs[0]
+		switch {
+		case d.gvk.Group == clusterv1.GroupVersion.Group && d.gvk.Kind == "ClusterClass":
+			for _, ref := range getRefs(obj.Object["spec"]) {
+				clusterClassRefs.Insert(ref)
+			}
+		case isCluster(obj):
+			if _, ok, _ := unstructured.NestedMap(obj.Object, "spec", "topology"); !ok {
+				continue
+			}
+			hasTopology = true
+			variables, _, _ := unstructured.NestedSlice(obj.Object, "spec", "topology", "variables")
+			for _, v := range variables {
+				if m, ok := v.(map[string]interface{}); ok {
+					name, _ := m["name"].(string)
+					topologyVariables.Insert(name)
+				}
+			}
+		}
+	}
+	if !hasTopology {
+		return nil, nil, errors.Errorf("the template does not support generating a Cluster using the ClusterClass %q: it does not define a Cluster with spec.topology", p.clusterClass)
+	}
+
+	kept := [][]byte{}
+	for _, d := range docs {
+		if d.gvk.Group == clusterv1.GroupVersion.Group && d.gvk.Kind == "ClusterClass" {
+			continue
+		}
+		if clusterClassRefs.Has(d.gvk.Kind + "/" + d.name) {
+			continue
+		}
+		kept = append(kept, d.raw)
+	}
+	return utilyaml.JoinYaml(kept...), topologyVariables, nil
+}
+
+// setTopology sets the ClusterClass used by a Cluster and the values of the variables it defines, validating the values
+// against the variables of the ClusterClass.
+func (p *clusterClassProcessor) setTopology(obj *unstructured.Unstructured, variablesClient func(string) (string, error)) error {
+	if err := unstructured.SetNestedField(obj.Object, p.clusterClass, "spec", "topology", "class"); err != nil {
+		return errors.Wrapf(err, "failed to set the ClusterClass of the Cluster %s", obj.GetName())
+	}
+
+	variables, _, err := unstructured.NestedSlice(obj.Object, "spec", "topology", "variables")
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the topology variables of the Cluster %s", obj.GetName())
+	}
+
+	definitions := map[string]clusterClassVariable{}
+	for _, v := range p.variables {
+		definitions[v.Name] = v
+	}
+
+	errList := []error{}
+	set := sets.NewString()
+	for _, v := range variables {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := m["name"].(string)
+		set.Insert(name)
+		definition, ok := definitions[name]
+		if !ok {
+			errList = append(errList, errors.Errorf("the variable %q is not defined by the ClusterClass %q", name, p.clusterClass))
+			continue
+		}
+		if err := definition.validate(m["value"]); err != nil {
+			errList = append(errList, err)
+		}
+	}
+
+	for _, definition := range p.variables {
+		if set.Has(definition.Name) {
+			continue
+		}
+		value, err := variablesClient(definition.templateVariableName())
+		if err != nil {
+			if definition.Required {
+				errList = append(errList, errors.Errorf("value for the variable %q (%s) of the ClusterClass %q is not set", definition.Name, definition.templateVariableName(), p.clusterClass))
+			}
+			continue
+		}
+		parsed, err := definition.parse(value)
+		if err != nil {
+			errList = append(errList, errors.Wrapf(err, "invalid value for the variable %q (%s) of the ClusterClass %q", definition.Name, definition.templateVariableName(), p.clusterClass))
+			continue
+		}
+		variables = append(variables, map[string]interface{}{"name": definition.Name, "value": parsed})
+	}
+	if len(errList) > 0 {
+		return errors.Wrapf(kerrors.NewAggregate(errList), "invalid topology variables for the Cluster %s", obj.GetName())
+	}
+
+	if len(variables) == 0 {
+		return nil
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, variables, "spec", "topology", "variables"); err != nil {
+		return errors.Wrapf(err, "failed to set the topology variables of the Cluster %s", obj.GetName())
+	}
+	return nil
+}
+
+// isCluster returns true if the object is a Cluster API Cluster.
+func isCluster(obj unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == clusterv1.GroupVersion.Group && gvk.Kind == "Cluster"
+}
+
+// getRefs returns the kind/name of the object references nested in a value, i.e. the maps with a kind and a name.
+func getRefs(value interface{}) []string {
+	refs := []string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		kind, _ := v["kind"].(string)
+		name, _ := v["name"].(string)
+		if kind != "" && name != "" {
+			refs = append(refs, kind+"/"+name)
+		}
+		for _, nested := range v {
+			refs = append(refs, getRefs(nested)...)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			refs = append(refs, getRefs(nested)...)
+		}
+	}
+	return refs
+}
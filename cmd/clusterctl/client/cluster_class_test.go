@@ -0,0 +1,254 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+var clusterClassTemplate = []byte(`apiVersion: cluster.x-k8s.io/v1alpha4
+kind: ClusterClass
+metadata:
+  name: quick-start
+spec:
+  infrastructure:
+    ref:
+      apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+      kind: DockerClusterTemplate
+      name: quick-start-cluster
+---
+apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+kind: DockerClusterTemplate
+metadata:
+  name: quick-start-cluster
+spec:
+  template:
+    spec:
+      customImage: ${CLUSTER_IMAGE}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: ${CLUSTER_NAME}-credentials
+---
+apiVersion: cluster.x-k8s.io/v1alpha4
+kind: Cluster
+metadata:
+  name: ${CLUSTER_NAME}
+spec:
+  topology:
+    class: quick-start
+    version: ${KUBERNETES_VERSION}
+    variables:
+    - name: imageRepository
+      value: ${IMAGE_REPOSITORY:=k8s.gcr.io}
+`)
+
+func clusterClassVariables() []clusterClassVariable {
+	variables := []clusterClassVariable{
+		{Name: "imageRepository", Required: true},
+		{Name: "etcdImageTag", Required: true},
+		{Name: "apiServerPort"},
+	}
+	variables[0].Schema.OpenAPIV3Schema.Type = "string"
+	variables[1].Schema.OpenAPIV3Schema.Type = "string"
+	variables[2].Schema.OpenAPIV3Schema.Type = "integer"
+	return variables
+}
+
+func Test_clusterClassVariable_templateVariableName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "imageRepository", want: "IMAGE_REPOSITORY"},
+		{name: "etcdImageTag", want: "ETCD_IMAGE_TAG"},
+		{name: "apiServerURL", want: "API_SERVER_URL"},
+		{name: "HTTPProxy", want: "HTTP_PROXY"},
+		{name: "ipv6", want: "IPV6"},
+		{name: "node-type", want: "NODE_TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(clusterClassVariable{Name: tt.name}.templateVariableName()).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_clusterClassProcessor(t *testing.T) {
+	g := NewWithT(t)
+
+	p := newClusterClassProcessor(nil, "quick-start-v2")
+	p.variables = clusterClassVariables()
+
+	// The variables used by the ClusterClass and its templates are dropped; the required ClusterClass variables not set
+	// in the template are added.
+	variables, err := p.GetVariables(clusterClassTemplate)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(variables).To(Equal([]string{"CLUSTER_NAME", "ETCD_IMAGE_TAG", "IMAGE_REPOSITORY", "KUBERNETES_VERSION"}))
+
+	variableMap, err := p.GetVariableMap(clusterClassTemplate)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(variableMap).To(HaveKeyWithValue("ETCD_IMAGE_TAG", BeNil()))
+	g.Expect(variableMap).To(HaveKeyWithValue("IMAGE_REPOSITORY", pointer.StringPtr("k8s.gcr.io")))
+
+	values := map[string]string{
+		"CLUSTER_NAME":       "test",
+		"KUBERNETES_VERSION": "v1.21.2",
+		"ETCD_IMAGE_TAG":     "3.4.13-0",
+		"API_SERVER_PORT":    "6443",
+	}
+	variablesClient := func(name string) (string, error) {
+		if v, ok := values[name]; ok {
+			return v, nil
+		}
+		return "", errors.Errorf("variable %q not set", name)
+	}
+	processed, err := p.Process(clusterClassTemplate, variablesClient)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	objs, err := utilyaml.ToUnstructured(processed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetKind()).To(Equal("Secret"))
+	g.Expect(objs[1].GetKind()).To(Equal("Cluster"))
+
+	class, _, _ := unstructured.NestedString(objs[1].Object, "spec", "topology", "class")
+	g.Expect(class).To(Equal("quick-start-v2"))
+	topologyVariables, _, _ := unstructured.NestedSlice(objs[1].Object, "spec", "topology", "variables")
+	gotVariables := map[string]interface{}{}
+	for _, v := range topologyVariables {
+		m := v.(map[string]interface{})
+		gotVariables[m["name"].(string)] = m["value"]
+	}
+	g.Expect(gotVariables).To(HaveLen(3))
+	g.Expect(gotVariables).To(HaveKeyWithValue("imageRepository", "k8s.gcr.io"))
+	g.Expect(gotVariables).To(HaveKeyWithValue("etcdImageTag", "3.4.13-0"))
+	g.Expect(gotVariables).To(HaveKeyWithValue("apiServerPort", BeNumerically("==", 6443)))
+
+	// Required variables must be set.
+	delete(values, "ETCD_IMAGE_TAG")
+	_, err = p.Process(clusterClassTemplate, variablesClient)
+	g.Expect(err).To(HaveOccurred())
+
+	// Values must match the type of the variables.
+	values["ETCD_IMAGE_TAG"] = "3.4.13-0"
+	values["API_SERVER_PORT"] = "port"
+	_, err = p.Process(clusterClassTemplate, variablesClient)
+	g.Expect(err).To(HaveOccurred())
+
+	// Variables must be defined by the ClusterClass.
+	p.variables = p.variables[1:]
+	delete(values, "API_SERVER_PORT")
+	_, err = p.Process(clusterClassTemplate, variablesClient)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_clusterClassProcessor_withoutTopology(t *testing.T) {
+	g := NewWithT(t)
+
+	p := newClusterClassProcessor(nil, "quick-start")
+	_, err := p.GetVariables([]byte("apiVersion: cluster.x-k8s.io/v1alpha4\nkind: Cluster\nmetadata:\n  name: ${CLUSTER_NAME}\n"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_getClusterClassVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterClass := &unstructured.Unstructured{}
+	clusterClass.SetGroupVersionKind(clusterv1.GroupVersion.WithKind("ClusterClass"))
+	clusterClass.SetNamespace("ns1")
+	clusterClass.SetName("quick-start")
+	clusterClass.Object["spec"] = map[string]interface{}{
+		"variables": []interface{}{
+			map[string]interface{}{
+				"name":     "imageRepository",
+				"required": true,
+				"schema":   map[string]interface{}{"openAPIV3Schema": map[string]interface{}{"type": "string"}},
+			},
+			map[string]interface{}{
+				"name":   "apiServerPort",
+				"schema": map[string]interface{}{"openAPIV3Schema": map[string]interface{}{"type": "integer"}},
+			},
+		},
+	}
+	proxy := test.NewFakeProxy().WithObjs(clusterClass)
+
+	variables, err := getClusterClassVariables(proxy, "ns1", "quick-start")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(variables).To(HaveLen(2))
+	g.Expect(variables[0].Name).To(Equal("imageRepository"))
+	g.Expect(variables[0].Required).To(BeTrue())
+	g.Expect(variables[0].Schema.OpenAPIV3Schema.Type).To(Equal("string"))
+	g.Expect(variables[1].Name).To(Equal("apiServerPort"))
+	g.Expect(variables[1].Required).To(BeFalse())
+	g.Expect(variables[1].Schema.OpenAPIV3Schema.Type).To(Equal("integer"))
+
+	_, err = getClusterClassVariables(proxy, "ns2", "quick-start")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_clusterctlClient_GetClusterTemplate_withClusterClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GetClusterTemplateOptions
+	}{
+		{
+			name: "Fails if the ClusterClass does not exist",
+			options: GetClusterTemplateOptions{
+				ClusterClass: "quick-start",
+			},
+		},
+		{
+			name: "Fails if worker pools are used with a ClusterClass",
+			options: GetClusterTemplateOptions{
+				ClusterClass: "quick-start",
+				WorkerPools:  []WorkerPool{{Name: "gpu"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig()
+			cluster1 := newFakeCluster(cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}, config1)
+			client := newFakeClient(config1).
+				WithCluster(cluster1)
+
+			options := tt.options
+			options.Kubeconfig = Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+			options.RawSource = &RawSourceOptions{Content: clusterClassTemplate}
+			options.ClusterName = "test"
+			options.TargetNamespace = "ns1"
+
+			_, err := client.GetClusterTemplate(options)
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
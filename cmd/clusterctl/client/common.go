@@ -22,12 +22,15 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/validation"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 )
 
 // getComponentsByName is a utility method that returns components
 // for a given provider with options including targetNamespace.
-func (c *clusterctlClient) getComponentsByName(provider string, providerType clusterctlv1.ProviderType, options repository.ComponentsOptions) (repository.Components, error) {
+// If localPaths contains a path for the provider, identified by its manifest label, the components
+// are read from the local path instead of the provider repository defined in the clusterctl configuration.
+func (c *clusterctlClient) getComponentsByName(provider string, providerType clusterctlv1.ProviderType, options repository.ComponentsOptions, localPaths map[string]string) (repository.Components, error) {
 	// Parse the abbreviated syntax for name[:version]
 	name, version, err := parseProviderName(provider)
 	if err != nil {
@@ -41,12 +44,18 @@ func (c *clusterctlClient) getComponentsByName(provider string, providerType clu
 		return nil, err
 	}
 
+	// If there is a local path for the provider, use it as a provider repository.
+	localPath, isLocal := localPaths[providerConfig.ManifestLabel()]
+	if isLocal {
+		providerConfig = config.NewProvider(providerConfig.Name(), localPath, providerConfig.Type())
+	}
+
 	// Get a client for the provider repository and read the provider components;
 	// during the process, provider components will be processed performing variable substitution, customization of target
 	// namespace etc.
 	// Currently we are not supporting custom yaml processors for the provider
 	// components. So we revert to using the default SimpleYamlProcessor.
-	repositoryClientFactory, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig})
+	repositoryClientFactory, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig, IncludePrereleases: options.IncludePrereleases})
 	if err != nil {
 		return nil, err
 	}
@@ -55,9 +64,29 @@ func (c *clusterctlClient) getComponentsByName(provider string, providerType clu
 	if err != nil {
 		return nil, err
 	}
+
+	if isLocal {
+		return &localComponents{Components: components}, nil
+	}
 	return components, nil
 }
 
+// localComponents wraps the components of a provider read from a local path, so the provider inventory records the components URL.
+type localComponents struct {
+	repository.Components
+}
+
+func (c *localComponents) InventoryObject() clusterctlv1.Provider {
+	provider := c.Components.InventoryObject()
+	annotations := provider.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[clusterctlv1.ProviderURLAnnotation] = c.URL()
+	provider.SetAnnotations(annotations)
+	return provider
+}
+
 // parseProviderName defines a utility function that parses the abbreviated syntax for name[:version].
 func parseProviderName(provider string) (name string, version string, err error) {
 	t := strings.Split(strings.ToLower(provider), ":")
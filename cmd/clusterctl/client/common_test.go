@@ -17,9 +17,14 @@ limitations under the License.
 package client
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
 )
 
 func Test_parseProviderName(t *testing.T) {
@@ -68,3 +73,52 @@ func Test_parseProviderName(t *testing.T) {
 		})
 	}
 }
+
+func Test_clusterctlClient_getComponentsByName_withLocalPaths(t *testing.T) {
+	g := NewWithT(t)
+
+	// Creates a local repository for the infra provider, with a version different from the configured repository.
+	dir := t.TempDir()
+	componentsPath := filepath.Join(dir, "infrastructure-infra", "v3.1.0", "components.yaml")
+	g.Expect(os.MkdirAll(filepath.Dir(componentsPath), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(componentsPath, componentsYAML("ns1"), 0600)).To(Succeed())
+	metadataYAML := []byte("apiVersion: clusterctl.cluster.x-k8s.io/v1alpha3\n" +
+		"kind: Metadata\n" +
+		"releaseSeries:\n" +
+		"- major: 3\n" +
+		"  minor: 1\n" +
+		"  contract: " + test.CurrentCAPIContract + "\n")
+	g.Expect(os.WriteFile(filepath.Join(filepath.Dir(componentsPath), "metadata.yaml"), metadataYAML, 0600)).To(Succeed())
+
+	configClient := newFakeConfig().WithProvider(infraProviderConfig)
+	c, err := newClusterctlClient("fake-config", InjectConfig(configClient))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Reads the components from the local path.
+	components, err := c.getComponentsByName("infra", clusterctlv1.InfrastructureProviderType, repository.ComponentsOptions{}, map[string]string{
+		"infrastructure-infra": componentsPath,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(components.Name()).To(Equal("infra"))
+	g.Expect(components.Type()).To(Equal(clusterctlv1.InfrastructureProviderType))
+	g.Expect(components.Version()).To(Equal("v3.1.0"))
+	g.Expect(components.URL()).To(Equal(componentsPath))
+
+	// The inventory object records the components version and URL.
+	inventoryObject := components.InventoryObject()
+	g.Expect(inventoryObject.Version).To(Equal("v3.1.0"))
+	g.Expect(inventoryObject.GetAnnotations()).To(HaveKeyWithValue(clusterctlv1.ProviderURLAnnotation, componentsPath))
+
+	// Local paths for other providers are ignored, so the components are read from the configured repository (which is not valid in this test).
+	_, err = c.getComponentsByName("infra", clusterctlv1.InfrastructureProviderType, repository.ComponentsOptions{}, map[string]string{
+		"infrastructure-other": componentsPath,
+	})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).ToNot(ContainSubstring(dir))
+
+	// Local paths must adhere to the local repository layout.
+	_, err = c.getComponentsByName("infra", clusterctlv1.InfrastructureProviderType, repository.ComponentsOptions{}, map[string]string{
+		"infrastructure-infra": filepath.Join(dir, "components.yaml"),
+	})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
 	"io"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/version"
@@ -26,6 +29,7 @@ import (
 
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
 )
@@ -45,8 +49,73 @@ func (c *clusterctlClient) GetProvidersConfig() ([]Provider, error) {
 	return rr, nil
 }
 
+func (c *clusterctlClient) GetProvidersConfigByType(providerType clusterctlv1.ProviderType) ([]Provider, error) {
+	r, err := c.GetProvidersConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	rr := []Provider{}
+	for _, provider := range r {
+		if provider.Type() == providerType {
+			rr = append(rr, provider)
+		}
+	}
+
+	return rr, nil
+}
+
+// ValidateConfigOptions carries the options supported by ValidateConfig.
+type ValidateConfigOptions struct {
+	// SkipRepositoryChecks skips checking that the provider repositories are reachable, e.g. when working offline.
+	SkipRepositoryChecks bool
+}
+
+func (c *clusterctlClient) ValidateConfig(options ValidateConfigOptions) ([]ConfigValidationFinding, error) {
+	findings := c.configClient.Validate()
+
+	providers, err := c.configClient.Providers().List()
+	if err != nil {
+		// NB. Providers().List fails for invalid provider configurations, which are already reported as findings.
+		providers = nil
+	}
+	findings = append(findings, repository.ValidateOverrides(c.configClient.Variables(), providers)...)
+
+	if !options.SkipRepositoryChecks {
+		for _, provider := range providers {
+			findings = append(findings, c.validateProviderRepository(provider)...)
+		}
+	}
+
+	// ConfigValidationFinding is an alias for config.ValidationFinding; this makes the conversion
+	rr := make([]ConfigValidationFinding, len(findings))
+	for i, finding := range findings {
+		rr[i] = ConfigValidationFinding(finding)
+	}
+	return rr, nil
+}
+
+// validateProviderRepository checks that the repository of a provider is reachable and that it has at least one release.
+func (c *clusterctlClient) validateProviderRepository(provider config.Provider) []config.ValidationFinding {
+	key := fmt.Sprintf("%s.%s", config.ProvidersConfigKey, provider.ManifestLabel())
+
+	repositoryClient, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: provider})
+	if err != nil {
+		return []config.ValidationFinding{{Severity: config.ErrorValidationSeverity, Key: key, Message: fmt.Sprintf("invalid repository URL %q: %v", provider.URL(), err)}}
+	}
+
+	versions, err := repositoryClient.GetVersions()
+	if err != nil {
+		return []config.ValidationFinding{{Severity: config.ErrorValidationSeverity, Key: key, Message: fmt.Sprintf("failed to read the releases from the repository %q: %v", provider.URL(), err)}}
+	}
+	if len(versions) == 0 {
+		return []config.ValidationFinding{{Severity: config.WarningValidationSeverity, Key: key, Message: fmt.Sprintf("no releases found in the repository %q", provider.URL())}}
+	}
+	return nil
+}
+
 func (c *clusterctlClient) GetProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) (Components, error) {
-	components, err := c.getComponentsByName(provider, providerType, repository.ComponentsOptions(options))
+	components, err := c.getComponentsByName(provider, providerType, repository.ComponentsOptions(options), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +123,80 @@ func (c *clusterctlClient) GetProviderComponents(provider string, providerType c
 	return components, nil
 }
 
+// ProviderVariable describes a variable used by the provider components.
+type ProviderVariable struct {
+	ResolvedVariable
+
+	// Required is true if the provider components do not define a default value for the variable.
+	Required bool
+
+	// Default is the default value defined in the provider components, if any.
+	Default *string
+}
+
+func (c *clusterctlClient) GetProviderVersions(provider string, providerType clusterctlv1.ProviderType) ([]ProviderVersion, error) {
+	providerConfig, err := c.configClient.Providers().Get(provider, providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryClientFactory, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig})
+	if err != nil {
+		return nil, err
+	}
+	return repositoryClientFactory.GetPublishedVersions()
+}
+
+func (c *clusterctlClient) GetProviderVariables(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ProviderVariable, error) {
+	// Parse the abbreviated syntax for name[:version]
+	name, version, err := parseProviderName(provider)
+	if err != nil {
+		return nil, err
+	}
+	options.Version = version
+
+	providerConfig, err := c.configClient.Providers().Get(name, providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryClientFactory, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig, IncludePrereleases: options.IncludePrereleases})
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. The variables are read from the raw components, so the components are neither processed nor validated.
+	rawYaml, err := repositoryClientFactory.Components().Raw(repository.ComponentsOptions(options))
+	if err != nil {
+		return nil, err
+	}
+
+	processor := yaml.NewSimpleProcessor()
+	variables, err := processor.GetVariables(rawYaml)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the variables of provider %q", providerConfig.ManifestLabel())
+	}
+	variableMap, err := processor.GetVariableMap(rawYaml)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the variables of provider %q", providerConfig.ManifestLabel())
+	}
+
+	ret := make([]ProviderVariable, 0, len(variables))
+	for _, name := range variables {
+		v := c.resolveVariable(name, nil, variableMap[name])
+		if v.IsSet && (isSensitiveVariable(name) || c.configClient.Variables().IsSecret(name)) {
+			v.Value = maskedVariableValue
+			v.Masked = true
+		}
+		ret = append(ret, ProviderVariable{
+			ResolvedVariable: v,
+			Required:         variableMap[name] == nil,
+			Default:          variableMap[name],
+		})
+	}
+	return ret, nil
+}
+
 // ReaderSourceOptions define the options to be used when reading a template
 // from an arbitrary reader.
 type ReaderSourceOptions struct {
@@ -69,9 +212,69 @@ type ProcessYAMLOptions struct {
 	// SkipTemplateProcess return the list of variables expected by the template
 	// without executing any further processing.
 	SkipTemplateProcess bool
+
+	// VariablesFile is the path of a file defining values for the variables used in the yaml, using the dotenv
+	// format or, for files with the .yaml, .yml or .json extension, a map of variable names to values.
+	// Values are resolved with the following precedence order: os environment variables, the variables file,
+	// the clusterctl config file, the default values defined in the yaml.
+	VariablesFile string
+
+	// ShowSecrets disables masking of the values of sensitive variables returned by ResolveYAMLVariables.
+	ShowSecrets bool
+}
+
+// VariableSource defines where the value of a variable is read from.
+type VariableSource string
+
+const (
+	// VariableSourceEnv identifies a variable value read from the os environment variables.
+	VariableSourceEnv VariableSource = "env"
+
+	// VariableSourceVariablesFile identifies a variable value read from the variables file.
+	VariableSourceVariablesFile VariableSource = "variables file"
+
+	// VariableSourceConfigFile identifies a variable value read from the clusterctl config file.
+	VariableSourceConfigFile VariableSource = "config file"
+
+	// VariableSourceDefault identifies a variable value defined as a default in the yaml, using the ${VAR:=default} syntax.
+	VariableSourceDefault VariableSource = "default"
+)
+
+// maskedVariableValue is the value returned instead of the actual value for sensitive variables.
+const maskedVariableValue = "******"
+
+// sensitiveVariableNameParts lists the name parts identifying variables which might host sensitive data.
+var sensitiveVariableNameParts = []string{"PASSWORD", "TOKEN", "KEY"}
+
+// ResolvedVariable describes a variable used by a yaml and the value it resolves to.
+type ResolvedVariable struct {
+	// Name of the variable.
+	Name string
+
+	// IsSet is true if a value for the variable is available, either from the os environment variables,
+	// the clusterctl config file or a default value in the yaml.
+	IsSet bool
+
+	// Value the variable resolves to; it is empty if the variable is not set.
+	// If the variable name identifies sensitive data, or the value is resolved from a secret source,
+	// the value is masked unless ShowSecrets is set.
+	Value string
+
+	// Masked is true when Value is masked.
+	Masked bool
+
+	// Source of the value; it is empty if the variable is not set.
+	Source VariableSource
 }
 
 func (c *clusterctlClient) ProcessYAML(options ProcessYAMLOptions) (YamlPrinter, error) {
+	if _, err := c.setVariablesFromFile(options.VariablesFile); err != nil {
+		return nil, err
+	}
+	return c.processYAML(options)
+}
+
+func (c *clusterctlClient) processYAML(options ProcessYAMLOptions) (YamlPrinter, error) {
 	if options.ReaderSource != nil {
 		// NOTE: Beware of potentially reading in large files all at once
 		// since this is inefficient and increases memory utilziation.
@@ -109,6 +312,91 @@ func (c *clusterctlClient) ProcessYAML(options ProcessYAMLOptions) (YamlPrinter,
 	return nil, errors.New("unable to read custom template. Please specify a template source")
 }
 
+func (c *clusterctlClient) ResolveYAMLVariables(options ProcessYAMLOptions) ([]ResolvedVariable, error) {
+	fileVariables, err := c.setVariablesFromFile(options.VariablesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	options.SkipTemplateProcess = true
+	printer, err := c.processYAML(options)
+	if err != nil {
+		return nil, err
+	}
+
+	variableMap := printer.VariableMap()
+	ret := make([]ResolvedVariable, 0, len(variableMap))
+	for _, name := range printer.Variables() {
+		v := c.resolveVariable(name, fileVariables, variableMap[name])
+		if v.IsSet && !options.ShowSecrets && (isSensitiveVariable(name) || c.configClient.Variables().IsSecret(name)) {
+			v.Value = maskedVariableValue
+			v.Masked = true
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// resolveVariable returns the value of a variable using the same precedence order applied when processing a yaml:
+// os environment variables, the variables file, the clusterctl config file, and then the default value defined
+// in the yaml, if any.
+func (c *clusterctlClient) resolveVariable(name string, fileVariables map[string]string, defaultValue *string) ResolvedVariable {
+	if value, ok := os.LookupEnv(envVariableName(name)); ok {
+		// NB. Use the config client for getting the value, so values referencing a secret source are resolved.
+		if resolved, err := c.configClient.Variables().Get(name); err == nil {
+			value = resolved
+		}
+		return ResolvedVariable{Name: name, IsSet: true, Value: value, Source: VariableSourceEnv}
+	}
+	if value, ok := fileVariables[name]; ok {
+		return ResolvedVariable{Name: name, IsSet: true, Value: value, Source: VariableSourceVariablesFile}
+	}
+	if value, err := c.configClient.Variables().Get(name); err == nil {
+		return ResolvedVariable{Name: name, IsSet: true, Value: value, Source: VariableSourceConfigFile}
+	}
+	if defaultValue != nil {
+		return ResolvedVariable{Name: name, IsSet: true, Value: *defaultValue, Source: VariableSourceDefault}
+	}
+	return ResolvedVariable{Name: name}
+}
+
+// setVariablesFromFile reads the variables defined in a file, if any, and sets them into the config client
+// unless they are defined as os environment variables, which take precedence.
+func (c *clusterctlClient) setVariablesFromFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	variables, err := config.ReadVariablesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range variables {
+		if _, ok := os.LookupEnv(envVariableName(k)); ok {
+			continue
+		}
+		c.configClient.Variables().Set(k, v)
+	}
+	return variables, nil
+}
+
+// envVariableName returns the name of the os environment variable matching a variable.
+// NOTE: this mimics the rule used by the config reader for matching environment variables.
+func envVariableName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// isSensitiveVariable returns true if the variable name suggests the variable hosts sensitive data, e.g. a password.
+func isSensitiveVariable(name string) bool {
+	upperName := strings.ToUpper(name)
+	for _, part := range sensitiveVariableNameParts {
+		if strings.Contains(upperName, part) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetClusterTemplateOptions carries the options supported by GetClusterTemplate.
 type GetClusterTemplateOptions struct {
 	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
@@ -126,6 +414,10 @@ type GetClusterTemplateOptions struct {
 	// ConfigMapSource to be used for reading the workload cluster template; only one template source can be used at time.
 	ConfigMapSource *ConfigMapSourceOptions
 
+	// RawSource to be used for reading the workload cluster template from memory, e.g. from a generated manifest or
+	// from stdin; only one template source can be used at time.
+	RawSource *RawSourceOptions
+
 	// TargetNamespace where the objects describing the workload cluster should be deployed. If unspecified,
 	// the current namespace will be used.
 	TargetNamespace string
@@ -152,8 +444,32 @@ type GetClusterTemplateOptions struct {
 	// YamlProcessor defines the yaml processor to use for the cluster
 	// template processing. If not defined, SimpleProcessor will be used.
 	YamlProcessor Processor
+
+	// VariablePrompt, if set, is invoked for each variable used by the template that is not set in the clusterctl
+	// configuration or in the environment, e.g. for asking values to the user interactively.
+	VariablePrompt VariablePromptFunc
+
+	// IncludePrereleases makes prerelease versions of the infrastructure provider, e.g. release candidates, eligible
+	// when the template version is a version range.
+	IncludePrereleases bool
+
+	// WorkerPools defines the worker pools to be added to the workload cluster; if set, the MachineDeployments defined
+	// in the template are generated once for each pool, see WorkerPool for more details.
+	WorkerPools []WorkerPool
+
+	// ClusterClass, if set, makes GetClusterTemplate return only the Cluster with a managed topology defined by the template,
+	// using the ClusterClass with this name already existing in the target namespace, instead of all the objects defined by
+	// the template. The template must define a Cluster with spec.topology; the values for the variables of the ClusterClass
+	// not set in the template are read from the template variables, e.g. IMAGE_REPOSITORY for imageRepository, and the
+	// required ones are reported by the Template's Variables().
+	ClusterClass string
 }
 
+// VariablePromptFunc returns the value for a variable used by a workload cluster template; defaultValue is the default
+// value defined in the template, nil if the variable is required. Returning an empty value leaves the variable unset, so
+// the default value is used, if any, and GetClusterTemplate fails for required variables.
+type VariablePromptFunc func(name string, defaultValue *string) (string, error)
+
 // numSources return the number of template sources currently set on a GetClusterTemplateOptions.
 func (o *GetClusterTemplateOptions) numSources() int {
 	numSources := 0
@@ -166,6 +482,9 @@ func (o *GetClusterTemplateOptions) numSources() int {
 	if o.URLSource != nil {
 		numSources++
 	}
+	if o.RawSource != nil {
+		numSources++
+	}
 	return numSources
 }
 
@@ -179,6 +498,13 @@ type ProviderRepositorySourceOptions struct {
 	// Flavor defines The workload cluster template variant to be used when reading from the infrastructure
 	// provider repository. If unspecified, the default cluster template will be used.
 	Flavor string
+
+	// Flavors defines a list of workload cluster template variants to be read from the infrastructure provider
+	// repository and merged into a single template, in order, e.g. a base cluster template and an add-on overlay;
+	// an empty string identifies the default cluster template. Identical objects defined in more than one
+	// flavor are de-duplicated, while objects with the same kind, namespace and name but different content are
+	// reported as a conflict. Flavor and Flavors can't be used at the same time.
+	Flavors []string
 }
 
 // URLSourceOptions defines the options to be used when reading a workload cluster template from an URL.
@@ -187,6 +513,15 @@ type URLSourceOptions struct {
 	URL string
 }
 
+// RawSourceOptions defines the options to be used when reading a workload cluster template from memory.
+type RawSourceOptions struct {
+	// Content of the workload cluster template.
+	Content []byte
+
+	// Reader to read the workload cluster template from, e.g. os.Stdin; it is used only if Content is empty.
+	Reader io.Reader
+}
+
 // DefaultCustomTemplateConfigMapKey  where the workload cluster template is hosted.
 const DefaultCustomTemplateConfigMapKey = "template"
 
@@ -209,6 +544,12 @@ func (c *clusterctlClient) GetClusterTemplate(options GetClusterTemplateOptions)
 	if numsSource > 1 {
 		return nil, errors.New("invalid cluster template source: only one template can be used at time")
 	}
+	if options.ProviderRepositorySource != nil && options.ProviderRepositorySource.Flavor != "" && len(options.ProviderRepositorySource.Flavors) > 0 {
+		return nil, errors.New("invalid cluster template source: only one of flavor and flavors can be used at time")
+	}
+	if options.ClusterClass != "" && len(options.WorkerPools) > 0 {
+		return nil, errors.New("invalid cluster template options: worker pools can't be used with a ClusterClass, the workers are defined by the Cluster topology")
+	}
 
 	// If no source is set, defaults to using an empty ProviderRepositorySource so values will be
 	// inferred from the cluster inventory.
@@ -216,8 +557,30 @@ func (c *clusterctlClient) GetClusterTemplate(options GetClusterTemplateOptions)
 		options.ProviderRepositorySource = &ProviderRepositorySourceOptions{}
 	}
 
+	// Reads the template from the RawSource reader, if any, so the content can be processed many times.
+	if options.RawSource != nil && len(options.RawSource.Content) == 0 && options.RawSource.Reader != nil {
+		content, err := io.ReadAll(options.RawSource.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the cluster template")
+		}
+		options.RawSource = &RawSourceOptions{Content: content}
+	}
+
+	// If worker pools are defined, the template is expanded into a copy of the MachineDeployments for each pool
+	// before processing it.
+	if len(options.WorkerPools) > 0 {
+		options.YamlProcessor = newWorkerPoolsProcessor(options.YamlProcessor, options.WorkerPools)
+	}
+
+	// If a ClusterClass is defined, the template is reduced to a Cluster using the ClusterClass.
+	var clusterClassProcessor *clusterClassProcessor
+	if options.ClusterClass != "" {
+		clusterClassProcessor = newClusterClassProcessor(options.YamlProcessor, options.ClusterClass)
+		options.YamlProcessor = clusterClassProcessor
+	}
+
 	// Gets  the client for the current management cluster
-	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{options.Kubeconfig, options.YamlProcessor})
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig, Processor: options.YamlProcessor})
 	if err != nil {
 		return nil, err
 	}
@@ -234,12 +597,63 @@ func (c *clusterctlClient) GetClusterTemplate(options GetClusterTemplateOptions)
 		options.TargetNamespace = currentNamespace
 	}
 
+	// Reads the variables of the ClusterClass, so the processor can validate the values provided for them.
+	if clusterClassProcessor != nil {
+		variables, err := getClusterClassVariables(clusterClient.Proxy(), options.TargetNamespace, options.ClusterClass)
+		if err != nil {
+			return nil, err
+		}
+		clusterClassProcessor.variables = variables
+	}
+
 	// Inject some of the templateOptions into the configClient so they can be consumed as a variables from the template.
 	if err := c.templateOptionsToVariables(options); err != nil {
 		return nil, err
 	}
 
-	// Gets the workload cluster template from the selected source
+	// Inject the values for the variables of the worker pools, if any, into the configClient.
+	if err := c.workerPoolsToVariables(options); err != nil {
+		return nil, err
+	}
+
+	// If requested, asks for the values of the variables not yet set before processing the template.
+	if options.VariablePrompt != nil && !options.ListVariablesOnly {
+		if err := c.promptTemplateVariables(clusterClient, options); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.getTemplate(clusterClient, options)
+}
+
+// promptTemplateVariables invokes the VariablePrompt for each variable used by the template not yet set, and
+// sets the returned values, if not empty, in the configClient.
+func (c *clusterctlClient) promptTemplateVariables(clusterClient cluster.Client, options GetClusterTemplateOptions) error {
+	options.ListVariablesOnly = true
+	template, err := c.getTemplate(clusterClient, options)
+	if err != nil {
+		return err
+	}
+
+	variableMap := template.VariableMap()
+	for _, name := range template.Variables() {
+		if _, err := c.configClient.Variables().Get(name); err == nil {
+			continue
+		}
+
+		value, err := options.VariablePrompt(name, variableMap[name])
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the value for the variable %q", name)
+		}
+		if value != "" {
+			c.configClient.Variables().Set(name, value)
+		}
+	}
+	return nil
+}
+
+// getTemplate returns a workload cluster template from the selected source.
+func (c *clusterctlClient) getTemplate(clusterClient cluster.Client, options GetClusterTemplateOptions) (Template, error) {
 	if options.ProviderRepositorySource != nil {
 		// Ensure this command only runs against management clusters with the current Cluster API contract.
 		// NOTE: This command tolerates also not existing cluster (Kubeconfig.Path=="") or clusters not yet initialized in order to allow
@@ -258,6 +672,9 @@ func (c *clusterctlClient) GetClusterTemplate(options GetClusterTemplateOptions)
 	if options.URLSource != nil {
 		return c.getTemplateFromURL(clusterClient, *options.URLSource, options.TargetNamespace, options.ListVariablesOnly)
 	}
+	if options.RawSource != nil {
+		return clusterClient.Template().GetFromRaw(options.RawSource.Content, options.TargetNamespace, options.ListVariablesOnly)
+	}
 
 	return nil, errors.New("unable to read custom template. Please specify a template source")
 }
@@ -322,14 +739,27 @@ func (c *clusterctlClient) getTemplateFromRepository(cluster cluster.Client, opt
 		return nil, err
 	}
 
-	repo, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig, Processor: processor})
+	repo, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig, Processor: processor, IncludePrereleases: options.IncludePrereleases})
 	if err != nil {
 		return nil, err
 	}
 
-	template, err := repo.Templates(version).Get(source.Flavor, targetNamespace, listVariablesOnly)
+	if len(source.Flavors) == 0 {
+		return repo.Templates(version).Get(source.Flavor, targetNamespace, listVariablesOnly)
+	}
+
+	templates := make([]repository.Template, 0, len(source.Flavors))
+	for _, flavor := range source.Flavors {
+		template, err := repo.Templates(version).Get(flavor, targetNamespace, listVariablesOnly)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	template, err := repository.MergeTemplates(templates...)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "failed to merge the cluster template flavors %q", source.Flavors)
 	}
 	return template, nil
 }
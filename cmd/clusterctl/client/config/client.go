@@ -17,6 +17,8 @@ limitations under the License.
 package config
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 )
 
@@ -38,11 +40,19 @@ type Client interface {
 
 	// ImageMeta provide access to to image meta configurations.
 	ImageMeta() ImageMetaClient
+
+	// Validate checks the clusterctl configuration, returning the list of problems found, if any.
+	Validate() []ValidationFinding
 }
 
 // configClient implements Client.
 type configClient struct {
-	reader Reader
+	reader             Reader
+	profile            string
+	secrets            *secretResolvers
+	identities         []IdentitySource
+	identityTokenFiles bool
+	readerOptions      []viperReaderOption
 }
 
 // ensure configClient implements Client.
@@ -57,7 +67,7 @@ func (c *configClient) Providers() ProvidersClient {
 }
 
 func (c *configClient) Variables() VariablesClient {
-	return newVariablesClient(c.reader)
+	return newVariablesClient(c.reader, c.secrets, c.identities, c.identityTokenFiles)
 }
 
 func (c *configClient) ImageMeta() ImageMetaClient {
@@ -74,20 +84,104 @@ func InjectReader(reader Reader) Option {
 	}
 }
 
+// WithProfile selects a profile defined in the clusterctl config file; the values defined in the profile
+// are merged over the ones defined in the base section of the config file.
+// NB. Profiles are not supported when a configuration reader is injected.
+func WithProfile(profile string) Option {
+	return func(c *configClient) {
+		c.profile = profile
+	}
+}
+
+// WithRemoteConfigCacheTTL sets for how long a config file downloaded from a remote location is reused before
+// downloading it again, overriding the remote-config-cache-ttl environment variable; the cached copy is also used
+// when the download fails, whatever its age.
+func WithRemoteConfigCacheTTL(ttl time.Duration) Option {
+	return func(c *configClient) {
+		c.readerOptions = append(c.readerOptions, injectRemoteConfigTTL(ttl))
+	}
+}
+
+// WithRemoteConfigChecksum sets the expected sha256 checksum of a config file downloaded from a remote location,
+// overriding the remote-config-checksum environment variable; files not matching the checksum are rejected.
+func WithRemoteConfigChecksum(checksum string) Option {
+	return func(c *configClient) {
+		c.readerOptions = append(c.readerOptions, injectRemoteConfigChecksum(checksum))
+	}
+}
+
+// WithConfigFiles sets additional local clusterctl config files, merged in order over the
+// config file passed to New, or over the default config file if any. Values defined in later files take precedence:
+// maps, e.g. cert-manager or images, are merged key by key, providers are merged by name and type, with a provider
+// replacing the one with the same name and type defined earlier, and any other value replaces the one defined earlier.
+// Profiles are applied after merging all the files; environment variables still take precedence over every file.
+// NB. Additional config files are not supported when a configuration reader is injected.
+func WithConfigFiles(paths ...string) Option {
+	return func(c *configClient) {
+		c.readerOptions = append(c.readerOptions, injectExtraConfigFiles(paths))
+	}
+}
+
+// WithFileSecretSource enables the file secret source, reading the variables with a value in the form ${file:/path/to/secret}
+// from the referenced file; the secret source is disabled by default, so the configuration can't read arbitrary files.
+func WithFileSecretSource() Option {
+	return func(c *configClient) {
+		c.secrets.resolvers[FileSecretSource] = SecretResolverFunc(resolveFileSecret)
+	}
+}
+
+// WithExecSecretSource enables the exec secret source, reading the variables with a value in the form ${exec:name} from
+// the output of a command in the allow-list, indexed by name, e.g. {"gh-token": {"gh", "auth", "token"}}; the command is
+// not executed in a shell. The secret source is disabled by default, and only the commands in the allow-list can be run.
+func WithExecSecretSource(commands map[string][]string) Option {
+	return func(c *configClient) {
+		c.secrets.resolvers[ExecSecretSource] = newExecSecretResolver(commands)
+	}
+}
+
+// WithSecretResolver registers a SecretResolver for a secret source, e.g. a vault; variables with a value in the
+// form ${source:ref} are resolved using the resolver. A resolver for the file or exec secret sources replaces the
+// built-in one, enabling the secret source.
+func WithSecretResolver(source string, resolver SecretResolver) Option {
+	return func(c *configClient) {
+		c.secrets.resolvers[source] = resolver
+	}
+}
+
+// WithIdentitySource registers an IdentitySource, e.g. for reading the credentials of a provider from the workload
+// identity of a cloud; identity sources are used, in order, for the variables not defined in the environment or in the
+// clusterctl config file, after the token files defined in the config file, if enabled.
+func WithIdentitySource(source IdentitySource) Option {
+	return func(c *configClient) {
+		c.identities = append(c.identities, source)
+	}
+}
+
+// WithIdentityTokenFiles enables reading the variables not defined in the environment or in the clusterctl config file
+// from the token files listed in the identity-token-files configuration; token files are disabled by default, so the
+// configuration can't read arbitrary files, and they can't be defined in a config file downloaded from a remote location.
+func WithIdentityTokenFiles() Option {
+	return func(c *configClient) {
+		c.identityTokenFiles = true
+	}
+}
+
 // New returns a Client for interacting with the clusterctl configuration.
 func New(path string, options ...Option) (Client, error) {
 	return newConfigClient(path, options...)
 }
 
 func newConfigClient(path string, options ...Option) (*configClient, error) {
-	client := &configClient{}
+	client := &configClient{
+		secrets: newSecretResolvers(),
+	}
 	for _, o := range options {
 		o(client)
 	}
 
 	// if there is an injected reader, use it, otherwise use a default one
 	if client.reader == nil {
-		client.reader = newViperReader()
+		client.reader = newViperReader(append([]viperReaderOption{injectProfile(client.profile)}, client.readerOptions...)...)
 		if err := client.reader.Init(path); err != nil {
 			return nil, errors.Wrap(err, "failed to initialize the configuration reader")
 		}
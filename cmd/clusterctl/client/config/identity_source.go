@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+// IdentityTokenFilesConfigKey defines the key of the clusterctl config file hosting a list of variables with the path
+// of the token file providing each variable, e.g. the service account token projected for workload identity; the
+// variables not defined in the environment or in the config file are read from the corresponding token file.
+// NB. Token files are disabled by default, see WithIdentityTokenFiles.
+const IdentityTokenFilesConfigKey = "identity-token-files"
+
+// configIdentityTokenFile is an entry of the identity-token-files list in the clusterctl config file.
+// NB. A list is used instead of a map, because the keys of the maps in the config file are lowercased.
+type configIdentityTokenFile struct {
+	Variable string `json:"variable"`
+	Path     string `json:"path"`
+}
+
+// IdentitySource provides the value of credential variables from a cloud-native identity, e.g. a workload identity
+// token, so the credentials required by a provider are not stored in plaintext in environment variables or in the
+// clusterctl config file. Identity sources are used only for the variables not defined otherwise.
+type IdentitySource interface {
+	// Credential returns the value of a variable and true, or false if the identity source does not provide the variable.
+	Credential(variable string) (string, bool, error)
+}
+
+// IdentitySourceFunc allows to use an ordinary function as an IdentitySource.
+type IdentitySourceFunc func(variable string) (string, bool, error)
+
+// Credential calls f(variable).
+func (f IdentitySourceFunc) Credential(variable string) (string, bool, error) {
+	return f(variable)
+}
+
+// TokenFileIdentitySource is an IdentitySource providing variables with the content of token files, indexed by
+// variable name; files are read every time a variable is requested, so rotated tokens are picked up.
+type TokenFileIdentitySource map[string]string
+
+// Credential returns the content of the token file for a variable.
+func (s TokenFileIdentitySource) Credential(variable string) (string, bool, error) {
+	path, ok := s[variable]
+	if !ok {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", true, errors.Wrapf(err, "failed to read the token file %q", path)
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// identityCredential returns the value of a variable from the token files defined in the clusterctl config file, if
+// enabled, or from the given identity sources, in this order, and true; it returns false if no identity source provides
+// the variable.
+func identityCredential(reader Reader, tokenFiles bool, sources []IdentitySource, key string) (string, bool, error) {
+	if tokenFiles {
+		tokenFileSource, err := configTokenFileIdentitySource(reader)
+		if err != nil {
+			return "", false, err
+		}
+		sources = append([]IdentitySource{tokenFileSource}, sources...)
+	}
+
+	for _, source := range sources {
+		// NB. The credential is never logged.
+		value, ok, err := source.Credential(key)
+		if err != nil {
+			return "", true, errors.Wrapf(err, "failed to read the value of variable %q from the identity source", key)
+		}
+		if ok {
+			logf.Log.V(5).Info("Variable read from identity source", "Variable", key)
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// configTokenFileIdentitySource returns the TokenFileIdentitySource for the token files defined in the clusterctl config
+// file. Token files defined in a config file downloaded from a remote location are rejected, given that whoever controls
+// the remote location should not be allowed to read files on the local machine.
+func configTokenFileIdentitySource(reader Reader) (TokenFileIdentitySource, error) {
+	configTokenFiles := []configIdentityTokenFile{}
+	if err := reader.UnmarshalKey(IdentityTokenFilesConfigKey, &configTokenFiles); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the %q configuration", IdentityTokenFilesConfigKey)
+	}
+	tokenFiles := TokenFileIdentitySource{}
+	if len(configTokenFiles) == 0 {
+		return tokenFiles, nil
+	}
+
+	if r, ok := reader.(remoteValuesReader); ok {
+		value, err := reader.Get(IdentityTokenFilesConfigKey)
+		if err == nil && r.isRemoteValue(IdentityTokenFilesConfigKey, value) {
+			return nil, errors.Errorf("the %q configuration can't be used in a config file downloaded from a remote location", IdentityTokenFilesConfigKey)
+		}
+	}
+
+	for _, f := range configTokenFiles {
+		tokenFiles[f.Variable] = f.Path
+	}
+	return tokenFiles, nil
+}
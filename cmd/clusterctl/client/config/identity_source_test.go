@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_variablesClient_Get_withIdentitySources(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	g.Expect(os.WriteFile(tokenFile, []byte("token-1\n"), 0600)).To(Succeed())
+
+	reader := test.NewFakeReader().
+		WithVar("DEFINED_TOKEN", "from-config").
+		WithVar(IdentityTokenFilesConfigKey, fmt.Sprintf(
+			"- variable: AZURE_FEDERATED_TOKEN\n  path: %s\n- variable: DEFINED_TOKEN\n  path: %s\n- variable: MISSING_TOKEN\n  path: %s\n",
+			tokenFile, tokenFile, filepath.Join(dir, "missing")))
+	source := IdentitySourceFunc(func(variable string) (string, bool, error) {
+		switch variable {
+		case "AWS_WEB_IDENTITY_TOKEN", "AZURE_FEDERATED_TOKEN":
+			return "from-source", true, nil
+		}
+		return "", false, nil
+	})
+	p := newVariablesClient(reader, newSecretResolvers(), []IdentitySource{source}, true)
+
+	// Undefined variables are read from the token files first, and then from the identity sources.
+	got, err := p.Get("AZURE_FEDERATED_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("token-1"))
+	g.Expect(p.IsSecret("AZURE_FEDERATED_TOKEN")).To(BeTrue())
+
+	got, err = p.Get("AWS_WEB_IDENTITY_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("from-source"))
+
+	// Token files are read every time, so rotated tokens are picked up.
+	g.Expect(os.WriteFile(tokenFile, []byte("token-2\n"), 0600)).To(Succeed())
+	got, err = p.Get("AZURE_FEDERATED_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("token-2"))
+
+	// Defined variables take precedence over the identity sources.
+	got, err = p.Get("DEFINED_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("from-config"))
+	g.Expect(p.IsSecret("DEFINED_TOKEN")).To(BeFalse())
+
+	// Variables not provided by any identity source are not defined.
+	_, err = p.Get("OTHER")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(p.IsSecret("OTHER")).To(BeFalse())
+
+	// Token files must exist.
+	_, err = p.Get("MISSING_TOKEN")
+	g.Expect(err).To(HaveOccurred())
+
+	// Token files are not read if not enabled.
+	p = newVariablesClient(reader, newSecretResolvers(), []IdentitySource{source}, false)
+	got, err = p.Get("AZURE_FEDERATED_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("from-source"))
+	_, err = p.Get("MISSING_TOKEN")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(p.IsSecret("MISSING_TOKEN")).To(BeFalse())
+}
+
+func Test_variablesClient_Get_RemoteConfigIdentityTokenFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	g.Expect(os.WriteFile(tokenFile, []byte("token-1\n"), 0600)).To(Succeed())
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s:\n- variable: AZURE_FEDERATED_TOKEN\n  path: %s\n", IdentityTokenFilesConfigKey, tokenFile)
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL,
+		func(c *configClient) {
+			c.readerOptions = append(c.readerOptions, injectConfigPaths([]string{dir}), injectHTTPClient(ts.Client()))
+		},
+		WithIdentityTokenFiles(),
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Token files defined in the remote config file are never read.
+	_, err = c.Variables().Get("AZURE_FEDERATED_TOKEN")
+	g.Expect(err).To(MatchError(ContainSubstring("can't be used in a config file downloaded from a remote location")))
+}
+
+func Test_configClient_WithIdentitySource(t *testing.T) {
+	g := NewWithT(t)
+
+	source := IdentitySourceFunc(func(variable string) (string, bool, error) {
+		return "from-source", variable == "GCP_TOKEN", nil
+	})
+	c, err := New("", InjectReader(test.NewFakeReader()), WithIdentitySource(source))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	got, err := c.Variables().Get("GCP_TOKEN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("from-source"))
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/docker/distribution/reference"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/cluster-api/util/container"
 )
@@ -55,7 +56,12 @@ func newImageMetaClient(reader Reader) *imageMetaClient {
 }
 
 func (p *imageMetaClient) AlterImage(component, imageString string) (string, error) {
-	image, err := container.ImageFromString(imageString)
+	// Images without an explicit registry are considered hosted on Docker Hub, e.g. nginx is parsed as docker.io/library/nginx.
+	normalized, err := reference.ParseNormalizedNamed(imageString)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't parse image name")
+	}
+	image, err := container.ImageFromString(normalized.String())
 	if err != nil {
 		return "", err
 	}
@@ -70,7 +76,7 @@ func (p *imageMetaClient) AlterImage(component, imageString string) (string, err
 	}
 
 	// Apply the image meta to image name
-	return meta.ApplyToImage(image), nil
+	return meta.ApplyToImage(image)
 }
 
 // getImageMeta returns the image meta that applies to the selected component/image.
@@ -126,6 +132,10 @@ type imageMeta struct {
 
 	// Tag allows to specify a tag for the images.
 	Tag string `json:"tag,omitempty"`
+
+	// Registry replaces the container registry of the images, preserving the repository path, e.g. for pulling
+	// images from a mirror; it is applied after repository.
+	Registry string `json:"registry,omitempty"`
 }
 
 // Union allows to merge two imageMeta transformation; in case both the imageMeta defines new values for the same field,
@@ -137,10 +147,13 @@ func (i *imageMeta) Union(other *imageMeta) {
 	if other.Tag != "" {
 		i.Tag = other.Tag
 	}
+	if other.Registry != "" {
+		i.Registry = other.Registry
+	}
 }
 
 // ApplyToImage changes an image name applying the transformations defined in the current imageMeta.
-func (i *imageMeta) ApplyToImage(image container.Image) string {
+func (i *imageMeta) ApplyToImage(image container.Image) (string, error) {
 	// apply transformations
 	if i.Repository != "" {
 		image.Repository = strings.TrimSuffix(i.Repository, "/")
@@ -148,7 +161,10 @@ func (i *imageMeta) ApplyToImage(image container.Image) string {
 	if i.Tag != "" {
 		image.Tag = i.Tag
 	}
+	if i.Registry != "" {
+		return container.ModifyImageRegistry(image.String(), i.Registry)
+	}
 
 	// returns the resulting image name
-	return image.String()
+	return image.String(), nil
 }
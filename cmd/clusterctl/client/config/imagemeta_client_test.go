@@ -116,6 +116,44 @@ func Test_imageMetaClient_AlterImage(t *testing.T) {
 			want:    "foo-repository.io/cert-manager-cainjector:bar-tag",
 			wantErr: false,
 		},
+		{
+			name: "image registry config for all: the registry of the images should be changed preserving the repository path",
+			fields: fields{
+				reader: test.NewFakeReader().WithImageRegistry(allImageConfig, "mirror.example.com"),
+			},
+			args: args{
+				component: CertManagerImageComponent,
+				image:     "quay.io/jetstack/cert-manager-cainjector:v1.1.0",
+			},
+			want:    "mirror.example.com/jetstack/cert-manager-cainjector:v1.1.0",
+			wantErr: false,
+		},
+		{
+			name: "image registry config for all: images without an explicit registry should be considered hosted on Docker Hub",
+			fields: fields{
+				reader: test.NewFakeReader().WithImageRegistry(allImageConfig, "mirror.example.com"),
+			},
+			args: args{
+				component: "any",
+				image:     "nginx:1.21",
+			},
+			want:    "mirror.example.com/library/nginx:1.21",
+			wantErr: false,
+		},
+		{
+			name: "image registry and repository config: the registry is applied after the repository",
+			fields: fields{
+				reader: test.NewFakeReader().
+					WithImageMeta(CertManagerImageComponent, "foo-repository.io/jetstack", "").
+					WithImageRegistry(allImageConfig, "mirror.example.com"),
+			},
+			args: args{
+				component: CertManagerImageComponent,
+				image:     "quay.io/jetstack/cert-manager-cainjector:v1.1.0",
+			},
+			want:    "mirror.example.com/jetstack/cert-manager-cainjector:v1.1.0",
+			wantErr: false,
+		},
 		{
 			name: "image config for cert-manager/cert-manager-cainjector and for cert-manager: images for the cert-manager/cert-manager-webhook should be changed according to the most generic",
 			fields: fields{
@@ -92,13 +92,16 @@ func NewProvider(name string, url string, ttype clusterctlv1.ProviderType) Provi
 	}
 }
 
+// MarshalJSON serializes a provider as its name, its type, and the URL of the provider repository split into
+// the base URL and the name of the components file.
+// NB. The keys are capitalized for compatibility with the output of previous versions of clusterctl.
 func (p provider) MarshalJSON() ([]byte, error) {
 	dir, file := filepath.Split(p.url)
 	j, err := json.Marshal(struct {
-		Name         string
-		ProviderType clusterctlv1.ProviderType
-		URL          string
-		File         string
+		Name         string                    `json:"Name"`
+		ProviderType clusterctlv1.ProviderType `json:"ProviderType"`
+		URL          string                    `json:"URL"`
+		File         string                    `json:"File"`
 	}{
 		Name:         p.name,
 		ProviderType: p.providerType,
@@ -64,6 +64,9 @@ const (
 // Other.
 const (
 	ProvidersConfigKey = "providers"
+
+	// ProviderAliasesConfigKey defines the name of the top level config key for provider aliases.
+	ProviderAliasesConfigKey = "provider-aliases"
 )
 
 // ProvidersClient has methods to work with provider configurations.
@@ -73,8 +76,14 @@ type ProvidersClient interface {
 	// In case of conflict, user-defined provider override the hard-coded configurations.
 	List() ([]Provider, error)
 
+	// ListUserDefined returns the provider configurations read from the clusterctl configuration file, including the
+	// ones overriding hard-coded configurations.
+	ListUserDefined() ([]Provider, error)
+
 	// Get returns the configuration for the provider with a given name/type.
-	// In case the name/type does not correspond to any existing provider, an error is returned.
+	// In case the name/type does not correspond to any existing provider, the name is resolved using the provider aliases
+	// defined in the clusterctl configuration file; the type can be ProviderTypeUnknown when resolving an alias referring
+	// to a provider unique across all the provider types. If the name can't be resolved, an error is returned.
 	Get(name string, providerType clusterctlv1.ProviderType) (Provider, error)
 }
 
@@ -219,18 +228,12 @@ func (p *providersClient) List() ([]Provider, error) {
 
 	// Gets user defined provider configurations, validate them, and merges with
 	// hard-coded configurations handling conflicts (user defined take precedence on hard-coded)
-
-	userDefinedProviders := []configProvider{}
-	if err := p.reader.UnmarshalKey(ProvidersConfigKey, &userDefinedProviders); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal providers from the clusterctl configuration file")
+	userDefinedProviders, err := p.ListUserDefined()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, u := range userDefinedProviders {
-		provider := NewProvider(u.Name, u.URL, u.Type)
-		if err := validateProvider(provider); err != nil {
-			return nil, errors.Wrapf(err, "error validating configuration for the %s with name %s. Please fix the providers value in clusterctl configuration file", provider.Type(), provider.Name())
-		}
-
+	for _, provider := range userDefinedProviders {
 		override := false
 		for i := range providers {
 			if providers[i].SameAs(provider) {
@@ -252,6 +255,23 @@ func (p *providersClient) List() ([]Provider, error) {
 	return providers, nil
 }
 
+func (p *providersClient) ListUserDefined() ([]Provider, error) {
+	userDefinedProviders := []configProvider{}
+	if err := p.reader.UnmarshalKey(ProvidersConfigKey, &userDefinedProviders); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal providers from the clusterctl configuration file")
+	}
+
+	providers := []Provider{}
+	for _, u := range userDefinedProviders {
+		provider := NewProvider(u.Name, u.URL, u.Type)
+		if err := validateProvider(provider); err != nil {
+			return nil, errors.Wrapf(err, "error validating configuration for the %s with name %s. Please fix the providers value in clusterctl configuration file", provider.Type(), provider.Name())
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
 func (p *providersClient) Get(name string, providerType clusterctlv1.ProviderType) (Provider, error) {
 	l, err := p.List()
 	if err != nil {
@@ -265,9 +285,56 @@ func (p *providersClient) Get(name string, providerType clusterctlv1.ProviderTyp
 		}
 	}
 
+	// If there is no provider with the given name, check if the name is an alias.
+	r, err := p.resolveAlias(l, name, providerType)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		return r, nil
+	}
+
 	return nil, errors.Errorf("failed to get configuration for the %s with name %s. Please check the provider name and/or add configuration for new providers using the .clusterctl config file", providerType, name)
 }
 
+// resolveAlias returns the provider an alias defined in the clusterctl configuration file refers to, or nil if the alias
+// is not defined. Aliases can refer to a provider by name, e.g. aws, or by provider label, e.g. infrastructure-aws.
+func (p *providersClient) resolveAlias(providers []Provider, alias string, providerType clusterctlv1.ProviderType) (Provider, error) {
+	aliases := map[string]string{}
+	if err := p.reader.UnmarshalKey(ProviderAliasesConfigKey, &aliases); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal provider aliases from the clusterctl configuration file")
+	}
+
+	target, ok := aliases[alias]
+	if !ok {
+		return nil, nil
+	}
+
+	matches := []Provider{}
+	for _, r := range providers {
+		if r.Name() != target && r.ManifestLabel() != target {
+			continue
+		}
+		if providerType != clusterctlv1.ProviderTypeUnknown && r.Type() != providerType {
+			continue
+		}
+		matches = append(matches, r)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.Errorf("failed to get configuration for the provider with alias %s: %s does not match any provider of type %s. Please check the provider-aliases value in clusterctl configuration file", alias, target, providerType)
+	case 1:
+		return matches[0], nil
+	default:
+		labels := make([]string, 0, len(matches))
+		for _, m := range matches {
+			labels = append(labels, m.ManifestLabel())
+		}
+		return nil, errors.Errorf("the provider alias %s is ambiguous: %s matches the providers %s. Please use the provider label in the provider-aliases value in clusterctl configuration file", alias, target, strings.Join(labels, ", "))
+	}
+}
+
 func validateProvider(r Provider) error {
 	if r.Name() == "" {
 		return errors.New("name value cannot be empty")
@@ -249,7 +249,10 @@ func Test_providers_Defaults(t *testing.T) {
 }
 
 func Test_providers_Get(t *testing.T) {
-	reader := test.NewFakeReader()
+	reader := test.NewFakeReader().
+		WithProviderAlias("capa", "infrastructure-aws").
+		WithProviderAlias("ck", KubeadmBootstrapProviderName).
+		WithProviderAlias("bar", "foo")
 
 	p := &providersClient{
 		reader: reader,
@@ -312,6 +315,60 @@ func Test_providers_Get(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "alias referring to a provider label",
+			args: args{
+				name:         "capa",
+				providerType: clusterctlv1.InfrastructureProviderType,
+			},
+			want:    NewProvider(AWSProviderName, "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases/latest/infrastructure-components.yaml", clusterctlv1.InfrastructureProviderType),
+			wantErr: false,
+		},
+		{
+			name: "alias referring to a provider label with unknown type",
+			args: args{
+				name:         "capa",
+				providerType: clusterctlv1.ProviderTypeUnknown,
+			},
+			want:    NewProvider(AWSProviderName, "https://github.com/kubernetes-sigs/cluster-api-provider-aws/releases/latest/infrastructure-components.yaml", clusterctlv1.InfrastructureProviderType),
+			wantErr: false,
+		},
+		{
+			name: "alias referring to a provider name",
+			args: args{
+				name:         "ck",
+				providerType: clusterctlv1.ControlPlaneProviderType,
+			},
+			want:    NewProvider(KubeadmControlPlaneProviderName, "https://github.com/kubernetes-sigs/cluster-api/releases/latest/control-plane-components.yaml", clusterctlv1.ControlPlaneProviderType),
+			wantErr: false,
+		},
+		{
+			name: "fails if the alias is ambiguous",
+			args: args{
+				name:         "ck",
+				providerType: clusterctlv1.ProviderTypeUnknown,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "fails if the alias refers to a provider of a different type",
+			args: args{
+				name:         "capa",
+				providerType: clusterctlv1.BootstrapProviderType,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "fails if the alias refers to a provider that does not exist",
+			args: args{
+				name:         "bar",
+				providerType: clusterctlv1.InfrastructureProviderType,
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
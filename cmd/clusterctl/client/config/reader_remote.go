@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+const (
+	// RemoteConfigCacheTTLVariable defines a variable hosting for how long a clusterctl config file downloaded from a
+	// remote location is reused before downloading it again, e.g. 1h; by default the file is downloaded every time.
+	// NB. This variable is read from the environment only, because it is used before reading the config file.
+	RemoteConfigCacheTTLVariable = "remote-config-cache-ttl"
+
+	// RemoteConfigChecksumVariable defines a variable hosting the expected sha256 checksum of a clusterctl config file
+	// downloaded from a remote location, in the sha256:{hex} or in the {hex} form.
+	// NB. This variable is read from the environment only, because it is used before reading the config file.
+	RemoteConfigChecksumVariable = "remote-config-checksum"
+)
+
+// remoteConfig defines how a clusterctl config file is downloaded from a remote location and cached on the local disk.
+type remoteConfig struct {
+	url      string
+	ttl      time.Duration
+	checksum string
+	// httpClient is used for downloading the file; if nil, a default client is used.
+	httpClient *http.Client
+}
+
+// cachePath returns the path of the cached copy of the remote config file; the name of the file includes a hash
+// of the URL, so config files downloaded from different locations do not override each other.
+func (r *remoteConfig) cachePath(configPath string) string {
+	urlHash := sha256.Sum256([]byte(r.url))
+	name := fmt.Sprintf("%s-%s.yaml", strings.TrimSuffix(DownloadConfigFile, ".yaml"), hex.EncodeToString(urlHash[:])[:12])
+	return filepath.Join(configPath, name)
+}
+
+// get returns the path of a local copy of the remote config file, reusing the cached copy if not expired; if the
+// download fails, e.g. due to a network failure, the cached copy is used whatever its age.
+// Both the downloaded file and the cached copy are checked against the expected checksum, if any; config files
+// downloaded using plain http are accepted only if a checksum is defined, given that they can be tampered with.
+func (r *remoteConfig) get(configPath string) (string, error) {
+	log := logf.Log
+
+	if strings.HasPrefix(r.url, "http://") && r.checksum == "" {
+		return "", errors.Errorf("failed to download the clusterctl config file from %s: a checksum is required for config files downloaded using http, please use https or set the %s variable", r.url, RemoteConfigChecksumVariable)
+	}
+
+	cachedFile := r.cachePath(configPath)
+	cachedErr := r.checkCache(cachedFile)
+	if cachedErr == nil && r.ttl > 0 {
+		if info, err := os.Stat(cachedFile); err == nil && time.Since(info.ModTime()) < r.ttl {
+			log.V(5).Info("Using cached clusterctl config file", "URL", r.url, "File", cachedFile)
+			return cachedFile, nil
+		}
+	}
+
+	content, err := downloadFile(r.httpClient, r.url)
+	if err != nil {
+		if cachedErr == nil {
+			log.Info("Failed to download the clusterctl config file, using the cached copy", "URL", r.url, "File", cachedFile, "Reason", err.Error())
+			return cachedFile, nil
+		}
+		return "", err
+	}
+	if err := verifyConfigChecksum(content, r.checksum); err != nil {
+		return "", errors.Wrapf(err, "failed to verify the clusterctl config file downloaded from %s", r.url)
+	}
+
+	if err := os.WriteFile(cachedFile, content, 0600); err != nil {
+		return "", errors.Wrapf(err, "failed to create the clusterctl config file %s", cachedFile)
+	}
+	return cachedFile, nil
+}
+
+// checkCache checks that the cached copy of the remote config file exists and that it matches the expected checksum.
+func (r *remoteConfig) checkCache(cachedFile string) error {
+	content, err := os.ReadFile(cachedFile)
+	if err != nil {
+		return err
+	}
+	if err := verifyConfigChecksum(content, r.checksum); err != nil {
+		return errors.Wrapf(err, "invalid cached clusterctl config file %s", cachedFile)
+	}
+	return nil
+}
+
+// remoteConfigSettings returns the TTL and the checksum for a remote config file, reading them from the
+// environment unless overridden by the viperReader options.
+func (v *viperReader) remoteConfigSettings() (time.Duration, string, error) {
+	ttl := v.remoteConfigTTL
+	if ttl == nil {
+		if value := viper.GetString(RemoteConfigCacheTTLVariable); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return 0, "", errors.Errorf("invalid value %q for the %s variable: it must be a non negative duration, e.g. 1h", value, RemoteConfigCacheTTLVariable)
+			}
+			ttl = &d
+		}
+	}
+
+	checksum := v.remoteConfigChecksum
+	if checksum == "" {
+		checksum = viper.GetString(RemoteConfigChecksumVariable)
+	}
+
+	if ttl == nil {
+		return 0, checksum, nil
+	}
+	return *ttl, checksum, nil
+}
+
+// verifyConfigChecksum checks the sha256 checksum of a config file, in the sha256:{hex} or in the {hex} form;
+// an empty checksum skips the check.
+func verifyConfigChecksum(content []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	sum := sha256.Sum256(content)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return errors.Errorf("checksum mismatch: expected sha256:%s, got sha256:%s", expected, actual)
+	}
+	return nil
+}
+
+// downloadFile returns the content of a file downloaded from a remote location.
+func downloadFile(client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download the clusterctl config file from %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download the clusterctl config file from %s got %d", url, resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the clusterctl config file downloaded from %s", url)
+	}
+	return content, nil
+}
@@ -18,7 +18,6 @@ package config
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -37,14 +36,25 @@ const (
 	ConfigFolder = ".cluster-api"
 	// ConfigName defines the name of the config file under ConfigFolder.
 	ConfigName = "clusterctl"
-	// DownloadConfigFile is the config file when fetching the config from a remote location.
+	// DownloadConfigFile is the base name of the config file when fetching the config from a remote location; the
+	// downloaded file is cached under the config folder with a name including a hash of the URL.
 	DownloadConfigFile = "clusterctl-download.yaml"
+	// ProfilesConfigKey defines the name of the top level config key for profiles; each profile
+	// is a named section of the clusterctl config file overriding the values in the base section.
+	ProfilesConfigKey = "profiles"
 )
 
 // viperReader implements Reader using viper as backend for reading from environment variables
 // and from a clusterctl config file.
 type viperReader struct {
-	configPaths []string
+	configPaths          []string
+	profile              string
+	remoteConfigTTL      *time.Duration
+	remoteConfigChecksum string
+	extraConfigFiles     []string
+	httpClient           *http.Client
+	// remoteValues hosts the values read from a config file downloaded from a remote location, if any.
+	remoteValues *viper.Viper
 }
 
 type viperReaderOption func(*viperReader)
@@ -55,6 +65,36 @@ func injectConfigPaths(configPaths []string) viperReaderOption {
 	}
 }
 
+func injectProfile(profile string) viperReaderOption {
+	return func(vr *viperReader) {
+		vr.profile = profile
+	}
+}
+
+func injectRemoteConfigTTL(ttl time.Duration) viperReaderOption {
+	return func(vr *viperReader) {
+		vr.remoteConfigTTL = &ttl
+	}
+}
+
+func injectRemoteConfigChecksum(checksum string) viperReaderOption {
+	return func(vr *viperReader) {
+		vr.remoteConfigChecksum = checksum
+	}
+}
+
+func injectExtraConfigFiles(paths []string) viperReaderOption {
+	return func(vr *viperReader) {
+		vr.extraConfigFiles = append(vr.extraConfigFiles, paths...)
+	}
+}
+
+func injectHTTPClient(client *http.Client) viperReaderOption {
+	return func(vr *viperReader) {
+		vr.httpClient = client
+	}
+}
+
 // newViperReader returns a viperReader.
 func newViperReader(opts ...viperReaderOption) Reader {
 	vr := &viperReader{
@@ -79,6 +119,7 @@ func (v *viperReader) Init(path string) error {
 	viper.AllowEmptyEnv(true)
 	viper.AutomaticEnv()
 
+	hasConfig := true
 	if path != "" {
 		url, err := url.Parse(path)
 		if err != nil {
@@ -95,12 +136,22 @@ func (v *viperReader) Init(path string) error {
 				return err
 			}
 
-			downloadConfigFile := filepath.Join(configPath, DownloadConfigFile)
-			err = downloadFile(url.String(), downloadConfigFile)
+			ttl, checksum, err := v.remoteConfigSettings()
+			if err != nil {
+				return err
+			}
+			remote := &remoteConfig{url: url.String(), ttl: ttl, checksum: checksum, httpClient: v.httpClient}
+			downloadConfigFile, err := remote.get(configPath)
 			if err != nil {
 				return err
 			}
 
+			v.remoteValues = viper.New()
+			v.remoteValues.SetConfigFile(downloadConfigFile)
+			if err := v.remoteValues.ReadInConfig(); err != nil {
+				return err
+			}
+
 			viper.SetConfigFile(downloadConfigFile)
 		default:
 			if _, err := os.Stat(path); err != nil {
@@ -109,58 +160,160 @@ func (v *viperReader) Init(path string) error {
 			// Use path file from the flag.
 			viper.SetConfigFile(path)
 		}
-	} else {
-		// Checks if there is a default .cluster-api/clusterctl{.extension} file in home directory
-		if !v.checkDefaultConfig() {
-			// since there is no default config to read from, just skip
-			// reading in config
-			log.V(5).Info("No default config file available")
-			return nil
-		}
+	} else if v.checkDefaultConfig() {
 		// Configure viper for reading .cluster-api/clusterctl{.extension} in home directory
 		viper.SetConfigName(ConfigName)
 		for _, p := range v.configPaths {
 			viper.AddConfigPath(p)
 		}
+	} else {
+		// since there is no default config to read from, just skip
+		// reading in config
+		log.V(5).Info("No default config file available")
+		hasConfig = false
+	}
+
+	if hasConfig {
+		if err := viper.ReadInConfig(); err != nil {
+			return err
+		}
+		log.V(5).Info("Using configuration", "File", viper.ConfigFileUsed())
+	}
+
+	for _, extraPath := range v.extraConfigFiles {
+		if err := v.mergeConfigFile(extraPath); err != nil {
+			return err
+		}
+		log.V(5).Info("Merging configuration", "File", extraPath)
 	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		return err
+	if v.profile != "" {
+		if !hasConfig && len(v.extraConfigFiles) == 0 {
+			return errors.Errorf("failed to use the profile %q: no clusterctl config file available", v.profile)
+		}
+		if err := v.applyProfile(); err != nil {
+			return err
+		}
+		log.V(5).Info("Using configuration profile", "Profile", v.profile)
 	}
-	log.V(5).Info("Using configuration", "File", viper.ConfigFileUsed())
 	return nil
 }
 
-func downloadFile(url string, filepath string) error {
-	// Create the file
-	out, err := os.Create(filepath)
-	if err != nil {
-		return errors.Wrapf(err, "failed to create the clusterctl config file %s", filepath)
+// mergeConfigFile merges the values defined in an additional config file over the ones read so far, with the same
+// semantic used for profiles: maps are merged key by key, providers are merged by name and type, and any other value
+// replaces the value read so far.
+func (v *viperReader) mergeConfigFile(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return errors.Wrap(err, "failed to check if clusterctl config file exists")
 	}
-	defer out.Close()
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	extra := viper.New()
+	extra.SetConfigFile(path)
+	if err := extra.ReadInConfig(); err != nil {
+		return errors.Wrapf(err, "failed to read the clusterctl config file %s", path)
 	}
-	// Get the data
-	resp, err := client.Get(url)
-	if err != nil {
-		return errors.Wrapf(err, "failed to download the clusterctl config file from %s", url)
+
+	settings := extra.AllSettings()
+	if _, ok := settings[ProvidersConfigKey]; ok {
+		base := []map[string]interface{}{}
+		if err := viper.UnmarshalKey(ProvidersConfigKey, &base); err != nil {
+			return errors.Wrap(err, "failed to unmarshal providers from the clusterctl configuration file")
+		}
+		overrides := []map[string]interface{}{}
+		if err := extra.UnmarshalKey(ProvidersConfigKey, &overrides); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal providers from the clusterctl config file %s", path)
+		}
+		settings[ProvidersConfigKey] = mergeProviders(base, overrides)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("failed to download the clusterctl config file from %s got %d", url, resp.StatusCode)
+
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return errors.Wrapf(err, "failed to merge the clusterctl config file %s", path)
+	}
+	return nil
+}
+
+// applyProfile merges the values defined in the selected profile over the base section of the config file.
+// Maps, e.g. cert-manager or images, are merged key by key, providers are merged by name and type, and any
+// other value defined in the profile replaces the value in the base section; environment variables
+// still take precedence over both.
+func (v *viperReader) applyProfile() error {
+	profileKey := fmt.Sprintf("%s.%s", ProfilesConfigKey, v.profile)
+	if !viper.IsSet(profileKey) {
+		return errors.Errorf("failed to use the profile %q: the profile is not defined in the clusterctl config file %s", v.profile, viper.ConfigFileUsed())
 	}
-	defer resp.Body.Close()
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return errors.Wrap(err, "failed to save the data in the clusterctl config")
+	profile := viper.GetStringMap(profileKey)
+	if _, ok := profile[ProvidersConfigKey]; ok {
+		providers, err := mergeProfileProviders(profileKey)
+		if err != nil {
+			return err
+		}
+		profile[ProvidersConfigKey] = providers
 	}
 
+	if err := viper.MergeConfigMap(profile); err != nil {
+		return errors.Wrapf(err, "failed to merge the profile %q", v.profile)
+	}
 	return nil
 }
 
+// mergeProfileProviders returns the providers defined in the base section of the config file, with the
+// providers defined in the profile replacing the ones with the same name and type, or appended to the list.
+func mergeProfileProviders(profileKey string) ([]interface{}, error) {
+	base := []map[string]interface{}{}
+	if err := viper.UnmarshalKey(ProvidersConfigKey, &base); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal providers from the clusterctl configuration file")
+	}
+	overrides := []map[string]interface{}{}
+	if err := viper.UnmarshalKey(fmt.Sprintf("%s.%s", profileKey, ProvidersConfigKey), &overrides); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal providers from the %s section of the clusterctl configuration file", profileKey)
+	}
+	return mergeProviders(base, overrides), nil
+}
+
+// mergeProviders returns the base providers, with the override providers replacing the ones with the same name
+// and type, or appended to the list.
+func mergeProviders(base, overrides []map[string]interface{}) []interface{} {
+	providerKey := func(p map[string]interface{}) string {
+		return fmt.Sprintf("%s/%s", stringField(p, "type"), stringField(p, "name"))
+	}
+
+	merged := make([]interface{}, 0, len(base)+len(overrides))
+	index := map[string]int{}
+	for _, p := range base {
+		index[providerKey(p)] = len(merged)
+		merged = append(merged, p)
+	}
+	for _, p := range overrides {
+		if i, ok := index[providerKey(p)]; ok {
+			merged[i] = p
+			continue
+		}
+		index[providerKey(p)] = len(merged)
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// isRemoteValue returns true if a value is defined in a config file downloaded from a remote location, either in the
+// base section or in the selected profile.
+// NB. Values defined also in a local config file or in the environment are reported as remote if they have the same value.
+func (v *viperReader) isRemoteValue(key, value string) bool {
+	if v.remoteValues == nil {
+		return false
+	}
+	keys := []string{key}
+	if v.profile != "" {
+		keys = append(keys, fmt.Sprintf("%s.%s.%s", ProfilesConfigKey, v.profile, key))
+	}
+	for _, k := range keys {
+		if v.remoteValues.IsSet(k) && v.remoteValues.GetString(k) == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *viperReader) Get(key string) (string, error) {
 	if viper.Get(key) == nil {
 		return "", errors.Errorf("Failed to get value for variable %q. Please set the variable value using os env variables or using the .clusterctl config file", key)
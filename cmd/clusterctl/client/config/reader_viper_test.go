@@ -17,15 +17,20 @@ limitations under the License.
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 )
 
 func Test_viperReader_Init(t *testing.T) {
@@ -48,7 +53,7 @@ func Test_viperReader_Init(t *testing.T) {
 	g.Expect(os.WriteFile(configFileBadContents, []byte("bad-contents"), 0600)).To(Succeed())
 
 	// To test the remote config file
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		_, err := w.Write([]byte("bar: bar"))
 		g.Expect(err).NotTo(HaveOccurred())
@@ -56,7 +61,7 @@ func Test_viperReader_Init(t *testing.T) {
 	defer ts.Close()
 
 	// To test the remote config file when fails to fetch
-	tsFail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	tsFail := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 	defer tsFail.Close()
@@ -65,6 +70,7 @@ func Test_viperReader_Init(t *testing.T) {
 		name       string
 		configPath string
 		configDirs []string
+		httpClient *http.Client
 		expectErr  bool
 	}{
 		{
@@ -95,12 +101,14 @@ func Test_viperReader_Init(t *testing.T) {
 			name:       "reads in config from remote successfully",
 			configPath: ts.URL,
 			configDirs: []string{clusterctlHomeDir},
+			httpClient: ts.Client(),
 			expectErr:  false,
 		},
 		{
 			name:       "fail to read remote config",
 			configPath: tsFail.URL,
 			configDirs: []string{clusterctlHomeDir},
+			httpClient: tsFail.Client(),
 			expectErr:  true,
 		},
 	}
@@ -108,7 +116,7 @@ func Test_viperReader_Init(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gg := NewWithT(t)
-			v := newViperReader(injectConfigPaths(tt.configDirs))
+			v := newViperReader(injectConfigPaths(tt.configDirs), injectHTTPClient(tt.httpClient))
 			if tt.expectErr {
 				gg.Expect(v.Init(tt.configPath)).ToNot(Succeed())
 				return
@@ -118,6 +126,175 @@ func Test_viperReader_Init(t *testing.T) {
 	}
 }
 
+func Test_viperReader_InitRemoteConfig(t *testing.T) {
+	content := []byte("bar: bar")
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	var requests int32
+	var fail int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		opts         []viperReaderOption
+		cached       []byte
+		serverFails  bool
+		wantRequests int32
+		wantErr      bool
+	}{
+		{
+			name:         "downloads the config file every time by default",
+			cached:       content,
+			wantRequests: 1,
+		},
+		{
+			name:         "reuses the cached config file if not expired",
+			opts:         []viperReaderOption{injectRemoteConfigTTL(time.Hour)},
+			cached:       content,
+			wantRequests: 0,
+		},
+		{
+			name:         "falls back to the cached config file if the download fails",
+			cached:       content,
+			serverFails:  true,
+			wantRequests: 1,
+		},
+		{
+			name:         "fails if the download fails and there is no cached config file",
+			serverFails:  true,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "accepts a config file matching the checksum",
+			opts:         []viperReaderOption{injectRemoteConfigChecksum(checksum)},
+			wantRequests: 1,
+		},
+		{
+			name:         "rejects a config file not matching the checksum",
+			opts:         []viperReaderOption{injectRemoteConfigChecksum("sha256:0000")},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "downloads again a cached config file not matching the checksum",
+			opts:         []viperReaderOption{injectRemoteConfigTTL(time.Hour), injectRemoteConfigChecksum(checksum)},
+			cached:       []byte("bar: stale"),
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir, err := os.MkdirTemp("", "clusterctl")
+			g.Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			remote := &remoteConfig{url: ts.URL}
+			if tt.cached != nil {
+				g.Expect(os.WriteFile(remote.cachePath(dir), tt.cached, 0600)).To(Succeed())
+			}
+
+			atomic.StoreInt32(&requests, 0)
+			atomic.StoreInt32(&fail, 0)
+			if tt.serverFails {
+				atomic.StoreInt32(&fail, 1)
+			}
+
+			v := newViperReader(append([]viperReaderOption{injectConfigPaths([]string{dir}), injectHTTPClient(ts.Client())}, tt.opts...)...)
+			err = v.Init(ts.URL)
+			g.Expect(atomic.LoadInt32(&requests)).To(Equal(tt.wantRequests))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := v.Get("bar")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal("bar"))
+		})
+	}
+}
+
+func Test_viperReader_InitRemoteConfig_http(t *testing.T) {
+	content := []byte("bar: bar")
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+
+	t.Run("rejects a config file downloaded using http without a checksum", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{t.TempDir()}))
+		g.Expect(v.Init(ts.URL)).To(MatchError(ContainSubstring("a checksum is required")))
+	})
+
+	t.Run("accepts a config file downloaded using http matching the checksum", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{t.TempDir()}), injectRemoteConfigChecksum(checksum))
+		g.Expect(v.Init(ts.URL)).To(Succeed())
+	})
+}
+
+func Test_variablesClient_Get_RemoteConfigSecretSources(t *testing.T) {
+	g := NewWithT(t)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("REMOTE_EXEC: ${exec:token}\nREMOTE_FILE: ${file:/etc/passwd}\nREMOTE_PLAIN: plain\n"))
+	}))
+	defer ts.Close()
+
+	os.Setenv("CLUSTERCTL_TEST_LOCAL_EXEC", "${exec:token}")
+	defer os.Unsetenv("CLUSTERCTL_TEST_LOCAL_EXEC")
+
+	execCalls := 0
+	dir := t.TempDir()
+	c, err := New(ts.URL,
+		func(c *configClient) {
+			c.readerOptions = append(c.readerOptions, injectConfigPaths([]string{dir}), injectHTTPClient(ts.Client()))
+		},
+		WithFileSecretSource(),
+		WithSecretResolver(ExecSecretSource, SecretResolverFunc(func(ref string) (string, error) {
+			execCalls++
+			return "exec-secret", nil
+		})),
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// References to the exec and file secret sources in the remote config file are never resolved.
+	_, err = c.Variables().Get("REMOTE_EXEC")
+	g.Expect(err).To(MatchError(ContainSubstring("can't be used in a config file downloaded from a remote location")))
+	_, err = c.Variables().Get("REMOTE_FILE")
+	g.Expect(err).To(MatchError(ContainSubstring("can't be used in a config file downloaded from a remote location")))
+	g.Expect(execCalls).To(Equal(0))
+
+	got, err := c.Variables().Get("REMOTE_PLAIN")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("plain"))
+
+	// Values defined locally are resolved.
+	got, err = c.Variables().Get("CLUSTERCTL_TEST_LOCAL_EXEC")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("exec-secret"))
+	g.Expect(execCalls).To(Equal(1))
+}
+
 func Test_viperReader_Get(t *testing.T) {
 	g := NewWithT(t)
 
@@ -200,6 +377,165 @@ func Test_viperReader_GetWithoutDefaultConfig(t *testing.T) {
 	g.Expect(got).To(Equal("bar"))
 }
 
+func Test_viperReader_Profile(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := os.MkdirTemp("", "clusterctl")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	os.Setenv("PROFILE_ENV", "env")
+
+	configFile := filepath.Join(dir, "clusterctl.yaml")
+	g.Expect(os.WriteFile(configFile, []byte(`
+providers:
+  - name: "foo"
+    url: "https://github.com/foo/infrastructure-foo/releases/latest/infrastructure-components.yaml"
+    type: "InfrastructureProvider"
+  - name: "bar"
+    url: "https://github.com/bar/infrastructure-bar/releases/latest/infrastructure-components.yaml"
+    type: "InfrastructureProvider"
+cert-manager:
+  version: "v1.1.1"
+  timeout: "15m"
+PROFILE_BASE: base
+PROFILE_OVERRIDE: base
+PROFILE_ENV: base
+profiles:
+  prod:
+    providers:
+      - name: "foo"
+        url: "https://github.com/prod/infrastructure-foo/releases/latest/infrastructure-components.yaml"
+        type: "InfrastructureProvider"
+      - name: "baz"
+        url: "https://github.com/baz/infrastructure-baz/releases/latest/infrastructure-components.yaml"
+        type: "InfrastructureProvider"
+    cert-manager:
+      version: "v1.5.3"
+    PROFILE_OVERRIDE: prod
+    PROFILE_ENV: prod
+`), 0600)).To(Succeed())
+
+	t.Run("Merges the profile over the base section", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{dir}), injectProfile("prod"))
+		g.Expect(v.Init(configFile)).To(Succeed())
+
+		for key, want := range map[string]string{
+			"PROFILE_BASE":     "base",
+			"PROFILE_OVERRIDE": "prod",
+			"PROFILE_ENV":      "env",
+		} {
+			got, err := v.Get(key)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(want), "unexpected value for %s", key)
+		}
+
+		certManager := &configCertManager{}
+		g.Expect(v.UnmarshalKey(CertManagerConfigKey, certManager)).To(Succeed())
+		g.Expect(certManager.Version).To(Equal("v1.5.3"))
+		g.Expect(certManager.Timeout).To(Equal("15m"))
+
+		providers := []configProvider{}
+		g.Expect(v.UnmarshalKey(ProvidersConfigKey, &providers)).To(Succeed())
+		g.Expect(providers).To(HaveLen(3))
+		g.Expect(providers[0].Name).To(Equal("foo"))
+		g.Expect(providers[0].URL).To(HavePrefix("https://github.com/prod/"))
+		g.Expect(providers[1].Name).To(Equal("bar"))
+		g.Expect(providers[2].Name).To(Equal("baz"))
+	})
+
+	t.Run("Fails for unknown profiles", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{dir}), injectProfile("staging"))
+		g.Expect(v.Init(configFile)).ToNot(Succeed())
+	})
+}
+
+func Test_viperReader_ExtraConfigFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := os.MkdirTemp("", "clusterctl")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "clusterctl.yaml")
+	g.Expect(os.WriteFile(configFile, []byte(`
+providers:
+  - name: "foo"
+    url: "https://github.com/foo/infrastructure-foo/releases/latest/infrastructure-components.yaml"
+    type: "InfrastructureProvider"
+  - name: "bar"
+    url: "https://github.com/bar/infrastructure-bar/releases/latest/infrastructure-components.yaml"
+    type: "InfrastructureProvider"
+cert-manager:
+  version: "v1.1.1"
+  timeout: "15m"
+EXTRA_BASE: base
+EXTRA_OVERRIDE: base
+`), 0600)).To(Succeed())
+
+	teamConfigFile := filepath.Join(dir, "team.yaml")
+	g.Expect(os.WriteFile(teamConfigFile, []byte(`
+providers:
+  - name: "foo"
+    url: "https://github.com/team/infrastructure-foo/releases/latest/infrastructure-components.yaml"
+    type: "InfrastructureProvider"
+  - name: "foo"
+    url: "https://github.com/team/bootstrap-foo/releases/latest/bootstrap-components.yaml"
+    type: "BootstrapProvider"
+cert-manager:
+  version: "v1.5.3"
+EXTRA_OVERRIDE: team
+EXTRA_TEAM: team
+`), 0600)).To(Succeed())
+
+	userConfigFile := filepath.Join(dir, "user.yaml")
+	g.Expect(os.WriteFile(userConfigFile, []byte(`
+EXTRA_OVERRIDE: user
+`), 0600)).To(Succeed())
+
+	t.Run("Merges the config files in order", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{dir}), injectExtraConfigFiles([]string{teamConfigFile, userConfigFile}))
+		g.Expect(v.Init(configFile)).To(Succeed())
+
+		for key, want := range map[string]string{
+			"EXTRA_BASE":     "base",
+			"EXTRA_OVERRIDE": "user",
+			"EXTRA_TEAM":     "team",
+		} {
+			got, err := v.Get(key)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(want), "unexpected value for %s", key)
+		}
+
+		certManager := &configCertManager{}
+		g.Expect(v.UnmarshalKey(CertManagerConfigKey, certManager)).To(Succeed())
+		g.Expect(certManager.Version).To(Equal("v1.5.3"))
+		g.Expect(certManager.Timeout).To(Equal("15m"))
+
+		providers := []configProvider{}
+		g.Expect(v.UnmarshalKey(ProvidersConfigKey, &providers)).To(Succeed())
+		g.Expect(providers).To(HaveLen(3))
+		g.Expect(providers[0].Name).To(Equal("foo"))
+		g.Expect(providers[0].URL).To(HavePrefix("https://github.com/team/infrastructure-foo/"))
+		g.Expect(providers[1].Name).To(Equal("bar"))
+		g.Expect(providers[2].Name).To(Equal("foo"))
+		g.Expect(providers[2].Type).To(Equal(clusterctlv1.BootstrapProviderType))
+	})
+
+	t.Run("Fails for missing config files", func(t *testing.T) {
+		g := NewWithT(t)
+
+		v := newViperReader(injectConfigPaths([]string{dir}), injectExtraConfigFiles([]string{filepath.Join(dir, "missing.yaml")}))
+		g.Expect(v.Init(configFile)).ToNot(Succeed())
+	})
+}
+
 func Test_viperReader_Set(t *testing.T) {
 	g := NewWithT(t)
 
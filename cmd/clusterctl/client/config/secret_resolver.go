@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+const (
+	// FileSecretSource identifies the secret source reading a value from a file, e.g. ${file:/path/to/secret}.
+	// NB. The secret source is disabled by default, see WithFileSecretSource.
+	FileSecretSource = "file"
+
+	// ExecSecretSource identifies the secret source reading a value from the output of a command in an allow-list,
+	// referenced by name, e.g. ${exec:vault-token}.
+	// NB. The secret source is disabled by default, see WithExecSecretSource.
+	ExecSecretSource = "exec"
+)
+
+// secretReferenceRegex matches variable values referencing a secret source, e.g. ${file:/path/to/secret}.
+var secretReferenceRegex = regexp.MustCompile(`^\$\{([a-z][a-z0-9-]*):(.+)\}$`)
+
+// SecretResolver resolves values stored in an external secret source, so sensitive variables, e.g. credentials,
+// are not stored in plaintext in the clusterctl config file or in environment variables.
+// Variables are resolved from a secret source when their value is a reference in the form ${source:ref}.
+type SecretResolver interface {
+	// Resolve returns the secret value for a reference, e.g. the path of a file for the file secret source.
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc allows to use an ordinary function as a SecretResolver.
+type SecretResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// secretResolvers resolves variable values referencing a secret source, caching the resolved values.
+type secretResolvers struct {
+	lock      sync.Mutex
+	resolvers map[string]SecretResolver
+	cache     map[string]string
+}
+
+// newSecretResolvers returns a secretResolvers without any secret source; secret sources must be registered explicitly,
+// given that they allow values in the configuration to read files or to run commands.
+func newSecretResolvers() *secretResolvers {
+	return &secretResolvers{
+		resolvers: map[string]SecretResolver{},
+		cache:     map[string]string{},
+	}
+}
+
+// remoteValuesReader is implemented by the Readers which can read values from a config file downloaded from a remote location.
+type remoteValuesReader interface {
+	isRemoteValue(key, value string) bool
+}
+
+// resolve returns the secret value referenced by a variable value, and true; if the value is not a reference to
+// a secret source, it is returned as is, and false. References to secret sources not registered are rejected, as well
+// as references to the file or the exec secret sources in values read from a remote location, given that whoever
+// controls the remote location should not be allowed to read files or to run commands on the local machine.
+func (s *secretResolvers) resolve(key, value string, remote bool) (string, bool, error) {
+	source, ref, ok := s.parseReference(value)
+	if !ok {
+		return value, false, nil
+	}
+	if remote && (source == FileSecretSource || source == ExecSecretSource) {
+		return "", true, errors.Errorf("failed to resolve the value of variable %q: the %s secret source can't be used in a config file downloaded from a remote location", key, source)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if resolved, ok := s.cache[value]; ok {
+		return resolved, true, nil
+	}
+
+	resolver, ok := s.resolvers[source]
+	if !ok {
+		return "", true, errors.Errorf("failed to resolve the value of variable %q: the %s secret source is not enabled", key, source)
+	}
+
+	// NB. The resolved value is never logged.
+	logf.Log.V(5).Info("Resolving variable from secret source", "Variable", key, "Source", source)
+	resolved, err := resolver.Resolve(ref)
+	if err != nil {
+		return "", true, errors.Wrapf(err, "failed to resolve the value of variable %q from the %s secret source", key, source)
+	}
+	s.cache[value] = resolved
+	return resolved, true, nil
+}
+
+// parseReference returns the secret source and the reference for a variable value referencing a secret source.
+func (s *secretResolvers) parseReference(value string) (string, string, bool) {
+	m := secretReferenceRegex.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+func resolveFileSecret(ref string) (string, error) {
+	content, err := os.ReadFile(ref)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the secret file")
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// newExecSecretResolver returns a SecretResolver running the commands in an allow-list, indexed by name; references
+// are names in the allow-list, so the configuration can't define the command to be run nor its arguments.
+func newExecSecretResolver(commands map[string][]string) SecretResolver {
+	allowed := make(map[string][]string, len(commands))
+	for name, args := range commands {
+		allowed[name] = append([]string{}, args...)
+	}
+	return SecretResolverFunc(func(ref string) (string, error) {
+		args, ok := allowed[ref]
+		if !ok {
+			return "", errors.Errorf("the command %q is not in the allow-list of the exec secret source", ref)
+		}
+		return runExecSecret(args)
+	})
+}
+
+func runExecSecret(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("the command is empty")
+	}
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec // The command is in the allow-list defined by the program using clusterctl.
+	cmd.Stderr = os.Stderr
+
+	// NB. The command output is not included in the error, because it might contain the secret value.
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run the command %q", args[0])
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
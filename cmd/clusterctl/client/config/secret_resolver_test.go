@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_variablesClient_Get_SecretSource(t *testing.T) {
+	g := NewWithT(t)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	g.Expect(os.WriteFile(secretFile, []byte("file-secret\n"), 0600)).To(Succeed())
+
+	vaultCalls := 0
+	vault := SecretResolverFunc(func(ref string) (string, error) {
+		vaultCalls++
+		if ref == "secret/foo" {
+			return "vault-secret", nil
+		}
+		return "", errors.Errorf("secret %s not found", ref)
+	})
+
+	tests := []struct {
+		name       string
+		value      string
+		want       string
+		wantSecret bool
+		wantErr    bool
+	}{
+		{
+			name:  "returns plain values as is",
+			value: "plain",
+			want:  "plain",
+		},
+		{
+			name:       "rejects values referencing secret sources not registered",
+			value:      "${unknown:foo}",
+			wantSecret: true,
+			wantErr:    true,
+		},
+		{
+			name:       "resolves values from the file source",
+			value:      "${file:" + secretFile + "}",
+			want:       "file-secret",
+			wantSecret: true,
+		},
+		{
+			name:       "fails for missing files",
+			value:      "${file:" + secretFile + "-missing}",
+			wantSecret: true,
+			wantErr:    true,
+		},
+		{
+			name:       "resolves values from a custom source",
+			value:      "${vault:secret/foo}",
+			want:       "vault-secret",
+			wantSecret: true,
+		},
+		{
+			name:       "fails if the custom source fails",
+			value:      "${vault:secret/bar}",
+			wantSecret: true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c, err := New("", InjectReader(test.NewFakeReader().WithVar("FOO", tt.value)), WithFileSecretSource(), WithSecretResolver("vault", vault))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(c.Variables().IsSecret("FOO")).To(Equal(tt.wantSecret))
+
+			got, err := c.Variables().Get("FOO")
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+
+	t.Run("caches resolved values", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c, err := New("", InjectReader(test.NewFakeReader().WithVar("FOO", "${vault:secret/foo}")), WithSecretResolver("vault", vault))
+		g.Expect(err).NotTo(HaveOccurred())
+
+		vaultCalls = 0
+		for i := 0; i < 3; i++ {
+			got, err := c.Variables().Get("FOO")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal("vault-secret"))
+		}
+		g.Expect(vaultCalls).To(Equal(1))
+	})
+}
+
+func Test_variablesClient_Get_SecretSourcesDisabledByDefault(t *testing.T) {
+	g := NewWithT(t)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	g.Expect(os.WriteFile(secretFile, []byte("file-secret\n"), 0600)).To(Succeed())
+
+	c, err := New("", InjectReader(test.NewFakeReader().
+		WithVar("FILE_SECRET", "${file:"+secretFile+"}").
+		WithVar("EXEC_SECRET", "${exec:echo exec-secret}")))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = c.Variables().Get("FILE_SECRET")
+	g.Expect(err).To(MatchError(ContainSubstring("the file secret source is not enabled")))
+	_, err = c.Variables().Get("EXEC_SECRET")
+	g.Expect(err).To(MatchError(ContainSubstring("the exec secret source is not enabled")))
+}
+
+func Test_variablesClient_Get_ExecSecretSource(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo is not available")
+	}
+	g := NewWithT(t)
+
+	c, err := New("", InjectReader(test.NewFakeReader().
+		WithVar("ALLOWED", "${exec:token}").
+		WithVar("NOT_ALLOWED", "${exec:echo exec-secret}").
+		WithVar("EMPTY", "${exec:empty}")),
+		WithExecSecretSource(map[string][]string{"token": {"echo", "exec-secret"}, "empty": {}}))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	got, err := c.Variables().Get("ALLOWED")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal("exec-secret"))
+
+	// Only the names in the allow-list can be referenced, the command is never read from the configuration.
+	_, err = c.Variables().Get("NOT_ALLOWED")
+	g.Expect(err).To(MatchError(ContainSubstring("is not in the allow-list")))
+
+	_, err = c.Variables().Get("EMPTY")
+	g.Expect(err).To(HaveOccurred())
+}
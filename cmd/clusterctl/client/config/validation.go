@@ -0,0 +1,220 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+)
+
+// ValidationSeverity defines the severity of a problem found when validating the clusterctl configuration.
+type ValidationSeverity string
+
+const (
+	// ErrorValidationSeverity is used for problems preventing clusterctl from using the configuration.
+	ErrorValidationSeverity = ValidationSeverity("Error")
+
+	// WarningValidationSeverity is used for problems likely to be mistakes, e.g. unknown or duplicated keys,
+	// that do not prevent clusterctl from using the configuration.
+	WarningValidationSeverity = ValidationSeverity("Warning")
+)
+
+// ValidationFinding is a problem found when validating the clusterctl configuration.
+type ValidationFinding struct {
+	// Severity of the problem.
+	Severity ValidationSeverity
+
+	// Key is the configuration key the problem refers to, e.g. providers[0].url.
+	Key string
+
+	// Message describes the problem.
+	Message string
+}
+
+// newValidationError returns a ValidationFinding with the Error severity.
+func newValidationError(key, format string, args ...interface{}) ValidationFinding {
+	return ValidationFinding{Severity: ErrorValidationSeverity, Key: key, Message: fmt.Sprintf(format, args...)}
+}
+
+// newValidationWarning returns a ValidationFinding with the Warning severity.
+func newValidationWarning(key, format string, args ...interface{}) ValidationFinding {
+	return ValidationFinding{Severity: WarningValidationSeverity, Key: key, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *configClient) Validate() []ValidationFinding {
+	findings := []ValidationFinding{}
+	findings = append(findings, c.validateProviders()...)
+	findings = append(findings, c.validateProviderAliases()...)
+	findings = append(findings, c.validateCertManager()...)
+	findings = append(findings, c.validateImages()...)
+	return findings
+}
+
+// validateProviders checks the user-defined provider configurations for invalid values, unknown fields and duplicates.
+func (c *configClient) validateProviders() []ValidationFinding {
+	findings := []ValidationFinding{}
+
+	userDefinedProviders := []map[string]interface{}{}
+	if err := c.reader.UnmarshalKey(ProvidersConfigKey, &userDefinedProviders); err != nil {
+		return append(findings, newValidationError(ProvidersConfigKey, "failed to unmarshal providers: %v", err))
+	}
+
+	seen := map[string]int{}
+	for i, u := range userDefinedProviders {
+		key := fmt.Sprintf("%s[%d]", ProvidersConfigKey, i)
+		findings = append(findings, unknownFields(key, u, "name", "url", "type")...)
+
+		provider := NewProvider(stringField(u, "name"), stringField(u, "url"), clusterctlv1.ProviderType(stringField(u, "type")))
+		if err := validateProvider(provider); err != nil {
+			findings = append(findings, newValidationError(key, "invalid provider: %v", err))
+			continue
+		}
+
+		if j, ok := seen[provider.ManifestLabel()]; ok {
+			findings = append(findings, newValidationWarning(key, "duplicated configuration for the %s with name %s, already defined in %s[%d]; the last configuration takes precedence", provider.Type(), provider.Name(), ProvidersConfigKey, j))
+		}
+		seen[provider.ManifestLabel()] = i
+	}
+	return findings
+}
+
+// validateProviderAliases checks that every provider alias resolves to a provider.
+func (c *configClient) validateProviderAliases() []ValidationFinding {
+	findings := []ValidationFinding{}
+
+	aliases := map[string]string{}
+	if err := c.reader.UnmarshalKey(ProviderAliasesConfigKey, &aliases); err != nil {
+		return append(findings, newValidationError(ProviderAliasesConfigKey, "failed to unmarshal provider aliases: %v", err))
+	}
+
+	providers, err := c.Providers().List()
+	if err != nil {
+		// NB. Errors in the provider configurations are already reported by validateProviders.
+		return findings
+	}
+
+	for _, alias := range sortedKeys(aliases) {
+		key := fmt.Sprintf("%s.%s", ProviderAliasesConfigKey, alias)
+
+		shadowed := false
+		for _, p := range providers {
+			if p.Name() == alias {
+				shadowed = true
+				break
+			}
+		}
+		if shadowed {
+			findings = append(findings, newValidationWarning(key, "the alias %s is also the name of a provider, so it is used only for provider types without a provider with this name", alias))
+		}
+
+		if _, err := c.Providers().Get(alias, clusterctlv1.ProviderTypeUnknown); err != nil {
+			findings = append(findings, newValidationError(key, "invalid alias: %v", err))
+		}
+	}
+	return findings
+}
+
+// validateCertManager checks the cert-manager configuration for invalid values and unknown fields.
+func (c *configClient) validateCertManager() []ValidationFinding {
+	findings := []ValidationFinding{}
+
+	certManager := map[string]interface{}{}
+	if err := c.reader.UnmarshalKey(CertManagerConfigKey, &certManager); err != nil {
+		return append(findings, newValidationError(CertManagerConfigKey, "failed to unmarshal the cert-manager configuration: %v", err))
+	}
+	findings = append(findings, unknownFields(CertManagerConfigKey, certManager, "url", "version", "timeout")...)
+
+	certManagerConfig, err := c.CertManager().Get()
+	if err != nil {
+		return append(findings, newValidationError(CertManagerConfigKey, "invalid cert-manager configuration: %v", err))
+	}
+	if _, err := version.ParseSemantic(certManagerConfig.Version()); err != nil {
+		findings = append(findings, newValidationError(CertManagerConfigKey+".version", "invalid cert-manager version %q: %v", certManagerConfig.Version(), err))
+	}
+	if timeout := stringField(certManager, "timeout"); timeout != "" {
+		if _, err := time.ParseDuration(timeout); err != nil {
+			findings = append(findings, newValidationWarning(CertManagerConfigKey+".timeout", "invalid cert-manager timeout %q, the default value %s is used instead", timeout, CertManagerDefaultTimeout))
+		}
+	}
+	return findings
+}
+
+// validateImages checks the image overrides for unknown fields.
+func (c *configClient) validateImages() []ValidationFinding {
+	findings := []ValidationFinding{}
+
+	images := map[string]map[string]interface{}{}
+	if err := c.reader.UnmarshalKey(imagesConfigKey, &images); err != nil {
+		return append(findings, newValidationError(imagesConfigKey, "failed to unmarshal image overrides: %v", err))
+	}
+
+	components := make([]string, 0, len(images))
+	for component := range images {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		findings = append(findings, unknownFields(fmt.Sprintf("%s.%s", imagesConfigKey, component), images[component], "repository", "tag", "registry")...)
+	}
+	return findings
+}
+
+// unknownFields returns a warning for each field of a configuration object not included in the list of known fields.
+func unknownFields(key string, obj map[string]interface{}, knownFields ...string) []ValidationFinding {
+	known := map[string]bool{}
+	for _, f := range knownFields {
+		known[f] = true
+	}
+
+	findings := []ValidationFinding{}
+	for _, f := range sortedKeys(obj) {
+		if !known[f] {
+			findings = append(findings, newValidationWarning(fmt.Sprintf("%s.%s", key, f), "unknown field %q; valid fields are %q", f, knownFields))
+		}
+	}
+	return findings
+}
+
+// stringField returns the value of a field of a configuration object, or an empty string if the field is not a string.
+func stringField(obj map[string]interface{}, field string) string {
+	if v, ok := obj[field].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_configClient_Validate(t *testing.T) {
+	type finding struct {
+		severity ValidationSeverity
+		key      string
+	}
+	tests := []struct {
+		name   string
+		reader Reader
+		want   []finding
+	}{
+		{
+			name:   "no findings for the default configuration",
+			reader: test.NewFakeReader(),
+			want:   []finding{},
+		},
+		{
+			name: "no findings for a valid configuration",
+			reader: test.NewFakeReader().
+				WithProvider("foo", clusterctlv1.InfrastructureProviderType, "https://github.com/foo/infrastructure-foo/releases/latest/infrastructure-components.yaml").
+				WithProviderAlias("f", "foo").
+				WithCertManager("", "v1.1.1", "15m").
+				WithImageMeta("all", "myorg.io/local-repo", ""),
+			want: []finding{},
+		},
+		{
+			name: "error for invalid provider",
+			reader: test.NewFakeReader().
+				WithProvider("foo", clusterctlv1.InfrastructureProviderType, ""),
+			want: []finding{
+				{severity: ErrorValidationSeverity, key: "providers[0]"},
+			},
+		},
+		{
+			name: "warning for duplicated provider",
+			reader: test.NewFakeReader().
+				WithProvider("foo", clusterctlv1.InfrastructureProviderType, "https://github.com/foo/infrastructure-foo/releases/latest/infrastructure-components.yaml").
+				WithProvider("foo", clusterctlv1.InfrastructureProviderType, "https://github.com/bar/infrastructure-foo/releases/latest/infrastructure-components.yaml"),
+			want: []finding{
+				{severity: WarningValidationSeverity, key: "providers[1]"},
+			},
+		},
+		{
+			name: "warning for unknown provider field",
+			reader: test.NewFakeReader().
+				WithVar(ProvidersConfigKey, "- name: foo\n  url: https://github.com/foo/infrastructure-foo/releases/latest/infrastructure-components.yaml\n  type: InfrastructureProvider\n  version: v1.0.0\n"),
+			want: []finding{
+				{severity: WarningValidationSeverity, key: "providers[0].version"},
+			},
+		},
+		{
+			name: "error for alias not matching any provider",
+			reader: test.NewFakeReader().
+				WithProviderAlias("capx", "infrastructure-x"),
+			want: []finding{
+				{severity: ErrorValidationSeverity, key: "provider-aliases.capx"},
+			},
+		},
+		{
+			name: "warning for alias shadowed by a provider name",
+			reader: test.NewFakeReader().
+				WithProviderAlias("aws", "infrastructure-azure"),
+			want: []finding{
+				{severity: WarningValidationSeverity, key: "provider-aliases.aws"},
+			},
+		},
+		{
+			name: "error for invalid cert-manager configuration",
+			reader: test.NewFakeReader().
+				WithCertManager("", "foo", "bar"),
+			want: []finding{
+				{severity: ErrorValidationSeverity, key: "cert-manager.version"},
+				{severity: WarningValidationSeverity, key: "cert-manager.timeout"},
+			},
+		},
+		{
+			name: "warning for unknown cert-manager and image fields",
+			reader: test.NewFakeReader().
+				WithVar(CertManagerConfigKey, "versions: v1.1.1\n").
+				WithVar(imagesConfigKey, "all:\n  repo: myorg.io/local-repo\n"),
+			want: []finding{
+				{severity: WarningValidationSeverity, key: "cert-manager.versions"},
+				{severity: WarningValidationSeverity, key: "images.all.repo"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c, err := New("", InjectReader(tt.reader))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got := []finding{}
+			for _, f := range c.Validate() {
+				g.Expect(f.Message).NotTo(BeEmpty())
+				got = append(got, finding{severity: f.Severity, key: f.Key})
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
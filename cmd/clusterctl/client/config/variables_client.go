@@ -19,6 +19,54 @@ package config
 const (
 	// GitHubTokenVariable defines a variable hosting the GitHub access token.
 	GitHubTokenVariable = "github-token"
+
+	// GitHubEnterpriseHostsVariable defines a variable hosting a comma separated list of GitHub Enterprise hosts;
+	// provider URLs pointing to these hosts are handled as GitHub repositories.
+	GitHubEnterpriseHostsVariable = "github-enterprise-hosts"
+
+	// GitLabTokenVariable defines a variable hosting the GitLab personal, project or group access token.
+	GitLabTokenVariable = "gitlab-token"
+
+	// GitLabHostsVariable defines a variable hosting a comma separated list of self-managed GitLab hosts;
+	// provider URLs pointing to these hosts are handled as GitLab repositories.
+	GitLabHostsVariable = "gitlab-hosts"
+
+	// OCIUsernameVariable defines a variable hosting the username for pulling providers from an OCI registry.
+	OCIUsernameVariable = "oci-username"
+
+	// OCIPasswordVariable defines a variable hosting the password or the access token for pulling providers from an OCI registry.
+	OCIPasswordVariable = "oci-password"
+
+	// RepositoryRetriesVariable defines a variable hosting the number of times a failed download from a provider repository is retried.
+	RepositoryRetriesVariable = "repository-retries"
+
+	// RepositoryRetryTimeoutVariable defines a variable hosting the maximum time spent retrying a failed download from a
+	// provider repository, e.g. 30s.
+	RepositoryRetryTimeoutVariable = "repository-retry-timeout"
+
+	// RepositoryCacheDirVariable defines a variable hosting the folder where the files downloaded from provider repositories are cached.
+	RepositoryCacheDirVariable = "repository-cache-dir"
+
+	// RepositoryCacheTTLVariable defines a variable hosting for how long the files downloaded from provider repositories are reused, e.g. 24h.
+	RepositoryCacheTTLVariable = "repository-cache-ttl"
+
+	// RepositoryCacheDisabledVariable defines a variable that, if set to true, disables caching the files downloaded from provider repositories.
+	RepositoryCacheDisabledVariable = "repository-cache-disabled"
+
+	// RepositoryVerifyChecksumsVariable defines a variable that, if set to true, requires all the files downloaded from provider
+	// repositories to have a published checksum; files not matching the published checksum are always rejected.
+	RepositoryVerifyChecksumsVariable = "repository-verify-checksums"
+
+	// RepositoryIncludePrereleasesVariable defines a variable that, if set to true, makes prerelease provider versions,
+	// e.g. release candidates, eligible when resolving the latest version, a version range or the upgrade targets.
+	RepositoryIncludePrereleasesVariable = "repository-include-prereleases"
+
+	// RepositoryProxyVariable defines a variable hosting the URL of the HTTP proxy to be used for accessing provider repositories.
+	RepositoryProxyVariable = "repository-proxy"
+
+	// RepositoryCABundleVariable defines a variable hosting the path of a PEM file with additional CA certificates to be trusted
+	// when accessing provider repositories, e.g. the CA of a corporate proxy.
+	RepositoryCABundleVariable = "repository-ca-bundle"
 )
 
 // VariablesClient has methods to work with environment variables and with variables defined in the clusterctl configuration file.
@@ -26,8 +74,14 @@ type VariablesClient interface {
 	// Get returns a variable value. If the variable is not defined an error is returned.
 	// In case the same variable is defined both within the environment variables and clusterctl configuration file,
 	// the environment variables value takes precedence.
+	// Values in the form ${source:ref} are resolved from the corresponding secret source, e.g. ${file:/path/to/secret}.
+	// Variables not defined in the environment or in the clusterctl configuration file are read from the identity
+	// sources, if any provides them, e.g. the token files defined in the clusterctl configuration file.
 	Get(key string) (string, error)
 
+	// IsSecret returns true if the variable value is resolved from a secret source or read from an identity source.
+	IsSecret(key string) bool
+
 	// Set allows to set an explicit override for a config value.
 	// e.g. It is used to set an override from a flag value over environment/config file variables.
 	Set(key, values string)
@@ -35,20 +89,52 @@ type VariablesClient interface {
 
 // variablesClient implements VariablesClient.
 type variablesClient struct {
-	reader Reader
+	reader             Reader
+	secrets            *secretResolvers
+	identities         []IdentitySource
+	identityTokenFiles bool
 }
 
 // ensure variablesClient implements VariablesClient.
 var _ VariablesClient = &variablesClient{}
 
-func newVariablesClient(reader Reader) *variablesClient {
+func newVariablesClient(reader Reader, secrets *secretResolvers, identities []IdentitySource, identityTokenFiles bool) *variablesClient {
 	return &variablesClient{
-		reader: reader,
+		reader:             reader,
+		secrets:            secrets,
+		identities:         identities,
+		identityTokenFiles: identityTokenFiles,
 	}
 }
 
 func (p *variablesClient) Get(key string) (string, error) {
-	return p.reader.Get(key)
+	value, err := p.reader.Get(key)
+	if err != nil {
+		credential, ok, identityErr := identityCredential(p.reader, p.identityTokenFiles, p.identities, key)
+		if identityErr != nil {
+			return "", identityErr
+		}
+		if ok {
+			return credential, nil
+		}
+		return "", err
+	}
+	remote := false
+	if r, ok := p.reader.(remoteValuesReader); ok {
+		remote = r.isRemoteValue(key, value)
+	}
+	value, _, err = p.secrets.resolve(key, value, remote)
+	return value, err
+}
+
+func (p *variablesClient) IsSecret(key string) bool {
+	value, err := p.reader.Get(key)
+	if err != nil {
+		_, ok, _ := identityCredential(p.reader, p.identityTokenFiles, p.identities, key)
+		return ok
+	}
+	_, _, ok := p.secrets.parseReference(value)
+	return ok
 }
 
 func (p *variablesClient) Set(key, value string) {
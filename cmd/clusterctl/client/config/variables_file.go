@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ReadVariablesFile reads variables from a file. Files with the .yaml, .yml or .json extension are read as
+// a map of variable names to scalar values, while all the other files are read using the dotenv format,
+// with one KEY=VALUE entry per line; empty lines and lines starting with # are ignored.
+// An error is returned if the same variable is defined more than once in the file.
+func ReadVariablesFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read variables file %q", path)
+	}
+
+	var variables map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		variables, err = parseYAMLVariables(content)
+	default:
+		variables, err = parseDotenvVariables(content)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse variables file %q", path)
+	}
+	return variables, nil
+}
+
+// parseYAMLVariables parses a YAML map of variable names to scalar values.
+func parseYAMLVariables(content []byte) (map[string]string, error) {
+	// NOTE: YAMLToJSONStrict fails if the same key is defined more than once.
+	jsonContent, err := yaml.YAMLToJSONStrict(content)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, errors.New("the file must contain a map of variable names to values")
+	}
+
+	variables := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch value := v.(type) {
+		case string:
+			variables[k] = value
+		case json.Number, bool:
+			variables[k] = fmt.Sprint(value)
+		case nil:
+			variables[k] = ""
+		default:
+			return nil, errors.Errorf("the value of variable %q must be a scalar", k)
+		}
+	}
+	return variables, nil
+}
+
+// parseDotenvVariables parses a dotenv file, e.g.
+//
+//	# comment
+//	export KEY1=value
+//	KEY2="quoted value"
+func parseDotenvVariables(content []byte) (map[string]string, error) {
+	variables := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "export ")
+
+		i := strings.Index(entry, "=")
+		if i < 1 {
+			return nil, errors.Errorf("invalid entry at line %d: expected KEY=VALUE", line)
+		}
+		key := strings.TrimSpace(entry[:i])
+		value := strings.TrimSpace(entry[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		if _, ok := variables[key]; ok {
+			return nil, errors.Errorf("variable %q is defined more than once (line %d)", key, line)
+		}
+		variables[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
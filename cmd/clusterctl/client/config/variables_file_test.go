@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReadVariablesFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "dotenv file",
+			fileName: "vars.env",
+			content: "# comment\n" +
+				"\n" +
+				"VAR1=value1\n" +
+				"export VAR2 = value2\n" +
+				"VAR3=\"quoted = value\"\n" +
+				"VAR4=\n",
+			want: map[string]string{
+				"VAR1": "value1",
+				"VAR2": "value2",
+				"VAR3": "quoted = value",
+				"VAR4": "",
+			},
+		},
+		{
+			name:     "dotenv file with duplicated keys",
+			fileName: "vars.env",
+			content:  "VAR1=value1\nVAR1=value2\n",
+			wantErr:  true,
+		},
+		{
+			name:     "dotenv file with invalid entries",
+			fileName: "vars.env",
+			content:  "VAR1\n",
+			wantErr:  true,
+		},
+		{
+			name:     "yaml file",
+			fileName: "vars.yaml",
+			content: "VAR1: value1\n" +
+				"VAR2: 3\n" +
+				"VAR3: true\n" +
+				"VAR4: 12345678901234567890\n",
+			want: map[string]string{
+				"VAR1": "value1",
+				"VAR2": "3",
+				"VAR3": "true",
+				"VAR4": "12345678901234567890",
+			},
+		},
+		{
+			name:     "yaml file with duplicated keys",
+			fileName: "vars.yaml",
+			content:  "VAR1: value1\nVAR1: value2\n",
+			wantErr:  true,
+		},
+		{
+			name:     "yaml file with non scalar values",
+			fileName: "vars.yml",
+			content:  "VAR1:\n  foo: bar\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir, err := os.MkdirTemp("", "clusterctl")
+			g.Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, tt.fileName)
+			g.Expect(os.WriteFile(path, []byte(tt.content), 0600)).To(Succeed())
+
+			got, err := ReadVariablesFile(path)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
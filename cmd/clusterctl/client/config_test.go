@@ -17,6 +17,7 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -129,9 +130,117 @@ func Test_clusterctlClient_GetProvidersConfig(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_GetProvidersConfigByType(t *testing.T) {
+	customProviderConfig := config.NewProvider("custom", "url", clusterctlv1.BootstrapProviderType)
+
+	tests := []struct {
+		name          string
+		providerType  clusterctlv1.ProviderType
+		wantProviders []string
+	}{
+		{
+			name:         "Returns bootstrap providers, including custom providers",
+			providerType: clusterctlv1.BootstrapProviderType,
+			wantProviders: []string{
+				config.AWSEKSBootstrapProviderName,
+				customProviderConfig.Name(),
+				config.KubeadmBootstrapProviderName,
+				config.TalosBootstrapProviderName,
+			},
+		},
+		{
+			name:         "Returns the core provider",
+			providerType: clusterctlv1.CoreProviderType,
+			wantProviders: []string{
+				config.ClusterAPIProviderName,
+			},
+		},
+		{
+			name:          "Returns no providers for unknown types",
+			providerType:  clusterctlv1.ProviderTypeUnknown,
+			wantProviders: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := newFakeClient(newFakeConfig().WithProvider(customProviderConfig))
+
+			got, err := client.GetProvidersConfigByType(tt.providerType)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotProviders := []string{}
+			for _, p := range got {
+				g.Expect(p.Type()).To(Equal(tt.providerType))
+				gotProviders = append(gotProviders, p.Name())
+			}
+			g.Expect(gotProviders).To(Equal(tt.wantProviders))
+		})
+	}
+}
+
+func Test_clusterctlClient_ValidateConfig(t *testing.T) {
+	reachableProvider := config.NewProvider("reachable", "url", clusterctlv1.InfrastructureProviderType)
+	emptyProvider := config.NewProvider("empty", "url", clusterctlv1.InfrastructureProviderType)
+	unreachableProvider := config.NewProvider("unreachable", "url", clusterctlv1.InfrastructureProviderType)
+
+	tests := []struct {
+		name    string
+		options ValidateConfigOptions
+		// wantFindings are the severity of the findings expected for each of the keys, if any.
+		wantFindings map[string]config.ValidationSeverity
+	}{
+		{
+			name: "Checks the provider repositories",
+			wantFindings: map[string]config.ValidationSeverity{
+				"providers.infrastructure-reachable":   "",
+				"providers.infrastructure-empty":       config.WarningValidationSeverity,
+				"providers.infrastructure-unreachable": config.ErrorValidationSeverity,
+				"overridesFolder":                      "",
+			},
+		},
+		{
+			name:    "Skips the provider repositories checks",
+			options: ValidateConfigOptions{SkipRepositoryChecks: true},
+			wantFindings: map[string]config.ValidationSeverity{
+				"providers.infrastructure-reachable":   "",
+				"providers.infrastructure-empty":       "",
+				"providers.infrastructure-unreachable": "",
+				"overridesFolder":                      "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cfg := newFakeConfig().
+				WithVar("overridesFolder", t.TempDir()).
+				WithProvider(reachableProvider).
+				WithProvider(emptyProvider).
+				WithProvider(unreachableProvider)
+			client := newFakeClient(cfg).
+				WithRepository(newFakeRepository(reachableProvider, cfg).WithVersions("v1.0.0")).
+				WithRepository(newFakeRepository(emptyProvider, cfg))
+
+			got, err := client.ValidateConfig(tt.options)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotFindings := map[string]config.ValidationSeverity{}
+			for _, f := range got {
+				gotFindings[f.Key] = f.Severity
+			}
+			for key, severity := range tt.wantFindings {
+				g.Expect(gotFindings[key]).To(Equal(severity), "unexpected finding for %s", key)
+			}
+		})
+	}
+}
 func Test_clusterctlClient_GetProviderComponents(t *testing.T) {
 	config1 := newFakeConfig().
-		WithProvider(capiProviderConfig)
+		WithProvider(capiProviderConfig).
+		WithProviderAlias("capi", capiProviderConfig.ManifestLabel())
 
 	repository1 := newFakeRepository(capiProviderConfig, config1).
 		WithPaths("root", "components.yaml").
@@ -167,6 +276,18 @@ func Test_clusterctlClient_GetProviderComponents(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Pass with an alias",
+			args: args{
+				provider:        "capi:v1.0.0",
+				targetNameSpace: "ns2",
+			},
+			want: want{
+				provider: capiProviderConfig,
+				version:  "v1.0.0",
+			},
+			wantErr: false,
+		},
 		{
 			name: "Fail",
 			args: args{
@@ -196,6 +317,121 @@ func Test_clusterctlClient_GetProviderComponents(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_GetProviderVersions(t *testing.T) {
+	metadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+			{Major: 1, Minor: 1, Contract: test.CurrentCAPIContract},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		includePrereleases string
+		want               []ProviderVersion
+	}{
+		{
+			name: "returns the published versions",
+			want: []ProviderVersion{
+				{Version: "v1.0.0", Contract: test.CurrentCAPIContract},
+			},
+		},
+		{
+			name:               "returns the prerelease versions if enabled in the configuration",
+			includePrereleases: "true",
+			want: []ProviderVersion{
+				{Version: "v1.0.0", Contract: test.CurrentCAPIContract},
+				{Version: "v1.1.0-rc.0", Contract: test.CurrentCAPIContract},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig().
+				WithProvider(capiProviderConfig)
+			if tt.includePrereleases != "" {
+				config1.WithVar(config.RepositoryIncludePrereleasesVariable, tt.includePrereleases)
+			}
+
+			repository1 := newFakeRepository(capiProviderConfig, config1).
+				WithPaths("root", "components.yaml").
+				WithDefaultVersion("v1.0.0").
+				WithMetadata("v1.0.0", metadata).
+				WithMetadata("v1.1.0-rc.0", metadata)
+
+			client := newFakeClient(config1).
+				WithRepository(repository1)
+
+			got, err := client.GetProviderVersions(capiProviderConfig.Name(), capiProviderConfig.Type())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+
+			_, err = client.GetProviderVersions("does-not-exist", capiProviderConfig.Type())
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
+
+func Test_clusterctlClient_GetProviderVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	components := []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  namespace: ns1
+stringData:
+  config: ${CONFIG_VAR}
+  default: ${DEFAULT_VAR:=default}
+  password: ${CLOUD_PASSWORD}
+  unset: ${UNSET_VAR}`)
+
+	config1 := newFakeConfig().
+		WithProvider(capiProviderConfig).
+		WithVar("CONFIG_VAR", "from-config").
+		WithVar("CLOUD_PASSWORD", "secret")
+
+	repository1 := newFakeRepository(capiProviderConfig, config1).
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion("v1.0.0").
+		WithFile("v1.0.0", "components.yaml", components)
+
+	client := newFakeClient(config1).
+		WithRepository(repository1)
+
+	got, err := client.GetProviderVariables(capiProviderConfig.Name(), capiProviderConfig.Type(), ComponentsOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]ProviderVariable{
+		{
+			ResolvedVariable: ResolvedVariable{Name: "CLOUD_PASSWORD", IsSet: true, Value: "******", Masked: true, Source: VariableSourceConfigFile},
+			Required:         true,
+		},
+		{
+			ResolvedVariable: ResolvedVariable{Name: "CONFIG_VAR", IsSet: true, Value: "from-config", Source: VariableSourceConfigFile},
+			Required:         true,
+		},
+		{
+			ResolvedVariable: ResolvedVariable{Name: "DEFAULT_VAR", IsSet: true, Value: "default", Source: VariableSourceDefault},
+			Required:         false,
+			Default:          pointer.StringPtr("default"),
+		},
+		{
+			ResolvedVariable: ResolvedVariable{Name: "UNSET_VAR", IsSet: false},
+			Required:         true,
+		},
+	}))
+
+	_, err = client.GetProviderVariables(fmt.Sprintf("%s:v0.2.0", capiProviderConfig.Name()), capiProviderConfig.Type(), ComponentsOptions{})
+	g.Expect(err).To(HaveOccurred())
+}
+
 func Test_getComponentsByName_withEmptyVariables(t *testing.T) {
 	g := NewWithT(t)
 
@@ -465,7 +701,9 @@ func Test_clusterctlClient_GetClusterTemplate(t *testing.T) {
 	repository1 := newFakeRepository(infraProviderConfig, config1).
 		WithPaths("root", "components").
 		WithDefaultVersion("v3.0.0").
-		WithFile("v3.0.0", "cluster-template.yaml", rawTemplate)
+		WithFile("v3.0.0", "cluster-template.yaml", rawTemplate).
+		WithFile("v3.0.0", "cluster-template-addon.yaml", addonTemplateYAML("ns3", "${ CLUSTER_NAME }")).
+		WithFile("v3.0.0", "cluster-template-conflict.yaml", append(templateYAML("ns3", "${ CLUSTER_NAME }"), []byte("\n  labels:\n    foo: bar")...))
 
 	cluster1 := newFakeCluster(cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}, config1).
 		WithProviderInventory(infraProviderConfig.Name(), infraProviderConfig.Type(), "v3.0.0", "foo").
@@ -512,6 +750,59 @@ func Test_clusterctlClient_GetClusterTemplate(t *testing.T) {
 				yaml:            templateYAML("ns1", "test"), // original template modified with target namespace and variable replacement
 			},
 		},
+		{
+			name: "repository source - merges multiple flavors",
+			args: args{
+				options: GetClusterTemplateOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ProviderRepositorySource: &ProviderRepositorySourceOptions{
+						InfrastructureProvider: "infra:v3.0.0",
+						Flavors:                []string{"", "addon"},
+					},
+					ClusterName:              "test",
+					TargetNamespace:          "ns1",
+					ControlPlaneMachineCount: pointer.Int64Ptr(1),
+				},
+			},
+			want: templateValues{
+				variables:       []string{"CLUSTER_NAME"}, // variable detected
+				targetNamespace: "ns1",
+				yaml:            addonTemplateYAML("ns1", "test"), // the Cluster defined in both flavors is de-duplicated
+			},
+		},
+		{
+			name: "repository source - fails for conflicting flavors",
+			args: args{
+				options: GetClusterTemplateOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ProviderRepositorySource: &ProviderRepositorySourceOptions{
+						InfrastructureProvider: "infra:v3.0.0",
+						Flavors:                []string{"addon", "conflict"},
+					},
+					ClusterName:              "test",
+					TargetNamespace:          "ns1",
+					ControlPlaneMachineCount: pointer.Int64Ptr(1),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "repository source - fails if both flavor and flavors are set",
+			args: args{
+				options: GetClusterTemplateOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ProviderRepositorySource: &ProviderRepositorySourceOptions{
+						InfrastructureProvider: "infra:v3.0.0",
+						Flavor:                 "addon",
+						Flavors:                []string{"", "addon"},
+					},
+					ClusterName:              "test",
+					TargetNamespace:          "ns1",
+					ControlPlaneMachineCount: pointer.Int64Ptr(1),
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "repository source - detects provider name/version if missing",
 			args: args{
@@ -592,6 +883,44 @@ func Test_clusterctlClient_GetClusterTemplate(t *testing.T) {
 				yaml:            templateYAML("ns1", "test"), // original template modified with target namespace and variable replacement
 			},
 		},
+		{
+			name: "Raw source - pass",
+			args: args{
+				options: GetClusterTemplateOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					RawSource: &RawSourceOptions{
+						Content: rawTemplate,
+					},
+					ClusterName:              "test",
+					TargetNamespace:          "ns1",
+					ControlPlaneMachineCount: pointer.Int64Ptr(1),
+				},
+			},
+			want: templateValues{
+				variables:       []string{"CLUSTER_NAME"}, // variable detected
+				targetNamespace: "ns1",
+				yaml:            templateYAML("ns1", "test"), // original template modified with target namespace and variable replacement
+			},
+		},
+		{
+			name: "Raw source from a reader - pass",
+			args: args{
+				options: GetClusterTemplateOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					RawSource: &RawSourceOptions{
+						Reader: bytes.NewReader(rawTemplate),
+					},
+					ClusterName:              "test",
+					TargetNamespace:          "ns1",
+					ControlPlaneMachineCount: pointer.Int64Ptr(1),
+				},
+			},
+			want: templateValues{
+				variables:       []string{"CLUSTER_NAME"}, // variable detected
+				targetNamespace: "ns1",
+				yaml:            templateYAML("ns1", "test"), // original template modified with target namespace and variable replacement
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -882,6 +1211,88 @@ func Test_clusterctlClient_GetClusterTemplate_withoutCluster(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_GetClusterTemplate_withVariablePrompt(t *testing.T) {
+	g := NewWithT(t)
+
+	rawTemplate := templateYAML("ns3", "${ CLUSTER_NAME }-${ SUFFIX }-${ZONE:=zone1}")
+
+	tmpDir, err := os.MkdirTemp("", "cc")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "cluster-template.yaml")
+	g.Expect(os.WriteFile(path, rawTemplate, 0600)).To(Succeed())
+
+	tests := []struct {
+		name         string
+		values       map[string]string
+		promptErr    error
+		wantPrompted map[string]*string
+		wantYaml     []byte
+		wantErr      bool
+	}{
+		{
+			name:   "prompts for the variables not set",
+			values: map[string]string{"SUFFIX": "foo"},
+			wantPrompted: map[string]*string{
+				"SUFFIX": nil,
+				"ZONE":   pointer.StringPtr("zone1"),
+			},
+			wantYaml: templateYAML("ns1", "test-foo-zone1"),
+		},
+		{
+			name:   "prompted values override the default values",
+			values: map[string]string{"SUFFIX": "foo", "ZONE": "zone2"},
+			wantPrompted: map[string]*string{
+				"SUFFIX": nil,
+				"ZONE":   pointer.StringPtr("zone1"),
+			},
+			wantYaml: templateYAML("ns1", "test-foo-zone2"),
+		},
+		{
+			name:    "fails if no value is returned for a required variable",
+			values:  map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:      "fails if the prompt fails",
+			promptErr: errors.New("failed to read from the terminal"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig()
+			cluster1 := newFakeCluster(cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}, config1)
+			client := newFakeClient(config1).WithCluster(cluster1)
+
+			prompted := map[string]*string{}
+			got, err := client.GetClusterTemplate(GetClusterTemplateOptions{
+				Kubeconfig:      Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				URLSource:       &URLSourceOptions{URL: path},
+				ClusterName:     "test",
+				TargetNamespace: "ns1",
+				VariablePrompt: func(name string, defaultValue *string) (string, error) {
+					prompted[name] = defaultValue
+					return tt.values[name], tt.promptErr
+				},
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(prompted).To(Equal(tt.wantPrompted))
+
+			gotYaml, err := got.Yaml()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(gotYaml).To(Equal(tt.wantYaml))
+		})
+	}
+}
+
 func Test_clusterctlClient_ProcessYAML(t *testing.T) {
 	g := NewWithT(t)
 	template := `v1: ${VAR1:=default1}
@@ -894,14 +1305,20 @@ v3: ${VAR3:-default3}`
 	templateFile := filepath.Join(dir, "template.yaml")
 	g.Expect(os.WriteFile(templateFile, []byte(template), 0600)).To(Succeed())
 
+	templateWithRequiredVars := `v1: ${VAR1:=default1}
+v2: ${VAR2}`
+	templateWithRequiredVarsFile := filepath.Join(dir, "template-with-required-vars.yaml")
+	g.Expect(os.WriteFile(templateWithRequiredVarsFile, []byte(templateWithRequiredVars), 0600)).To(Succeed())
+
 	inputReader := strings.NewReader(template)
 
 	tests := []struct {
-		name         string
-		options      ProcessYAMLOptions
-		expectErr    bool
-		expectedYaml string
-		expectedVars []string
+		name           string
+		options        ProcessYAMLOptions
+		expectErr      bool
+		expectedYaml   string
+		expectedVars   []string
+		expectedVarMap map[string]*string
 	}{
 		{
 			name: "returns the expected yaml and variables",
@@ -929,6 +1346,32 @@ v3: default3`,
 			expectedYaml: ``,
 			expectedVars: []string{"VAR1", "VAR2", "VAR3"},
 		},
+		{
+			name: "returns required variables and variables with defaults",
+			options: ProcessYAMLOptions{
+				URLSource: &URLSourceOptions{
+					URL: templateWithRequiredVarsFile,
+				},
+				SkipTemplateProcess: true,
+			},
+			expectErr:    false,
+			expectedYaml: ``,
+			expectedVars: []string{"VAR1", "VAR2"},
+			expectedVarMap: map[string]*string{
+				"VAR1": pointer.StringPtr("default1"),
+				"VAR2": nil,
+			},
+		},
+		{
+			name: "returns error if a required variable is not set",
+			options: ProcessYAMLOptions{
+				URLSource: &URLSourceOptions{
+					URL: templateWithRequiredVarsFile,
+				},
+				SkipTemplateProcess: false,
+			},
+			expectErr: true,
+		},
 		{
 			name:      "returns error if no source was specified",
 			options:   ProcessYAMLOptions{},
@@ -980,10 +1423,136 @@ v3: default3`,
 
 			expectedVars := printer.Variables()
 			g.Expect(expectedVars).To(ConsistOf(tt.expectedVars))
+
+			if tt.expectedVarMap != nil {
+				g.Expect(printer.VariableMap()).To(Equal(tt.expectedVarMap))
+			}
 		})
 	}
 }
 
+func Test_clusterctlClient_ResolveYAMLVariables(t *testing.T) {
+	template := `v1: ${CLUSTERCTL_TEST_ENV_VAR}
+v2: ${CONFIG_VAR}
+v3: ${DEFAULT_VAR:=default}
+v4: ${UNSET_VAR}
+v5: ${CLOUD_PASSWORD}
+v6: ${SECRET_VAR}`
+
+	g := NewWithT(t)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	g.Expect(os.WriteFile(secretFile, []byte("from-secret"), 0600)).To(Succeed())
+	g.Expect(os.Setenv("CLUSTERCTL_TEST_ENV_VAR", "from-env")).To(Succeed())
+	defer os.Unsetenv("CLUSTERCTL_TEST_ENV_VAR")
+
+	tests := []struct {
+		name        string
+		showSecrets bool
+		want        []ResolvedVariable
+	}{
+		{
+			name:        "resolves variables masking sensitive values",
+			showSecrets: false,
+			want: []ResolvedVariable{
+				{Name: "CLOUD_PASSWORD", IsSet: true, Value: "******", Masked: true, Source: VariableSourceConfigFile},
+				{Name: "CLUSTERCTL_TEST_ENV_VAR", IsSet: true, Value: "from-env", Source: VariableSourceEnv},
+				{Name: "CONFIG_VAR", IsSet: true, Value: "from-config", Source: VariableSourceConfigFile},
+				{Name: "DEFAULT_VAR", IsSet: true, Value: "default", Source: VariableSourceDefault},
+				{Name: "SECRET_VAR", IsSet: true, Value: "******", Masked: true, Source: VariableSourceConfigFile},
+				{Name: "UNSET_VAR", IsSet: false},
+			},
+		},
+		{
+			name:        "resolves variables showing sensitive values",
+			showSecrets: true,
+			want: []ResolvedVariable{
+				{Name: "CLOUD_PASSWORD", IsSet: true, Value: "secret", Source: VariableSourceConfigFile},
+				{Name: "CLUSTERCTL_TEST_ENV_VAR", IsSet: true, Value: "from-env", Source: VariableSourceEnv},
+				{Name: "CONFIG_VAR", IsSet: true, Value: "from-config", Source: VariableSourceConfigFile},
+				{Name: "DEFAULT_VAR", IsSet: true, Value: "default", Source: VariableSourceDefault},
+				{Name: "SECRET_VAR", IsSet: true, Value: "from-secret", Source: VariableSourceConfigFile},
+				{Name: "UNSET_VAR", IsSet: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig(config.WithFileSecretSource()).
+				WithVar("CONFIG_VAR", "from-config").
+				WithVar("CLOUD_PASSWORD", "secret").
+				WithVar("SECRET_VAR", "${file:"+secretFile+"}")
+			client := newFakeClient(config1)
+
+			got, err := client.ResolveYAMLVariables(ProcessYAMLOptions{
+				ReaderSource: &ReaderSourceOptions{
+					Reader: strings.NewReader(template),
+				},
+				ShowSecrets: tt.showSecrets,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_clusterctlClient_ProcessYAML_withVariablesFile(t *testing.T) {
+	g := NewWithT(t)
+
+	template := `v1: ${CLUSTERCTL_TEST_ENV_VAR}
+v2: ${FILE_VAR}
+v3: ${CONFIG_VAR}`
+
+	dir, err := os.MkdirTemp("", "clusterctl")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	variablesFile := filepath.Join(dir, "variables.env")
+	g.Expect(os.WriteFile(variablesFile, []byte("CLUSTERCTL_TEST_ENV_VAR=from-file\nFILE_VAR=from-file\n"), 0600)).To(Succeed())
+
+	g.Expect(os.Setenv("CLUSTERCTL_TEST_ENV_VAR", "from-env")).To(Succeed())
+	defer os.Unsetenv("CLUSTERCTL_TEST_ENV_VAR")
+
+	newClient := func() *fakeClient {
+		config1 := newFakeConfig().
+			WithVar("FILE_VAR", "from-config").
+			WithVar("CONFIG_VAR", "from-config")
+		return newFakeClient(config1)
+	}
+
+	// Checks variables are resolved using the expected precedence order.
+	got, err := newClient().ResolveYAMLVariables(ProcessYAMLOptions{
+		ReaderSource:  &ReaderSourceOptions{Reader: strings.NewReader(template)},
+		VariablesFile: variablesFile,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]ResolvedVariable{
+		{Name: "CLUSTERCTL_TEST_ENV_VAR", IsSet: true, Value: "from-env", Source: VariableSourceEnv},
+		{Name: "CONFIG_VAR", IsSet: true, Value: "from-config", Source: VariableSourceConfigFile},
+		{Name: "FILE_VAR", IsSet: true, Value: "from-file", Source: VariableSourceVariablesFile},
+	}))
+
+	// Checks the variables file does not override os environment variables.
+	client := newClient()
+	_, err = client.ProcessYAML(ProcessYAMLOptions{
+		ReaderSource:        &ReaderSourceOptions{Reader: strings.NewReader(template)},
+		VariablesFile:       variablesFile,
+		SkipTemplateProcess: true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = client.configClient.Variables().Get("CLUSTERCTL_TEST_ENV_VAR")
+	g.Expect(err).To(HaveOccurred()) // NB. the fake config client does not read os environment variables
+	g.Expect(client.configClient.Variables().Get("FILE_VAR")).To(Equal("from-file"))
+
+	// Fails if the variables file does not exist.
+	_, err = newClient().ProcessYAML(ProcessYAMLOptions{
+		ReaderSource:  &ReaderSourceOptions{Reader: strings.NewReader(template)},
+		VariablesFile: filepath.Join(dir, "do-not-exist.env"),
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
 // errReader returns a non-EOF error on the first read.
 type errReader struct{}
 
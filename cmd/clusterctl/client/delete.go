@@ -17,10 +17,16 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
 )
 
 // DeleteOptions carries the options supported by Delete.
@@ -53,28 +59,191 @@ type DeleteOptions struct {
 
 	// IncludeCRDs forces the deletion of the provider's CRDs (and of all the related objects).
 	IncludeCRDs bool
+
+	// PreserveNamespace forces the deletion of the provider's CRDs (and of all the related objects) together with the
+	// other provider components, while preserving the namespace where the providers are hosted and the objects in it
+	// not labeled as provider components; this is useful when the namespace is shared with other tools.
+	// The objects left behind in the namespace are logged. It can't be used together with IncludeNamespace.
+	PreserveNamespace bool
+
+	// Force allows to delete providers even if workload Clusters depending on them still exist in the
+	// management cluster; in this case the list of the dependent Clusters is logged as a warning.
+	// NOTE: deleting e.g. the infrastructure provider leaves the machines of the dependent Clusters running
+	// without a controller reconciling them.
+	Force bool
+
+	// DryRun means the delete action is a dry run, no real action will be performed; instead, the objects
+	// that are going to be deleted and the workload Clusters depending on the providers are logged.
+	// Use DeleteDryRun for getting the report programmatically.
+	DryRun bool
+}
+
+// DeleteProviderOptions carries the options supported by DeleteProvider.
+type DeleteProviderOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Provider is the name of the provider to delete, e.g. aws.
+	Provider string
+
+	// ProviderType is the type of the provider to delete, e.g. InfrastructureProvider.
+	ProviderType clusterctlv1.ProviderType
+
+	// IncludeNamespace forces the deletion of the namespace where the provider is hosted (and of all the contained objects).
+	// It can't be used if the namespace hosts other providers too.
+	IncludeNamespace bool
+
+	// IncludeCRDs forces the deletion of the provider's CRDs (and of all the related objects).
+	IncludeCRDs bool
+
+	// Force allows to delete the provider even if workload Clusters depending on it still exist in the
+	// management cluster; in this case the list of the dependent Clusters is logged as a warning.
+	Force bool
+
+	// DryRun means the delete action is a dry run, no real action will be performed; instead, the objects
+	// that are going to be deleted and the workload Clusters depending on the provider are logged.
+	DryRun bool
 }
 
 func (c *clusterctlClient) Delete(options DeleteOptions) error {
-	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	return c.DeleteContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) DeleteContext(ctx context.Context, options DeleteOptions) (retErr error) {
+	c = c.withContext(ctx)
+
+	if options.DryRun {
+		report, err := c.DeleteDryRun(options)
+		if err != nil {
+			return err
+		}
+
+		logDeleteReport(report)
+		return nil
+	}
+
+	op := c.startOperation(DeleteOperation)
+	defer func() { op.done(retErr) }()
+
+	clusterClient, providersToDelete, err := c.getProvidersToDelete(options)
+	if err != nil {
+		return err
+	}
+	for _, provider := range providersToDelete {
+		op.providers = append(op.providers, provider.ManifestLabel())
+	}
+
+	// Ensure no workload Clusters depend on the selected providers, unless forced.
+	if err := checkDependentClusters(clusterClient, providersToDelete, options.Force); err != nil {
+		return err
+	}
+
+	// Delete the selected providers
+	for _, provider := range providersToDelete {
+		if err := clusterClient.ProviderComponents().Delete(options.componentsDeleteOptions(provider)); err != nil {
+			return err
+		}
+	}
+
+	// If the namespaces have been preserved, report the objects left behind.
+	if options.PreserveNamespace {
+		return logLeftovers(clusterClient, providersToDelete)
+	}
+
+	return nil
+}
+
+func (c *clusterctlClient) DeleteProvider(options DeleteProviderOptions) (retErr error) {
+	log := logf.Log
+
+	clusterClient, provider, err := c.getProviderToDelete(options)
 	if err != nil {
 		return err
 	}
+	deleteOptions := cluster.DeleteOptions{Provider: *provider, IncludeNamespace: options.IncludeNamespace, IncludeCRDs: options.IncludeCRDs}
+
+	if options.DryRun {
+		report, err := clusterClient.ProviderComponents().DeleteDryRun(deleteOptions)
+		if err != nil {
+			return err
+		}
+		logDeleteReport((*DeleteReport)(report))
+		return nil
+	}
+
+	op := c.startOperation(DeleteOperation)
+	defer func() { op.done(retErr) }()
+	op.providers = append(op.providers, provider.ManifestLabel())
+
+	// Ensure no workload Clusters depend on the provider, unless forced.
+	if err := checkDependentClusters(clusterClient, []clusterctlv1.Provider{*provider}, options.Force); err != nil {
+		return err
+	}
+
+	if err := clusterClient.ProviderComponents().Delete(deleteOptions); err != nil {
+		return err
+	}
+
+	// Ensure the provider is removed from the inventory, also if its inventory item was not labeled as a provider component.
+	if err := clusterClient.ProviderInventory().Delete(*provider); err != nil {
+		return err
+	}
+	log.Info("Provider deleted", "Provider", provider.ManifestLabel(), "Namespace", provider.Namespace)
+	return nil
+}
+
+func (c *clusterctlClient) DeleteDryRun(options DeleteOptions) (*DeleteReport, error) {
+	clusterClient, providersToDelete, err := c.getProvidersToDelete(options)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &cluster.DeleteReport{}
+	for _, provider := range providersToDelete {
+		providerReport, err := clusterClient.ProviderComponents().DeleteDryRun(options.componentsDeleteOptions(provider))
+		if err != nil {
+			return nil, err
+		}
+		report.Merge(providerReport)
+	}
+
+	return (*DeleteReport)(report), nil
+}
+
+// componentsDeleteOptions returns the options for deleting the components of a provider.
+func (o DeleteOptions) componentsDeleteOptions(provider clusterctlv1.Provider) cluster.DeleteOptions {
+	if o.PreserveNamespace {
+		return cluster.DeleteOptions{Provider: provider, IncludeNamespace: false, IncludeCRDs: true}
+	}
+	return cluster.DeleteOptions{Provider: provider, IncludeNamespace: o.IncludeNamespace, IncludeCRDs: o.IncludeCRDs}
+}
+
+// getProvidersToDelete returns the client for the management cluster and the list of providers selected for deletion.
+func (c *clusterctlClient) getProvidersToDelete(options DeleteOptions) (cluster.Client, []clusterctlv1.Provider, error) {
+	if options.PreserveNamespace && options.IncludeNamespace {
+		return nil, nil, errors.New("the PreserveNamespace and IncludeNamespace options can't be used together")
+	}
+
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Ensure this command only runs against management clusters with the current Cluster API contract.
 	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Ensure the custom resource definitions required by clusterctl are in place.
 	if err := clusterClient.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Get the list of installed providers.
 	installedProviders, err := clusterClient.ProviderInventory().List()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Prepare the list of providers to delete.
@@ -94,32 +263,159 @@ func (c *clusterctlClient) Delete(options DeleteOptions) error {
 			// Parse the abbreviated syntax for name[:version]
 			name, _, err := parseProviderName(provider.Name)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 
 			// Try to detect the namespace where the provider lives
 			provider.Namespace, err = clusterClient.ProviderInventory().GetProviderNamespace(provider.ProviderName, provider.GetProviderType())
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 			if provider.Namespace == "" {
-				return errors.Errorf("Failed to identify the namespace for the %q provider.", name)
+				return nil, nil, errors.Errorf("Failed to identify the namespace for the %q provider.", name)
 			}
 
 			providersToDelete = append(providersToDelete, provider)
 		}
 	}
 
-	// Delete the selected providers
+	return clusterClient, providersToDelete, nil
+}
+
+// getProviderToDelete returns the client for the management cluster and the inventory item of the provider selected
+// for deletion by DeleteProvider.
+func (c *clusterctlClient) getProviderToDelete(options DeleteProviderOptions) (cluster.Client, *clusterctlv1.Provider, error) {
+	if options.Provider == "" || options.ProviderType == "" {
+		return nil, nil, errors.New("the name and the type of the provider to delete must be specified")
+	}
+
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure the custom resource definitions required by clusterctl are in place.
+	if err := clusterClient.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
+		return nil, nil, err
+	}
+
+	installedProviders, err := clusterClient.ProviderInventory().List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var provider *clusterctlv1.Provider
+	for i := range installedProviders.Items {
+		p := installedProviders.Items[i]
+		if p.ProviderName == options.Provider && p.GetProviderType() == options.ProviderType {
+			provider = &p
+			break
+		}
+	}
+	if provider == nil {
+		return nil, nil, errors.Errorf("the %s with name %s is not installed in the management cluster", options.ProviderType, options.Provider)
+	}
+
+	// Deleting the namespace would delete the other providers hosted in the same namespace too.
+	if options.IncludeNamespace {
+		for _, p := range installedProviders.Items {
+			if p.Namespace == provider.Namespace && p.Name != provider.Name {
+				return nil, nil, errors.Errorf("the namespace %s of the %s provider can't be deleted because it hosts the %s provider too", provider.Namespace, provider.ManifestLabel(), p.ManifestLabel())
+			}
+		}
+	}
+
+	return clusterClient, provider, nil
+}
+
+// checkDependentClusters returns an error listing the workload Clusters depending on the providers to delete, if any;
+// if force is set, the list is logged as a warning instead.
+func checkDependentClusters(clusterClient cluster.Client, providersToDelete []clusterctlv1.Provider, force bool) error {
+	log := logf.Log
+
+	report := &cluster.DeleteReport{}
 	for _, provider := range providersToDelete {
-		if err := clusterClient.ProviderComponents().Delete(cluster.DeleteOptions{Provider: provider, IncludeNamespace: options.IncludeNamespace, IncludeCRDs: options.IncludeCRDs}); err != nil {
+		clusters, err := clusterClient.ProviderComponents().GetDependentClusters(provider)
+		if err != nil {
 			return err
 		}
+		providerReport := &cluster.DeleteReport{}
+		for _, c := range clusters {
+			providerReport.DependentClusters = append(providerReport.DependentClusters, cluster.DependentCluster{Cluster: c, Providers: []string{provider.InstanceName()}})
+		}
+		report.Merge(providerReport)
 	}
 
+	if !report.HasDependentClusters() {
+		return nil
+	}
+
+	if force {
+		for _, d := range report.DependentClusters {
+			log.Info("Warning: deleting providers the Cluster depends on, it will not be reconciled anymore", "Cluster", d.Cluster.Name, "Namespace", d.Cluster.Namespace, "Providers", strings.Join(d.Providers, ", "))
+		}
+		return nil
+	}
+
+	clusters := make([]string, 0, len(report.DependentClusters))
+	for _, d := range report.DependentClusters {
+		clusters = append(clusters, fmt.Sprintf("%s/%s (%s)", d.Cluster.Namespace, d.Cluster.Name, strings.Join(d.Providers, ", ")))
+	}
+	return errors.Errorf("failed to delete providers because the following workload Clusters depend on them: %s. Delete the Clusters first or use the force option", strings.Join(clusters, ", "))
+}
+
+// logLeftovers logs the objects left behind in the namespaces of the deleted providers because not labeled as
+// provider components.
+func logLeftovers(clusterClient cluster.Client, deletedProviders []clusterctlv1.Provider) error {
+	log := logf.Log
+
+	namespaces := sets.NewString()
+	for _, provider := range deletedProviders {
+		namespaces.Insert(provider.Namespace)
+	}
+
+	for _, namespace := range namespaces.List() {
+		leftovers, err := clusterClient.ProviderComponents().ListLeftovers(namespace)
+		if err != nil {
+			return err
+		}
+		for _, ref := range leftovers {
+			log.Info("Object not labeled as provider component left behind", ref.Kind, ref.Name, "Namespace", ref.Namespace)
+		}
+	}
 	return nil
 }
 
+// logDeleteReport logs the objects that are going to be deleted, and loudly flags the workload Clusters depending on
+// the providers being deleted.
+func logDeleteReport(report *DeleteReport) {
+	log := logf.Log
+
+	for _, p := range report.Providers {
+		log.Info("Provider to be deleted", "Provider", p)
+	}
+	for _, n := range report.Namespaces {
+		log.Info("Namespace to be deleted, including all the contained objects", "Namespace", n)
+	}
+	for _, crd := range report.CustomResourceDefinitions {
+		log.Info("CustomResourceDefinition to be deleted, including all the objects of this kind", "CustomResourceDefinition", crd)
+	}
+	for _, ref := range report.ClusterResources {
+		log.Info("Object to be deleted", ref.Kind, ref.Name)
+	}
+	for _, ref := range report.Objects {
+		log.Info("Object to be deleted", ref.Kind, ref.Name, "Namespace", ref.Namespace)
+	}
+	for _, d := range report.DependentClusters {
+		log.Info("Warning: the Cluster depends on providers being deleted and it will not be reconciled anymore", "Cluster", d.Cluster.Name, "Namespace", d.Cluster.Namespace, "Providers", strings.Join(d.Providers, ", "))
+	}
+}
+
 func appendProviders(list []clusterctlv1.Provider, providerType clusterctlv1.ProviderType, names ...string) []clusterctlv1.Provider {
 	for _, name := range names {
 		if name == "" {
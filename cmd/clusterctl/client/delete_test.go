@@ -17,12 +17,16 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
 )
 
 var namespace = "foobar"
@@ -136,6 +140,248 @@ func Test_clusterctlClient_Delete(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_DeleteWithDependentClusters(t *testing.T) {
+	tests := []struct {
+		name    string
+		force   bool
+		wantErr bool
+	}{
+		{
+			name:    "Fails if workload Clusters depend on the providers",
+			force:   false,
+			wantErr: true,
+		},
+		{
+			name:    "Deletes the providers if forced",
+			force:   true,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakeClusterForDelete()
+			options := DeleteOptions{
+				Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				DeleteAll:  true,
+				Force:      tt.force,
+			}
+			proxy := client.clusters[cluster.Kubeconfig(options.Kubeconfig)].Proxy().(*test.FakeProxy)
+			// NOTE: the inventory CRD is added as a typed object, because the fake client fails to list CRDs
+			// when one of them is created by EnsureCustomResourceDefinitions as an unstructured object.
+			inventoryCRD := &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("providers.%s", clusterctlv1.GroupVersion.Group)},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: clusterctlv1.GroupVersion.Version, Storage: true}},
+				},
+			}
+			proxy.WithObjs(inventoryCRD)
+			proxy.WithObjs(test.NewFakeCluster("ns1", "cluster1").Objs()...)
+
+			err := client.Delete(options)
+
+			c, cErr := proxy.NewClient()
+			g.Expect(cErr).NotTo(HaveOccurred())
+			gotProviders := &clusterctlv1.ProviderList{}
+			g.Expect(c.List(ctx, gotProviders)).To(Succeed())
+
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("ns1/cluster1"))
+				g.Expect(gotProviders.Items).To(HaveLen(4))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(gotProviders.Items).To(BeEmpty())
+		})
+	}
+}
+
+func Test_clusterctlClient_DeleteProvider(t *testing.T) {
+	tests := []struct {
+		name             string
+		options          DeleteProviderOptions
+		dependentCluster bool
+		wantProviders    sets.String
+		wantErr          bool
+	}{
+		{
+			name: "Deletes only the selected provider",
+			options: DeleteProviderOptions{
+				Provider:     bootstrapProviderConfig.Name(),
+				ProviderType: bootstrapProviderConfig.Type(),
+			},
+			wantProviders: sets.NewString(
+				capiProviderConfig.Name(),
+				clusterctlv1.ManifestLabel(controlPlaneProviderConfig.Name(), controlPlaneProviderConfig.Type()),
+				clusterctlv1.ManifestLabel(infraProviderConfig.Name(), infraProviderConfig.Type())),
+			wantErr: false,
+		},
+		{
+			name: "Deletes the selected provider together with its namespace",
+			options: DeleteProviderOptions{
+				Provider:         bootstrapProviderConfig.Name(),
+				ProviderType:     bootstrapProviderConfig.Type(),
+				IncludeNamespace: true,
+			},
+			wantProviders: sets.NewString(
+				capiProviderConfig.Name(),
+				clusterctlv1.ManifestLabel(controlPlaneProviderConfig.Name(), controlPlaneProviderConfig.Type()),
+				clusterctlv1.ManifestLabel(infraProviderConfig.Name(), infraProviderConfig.Type())),
+			wantErr: false,
+		},
+		{
+			name: "Fails if the namespace to delete hosts other providers",
+			options: DeleteProviderOptions{
+				Provider:         infraProviderConfig.Name(),
+				ProviderType:     infraProviderConfig.Type(),
+				IncludeNamespace: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Fails if the provider is not installed",
+			options: DeleteProviderOptions{
+				Provider:     infraProviderConfig.Name(),
+				ProviderType: clusterctlv1.BootstrapProviderType,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Fails if workload Clusters depend on the provider",
+			options: DeleteProviderOptions{
+				Provider:     capiProviderConfig.Name(),
+				ProviderType: capiProviderConfig.Type(),
+			},
+			dependentCluster: true,
+			wantErr:          true,
+		},
+		{
+			name: "Deletes the provider with dependent workload Clusters if forced",
+			options: DeleteProviderOptions{
+				Provider:     capiProviderConfig.Name(),
+				ProviderType: capiProviderConfig.Type(),
+				Force:        true,
+			},
+			dependentCluster: true,
+			wantProviders: sets.NewString(
+				clusterctlv1.ManifestLabel(bootstrapProviderConfig.Name(), bootstrapProviderConfig.Type()),
+				clusterctlv1.ManifestLabel(controlPlaneProviderConfig.Name(), controlPlaneProviderConfig.Type()),
+				clusterctlv1.ManifestLabel(infraProviderConfig.Name(), infraProviderConfig.Type())),
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakeClusterForDelete()
+			options := tt.options
+			options.Kubeconfig = Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+			proxy := client.clusters[cluster.Kubeconfig(options.Kubeconfig)].Proxy().(*test.FakeProxy)
+			if tt.dependentCluster {
+				// NOTE: the inventory CRD is added as a typed object, see Test_clusterctlClient_DeleteWithDependentClusters.
+				inventoryCRD := &apiextensionsv1.CustomResourceDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("providers.%s", clusterctlv1.GroupVersion.Group)},
+					Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+						Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: clusterctlv1.GroupVersion.Version, Storage: true}},
+					},
+				}
+				proxy.WithObjs(inventoryCRD)
+				proxy.WithObjs(test.NewFakeCluster("ns1", "cluster1").Objs()...)
+			}
+
+			err := client.DeleteProvider(options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			c, err := proxy.NewClient()
+			g.Expect(err).NotTo(HaveOccurred())
+			gotProviders := &clusterctlv1.ProviderList{}
+			g.Expect(c.List(ctx, gotProviders)).To(Succeed())
+
+			gotProvidersSet := sets.NewString()
+			for _, gotProvider := range gotProviders.Items {
+				gotProvidersSet.Insert(gotProvider.Name)
+			}
+			g.Expect(gotProvidersSet).To(Equal(tt.wantProviders))
+		})
+	}
+}
+
+func Test_clusterctlClient_DeleteWithPreserveNamespace(t *testing.T) {
+	t.Run("Fails if used together with IncludeNamespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		client := fakeClusterForDelete()
+		err := client.Delete(DeleteOptions{
+			Kubeconfig:        Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+			DeleteAll:         true,
+			IncludeNamespace:  true,
+			PreserveNamespace: true,
+		})
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("Deletes the providers and the CRDs", func(t *testing.T) {
+		g := NewWithT(t)
+
+		client := fakeClusterForDelete()
+		options := DeleteOptions{
+			Kubeconfig:        Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+			DeleteAll:         true,
+			PreserveNamespace: true,
+		}
+
+		report, err := client.DeleteDryRun(options)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.Namespaces).To(BeEmpty())
+
+		g.Expect(client.Delete(options)).To(Succeed())
+
+		proxy := client.clusters[cluster.Kubeconfig(options.Kubeconfig)].Proxy()
+		c, err := proxy.NewClient()
+		g.Expect(err).NotTo(HaveOccurred())
+		gotProviders := &clusterctlv1.ProviderList{}
+		g.Expect(c.List(ctx, gotProviders)).To(Succeed())
+		g.Expect(gotProviders.Items).To(BeEmpty())
+	})
+}
+
+func Test_clusterctlClient_DeleteDryRun(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakeClusterForDelete()
+	options := DeleteOptions{
+		Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+		DeleteAll:  true,
+		DryRun:     true,
+	}
+
+	report, err := client.DeleteDryRun(options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Providers).To(ConsistOf(
+		"capi-system/"+capiProviderConfig.Name(),
+		"capbpk-system/"+clusterctlv1.ManifestLabel(bootstrapProviderConfig.Name(), bootstrapProviderConfig.Type()),
+		namespace+"/"+clusterctlv1.ManifestLabel(controlPlaneProviderConfig.Name(), controlPlaneProviderConfig.Type()),
+		namespace+"/"+clusterctlv1.ManifestLabel(infraProviderConfig.Name(), infraProviderConfig.Type()),
+	))
+
+	// Delete with DryRun must not delete any provider.
+	g.Expect(client.Delete(options)).To(Succeed())
+
+	proxy := client.clusters[cluster.Kubeconfig(options.Kubeconfig)].Proxy()
+	c, err := proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	gotProviders := &clusterctlv1.ProviderList{}
+	g.Expect(c.List(ctx, gotProviders)).To(Succeed())
+	g.Expect(gotProviders.Items).To(HaveLen(4))
+}
+
 // clusterctl client for a management cluster with capi and bootstrap provider.
 func fakeClusterForDelete() *fakeClient {
 	config1 := newFakeConfig().
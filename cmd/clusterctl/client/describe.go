@@ -19,6 +19,8 @@ package client
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/tree"
 )
 
@@ -82,3 +84,56 @@ func (c *clusterctlClient) DescribeCluster(options DescribeClusterOptions) (*tre
 		DisableGrouping:     options.DisableGrouping,
 	})
 }
+
+// GetObjectGraphOptions carries the options supported by GetObjectGraph.
+type GetObjectGraphOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace where the objects are located. If unspecified, the current namespace will be used.
+	// Use AllNamespaces for getting the objects from all the namespaces.
+	Namespace string
+
+	// AllNamespaces gets the objects from all the namespaces; it can not be used together with ClusterName.
+	AllNamespaces bool
+
+	// ClusterName restricts the graph to the Cluster with the given name and to the objects belonging to it.
+	// If unspecified, the graph includes all the objects in the namespace.
+	ClusterName string
+}
+
+// GetObjectGraph returns the graph of the Cluster API objects existing in a management cluster.
+func (c *clusterctlClient) GetObjectGraph(options GetObjectGraphOptions) (*ObjectGraph, error) {
+	if options.AllNamespaces && options.ClusterName != "" {
+		return nil, errors.New("the ClusterName option can not be used together with AllNamespaces")
+	}
+
+	// gets access to the management cluster
+	cluster, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := cluster.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, err
+	}
+
+	// If the option specifying the Namespace is empty, try to detect it.
+	if options.AllNamespaces {
+		options.Namespace = ""
+	} else if options.Namespace == "" {
+		currentNamespace, err := cluster.Proxy().CurrentNamespace()
+		if err != nil {
+			return nil, err
+		}
+		options.Namespace = currentNamespace
+	}
+
+	graph, err := cluster.ObjectMover().ObjectGraph(options.Namespace, options.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+	return (*ObjectGraph)(graph), nil
+}
@@ -17,7 +17,16 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 )
 
 // GetKubeconfigOptions carries all the options supported by GetKubeconfig.
@@ -31,8 +40,56 @@ type GetKubeconfigOptions struct {
 
 	// WorkloadClusterName is the name of the workload cluster.
 	WorkloadClusterName string
+
+	// SecretName, if set, is the name of the Secret storing the workload cluster's kubeconfig, for workload clusters
+	// not using the conventional <cluster>-kubeconfig Secret, e.g. clusters with a managed control plane.
+	// SecretNamespace defaults to Namespace, and SecretKey defaults to "value"; the key must contain a valid kubeconfig.
+	SecretName      string
+	SecretNamespace string
+	SecretKey       string
+
+	// ContextName, if set, is used for renaming the current context of the workload cluster's kubeconfig,
+	// as well as the cluster and the user referenced by it.
+	ContextName string
+
+	// Merge instructs GetKubeconfig to merge the workload cluster's kubeconfig into the kubeconfig file used for
+	// accessing the management cluster (or into the default kubeconfig file, if Kubeconfig.Path is empty), instead
+	// of returning it. When Merge is set, GetKubeconfig returns the path of the kubeconfig file.
+	Merge bool
+
+	// Overwrite allows Merge to replace the clusters, users and contexts already existing in the kubeconfig file with
+	// the ones with the same name in the workload cluster's kubeconfig; if not set, Merge fails on any name collision.
+	Overwrite bool
 }
 
+// GetKubeconfigsOptions carries all the options supported by GetKubeconfigs.
+type GetKubeconfigsOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace where the workload clusters exist. If unspecified, the current namespace will be used.
+	Namespace string
+}
+
+// RegenerateKubeconfigOptions carries all the options supported by RegenerateKubeconfig.
+type RegenerateKubeconfigOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace where the workload cluster exists. If unspecified, the current namespace will be used.
+	Namespace string
+
+	// WorkloadClusterName is the name of the workload cluster.
+	WorkloadClusterName string
+
+	// Timeout defines how long to wait for the control plane provider to create the new kubeconfig Secret (5 minutes if zero).
+	Timeout time.Duration
+}
+
+const defaultRegenerateKubeconfigTimeout = 5 * time.Minute
+
 func (c *clusterctlClient) GetKubeconfig(options GetKubeconfigOptions) (string, error) {
 	// gets access to the management cluster
 	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
@@ -45,16 +102,208 @@ func (c *clusterctlClient) GetKubeconfig(options GetKubeconfigOptions) (string,
 		return "", err
 	}
 
-	if options.Namespace == "" {
-		currentNamespace, err := clusterClient.Proxy().CurrentNamespace()
+	namespace, err := getKubeconfigNamespace(clusterClient, options.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	var kubeconfig string
+	if options.SecretName != "" {
+		secretNamespace := options.SecretNamespace
+		if secretNamespace == "" {
+			secretNamespace = namespace
+		}
+		kubeconfig, err = clusterClient.WorkloadCluster().GetKubeconfigFromSecret(secretNamespace, options.SecretName, options.SecretKey)
+	} else {
+		if options.SecretNamespace != "" || options.SecretKey != "" {
+			return "", errors.New("the SecretNamespace and SecretKey options can be used only together with SecretName")
+		}
+		kubeconfig, err = clusterClient.WorkloadCluster().GetKubeconfig(options.WorkloadClusterName, namespace)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if options.ContextName != "" {
+		kubeconfig, err = renameKubeconfigContext(kubeconfig, options.ContextName)
 		if err != nil {
 			return "", err
 		}
-		if currentNamespace == "" {
-			return "", errors.New("failed to identify the current namespace. Please specify the namespace where the workload cluster exists")
+	}
+
+	if options.Merge {
+		path := options.Kubeconfig.Path
+		if path == "" {
+			path = clientcmd.NewDefaultClientConfigLoadingRules().GetDefaultFilename()
+		}
+		if err := mergeKubeconfig(kubeconfig, path, options.Overwrite); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	return kubeconfig, nil
+}
+
+func (c *clusterctlClient) GetKubeconfigs(options GetKubeconfigsOptions) (map[string]string, error) {
+	// gets access to the management cluster
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, err
+	}
+
+	namespace, err := getKubeconfigNamespace(clusterClient, options.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterClient.WorkloadCluster().GetKubeconfigs(namespace)
+}
+
+func (c *clusterctlClient) RegenerateKubeconfig(options RegenerateKubeconfigOptions) (string, error) {
+	if options.WorkloadClusterName == "" {
+		return "", errors.New("the WorkloadClusterName option is required")
+	}
+
+	// gets access to the management cluster
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
+		return "", err
+	}
+
+	namespace, err := getKubeconfigNamespace(clusterClient, options.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultRegenerateKubeconfigTimeout
+	}
+	return clusterClient.WorkloadCluster().RegenerateKubeconfig(options.WorkloadClusterName, namespace, timeout)
+}
+
+// getKubeconfigNamespace returns the namespace where the workload clusters exist, defaulting to the current namespace.
+func getKubeconfigNamespace(clusterClient cluster.Client, namespace string) (string, error) {
+	if namespace != "" {
+		return namespace, nil
+	}
+
+	currentNamespace, err := clusterClient.Proxy().CurrentNamespace()
+	if err != nil {
+		return "", err
+	}
+	if currentNamespace == "" {
+		return "", errors.New("failed to identify the current namespace. Please specify the namespace where the workload cluster exists")
+	}
+	return currentNamespace, nil
+}
+
+// renameKubeconfigContext renames the current context of a kubeconfig, as well as the cluster and the user referenced by it.
+func renameKubeconfigContext(kubeconfig, contextName string) (string, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse the workload cluster kubeconfig")
+	}
+
+	currentContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return "", errors.Errorf("failed to rename the workload cluster kubeconfig: current context %q not found", config.CurrentContext)
+	}
+
+	currentCluster, ok := config.Clusters[currentContext.Cluster]
+	if !ok {
+		return "", errors.Errorf("failed to rename the workload cluster kubeconfig: cluster %q not found", currentContext.Cluster)
+	}
+	delete(config.Clusters, currentContext.Cluster)
+	config.Clusters[contextName] = currentCluster
+
+	if authInfo, ok := config.AuthInfos[currentContext.AuthInfo]; ok {
+		delete(config.AuthInfos, currentContext.AuthInfo)
+		config.AuthInfos[contextName] = authInfo
+		currentContext.AuthInfo = contextName
+	}
+
+	delete(config.Contexts, config.CurrentContext)
+	currentContext.Cluster = contextName
+	config.Contexts[contextName] = currentContext
+	config.CurrentContext = contextName
+
+	out, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to serialize the workload cluster kubeconfig")
+	}
+	return string(out), nil
+}
+
+// mergeKubeconfig merges the clusters, users and contexts of a kubeconfig into the kubeconfig file at path; entries with
+// the same name of existing entries are rejected, unless overwrite is set. The current context of the kubeconfig file
+// is preserved, if any.
+func mergeKubeconfig(kubeconfig, path string, overwrite bool) error {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the workload cluster kubeconfig")
+	}
+
+	existing := clientcmdapi.NewConfig()
+	if _, err := os.Stat(path); err == nil {
+		existing, err = clientcmd.LoadFromFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the kubeconfig file %q", path)
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read the kubeconfig file %q", path)
+	}
+
+	// Checks for name collisions before changing anything.
+	if !overwrite {
+		collisions := []string{}
+		for name := range config.Clusters {
+			if _, ok := existing.Clusters[name]; ok {
+				collisions = append(collisions, fmt.Sprintf("cluster %q", name))
+			}
+		}
+		for name := range config.AuthInfos {
+			if _, ok := existing.AuthInfos[name]; ok {
+				collisions = append(collisions, fmt.Sprintf("user %q", name))
+			}
+		}
+		for name := range config.Contexts {
+			if _, ok := existing.Contexts[name]; ok {
+				collisions = append(collisions, fmt.Sprintf("context %q", name))
+			}
+		}
+		if len(collisions) > 0 {
+			sort.Strings(collisions)
+			return errors.Errorf("the kubeconfig file %q already contains %s; please use a different context name, or overwrite the existing entries", path, strings.Join(collisions, ", "))
 		}
-		options.Namespace = currentNamespace
 	}
 
-	return clusterClient.WorkloadCluster().GetKubeconfig(options.WorkloadClusterName, options.Namespace)
+	for name, c := range config.Clusters {
+		existing.Clusters[name] = c
+	}
+	for name, authInfo := range config.AuthInfos {
+		existing.AuthInfos[name] = authInfo
+	}
+	for name, c := range config.Contexts {
+		existing.Contexts[name] = c
+	}
+	if existing.CurrentContext == "" {
+		existing.CurrentContext = config.CurrentContext
+	}
+
+	if err := clientcmd.WriteToFile(*existing, path); err != nil {
+		return errors.Wrapf(err, "failed to write the kubeconfig file %q", path)
+	}
+	return nil
 }
@@ -17,11 +17,18 @@ limitations under the License.
 package client
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func Test_clusterctlClient_GetKubeconfig(t *testing.T) {
@@ -66,3 +73,259 @@ func Test_clusterctlClient_GetKubeconfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_clusterctlClient_GetKubeconfig_withSecretName(t *testing.T) {
+	kubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1-user-kubeconfig", Namespace: "ns1"},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("clusters:\n- cluster:\n    server: https://cluster1:6443\n  name: cluster1\ncontexts:\n- context:\n    cluster: cluster1\n    user: admin\n  name: admin@cluster1\ncurrent-context: admin@cluster1\nkind: Config\nusers:\n- name: admin\n  user:\n    token: foo\n"),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		options   GetKubeconfigOptions
+		expectErr bool
+	}{
+		{
+			name:      "returns the kubeconfig stored in the secret",
+			options:   GetKubeconfigOptions{SecretName: "cluster1-user-kubeconfig", SecretKey: "kubeconfig"},
+			expectErr: false,
+		},
+		{
+			name:      "returns error if the secret does not have the key",
+			options:   GetKubeconfigOptions{SecretName: "cluster1-user-kubeconfig"},
+			expectErr: true,
+		},
+		{
+			name:      "returns error if the secret exists in another namespace",
+			options:   GetKubeconfigOptions{SecretName: "cluster1-user-kubeconfig", SecretNamespace: "ns2", SecretKey: "kubeconfig"},
+			expectErr: true,
+		},
+		{
+			name:      "returns error if the secret key is set without the secret name",
+			options:   GetKubeconfigOptions{SecretKey: "kubeconfig"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient := newFakeConfig()
+			kubeconfig := cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+			clusterClient := newFakeCluster(kubeconfig, configClient)
+			clusterClient.fakeProxy.WithFakeCAPISetup().WithObjs(kubeconfigSecret)
+			client := newFakeClient(configClient).WithCluster(clusterClient)
+
+			options := tt.options
+			options.Kubeconfig = Kubeconfig(kubeconfig)
+			options.Namespace = "ns1"
+			options.WorkloadClusterName = "cluster1"
+
+			config, err := client.GetKubeconfig(options)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(config).To(Equal(string(kubeconfigSecret.Data["kubeconfig"])))
+		})
+	}
+}
+
+func Test_clusterctlClient_GetKubeconfigs(t *testing.T) {
+	configClient := newFakeConfig()
+	kubeconfig := cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+
+	// create a management cluster with two workload clusters, one of them without a kubeconfig yet.
+	objs := []client.Object{}
+	for _, o := range test.NewFakeCluster("ns1", "foo").Objs() {
+		if s, ok := o.(*corev1.Secret); ok && s.Name == "foo-kubeconfig" {
+			s.Data = map[string][]byte{
+				secret.KubeconfigDataName: []byte("foo-kubeconfig-data"),
+			}
+		}
+		objs = append(objs, o)
+	}
+	objs = append(objs, test.NewFakeCluster("ns1", "bar").Objs()...)
+
+	clusterClient := newFakeCluster(kubeconfig, configClient).WithObjs(test.FakeCAPISetupObjects()...).WithObjs(objs...)
+	clusterClient.fakeProxy.WithNamespace("ns1")
+	goodClient := newFakeClient(configClient).WithCluster(clusterClient)
+
+	// create a clusterctl client where the proxy returns an empty namespace
+	emptyNamespaceClusterClient := newFakeCluster(kubeconfig, configClient).WithObjs(test.FakeCAPISetupObjects()...)
+	emptyNamespaceClusterClient.fakeProxy.WithNamespace("")
+	badClient := newFakeClient(configClient).WithCluster(emptyNamespaceClusterClient)
+
+	tests := []struct {
+		name           string
+		client         *fakeClient
+		options        GetKubeconfigsOptions
+		want           map[string]string
+		wantPartialErr bool
+		expectErr      bool
+	}{
+		{
+			name:      "returns error if unable to get client for mgmt cluster",
+			client:    fakeEmptyCluster(),
+			expectErr: true,
+		},
+		{
+			name:      "returns error if the current namespace is empty",
+			client:    badClient,
+			options:   GetKubeconfigsOptions{Kubeconfig: Kubeconfig(kubeconfig)},
+			expectErr: true,
+		},
+		{
+			name:    "returns the available kubeconfigs and an error for workload clusters without kubeconfig",
+			client:  goodClient,
+			options: GetKubeconfigsOptions{Kubeconfig: Kubeconfig(kubeconfig)},
+			want: map[string]string{
+				"foo": "foo-kubeconfig-data",
+			},
+			wantPartialErr: true,
+		},
+		{
+			name:    "returns nothing for namespaces without workload clusters",
+			client:  goodClient,
+			options: GetKubeconfigsOptions{Kubeconfig: Kubeconfig(kubeconfig), Namespace: "ns2"},
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := tt.client.GetKubeconfigs(tt.options)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			if tt.wantPartialErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("bar-kubeconfig"))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+const workloadClusterKubeconfig = `
+apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2EtZGF0YQ==
+    server: https://test-cluster-api:6443
+  name: test1
+contexts:
+- context:
+    cluster: test1
+    user: test1-admin
+  name: test1-admin@test1
+current-context: test1-admin@test1
+kind: Config
+preferences: {}
+users:
+- name: test1-admin
+  user:
+    client-certificate-data: Y2VydC1kYXRh
+    client-key-data: a2V5LWRhdGE=
+`
+
+func Test_renameKubeconfigContext(t *testing.T) {
+	g := NewWithT(t)
+
+	got, err := renameKubeconfigContext(workloadClusterKubeconfig, "foo")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	config, err := clientcmd.Load([]byte(got))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.CurrentContext).To(Equal("foo"))
+	g.Expect(config.Contexts).To(HaveLen(1))
+	g.Expect(config.Contexts).To(HaveKey("foo"))
+	g.Expect(config.Contexts["foo"].Cluster).To(Equal("foo"))
+	g.Expect(config.Contexts["foo"].AuthInfo).To(Equal("foo"))
+	g.Expect(config.Clusters).To(HaveLen(1))
+	g.Expect(config.Clusters).To(HaveKey("foo"))
+	g.Expect(config.Clusters["foo"].Server).To(Equal("https://test-cluster-api:6443"))
+	g.Expect(config.AuthInfos).To(HaveLen(1))
+	g.Expect(config.AuthInfos).To(HaveKey("foo"))
+	g.Expect(config.AuthInfos["foo"].ClientCertificateData).To(Equal([]byte("cert-data")))
+
+	// Fails if the current context does not exist.
+	_, err = renameKubeconfigContext("apiVersion: v1\nkind: Config\ncurrent-context: bar\n", "foo")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_mergeKubeconfig(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+
+	// Merging into a kubeconfig file that does not exist yet creates it.
+	path := filepath.Join(dir, "config")
+	g.Expect(mergeKubeconfig(workloadClusterKubeconfig, path, false)).To(Succeed())
+
+	config, err := clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.CurrentContext).To(Equal("test1-admin@test1"))
+	g.Expect(config.Contexts).To(HaveKey("test1-admin@test1"))
+
+	// Merging into an existing kubeconfig file preserves existing entries and the current context.
+	renamed, err := renameKubeconfigContext(workloadClusterKubeconfig, "foo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mergeKubeconfig(renamed, path, false)).To(Succeed())
+
+	config, err = clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.CurrentContext).To(Equal("test1-admin@test1"))
+	g.Expect(config.Contexts).To(HaveLen(2))
+	g.Expect(config.Contexts).To(HaveKey("test1-admin@test1"))
+	g.Expect(config.Contexts).To(HaveKey("foo"))
+	g.Expect(config.Clusters).To(HaveKey("test1"))
+	g.Expect(config.Clusters).To(HaveKey("foo"))
+
+	// Fails if the kubeconfig file is not valid.
+	invalidPath := filepath.Join(dir, "invalid")
+	g.Expect(os.WriteFile(invalidPath, []byte("not a kubeconfig"), 0600)).To(Succeed())
+	g.Expect(mergeKubeconfig(workloadClusterKubeconfig, invalidPath, false)).ToNot(Succeed())
+}
+
+func Test_mergeKubeconfig_collisions(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config")
+	g.Expect(mergeKubeconfig(workloadClusterKubeconfig, path, false)).To(Succeed())
+	original, err := os.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Changes the server of the workload cluster, so it is possible to check if the existing entries are replaced.
+	config, err := clientcmd.Load([]byte(workloadClusterKubeconfig))
+	g.Expect(err).ToNot(HaveOccurred())
+	config.Clusters["test1"].Server = "https://changed:6443"
+	changed, err := clientcmd.Write(*config)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Fails on name collisions, reporting all the colliding entries and leaving the kubeconfig file unchanged.
+	err = mergeKubeconfig(string(changed), path, false)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring(`cluster "test1"`))
+	g.Expect(err.Error()).To(ContainSubstring(`user "test1-admin"`))
+	g.Expect(err.Error()).To(ContainSubstring(`context "test1-admin@test1"`))
+	current, err := os.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(current).To(Equal(original))
+
+	// Replaces the existing entries if overwrite is set.
+	g.Expect(mergeKubeconfig(string(changed), path, true)).To(Succeed())
+	merged, err := clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(merged.Clusters).To(HaveLen(1))
+	g.Expect(merged.Clusters["test1"].Server).To(Equal("https://changed:6443"))
+}
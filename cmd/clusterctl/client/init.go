@@ -17,14 +17,19 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/cluster-api/util/container"
 )
 
 // NoopProvider determines if a provider passed in should behave as a no-op.
@@ -52,12 +57,91 @@ type InitOptions struct {
 	ControlPlaneProviders []string
 
 	// TargetNamespace defines the namespace where the providers should be deployed. If unspecified, each provider
-	// will be installed in a provider's default namespace.
-	TargetNamespace string
+	// will be installed in a provider's default namespace. The namespace of some of the providers can be set to a
+	// different value using ProviderTargetNamespaces, indexed by provider manifest label (e.g. infrastructure-aws).
+	TargetNamespace          string
+	ProviderTargetNamespaces map[string]string
+
+	// SpecFile is the path of a YAML file with a declarative InitSpec of the providers to add to the management cluster,
+	// their versions and target namespaces, and of the variables for processing their components YAML, e.g. for
+	// reproducible installs; it can't be used together with the options defining providers or TargetNamespace.
+	SpecFile string
+
+	// LocalProviderPaths defines, for some of the providers to add to the management cluster, the path of the components YAML
+	// on the local filesystem that should be used instead of the provider repository defined in the clusterctl configuration.
+	// Paths are indexed by provider manifest label (e.g. infrastructure-aws, bootstrap-kubeadm), and they must adhere to the
+	// same layout defined for local repositories, {basepath}/{provider-label}/{version}/{components.yaml}, with the
+	// metadata.yaml file in the same folder of the components YAML.
+	LocalProviderPaths map[string]string
+
+	// SkipCertManager instructs Init to not install cert-manager, e.g. because cert-manager is managed outside of clusterctl;
+	// when set, Init checks that cert-manager is already installed and working before installing providers.
+	SkipCertManager bool
+
+	// CertManagerVersion pins the cert-manager version to install, if cert-manager is not already installed, overriding
+	// the version defined in the clusterctl configuration or the default version embedded in clusterctl.
+	CertManagerVersion string
+
+	// ExtraLabels and ExtraAnnotations are applied to all the provider components, e.g. for tagging them with owner or
+	// cost-center metadata; keys reserved for clusterctl can't be used.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+
+	// ImageRegistry, if set, replaces the registry of all the images in the provider components, e.g. with the registry
+	// of an internal mirror, preserving the repository path, the tag and the digest of each image; images without an
+	// explicit registry are considered hosted on Docker Hub. The registry of all the images of a provider can be replaced
+	// with a different value using ProviderImageRegistries, indexed by provider manifest label (e.g. infrastructure-aws).
+	// NOTE: the image overrides defined in the clusterctl configuration, if any, are applied before.
+	ImageRegistry           string
+	ProviderImageRegistries map[string]string
+
+	// CertManagerImageRegistry, if set, replaces the registry of the cert-manager images, i.e. the controller, the cainjector
+	// and the webhook images, when installing cert-manager and in the list returned by InitImages, e.g. for air-gapped
+	// installations; ImageRegistry does not apply to cert-manager.
+	// NOTE: the image overrides defined in the clusterctl configuration for cert-manager, if any, are applied before.
+	CertManagerImageRegistry string
+
+	// IncludePrereleases makes prerelease provider versions, e.g. release candidates, eligible when resolving the
+	// latest version or a version range of the providers; this is intended for testing upcoming releases only.
+	IncludePrereleases bool
 
 	// LogUsageInstructions instructs the init command to print the usage instructions in case of first run.
 	LogUsageInstructions bool
 
+	// Concurrency defines the maximum number of providers of the same type installed concurrently, e.g. many
+	// infrastructure providers; providers of different types are always installed in order, i.e. core, bootstrap,
+	// control-plane and then infrastructure. If unspecified, providers are installed one at a time.
+	Concurrency int
+
+	// Timeout defines the maximum duration of the Init operation, including fetching the provider components and
+	// waiting for cert-manager; in-flight requests are interrupted when the timeout expires. Zero means no timeout.
+	Timeout time.Duration
+
+	// WaitForReady instructs Init to wait, after installing the providers, until the provider controllers are available,
+	// the provider CRDs are established and the provider webhooks are serving, for at most WaitForReadyTimeout (5 minutes
+	// if unspecified); a ProvidersNotReadyError with the readiness of each provider is returned if the time expires.
+	WaitForReady        bool
+	WaitForReadyTimeout time.Duration
+
+	// PreInstallHooks and PostInstallHooks are executed, in order, for each provider before creating the provider
+	// components and before adding the provider to the inventory, respectively, after the install hooks declared in the
+	// provider's metadata, if any; a failing hook fails the installation of the provider. Hooks must be idempotent.
+	PreInstallHooks  []InstallHook
+	PostInstallHooks []InstallHook
+
+	// EventFunc, if set, is invoked for streaming the steps of the Init operation, e.g. fetching the components of a
+	// provider, applying them or waiting for the provider to be ready, so the progress can be reported live.
+	// EventFunc is never invoked concurrently, and it is never invoked after Init returns.
+	EventFunc EventFunc
+
+	// ImagesProviderTypes and ImagesProviders restrict the images returned by InitImages and InitImagesDetailed to the
+	// providers of the given types, e.g. InfrastructureProvider, and to the given providers, identified by name (e.g. aws)
+	// or by manifest label (e.g. infrastructure-aws), e.g. for mirroring the images incrementally; a provider is selected
+	// if it matches any of the types or any of the providers. The cert-manager images are selected using "cert-manager" as
+	// a provider. If both are empty, all the images are returned.
+	ImagesProviderTypes []clusterctlv1.ProviderType
+	ImagesProviders     []string
+
 	// SkipTemplateProcess allows for skipping the call to the template processor, including also variable replacement in the component YAML.
 	// NOTE this works only if the rawYaml is a valid yaml by itself, like e.g when using envsubst/the simple processor.
 	skipTemplateProcess bool
@@ -65,10 +149,29 @@ type InitOptions struct {
 
 // Init initializes a management cluster by adding the requested list of providers.
 func (c *clusterctlClient) Init(options InitOptions) ([]Components, error) {
+	return c.InitContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) InitContext(ctx context.Context, options InitOptions) (_ []Components, retErr error) {
 	log := logf.Log
 
+	op := c.startOperation(InitOperation)
+	defer func() { op.done(retErr) }()
+
+	eventFunc, closeEvents := cluster.NewEventStream(options.EventFunc)
+	defer closeEvents()
+	options.EventFunc = eventFunc
+
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	c = c.withContext(ctx)
+
+	if err := c.applyInitSpec(&options); err != nil {
+		return nil, err
+	}
+
 	// gets access to the management cluster
-	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig, EventFunc: options.EventFunc})
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +199,16 @@ func (c *clusterctlClient) Init(options InitOptions) ([]Components, error) {
 		return nil, err
 	}
 
+	// Skips the providers already installed at the requested version, so Init can be executed many times, e.g. by
+	// declarative/GitOps workflows; providers installed at a different version must be upgraded instead.
+	upToDate, err := installer.SkipInstalled()
+	if err != nil {
+		return nil, err
+	}
+	for _, provider := range upToDate {
+		log.Info("Skipping installation, the provider is up to date", "Provider", provider.ManifestLabel(), "Version", provider.Version, "TargetNamespace", provider.Namespace)
+	}
+
 	// Before installing the providers, validates the management cluster resulting by the planned installation. The following checks are performed:
 	// - There should be only one instance of the same provider.
 	// - All the providers must support the same API Version of Cluster API (contract)
@@ -104,15 +217,39 @@ func (c *clusterctlClient) Init(options InitOptions) ([]Components, error) {
 	}
 
 	// Before installing the providers, ensure the cert-manager Webhook is in place.
-	certManager := clusterClient.CertManager()
-	if err := certManager.EnsureInstalled(); err != nil {
-		return nil, err
+	certManager := clusterClient.CertManager(options.certManagerOptions()...)
+	if options.SkipCertManager {
+		if err := certManager.CheckReady(); err != nil {
+			return nil, err
+		}
+	} else {
+		options.EventFunc.Notify(cluster.InstallingCertManagerEvent, "", "Ensuring cert-manager is installed")
+		if err := certManager.EnsureInstalled(); err != nil {
+			return nil, err
+		}
 	}
 
-	components, err := installer.Install()
+	components, err := installer.Install(
+		cluster.WithInstallConcurrency(options.Concurrency),
+		cluster.WithPreInstallHooks(options.PreInstallHooks...),
+		cluster.WithPostInstallHooks(options.PostInstallHooks...),
+	)
 	if err != nil {
 		return nil, err
 	}
+	for _, comp := range components {
+		op.providers = append(op.providers, comp.ManifestLabel())
+	}
+
+	if options.WaitForReady {
+		providers := make([]clusterctlv1.Provider, 0, len(components))
+		for _, comp := range components {
+			providers = append(providers, comp.InventoryObject())
+		}
+		if err := waitForProvidersReady(ctx, clusterClient, providers, options.WaitForReadyTimeout, options.EventFunc); err != nil {
+			return nil, err
+		}
+	}
 
 	// If this is the firstRun, then log the usage instructions.
 	if firstRun && options.LogUsageInstructions {
@@ -133,17 +270,177 @@ func (c *clusterctlClient) Init(options InitOptions) ([]Components, error) {
 	return aliasComponents, nil
 }
 
-// Init returns the list of images required for init.
+// ImageReference describes a container image required for executing the init command.
+type ImageReference struct {
+	// Provider is the name of the provider requiring the image, or "cert-manager" for the images required for installing the cert-manager.
+	Provider string
+
+	// ProviderType is the type of the provider requiring the image; it is empty for the images required for installing the cert-manager.
+	ProviderType clusterctlv1.ProviderType
+
+	// Repository is the image repository, including the image name (e.g. k8s.gcr.io/cluster-api/cluster-api-controller).
+	Repository string
+
+	// Tag is the image tag, if any.
+	Tag string
+
+	// Digest is the image digest, if any.
+	Digest string
+
+	// Source is the URL of the manifest the image is read from.
+	Source string
+}
+
+// String returns the image in the same format used in the manifest (e.g. repository:tag).
+func (r ImageReference) String() string {
+	ref := r.Repository
+	if r.Tag != "" {
+		ref = fmt.Sprintf("%s:%s", ref, r.Tag)
+	}
+	if r.Digest != "" {
+		ref = fmt.Sprintf("%s@%s", ref, r.Digest)
+	}
+	return ref
+}
+
+// InitImages returns the list of images required for init.
 func (c *clusterctlClient) InitImages(options InitOptions) ([]string, error) {
+	clusterClient, installer, err := c.setupInitImages(options)
+	if err != nil {
+		return nil, err
+	}
+	return initImages(clusterClient, installer, options)
+}
+
+// initImages returns the list of images required for installing cert-manager and the providers in the install queue.
+func initImages(clusterClient cluster.Client, installer cluster.ProviderInstaller, options InitOptions) ([]string, error) {
+	// Gets the list of container images required for the cert-manager (if not already installed, and if not managed outside of clusterctl).
+	images := sets.NewString()
+	if !options.SkipCertManager && options.selectsImagesOf(certManagerImagesProvider, "", certManagerImagesProvider) {
+		certManagerImages, err := clusterClient.CertManager(options.certManagerOptions()...).Images()
+		if err != nil {
+			return nil, err
+		}
+		images.Insert(certManagerImages...)
+	}
+
+	// Appends the list of container images required for the selected providers.
+	for _, components := range installer.InstallQueue() {
+		if options.selectsImagesOf(components.Name(), components.Type(), components.ManifestLabel()) {
+			images.Insert(components.Images()...)
+		}
+	}
+
+	return images.List(), nil
+}
+
+// InitImagesDetailed returns the list of images required for init, with the details about the provider requiring each image.
+func (c *clusterctlClient) InitImagesDetailed(options InitOptions) ([]ImageReference, error) {
+	clusterClient, installer, err := c.setupInitImages(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []ImageReference
+
+	// Gets the list of container images required for the cert-manager (if not already installed, and if not managed outside of clusterctl).
+	var certManagerImages []string
+	if !options.SkipCertManager && options.selectsImagesOf(certManagerImagesProvider, "", certManagerImagesProvider) {
+		certManagerImages, err = clusterClient.CertManager(options.certManagerOptions()...).Images()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(certManagerImages) > 0 {
+		certManagerConfig, err := c.configClient.CertManager().Get()
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range certManagerImages {
+			ref, err := newImageReference(image, certManagerImagesProvider, "", certManagerConfig.URL())
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+
+	// Appends the list of container images required for the selected providers.
+	for _, components := range installer.InstallQueue() {
+		if !options.selectsImagesOf(components.Name(), components.Type(), components.ManifestLabel()) {
+			continue
+		}
+		for _, image := range components.Images() {
+			ref, err := newImageReference(image, components.Name(), components.Type(), components.URL())
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].String() != refs[j].String() {
+			return refs[i].String() < refs[j].String()
+		}
+		return refs[i].Provider < refs[j].Provider
+	})
+	return refs, nil
+}
+
+// certManagerOptions returns the options for installing cert-manager.
+func (o InitOptions) certManagerOptions() []cluster.CertManagerOption {
+	return []cluster.CertManagerOption{
+		cluster.WithCertManagerVersion(o.CertManagerVersion),
+		cluster.WithCertManagerImageRegistry(o.CertManagerImageRegistry),
+	}
+}
+
+// certManagerImagesProvider is the provider name used for the images required for installing the cert-manager.
+const certManagerImagesProvider = "cert-manager"
+
+// selectsImagesOf returns true if the images of a provider are selected by the ImagesProviderTypes and the ImagesProviders.
+func (o InitOptions) selectsImagesOf(name string, providerType clusterctlv1.ProviderType, manifestLabel string) bool {
+	if len(o.ImagesProviderTypes) == 0 && len(o.ImagesProviders) == 0 {
+		return true
+	}
+	for _, t := range o.ImagesProviderTypes {
+		if t == providerType {
+			return true
+		}
+	}
+	for _, p := range o.ImagesProviders {
+		if p == name || p == manifestLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// setupInitImages gets access to the management cluster and creates an installer service with the requested providers
+// in the install queue, without processing the component YAML templates.
+func (c *clusterctlClient) setupInitImages(options InitOptions) (cluster.Client, cluster.ProviderInstaller, error) {
+	if err := c.applyInitSpec(&options); err != nil {
+		return nil, nil, err
+	}
+
+	for _, t := range options.ImagesProviderTypes {
+		switch t {
+		case clusterctlv1.CoreProviderType, clusterctlv1.BootstrapProviderType, clusterctlv1.ControlPlaneProviderType, clusterctlv1.InfrastructureProviderType:
+		default:
+			return nil, nil, errors.Errorf("invalid provider type %q for filtering the images", t)
+		}
+	}
+
 	// gets access to the management cluster
 	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Ensure this command only runs against empty management clusters or v1alpha4 management clusters.
 	if err := clusterClient.ProviderInventory().CheckCAPIContract(cluster.AllowCAPINotInstalled{}); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// checks if the cluster already contains a Core provider.
@@ -158,30 +455,46 @@ func (c *clusterctlClient) InitImages(options InitOptions) ([]string, error) {
 	// of the target state of the management cluster before starting the installation.
 	installer, err := c.setupInstaller(clusterClient, options)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return clusterClient, installer, nil
+}
 
-	// Gets the list of container images required for the cert-manager (if not already installed).
-	certManager := clusterClient.CertManager()
-	images, err := certManager.Images()
+func newImageReference(image, provider string, providerType clusterctlv1.ProviderType, source string) (ImageReference, error) {
+	img, err := container.ImageFromString(image)
 	if err != nil {
-		return nil, err
+		return ImageReference{}, errors.Wrapf(err, "failed to parse image %q required by %q", image, provider)
 	}
 
-	// Appends the list of container images required for the selected providers.
-	images = append(images, installer.Images()...)
-
-	sort.Strings(images)
-	return images, nil
+	repo := img.Name
+	if img.Repository != "" {
+		repo = fmt.Sprintf("%s/%s", img.Repository, img.Name)
+	}
+	return ImageReference{
+		Provider:     provider,
+		ProviderType: providerType,
+		Repository:   repo,
+		Tag:          img.Tag,
+		Digest:       img.Digest,
+		Source:       source,
+	}, nil
 }
 
 func (c *clusterctlClient) setupInstaller(cluster cluster.Client, options InitOptions) (cluster.ProviderInstaller, error) {
 	installer := cluster.ProviderInstaller()
 
 	addOptions := addToInstallerOptions{
-		installer:           installer,
-		targetNamespace:     options.TargetNamespace,
-		skipTemplateProcess: options.skipTemplateProcess,
+		installer:                installer,
+		targetNamespace:          options.TargetNamespace,
+		providerTargetNamespaces: options.ProviderTargetNamespaces,
+		skipTemplateProcess:      options.skipTemplateProcess,
+		localProviderPaths:       options.LocalProviderPaths,
+		extraLabels:              options.ExtraLabels,
+		extraAnnotations:         options.ExtraAnnotations,
+		imageRegistry:            options.ImageRegistry,
+		providerImageRegistries:  options.ProviderImageRegistries,
+		includePrereleases:       options.IncludePrereleases,
+		eventFunc:                options.EventFunc,
 	}
 
 	if options.CoreProvider != "" {
@@ -230,9 +543,17 @@ func (c *clusterctlClient) addDefaultProviders(cluster cluster.Client, options *
 }
 
 type addToInstallerOptions struct {
-	installer           cluster.ProviderInstaller
-	targetNamespace     string
-	skipTemplateProcess bool
+	installer                cluster.ProviderInstaller
+	targetNamespace          string
+	providerTargetNamespaces map[string]string
+	skipTemplateProcess      bool
+	localProviderPaths       map[string]string
+	extraLabels              map[string]string
+	extraAnnotations         map[string]string
+	imageRegistry            string
+	providerImageRegistries  map[string]string
+	includePrereleases       bool
+	eventFunc                cluster.EventFunc
 }
 
 // addToInstaller adds the components to the install queue and checks that the actual provider type match the target group.
@@ -245,11 +566,29 @@ func (c *clusterctlClient) addToInstaller(options addToInstallerOptions, provide
 			}
 			continue
 		}
+		name, _, err := parseProviderName(provider)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get provider components for the %q provider", provider)
+		}
+		imageRegistry := options.imageRegistry
+		if registry, ok := options.providerImageRegistries[clusterctlv1.ManifestLabel(name, providerType)]; ok {
+			imageRegistry = registry
+		}
+		targetNamespace := options.targetNamespace
+		if namespace, ok := options.providerTargetNamespaces[clusterctlv1.ManifestLabel(name, providerType)]; ok {
+			targetNamespace = namespace
+		}
+
 		componentsOptions := repository.ComponentsOptions{
-			TargetNamespace:     options.targetNamespace,
+			TargetNamespace:     targetNamespace,
 			SkipTemplateProcess: options.skipTemplateProcess,
+			ExtraLabels:         options.extraLabels,
+			ExtraAnnotations:    options.extraAnnotations,
+			ImageRegistry:       imageRegistry,
+			IncludePrereleases:  options.includePrereleases,
 		}
-		components, err := c.getComponentsByName(provider, providerType, componentsOptions)
+		options.eventFunc.Notify(cluster.FetchingComponentsEvent, clusterctlv1.ManifestLabel(name, providerType), "Fetching the components of %s", provider)
+		components, err := c.getComponentsByName(provider, providerType, componentsOptions, options.localProviderPaths)
 		if err != nil {
 			return errors.Wrapf(err, "failed to get provider components for the %q provider", provider)
 		}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/yaml"
+)
+
+// InitSpec is a declarative specification of the providers to add to a management cluster with Init, e.g.
+//
+//	targetNamespace: capi-providers
+//	providers:
+//	- name: cluster-api
+//	  type: CoreProvider
+//	  version: v0.4.0
+//	- name: aws
+//	  type: InfrastructureProvider
+//	  targetNamespace: capa-system
+//	variables:
+//	  AWS_B64ENCODED_CREDENTIALS: ...
+type InitSpec struct {
+	// TargetNamespace defines the namespace where the providers should be deployed, unless a provider defines its own.
+	// If unspecified, each provider will be installed in a provider's default namespace.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// Providers to add to the management cluster. As for Init, the default core, bootstrap and control-plane providers
+	// are added if the management cluster does not have a core provider yet and none of the given type is listed.
+	Providers []InitSpecProvider `json:"providers"`
+
+	// Variables are used for processing the components YAML of the providers, overriding the values defined in the
+	// environment and in the clusterctl configuration file.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// InitSpecProvider defines a provider to add to the management cluster.
+type InitSpecProvider struct {
+	// Name of the provider, as defined in the clusterctl configuration (e.g. aws).
+	Name string `json:"name"`
+
+	// Type of the provider, e.g. InfrastructureProvider.
+	Type clusterctlv1.ProviderType `json:"type"`
+
+	// Version of the provider (e.g. v0.5.0). If unspecified, the provider's latest release is used.
+	Version string `json:"version,omitempty"`
+
+	// TargetNamespace defines the namespace where the provider should be deployed, overriding InitSpec.TargetNamespace.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+}
+
+// readInitSpec reads an InitSpec from a YAML or JSON file; unknown fields are rejected.
+func readInitSpec(path string) (*InitSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the init spec file %q", path)
+	}
+
+	spec := &InitSpec{}
+	if err := yaml.UnmarshalStrict(content, spec); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the init spec file %q", path)
+	}
+	return spec, nil
+}
+
+// validateInitSpec checks that the providers in the spec are well formed, are defined in the clusterctl configuration or in
+// the local provider paths, and are listed only once.
+func (c *clusterctlClient) validateInitSpec(spec *InitSpec, localProviderPaths map[string]string) error {
+	if len(spec.Providers) == 0 {
+		return errors.New("invalid init spec: at least one provider must be defined")
+	}
+	if spec.TargetNamespace != "" {
+		if err := validateDNS1123Label(spec.TargetNamespace); err != nil {
+			return errors.Wrapf(err, "invalid init spec: invalid target namespace %q", spec.TargetNamespace)
+		}
+	}
+
+	errList := []error{}
+	manifestLabels := sets.NewString()
+	coreProviders := 0
+	for i, p := range spec.Providers {
+		switch p.Type {
+		case clusterctlv1.CoreProviderType:
+			coreProviders++
+		case clusterctlv1.BootstrapProviderType, clusterctlv1.ControlPlaneProviderType, clusterctlv1.InfrastructureProviderType:
+		default:
+			errList = append(errList, errors.Errorf("providers[%d]: invalid provider type %q", i, p.Type))
+			continue
+		}
+
+		if _, _, err := parseProviderName(p.reference()); err != nil {
+			errList = append(errList, errors.Wrapf(err, "providers[%d]", i))
+			continue
+		}
+		if p.TargetNamespace != "" {
+			if err := validateDNS1123Label(p.TargetNamespace); err != nil {
+				errList = append(errList, errors.Wrapf(err, "providers[%d]: invalid target namespace %q", i, p.TargetNamespace))
+			}
+		}
+
+		manifestLabel := clusterctlv1.ManifestLabel(p.Name, p.Type)
+		if manifestLabels.Has(manifestLabel) {
+			errList = append(errList, errors.Errorf("providers[%d]: the %s provider is defined more than once", i, manifestLabel))
+			continue
+		}
+		manifestLabels.Insert(manifestLabel)
+
+		if _, ok := localProviderPaths[manifestLabel]; ok {
+			continue
+		}
+		if _, err := c.configClient.Providers().Get(p.Name, p.Type); err != nil {
+			errList = append(errList, errors.Wrapf(err, "providers[%d]: the %s provider is not defined in the clusterctl configuration", i, manifestLabel))
+		}
+	}
+	if coreProviders > 1 {
+		errList = append(errList, errors.New("only one core provider can be defined"))
+	}
+
+	if err := kerrors.NewAggregate(errList); err != nil {
+		return errors.Wrap(err, "invalid init spec")
+	}
+	return nil
+}
+
+// reference returns the provider in the name[:version] format used by InitOptions.
+func (p InitSpecProvider) reference() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s:%s", p.Name, p.Version)
+}
+
+// applyInitSpec reads the spec file, if any, and sets the providers, the target namespaces and the variables defined
+// in the spec, so Init behaves as if they were passed as options.
+func (c *clusterctlClient) applyInitSpec(options *InitOptions) error {
+	if options.SpecFile == "" {
+		return nil
+	}
+	if options.CoreProvider != "" || len(options.BootstrapProviders) > 0 || len(options.ControlPlaneProviders) > 0 ||
+		len(options.InfrastructureProviders) > 0 || options.TargetNamespace != "" {
+		return errors.New("the SpecFile option can't be used together with the options defining providers or the target namespace")
+	}
+
+	spec, err := readInitSpec(options.SpecFile)
+	if err != nil {
+		return err
+	}
+	if err := c.validateInitSpec(spec, options.LocalProviderPaths); err != nil {
+		return err
+	}
+
+	// NB. The target namespaces are copied, so the map passed by the caller is not modified.
+	targetNamespaces := make(map[string]string, len(options.ProviderTargetNamespaces))
+	for k, v := range options.ProviderTargetNamespaces {
+		targetNamespaces[k] = v
+	}
+
+	options.TargetNamespace = spec.TargetNamespace
+	for _, p := range spec.Providers {
+		switch p.Type {
+		case clusterctlv1.CoreProviderType:
+			options.CoreProvider = p.reference()
+		case clusterctlv1.BootstrapProviderType:
+			options.BootstrapProviders = append(options.BootstrapProviders, p.reference())
+		case clusterctlv1.ControlPlaneProviderType:
+			options.ControlPlaneProviders = append(options.ControlPlaneProviders, p.reference())
+		case clusterctlv1.InfrastructureProviderType:
+			options.InfrastructureProviders = append(options.InfrastructureProviders, p.reference())
+		}
+
+		if p.TargetNamespace != "" {
+			targetNamespaces[clusterctlv1.ManifestLabel(p.Name, p.Type)] = p.TargetNamespace
+		}
+	}
+	options.ProviderTargetNamespaces = targetNamespaces
+
+	for name, value := range spec.Variables {
+		c.configClient.Variables().Set(name, value)
+	}
+	return nil
+}
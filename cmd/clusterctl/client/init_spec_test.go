@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+)
+
+func Test_clusterctlClient_Init_withSpecFile(t *testing.T) {
+	newClient := func() *fakeClient {
+		fconfig := newFakeConfig().
+			WithProvider(capiProviderConfig).
+			WithProvider(bootstrapProviderConfig).
+			WithProvider(controlPlaneProviderConfig).
+			WithProvider(infraProviderConfig)
+		frepositories := fakeRepositories(fconfig, nil)
+		fcluster := fakeCluster(fconfig, frepositories, newFakeCertManagerClient(nil, nil))
+		return fakeClusterCtlClient(fconfig, frepositories, []*fakeClusterClient{fcluster})
+	}
+	writeSpec := func(g *WithT, content string) string {
+		path := filepath.Join(t.TempDir(), "init.yaml")
+		g.Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+	inventory := func(components []Components) []clusterctlv1.Provider {
+		providers := []clusterctlv1.Provider{}
+		for _, c := range components {
+			p := c.InventoryObject()
+			providers = append(providers, clusterctlv1.Provider{
+				ObjectMeta:   p.ObjectMeta,
+				ProviderName: p.ProviderName,
+				Type:         p.Type,
+				Version:      p.Version,
+			})
+		}
+		return providers
+	}
+
+	t.Run("installs the providers defined in the spec as the equivalent options", func(t *testing.T) {
+		g := NewWithT(t)
+
+		path := writeSpec(g, `
+targetNamespace: capi-providers
+providers:
+- name: cluster-api
+  type: CoreProvider
+  version: v1.0.0
+- name: infra
+  type: InfrastructureProvider
+  version: v3.1.0
+  targetNamespace: capi-infra
+variables:
+  SOME_VARIABLE: from-spec
+`)
+		fromSpec, err := newClient().Init(InitOptions{
+			Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+			SpecFile:   path,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		fromOptionsClient := newClient()
+		fromOptionsClient.configClient.Variables().Set("SOME_VARIABLE", "from-spec")
+		fromOptions, err := fromOptionsClient.Init(InitOptions{
+			Kubeconfig:               Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+			CoreProvider:             "cluster-api:v1.0.0",
+			InfrastructureProviders:  []string{"infra:v3.1.0"},
+			TargetNamespace:          "capi-providers",
+			ProviderTargetNamespaces: map[string]string{"infrastructure-infra": "capi-infra"},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(inventory(fromSpec)).To(Equal(inventory(fromOptions)))
+
+		// The default bootstrap and control-plane providers are added on the first run.
+		g.Expect(fromSpec).To(HaveLen(4))
+		for _, c := range fromSpec {
+			switch c.ManifestLabel() {
+			case "infrastructure-infra":
+				g.Expect(c.Version()).To(Equal("v3.1.0"))
+				g.Expect(c.TargetNamespace()).To(Equal("capi-infra"))
+				for _, o := range c.Objs() {
+					if o.GetKind() != "Deployment" {
+						continue
+					}
+					volumes, _, _ := unstructured.NestedSlice(o.Object, "spec", "template", "spec", "volumes")
+					g.Expect(volumes).To(HaveLen(1))
+					secretName, _, _ := unstructured.NestedString(volumes[0].(map[string]interface{}), "secret", "secretName")
+					g.Expect(secretName).To(Equal("from-spec"))
+				}
+			default:
+				g.Expect(c.TargetNamespace()).To(Equal("capi-providers"))
+			}
+		}
+	})
+
+	tests := []struct {
+		name    string
+		spec    string
+		options InitOptions
+	}{
+		{
+			name: "fails if the spec does not define providers",
+			spec: "targetNamespace: capi-providers\n",
+		},
+		{
+			name: "fails if the spec has unknown fields",
+			spec: "providers:\n- name: infra\n  type: InfrastructureProvider\n  namespace: capi-infra\n",
+		},
+		{
+			name: "fails if a provider has an invalid type",
+			spec: "providers:\n- name: infra\n  type: infrastructure\n",
+		},
+		{
+			name: "fails if a provider is not defined in the clusterctl configuration",
+			spec: "providers:\n- name: aws\n  type: InfrastructureProvider\n",
+		},
+		{
+			name: "fails if a provider is defined more than once",
+			spec: "providers:\n- name: infra\n  type: InfrastructureProvider\n- name: infra\n  type: InfrastructureProvider\n  version: v3.1.0\n",
+		},
+		{
+			name: "fails if a provider has an invalid target namespace",
+			spec: "providers:\n- name: infra\n  type: InfrastructureProvider\n  targetNamespace: Capi_Infra\n",
+		},
+		{
+			name:    "fails if the spec is used together with the options defining providers",
+			spec:    "providers:\n- name: infra\n  type: InfrastructureProvider\n",
+			options: InitOptions{BootstrapProviders: []string{"kubeadm"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			options := tt.options
+			options.Kubeconfig = Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+			options.SpecFile = writeSpec(g, tt.spec)
+
+			_, err := newClient().Init(options)
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
@@ -41,11 +42,15 @@ func Test_clusterctlClient_InitImages(t *testing.T) {
 	}
 
 	type args struct {
-		kubeconfigContext      string
-		coreProvider           string
-		bootstrapProvider      []string
-		controlPlaneProvider   []string
-		infrastructureProvider []string
+		kubeconfigContext       string
+		coreProvider            string
+		bootstrapProvider       []string
+		controlPlaneProvider    []string
+		infrastructureProvider  []string
+		imageRegistry           string
+		providerImageRegistries map[string]string
+		imagesProviderTypes     []clusterctlv1.ProviderType
+		imagesProviders         []string
 	}
 
 	tests := []struct {
@@ -84,6 +89,31 @@ func Test_clusterctlClient_InitImages(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "returns list of images with the image registry override",
+			args: args{
+				infrastructureProvider: []string{"infra"},
+				kubeconfigContext:      "mgmt-context",
+				imageRegistry:          "mirror.example.com",
+			},
+			expectedImages: []string{
+				"mirror.example.com/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns list of images with the provider image registry override",
+			args: args{
+				infrastructureProvider:  []string{"infra"},
+				kubeconfigContext:       "mgmt-context",
+				imageRegistry:           "mirror.example.com",
+				providerImageRegistries: map[string]string{"infrastructure-infra": "infra-mirror.example.com:5000"},
+			},
+			expectedImages: []string{
+				"infra-mirror.example.com:5000/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+			},
+			wantErr: false,
+		},
 		{
 			name: "returns error when core provider name is invalid",
 			args: args{
@@ -150,6 +180,63 @@ func Test_clusterctlClient_InitImages(t *testing.T) {
 				"some.registry.com/cert-image-2:some-tag",
 			},
 		},
+		{
+			name: "returns only the images of the selected provider types",
+			args: args{
+				infrastructureProvider: []string{"infra"},
+				kubeconfigContext:      "mgmt-context",
+				imagesProviderTypes:    []clusterctlv1.ProviderType{clusterctlv1.InfrastructureProviderType},
+			},
+			certManagerImages: []string{
+				"some.registry.com/cert-image-1:latest",
+			},
+			expectedImages: []string{
+				"k8s.gcr.io/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns only the images of the selected providers",
+			args: args{
+				infrastructureProvider: []string{"infra"},
+				kubeconfigContext:      "mgmt-context",
+				imagesProviders:        []string{"cert-manager"},
+			},
+			certManagerImages: []string{
+				"some.registry.com/cert-image-1:latest",
+			},
+			expectedImages: []string{
+				"some.registry.com/cert-image-1:latest",
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns the images of the providers matching any of the filters",
+			args: args{
+				infrastructureProvider: []string{"infra"},
+				kubeconfigContext:      "mgmt-context",
+				imagesProviderTypes:    []clusterctlv1.ProviderType{clusterctlv1.BootstrapProviderType},
+				imagesProviders:        []string{"infrastructure-infra", "cert-manager"},
+			},
+			certManagerImages: []string{
+				"some.registry.com/cert-image-1:latest",
+			},
+			expectedImages: []string{
+				"k8s.gcr.io/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+				"some.registry.com/cert-image-1:latest",
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns error when filtering by an invalid provider type",
+			args: args{
+				infrastructureProvider: []string{"infra"},
+				kubeconfigContext:      "mgmt-context",
+				imagesProviderTypes:    []clusterctlv1.ProviderType{"FooProvider"},
+			},
+			wantErr:              true,
+			expectedErrorMessage: "invalid provider type",
+		},
 		{
 			name: "returns error when cert-manager client cannot retrieve the image list",
 			args: args{
@@ -175,6 +262,10 @@ func Test_clusterctlClient_InitImages(t *testing.T) {
 				BootstrapProviders:      tt.args.bootstrapProvider,
 				ControlPlaneProviders:   tt.args.controlPlaneProvider,
 				InfrastructureProviders: tt.args.infrastructureProvider,
+				ImageRegistry:           tt.args.imageRegistry,
+				ProviderImageRegistries: tt.args.providerImageRegistries,
+				ImagesProviderTypes:     tt.args.imagesProviderTypes,
+				ImagesProviders:         tt.args.imagesProviders,
 			})
 
 			if tt.wantErr {
@@ -192,6 +283,73 @@ func Test_clusterctlClient_InitImages(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_InitImagesDetailed(t *testing.T) {
+	tests := []struct {
+		name                   string
+		kubeconfigContext      string
+		infrastructureProvider []string
+		certManagerImages      []string
+		want                   []ImageReference
+		wantErr                bool
+	}{
+		{
+			name:              "returns error if cannot find cluster client",
+			kubeconfigContext: "does-not-exist",
+			wantErr:           true,
+		},
+		{
+			name:                   "returns the images with the provider they come from",
+			kubeconfigContext:      "mgmt-context",
+			infrastructureProvider: []string{"infra"},
+			certManagerImages: []string{
+				"some.registry.com/cert-image-1:latest",
+			},
+			want: []ImageReference{
+				{
+					Provider:     "infra",
+					ProviderType: clusterctlv1.InfrastructureProviderType,
+					Repository:   "k8s.gcr.io/cluster-api-aws/cluster-api-aws-controller",
+					Tag:          "v0.5.3",
+					Source:       "url",
+				},
+				{
+					Provider:   "cert-manager",
+					Repository: "some.registry.com/cert-image-1",
+					Tag:        "latest",
+					Source:     config.CertManagerDefaultURL,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, fc := setupCluster(nil, newFakeCertManagerClient(tt.certManagerImages, nil))
+
+			got, err := fc.InitImagesDetailed(InitOptions{
+				Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: tt.kubeconfigContext},
+				InfrastructureProviders: tt.infrastructureProvider,
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_ImageReference_String(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ImageReference{Repository: "k8s.gcr.io/foo"}.String()).To(Equal("k8s.gcr.io/foo"))
+	g.Expect(ImageReference{Repository: "k8s.gcr.io/foo", Tag: "v1.0.0"}.String()).To(Equal("k8s.gcr.io/foo:v1.0.0"))
+	g.Expect(ImageReference{Repository: "k8s.gcr.io/foo", Tag: "v1.0.0", Digest: "sha256:abc"}.String()).To(Equal("k8s.gcr.io/foo:v1.0.0@sha256:abc"))
+}
+
 func Test_clusterctlClient_Init(t *testing.T) {
 	// create a config variables client which does not have the value for
 	// SOME_VARIABLE as expected in the infra components YAML
@@ -404,6 +562,40 @@ func Test_clusterctlClient_Init(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Init (with a NOT empty cluster) skips the providers already installed at the requested version",
+			field: field{
+				client: fakeInitializedClusterWithInfra("v3.0.0"), // clusterctl client for an management cluster with capi and infra installed
+				hasCRD: true,
+			},
+			args: args{
+				coreProvider:           "", // with a NOT empty cluster, a core provider should NOT be added automatically
+				bootstrapProvider:      []string{config.KubeadmBootstrapProviderName},
+				infrastructureProvider: []string{"infra"},
+				targetNameSpace:        "",
+			},
+			want: []want{
+				{
+					provider:        bootstrapProviderConfig,
+					version:         "v2.0.0",
+					targetNamespace: "ns2",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Init (with a NOT empty cluster) fails when a provider is already installed at a different version",
+			field: field{
+				client: fakeInitializedClusterWithInfra("v3.0.0"), // clusterctl client for an management cluster with capi and infra installed
+				hasCRD: true,
+			},
+			args: args{
+				coreProvider:           "", // with a NOT empty cluster, a core provider should NOT be added automatically
+				infrastructureProvider: []string{"infra:v3.1.0"},
+				targetNameSpace:        "",
+			},
+			wantErr: true,
+		},
 		{
 			name: "Fails when opting out from coreProvider automatic installation",
 			field: field{
@@ -552,6 +744,119 @@ var (
 )
 
 // setup a cluster client and the fake configuration for testing.
+func Test_clusterctlClient_Init_withSkipCertManager(t *testing.T) {
+	tests := []struct {
+		name            string
+		skipCertManager bool
+		checkReadyError error
+		wantErr         bool
+	}{
+		{
+			name:            "installs cert-manager if SkipCertManager is not set",
+			skipCertManager: false,
+		},
+		{
+			name:            "does not install cert-manager if SkipCertManager is set",
+			skipCertManager: true,
+		},
+		{
+			name:            "returns an error if SkipCertManager is set and cert-manager is not working",
+			skipCertManager: true,
+			checkReadyError: errors.New("cert-manager is not installed"),
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fconfig := newFakeConfig().
+				WithVar("SOME_VARIABLE", "value").
+				WithProvider(capiProviderConfig).
+				WithProvider(infraProviderConfig)
+			frepositories := fakeRepositories(fconfig, nil)
+			certManager := newFakeCertManagerClient(nil, nil)
+			certManager.checkReadyError = tt.checkReadyError
+			fcluster := fakeCluster(fconfig, frepositories, certManager)
+			fclient := fakeClusterCtlClient(fconfig, frepositories, []*fakeClusterClient{fcluster})
+
+			_, err := fclient.Init(InitOptions{
+				Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				InfrastructureProviders: []string{"infra"},
+				SkipCertManager:         tt.skipCertManager,
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(certManager.ensureInstalledCalled).To(Equal(!tt.skipCertManager))
+		})
+	}
+}
+
+func Test_clusterctlClient_Init_withEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	fconfig := newFakeConfig().
+		WithVar("SOME_VARIABLE", "value").
+		WithProvider(capiProviderConfig).
+		WithProvider(infraProviderConfig)
+	frepositories := fakeRepositories(fconfig, nil)
+	fcluster := fakeCluster(fconfig, frepositories, newFakeCertManagerClient(nil, nil))
+	fclient := fakeClusterCtlClient(fconfig, frepositories, []*fakeClusterClient{fcluster})
+
+	events := map[EventType][]string{}
+	_, err := fclient.Init(InitOptions{
+		Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+		InfrastructureProviders: []string{"infra"},
+		EventFunc: func(event Event) {
+			events[event.Type] = append(events[event.Type], event.Provider)
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events[FetchingComponentsEvent]).To(ContainElements("cluster-api", "infrastructure-infra"))
+	g.Expect(events[InstallingCertManagerEvent]).To(HaveLen(1))
+}
+
+func Test_clusterctlClient_Init_withExtraMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	fconfig := newFakeConfig().
+		WithVar("SOME_VARIABLE", "value").
+		WithProvider(capiProviderConfig).
+		WithProvider(infraProviderConfig)
+	frepositories := fakeRepositories(fconfig, nil)
+	fcluster := fakeCluster(fconfig, frepositories, newFakeCertManagerClient(nil, nil))
+	fclient := fakeClusterCtlClient(fconfig, frepositories, []*fakeClusterClient{fcluster})
+
+	got, err := fclient.Init(InitOptions{
+		Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+		InfrastructureProviders: []string{"infra"},
+		ExtraLabels:             map[string]string{"cost-center": "123"},
+		ExtraAnnotations:        map[string]string{"owner": "team-a"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).NotTo(BeEmpty())
+
+	for _, components := range got {
+		for _, o := range components.Objs() {
+			g.Expect(o.GetLabels()).To(HaveKeyWithValue("cost-center", "123"))
+			g.Expect(o.GetLabels()).To(HaveKeyWithValue(clusterv1.ProviderLabelName, components.ManifestLabel()))
+			g.Expect(o.GetLabels()).To(HaveKey(clusterctlv1.ClusterctlLabelName))
+			g.Expect(o.GetAnnotations()).To(HaveKeyWithValue("owner", "team-a"))
+		}
+	}
+
+	_, err = fclient.Init(InitOptions{
+		Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+		InfrastructureProviders: []string{"infra"},
+		ExtraLabels:             map[string]string{clusterv1.ProviderLabelName: "foo"},
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
 func setupCluster(providers []Provider, certManagerClient cluster.CertManagerClient) (*fakeConfigClient, *fakeClient) {
 	// create a config variables client which does not have the value for
 	// SOME_VARIABLE as expected in the infra components YAML
@@ -753,6 +1058,21 @@ func fakeInitializedCluster() *fakeClient {
 	return client
 }
 
+func fakeInitializedClusterWithInfra(version string) *fakeClient {
+	client := fakeInitializedCluster()
+
+	input := cluster.Kubeconfig{
+		Path:    "kubeconfig",
+		Context: "mgmt-context",
+	}
+	p := client.clusters[input].Proxy()
+	fp := p.(*test.FakeProxy)
+
+	fp.WithProviderInventory(infraProviderConfig.Name(), infraProviderConfig.Type(), version, "ns4")
+
+	return client
+}
+
 func componentsYAML(ns string) []byte {
 	var namespaceYaml = []byte("apiVersion: v1\n" +
 		"kind: Namespace\n" +
@@ -777,6 +1097,19 @@ func templateYAML(ns string, clusterName string) []byte {
 	return podYaml
 }
 
+// addonTemplateYAML defines the same Cluster of templateYAML plus a ConfigMap, so it can be used
+// to test merging multiple flavors.
+func addonTemplateYAML(ns string, clusterName string) []byte {
+	var addonYaml = []byte("---\n" +
+		"apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"metadata:\n" +
+		fmt.Sprintf("  name: %s-addon\n", clusterName) +
+		fmt.Sprintf("  namespace: %s", ns))
+
+	return append(templateYAML(ns, clusterName), append([]byte("\n"), addonYaml...)...)
+}
+
 // infraComponentsYAML defines a namespace and deployment with container
 // images and a variable.
 func infraComponentsYAML(namespace string) []byte {
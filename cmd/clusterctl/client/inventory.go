@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrInventoryNotInstalled is returned by GetInstalledProviders and CheckVersionSkew when the clusterctl inventory CRD is not installed
+// in the management cluster, e.g. because clusterctl init was never run against it.
+var ErrInventoryNotInstalled = errors.New("the clusterctl inventory CRD is not installed in the management cluster")
+
+// GetInstalledProvidersOptions carries the options supported by GetInstalledProviders.
+type GetInstalledProvidersOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace restricts the inventory to the provider instances installed in a namespace. If empty, the provider
+	// instances installed in all the namespaces are returned.
+	Namespace string
+}
+
+// InventoryReport describes the provider instances installed in a management cluster, or in a namespace of it.
+type InventoryReport struct {
+	// Namespace of the provider instances in the report; empty if the report includes all the namespaces.
+	Namespace string
+
+	// Providers lists the provider instances, sorted by type and name.
+	Providers []clusterctlv1.Provider
+
+	// SharedCustomResourceDefinitions lists the CRDs, sorted by name, of the providers in the report that are shared with
+	// other instances of the same provider, e.g. in other namespaces; CRDs are cluster-scoped, so they are not included in
+	// the footprint of a single provider instance.
+	SharedCustomResourceDefinitions []SharedCustomResourceDefinition
+}
+
+// SharedCustomResourceDefinition is a CRD shared by many instances of a provider.
+type SharedCustomResourceDefinition struct {
+	// Name of the CRD.
+	Name string
+
+	// Providers lists the instance names of the providers sharing the CRD, including the ones not in the report, sorted.
+	Providers []string
+}
+
+func (c *clusterctlClient) GetInstalledProviders(options GetInstalledProvidersOptions) ([]clusterctlv1.Provider, error) {
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	return getInstalledProviders(clusterClient, options.Namespace)
+}
+
+func (c *clusterctlClient) GetInventoryReport(options GetInstalledProvidersOptions) (*InventoryReport, error) {
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := getInstalledProviders(clusterClient, options.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InventoryReport{
+		Namespace:                       options.Namespace,
+		Providers:                       providers,
+		SharedCustomResourceDefinitions: []SharedCustomResourceDefinition{},
+	}
+
+	// Group all the provider instances in the cluster by manifest label, because all the instances of a provider share
+	// the same CRDs.
+	allProviders, err := clusterClient.ProviderInventory().List()
+	if err != nil {
+		return nil, err
+	}
+	instances := map[string][]string{}
+	for _, p := range allProviders.Items {
+		instances[p.ManifestLabel()] = append(instances[p.ManifestLabel()], p.InstanceName())
+	}
+
+	cl, err := clusterClient.Proxy().NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. Many instances of a provider in the report, e.g. when the report includes all the namespaces, are sharing the
+	// same CRDs, so provider labels are processed once.
+	processed := sets.NewString()
+	for _, p := range providers {
+		label := p.ManifestLabel()
+		if processed.Has(label) || len(instances[label]) < 2 {
+			continue
+		}
+		processed.Insert(label)
+
+		crdList := &apiextensionsv1.CustomResourceDefinitionList{}
+		if err := cl.List(context.TODO(), crdList, client.MatchingLabels{clusterv1.ProviderLabelName: label}); err != nil {
+			return nil, errors.Wrapf(err, "failed to list the CRDs of provider %q", label)
+		}
+
+		sharedBy := append([]string{}, instances[label]...)
+		sort.Strings(sharedBy)
+		for _, crd := range crdList.Items {
+			report.SharedCustomResourceDefinitions = append(report.SharedCustomResourceDefinitions, SharedCustomResourceDefinition{
+				Name:      crd.Name,
+				Providers: sharedBy,
+			})
+		}
+	}
+	sort.Slice(report.SharedCustomResourceDefinitions, func(i, j int) bool {
+		return report.SharedCustomResourceDefinitions[i].Name < report.SharedCustomResourceDefinitions[j].Name
+	})
+	return report, nil
+}
+
+// getInstalledProviders returns the provider instances installed in a namespace, or in all the namespaces if empty,
+// sorted by type and name.
+func getInstalledProviders(clusterClient cluster.Client, namespace string) ([]clusterctlv1.Provider, error) {
+	installed, err := clusterClient.ProviderInventory().HasCustomResourceDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	if !installed {
+		return nil, ErrInventoryNotInstalled
+	}
+
+	var providerList *clusterctlv1.ProviderList
+	if namespace == "" {
+		providerList, err = clusterClient.ProviderInventory().List()
+	} else {
+		providerList, err = clusterClient.ProviderInventory().ListInNamespace(namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	providers := providerList.Items
+	sort.Slice(providers, func(i, j int) bool {
+		a, b := providers[i], providers[j]
+		if a.GetProviderType().Order() != b.GetProviderType().Order() {
+			return a.GetProviderType().Order() < b.GetProviderType().Order()
+		}
+		return a.InstanceName() < b.InstanceName()
+	})
+	return providers, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+func Test_clusterctlClient_GetInstalledProviders(t *testing.T) {
+	kubeconfig := Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+
+	tests := []struct {
+		name          string
+		installCRD    bool
+		namespace     string
+		wantProviders []string
+		wantErr       error
+	}{
+		{
+			name:       "returns the provider inventory sorted by type and name",
+			installCRD: true,
+			wantProviders: []string{
+				"capi-system/cluster-api",
+				"capbpk-system/bootstrap-kubeadm",
+				"foobar/control-plane-kubeadm",
+				"foobar/infrastructure-infra",
+			},
+		},
+		{
+			name:       "returns the provider inventory for a namespace",
+			installCRD: true,
+			namespace:  "foobar",
+			wantProviders: []string{
+				"foobar/control-plane-kubeadm",
+				"foobar/infrastructure-infra",
+			},
+		},
+		{
+			name:       "fails if the inventory CRD is not installed",
+			installCRD: false,
+			wantErr:    ErrInventoryNotInstalled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakeClusterForDelete()
+			if tt.installCRD {
+				g.Expect(client.clusters[cluster.Kubeconfig(kubeconfig)].ProviderInventory().EnsureCustomResourceDefinitions()).To(Succeed())
+			}
+
+			got, err := client.GetInstalledProviders(GetInstalledProvidersOptions{Kubeconfig: kubeconfig, Namespace: tt.namespace})
+			if tt.wantErr != nil {
+				g.Expect(errors.Is(err, tt.wantErr)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotProviders := []string{}
+			for _, p := range got {
+				g.Expect(p.Version).To(Equal("v1.0.0"))
+				gotProviders = append(gotProviders, p.InstanceName())
+			}
+			g.Expect(gotProviders).To(Equal(tt.wantProviders))
+		})
+	}
+}
+
+func Test_clusterctlClient_GetInventoryReport(t *testing.T) {
+	kubeconfig := Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+
+	crd := func(name, providerLabel string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{clusterv1.ProviderLabelName: providerLabel}},
+		}
+	}
+
+	inventoryCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "providers." + clusterctlv1.GroupVersion.Group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: clusterctlv1.GroupVersion.Version, Storage: true}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		namespace     string
+		wantProviders []string
+		wantShared    []SharedCustomResourceDefinition
+	}{
+		{
+			name:          "reports the CRDs shared with providers in other namespaces",
+			namespace:     "ns1",
+			wantProviders: []string{"ns1/infrastructure-infra"},
+			wantShared: []SharedCustomResourceDefinition{
+				{Name: "infraclusters.infrastructure.cluster.x-k8s.io", Providers: []string{"ns1/infrastructure-infra", "ns2/infrastructure-infra"}},
+				{Name: "inframachines.infrastructure.cluster.x-k8s.io", Providers: []string{"ns1/infrastructure-infra", "ns2/infrastructure-infra"}},
+			},
+		},
+		{
+			name:          "reports the shared CRDs once for all the namespaces",
+			wantProviders: []string{"capi-system/cluster-api", "ns1/infrastructure-infra", "ns2/infrastructure-infra"},
+			wantShared: []SharedCustomResourceDefinition{
+				{Name: "infraclusters.infrastructure.cluster.x-k8s.io", Providers: []string{"ns1/infrastructure-infra", "ns2/infrastructure-infra"}},
+				{Name: "inframachines.infrastructure.cluster.x-k8s.io", Providers: []string{"ns1/infrastructure-infra", "ns2/infrastructure-infra"}},
+			},
+		},
+		{
+			name:          "does not report CRDs not shared",
+			namespace:     "capi-system",
+			wantProviders: []string{"capi-system/cluster-api"},
+			wantShared:    []SharedCustomResourceDefinition{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig()
+			cluster1 := newFakeCluster(cluster.Kubeconfig(kubeconfig), config1)
+			cluster1.fakeProxy.
+				WithProviderInventory("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "capi-system").
+				WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v1.0.0", "ns1").
+				WithProviderInventory("infra", clusterctlv1.InfrastructureProviderType, "v1.0.0", "ns2").
+				WithObjs(
+					inventoryCRD,
+					crd("clusters.cluster.x-k8s.io", "cluster-api"),
+					crd("infraclusters.infrastructure.cluster.x-k8s.io", "infrastructure-infra"),
+					crd("inframachines.infrastructure.cluster.x-k8s.io", "infrastructure-infra"),
+				)
+			client := newFakeClient(config1).WithCluster(cluster1)
+
+			got, err := client.GetInventoryReport(GetInstalledProvidersOptions{Kubeconfig: kubeconfig, Namespace: tt.namespace})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(got.Namespace).To(Equal(tt.namespace))
+			gotProviders := []string{}
+			for _, p := range got.Providers {
+				gotProviders = append(gotProviders, p.InstanceName())
+			}
+			g.Expect(gotProviders).To(Equal(tt.wantProviders))
+			g.Expect(got.SharedCustomResourceDefinitions).To(Equal(tt.wantShared))
+		})
+	}
+}
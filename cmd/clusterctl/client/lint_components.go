@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+)
+
+// ComponentsLintRule identifies a check performed by LintProviderComponents.
+type ComponentsLintRule string
+
+const (
+	// ControllerDeploymentLintRule checks that the components include the Deployment of the provider controller.
+	ControllerDeploymentLintRule ComponentsLintRule = "ControllerDeployment"
+
+	// WebhookServiceLintRule checks that the webhook configurations and the CRD conversion webhooks refer to
+	// Services included in the components.
+	WebhookServiceLintRule ComponentsLintRule = "WebhookService"
+
+	// CRDConversionLintRule checks that the CRDs serving more than one version define a conversion webhook.
+	CRDConversionLintRule ComponentsLintRule = "CRDConversion"
+
+	// ServiceAccountLintRule checks that the RBAC bindings and the Deployments refer to ServiceAccounts included
+	// in the components.
+	ServiceAccountLintRule ComponentsLintRule = "ServiceAccount"
+)
+
+// ComponentsLintFinding is a misconfiguration found in the components of a provider.
+type ComponentsLintFinding struct {
+	// Rule is the check reporting the finding.
+	Rule ComponentsLintRule
+
+	// Object is the misconfigured object; it is empty for findings about the components as a whole, e.g. a missing Deployment.
+	Object corev1.ObjectReference
+
+	// Message describes the misconfiguration.
+	Message string
+}
+
+func (c *clusterctlClient) LintProviderComponents(provider string, providerType clusterctlv1.ProviderType, options ComponentsOptions) ([]ComponentsLintFinding, error) {
+	components, err := c.getComponentsByName(provider, providerType, repository.ComponentsOptions(options), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return lintComponents(components.Objs())
+}
+
+// lintComponents returns the misconfigurations found in the objects of the provider components, sorted by object.
+func lintComponents(objs []unstructured.Unstructured) ([]ComponentsLintFinding, error) {
+	services := sets.NewString()
+	serviceAccounts := sets.NewString()
+	hasDeployment := false
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "Service":
+			services.Insert(namespacedObjectName(obj.GetNamespace(), obj.GetName()))
+		case "ServiceAccount":
+			serviceAccounts.Insert(namespacedObjectName(obj.GetNamespace(), obj.GetName()))
+		case "Deployment":
+			hasDeployment = true
+		}
+	}
+
+	findings := []ComponentsLintFinding{}
+	if !hasDeployment {
+		findings = append(findings, ComponentsLintFinding{
+			Rule:    ControllerDeploymentLintRule,
+			Message: "the components do not include the Deployment of the provider controller",
+		})
+	}
+
+	for i := range objs {
+		obj := objs[i]
+		newFinding := func(rule ComponentsLintRule, format string, args ...interface{}) ComponentsLintFinding {
+			return ComponentsLintFinding{
+				Rule: rule,
+				Object: corev1.ObjectReference{
+					APIVersion: obj.GetAPIVersion(),
+					Kind:       obj.GetKind(),
+					Namespace:  obj.GetNamespace(),
+					Name:       obj.GetName(),
+				},
+				Message: fmt.Sprintf(format, args...),
+			}
+		}
+
+		switch obj.GetKind() {
+		case "CustomResourceDefinition":
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := scheme.Scheme.Convert(&obj, crd, nil); err != nil {
+				return nil, errors.Wrapf(err, "failed to convert the CustomResourceDefinition %s", obj.GetName())
+			}
+
+			served := []string{}
+			for _, version := range crd.Spec.Versions {
+				if version.Served {
+					served = append(served, version.Name)
+				}
+			}
+			conversion := crd.Spec.Conversion
+			if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter {
+				if len(served) > 1 {
+					findings = append(findings, newFinding(CRDConversionLintRule, "the CRD serves the versions %v without a conversion webhook", served))
+				}
+				continue
+			}
+			var clientConfig *apiextensionsv1.WebhookClientConfig
+			if conversion.Webhook != nil {
+				clientConfig = conversion.Webhook.ClientConfig
+			}
+			switch {
+			case clientConfig == nil || (clientConfig.Service == nil && clientConfig.URL == nil):
+				findings = append(findings, newFinding(WebhookServiceLintRule, "the CRD conversion webhook does not define a Service"))
+			case clientConfig.Service != nil && !services.Has(namespacedObjectName(clientConfig.Service.Namespace, clientConfig.Service.Name)):
+				findings = append(findings, newFinding(WebhookServiceLintRule, "the CRD conversion webhook refers to the Service %s/%s, which is not included in the components",
+					clientConfig.Service.Namespace, clientConfig.Service.Name))
+			}
+
+		case "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration":
+			webhooks, _, err := unstructured.NestedSlice(obj.Object, "webhooks")
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read the webhooks of the %s %s", obj.GetKind(), obj.GetName())
+			}
+			for _, w := range webhooks {
+				webhook, ok := w.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				webhookName, _, _ := unstructured.NestedString(webhook, "name")
+				serviceName, ok, _ := unstructured.NestedString(webhook, "clientConfig", "service", "name")
+				if !ok {
+					continue
+				}
+				serviceNamespace, _, _ := unstructured.NestedString(webhook, "clientConfig", "service", "namespace")
+				if !services.Has(namespacedObjectName(serviceNamespace, serviceName)) {
+					findings = append(findings, newFinding(WebhookServiceLintRule, "the webhook %s refers to the Service %s/%s, which is not included in the components", webhookName, serviceNamespace, serviceName))
+				}
+			}
+
+		case "RoleBinding", "ClusterRoleBinding":
+			subjects, _, err := unstructured.NestedSlice(obj.Object, "subjects")
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read the subjects of the %s %s", obj.GetKind(), obj.GetName())
+			}
+			for _, s := range subjects {
+				subject, ok := s.(map[string]interface{})
+				if !ok || subject["kind"] != "ServiceAccount" {
+					continue
+				}
+				name, _, _ := unstructured.NestedString(subject, "name")
+				namespace, _, _ := unstructured.NestedString(subject, "namespace")
+				if namespace == "" {
+					namespace = obj.GetNamespace()
+				}
+				if !serviceAccounts.Has(namespacedObjectName(namespace, name)) {
+					findings = append(findings, newFinding(ServiceAccountLintRule, "the binding refers to the ServiceAccount %s/%s, which is not included in the components", namespace, name))
+				}
+			}
+
+		case "Deployment":
+			// NB. The default ServiceAccount exists in every namespace.
+			name, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName")
+			if name != "" && name != "default" && !serviceAccounts.Has(namespacedObjectName(obj.GetNamespace(), name)) {
+				findings = append(findings, newFinding(ServiceAccountLintRule, "the Deployment runs as the ServiceAccount %s/%s, which is not included in the components", obj.GetNamespace(), name))
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return lintFindingSortKey(findings[i]) < lintFindingSortKey(findings[j])
+	})
+	return findings, nil
+}
+
+func namespacedObjectName(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+func lintFindingSortKey(f ComponentsLintFinding) string {
+	return fmt.Sprintf("%s/%s/%s", f.Object.Kind, f.Object.Namespace, f.Object.Name)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_clusterctlClient_LintProviderComponents(t *testing.T) {
+	const namespace = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns1
+`
+	const serviceAccount = `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: manager
+  namespace: ns1
+`
+	const deployment = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller-manager
+  namespace: ns1
+spec:
+  template:
+    spec:
+      serviceAccountName: manager
+      containers:
+      - name: manager
+        image: registry.io/manager:v1.0.0
+`
+	const webhookService = `---
+apiVersion: v1
+kind: Service
+metadata:
+  name: webhook-service
+  namespace: ns1
+spec:
+  ports:
+  - port: 443
+`
+	const crd = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: machines.infrastructure.cluster.x-k8s.io
+spec:
+  group: infrastructure.cluster.x-k8s.io
+  names:
+    kind: Machine
+    plural: machines
+  scope: Namespaced
+  conversion:
+    strategy: Webhook
+    webhook:
+      conversionReviewVersions: ["v1"]
+      clientConfig:
+        service:
+          name: webhook-service
+          namespace: ns1
+  versions:
+  - name: v1alpha3
+    served: true
+    storage: false
+  - name: v1alpha4
+    served: true
+    storage: true
+`
+	const webhookConfiguration = `---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: validating-webhook-configuration
+webhooks:
+- name: validation.machine.infrastructure.cluster.x-k8s.io
+  admissionReviewVersions: ["v1"]
+  sideEffects: None
+  clientConfig:
+    service:
+      name: webhook-service
+      namespace: ns1
+      port: 443
+`
+	const roleBinding = `---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: manager-rolebinding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: manager-role
+subjects:
+- kind: ServiceAccount
+  name: manager
+  namespace: ns1
+`
+
+	tests := []struct {
+		name       string
+		components string
+		want       []ComponentsLintFinding
+	}{
+		{
+			name:       "well-formed components",
+			components: namespace + serviceAccount + deployment + webhookService + crd + webhookConfiguration + roleBinding,
+			want:       []ComponentsLintFinding{},
+		},
+		{
+			name:       "missing controller Deployment",
+			components: namespace + serviceAccount,
+			want: []ComponentsLintFinding{
+				{Rule: ControllerDeploymentLintRule, Message: "the components do not include the Deployment of the provider controller"},
+			},
+		},
+		{
+			name:       "missing webhook Service",
+			components: namespace + serviceAccount + deployment + crd + webhookConfiguration,
+			want: []ComponentsLintFinding{
+				{
+					Rule:    WebhookServiceLintRule,
+					Object:  corev1.ObjectReference{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "machines.infrastructure.cluster.x-k8s.io"},
+					Message: "the CRD conversion webhook refers to the Service ns1/webhook-service, which is not included in the components",
+				},
+				{
+					Rule:    WebhookServiceLintRule,
+					Object:  corev1.ObjectReference{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingWebhookConfiguration", Name: "validating-webhook-configuration"},
+					Message: "the webhook validation.machine.infrastructure.cluster.x-k8s.io refers to the Service ns1/webhook-service, which is not included in the components",
+				},
+			},
+		},
+		{
+			name: "CRD serving many versions without a conversion webhook",
+			components: namespace + serviceAccount + deployment + `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: machines.infrastructure.cluster.x-k8s.io
+spec:
+  group: infrastructure.cluster.x-k8s.io
+  names:
+    kind: Machine
+    plural: machines
+  scope: Namespaced
+  versions:
+  - name: v1alpha3
+    served: true
+    storage: false
+  - name: v1alpha4
+    served: true
+    storage: true
+`,
+			want: []ComponentsLintFinding{
+				{
+					Rule:    CRDConversionLintRule,
+					Object:  corev1.ObjectReference{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "machines.infrastructure.cluster.x-k8s.io"},
+					Message: "the CRD serves the versions [v1alpha3 v1alpha4] without a conversion webhook",
+				},
+			},
+		},
+		{
+			name:       "missing ServiceAccount",
+			components: namespace + deployment + roleBinding,
+			want: []ComponentsLintFinding{
+				{
+					Rule:    ServiceAccountLintRule,
+					Object:  corev1.ObjectReference{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding", Name: "ns1-manager-rolebinding"},
+					Message: "the binding refers to the ServiceAccount ns1/manager, which is not included in the components",
+				},
+				{
+					Rule:    ServiceAccountLintRule,
+					Object:  corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns1", Name: "controller-manager"},
+					Message: "the Deployment runs as the ServiceAccount ns1/manager, which is not included in the components",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig().
+				WithProvider(infraProviderConfig)
+
+			repository1 := newFakeRepository(infraProviderConfig, config1).
+				WithPaths("root", "components.yaml").
+				WithDefaultVersion("v1.0.0").
+				WithFile("v1.0.0", "components.yaml", []byte(tt.components))
+
+			client := newFakeClient(config1).
+				WithRepository(repository1)
+
+			got, err := client.LintProviderComponents(infraProviderConfig.Name()+":v1.0.0", infraProviderConfig.Type(), ComponentsOptions{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
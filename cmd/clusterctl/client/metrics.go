@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sort"
+	"time"
+)
+
+// OperationType defines the type of an operation reported to a MetricsRecorder.
+type OperationType string
+
+const (
+	// InitOperation is reported by Init.
+	InitOperation OperationType = "Init"
+
+	// DeleteOperation is reported by Delete.
+	DeleteOperation OperationType = "Delete"
+
+	// MoveOperation is reported by Move.
+	MoveOperation OperationType = "Move"
+
+	// ApplyUpgradeOperation is reported by ApplyUpgrade.
+	ApplyUpgradeOperation OperationType = "ApplyUpgrade"
+)
+
+// OperationEvent describes the timing and the outcome of an operation executed by the clusterctl client.
+type OperationEvent struct {
+	// Operation is the type of the operation.
+	Operation OperationType
+
+	// Providers lists the providers affected by the operation, sorted by name, e.g. the providers installed by Init or
+	// deleted by Delete; it is empty if the providers are not known, e.g. when the operation fails before starting.
+	Providers []string
+
+	// Objects is the number of Cluster API objects processed by a Move operation, i.e. the objects moved, saved to a
+	// directory or restored, also if the operation fails; for dry runs, it is the number of objects to be moved.
+	Objects int
+
+	// Duration of the operation.
+	Duration time.Duration
+
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+// MetricsRecorder receives an event for each operation executed by the clusterctl client, e.g. for exporting
+// the events as Prometheus metrics; events are reported synchronously, so RecordOperation should not block.
+type MetricsRecorder interface {
+	RecordOperation(event OperationEvent)
+}
+
+// MetricsRecorderFunc is an adapter for using a function as a MetricsRecorder.
+type MetricsRecorderFunc func(event OperationEvent)
+
+// RecordOperation calls f(event).
+func (f MetricsRecorderFunc) RecordOperation(event OperationEvent) {
+	f(event)
+}
+
+// WithMetrics sets the MetricsRecorder receiving the events for the operations executed by the client.
+func WithMetrics(recorder MetricsRecorder) Option {
+	return func(c *clusterctlClient) {
+		c.metricsRecorder = recorder
+	}
+}
+
+// operationRecorder collects the information about an operation to be reported to the MetricsRecorder.
+type operationRecorder struct {
+	recorder  MetricsRecorder
+	operation OperationType
+	start     time.Time
+	providers []string
+	objects   int
+}
+
+// startOperation returns an operationRecorder for an operation starting now.
+func (c *clusterctlClient) startOperation(operation OperationType) *operationRecorder {
+	return &operationRecorder{
+		recorder:  c.metricsRecorder,
+		operation: operation,
+		start:     time.Now(),
+	}
+}
+
+// done reports the operation to the MetricsRecorder, if any.
+func (o *operationRecorder) done(err error) {
+	if o.recorder == nil {
+		return
+	}
+	providers := append([]string{}, o.providers...)
+	sort.Strings(providers)
+	o.recorder.RecordOperation(OperationEvent{
+		Operation: o.operation,
+		Providers: providers,
+		Objects:   o.objects,
+		Duration:  time.Since(o.start),
+		Err:       err,
+	})
+}
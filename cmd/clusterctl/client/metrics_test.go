@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+func Test_clusterctlClient_Metrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       DeleteOptions
+		wantProviders []string
+		wantErr       bool
+	}{
+		{
+			name: "successful operations report the affected providers",
+			options: DeleteOptions{
+				Kubeconfig:         Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				BootstrapProviders: []string{bootstrapProviderConfig.Name()},
+			},
+			wantProviders: []string{clusterctlv1.ManifestLabel(bootstrapProviderConfig.Name(), bootstrapProviderConfig.Type())},
+			wantErr:       false,
+		},
+		{
+			name: "failed operations report the error",
+			options: DeleteOptions{
+				Kubeconfig:         Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				BootstrapProviders: []string{"does-not-exist"},
+			},
+			wantProviders: []string{},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var events []OperationEvent
+			client := fakeClusterForDelete()
+			WithMetrics(MetricsRecorderFunc(func(event OperationEvent) {
+				events = append(events, event)
+			}))(client.internalClient)
+
+			err := client.Delete(tt.options)
+			g.Expect(err != nil).To(Equal(tt.wantErr))
+
+			g.Expect(events).To(HaveLen(1))
+			g.Expect(events[0].Operation).To(Equal(DeleteOperation))
+			g.Expect(events[0].Providers).To(Equal(tt.wantProviders))
+			g.Expect(events[0].Err != nil).To(Equal(tt.wantErr))
+		})
+	}
+}
+
+func Test_clusterctlClient_MoveMetrics(t *testing.T) {
+	report := &cluster.MoveReport{
+		Namespaces: []cluster.MoveReportNamespace{
+			{
+				Namespace: "ns1",
+				Objects: []cluster.MoveReportObject{
+					{Object: corev1.ObjectReference{Kind: "Cluster", Namespace: "ns1", Name: "cluster1"}},
+					{Object: corev1.ObjectReference{Kind: "Secret", Namespace: "ns1", Name: "cluster1-kubeconfig"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		options     MoveOptions
+		wantObjects int
+		wantErr     bool
+	}{
+		{
+			name: "move reports the objects moved",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+			},
+			wantObjects: 3,
+			wantErr:     false,
+		},
+		{
+			name: "dry run reports the objects to be moved",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				DryRun:         true,
+			},
+			wantObjects: 2,
+			wantErr:     false,
+		},
+		{
+			name: "backup reports the objects saved",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				ToDirectory:    "/tmp/backup",
+			},
+			wantObjects: 3,
+			wantErr:     false,
+		},
+		{
+			name: "restore reports the objects restored",
+			options: MoveOptions{
+				ToKubeconfig:  Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+				FromDirectory: "/tmp/backup",
+			},
+			wantObjects: 3,
+			wantErr:     false,
+		},
+		{
+			name: "failed operations report the error",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+				ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+			},
+			wantObjects: 0,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var events []OperationEvent
+			client := fakeClientForMove()
+			for _, c := range client.clusters {
+				c.(*fakeClusterClient).WithObjectMover(&fakeObjectMover{movedObjects: 3, dryRunReport: report})
+			}
+			WithMetrics(MetricsRecorderFunc(func(event OperationEvent) {
+				events = append(events, event)
+			}))(client.internalClient)
+
+			err := client.Move(tt.options)
+			g.Expect(err != nil).To(Equal(tt.wantErr))
+
+			g.Expect(events).To(HaveLen(1))
+			g.Expect(events[0].Operation).To(Equal(MoveOperation))
+			g.Expect(events[0].Objects).To(Equal(tt.wantObjects))
+			g.Expect(events[0].Err != nil).To(Equal(tt.wantErr))
+		})
+	}
+}
@@ -17,7 +17,21 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // MoveOptions carries the options supported by move.
@@ -28,50 +42,421 @@ type MoveOptions struct {
 
 	// ToKubeconfig defines the kubeconfig to use for accessing the target management cluster. If empty,
 	// default rules for kubeconfig discovery will be used.
+	// NB. The source and the target management cluster must be different clusters, no matter of the kubeconfig and context used.
 	ToKubeconfig Kubeconfig
 
 	// Namespace where the objects describing the workload cluster exists. If unspecified, the current
 	// namespace will be used.
 	Namespace string
 
-	// DryRun means the move action is a dry run, no real action will be performed
+	// DryRun means the move action is a dry run, no real action will be performed; instead, the list of objects to be
+	// moved is logged, and an error is returned if any of those objects already exists in the target management cluster.
+	// If ToKubeconfig is empty, checking for conflicts with the target management cluster is skipped.
 	DryRun bool
+
+	// Resume resumes a move operation previously failed. Objects already created in the target management cluster
+	// are skipped, if they still match the corresponding objects in the source management cluster, and only the remaining
+	// objects are moved. The progress of a move operation is tracked in a ConfigMap in the source management cluster.
+	Resume bool
+
+	// LabelSelector restricts the move to the objects matching the label selector (e.g. "env=staging"), together with
+	// all the objects belonging to them. The move fails if an object belongs both to objects matching the label selector and
+	// to objects not matching it. If empty, all the objects in the namespace are moved.
+	LabelSelector string
+
+	// ExcludedKinds lists the kinds of the objects not to be moved, e.g. objects managed separately in each management
+	// cluster; kinds without a version match all the versions. The move fails if an excluded object owns any of the
+	// objects to be moved, because this would leave dangling owner references in the target management cluster.
+	ExcludedKinds []schema.GroupVersionKind
+
+	// Scope defines which of the objects belonging to the Clusters are moved; if empty, all the objects are moved.
+	// ControlPlaneMoveScope moves only the control plane of the Clusters, copying the Cluster and the objects shared with
+	// the rest of the Cluster, which is left paused in the source management cluster and must be recovered manually.
+	// Scope is not supported when saving objects to a directory or restoring them.
+	Scope MoveScope
+
+	// PreserveStatus restores the status of the objects after creating them in the target management cluster, so the
+	// target controllers can resume from the known state instead of reconciling from scratch. Objects whose status
+	// can't be restored are moved without status; creation timestamps and other server-assigned metadata are never preserved.
+	PreserveStatus bool
+
+	// Concurrency defines the maximum number of objects created concurrently in the target management cluster; objects
+	// are still created in order, owners before the objects they own, and only objects at the same level of the ownership
+	// chain, e.g. the Machines of a Cluster, are created concurrently. If unspecified, objects are created one at a time.
+	Concurrency int
+
+	// DiscoveryPageSize defines the maximum number of objects read by each list request when discovering the objects to be
+	// moved; smaller pages reduce the load of each request on the API server of the source management cluster. If unspecified,
+	// objects are read in pages of 500.
+	DiscoveryPageSize int64
+
+	// DiscoveryConcurrency defines the maximum number of object types listed concurrently when discovering the objects to be
+	// moved; list requests throttled by the API server are retried after the suggested delay. If unspecified, object types are
+	// listed one at a time.
+	DiscoveryConcurrency int
+
+	// ToNamespace defines the namespace where the objects are moved in the target management cluster. If unspecified,
+	// the objects are moved to the same namespace they have in the source management cluster. References between the
+	// moved objects are updated accordingly, and the move fails if any of the moved objects collides with another object
+	// in the target namespace.
+	ToNamespace string
+
+	// APIGroupMappings maps API groups of the source management cluster to equivalent API groups of the target management
+	// cluster, e.g. the API groups renamed by a provider migration. Objects of the mapped API groups, and the references to them,
+	// are moved using the target API group and the same API version. The move fails, reporting the API groups available in
+	// the target management cluster, if the types of the objects to be moved are defined there only under different API groups.
+	APIGroupMappings map[string]string
+
+	// ProgressFunc, if set, is invoked each time an object is created in the target management cluster or deleted from
+	// the source management cluster, reporting the number of operations completed, the overall number of operations, and
+	// the object being processed. The overall number of operations is computed from the objects discovered before moving.
+	// ProgressFunc can be invoked from many goroutines, but never concurrently.
+	ProgressFunc func(done, total int, currentObject string)
+
+	// ToDirectory defines the directory where the Cluster API objects are saved, one YAML file for each object,
+	// instead of moving them to a target management cluster. ToKubeconfig is ignored when ToDirectory is set.
+	ToDirectory string
+
+	// FromDirectory defines the directory where the Cluster API objects to be restored in the target management cluster
+	// have been previously saved using ToDirectory. FromKubeconfig is ignored when FromDirectory is set.
+	FromDirectory string
+
+	// RedactSecrets removes the data of the Secrets when saving Cluster API objects to a directory. Redacted Secrets are
+	// not restored, and they should be re-created manually.
+	RedactSecrets bool
+
+	// EncryptionKey defines the key used for encrypting the data of the Secrets when saving Cluster API objects to a directory,
+	// and for decrypting them when restoring. If empty, Secrets are saved in clear text, unless RedactSecrets is set.
+	EncryptionKey string
+
+	// Timeout defines the maximum duration of the Move operation; in-flight requests are interrupted when the timeout
+	// expires, and the move can be completed later using Resume. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// BackupOptions carries the options supported by backup.
+type BackupOptions struct {
+	// FromKubeconfig defines the kubeconfig to use for accessing the source management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	FromKubeconfig Kubeconfig
+
+	// Namespace where the objects describing the workload cluster exists. If unspecified, the current
+	// namespace will be used.
+	Namespace string
+
+	// LabelSelector restricts the backup to the objects matching the label selector, together with
+	// all the objects belonging to them. If empty, all the objects in the namespace are saved.
+	LabelSelector string
+
+	// ExcludedKinds lists the kinds of the objects not to be saved; kinds without a version match all the versions.
+	// The backup fails if an excluded object owns any of the objects to be saved.
+	ExcludedKinds []schema.GroupVersionKind
+
+	// Directory defines the directory where the Cluster API objects are saved, one YAML file for each object.
+	Directory string
+
+	// RedactSecrets removes the data of the Secrets. Redacted Secrets are not restored, and they should be re-created manually.
+	RedactSecrets bool
+
+	// EncryptionKey defines the key used for encrypting the data of the Secrets. If empty, Secrets are saved in clear text,
+	// unless RedactSecrets is set.
+	EncryptionKey string
+
+	// DiscoveryPageSize defines the maximum number of objects read by each list request when discovering the objects to be
+	// saved. If unspecified, objects are read in pages of 500.
+	DiscoveryPageSize int64
+
+	// DiscoveryConcurrency defines the maximum number of object types listed concurrently when discovering the objects to be
+	// saved. If unspecified, object types are listed one at a time.
+	DiscoveryConcurrency int
+}
+
+// RestoreOptions carries the options supported by restore.
+type RestoreOptions struct {
+	// ToKubeconfig defines the kubeconfig to use for accessing the target management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	ToKubeconfig Kubeconfig
+
+	// Directory defines the directory where the Cluster API objects have been previously saved by backup.
+	Directory string
+
+	// EncryptionKey defines the key used for decrypting the data of the Secrets, if encrypted when saving the objects.
+	EncryptionKey string
+
+	// ProgressFunc, if set, is invoked each time an object is created in the target management cluster.
+	ProgressFunc func(done, total int, currentObject string)
+
+	// PreserveStatus restores the status saved with the objects after creating them in the target management cluster.
+	PreserveStatus bool
+
+	// Concurrency defines the maximum number of objects at the same level of the ownership chain created concurrently
+	// in the target management cluster. If unspecified, objects are created one at a time.
+	Concurrency int
 }
 
 func (c *clusterctlClient) Move(options MoveOptions) error {
-	// Get the client for interacting with the source management cluster.
-	fromCluster, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.FromKubeconfig})
+	return c.MoveContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) MoveContext(ctx context.Context, options MoveOptions) (retErr error) {
+	op := c.startOperation(MoveOperation)
+	defer func() { op.done(retErr) }()
+
+	var err error
+	op.objects, err = c.move(ctx, options)
+	return err
+}
+
+// move executes a move operation, returning the number of objects moved, saved to a directory or restored; for dry
+// runs, the number of objects that would be moved is returned.
+func (c *clusterctlClient) move(ctx context.Context, options MoveOptions) (int, error) {
+	if options.ToDirectory != "" && options.FromDirectory != "" {
+		return 0, errors.New("only one of ToDirectory and FromDirectory can be set")
+	}
+
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	c = c.withContext(ctx)
+
+	if options.DryRun {
+		report, err := c.moveDryRun(ctx, options)
+		if err != nil {
+			return 0, err
+		}
+
+		logMoveReport(report)
+		objects := (*cluster.MoveReport)(report).ObjectsCount()
+		if len(report.Conflicts) > 0 {
+			return objects, errors.Errorf("%d object(s) to be moved already exist in the target management cluster", len(report.Conflicts))
+		}
+		return objects, nil
+	}
+
+	if (options.ToDirectory != "" || options.FromDirectory != "") && options.Scope != "" && options.Scope != AllMoveScope {
+		return 0, errors.Errorf("the %q move scope is not supported when saving objects to a directory or restoring them", options.Scope)
+	}
+
+	if options.ToDirectory != "" {
+		return c.backup(ctx, BackupOptions{
+			FromKubeconfig:       options.FromKubeconfig,
+			Namespace:            options.Namespace,
+			LabelSelector:        options.LabelSelector,
+			ExcludedKinds:        options.ExcludedKinds,
+			Directory:            options.ToDirectory,
+			RedactSecrets:        options.RedactSecrets,
+			EncryptionKey:        options.EncryptionKey,
+			DiscoveryPageSize:    options.DiscoveryPageSize,
+			DiscoveryConcurrency: options.DiscoveryConcurrency,
+		})
+	}
+
+	if options.FromDirectory != "" {
+		return c.restore(ctx, RestoreOptions{
+			ToKubeconfig:   options.ToKubeconfig,
+			Directory:      options.FromDirectory,
+			EncryptionKey:  options.EncryptionKey,
+			ProgressFunc:   options.ProgressFunc,
+			PreserveStatus: options.PreserveStatus,
+			Concurrency:    options.Concurrency,
+		})
+	}
+
+	selector, err := parseMoveLabelSelector(options.LabelSelector)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Ensure this command only runs against management clusters with the current Cluster API contract.
-	if err := fromCluster.ProviderInventory().CheckCAPIContract(); err != nil {
-		return err
+	// Get the client for interacting with the source management cluster.
+	fromCluster, err := c.getMoveSourceCluster(options)
+	if err != nil {
+		return 0, err
 	}
 
 	// Ensures the custom resource definitions required by clusterctl are in place.
 	if err := fromCluster.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
-		return err
+		return 0, err
+	}
+
+	// Get the client for interacting with the target management cluster.
+	toCluster, err := c.getMoveTargetCluster(options)
+	if err != nil {
+		return 0, err
+	}
+
+	// Ensures the source and the target management cluster are not the same cluster.
+	if err := checkMoveClusters(ctx, fromCluster, toCluster); err != nil {
+		return 0, err
+	}
+
+	// Ensures the custom resource definitions required by clusterctl are in place
+	if err := toCluster.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
+		return 0, err
+	}
+
+	// If the option specifying the Namespace is empty, try to detect it.
+	if options.Namespace == "" {
+		currentNamespace, err := fromCluster.Proxy().CurrentNamespace()
+		if err != nil {
+			return 0, err
+		}
+		options.Namespace = currentNamespace
+	}
+
+	return fromCluster.ObjectMover().Move(options.Namespace, toCluster, false,
+		cluster.WithResume(options.Resume),
+		cluster.WithLabelSelector(selector),
+		cluster.WithExcludedKinds(options.ExcludedKinds...),
+		cluster.WithScope(options.Scope),
+		cluster.WithDiscoveryPageSize(options.DiscoveryPageSize),
+		cluster.WithDiscoveryConcurrency(options.DiscoveryConcurrency),
+		cluster.WithPreserveStatus(options.PreserveStatus),
+		cluster.WithConcurrency(options.Concurrency),
+		cluster.WithToNamespace(options.ToNamespace),
+		cluster.WithAPIGroupMappings(options.APIGroupMappings),
+		cluster.WithProgress(options.ProgressFunc),
+	)
+}
+
+func (c *clusterctlClient) MoveDryRun(options MoveOptions) (*MoveReport, error) {
+	return c.MoveDryRunContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) MoveDryRunContext(ctx context.Context, options MoveOptions) (*MoveReport, error) {
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	return c.withContext(ctx).moveDryRun(ctx, options)
+}
+
+func (c *clusterctlClient) moveDryRun(ctx context.Context, options MoveOptions) (*MoveReport, error) {
+	selector, err := parseMoveLabelSelector(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	fromCluster, toCluster, namespace, err := c.getMoveDryRunClusters(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := fromCluster.ObjectMover().DryRun(namespace, toCluster,
+		cluster.WithLabelSelector(selector),
+		cluster.WithExcludedKinds(options.ExcludedKinds...),
+		cluster.WithScope(options.Scope),
+		cluster.WithDiscoveryPageSize(options.DiscoveryPageSize),
+		cluster.WithDiscoveryConcurrency(options.DiscoveryConcurrency),
+		cluster.WithToNamespace(options.ToNamespace),
+		cluster.WithAPIGroupMappings(options.APIGroupMappings),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return (*MoveReport)(report), nil
+}
+
+func (c *clusterctlClient) MovePlan(options MoveOptions) (*MovePlan, error) {
+	return c.MovePlanContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) MovePlanContext(ctx context.Context, options MoveOptions) (*MovePlan, error) {
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	return c.withContext(ctx).movePlan(ctx, options)
+}
+
+func (c *clusterctlClient) movePlan(ctx context.Context, options MoveOptions) (*MovePlan, error) {
+	selector, err := parseMoveLabelSelector(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	fromCluster, toCluster, namespace, err := c.getMoveDryRunClusters(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := fromCluster.ObjectMover().Plan(namespace, toCluster,
+		cluster.WithLabelSelector(selector),
+		cluster.WithExcludedKinds(options.ExcludedKinds...),
+		cluster.WithScope(options.Scope),
+		cluster.WithDiscoveryPageSize(options.DiscoveryPageSize),
+		cluster.WithDiscoveryConcurrency(options.DiscoveryConcurrency),
+		cluster.WithConcurrency(options.Concurrency),
+		cluster.WithToNamespace(options.ToNamespace),
+		cluster.WithAPIGroupMappings(options.APIGroupMappings),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return (*MovePlan)(plan), nil
+}
+
+// getMoveDryRunClusters returns the clients for the source and, if specified, the target management cluster of a move
+// operation that does not change them, together with the namespace of the objects to be moved.
+func (c *clusterctlClient) getMoveDryRunClusters(ctx context.Context, options MoveOptions) (cluster.Client, cluster.Client, string, error) {
+	// Get the client for interacting with the source management cluster.
+	fromCluster, err := c.getMoveSourceCluster(options)
+	if err != nil {
+		return nil, nil, "", err
 	}
 
+	// If a target management cluster is specified, get a client for checking conflicts with
+	// the objects already existing there.
 	var toCluster cluster.Client
-	if !options.DryRun {
-		// Get the client for interacting with the target management cluster.
-		toCluster, err = c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.ToKubeconfig})
+	if options.ToKubeconfig != (Kubeconfig{}) {
+		toCluster, err = c.getMoveTargetCluster(options)
 		if err != nil {
-			return err
+			return nil, nil, "", err
 		}
 
-		// Ensure this command only runs against management clusters with the current Cluster API contract.
-		if err := toCluster.ProviderInventory().CheckCAPIContract(); err != nil {
-			return err
+		// Ensures the source and the target management cluster are not the same cluster.
+		if err := checkMoveClusters(ctx, fromCluster, toCluster); err != nil {
+			return nil, nil, "", err
 		}
+	}
 
-		// Ensures the custom resource definitions required by clusterctl are in place
-		if err := toCluster.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
-			return err
+	// If the option specifying the Namespace is empty, try to detect it.
+	namespace := options.Namespace
+	if namespace == "" {
+		currentNamespace, err := fromCluster.Proxy().CurrentNamespace()
+		if err != nil {
+			return nil, nil, "", err
 		}
+		namespace = currentNamespace
+	}
+	return fromCluster, toCluster, namespace, nil
+}
+
+func (c *clusterctlClient) CanMove(options MoveOptions) error {
+	return c.CanMoveContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) CanMoveContext(ctx context.Context, options MoveOptions) error {
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	return c.withContext(ctx).canMove(ctx, options)
+}
+
+func (c *clusterctlClient) canMove(ctx context.Context, options MoveOptions) error {
+	selector, err := parseMoveLabelSelector(options.LabelSelector)
+	if err != nil {
+		return err
+	}
+
+	// Get the client for interacting with the source management cluster.
+	fromCluster, err := c.getMoveSourceCluster(options)
+	if err != nil {
+		return err
+	}
+
+	// Get the client for interacting with the target management cluster.
+	toCluster, err := c.getMoveTargetCluster(options)
+	if err != nil {
+		return err
+	}
+
+	// Ensures the source and the target management cluster are not the same cluster.
+	if err := checkMoveClusters(ctx, fromCluster, toCluster); err != nil {
+		return err
 	}
 
 	// If the option specifying the Namespace is empty, try to detect it.
@@ -83,5 +468,215 @@ func (c *clusterctlClient) Move(options MoveOptions) error {
 		options.Namespace = currentNamespace
 	}
 
-	return fromCluster.ObjectMover().Move(options.Namespace, toCluster, options.DryRun)
+	return fromCluster.ObjectMover().CanMove(options.Namespace, toCluster,
+		cluster.WithLabelSelector(selector),
+		cluster.WithExcludedKinds(options.ExcludedKinds...),
+		cluster.WithScope(options.Scope),
+		cluster.WithDiscoveryPageSize(options.DiscoveryPageSize),
+		cluster.WithDiscoveryConcurrency(options.DiscoveryConcurrency),
+		cluster.WithAPIGroupMappings(options.APIGroupMappings),
+	)
+}
+
+func (c *clusterctlClient) Backup(options BackupOptions) error {
+	return c.BackupContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) BackupContext(ctx context.Context, options BackupOptions) error {
+	_, err := c.backup(ctx, options)
+	return err
+}
+
+// backup saves the Cluster API objects to a directory, returning the number of objects saved.
+func (c *clusterctlClient) backup(ctx context.Context, options BackupOptions) (int, error) {
+	if options.Directory == "" {
+		return 0, errors.New("please specify the directory where to save the Cluster API objects")
+	}
+	c = c.withContext(ctx)
+
+	selector, err := parseMoveLabelSelector(options.LabelSelector)
+	if err != nil {
+		return 0, err
+	}
+
+	// Get the client for interacting with the source management cluster.
+	fromCluster, err := c.getMoveSourceCluster(MoveOptions{FromKubeconfig: options.FromKubeconfig})
+	if err != nil {
+		return 0, err
+	}
+
+	// If the option specifying the Namespace is empty, try to detect it.
+	if options.Namespace == "" {
+		currentNamespace, err := fromCluster.Proxy().CurrentNamespace()
+		if err != nil {
+			return 0, err
+		}
+		options.Namespace = currentNamespace
+	}
+
+	return fromCluster.ObjectMover().Backup(options.Namespace, options.Directory,
+		cluster.WithLabelSelector(selector),
+		cluster.WithExcludedKinds(options.ExcludedKinds...),
+		cluster.WithRedactSecrets(options.RedactSecrets),
+		cluster.WithEncryptionKey(options.EncryptionKey),
+		cluster.WithDiscoveryPageSize(options.DiscoveryPageSize),
+		cluster.WithDiscoveryConcurrency(options.DiscoveryConcurrency),
+	)
+}
+
+func (c *clusterctlClient) Restore(options RestoreOptions) error {
+	return c.RestoreContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) RestoreContext(ctx context.Context, options RestoreOptions) error {
+	_, err := c.restore(ctx, options)
+	return err
+}
+
+// restore creates the Cluster API objects saved in a directory, returning the number of objects restored.
+func (c *clusterctlClient) restore(ctx context.Context, options RestoreOptions) (int, error) {
+	if options.Directory == "" {
+		return 0, errors.New("please specify the directory where the Cluster API objects have been saved")
+	}
+	c = c.withContext(ctx)
+
+	// Get the client for interacting with the target management cluster.
+	toCluster, err := c.getMoveTargetCluster(MoveOptions{ToKubeconfig: options.ToKubeconfig})
+	if err != nil {
+		return 0, err
+	}
+
+	// Ensures the custom resource definitions required by clusterctl are in place
+	if err := toCluster.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
+		return 0, err
+	}
+
+	return toCluster.ObjectMover().Restore(toCluster, options.Directory,
+		cluster.WithEncryptionKey(options.EncryptionKey),
+		cluster.WithProgress(options.ProgressFunc),
+		cluster.WithPreserveStatus(options.PreserveStatus),
+		cluster.WithConcurrency(options.Concurrency),
+	)
+}
+
+// parseMoveLabelSelector parses the label selector for a move operation; if the label selector is empty, nil is returned,
+// so all the objects are moved.
+func parseMoveLabelSelector(labelSelector string) (labels.Selector, error) {
+	if labelSelector == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", labelSelector)
+	}
+	return selector, nil
+}
+
+// getMoveSourceCluster returns a client for the source management cluster, ensuring it uses the current Cluster API contract.
+func (c *clusterctlClient) getMoveSourceCluster(options MoveOptions) (cluster.Client, error) {
+	fromCluster, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.FromKubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := fromCluster.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, err
+	}
+	return fromCluster, nil
+}
+
+// getMoveTargetCluster returns a client for the target management cluster, ensuring it uses the current Cluster API contract.
+func (c *clusterctlClient) getMoveTargetCluster(options MoveOptions) (cluster.Client, error) {
+	toCluster, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.ToKubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := toCluster.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, err
+	}
+	return toCluster, nil
+}
+
+// checkMoveClusters ensures the source and the target management cluster of a move operation are not the same cluster,
+// because moving objects in place would pause them and then delete them.
+// The clusters are considered the same if they are accessed using the same kubeconfig and context, if they have the same API server,
+// or if they have the same kube-system namespace, that is unique for each cluster.
+func checkMoveClusters(ctx context.Context, fromCluster, toCluster cluster.Client) error {
+	if fromCluster.Kubeconfig() == toCluster.Kubeconfig() {
+		return errors.Errorf("the source and the target management cluster are the same (kubeconfig %q, context %q)", fromCluster.Kubeconfig().Path, fromCluster.Kubeconfig().Context)
+	}
+
+	fromConfig, err := fromCluster.Proxy().GetConfig()
+	if err != nil {
+		return err
+	}
+	toConfig, err := toCluster.Proxy().GetConfig()
+	if err != nil {
+		return err
+	}
+	if fromConfig != nil && toConfig != nil && fromConfig.Host == toConfig.Host {
+		return errors.Errorf("the source and the target management cluster are the same (API server %q)", fromConfig.Host)
+	}
+
+	fromUID, err := getClusterUID(ctx, fromCluster)
+	if err != nil {
+		return err
+	}
+	toUID, err := getClusterUID(ctx, toCluster)
+	if err != nil {
+		return err
+	}
+	if fromUID != "" && fromUID == toUID {
+		return errors.Errorf("the source and the target management cluster are the same (%s namespace UID %q)", metav1.NamespaceSystem, fromUID)
+	}
+	return nil
+}
+
+// getClusterUID returns the UID of the kube-system namespace, that can be used for identifying a cluster.
+// If the namespace can't be read, e.g. because of RBAC restrictions, an empty UID is returned.
+func getClusterUID(ctx context.Context, c cluster.Client) (types.UID, error) {
+	cs, err := c.Proxy().NewClient()
+	if err != nil {
+		return "", err
+	}
+
+	ns := &corev1.Namespace{}
+	if err := cs.Get(ctx, client.ObjectKey{Name: metav1.NamespaceSystem}, ns); err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to read the %s namespace", metav1.NamespaceSystem)
+	}
+	return ns.UID, nil
+}
+
+// logMoveReport logs the objects that are going to be moved, and the issues detected during the move dry-run.
+func logMoveReport(report *MoveReport) {
+	log := logf.Log
+
+	for _, n := range report.Namespaces {
+		namespace := n.Namespace
+		if namespace == "" {
+			namespace = "(cluster-wide)"
+		}
+		log.Info("Objects to be moved", "Namespace", namespace, "Count", len(n.Objects))
+		for _, o := range n.Objects {
+			owners := make([]string, 0, len(o.OwnerChain))
+			for _, owner := range o.OwnerChain {
+				owners = append(owners, fmt.Sprintf("%s/%s", owner.Kind, owner.Name))
+			}
+			log.V(1).Info("Object to be moved", o.Object.Kind, o.Object.Name, "Owners", strings.Join(owners, " -> "), "KeptInSource", o.KeptInSource)
+		}
+	}
+
+	for _, w := range report.Warnings {
+		log.Info("Warning: " + w)
+	}
+
+	for _, ref := range report.Conflicts {
+		log.Info("Conflict: object already exists in the target management cluster", ref.Kind, ref.Name, "Namespace", ref.Namespace)
+	}
 }
@@ -18,8 +18,12 @@ package client
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
@@ -78,6 +82,74 @@ func Test_clusterctlClient_Move(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "does not return error if a label selector is set",
+			fields: fields{
+				client: fakeClientForMove(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+					LabelSelector:  "env=staging",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if the label selector is not valid",
+			fields: fields{
+				client: fakeClientForMove(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+					LabelSelector:  "env in (staging",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if source and target kubeconfig are the same",
+			fields: fields{
+				client: fakeClientForMove(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if source and target kubeconfig point to the same cluster",
+			fields: fields{
+				client: fakeClientForMoveToSameCluster(),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if both ToDirectory and FromDirectory are set",
+			fields: fields{
+				client: fakeClientForMove(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToDirectory:    "/tmp/backup",
+					FromDirectory:  "/tmp/backup",
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -94,6 +166,396 @@ func Test_clusterctlClient_Move(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_MoveDryRun(t *testing.T) {
+	conflict := corev1.ObjectReference{APIVersion: "cluster.x-k8s.io/v1alpha4", Kind: "Cluster", Namespace: "ns1", Name: "foo"}
+
+	type fields struct {
+		client *fakeClient
+	}
+	type args struct {
+		options MoveOptions
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "does not return error if there are no conflicts",
+			fields: fields{
+				client: fakeClientForMoveDryRun(&cluster.MoveReport{}),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+					DryRun:         true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "does not require a target cluster",
+			fields: fields{
+				client: fakeClientForMoveDryRun(&cluster.MoveReport{}),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					DryRun:         true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if some objects already exist in the target cluster",
+			fields: fields{
+				client: fakeClientForMoveDryRun(&cluster.MoveReport{Conflicts: []corev1.ObjectReference{conflict}}),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+					DryRun:         true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if to cluster client is not found",
+			fields: fields{
+				client: fakeClientForMoveDryRun(&cluster.MoveReport{}),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+					DryRun:         true,
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := tt.fields.client.Move(tt.args.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			report, err := tt.fields.client.MoveDryRun(tt.args.options)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(report.Conflicts).To(BeEmpty())
+		})
+	}
+}
+
+func Test_clusterctlClient_MovePlan(t *testing.T) {
+	plan := &cluster.MovePlan{
+		ObjectsByKind:     map[string]int{"Cluster": 1, "Secret": 2},
+		ObjectsCount:      3,
+		EstimatedDuration: 3 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		options MoveOptions
+		wantErr bool
+	}{
+		{
+			name: "returns the plan for moving to a target cluster",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "does not require a target cluster",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if to cluster client is not found",
+			options: MoveOptions{
+				FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakeClientForMove()
+			for _, c := range client.clusters {
+				if c.Kubeconfig().Context == "mgmt-context" {
+					c.(*fakeClusterClient).WithObjectMover(&fakeObjectMover{plan: plan})
+				}
+			}
+
+			got, err := client.MovePlan(tt.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal((*MovePlan)(plan)))
+		})
+	}
+}
+
+func Test_clusterctlClient_CanMove(t *testing.T) {
+	type fields struct {
+		client *fakeClient
+	}
+	type args struct {
+		options MoveOptions
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "does not return error if the target cluster is ready",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if the target cluster is not ready",
+			fields: fields{
+				client: fakeClientForCanMove(errors.New("provider infrastructure-infra not found in the target cluster")),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if to cluster client is not found",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: MoveOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					ToKubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := tt.fields.client.CanMove(tt.args.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func fakeClientForCanMove(err error) *fakeClient {
+	client := fakeClientForMove()
+	for _, c := range client.clusters {
+		if c.Kubeconfig().Context == "mgmt-context" {
+			c.(*fakeClusterClient).WithObjectMover(&fakeObjectMover{moveErr: err})
+		}
+	}
+	return client
+}
+
+func Test_clusterctlClient_Backup(t *testing.T) {
+	type fields struct {
+		client *fakeClient
+	}
+	type args struct {
+		options BackupOptions
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "does not return error if cluster client is found",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: BackupOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					Directory:      "/tmp/backup",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if the directory is not set",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: BackupOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if from cluster client is not found",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: BackupOptions{
+					FromKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+					Directory:      "/tmp/backup",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := tt.fields.client.Backup(tt.args.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func Test_clusterctlClient_Restore(t *testing.T) {
+	type fields struct {
+		client *fakeClient
+	}
+	type args struct {
+		options RestoreOptions
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "does not return error if cluster client is found",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: RestoreOptions{
+					ToKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+					Directory:    "/tmp/backup",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "returns an error if the directory is not set",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: RestoreOptions{
+					ToKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "worker-context"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "returns an error if to cluster client is not found",
+			fields: fields{
+				client: fakeClientForMove(),
+			},
+			args: args{
+				options: RestoreOptions{
+					ToKubeconfig: Kubeconfig{Path: "kubeconfig", Context: "does-not-exist"},
+					Directory:    "/tmp/backup",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := tt.fields.client.Restore(tt.args.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+// fakeClientForMoveToSameCluster returns a fake client where the source and the target management cluster,
+// even if accessed with different contexts, have the same kube-system namespace and thus they are the same cluster.
+func fakeClientForMoveToSameCluster() *fakeClient {
+	client := fakeClientForMove()
+	for _, c := range client.clusters {
+		c.(*fakeClusterClient).WithObjs(&corev1.Namespace{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: metav1.NamespaceSystem,
+				UID:  "kube-system-uid",
+			},
+		})
+	}
+	return client
+}
+
+func fakeClientForMoveDryRun(report *cluster.MoveReport) *fakeClient {
+	client := fakeClientForMove()
+	for _, c := range client.clusters {
+		if c.Kubeconfig().Context == "mgmt-context" {
+			c.(*fakeClusterClient).WithObjectMover(&fakeObjectMover{dryRunReport: report})
+		}
+	}
+	return client
+}
+
 func fakeClientForMove() *fakeClient {
 	core := config.NewProvider("cluster-api", "https://somewhere.com", clusterctlv1.CoreProviderType)
 	infra := config.NewProvider("infra", "https://somewhere.com", clusterctlv1.InfrastructureProviderType)
@@ -112,6 +574,7 @@ func fakeClientForMove() *fakeClient {
 	cluster2 := newFakeCluster(cluster.Kubeconfig{Path: "kubeconfig", Context: "worker-context"}, config1).
 		WithProviderInventory(core.Name(), core.Type(), "v1.0.0", "cluster-api-system").
 		WithProviderInventory(infra.Name(), infra.Type(), "v2.0.0", "infra-system").
+		WithObjectMover(&fakeObjectMover{}).
 		WithObjs(test.FakeCAPISetupObjects()...)
 
 	client := newFakeClient(config1).
@@ -122,9 +585,50 @@ func fakeClientForMove() *fakeClient {
 }
 
 type fakeObjectMover struct {
-	moveErr error
+	moveErr      error
+	movedObjects int
+	dryRunReport *cluster.MoveReport
+	plan         *cluster.MovePlan
+}
+
+func (f *fakeObjectMover) Move(namespace string, toCluster cluster.Client, dryRun bool, options ...cluster.MoveOption) (int, error) {
+	return f.movedObjects, f.moveErr
+}
+
+func (f *fakeObjectMover) DryRun(namespace string, toCluster cluster.Client, options ...cluster.MoveOption) (*cluster.MoveReport, error) {
+	if f.dryRunReport == nil {
+		return &cluster.MoveReport{}, f.moveErr
+	}
+	return f.dryRunReport, f.moveErr
+}
+
+func (f *fakeObjectMover) Plan(namespace string, toCluster cluster.Client, options ...cluster.MoveOption) (*cluster.MovePlan, error) {
+	if f.plan == nil {
+		return &cluster.MovePlan{}, f.moveErr
+	}
+	return f.plan, f.moveErr
+}
+
+func (f *fakeObjectMover) CanMove(namespace string, toCluster cluster.Client, options ...cluster.MoveOption) error {
+	return f.moveErr
+}
+
+func (f *fakeObjectMover) Backup(namespace string, directory string, options ...cluster.MoveOption) (int, error) {
+	return f.movedObjects, f.moveErr
+}
+
+func (f *fakeObjectMover) Restore(toCluster cluster.Client, directory string, options ...cluster.MoveOption) (int, error) {
+	return f.movedObjects, f.moveErr
+}
+
+func (f *fakeObjectMover) ObjectGraph(namespace, clusterName string) (*cluster.ObjectGraph, error) {
+	return &cluster.ObjectGraph{}, f.moveErr
+}
+
+func (f *fakeObjectMover) Pause(namespace, clusterName string) error {
+	return f.moveErr
 }
 
-func (f *fakeObjectMover) Move(namespace string, toCluster cluster.Client, dryRun bool) error {
+func (f *fakeObjectMover) Resume(namespace, clusterName string) error {
 	return f.moveErr
 }
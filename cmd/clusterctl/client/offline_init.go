@@ -0,0 +1,202 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OfflineInitConfigFile is the name of the clusterctl config file generated by PrepareOfflineInit; the file defines
+	// the providers and the cert-manager repositories pointing to the files downloaded in the same folder.
+	OfflineInitConfigFile = "clusterctl.yaml"
+
+	// OfflineInitImagesFile is the name of the file generated by PrepareOfflineInit listing the images required
+	// for executing the init command, one per line.
+	OfflineInitImagesFile = "images.txt"
+
+	// metadataFile is the name of the metadata file of the providers.
+	metadataFile = "metadata.yaml"
+)
+
+// offlineInitProvider is a provider entry of the clusterctl config file generated by PrepareOfflineInit.
+type offlineInitProvider struct {
+	Name string                    `json:"name"`
+	URL  string                    `json:"url"`
+	Type clusterctlv1.ProviderType `json:"type"`
+}
+
+// offlineInitCertManager is the cert-manager entry of the clusterctl config file generated by PrepareOfflineInit.
+type offlineInitCertManager struct {
+	URL     string `json:"url"`
+	Version string `json:"version"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (c *clusterctlClient) PrepareOfflineInit(options InitOptions, dir string) ([]string, error) {
+	log := logf.Log
+
+	// NB. Local repositories require an absolute path.
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the absolute path of %q", dir)
+	}
+	dir = absDir
+
+	clusterClient, installer, err := c.setupInitImages(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Downloads the components and the metadata of the providers in the install queue, using the layout of the
+	// local repositories, i.e. {dir}/{provider-label}/{version}/{components.yaml}.
+	providers := []offlineInitProvider{}
+	for _, components := range installer.InstallQueue() {
+		log.Info("Downloading", "Provider", components.ManifestLabel(), "Version", components.Version())
+
+		repositoryClient, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: components, IncludePrereleases: options.IncludePrereleases})
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := repositoryClient.Components().Raw(repository.ComponentsOptions{Version: components.Version()})
+		if err != nil {
+			return nil, err
+		}
+		componentsFile, err := offlineInitFileName(components.URL())
+		if err != nil {
+			return nil, err
+		}
+		componentsPath := filepath.Join(dir, components.ManifestLabel(), components.Version(), componentsFile)
+		if err := writeOfflineInitFile(componentsPath, file); err != nil {
+			return nil, err
+		}
+
+		metadata, err := repositoryClient.Metadata(components.Version()).Get()
+		if err != nil {
+			return nil, err
+		}
+		// NB. The type information is dropped when decoding the metadata.
+		metadata.SetGroupVersionKind(clusterctlv1.GroupVersion.WithKind("Metadata"))
+		file, err = yaml.Marshal(metadata)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal the metadata of the provider %q", components.ManifestLabel())
+		}
+		if err := writeOfflineInitFile(filepath.Join(dir, components.ManifestLabel(), components.Version(), metadataFile), file); err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, offlineInitProvider{
+			Name: components.Name(),
+			URL:  componentsPath,
+			Type: components.Type(),
+		})
+	}
+
+	offlineConfig := map[string]interface{}{
+		config.ProvidersConfigKey: providers,
+	}
+
+	// Downloads the cert-manager manifest, unless cert-manager is managed outside of clusterctl.
+	if !options.SkipCertManager {
+		certManagerConfig, err := c.configClient.CertManager().Get()
+		if err != nil {
+			return nil, err
+		}
+		version := certManagerConfig.Version()
+		if options.CertManagerVersion != "" {
+			version = options.CertManagerVersion
+		}
+		log.Info("Downloading", "Provider", "cert-manager", "Version", version)
+
+		// NB. The cert-manager manifest is read using a fake provider, like when installing cert-manager.
+		certManagerProvider := config.NewProvider("cert-manager", certManagerConfig.URL(), "")
+		repositoryClient, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: certManagerProvider})
+		if err != nil {
+			return nil, err
+		}
+		file, err := repositoryClient.Components().Raw(repository.ComponentsOptions{Version: version})
+		if err != nil {
+			return nil, err
+		}
+		certManagerFile, err := offlineInitFileName(certManagerConfig.URL())
+		if err != nil {
+			return nil, err
+		}
+		certManagerPath := filepath.Join(dir, certManagerProvider.ManifestLabel(), version, certManagerFile)
+		if err := writeOfflineInitFile(certManagerPath, file); err != nil {
+			return nil, err
+		}
+
+		offlineConfig[config.CertManagerConfigKey] = offlineInitCertManager{
+			URL:     certManagerPath,
+			Version: version,
+			Timeout: certManagerConfig.Timeout(),
+		}
+	}
+
+	file, err := yaml.Marshal(offlineConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the clusterctl config file")
+	}
+	if err := writeOfflineInitFile(filepath.Join(dir, OfflineInitConfigFile), file); err != nil {
+		return nil, err
+	}
+
+	images, err := initImages(clusterClient, installer, options)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.Join(images, "\n")
+	if len(images) > 0 {
+		content += "\n"
+	}
+	if err := writeOfflineInitFile(filepath.Join(dir, OfflineInitImagesFile), []byte(content)); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// offlineInitFileName returns the name of the file a repository URL points to, e.g. infrastructure-components.yaml.
+func offlineInitFileName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the repository URL %q", rawURL)
+	}
+	return path.Base(filepath.ToSlash(u.Path)), nil
+}
+
+func writeOfflineInitFile(filePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return errors.Wrapf(err, "failed to create the folder %q", filepath.Dir(filePath))
+	}
+	if err := os.WriteFile(filePath, content, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %q", filePath)
+	}
+	return nil
+}
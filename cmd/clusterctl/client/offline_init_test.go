@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_clusterctlClient_PrepareOfflineInit(t *testing.T) {
+	const certManagerManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: cert-manager
+`
+	tests := []struct {
+		name            string
+		skipCertManager bool
+		wantImages      []string
+	}{
+		{
+			name: "downloads the providers and the cert-manager manifest",
+			wantImages: []string{
+				"k8s.gcr.io/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+				"some.registry.com/cert-image-1:latest",
+			},
+		},
+		{
+			name:            "downloads the providers only if cert-manager is managed outside of clusterctl",
+			skipCertManager: true,
+			wantImages: []string{
+				"k8s.gcr.io/cluster-api-aws/cluster-api-aws-controller:v0.5.3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cfg, client := setupCluster(nil, newFakeCertManagerClient([]string{"some.registry.com/cert-image-1:latest"}, nil))
+			certManagerProvider := config.NewProvider("cert-manager", config.CertManagerDefaultURL, "")
+			client.WithRepository(newFakeRepository(certManagerProvider, cfg).
+				WithPaths("root", "cert-manager.yaml").
+				WithDefaultVersion(config.CertManagerDefaultVersion).
+				WithFile(config.CertManagerDefaultVersion, "cert-manager.yaml", []byte(certManagerManifest)))
+
+			dir := t.TempDir()
+			images, err := client.PrepareOfflineInit(InitOptions{
+				Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				InfrastructureProviders: []string{"infra"},
+				SkipCertManager:         tt.skipCertManager,
+			}, dir)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(images).To(ConsistOf(tt.wantImages))
+
+			content, err := os.ReadFile(filepath.Join(dir, OfflineInitImagesFile))
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, image := range tt.wantImages {
+				g.Expect(string(content)).To(ContainSubstring(image + "\n"))
+			}
+
+			content, err = os.ReadFile(filepath.Join(dir, OfflineInitConfigFile))
+			g.Expect(err).NotTo(HaveOccurred())
+			offlineConfig := struct {
+				Providers   []offlineInitProvider   `json:"providers"`
+				CertManager *offlineInitCertManager `json:"cert-manager"`
+			}{}
+			g.Expect(yaml.Unmarshal(content, &offlineConfig)).To(Succeed())
+
+			// The generated config points to local repositories, serving the same components and metadata of the provider repositories.
+			g.Expect(offlineConfig.Providers).To(HaveLen(4))
+			for _, p := range offlineConfig.Providers {
+				g.Expect(p.URL).To(HavePrefix(dir))
+
+				repositoryClient, err := repository.New(config.NewProvider(p.Name, p.URL, p.Type), cfg)
+				g.Expect(err).NotTo(HaveOccurred())
+				versions, err := repositoryClient.GetVersions()
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(versions).To(HaveLen(1))
+
+				file, err := repositoryClient.Components().Raw(repository.ComponentsOptions{Version: versions[0]})
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(file).NotTo(BeEmpty())
+
+				metadata, err := repositoryClient.Metadata(versions[0]).Get()
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(metadata.ReleaseSeries).NotTo(BeEmpty())
+			}
+
+			if tt.skipCertManager {
+				g.Expect(offlineConfig.CertManager).To(BeNil())
+				return
+			}
+			g.Expect(offlineConfig.CertManager).NotTo(BeNil())
+			g.Expect(offlineConfig.CertManager.Version).To(Equal(config.CertManagerDefaultVersion))
+			g.Expect(offlineConfig.CertManager.URL).To(Equal(filepath.Join(dir, "cert-manager", config.CertManagerDefaultVersion, "cert-manager.yaml")))
+			content, err = os.ReadFile(offlineConfig.CertManager.URL)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(content)).To(Equal(certManagerManifest))
+		})
+	}
+}
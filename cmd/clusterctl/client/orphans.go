@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+// DeleteOrphansOptions carries the options supported by DeleteOrphans.
+type DeleteOrphansOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// IncludeNamespaces and IncludeCRDs opt-in for the deletion of the orphaned Namespaces and CustomResourceDefinitions,
+	// which implies the deletion of all the objects they host or define, including the objects created by users.
+	IncludeNamespaces bool
+	IncludeCRDs       bool
+
+	// Confirm must be set for deleting the orphaned objects; it is a safeguard against accidental deletions, given
+	// that the objects to be deleted are known only at execution time. Use FindOrphans for listing them before.
+	Confirm bool
+}
+
+func (c *clusterctlClient) FindOrphans(kubeconfig Kubeconfig) ([]OrphanedComponent, error) {
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+	return findOrphans(clusterClient)
+}
+
+func (c *clusterctlClient) DeleteOrphans(options DeleteOrphansOptions) ([]OrphanedComponent, error) {
+	log := logf.Log
+
+	if !options.Confirm {
+		return nil, errors.New("the Confirm option must be set for deleting the orphaned objects")
+	}
+
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := findOrphans(clusterClient)
+	if err != nil {
+		return nil, err
+	}
+
+	toDelete := []OrphanedComponent{}
+	for _, orphan := range orphans {
+		if orphan.Kind == "Namespace" && !options.IncludeNamespaces {
+			continue
+		}
+		if orphan.Kind == "CustomResourceDefinition" && !options.IncludeCRDs {
+			continue
+		}
+		log.Info("Deleting orphaned object", "Provider", orphan.Provider, "Kind", orphan.Kind, "Namespace", orphan.Namespace, "Name", orphan.Name)
+		toDelete = append(toDelete, orphan)
+	}
+
+	if err := clusterClient.ProviderComponents().DeleteOrphans(toDelete); err != nil {
+		return nil, err
+	}
+	return toDelete, nil
+}
+
+// findOrphans returns the orphaned provider components existing in the management cluster.
+func findOrphans(clusterClient cluster.Client) ([]OrphanedComponent, error) {
+	// NB. A management cluster without the inventory CRD has no providers installed, so all the provider components are orphaned.
+	installed, err := getInstalledProviders(clusterClient, "")
+	if err != nil && !errors.Is(err, ErrInventoryNotInstalled) {
+		return nil, err
+	}
+	return clusterClient.ProviderComponents().ListOrphans(installed)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+func Test_clusterctlClient_DeleteOrphans(t *testing.T) {
+	labels := map[string]string{
+		clusterctlv1.ClusterctlLabelName: "",
+		clusterv1.ProviderLabelName:      "infrastructure-aws",
+	}
+	kubeconfig := cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+
+	tests := []struct {
+		name      string
+		options   DeleteOrphansOptions
+		wantKinds []string
+		wantErr   bool
+	}{
+		{
+			name:    "fails without the confirmation",
+			options: DeleteOrphansOptions{},
+			wantErr: true,
+		},
+		{
+			name:      "deletes the orphaned objects, except Namespaces and CRDs",
+			options:   DeleteOrphansOptions{Confirm: true},
+			wantKinds: []string{"ServiceAccount"},
+		},
+		{
+			name:      "deletes all the orphaned objects, including Namespaces and CRDs",
+			options:   DeleteOrphansOptions{IncludeNamespaces: true, IncludeCRDs: true, Confirm: true},
+			wantKinds: []string{"CustomResourceDefinition", "Namespace", "ServiceAccount"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig()
+			cluster1 := newFakeCluster(kubeconfig, config1)
+			cluster1.fakeProxy.
+				WithProviderInventory(capiProviderConfig.Name(), capiProviderConfig.Type(), "v1.0.0", "capi-system").
+				WithObjs(
+					&corev1.Namespace{
+						TypeMeta:   metav1.TypeMeta{Kind: "Namespace"},
+						ObjectMeta: metav1.ObjectMeta{Name: "capa-system", Labels: labels},
+					},
+					&corev1.ServiceAccount{
+						TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount"},
+						ObjectMeta: metav1.ObjectMeta{Namespace: "capa-system", Name: "capa-manager", Labels: labels},
+					},
+					&apiextensionsv1.CustomResourceDefinition{
+						TypeMeta:   metav1.TypeMeta{Kind: "CustomResourceDefinition"},
+						ObjectMeta: metav1.ObjectMeta{Name: "awsclusters.infrastructure.cluster.x-k8s.io", Labels: labels},
+					},
+				)
+			client := newFakeClient(config1).WithCluster(cluster1)
+
+			orphans, err := client.FindOrphans(Kubeconfig(kubeconfig))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(orphans).To(HaveLen(3))
+
+			options := tt.options
+			options.Kubeconfig = Kubeconfig(kubeconfig)
+			deleted, err := client.DeleteOrphans(options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			kinds := []string{}
+			for _, o := range deleted {
+				kinds = append(kinds, o.Kind)
+				g.Expect(o.Provider).To(Equal("infrastructure-aws"))
+			}
+			g.Expect(kinds).To(Equal(tt.wantKinds))
+		})
+	}
+}
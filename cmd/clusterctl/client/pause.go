@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+// PauseClusterOptions carries the options supported by PauseCluster.
+type PauseClusterOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace where the workload cluster is located. If unspecified, the current namespace will be used.
+	Namespace string
+
+	// ClusterName of the workload cluster to be paused.
+	ClusterName string
+}
+
+// ResumeClusterOptions carries the options supported by ResumeCluster.
+type ResumeClusterOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty,
+	// default rules for kubeconfig discovery will be used.
+	Kubeconfig Kubeconfig
+
+	// Namespace where the workload cluster is located. If unspecified, the current namespace will be used.
+	Namespace string
+
+	// ClusterName of the workload cluster to be resumed.
+	ClusterName string
+}
+
+func (c *clusterctlClient) PauseCluster(options PauseClusterOptions) error {
+	clusterClient, namespace, err := c.getPauseCluster(options.Kubeconfig, options.Namespace, options.ClusterName)
+	if err != nil {
+		return err
+	}
+	return clusterClient.ObjectMover().Pause(namespace, options.ClusterName)
+}
+
+func (c *clusterctlClient) ResumeCluster(options ResumeClusterOptions) error {
+	clusterClient, namespace, err := c.getPauseCluster(options.Kubeconfig, options.Namespace, options.ClusterName)
+	if err != nil {
+		return err
+	}
+	return clusterClient.ObjectMover().Resume(namespace, options.ClusterName)
+}
+
+// getPauseCluster returns a client for the management cluster, ensuring it uses the current Cluster API contract,
+// and the namespace of the workload cluster to be paused or resumed.
+func (c *clusterctlClient) getPauseCluster(kubeconfig Kubeconfig, namespace, clusterName string) (cluster.Client, string, error) {
+	if clusterName == "" {
+		return nil, "", errors.New("the name of the Cluster to be paused or resumed is required")
+	}
+
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: kubeconfig})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(); err != nil {
+		return nil, "", err
+	}
+
+	// If the option specifying the Namespace is empty, try to detect it.
+	if namespace == "" {
+		currentNamespace, err := clusterClient.Proxy().CurrentNamespace()
+		if err != nil {
+			return nil, "", err
+		}
+		namespace = currentNamespace
+	}
+	return clusterClient, namespace, nil
+}
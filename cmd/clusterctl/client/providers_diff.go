@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// ProviderDiffType defines the type of a difference between the configured and the installed providers.
+type ProviderDiffType string
+
+const (
+	// ProviderAdded is a provider defined in the clusterctl configuration file, but not installed.
+	ProviderAdded ProviderDiffType = "Added"
+
+	// ProviderRemoved is a provider installed, but no longer configured.
+	ProviderRemoved ProviderDiffType = "Removed"
+
+	// ProviderVersionMismatch is a provider installed with a version different from the configured one.
+	ProviderVersionMismatch ProviderDiffType = "VersionMismatch"
+)
+
+// ProviderDiffAction defines the clusterctl operation reconciling a difference between the configured and the installed providers.
+type ProviderDiffAction string
+
+const (
+	// InitAction means the difference can be reconciled by clusterctl init.
+	InitAction ProviderDiffAction = "Init"
+
+	// UpgradeAction means the difference can be reconciled by clusterctl upgrade.
+	UpgradeAction ProviderDiffAction = "Upgrade"
+
+	// DeleteAction means the difference can be reconciled by clusterctl delete.
+	DeleteAction ProviderDiffAction = "Delete"
+
+	// NoAction means the difference can't be reconciled by clusterctl, e.g. when the configured version is older than
+	// the installed one.
+	NoAction ProviderDiffAction = "None"
+)
+
+// ProviderDiff is a difference between the configured and the installed providers.
+type ProviderDiff struct {
+	// Type of the difference.
+	Type ProviderDiffType
+
+	// Name of the provider.
+	Name string
+
+	// ProviderType of the provider.
+	ProviderType clusterctlv1.ProviderType
+
+	// Namespace of the installed provider instance; empty for providers not installed.
+	Namespace string
+
+	// InstalledVersion of the provider instance; empty for providers not installed.
+	InstalledVersion string
+
+	// ConfiguredVersion is the version the provider repository defaults to, e.g. the version in the provider URL or the
+	// latest release; empty for providers no longer configured.
+	ConfiguredVersion string
+
+	// Action is the clusterctl operation reconciling the difference.
+	Action ProviderDiffAction
+}
+
+func (c *clusterctlClient) DiffProviders(kubeconfig Kubeconfig) ([]ProviderDiff, error) {
+	configured, err := c.configClient.Providers().List()
+	if err != nil {
+		return nil, err
+	}
+	userDefined, err := c.configClient.Providers().ListUserDefined()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. A management cluster without the inventory CRD has no providers installed.
+	installed, err := getInstalledProviders(clusterClient, "")
+	if err != nil && !errors.Is(err, ErrInventoryNotInstalled) {
+		return nil, err
+	}
+
+	diffs := []ProviderDiff{}
+	configuredVersions := map[string]string{}
+	for _, p := range installed {
+		diff := ProviderDiff{
+			Name:             p.ProviderName,
+			ProviderType:     p.GetProviderType(),
+			Namespace:        p.Namespace,
+			InstalledVersion: p.Version,
+		}
+
+		providerConfig := findProviderConfig(configured, p.ProviderName, p.GetProviderType())
+		if providerConfig == nil {
+			diff.Type = ProviderRemoved
+			diff.Action = DeleteAction
+			diffs = append(diffs, diff)
+			continue
+		}
+
+		// NB. All the instances of a provider have the same configured version, so it is resolved only once.
+		configuredVersion, ok := configuredVersions[providerConfig.ManifestLabel()]
+		if !ok {
+			repo, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get the repository for provider %q", providerConfig.ManifestLabel())
+			}
+			configuredVersion = repo.DefaultVersion()
+			configuredVersions[providerConfig.ManifestLabel()] = configuredVersion
+		}
+
+		installedVersion, err := version.ParseSemantic(p.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the version of provider %q", p.InstanceName())
+		}
+		// NB. Repositories without a resolved default version, e.g. local repositories using latest, can't be compared.
+		desiredVersion, err := version.ParseSemantic(configuredVersion)
+		if err != nil || desiredVersion.String() == installedVersion.String() {
+			continue
+		}
+
+		diff.Type = ProviderVersionMismatch
+		diff.ConfiguredVersion = configuredVersion
+		diff.Action = UpgradeAction
+		if desiredVersion.LessThan(installedVersion) {
+			diff.Action = NoAction
+		}
+		diffs = append(diffs, diff)
+	}
+
+	// Providers defined in the clusterctl configuration file are expected to be installed; hard-coded provider
+	// configurations are not, because they only make the providers available to init.
+	for _, providerConfig := range userDefined {
+		if isProviderInstalled(installed, providerConfig) {
+			continue
+		}
+
+		diff := ProviderDiff{
+			Type:         ProviderAdded,
+			Name:         providerConfig.Name(),
+			ProviderType: providerConfig.Type(),
+			Action:       InitAction,
+		}
+		repo, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the repository for provider %q", providerConfig.ManifestLabel())
+		}
+		diff.ConfiguredVersion = repo.DefaultVersion()
+		diffs = append(diffs, diff)
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		a, b := diffs[i], diffs[j]
+		if a.ProviderType.Order() != b.ProviderType.Order() {
+			return a.ProviderType.Order() < b.ProviderType.Order()
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	})
+	return diffs, nil
+}
+
+// findProviderConfig returns the provider configuration with the given name and type, if any.
+func findProviderConfig(providers []config.Provider, name string, providerType clusterctlv1.ProviderType) config.Provider {
+	for _, p := range providers {
+		if p.Name() == name && p.Type() == providerType {
+			return p
+		}
+	}
+	return nil
+}
+
+// isProviderInstalled returns true if at least one instance of the provider is installed.
+func isProviderInstalled(installed []clusterctlv1.Provider, providerConfig config.Provider) bool {
+	for _, p := range installed {
+		if p.ProviderName == providerConfig.Name() && p.GetProviderType() == providerConfig.Type() {
+			return true
+		}
+	}
+	return false
+}
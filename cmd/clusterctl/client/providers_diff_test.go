@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+func Test_clusterctlClient_DiffProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	kubeconfig := Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+	olderInfraProviderConfig := config.NewProvider("older-infra", "url", clusterctlv1.InfrastructureProviderType)
+
+	config1 := newFakeConfig().
+		WithProvider(capiProviderConfig).
+		WithProvider(bootstrapProviderConfig).
+		WithProvider(infraProviderConfig).
+		WithProvider(olderInfraProviderConfig)
+
+	cluster1 := newFakeCluster(cluster.Kubeconfig(kubeconfig), config1)
+	cluster1.fakeProxy.
+		WithProviderInventory(capiProviderConfig.Name(), capiProviderConfig.Type(), "v1.0.0", "capi-system").
+		WithProviderInventory(bootstrapProviderConfig.Name(), bootstrapProviderConfig.Type(), "v1.0.0", "capbpk-system").
+		WithProviderInventory("removed", clusterctlv1.ControlPlaneProviderType, "v1.0.0", "removed-system").
+		WithProviderInventory(olderInfraProviderConfig.Name(), olderInfraProviderConfig.Type(), "v2.0.0", "older-infra-system")
+
+	client := newFakeClient(config1).
+		WithRepository(newFakeRepository(capiProviderConfig, config1).WithDefaultVersion("v1.1.0")).
+		WithRepository(newFakeRepository(bootstrapProviderConfig, config1).WithDefaultVersion("v1.0.0")).
+		WithRepository(newFakeRepository(infraProviderConfig, config1).WithDefaultVersion("v1.0.0")).
+		WithRepository(newFakeRepository(olderInfraProviderConfig, config1).WithDefaultVersion("v1.0.0")).
+		WithCluster(cluster1)
+	g.Expect(cluster1.ProviderInventory().EnsureCustomResourceDefinitions()).To(Succeed())
+
+	got, err := client.DiffProviders(kubeconfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]ProviderDiff{
+		{
+			Type:              ProviderVersionMismatch,
+			Name:              capiProviderConfig.Name(),
+			ProviderType:      clusterctlv1.CoreProviderType,
+			Namespace:         "capi-system",
+			InstalledVersion:  "v1.0.0",
+			ConfiguredVersion: "v1.1.0",
+			Action:            UpgradeAction,
+		},
+		{
+			Type:             ProviderRemoved,
+			Name:             "removed",
+			ProviderType:     clusterctlv1.ControlPlaneProviderType,
+			Namespace:        "removed-system",
+			InstalledVersion: "v1.0.0",
+			Action:           DeleteAction,
+		},
+		{
+			Type:              ProviderAdded,
+			Name:              infraProviderConfig.Name(),
+			ProviderType:      clusterctlv1.InfrastructureProviderType,
+			ConfiguredVersion: "v1.0.0",
+			Action:            InitAction,
+		},
+		{
+			Type:              ProviderVersionMismatch,
+			Name:              olderInfraProviderConfig.Name(),
+			ProviderType:      clusterctlv1.InfrastructureProviderType,
+			Namespace:         "older-infra-system",
+			InstalledVersion:  "v2.0.0",
+			ConfiguredVersion: "v1.0.0",
+			Action:            NoAction,
+		},
+	}))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+const (
+	defaultWaitForReadyTimeout = 5 * time.Minute
+	waitForReadyInterval       = 5 * time.Second
+)
+
+// ProvidersNotReadyError is returned by Init and ApplyUpgrade when WaitForReady is set and the providers are not ready
+// before the timeout expires.
+type ProvidersNotReadyError struct {
+	// Providers describes the readiness of all the providers being waited for, sorted by instance name.
+	Providers []cluster.ComponentsHealth
+}
+
+func (e *ProvidersNotReadyError) Error() string {
+	notReady := []string{}
+	for _, p := range e.Providers {
+		if p.Healthy {
+			continue
+		}
+		failing := []string{}
+		for _, c := range p.Checks {
+			if !c.Healthy {
+				failing = append(failing, fmt.Sprintf("%s %s: %s", c.Kind, c.Name, c.Message))
+			}
+		}
+		notReady = append(notReady, fmt.Sprintf("%s (%s)", p.Name, strings.Join(failing, ", ")))
+	}
+	return fmt.Sprintf("timed out waiting for providers to be ready: %s", strings.Join(notReady, "; "))
+}
+
+// waitForProvidersReady waits until the controllers Deployments of the providers are available, the providers CRDs
+// are established and the providers webhooks are serving; a zero timeout means defaultWaitForReadyTimeout.
+func waitForProvidersReady(ctx context.Context, clusterClient cluster.Client, providers []clusterctlv1.Provider, timeout time.Duration, eventFunc cluster.EventFunc) error {
+	log := logf.Log
+	log.Info("Waiting for providers to be ready...")
+	eventFunc.Notify(cluster.WaitingForProvidersEvent, "", "Waiting for %d providers to be ready", len(providers))
+
+	if timeout <= 0 {
+		timeout = defaultWaitForReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var report []cluster.ComponentsHealth
+	readyProviders := sets.NewString()
+	err := wait.PollImmediateUntil(waitForReadyInterval, func() (bool, error) {
+		report = make([]cluster.ComponentsHealth, 0, len(providers))
+		ready := true
+		for _, provider := range providers {
+			health, err := clusterClient.ProviderComponents().CheckHealth(provider)
+			if err != nil {
+				return false, err
+			}
+			report = append(report, *health)
+			ready = ready && health.Healthy
+			if health.Healthy && !readyProviders.Has(provider.ManifestLabel()) {
+				readyProviders.Insert(provider.ManifestLabel())
+				eventFunc.Notify(cluster.ProviderReadyEvent, provider.ManifestLabel(), "The provider is ready")
+			}
+		}
+		return ready, nil
+	}, ctx.Done())
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		sortComponentsHealth(report)
+		return &ProvidersNotReadyError{Providers: report}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+)
+
+func Test_waitForProvidersReady(t *testing.T) {
+	provider := func(name string, providerType clusterctlv1.ProviderType) clusterctlv1.Provider {
+		return clusterctlv1.Provider{
+			ObjectMeta:   metav1.ObjectMeta{Namespace: "ns1", Name: clusterctlv1.ManifestLabel(name, providerType)},
+			ProviderName: name,
+			Type:         string(providerType),
+			Version:      "v1.0.0",
+		}
+	}
+	deployment := func(provider clusterctlv1.Provider, available corev1.ConditionStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: provider.Namespace,
+				Name:      provider.ProviderName + "-controller-manager",
+				Labels: map[string]string{
+					clusterctlv1.ClusterctlLabelName: "",
+					clusterv1.ProviderLabelName:      provider.ManifestLabel(),
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: available}},
+			},
+		}
+	}
+
+	core := provider("cluster-api", clusterctlv1.CoreProviderType)
+	infra := provider("infra", clusterctlv1.InfrastructureProviderType)
+
+	t.Run("returns when all the providers are ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster1 := newFakeCluster(cluster.Kubeconfig{}, newFakeConfig()).
+			WithObjs(deployment(core, corev1.ConditionTrue), deployment(infra, corev1.ConditionTrue))
+
+		err := waitForProvidersReady(ctx, cluster1, []clusterctlv1.Provider{core, infra}, time.Second, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+	})
+
+	t.Run("returns the readiness of each provider on timeout", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster1 := newFakeCluster(cluster.Kubeconfig{}, newFakeConfig()).
+			WithObjs(deployment(core, corev1.ConditionTrue), deployment(infra, corev1.ConditionFalse))
+
+		err := waitForProvidersReady(ctx, cluster1, []clusterctlv1.Provider{infra, core}, 100*time.Millisecond, nil)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("ns1/infrastructure-infra (Deployment ns1/infra-controller-manager"))
+
+		var notReadyErr *ProvidersNotReadyError
+		g.Expect(errors.As(err, &notReadyErr)).To(BeTrue())
+		g.Expect(notReadyErr.Providers).To(HaveLen(2))
+		g.Expect(notReadyErr.Providers[0].Name).To(Equal("ns1/cluster-api"))
+		g.Expect(notReadyErr.Providers[0].Healthy).To(BeTrue())
+		g.Expect(notReadyErr.Providers[1].Name).To(Equal("ns1/infrastructure-infra"))
+		g.Expect(notReadyErr.Providers[1].Healthy).To(BeFalse())
+	})
+}
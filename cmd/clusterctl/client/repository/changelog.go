@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+// changelogFile is the name of the file read from a release when the repository does not publish release notes.
+const changelogFile = "CHANGELOG.md"
+
+// errReleaseNotesNotPublished is returned when a repository does not publish the release notes of a version.
+var errReleaseNotesNotPublished = errors.New("release notes not published")
+
+// releaseNotesGetter is implemented by repositories publishing release notes for each release, e.g. the body of
+// GitHub releases.
+type releaseNotesGetter interface {
+	// GetReleaseNotes returns the release notes for a given provider version.
+	GetReleaseNotes(version string) (string, error)
+}
+
+// ReleaseNotes defines the release notes of a provider version.
+type ReleaseNotes struct {
+	// Version is the provider version the release notes refer to.
+	Version string `json:"version"`
+
+	// Notes is the content of the release notes, usually in markdown.
+	Notes string `json:"notes"`
+}
+
+// getChangelog returns the release notes of the versions after fromVersion and up to toVersion, in ascending order;
+// versions not publishing release notes, nor a CHANGELOG.md file, are omitted.
+// Prereleases are considered only if includePrereleases is true, or if they are the target version.
+func getChangelog(repository Repository, fromVersion, toVersion string, includePrereleases bool) ([]ReleaseNotes, error) {
+	log := logf.Log
+
+	from, err := version.ParseSemantic(fromVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid version %q", fromVersion)
+	}
+	to, err := version.ParseSemantic(toVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid version %q", toVersion)
+	}
+
+	versions, err := repository.GetVersions()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the versions for reading the changelog")
+	}
+
+	type versionTag struct {
+		tag     string
+		version *version.Version
+	}
+	inRange := []versionTag{}
+	for _, tag := range versions {
+		sv, err := version.ParseSemantic(tag)
+		if err != nil || !from.LessThan(sv) || to.LessThan(sv) {
+			continue
+		}
+		if sv.PreRelease() != "" && !includePrereleases && sv.String() != to.String() {
+			continue
+		}
+		inRange = append(inRange, versionTag{tag: tag, version: sv})
+	}
+	sort.Slice(inRange, func(i, j int) bool {
+		return inRange[i].version.LessThan(inRange[j].version)
+	})
+
+	changelog := []ReleaseNotes{}
+	for _, v := range inRange {
+		notes, err := getReleaseNotes(repository, v.tag)
+		if err != nil {
+			// NB. a missing changelog should not prevent operations like upgrade plans, so the version is skipped.
+			log.V(5).Info("Skipping the changelog for a version without release notes", "Version", v.tag, "Reason", err.Error())
+			continue
+		}
+		changelog = append(changelog, ReleaseNotes{Version: v.tag, Notes: notes})
+	}
+	return changelog, nil
+}
+
+// getReleaseNotes returns the release notes published by the repository for a version, falling back to the
+// CHANGELOG.md file of the release.
+func getReleaseNotes(repository Repository, version string) (string, error) {
+	if getter, ok := repository.(releaseNotesGetter); ok {
+		notes, err := getter.GetReleaseNotes(version)
+		if err == nil && strings.TrimSpace(notes) != "" {
+			return notes, nil
+		}
+	}
+
+	content, err := repository.GetFile(version, changelogFile)
+	if err != nil {
+		return "", errors.Wrapf(errReleaseNotesNotPublished, "failed to read the release notes for version %s: %v", version, err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return "", errors.Wrapf(errReleaseNotesNotPublished, "the %s file for version %s is empty", changelogFile, version)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+// fakeReleaseNotesRepository is a Repository publishing release notes.
+type fakeReleaseNotesRepository struct {
+	*test.FakeRepository
+	notes map[string]string
+}
+
+func (f *fakeReleaseNotesRepository) GetReleaseNotes(version string) (string, error) {
+	if notes, ok := f.notes[version]; ok {
+		return notes, nil
+	}
+	return "", errReleaseNotesNotPublished
+}
+
+func Test_getChangelog(t *testing.T) {
+	repository := &fakeReleaseNotesRepository{
+		FakeRepository: test.NewFakeRepository().
+			WithVersions("v1.0.0", "v1.0.1", "v1.1.0-rc.0", "v1.1.0", "v1.2.0", "foo").
+			WithFile("v1.0.1", changelogFile, []byte("changelog v1.0.1")).
+			WithFile("v1.1.0", changelogFile, []byte("changelog v1.1.0")),
+		notes: map[string]string{
+			"v1.1.0-rc.0": "notes v1.1.0-rc.0",
+			"v1.1.0":      "notes v1.1.0",
+			"v1.2.0":      " ",
+		},
+	}
+
+	tests := []struct {
+		name               string
+		fromVersion        string
+		toVersion          string
+		includePrereleases bool
+		want               []ReleaseNotes
+		wantErr            bool
+	}{
+		{
+			name:        "returns the release notes after the current version, preferring the published ones to the changelog file",
+			fromVersion: "v1.0.0",
+			toVersion:   "v1.1.0",
+			want: []ReleaseNotes{
+				{Version: "v1.0.1", Notes: "changelog v1.0.1"},
+				{Version: "v1.1.0", Notes: "notes v1.1.0"},
+			},
+		},
+		{
+			name:               "includes prereleases if requested",
+			fromVersion:        "v1.0.1",
+			toVersion:          "v1.1.0",
+			includePrereleases: true,
+			want: []ReleaseNotes{
+				{Version: "v1.1.0-rc.0", Notes: "notes v1.1.0-rc.0"},
+				{Version: "v1.1.0", Notes: "notes v1.1.0"},
+			},
+		},
+		{
+			name:        "includes the target version if it is a prerelease",
+			fromVersion: "v1.0.1",
+			toVersion:   "v1.1.0-rc.0",
+			want: []ReleaseNotes{
+				{Version: "v1.1.0-rc.0", Notes: "notes v1.1.0-rc.0"},
+			},
+		},
+		{
+			name:        "omits versions without release notes",
+			fromVersion: "v1.1.0",
+			toVersion:   "v1.2.0",
+			want:        []ReleaseNotes{},
+		},
+		{
+			name:        "fails for invalid versions",
+			fromVersion: "foo",
+			toVersion:   "v1.2.0",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := getChangelog(repository, tt.fromVersion, tt.toVersion, tt.includePrereleases)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
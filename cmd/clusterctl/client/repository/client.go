@@ -17,6 +17,7 @@ limitations under the License.
 package repository
 
 import (
+	"context"
 	"net/url"
 
 	"github.com/pkg/errors"
@@ -35,6 +36,10 @@ type Client interface {
 	// GetVersion return the list of versions that are available in a provider repository
 	GetVersions() ([]string, error)
 
+	// DefaultVersion returns the version used when no version is requested, e.g. the version in the provider URL
+	// or the latest release.
+	DefaultVersion() string
+
 	// Components provide access to YAML file for creating provider components.
 	Components() ComponentsClient
 
@@ -44,6 +49,18 @@ type Client interface {
 
 	// Metadata provide access to YAML with the provider's metadata.
 	Metadata(version string) MetadataClient
+
+	// GetChangelog returns the release notes of the versions after fromVersion and up to toVersion, in ascending order;
+	// versions without release notes are omitted, so the changelog is empty if the provider does not publish any.
+	GetChangelog(fromVersion, toVersion string) ([]ReleaseNotes, error)
+
+	// GetVersionInfo returns the contract and the cluster template flavors of a version, reading only the provider's
+	// metadata and the list of files in the release; an empty version means the default version.
+	GetVersionInfo(version string) (*VersionInfo, error)
+
+	// GetPublishedVersions returns all the versions published by the provider, sorted from the oldest to the newest,
+	// with the contract of each version; prerelease versions are included only if prereleases are included.
+	GetPublishedVersions() ([]ProviderVersion, error)
 }
 
 // repositoryClient implements Client.
@@ -52,6 +69,11 @@ type repositoryClient struct {
 	configClient config.Client
 	repository   Repository
 	processor    yaml.Processor
+	ctx          context.Context
+	// includePrereleases makes prerelease versions eligible when resolving the latest version or a version range.
+	includePrereleases bool
+	// transformers are applied to the provider components, in order.
+	transformers []ComponentsTransformer
 }
 
 // ensure repositoryClient implements Client.
@@ -61,12 +83,31 @@ func (c *repositoryClient) GetVersions() ([]string, error) {
 	return c.repository.GetVersions()
 }
 
+func (c *repositoryClient) DefaultVersion() string {
+	return c.repository.DefaultVersion()
+}
+
+func (c *repositoryClient) GetChangelog(fromVersion, toVersion string) ([]ReleaseNotes, error) {
+	return getChangelog(c.repository, fromVersion, toVersion, c.includePrereleases)
+}
+
+func (c *repositoryClient) GetVersionInfo(version string) (*VersionInfo, error) {
+	return getVersionInfo(c, version)
+}
+
+func (c *repositoryClient) GetPublishedVersions() ([]ProviderVersion, error) {
+	return getPublishedVersions(c)
+}
+
 func (c *repositoryClient) Components() ComponentsClient {
-	return newComponentsClient(c.Provider, c.repository, c.configClient)
+	componentsClient := newComponentsClient(c.Provider, c.repository, c.configClient)
+	componentsClient.includePrereleases = c.includePrereleases
+	componentsClient.transformers = c.transformers
+	return componentsClient
 }
 
 func (c *repositoryClient) Templates(version string) TemplateClient {
-	return newTemplateClient(TemplateClientInput{version, c.Provider, c.repository, c.configClient.Variables(), c.processor})
+	return newTemplateClient(TemplateClientInput{version, c.Provider, c.repository, c.configClient.Variables(), c.processor, c.includePrereleases})
 }
 
 func (c *repositoryClient) Metadata(version string) MetadataClient {
@@ -96,6 +137,30 @@ func InjectYamlProcessor(p yaml.Processor) Option {
 	}
 }
 
+// InjectContext binds the requests to the default repository implementation to a context, so they are
+// interrupted when the context is cancelled or its deadline expires.
+func InjectContext(ctx context.Context) Option {
+	return func(c *repositoryClient) {
+		c.ctx = ctx
+	}
+}
+
+// InjectIncludePrereleases makes prerelease versions, e.g. release candidates, eligible when resolving the latest
+// version or a version range, as if the repository-include-prereleases variable was set to true.
+func InjectIncludePrereleases(include bool) Option {
+	return func(c *repositoryClient) {
+		c.includePrereleases = c.includePrereleases || include
+	}
+}
+
+// InjectComponentsTransformers adds transformers to be applied, in order, to the provider components; see
+// ComponentsTransformer for more details.
+func InjectComponentsTransformers(transformers ...ComponentsTransformer) Option {
+	return func(c *repositoryClient) {
+		c.transformers = append(c.transformers, transformers...)
+	}
+}
+
 // New returns a Client.
 func New(provider config.Provider, configClient config.Client, options ...Option) (Client, error) {
 	return newRepositoryClient(provider, configClient, options...)
@@ -111,9 +176,15 @@ func newRepositoryClient(provider config.Provider, configClient config.Client, o
 		o(client)
 	}
 
+	includePrereleases, err := IsPrereleaseIncluded(configClient.Variables())
+	if err != nil {
+		return nil, err
+	}
+	client.includePrereleases = client.includePrereleases || includePrereleases
+
 	// if there is an injected repository, use it, otherwise use a default one
 	if client.repository == nil {
-		r, err := repositoryFactory(provider, configClient.Variables())
+		r, err := repositoryFactory(client.ctx, provider, configClient.Variables(), client.includePrereleases)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get repository client for the %s with name %s", provider.Type(), provider.Name())
 		}
@@ -150,8 +221,9 @@ type Repository interface {
 
 var _ Repository = &test.FakeRepository{}
 
-// repositoryFactory returns the repository implementation corresponding to the provider URL.
-func repositoryFactory(providerConfig config.Provider, configVariablesClient config.VariablesClient) (Repository, error) {
+// repositoryFactory returns the repository implementation corresponding to the provider URL; if ctx is not nil,
+// the requests to remote repositories are bound to it.
+func repositoryFactory(ctx context.Context, providerConfig config.Provider, configVariablesClient config.VariablesClient, includePrereleases bool) (Repository, error) {
 	// parse the repository url
 	rURL, err := url.Parse(providerConfig.URL())
 	if err != nil {
@@ -159,17 +231,60 @@ func repositoryFactory(providerConfig config.Provider, configVariablesClient con
 	}
 
 	// if the url is a github repository
-	if rURL.Scheme == httpsScheme && rURL.Host == githubDomain {
-		repo, err := newGitHubRepository(providerConfig, configVariablesClient)
+	if rURL.Scheme == httpsScheme && isGitHubHost(rURL.Host, configVariablesClient) {
+		opts := []githubRepositoryOption{}
+		if ctx != nil {
+			opts = append(opts, injectGithubContext(ctx))
+		}
+		if includePrereleases {
+			opts = append(opts, injectGithubIncludePrereleases())
+		}
+		repo, err := newGitHubRepository(providerConfig, configVariablesClient, opts...)
 		if err != nil {
 			return nil, errors.Wrap(err, "error creating the GitHub repository client")
 		}
-		return repo, err
+		return newCachedRepository(repo, providerConfig, configVariablesClient)
+	}
+
+	// if the url is a gitlab repository
+	if rURL.Scheme == httpsScheme && isGitLabHost(rURL.Host, configVariablesClient) {
+		opts := []gitlabRepositoryOption{}
+		if ctx != nil {
+			opts = append(opts, injectGitLabContext(ctx))
+		}
+		if includePrereleases {
+			opts = append(opts, injectGitLabIncludePrereleases())
+		}
+		repo, err := newGitLabRepository(providerConfig, configVariablesClient, opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the GitLab repository client")
+		}
+		return newCachedRepository(repo, providerConfig, configVariablesClient)
+	}
+
+	// if the url is an OCI registry repository
+	if rURL.Scheme == ociScheme {
+		opts := []ociRepositoryOption{}
+		if ctx != nil {
+			opts = append(opts, injectOCIContext(ctx))
+		}
+		if includePrereleases {
+			opts = append(opts, injectOCIIncludePrereleases())
+		}
+		repo, err := newOCIRepository(providerConfig, configVariablesClient, opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the OCI repository client")
+		}
+		return newCachedRepository(repo, providerConfig, configVariablesClient)
 	}
 
 	// if the url is a local filesystem repository
 	if rURL.Scheme == "file" || rURL.Scheme == "" {
-		repo, err := newLocalRepository(providerConfig, configVariablesClient)
+		opts := []localRepositoryOption{}
+		if includePrereleases {
+			opts = append(opts, injectLocalIncludePrereleases())
+		}
+		repo, err := newLocalRepository(providerConfig, configVariablesClient, opts...)
 		if err != nil {
 			return nil, errors.Wrap(err, "error creating the local filesystem repository client")
 		}
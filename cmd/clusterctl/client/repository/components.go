@@ -18,17 +18,21 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+	"sigs.k8s.io/cluster-api/util/container"
 	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
 )
 
@@ -63,13 +67,19 @@ type Components interface {
 	// This value is derived by the component YAML.
 	Variables() []string
 
-	// Images required to install the provider components.
+	// VariableMap used by the provider components with their default values. If the value is `nil`, there is no
+	// default and the variable is required.
+	// This value is derived by the component YAML.
+	VariableMap() map[string]*string
+
+	// Images required to install the provider components, sorted and without duplicates, including the images
+	// used by init containers and sidecars; image overrides and image registry overrides are already applied.
 	// This value is derived by the component YAML.
 	Images() []string
 
 	// TargetNamespace where the provider components will be installed.
-	// By default this value is derived by the component YAML, but it is possible to override it
-	// during the creation of the Components object.
+	// By default this value is derived by the provider's metadata or by the component YAML, but it is possible
+	// to override it during the creation of the Components object.
 	TargetNamespace() string
 
 	// InventoryObject returns the clusterctl inventory object representing the provider that will be
@@ -81,6 +91,17 @@ type Components interface {
 
 	// Objs return the components in the form of a list of Unstructured objects.
 	Objs() []unstructured.Unstructured
+
+	// SplitByKind splits the components into subsets by category, e.g. for managing CRDs, RBAC and controllers
+	// in separate GitOps sync waves. Subsets are returned in the order they should be installed, starting with
+	// the CRDs; empty subsets are omitted.
+	// All the subsets share the version, variables and images of the original components.
+	SplitByKind() []ComponentsSubset
+
+	// PreInstallObjs and PostInstallObjs return the objects declared by the install hooks in the provider's metadata,
+	// to be created before and after the provider components; the objects are not included in Objs.
+	PreInstallObjs() []unstructured.Unstructured
+	PostInstallObjs() []unstructured.Unstructured
 }
 
 // components implement Components.
@@ -88,9 +109,12 @@ type components struct {
 	config.Provider
 	version         string
 	variables       []string
+	variableMap     map[string]*string
 	images          []string
 	targetNamespace string
 	objs            []unstructured.Unstructured
+	preInstallObjs  []unstructured.Unstructured
+	postInstallObjs []unstructured.Unstructured
 }
 
 // ensure components implement Components.
@@ -104,6 +128,10 @@ func (c *components) Variables() []string {
 	return c.variables
 }
 
+func (c *components) VariableMap() map[string]*string {
+	return c.variableMap
+}
+
 func (c *components) Images() []string {
 	return c.images
 }
@@ -136,6 +164,14 @@ func (c *components) Objs() []unstructured.Unstructured {
 	return c.objs
 }
 
+func (c *components) PreInstallObjs() []unstructured.Unstructured {
+	return c.preInstallObjs
+}
+
+func (c *components) PostInstallObjs() []unstructured.Unstructured {
+	return c.postInstallObjs
+}
+
 func (c *components) Yaml() ([]byte, error) {
 	return utilyaml.FromUnstructured(c.objs)
 }
@@ -148,6 +184,21 @@ type ComponentsOptions struct {
 	// SkipTemplateProcess allows for skipping the call to the template processor, including also variable replacement in the component YAML.
 	// NOTE this works only if the rawYaml is a valid yaml by itself, like e.g when using envsubst/the simple processor.
 	SkipTemplateProcess bool
+	// Categories, if set, restricts the components to the objects belonging to the given categories,
+	// e.g. for rendering the provider components without the CRDs.
+	Categories []ComponentsCategory
+	// ExtraLabels and ExtraAnnotations are applied to all the provider components, e.g. for tagging them with
+	// owner or cost-center metadata; keys in the clusterctl.cluster.x-k8s.io domain and the provider label are
+	// reserved for clusterctl and can't be used.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+	// ImageRegistry, if set, replaces the registry of all the images in the components, preserving the repository path,
+	// the tag and the digest of each image.
+	ImageRegistry string
+	// IncludePrereleases makes prerelease versions, e.g. release candidates, eligible when Version is a version range.
+	// NOTE: the latest version is resolved when creating the repository client, so it is affected only by the
+	// corresponding repository client option.
+	IncludePrereleases bool
 }
 
 // ComponentsInput represents all the inputs required by NewComponents.
@@ -157,6 +208,26 @@ type ComponentsInput struct {
 	Processor    yaml.Processor
 	RawYaml      []byte
 	Options      ComponentsOptions
+	// Metadata of the provider, if available; it is used for defaulting the target namespace and for reading the install hooks.
+	Metadata *clusterctlv1.Metadata
+	// Transformers to be applied to the provider objects, in order.
+	Transformers []ComponentsTransformer
+}
+
+// ComponentsTransformer mutates the objects of the provider components, e.g. for adding sidecars or for adjusting
+// the resources of the provider controllers.
+// Transformers receive the objects after the clusterctl transformations setting the target namespace, the RBAC names,
+// the image overrides and the extra labels and annotations, and before adding the clusterctl labels.
+type ComponentsTransformer interface {
+	Transform(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+}
+
+// ComponentsTransformerFunc is an adapter for using a function as a ComponentsTransformer.
+type ComponentsTransformerFunc func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error)
+
+// Transform calls f(provider, objs).
+func (f ComponentsTransformerFunc) Transform(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	return f(provider, objs)
 }
 
 // NewComponents returns a new objects embedding a component YAML file
@@ -165,15 +236,36 @@ type ComponentsInput struct {
 // from the provider repositories:
 // 1. Checks for all the variables in the component YAML file and replace with corresponding config values
 // 2. The variables replacement can be skipped using the SkipTemplateProcess flag in the input options
-// 3. Ensure all the provider components are deployed in the target namespace (apply only to namespaced objects)
+// 3. Ensure all the provider components are deployed in the target namespace (apply only to namespaced objects);
+// if not specified in the input options, the target namespace defaults to the DefaultTargetNamespace in the provider's
+// metadata, or to the namespace defined in the component YAML.
 // 4. Ensure all the ClusterRoleBinding which are referencing namespaced objects have the name prefixed with the namespace name
-// 5. Adds labels to all the components in order to allow easy identification of the provider objects.
+// 5. Replaces the registry of the images, if requested; this happens after applying the image overrides
+// defined in the clusterctl configuration.
+// 6. Adds the extra labels and annotations in the input options, if any.
+// 7. Applies the transformers in the input, if any, in order; the resulting objects are validated, and namespaced
+// objects without a namespace are moved to the target namespace.
+// 8. Adds labels to all the components in order to allow easy identification of the provider objects.
+// 9. If requested, restricts the components to the objects belonging to the given categories.
 func NewComponents(input ComponentsInput) (Components, error) {
+	if err := validateComponentsCategories(input.Options.Categories); err != nil {
+		return nil, err
+	}
+
+	if err := validateExtraMetadata(input.Options.ExtraLabels, input.Options.ExtraAnnotations); err != nil {
+		return nil, err
+	}
+
 	variables, err := input.Processor.GetVariables(input.RawYaml)
 	if err != nil {
 		return nil, err
 	}
 
+	variableMap, err := input.Processor.GetVariableMap(input.RawYaml)
+	if err != nil {
+		return nil, err
+	}
+
 	// If requested, we are skipping the call to the template processor; however, it is important to
 	// notice that this could work only if the rawYaml is a valid yaml by itself.
 	processedYaml := input.RawYaml
@@ -198,10 +290,14 @@ func NewComponents(input ComponentsInput) (Components, error) {
 		return nil, errors.Wrap(err, "failed to apply image overrides")
 	}
 
-	// Inspect the list of objects for the images required by the provider component.
-	images, err := util.InspectImages(objs)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to detect required images")
+	// Apply the image registry override, if defined
+	if input.Options.ImageRegistry != "" {
+		objs, err = util.FixImages(objs, func(image string) (string, error) {
+			return container.ModifyImageRegistry(image, input.Options.ImageRegistry)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to apply the image registry override")
+		}
 	}
 
 	// inspect the list of objects for the default target namespace
@@ -212,8 +308,12 @@ func NewComponents(input ComponentsInput) (Components, error) {
 	}
 
 	// Ensures all the provider components are deployed in the target namespace (apply only to namespaced objects)
-	// if targetNamespace is not specified, then defaultTargetNamespace is used. In case both targetNamespace and defaultTargetNamespace
-	// are empty, an error is returned
+	// if targetNamespace is not specified, then the default target namespace from the provider's metadata is used, and
+	// then defaultTargetNamespace. In case all of them are empty, an error is returned
+
+	if input.Options.TargetNamespace == "" && input.Metadata != nil {
+		input.Options.TargetNamespace = input.Metadata.DefaultTargetNamespace
+	}
 
 	if input.Options.TargetNamespace == "" {
 		input.Options.TargetNamespace = defaultTargetNamespace
@@ -237,19 +337,90 @@ func NewComponents(input ComponentsInput) (Components, error) {
 		return nil, errors.Wrap(err, "failed to fix ClusterRoleBinding names")
 	}
 
+	// Add extra labels and annotations, if any.
+	// NOTE: this happens before adding common labels, so clusterctl's own labels always take precedence.
+	objs = addExtraMetadata(objs, input.Options.ExtraLabels, input.Options.ExtraAnnotations)
+
+	// Apply the transformers, if any.
+	// NOTE: this happens before adding common labels, so transformers can't alter the labels identifying the provider objects.
+	for i, t := range input.Transformers {
+		objs, err = t.Transform(input.Provider, objs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to apply the components transformer %d", i)
+		}
+	}
+	if len(input.Transformers) > 0 {
+		if err := validateTransformedObjs(objs, input.Options.TargetNamespace); err != nil {
+			return nil, errors.Wrap(err, "invalid objects returned by the components transformers")
+		}
+	}
+
+	// Inspect the list of objects for the images required by the provider component.
+	// NOTE: this happens after applying the transformers, so images added by transformers (e.g. sidecars) are included.
+	images, err := util.InspectImages(objs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to detect required images")
+	}
+	images = sets.NewString(images...).List()
+
 	// Add common labels.
 	objs = addCommonLabels(objs, input.Provider)
 
+	// Filter the objects by category, if requested.
+	if len(input.Options.Categories) > 0 {
+		objs = filterObjsByCategory(objs, input.Options.Categories...)
+	}
+
+	// Process the objects declared by the install hooks in the provider's metadata, if any.
+	var preInstallObjs, postInstallObjs []unstructured.Unstructured
+	if input.Metadata != nil && input.Metadata.InstallHooks != nil {
+		preInstallObjs, err = getInstallHookObjs(input, input.Metadata.InstallHooks.PreInstall)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to process the pre-install hooks")
+		}
+		postInstallObjs, err = getInstallHookObjs(input, input.Metadata.InstallHooks.PostInstall)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to process the post-install hooks")
+		}
+	}
+
 	return &components{
 		Provider:        input.Provider,
 		version:         input.Options.Version,
 		variables:       variables,
+		variableMap:     variableMap,
 		images:          images,
 		targetNamespace: input.Options.TargetNamespace,
 		objs:            objs,
+		preInstallObjs:  preInstallObjs,
+		postInstallObjs: postInstallObjs,
 	}, nil
 }
 
+// validateTransformedObjs checks that all the objects returned by the transformers are valid Kubernetes objects, and
+// that all the namespaced objects are in the target namespace; namespaced objects without a namespace are moved to
+// the target namespace.
+func validateTransformedObjs(objs []unstructured.Unstructured, targetNamespace string) error {
+	errList := []error{}
+	for i := range objs {
+		o := &objs[i]
+		if o.GetAPIVersion() == "" || o.GetKind() == "" || o.GetName() == "" {
+			errList = append(errList, errors.Errorf("object %d must have apiVersion, kind and name", i))
+			continue
+		}
+		if !util.IsResourceNamespaced(o.GetKind()) {
+			continue
+		}
+		if o.GetNamespace() == "" {
+			o.SetNamespace(targetNamespace)
+		}
+		if o.GetNamespace() != targetNamespace {
+			errList = append(errList, errors.Errorf("%s %s/%s must be in the %q target namespace", o.GetKind(), o.GetNamespace(), o.GetName(), targetNamespace))
+		}
+	}
+	return kerrors.NewAggregate(errList)
+}
+
 // inspectTargetNamespace identifies the name of the namespace object contained in the components YAML, if any.
 // In case more than one Namespace object is identified, an error is returned.
 func inspectTargetNamespace(objs []unstructured.Unstructured) (string, error) {
@@ -281,7 +452,8 @@ func addNamespaceIfMissing(objs []unstructured.Unstructured, targetNamespace str
 	if !namespaceObjectFound {
 		objs = append(objs, unstructured.Unstructured{
 			Object: map[string]interface{}{
-				"kind": namespaceKind,
+				"apiVersion": "v1",
+				"kind":       namespaceKind,
 				"metadata": map[string]interface{}{
 					"name": targetNamespace,
 				},
@@ -397,6 +569,63 @@ func addCommonLabels(objs []unstructured.Unstructured, provider config.Provider)
 	return objs
 }
 
+// validateExtraMetadata ensures extra labels and annotations do not use keys reserved for clusterctl.
+func validateExtraMetadata(labels, annotations map[string]string) error {
+	for k := range labels {
+		if isReservedMetadataKey(k) {
+			return errors.Errorf("invalid extra label %q: the key is reserved for clusterctl", k)
+		}
+	}
+	for k := range annotations {
+		if isReservedMetadataKey(k) {
+			return errors.Errorf("invalid extra annotation %q: the key is reserved for clusterctl", k)
+		}
+	}
+	return nil
+}
+
+// isReservedMetadataKey returns true if a label or annotation key is in the clusterctl.cluster.x-k8s.io domain
+// (or in one of its subdomains), or if it is the provider label.
+func isReservedMetadataKey(key string) bool {
+	if key == clusterv1.ProviderLabelName {
+		return true
+	}
+	domain := strings.SplitN(key, "/", 2)[0]
+	return domain == clusterctlv1.ClusterctlLabelName || strings.HasSuffix(domain, "."+clusterctlv1.ClusterctlLabelName)
+}
+
+// addExtraMetadata adds extra labels and annotations to all the provider components.
+func addExtraMetadata(objs []unstructured.Unstructured, extraLabels, extraAnnotations map[string]string) []unstructured.Unstructured {
+	if len(extraLabels) == 0 && len(extraAnnotations) == 0 {
+		return objs
+	}
+
+	for _, o := range objs {
+		if len(extraLabels) > 0 {
+			labels := o.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			for k, v := range extraLabels {
+				labels[k] = v
+			}
+			o.SetLabels(labels)
+		}
+		if len(extraAnnotations) > 0 {
+			annotations := o.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			for k, v := range extraAnnotations {
+				annotations[k] = v
+			}
+			o.SetAnnotations(annotations)
+		}
+	}
+
+	return objs
+}
+
 func getCommonLabels(provider config.Provider) map[string]string {
 	return map[string]string{
 		clusterctlv1.ClusterctlLabelName: "",
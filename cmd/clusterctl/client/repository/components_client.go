@@ -18,6 +18,7 @@ package repository
 
 import (
 	"github.com/pkg/errors"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
 	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
@@ -36,6 +37,10 @@ type componentsClient struct {
 	repository   Repository
 	configClient config.Client
 	processor    yaml.Processor
+	// includePrereleases makes prerelease versions eligible when resolving a version range.
+	includePrereleases bool
+	// transformers are applied to the provider components, in order.
+	transformers []ComponentsTransformer
 }
 
 // ensure componentsClient implements ComponentsClient.
@@ -58,11 +63,54 @@ func (f *componentsClient) Raw(options ComponentsOptions) ([]byte, error) {
 
 // Get returns the components from a repository.
 func (f *componentsClient) Get(options ComponentsOptions) (Components, error) {
+	metadata, err := f.validateContract(&options)
+	if err != nil {
+		return nil, err
+	}
+
 	file, err := f.getRawBytes(&options)
 	if err != nil {
 		return nil, err
 	}
-	return NewComponents(ComponentsInput{f.provider, f.configClient, f.processor, file, options})
+	return NewComponents(ComponentsInput{
+		Provider:     f.provider,
+		ConfigClient: f.configClient,
+		Processor:    f.processor,
+		RawYaml:      file,
+		Options:      options,
+		Metadata:     metadata,
+		Transformers: f.transformers,
+	})
+}
+
+// validateContract reads the provider's metadata and checks, before downloading the components, that the requested
+// version is defined in the metadata and that its contract is supported by the current version of clusterctl.
+func (f *componentsClient) validateContract(options *ComponentsOptions) (*clusterctlv1.Metadata, error) {
+	if options.Version == "" {
+		options.Version = f.repository.DefaultVersion()
+	}
+
+	resolvedVersion, err := resolveVersion(f.repository, options.Version, f.includePrereleases || options.IncludePrereleases)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the version for provider %q", f.provider.ManifestLabel())
+	}
+	options.Version = resolvedVersion
+
+	metadata, err := newMetadataClient(f.provider, options.Version, f.repository, f.configClient.Variables()).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. The latest version of a local repository is resolved only when reading files; in this case the contract
+	// is validated when installing the components.
+	if options.Version == latestVersionTag {
+		return metadata, nil
+	}
+
+	if _, err := ValidateMetadataContract(metadata, options.Version); err != nil {
+		return nil, errors.Wrapf(err, "invalid version for provider %q", f.provider.ManifestLabel())
+	}
+	return metadata, nil
 }
 
 func (f *componentsClient) getRawBytes(options *ComponentsOptions) ([]byte, error) {
@@ -73,6 +121,12 @@ func (f *componentsClient) getRawBytes(options *ComponentsOptions) ([]byte, erro
 		options.Version = f.repository.DefaultVersion()
 	}
 
+	resolvedVersion, err := resolveVersion(f.repository, options.Version, f.includePrereleases || options.IncludePrereleases)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the version for provider %q", f.provider.ManifestLabel())
+	}
+	options.Version = resolvedVersion
+
 	// Retrieve the path where the path is stored
 	path := f.repository.ComponentsPath()
 
@@ -69,6 +69,12 @@ func Test_componentsClient_Get(t *testing.T) {
 	configClient, err := config.New("", config.InjectReader(test.NewFakeReader().WithVar(variableName, variableValue)))
 	g.Expect(err).NotTo(HaveOccurred())
 
+	metadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+		},
+	}
+
 	type fields struct {
 		provider   config.Provider
 		repository Repository
@@ -99,6 +105,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -120,6 +127,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -142,6 +150,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -177,6 +186,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -192,6 +202,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -206,6 +217,73 @@ func Test_componentsClient_Get(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Default targetNamespace from the metadata takes precedence over the one in the components YAML",
+			fields: fields{
+				provider: p1,
+				repository: test.NewFakeRepository().
+					WithPaths("root", "components.yaml").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", &clusterctlv1.Metadata{
+						ReleaseSeries: []clusterctlv1.ReleaseSeries{
+							{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+						},
+						DefaultTargetNamespace: "ns-from-metadata",
+					}).
+					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
+			},
+			args: args{
+				version:         "v1.0.0",
+				targetNamespace: "",
+			},
+			want: want{
+				provider:        p1,
+				version:         "v1.0.0",               // version detected
+				targetNamespace: "ns-from-metadata",     // default targetNamespace from the metadata applied
+				variables:       []string{variableName}, // variable detected
+			},
+			wantErr: false,
+		},
+		{
+			name: "successfully gets the components for a version range",
+			fields: fields{
+				provider: p1,
+				repository: test.NewFakeRepository().
+					WithPaths("root", "components.yaml").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.1", metadata).
+					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)).
+					WithFile("v1.0.1", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)).
+					WithVersions("v2.0.0"),
+			},
+			args: args{
+				version:         ">=1.0.0 <2.0.0",
+				targetNamespace: "",
+			},
+			want: want{
+				provider:        p1,
+				version:         "v1.0.1", // highest version in the range
+				targetNamespace: namespaceName,
+				variables:       []string{variableName},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Fails if no version satisfies the requested version range",
+			fields: fields{
+				provider: p1,
+				repository: test.NewFakeRepository().
+					WithPaths("root", "components.yaml").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
+					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
+			},
+			args: args{
+				version:         ">=2.0.0",
+				targetNamespace: "",
+			},
+			wantErr: true,
+		},
 		{
 			name: "Fails if requested version does not exists",
 			fields: fields{
@@ -213,6 +291,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(controllerYaml, configMapYaml)),
 			},
 			args: args{
@@ -221,6 +300,42 @@ func Test_componentsClient_Get(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Fails if requested version is not defined in the metadata",
+			fields: fields{
+				provider: p1,
+				repository: test.NewFakeRepository().
+					WithPaths("root", "components.yaml").
+					WithDefaultVersion("v1.1.0").
+					WithMetadata("v1.1.0", metadata).
+					WithFile("v1.1.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
+			},
+			args: args{
+				version:         "v1.1.0",
+				targetNamespace: "",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Fails if requested version has a contract not supported by clusterctl",
+			fields: fields{
+				provider: p1,
+				repository: test.NewFakeRepository().
+					WithPaths("root", "components.yaml").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", &clusterctlv1.Metadata{
+						ReleaseSeries: []clusterctlv1.ReleaseSeries{
+							{Major: 1, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+						},
+					}).
+					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
+			},
+			args: args{
+				version:         "v1.0.0",
+				targetNamespace: "",
+			},
+			wantErr: true,
+		},
 		{
 			name: "Fails if yaml processor cannot get Variables",
 			fields: fields{
@@ -228,6 +343,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
 				processor: test.NewFakeProcessor().WithGetVariablesErr(errors.New("cannot get vars")),
 			},
@@ -244,6 +360,7 @@ func Test_componentsClient_Get(t *testing.T) {
 				repository: test.NewFakeRepository().
 					WithPaths("root", "components.yaml").
 					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", metadata).
 					WithFile("v1.0.0", "components.yaml", utilyaml.JoinYaml(namespaceYaml, controllerYaml, configMapYaml)),
 
 				processor: test.NewFakeProcessor().WithProcessErr(errors.New("cannot process")),
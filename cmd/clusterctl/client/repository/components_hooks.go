@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+// getInstallHookObjs returns the objects declared by an install hook in the provider's metadata, processed with the same
+// variables of the provider components; namespaced objects without a namespace are moved to the target namespace.
+func getInstallHookObjs(input ComponentsInput, hooks []runtime.RawExtension) ([]unstructured.Unstructured, error) {
+	objs := []unstructured.Unstructured{}
+	for i, hook := range hooks {
+		raw := hook.Raw
+		if !input.Options.SkipTemplateProcess {
+			var err error
+			raw, err = input.Processor.Process(raw, input.ConfigClient.Variables().Get)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to perform variable substitution on object %d", i)
+			}
+		}
+
+		hookObjs, err := utilyaml.ToUnstructured(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse object %d", i)
+		}
+		for j := range hookObjs {
+			o := &hookObjs[j]
+			if o.GetAPIVersion() == "" || o.GetKind() == "" || o.GetName() == "" {
+				return nil, errors.Errorf("object %d must have apiVersion, kind and name", i)
+			}
+			if util.IsResourceNamespaced(o.GetKind()) && o.GetNamespace() == "" {
+				o.SetNamespace(input.Options.TargetNamespace)
+			}
+		}
+		objs = append(objs, hookObjs...)
+	}
+	return objs, nil
+}
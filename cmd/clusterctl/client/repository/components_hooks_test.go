@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_components_InstallHooks(t *testing.T) {
+	rawYaml := []byte("apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: manager\n")
+
+	configMap := runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"region":"${REGION}"}}`)}
+	namespace := runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"shared"}}`)}
+	invalid := runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)}
+
+	tests := []struct {
+		name                string
+		hooks               *clusterctlv1.InstallHooks
+		skipTemplateProcess bool
+		wantPreInstall      int
+		wantPostInstall     int
+		wantErr             bool
+	}{
+		{
+			name:  "no hooks",
+			hooks: nil,
+		},
+		{
+			name: "hook objects are processed",
+			hooks: &clusterctlv1.InstallHooks{
+				PreInstall:  []runtime.RawExtension{namespace},
+				PostInstall: []runtime.RawExtension{configMap},
+			},
+			wantPreInstall:  1,
+			wantPostInstall: 1,
+		},
+		{
+			name: "fails if a variable is missing",
+			hooks: &clusterctlv1.InstallHooks{
+				PreInstall: []runtime.RawExtension{{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"${MISSING}"}}`)}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fails if an object has no name",
+			hooks: &clusterctlv1.InstallHooks{
+				PostInstall: []runtime.RawExtension{invalid},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient, err := config.New("", config.InjectReader(test.NewFakeReader().WithVar("REGION", "eu-west-1")))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			components, err := NewComponents(ComponentsInput{
+				Provider:     config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+				ConfigClient: configClient,
+				Processor:    yaml.NewSimpleProcessor(),
+				RawYaml:      rawYaml,
+				Options: ComponentsOptions{
+					Version:         "v1.0.0",
+					TargetNamespace: "infra-system",
+				},
+				Metadata: &clusterctlv1.Metadata{InstallHooks: tt.hooks},
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(components.PreInstallObjs()).To(HaveLen(tt.wantPreInstall))
+			g.Expect(components.PostInstallObjs()).To(HaveLen(tt.wantPostInstall))
+
+			// hook objects are not part of the provider components.
+			g.Expect(components.Objs()).To(HaveLen(2)) // the namespace and the manager
+			for _, o := range components.PreInstallObjs() {
+				g.Expect(o.GetNamespace()).To(BeEmpty())
+			}
+			for _, o := range components.PostInstallObjs() {
+				g.Expect(o.GetNamespace()).To(Equal("infra-system"))
+				g.Expect(o.GetLabels()).NotTo(HaveKey(clusterv1.ProviderLabelName))
+				g.Expect(o.Object["data"]).To(HaveKeyWithValue("region", "eu-west-1"))
+			}
+		})
+	}
+}
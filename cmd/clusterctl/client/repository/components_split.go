@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ComponentsCategory defines a category of provider components.
+type ComponentsCategory string
+
+const (
+	// CRDsComponentsCategory groups the CustomResourceDefinitions of a provider.
+	CRDsComponentsCategory = ComponentsCategory("crds")
+
+	// NamespaceComponentsCategory groups the Namespace where the provider components are hosted.
+	NamespaceComponentsCategory = ComponentsCategory("namespace")
+
+	// RBACComponentsCategory groups the ServiceAccounts, Roles, ClusterRoles and related bindings of a provider.
+	RBACComponentsCategory = ComponentsCategory("rbac")
+
+	// ControllerComponentsCategory groups all the other provider components, e.g. the controller Deployment,
+	// Services, webhook configurations and certificates.
+	ControllerComponentsCategory = ComponentsCategory("controller")
+)
+
+// componentsCategories lists all the components categories in the order they should be installed.
+var componentsCategories = []ComponentsCategory{
+	CRDsComponentsCategory,
+	NamespaceComponentsCategory,
+	RBACComponentsCategory,
+	ControllerComponentsCategory,
+}
+
+// ComponentsSubset is the subset of the provider components belonging to a category.
+type ComponentsSubset struct {
+	Category   ComponentsCategory
+	Components Components
+}
+
+func (c *components) SplitByKind() []ComponentsSubset {
+	subsets := []ComponentsSubset{}
+	for _, category := range componentsCategories {
+		objs := filterObjsByCategory(c.objs, category)
+		if len(objs) == 0 {
+			continue
+		}
+		subsets = append(subsets, ComponentsSubset{
+			Category:   category,
+			Components: c.withObjs(objs),
+		})
+	}
+	return subsets
+}
+
+// withObjs returns a copy of the components with a different set of objects.
+func (c *components) withObjs(objs []unstructured.Unstructured) *components {
+	return &components{
+		Provider:        c.Provider,
+		version:         c.version,
+		variables:       c.variables,
+		variableMap:     c.variableMap,
+		images:          c.images,
+		targetNamespace: c.targetNamespace,
+		objs:            objs,
+	}
+}
+
+// validateComponentsCategories returns an error if any of the given categories is unknown.
+func validateComponentsCategories(categories []ComponentsCategory) error {
+	for _, category := range categories {
+		found := false
+		for _, c := range componentsCategories {
+			if category == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("invalid components category %q. Valid values are %q", category, componentsCategories)
+		}
+	}
+	return nil
+}
+
+// filterObjsByCategory returns the objects belonging to one of the given categories, preserving their order.
+func filterObjsByCategory(objs []unstructured.Unstructured, categories ...ComponentsCategory) []unstructured.Unstructured {
+	ret := []unstructured.Unstructured{}
+	for _, o := range objs {
+		objCategory := componentsCategoryOf(o)
+		for _, category := range categories {
+			if objCategory == category {
+				ret = append(ret, o)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// componentsCategoryOf returns the category of a provider component.
+func componentsCategoryOf(obj unstructured.Unstructured) ComponentsCategory {
+	switch obj.GetKind() {
+	case "CustomResourceDefinition":
+		return CRDsComponentsCategory
+	case namespaceKind:
+		return NamespaceComponentsCategory
+	case "ServiceAccount", "Role", roleBindingKind, clusterRoleKind, clusterRoleBindingKind:
+		return RBACComponentsCategory
+	default:
+		return ControllerComponentsCategory
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+var splitComponentsYaml = []byte("apiVersion: apps/v1\n" +
+	"kind: Deployment\n" +
+	"metadata:\n" +
+	"  name: manager\n" +
+	"---\n" +
+	"apiVersion: rbac.authorization.k8s.io/v1\n" +
+	"kind: ClusterRole\n" +
+	"metadata:\n" +
+	"  name: manager-role\n" +
+	"---\n" +
+	"apiVersion: v1\n" +
+	"kind: ServiceAccount\n" +
+	"metadata:\n" +
+	"  name: manager\n" +
+	"---\n" +
+	"apiVersion: apiextensions.k8s.io/v1\n" +
+	"kind: CustomResourceDefinition\n" +
+	"metadata:\n" +
+	"  name: infraclusters.infrastructure.cluster.x-k8s.io\n")
+
+func newSplitComponents(g *WithT, categories ...ComponentsCategory) (Components, error) {
+	configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	return NewComponents(ComponentsInput{
+		Provider:     config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+		ConfigClient: configClient,
+		Processor:    yaml.NewSimpleProcessor(),
+		RawYaml:      splitComponentsYaml,
+		Options: ComponentsOptions{
+			Version:         "v1.0.0",
+			TargetNamespace: "infra-system",
+			Categories:      categories,
+		},
+	})
+}
+
+func componentsKinds(components Components) []string {
+	ret := []string{}
+	for _, o := range components.Objs() {
+		ret = append(ret, o.GetKind())
+	}
+	return ret
+}
+
+func Test_components_SplitByKind(t *testing.T) {
+	g := NewWithT(t)
+
+	components, err := newSplitComponents(g)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	subsets := components.SplitByKind()
+	g.Expect(subsets).To(HaveLen(4))
+
+	g.Expect(subsets[0].Category).To(Equal(CRDsComponentsCategory))
+	g.Expect(componentsKinds(subsets[0].Components)).To(Equal([]string{"CustomResourceDefinition"}))
+	g.Expect(subsets[1].Category).To(Equal(NamespaceComponentsCategory))
+	g.Expect(componentsKinds(subsets[1].Components)).To(Equal([]string{"Namespace"}))
+	g.Expect(subsets[2].Category).To(Equal(RBACComponentsCategory))
+	g.Expect(componentsKinds(subsets[2].Components)).To(Equal([]string{"ClusterRole", "ServiceAccount"}))
+	g.Expect(subsets[3].Category).To(Equal(ControllerComponentsCategory))
+	g.Expect(componentsKinds(subsets[3].Components)).To(Equal([]string{"Deployment"}))
+
+	for _, s := range subsets {
+		g.Expect(s.Components.Version()).To(Equal("v1.0.0"))
+		g.Expect(s.Components.TargetNamespace()).To(Equal("infra-system"))
+		g.Expect(s.Components.InventoryObject()).To(Equal(components.InventoryObject()))
+	}
+}
+
+func Test_NewComponents_Categories(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []ComponentsCategory
+		wantKinds  []string
+		wantErr    bool
+	}{
+		{
+			name:       "all the components if categories are not set",
+			categories: nil,
+			wantKinds:  []string{"Deployment", "ClusterRole", "ServiceAccount", "CustomResourceDefinition", "Namespace"},
+		},
+		{
+			name:       "components without CRDs",
+			categories: []ComponentsCategory{NamespaceComponentsCategory, RBACComponentsCategory, ControllerComponentsCategory},
+			wantKinds:  []string{"Deployment", "ClusterRole", "ServiceAccount", "Namespace"},
+		},
+		{
+			name:       "only CRDs",
+			categories: []ComponentsCategory{CRDsComponentsCategory},
+			wantKinds:  []string{"CustomResourceDefinition"},
+		},
+		{
+			name:       "fails for invalid categories",
+			categories: []ComponentsCategory{"foo"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			components, err := newSplitComponents(g, tt.categories...)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(componentsKinds(components)).To(Equal(tt.wantKinds))
+		})
+	}
+}
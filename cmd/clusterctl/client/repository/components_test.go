@@ -20,11 +20,14 @@ import (
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
 )
 
 func Test_inspectTargetNamespace(t *testing.T) {
@@ -550,3 +553,311 @@ func Test_addCommonLabels(t *testing.T) {
 		})
 	}
 }
+
+func Test_addExtraMetadata(t *testing.T) {
+	type args struct {
+		objs        []unstructured.Unstructured
+		labels      map[string]string
+		annotations map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []unstructured.Unstructured
+	}{
+		{
+			name: "add labels and annotations",
+			args: args{
+				objs: []unstructured.Unstructured{
+					{
+						Object: map[string]interface{}{
+							"kind": "Deployment",
+							"metadata": map[string]interface{}{
+								"labels": map[string]interface{}{
+									"app": "manager",
+								},
+							},
+						},
+					},
+				},
+				labels:      map[string]string{"cost-center": "123"},
+				annotations: map[string]string{"owner": "team-a"},
+			},
+			want: []unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"kind": "Deployment",
+						"metadata": map[string]interface{}{
+							"labels": map[string]interface{}{
+								"app":         "manager",
+								"cost-center": "123",
+							},
+							"annotations": map[string]interface{}{
+								"owner": "team-a",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "no-op if there are no extra labels and annotations",
+			args: args{
+				objs: []unstructured.Unstructured{
+					{
+						Object: map[string]interface{}{
+							"kind": "Deployment",
+						},
+					},
+				},
+			},
+			want: []unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"kind": "Deployment",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got := addExtraMetadata(tt.args.objs, tt.args.labels, tt.args.annotations)
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_validateExtraMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		wantErr     bool
+	}{
+		{
+			name:        "pass with user defined keys",
+			labels:      map[string]string{"cost-center": "123", "example.com/owner": "team-a"},
+			annotations: map[string]string{"owner": "team-a"},
+			wantErr:     false,
+		},
+		{
+			name:    "fails with the clusterctl label",
+			labels:  map[string]string{clusterctlv1.ClusterctlLabelName: ""},
+			wantErr: true,
+		},
+		{
+			name:    "fails with the provider label",
+			labels:  map[string]string{clusterv1.ProviderLabelName: "infrastructure-foo"},
+			wantErr: true,
+		},
+		{
+			name:    "fails with labels in the clusterctl domain",
+			labels:  map[string]string{clusterctlv1.ClusterctlCoreLabelName: "inventory"},
+			wantErr: true,
+		},
+		{
+			name:        "fails with annotations in a clusterctl subdomain",
+			annotations: map[string]string{clusterctlv1.CertManagerVersionAnnotation: "v1.0.0"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateExtraMetadata(tt.labels, tt.annotations)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func Test_components_Images(t *testing.T) {
+	g := NewWithT(t)
+
+	rawYaml := []byte("apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: manager\n" +
+		"spec:\n" +
+		"  template:\n" +
+		"    spec:\n" +
+		"      initContainers:\n" +
+		"      - name: init\n" +
+		"        image: k8s.gcr.io/init:v1.0.0\n" +
+		"      containers:\n" +
+		"      - name: manager\n" +
+		"        image: k8s.gcr.io/manager:v1.0.0\n" +
+		"      - name: sidecar\n" +
+		"        image: k8s.gcr.io/kube-rbac-proxy:v0.4.1\n" +
+		"---\n" +
+		"apiVersion: apps/v1\n" +
+		"kind: DaemonSet\n" +
+		"metadata:\n" +
+		"  name: agent\n" +
+		"spec:\n" +
+		"  template:\n" +
+		"    spec:\n" +
+		"      containers:\n" +
+		"      - name: agent\n" +
+		"        image: k8s.gcr.io/manager:v1.0.0\n")
+
+	configClient, err := config.New("", config.InjectReader(test.NewFakeReader().
+		WithImageMeta("infrastructure-infra", "", "v1.0.1")))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	components, err := NewComponents(ComponentsInput{
+		Provider:     config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+		ConfigClient: configClient,
+		Processor:    yaml.NewSimpleProcessor(),
+		RawYaml:      rawYaml,
+		Options: ComponentsOptions{
+			Version:         "v1.0.0",
+			TargetNamespace: "infra-system",
+			ImageRegistry:   "registry.example.com",
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Images include init containers and sidecars, with overrides applied, sorted and without duplicates.
+	g.Expect(components.Images()).To(Equal([]string{
+		"registry.example.com/init:v1.0.1",
+		"registry.example.com/kube-rbac-proxy:v1.0.1",
+		"registry.example.com/manager:v1.0.1",
+	}))
+}
+
+func Test_components_Transformers(t *testing.T) {
+	rawYaml := []byte("apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: manager\n" +
+		"spec:\n" +
+		"  template:\n" +
+		"    spec:\n" +
+		"      containers:\n" +
+		"      - name: manager\n" +
+		"        image: k8s.gcr.io/manager:v1.0.0\n")
+
+	sidecar := func() unstructured.Unstructured {
+		u := unstructured.Unstructured{}
+		u.SetAPIVersion("apps/v1")
+		u.SetKind("Deployment")
+		u.SetName("sidecar")
+		_ = unstructured.SetNestedSlice(u.Object, []interface{}{
+			map[string]interface{}{"name": "sidecar", "image": "k8s.gcr.io/sidecar:v1.0.0"},
+		}, "spec", "template", "spec", "containers")
+		return u
+	}
+
+	// appendOrder records the order the transformers are applied in on the manager Deployment.
+	appendOrder := func(name string) ComponentsTransformer {
+		return ComponentsTransformerFunc(func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+			for i := range objs {
+				if objs[i].GetName() == "manager" {
+					annotations := objs[i].GetAnnotations()
+					if annotations == nil {
+						annotations = map[string]string{}
+					}
+					annotations["order"] += name
+					objs[i].SetAnnotations(annotations)
+				}
+			}
+			return objs, nil
+		})
+	}
+
+	tests := []struct {
+		name         string
+		transformers []ComponentsTransformer
+		wantImages   []string
+		wantErr      bool
+	}{
+		{
+			name: "transformers are applied in order, and objects added by transformers are processed",
+			transformers: []ComponentsTransformer{
+				appendOrder("a"),
+				ComponentsTransformerFunc(func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+					return append(objs, sidecar()), nil
+				}),
+				appendOrder("b"),
+			},
+			wantImages: []string{"k8s.gcr.io/manager:v1.0.0", "k8s.gcr.io/sidecar:v1.0.0"},
+		},
+		{
+			name: "fails if a transformer fails",
+			transformers: []ComponentsTransformer{
+				ComponentsTransformerFunc(func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+					return nil, errors.New("failed")
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "fails if a transformer returns an invalid object",
+			transformers: []ComponentsTransformer{
+				ComponentsTransformerFunc(func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+					u := sidecar()
+					u.SetName("")
+					return append(objs, u), nil
+				}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "fails if a transformer moves an object out of the target namespace",
+			transformers: []ComponentsTransformer{
+				ComponentsTransformerFunc(func(provider config.Provider, objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+					objs[0].SetNamespace("other")
+					return objs, nil
+				}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			components, err := NewComponents(ComponentsInput{
+				Provider:     config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+				ConfigClient: configClient,
+				Processor:    yaml.NewSimpleProcessor(),
+				RawYaml:      rawYaml,
+				Options: ComponentsOptions{
+					Version:         "v1.0.0",
+					TargetNamespace: "infra-system",
+				},
+				Transformers: tt.transformers,
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(components.Images()).To(Equal(tt.wantImages))
+
+			objs := components.Objs()
+			g.Expect(objs).To(HaveLen(3)) // the namespace, the manager and the sidecar
+			for _, o := range objs {
+				// The clusterctl labels are added to the objects returned by the transformers.
+				g.Expect(o.GetLabels()).To(HaveKeyWithValue(clusterv1.ProviderLabelName, "infrastructure-infra"))
+				if o.GetKind() == "Deployment" {
+					g.Expect(o.GetNamespace()).To(Equal("infra-system"))
+				}
+				if o.GetName() == "manager" {
+					g.Expect(o.GetAnnotations()).To(HaveKeyWithValue("order", "ab"))
+				}
+			}
+		})
+	}
+}
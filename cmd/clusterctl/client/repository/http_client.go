@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// NewHTTPClient returns the HTTP client to be used for accessing provider repositories and the other remote sources
+// used by clusterctl, e.g. cluster templates hosted on GitHub.
+//
+// The client uses the proxy defined in the repository-proxy variable, if any, otherwise the proxy defined by the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables; in addition to the system CA certificates, the client
+// trusts the CA certificates in the PEM file defined in the repository-ca-bundle variable, if any.
+func NewHTTPClient(configVariablesClient config.VariablesClient) (*http.Client, error) {
+	proxy, _ := configVariablesClient.Get(config.RepositoryProxyVariable)
+	caBundle, _ := configVariablesClient.Get(config.RepositoryCABundleVariable)
+	if proxy == "" && caBundle == "" {
+		return http.DefaultClient, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, errors.Errorf("invalid value %q for the %s variable: it must be an URL, e.g. http://proxy.example.com:3128", proxy, config.RepositoryProxyVariable)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if caBundle != "" {
+		pemCerts, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the CA bundle defined in the %s variable", config.RepositoryCABundleVariable)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pemCerts) {
+			return nil, errors.Errorf("failed to read the CA bundle defined in the %s variable: the file %q does not contain valid PEM certificates", config.RepositoryCABundleVariable, caBundle)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_NewHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caBundle := filepath.Join(dir, "ca.pem")
+	g := NewWithT(t)
+	g.Expect(os.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)).To(Succeed())
+	invalidCABundle := filepath.Join(dir, "invalid.pem")
+	g.Expect(os.WriteFile(invalidCABundle, []byte("foo"), 0600)).To(Succeed())
+
+	tests := []struct {
+		name         string
+		variables    map[string]string
+		wantDefault  bool
+		wantProxy    string
+		wantTrustsCA bool
+		wantErr      bool
+	}{
+		{
+			name:        "default client if no proxy and CA bundle are set",
+			wantDefault: true,
+		},
+		{
+			name:      "client with proxy",
+			variables: map[string]string{config.RepositoryProxyVariable: "http://proxy.example.com:3128"},
+			wantProxy: "http://proxy.example.com:3128",
+		},
+		{
+			name:         "client with CA bundle",
+			variables:    map[string]string{config.RepositoryCABundleVariable: caBundle},
+			wantTrustsCA: true,
+		},
+		{
+			name:      "fails for invalid proxy",
+			variables: map[string]string{config.RepositoryProxyVariable: "proxy.example.com"},
+			wantErr:   true,
+		},
+		{
+			name:      "fails if the CA bundle does not exist",
+			variables: map[string]string{config.RepositoryCABundleVariable: filepath.Join(dir, "foo.pem")},
+			wantErr:   true,
+		},
+		{
+			name:      "fails if the CA bundle is not valid",
+			variables: map[string]string{config.RepositoryCABundleVariable: invalidCABundle},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			got, err := NewHTTPClient(variableClient)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			if tt.wantDefault {
+				g.Expect(got).To(Equal(http.DefaultClient))
+				return
+			}
+
+			transport, ok := got.Transport.(*http.Transport)
+			g.Expect(ok).To(BeTrue())
+			if tt.wantProxy != "" {
+				proxyURL, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://github.com", nil))
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(proxyURL.String()).To(Equal(tt.wantProxy))
+			}
+			if tt.wantTrustsCA {
+				response, err := got.Get(server.URL)
+				g.Expect(err).NotTo(HaveOccurred())
+				defer response.Body.Close()
+				g.Expect(response.StatusCode).To(Equal(http.StatusOK))
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
@@ -33,6 +35,10 @@ const metadataFile = "metadata.yaml"
 type MetadataClient interface {
 	// Get returns the provider's metadata.
 	Get() (*clusterctlv1.Metadata, error)
+
+	// ValidateContract returns the release series for the version, after checking that the version is defined in the
+	// provider's metadata and that its contract is supported by the current version of clusterctl.
+	ValidateContract() (*clusterctlv1.ReleaseSeries, error)
 }
 
 // metadataClient implements MetadataClient.
@@ -89,7 +95,62 @@ func (f *metadataClient) Get() (*clusterctlv1.Metadata, error) {
 		return nil, errors.Wrapf(err, "error decoding %q for provider %q", metadataFile, f.provider.ManifestLabel())
 	}
 
-	//TODO: consider if to add metadata validation (TBD)
+	if err := validateMetadata(obj); err != nil {
+		return nil, errors.Wrapf(err, "invalid %q for provider %q", metadataFile, f.provider.ManifestLabel())
+	}
 
 	return obj, nil
 }
+
+func (f *metadataClient) ValidateContract() (*clusterctlv1.ReleaseSeries, error) {
+	metadata, err := f.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	releaseSeries, err := ValidateMetadataContract(metadata, f.version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid version for provider %q", f.provider.ManifestLabel())
+	}
+	return releaseSeries, nil
+}
+
+// ValidateMetadataContract returns the release series for a version, after checking that the version is defined in
+// the metadata and that its contract is supported by the current version of clusterctl.
+func ValidateMetadataContract(metadata *clusterctlv1.Metadata, v string) (*clusterctlv1.ReleaseSeries, error) {
+	semVersion, err := version.ParseSemantic(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse version %q", v)
+	}
+
+	releaseSeries := metadata.GetReleaseSeriesForVersion(semVersion)
+	if releaseSeries == nil {
+		return nil, errors.Errorf("version %s is not defined in %q, release series v%d.%d is missing", v, metadataFile, semVersion.Major(), semVersion.Minor())
+	}
+
+	if releaseSeries.Contract != clusterv1.GroupVersion.Version {
+		return nil, errors.Errorf("version %s supports the %s contract, while the current version of clusterctl supports only the %s contract", v, releaseSeries.Contract, clusterv1.GroupVersion.Version)
+	}
+	return releaseSeries, nil
+}
+
+// validateMetadata checks that the metadata defines at least one release series, and that each release series
+// is defined only once and has a contract.
+func validateMetadata(metadata *clusterctlv1.Metadata) error {
+	if len(metadata.ReleaseSeries) == 0 {
+		return errors.New("at least one release series must be defined")
+	}
+
+	series := map[clusterctlv1.ReleaseSeries]bool{}
+	for _, rs := range metadata.ReleaseSeries {
+		if rs.Contract == "" {
+			return errors.Errorf("release series v%d.%d does not define a contract", rs.Major, rs.Minor)
+		}
+		key := clusterctlv1.ReleaseSeries{Major: rs.Major, Minor: rs.Minor}
+		if series[key] {
+			return errors.Errorf("release series v%d.%d is defined more than once", rs.Major, rs.Minor)
+		}
+		series[key] = true
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
@@ -68,6 +69,47 @@ func Test_metadataClient_Get(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Pass with install hooks",
+			fields: fields{
+				provider: config.NewProvider("p1", "", clusterctlv1.CoreProviderType),
+				version:  "v1.0.0",
+				repository: test.NewFakeRepository().
+					WithPaths("root", "").
+					WithDefaultVersion("v1.0.0").
+					WithFile("v1.0.0", "metadata.yaml", []byte("apiVersion: clusterctl.cluster.x-k8s.io/v1alpha3\n"+
+						"kind: Metadata\n"+
+						"releaseSeries:\n"+
+						"- major: 1\n"+
+						"  minor: 2\n"+
+						"  contract: "+test.CurrentCAPIContract+"\n"+
+						"installHooks:\n"+
+						"  preInstall:\n"+
+						"  - apiVersion: v1\n"+
+						"    kind: ConfigMap\n"+
+						"    metadata:\n"+
+						"      name: settings\n")),
+			},
+			want: &clusterctlv1.Metadata{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: clusterctlv1.GroupVersion.String(),
+					Kind:       "Metadata",
+				},
+				ReleaseSeries: []clusterctlv1.ReleaseSeries{
+					{
+						Major:    1,
+						Minor:    2,
+						Contract: test.CurrentCAPIContract,
+					},
+				},
+				InstallHooks: &clusterctlv1.InstallHooks{
+					PreInstall: []runtime.RawExtension{
+						{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"}}`)},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "Fails if the file does not exists",
 			fields: fields{
@@ -110,6 +152,54 @@ func Test_metadataClient_Get(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "Fails if the metadata does not define release series",
+			fields: fields{
+				provider: config.NewProvider("p1", "", clusterctlv1.CoreProviderType),
+				version:  "v1.0.0",
+				repository: test.NewFakeRepository().
+					WithPaths("root", "").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", &clusterctlv1.Metadata{}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Fails if a release series does not define a contract",
+			fields: fields{
+				provider: config.NewProvider("p1", "", clusterctlv1.CoreProviderType),
+				version:  "v1.0.0",
+				repository: test.NewFakeRepository().
+					WithPaths("root", "").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", &clusterctlv1.Metadata{
+						ReleaseSeries: []clusterctlv1.ReleaseSeries{
+							{Major: 1, Minor: 0},
+						},
+					}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Fails if a release series is defined more than once",
+			fields: fields{
+				provider: config.NewProvider("p1", "", clusterctlv1.CoreProviderType),
+				version:  "v1.0.0",
+				repository: test.NewFakeRepository().
+					WithPaths("root", "").
+					WithDefaultVersion("v1.0.0").
+					WithMetadata("v1.0.0", &clusterctlv1.Metadata{
+						ReleaseSeries: []clusterctlv1.ReleaseSeries{
+							{Major: 1, Minor: 0, Contract: test.PreviousCAPIContractNotSupported},
+							{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+						},
+					}),
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
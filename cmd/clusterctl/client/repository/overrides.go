@@ -17,6 +17,7 @@ limitations under the License.
 package repository
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -64,12 +65,7 @@ func newOverride(o *newOverrideInput) Overrider {
 // Path returns the fully formed path to the file within the specified
 // overrides config.
 func (o *overrides) Path() string {
-	basepath := filepath.Join(homedir.HomeDir(), config.ConfigFolder, overrideFolder)
-	f, err := o.configVariablesClient.Get(overrideFolderKey)
-	if err == nil && len(strings.TrimSpace(f)) != 0 {
-		basepath = f
-	}
-
+	basepath, _ := overridesBasePath(o.configVariablesClient)
 	return filepath.Join(
 		basepath,
 		o.providerLabel,
@@ -78,6 +74,51 @@ func (o *overrides) Path() string {
 	)
 }
 
+// overridesBasePath returns the path of the overrides folder, and true if the path is defined in the clusterctl configuration.
+func overridesBasePath(configVariablesClient config.VariablesClient) (string, bool) {
+	f, err := configVariablesClient.Get(overrideFolderKey)
+	if err == nil && len(strings.TrimSpace(f)) != 0 {
+		return f, true
+	}
+	return filepath.Join(homedir.HomeDir(), config.ConfigFolder, overrideFolder), false
+}
+
+// ValidateOverrides checks the overrides layer, reporting a custom overrides folder that does not exist,
+// as well as override folders not matching any of the given providers.
+func ValidateOverrides(configVariablesClient config.VariablesClient, providers []config.Provider) []config.ValidationFinding {
+	findings := []config.ValidationFinding{}
+
+	basepath, custom := overridesBasePath(configVariablesClient)
+	entries, err := os.ReadDir(basepath)
+	if err != nil {
+		// NB. The default overrides folder is optional, while a custom overrides folder is expected to exist.
+		if custom || !os.IsNotExist(err) {
+			findings = append(findings, config.ValidationFinding{
+				Severity: config.ErrorValidationSeverity,
+				Key:      overrideFolderKey,
+				Message:  fmt.Sprintf("failed to read the overrides folder %q: %v", basepath, err),
+			})
+		}
+		return findings
+	}
+
+	labels := map[string]bool{}
+	for _, p := range providers {
+		labels[p.ManifestLabel()] = true
+	}
+	for _, e := range entries {
+		if !e.IsDir() || labels[e.Name()] {
+			continue
+		}
+		findings = append(findings, config.ValidationFinding{
+			Severity: config.WarningValidationSeverity,
+			Key:      overrideFolderKey,
+			Message:  fmt.Sprintf("the override folder %q does not match any provider; override folders must be named {type}-{name}, e.g. infrastructure-aws", filepath.Join(basepath, e.Name())),
+		})
+	}
+	return findings
+}
+
 // getLocalOverride return local override file from the config folder, if it exists.
 // This is required for development purposes, but it can be used also in production as a workaround for problems on the official repositories.
 func getLocalOverride(info *newOverrideInput) ([]byte, error) {
@@ -106,3 +106,53 @@ func TestGetLocalOverrides(t *testing.T) {
 		g.Expect(err).ToNot(HaveOccurred())
 	})
 }
+
+func TestValidateOverrides(t *testing.T) {
+	provider := config.NewProvider("myinfra", "", clusterctlv1.InfrastructureProviderType)
+
+	tests := []struct {
+		name         string
+		dirs         []string
+		missing      bool
+		wantSeverity []config.ValidationSeverity
+	}{
+		{
+			name:         "no findings for override folders matching the providers",
+			dirs:         []string{"infrastructure-myinfra"},
+			wantSeverity: []config.ValidationSeverity{},
+		},
+		{
+			name:         "warning for override folders not matching any provider",
+			dirs:         []string{"infrastructure-myinfra", "myinfra"},
+			wantSeverity: []config.ValidationSeverity{config.WarningValidationSeverity},
+		},
+		{
+			name:         "error if the custom overrides folder does not exist",
+			missing:      true,
+			wantSeverity: []config.ValidationSeverity{config.ErrorValidationSeverity},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			folder := t.TempDir()
+			for _, d := range tt.dirs {
+				g.Expect(os.MkdirAll(filepath.Join(folder, d), 0755)).To(Succeed())
+			}
+			if tt.missing {
+				folder = filepath.Join(folder, "missing")
+			}
+
+			configVariablesClient := test.NewFakeVariableClient().WithVar(overrideFolderKey, folder)
+
+			got := ValidateOverrides(configVariablesClient, []config.Provider{provider})
+			gotSeverity := []config.ValidationSeverity{}
+			for _, f := range got {
+				g.Expect(f.Key).To(Equal(overrideFolderKey))
+				gotSeverity = append(gotSeverity, f.Severity)
+			}
+			g.Expect(gotSeverity).To(Equal(tt.wantSeverity))
+		})
+	}
+}
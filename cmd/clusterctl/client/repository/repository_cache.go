@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+const (
+	// repositoryCacheFolder defines the name of the folder under the clusterctl config folder where the files
+	// downloaded from provider repositories are cached.
+	repositoryCacheFolder = "cache"
+
+	// defaultRepositoryCacheTTL defines for how long cached files are reused by default.
+	defaultRepositoryCacheTTL = 24 * time.Hour
+
+	// checksumFileSuffix defines the suffix of the files storing the checksum of the cached files.
+	checksumFileSuffix = ".sha256"
+)
+
+// fileChecksumGetter is implemented by repositories publishing the checksum of the files in a release, that
+// is used for validating cached files before reuse.
+type fileChecksumGetter interface {
+	// GetFileChecksum returns the checksum of a file for a given provider version, in the sha256:{hex} form.
+	GetFileChecksum(version, path string) (string, error)
+}
+
+// cachedRepository is a Repository caching on the local disk the files downloaded from a remote repository.
+//
+// Cached files are stored in a folder for each provider and version, together with their checksum, and they are reused
+// until they expire, unless the checksum does not match the content or the checksum published by the repository.
+type cachedRepository struct {
+	Repository
+	dir string
+	ttl time.Duration
+}
+
+var _ Repository = &cachedRepository{}
+
+// newCachedRepository wraps a repository with a disk cache, unless the cache is disabled using the
+// repository-cache-disabled variable; the cache folder and the cache TTL can be changed using
+// the repository-cache-dir and repository-cache-ttl variables.
+func newCachedRepository(repo Repository, providerConfig config.Provider, configVariablesClient config.VariablesClient) (Repository, error) {
+	if v, err := configVariablesClient.Get(config.RepositoryCacheDisabledVariable); err == nil {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid value %q for the %s variable: it must be a boolean", v, config.RepositoryCacheDisabledVariable)
+		}
+		if disabled {
+			return repo, nil
+		}
+	}
+
+	dir := filepath.Join(homedir.HomeDir(), config.ConfigFolder, repositoryCacheFolder)
+	if v, err := configVariablesClient.Get(config.RepositoryCacheDirVariable); err == nil && v != "" {
+		dir = v
+	}
+
+	ttl := defaultRepositoryCacheTTL
+	if v, err := configVariablesClient.Get(config.RepositoryCacheTTLVariable); err == nil {
+		ttl, err = time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			return nil, errors.Errorf("invalid value %q for the %s variable: it must be a non negative duration, e.g. 24h", v, config.RepositoryCacheTTLVariable)
+		}
+	}
+
+	// NB. the cache folder of a provider includes a hash of the repository URL, so overriding the URL of a provider,
+	// e.g. for using a fork, does not reuse the files cached from the original repository.
+	urlHash := sha256.Sum256([]byte(providerConfig.URL()))
+	return &cachedRepository{
+		Repository: repo,
+		dir:        filepath.Join(dir, fmt.Sprintf("%s-%s", providerConfig.ManifestLabel(), hex.EncodeToString(urlHash[:])[:12])),
+		ttl:        ttl,
+	}, nil
+}
+
+// GetFile returns a file for a given provider version, reusing the cached file if valid.
+func (c *cachedRepository) GetFile(version, path string) ([]byte, error) {
+	log := logf.Log
+
+	filePath, ok := c.filePath(version, path)
+	if !ok {
+		return c.Repository.GetFile(version, path)
+	}
+
+	if content, ok := c.get(version, path, filePath); ok {
+		log.V(5).Info("Using cached file", "Path", filePath)
+		return content, nil
+	}
+
+	content, err := c.Repository.GetFile(version, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. caching is a best effort operation, so errors are logged but not returned.
+	if err := c.put(filePath, content); err != nil {
+		log.V(5).Info("Failed to cache file", "Path", filePath, "Cause", err.Error())
+	}
+	return content, nil
+}
+
+// filePath returns the path of the cached file for a given provider version; it returns false if the
+// file can't be cached, e.g. because the path points outside of the cache folder.
+func (c *cachedRepository) filePath(version, path string) (string, bool) {
+	if version == "" || version == latestVersionTag {
+		return "", false
+	}
+	versionDir := filepath.Join(c.dir, version)
+	if filepath.Dir(versionDir) != c.dir {
+		return "", false
+	}
+	filePath := filepath.Join(versionDir, path)
+	if !strings.HasPrefix(filePath, versionDir+string(filepath.Separator)) {
+		return "", false
+	}
+	return filePath, true
+}
+
+// get returns the content of a cached file, if the file exists, it is not expired and its checksum matches
+// both the stored checksum and the checksum published by the repository, if any.
+func (c *cachedRepository) get(version, path, filePath string) ([]byte, bool) {
+	log := logf.Log
+
+	info, err := os.Stat(filePath)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, false
+	}
+	checksum, err := os.ReadFile(filePath + checksumFileSuffix)
+	if err != nil {
+		return nil, false
+	}
+
+	actual := fileChecksum(content)
+	if strings.TrimSpace(string(checksum)) != actual {
+		log.V(5).Info("Ignoring cached file not matching the stored checksum", "Path", filePath)
+		return nil, false
+	}
+
+	if getter, ok := c.Repository.(fileChecksumGetter); ok {
+		published, err := getter.GetFileChecksum(version, path)
+		// NB. if the published checksum can't be read, e.g. when offline, the cached file is reused.
+		if err == nil && published != actual {
+			log.V(5).Info("Ignoring cached file not matching the checksum published by the repository", "Path", filePath)
+			return nil, false
+		}
+	}
+
+	return content, true
+}
+
+// GetReleaseNotes returns the release notes published by the cached repository, if any; release notes are not cached.
+func (c *cachedRepository) GetReleaseNotes(version string) (string, error) {
+	if getter, ok := c.Repository.(releaseNotesGetter); ok {
+		return getter.GetReleaseNotes(version)
+	}
+	return "", errReleaseNotesNotPublished
+}
+
+// ListFiles returns the files in a release of the cached repository, if supported; the list of files is not cached.
+func (c *cachedRepository) ListFiles(version string) ([]string, error) {
+	if lister, ok := c.Repository.(fileLister); ok {
+		return lister.ListFiles(version)
+	}
+	return nil, errFileListNotSupported
+}
+
+// put stores a file and its checksum in the cache.
+func (c *cachedRepository) put(filePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the cache folder %q", filepath.Dir(filePath))
+	}
+	if err := os.WriteFile(filePath, content, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %q", filePath)
+	}
+	if err := os.WriteFile(filePath+checksumFileSuffix, []byte(fileChecksum(content)), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %q", filePath+checksumFileSuffix)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+// countingRepository is a Repository counting the calls to GetFile.
+type countingRepository struct {
+	Repository
+	downloads int
+}
+
+func (r *countingRepository) GetFile(version, path string) ([]byte, error) {
+	r.downloads++
+	return r.Repository.GetFile(version, path)
+}
+
+// checksumRepository is a countingRepository publishing the checksum of the files.
+type checksumRepository struct {
+	*countingRepository
+	checksum string
+}
+
+func (r *checksumRepository) GetFileChecksum(version, path string) (string, error) {
+	return r.checksum, nil
+}
+
+func Test_cachedRepository_GetFile(t *testing.T) {
+	content := []byte("components")
+
+	tests := []struct {
+		name          string
+		variables     map[string]string
+		checksum      string
+		path          string
+		corrupt       bool
+		wantDownloads int
+	}{
+		{
+			name:          "reuses the cached file",
+			path:          "components.yaml",
+			wantDownloads: 1,
+		},
+		{
+			name:          "does not reuse expired files",
+			variables:     map[string]string{config.RepositoryCacheTTLVariable: "0s"},
+			path:          "components.yaml",
+			wantDownloads: 2,
+		},
+		{
+			name:          "does not reuse files not matching the stored checksum",
+			path:          "components.yaml",
+			corrupt:       true,
+			wantDownloads: 2,
+		},
+		{
+			name:          "reuses files matching the published checksum",
+			checksum:      fileChecksum(content),
+			path:          "components.yaml",
+			wantDownloads: 1,
+		},
+		{
+			name:          "does not reuse files not matching the published checksum",
+			checksum:      fileChecksum([]byte("other")),
+			path:          "components.yaml",
+			wantDownloads: 2,
+		},
+		{
+			name:          "does not cache files outside of the cache folder",
+			path:          "../../components.yaml",
+			wantDownloads: 2,
+		},
+		{
+			name:          "does not cache if the cache is disabled",
+			variables:     map[string]string{config.RepositoryCacheDisabledVariable: "true"},
+			path:          "components.yaml",
+			wantDownloads: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			variableClient := test.NewFakeVariableClient().WithVar(config.RepositoryCacheDirVariable, dir)
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			fakeRepository := test.NewFakeRepository().
+				WithPaths("root", "components.yaml").
+				WithDefaultVersion("v1.0.0").
+				WithFile("v1.0.0", tt.path, content)
+
+			counting := &countingRepository{Repository: fakeRepository}
+			var repo Repository = counting
+			if tt.checksum != "" {
+				repo = &checksumRepository{countingRepository: counting, checksum: tt.checksum}
+			}
+
+			providerConfig := config.NewProvider("infra", "https://github.com/o/r/releases/v1.0.0/components.yaml", clusterctlv1.InfrastructureProviderType)
+			cached, err := newCachedRepository(repo, providerConfig, variableClient)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := cached.GetFile("v1.0.0", tt.path)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(content))
+
+			if tt.corrupt {
+				files, err := filepath.Glob(filepath.Join(dir, "*", "v1.0.0", tt.path))
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(files).To(HaveLen(1))
+				g.Expect(os.WriteFile(files[0], []byte("corrupted"), 0600)).To(Succeed())
+			}
+
+			got, err = cached.GetFile("v1.0.0", tt.path)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(content))
+			g.Expect(counting.downloads).To(Equal(tt.wantDownloads))
+		})
+	}
+}
+
+func Test_newCachedRepository(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "fails for invalid ttl",
+			variables: map[string]string{config.RepositoryCacheTTLVariable: "foo"},
+			wantErr:   true,
+		},
+		{
+			name:      "fails for invalid disabled value",
+			variables: map[string]string{config.RepositoryCacheDisabledVariable: "foo"},
+			wantErr:   true,
+		},
+		{
+			name:      "pass with a custom cache folder",
+			variables: map[string]string{config.RepositoryCacheDirVariable: "/tmp/cache"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			providerConfig := config.NewProvider("infra", "https://github.com/o/r/releases/v1.0.0/components.yaml", clusterctlv1.InfrastructureProviderType)
+			got, err := newCachedRepository(test.NewFakeRepository(), providerConfig, variableClient)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got.(*cachedRepository).dir).To(HavePrefix("/tmp/cache/infrastructure-infra-"))
+		})
+	}
+}
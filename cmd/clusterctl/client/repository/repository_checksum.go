@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// errChecksumNotPublished is returned when a repository does not publish the checksum of a file.
+var errChecksumNotPublished = errors.New("checksum not published")
+
+// isChecksumVerificationRequired returns true if the repository-verify-checksums variable requires all the
+// files downloaded from provider repositories to have a published checksum.
+func isChecksumVerificationRequired(configVariablesClient config.VariablesClient) (bool, error) {
+	v, err := configVariablesClient.Get(config.RepositoryVerifyChecksumsVariable)
+	if err != nil {
+		return false, nil // nolint:nilerr
+	}
+	required, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Errorf("invalid value %q for the %s variable: it must be a boolean", v, config.RepositoryVerifyChecksumsVariable)
+	}
+	return required, nil
+}
+
+// verifyFileChecksum verifies the content of a file against the checksum published by the repository; if the checksum
+// is not published, the file is accepted unless checksum verification is required.
+func verifyFileChecksum(getter fileChecksumGetter, version, path string, content []byte, required bool) error {
+	checksum, err := getter.GetFileChecksum(version, path)
+	if err != nil {
+		if errors.Is(err, errChecksumNotPublished) && !required {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to verify the checksum of file %q", path)
+	}
+	if checksum != fileChecksum(content) {
+		return errors.Errorf("the checksum of file %q does not match the published checksum %q", path, checksum)
+	}
+	return nil
+}
+
+// parseChecksumFile parses a checksum file in the format generated by sha256sum, returning the checksum in the
+// sha256:{hex} form; the file name after the checksum, if any, is ignored.
+func parseChecksumFile(content []byte) (string, error) {
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", errors.New("invalid checksum file: the file is empty")
+	}
+	checksum := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(checksum); err != nil || len(b) != 32 {
+		return "", errors.Errorf("invalid checksum file: %q is not a valid sha256 checksum", fields[0])
+	}
+	return "sha256:" + checksum, nil
+}
+
+// fileChecksum returns the sha256 checksum of a file, in the sha256:{hex} form.
+func fileChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
 
 	"github.com/google/go-github/v33/github"
 	"github.com/pkg/errors"
@@ -62,16 +63,24 @@ var (
 type gitHubRepository struct {
 	providerConfig           config.Provider
 	configVariablesClient    config.VariablesClient
+	httpClient               *http.Client
 	authenticatingHTTPClient *http.Client
+	host                     string
 	owner                    string
 	repository               string
 	defaultVersion           string
 	rootPath                 string
 	componentsPath           string
+	retryOptions             retryOptions
+	verifyChecksums          bool
+	includePrereleases       bool
 	injectClient             *github.Client
 }
 
 var _ Repository = &gitHubRepository{}
+var _ fileChecksumGetter = &gitHubRepository{}
+var _ releaseNotesGetter = &gitHubRepository{}
+var _ fileLister = &gitHubRepository{}
 
 type githubRepositoryOption func(*gitHubRepository)
 
@@ -81,6 +90,18 @@ func injectGithubClient(c *github.Client) githubRepositoryOption {
 	}
 }
 
+func injectGithubContext(ctx context.Context) githubRepositoryOption {
+	return func(g *gitHubRepository) {
+		g.httpClient = util.NewContextHTTPClient(ctx, g.httpClient)
+	}
+}
+
+func injectGithubIncludePrereleases() githubRepositoryOption {
+	return func(g *gitHubRepository) {
+		g.includePrereleases = true
+	}
+}
+
 // DefaultVersion returns defaultVersion field of gitHubRepository struct.
 func (g *gitHubRepository) DefaultVersion() string {
 	return g.defaultVersion
@@ -118,9 +139,61 @@ func (g *gitHubRepository) GetFile(version, path string) ([]byte, error) {
 		return nil, errors.Wrapf(err, "failed to download files from GitHub release %s", version)
 	}
 
+	if err := verifyFileChecksum(g, version, path, files, g.verifyChecksums); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify files from GitHub release %s", version)
+	}
+
 	return files, nil
 }
 
+// ListFiles returns the files in a release for a given provider version, using the names of the release assets.
+func (g *gitHubRepository) ListFiles(version string) ([]string, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get GitHub release %s", version)
+	}
+
+	names := []string{}
+	for _, a := range release.Assets {
+		if a.Name != nil {
+			names = append(names, *a.Name)
+		}
+	}
+	return relativeFileNames(g.rootPath, names), nil
+}
+
+// GetFileChecksum returns the checksum of a file for a given provider version, reading it from the
+// {file}.sha256 asset of the release.
+func (g *gitHubRepository) GetFileChecksum(version, path string) (string, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get GitHub release %s", version)
+	}
+
+	checksumFile := path + checksumFileSuffix
+	if g.getReleaseAssetID(release, checksumFile) == nil {
+		return "", errors.Wrapf(errChecksumNotPublished, "failed to get file %q from %q release", checksumFile, *release.TagName)
+	}
+
+	content, err := g.downloadFilesFromRelease(release, checksumFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download files from GitHub release %s", version)
+	}
+	return parseChecksumFile(content)
+}
+
+// GetReleaseNotes returns the release notes for a given provider version, reading them from the body of the release.
+func (g *gitHubRepository) GetReleaseNotes(version string) (string, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get GitHub release %s", version)
+	}
+	if strings.TrimSpace(release.GetBody()) == "" {
+		return "", errors.Wrapf(errReleaseNotesNotPublished, "the GitHub release %s has no release notes", version)
+	}
+	return release.GetBody(), nil
+}
+
 // newGitHubRepository returns a gitHubRepository implementation.
 func newGitHubRepository(providerConfig config.Provider, configVariablesClient config.VariablesClient, opts ...githubRepositoryOption) (*gitHubRepository, error) {
 	if configVariablesClient == nil {
@@ -133,8 +206,8 @@ func newGitHubRepository(providerConfig config.Provider, configVariablesClient c
 	}
 
 	// Check if the url is a github repository
-	if rURL.Scheme != httpsScheme || rURL.Host != githubDomain {
-		return nil, errors.New("invalid url: a GitHub repository url should start with https://github.com")
+	if rURL.Scheme != httpsScheme || !isGitHubHost(rURL.Host, configVariablesClient) {
+		return nil, errors.Errorf("invalid url: a GitHub repository url should start with https://github.com or with https:// followed by one of the hosts listed in the %s variable", config.GitHubEnterpriseHostsVariable)
 	}
 
 	// Check if the path is in the expected format,
@@ -142,7 +215,7 @@ func newGitHubRepository(providerConfig config.Provider, configVariablesClient c
 	urlSplit := strings.Split(strings.TrimPrefix(rURL.Path, "/"), "/")
 	if len(urlSplit) < 5 || urlSplit[2] != githubReleaseRepository {
 		return nil, errors.Errorf(
-			"invalid url: a GitHub repository url should be in the form https://github.com/{owner}/{Repository}/%s/{latest|version-tag}/{componentsClient.yaml}",
+			"invalid url: a GitHub repository url should be in the form https://{host}/{owner}/{Repository}/%s/{latest|version-tag}/{componentsClient.yaml}",
 			githubReleaseRepository,
 		)
 	}
@@ -161,6 +234,7 @@ func newGitHubRepository(providerConfig config.Provider, configVariablesClient c
 	repo := &gitHubRepository{
 		providerConfig:        providerConfig,
 		configVariablesClient: configVariablesClient,
+		host:                  rURL.Host,
 		owner:                 owner,
 		repository:            repository,
 		defaultVersion:        defaultVersion,
@@ -168,12 +242,27 @@ func newGitHubRepository(providerConfig config.Provider, configVariablesClient c
 		componentsPath:        componentsPath,
 	}
 
+	repo.httpClient, err = NewHTTPClient(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.retryOptions, err = newRetryOptions(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.verifyChecksums, err = isChecksumVerificationRequired(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
 	// process githubRepositoryOptions
 	for _, o := range opts {
 		o(repo)
 	}
 
-	if token, err := configVariablesClient.Get(config.GitHubTokenVariable); err == nil {
+	if token, err := getGitHubToken(rURL.Host, configVariablesClient); err == nil {
 		repo.setClientToken(token)
 	}
 
@@ -187,6 +276,52 @@ func newGitHubRepository(providerConfig config.Provider, configVariablesClient c
 	return repo, nil
 }
 
+// isGitHubHost returns true if the host is github.com or one of the GitHub Enterprise hosts
+// listed in the github-enterprise-hosts variable.
+func isGitHubHost(host string, configVariablesClient config.VariablesClient) bool {
+	return host == githubDomain || isListedHost(host, config.GitHubEnterpriseHostsVariable, configVariablesClient)
+}
+
+// isListedHost returns true if the host is listed in a variable hosting a comma separated list of hosts.
+func isListedHost(host, hostsVariable string, configVariablesClient config.VariablesClient) bool {
+	if configVariablesClient == nil {
+		return false
+	}
+	hosts, err := configVariablesClient.Get(hostsVariable)
+	if err != nil {
+		return false
+	}
+	for _, h := range strings.Split(hosts, ",") {
+		if strings.EqualFold(strings.TrimSpace(h), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenVariableForHost returns the name of the variable hosting the access token for a self-hosted instance,
+// e.g. github-token-ghe-example-com for ghe.example.com, that can be set with the GITHUB_TOKEN_GHE_EXAMPLE_COM env variable.
+func tokenVariableForHost(tokenVariable, host string) string {
+	return fmt.Sprintf("%s-%s", tokenVariable, strings.ToLower(strings.NewReplacer(".", "-", ":", "-").Replace(host)))
+}
+
+// getGitHubToken returns the access token for a GitHub host; for GitHub Enterprise hosts the host specific
+// token takes precedence over the github-token variable.
+func getGitHubToken(host string, configVariablesClient config.VariablesClient) (string, error) {
+	return getHostToken(host, githubDomain, config.GitHubTokenVariable, configVariablesClient)
+}
+
+// getHostToken returns the access token for a host; for hosts other than the public domain of the service the host
+// specific token takes precedence over the token variable.
+func getHostToken(host, publicDomain, tokenVariable string, configVariablesClient config.VariablesClient) (string, error) {
+	if host != publicDomain {
+		if token, err := configVariablesClient.Get(tokenVariableForHost(tokenVariable, host)); err == nil {
+			return token, nil
+		}
+	}
+	return configVariablesClient.Get(tokenVariable)
+}
+
 // getComponentsPath returns the file name.
 func getComponentsPath(path string, rootPath string) string {
 	// filePath = "/filename"
@@ -197,11 +332,35 @@ func getComponentsPath(path string, rootPath string) string {
 }
 
 // getClient returns a github API client.
-func (g *gitHubRepository) getClient() *github.Client {
+func (g *gitHubRepository) getClient() (*github.Client, error) {
 	if g.injectClient != nil {
-		return g.injectClient
+		return g.injectClient, nil
+	}
+	httpClient := g.httpClient
+	if g.authenticatingHTTPClient != nil {
+		httpClient = g.authenticatingHTTPClient
+	}
+	if g.host != "" && g.host != githubDomain {
+		client, err := github.NewEnterpriseClient(
+			fmt.Sprintf("https://%s/api/v3/", g.host),
+			fmt.Sprintf("https://%s/api/uploads/", g.host),
+			httpClient,
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create the GitHub Enterprise client for %q", g.host)
+		}
+		return client, nil
+	}
+	return github.NewClient(httpClient), nil
+}
+
+// cacheKey returns the prefix for the cache entries of the repository; the host is included only for
+// GitHub Enterprise repositories, so the same owner/repository on different hosts do not conflict.
+func (g *gitHubRepository) cacheKey() string {
+	if g.host == "" || g.host == githubDomain {
+		return fmt.Sprintf("%s/%s", g.owner, g.repository)
 	}
-	return github.NewClient(g.authenticatingHTTPClient)
+	return fmt.Sprintf("%s/%s/%s", g.host, g.owner, g.repository)
 }
 
 // setClientToken sets authenticatingHTTPClient field of gitHubRepository struct.
@@ -209,21 +368,34 @@ func (g *gitHubRepository) setClientToken(token string) {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	g.authenticatingHTTPClient = oauth2.NewClient(context.TODO(), ts)
+	ctx := context.TODO()
+	if g.httpClient != nil {
+		// NB. the authenticating client uses the transport of the HTTP client, e.g. for using a proxy.
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, g.httpClient)
+	}
+	g.authenticatingHTTPClient = oauth2.NewClient(ctx, ts)
 }
 
 // getVersions returns all the release versions for a github repository.
 func (g *gitHubRepository) getVersions() ([]string, error) {
-	cacheID := fmt.Sprintf("%s/%s", g.owner, g.repository)
+	cacheID := g.cacheKey()
 	if versions, ok := cacheVersions[cacheID]; ok {
 		return versions, nil
 	}
 
-	client := g.getClient()
+	client, err := g.getClient()
+	if err != nil {
+		return nil, err
+	}
 
 	// get all the releases
 	// NB. currently Github API does not support result ordering, so it not possible to limit results
-	releases, _, err := client.Repositories.ListReleases(context.TODO(), g.owner, g.repository, nil)
+	var releases []*github.RepositoryRelease
+	err = g.retryOptions.retry(func() error {
+		var err error
+		releases, _, err = client.Repositories.ListReleases(context.TODO(), g.owner, g.repository, nil)
+		return err
+	})
 	if err != nil {
 		return nil, g.handleGithubErr(err, "failed to get the list of releases")
 	}
@@ -297,62 +469,27 @@ func (g *gitHubRepository) getLatestPatchRelease(major, minor *uint) (string, er
 		return "", g.handleGithubErr(err, "failed to get the list of versions")
 	}
 
-	// Search for the latest release according to semantic version ordering.
-	// Releases with tag name that are not in semver format are ignored.
-	var latestTag string
-	var latestPrereleaseTag string
-
-	var latestReleaseVersion *version.Version
-	var latestPrereleaseVersion *version.Version
-
-	for _, v := range versions {
-		sv, err := version.ParseSemantic(v)
-		if err != nil {
-			// discard releases with tags that are not a valid semantic versions (the user can point explicitly to such releases)
-			continue
-		}
-
-		if (major != nil && sv.Major() != *major) || (minor != nil && sv.Minor() != *minor) {
-			// skip versions that don't match the desired Major.Minor version.
-			continue
-		}
-
-		// track prereleases separately
-		if sv.PreRelease() != "" {
-			if latestPrereleaseVersion == nil || latestPrereleaseVersion.LessThan(sv) {
-				latestPrereleaseTag = v
-				latestPrereleaseVersion = sv
-			}
-			continue
-		}
-
-		if latestReleaseVersion == nil || latestReleaseVersion.LessThan(sv) {
-			latestTag = v
-			latestReleaseVersion = sv
-		}
-	}
-
-	// Fall back to returning latest prereleases if no release has been cut or bail if it's also empty
-	if latestTag == "" {
-		if latestPrereleaseTag == "" {
-			return "", errors.New("failed to find releases tagged with a valid semantic version number")
-		}
-
-		return latestPrereleaseTag, nil
-	}
-	return latestTag, nil
+	return latestVersion(versions, major, minor, g.includePrereleases)
 }
 
 // getReleaseByTag returns the github repository release with a specific tag name.
 func (g *gitHubRepository) getReleaseByTag(tag string) (*github.RepositoryRelease, error) {
-	cacheID := fmt.Sprintf("%s/%s:%s", g.owner, g.repository, tag)
+	cacheID := fmt.Sprintf("%s:%s", g.cacheKey(), tag)
 	if release, ok := cacheReleases[cacheID]; ok {
 		return release, nil
 	}
 
-	client := g.getClient()
+	client, err := g.getClient()
+	if err != nil {
+		return nil, err
+	}
 
-	release, _, err := client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repository, tag)
+	var release *github.RepositoryRelease
+	err = g.retryOptions.retry(func() error {
+		var err error
+		release, _, err = client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repository, tag)
+		return err
+	})
 	if err != nil {
 		return nil, g.handleGithubErr(err, "failed to read release %q", tag)
 	}
@@ -367,34 +504,72 @@ func (g *gitHubRepository) getReleaseByTag(tag string) (*github.RepositoryReleas
 
 // downloadFilesFromRelease download a file from release.
 func (g *gitHubRepository) downloadFilesFromRelease(release *github.RepositoryRelease, fileName string) ([]byte, error) {
-	cacheID := fmt.Sprintf("%s/%s:%s:%s", g.owner, g.repository, *release.TagName, fileName)
+	cacheID := fmt.Sprintf("%s:%s:%s", g.cacheKey(), *release.TagName, fileName)
 	if content, ok := cacheFiles[cacheID]; ok {
 		return content, nil
 	}
 
-	client := g.getClient()
-	absoluteFileName := filepath.Join(g.rootPath, fileName)
-
+	client, err := g.getClient()
+	if err != nil {
+		return nil, err
+	}
 	// search for the file into the release assets, retrieving the asset id
-	var assetID *int64
+	assetID := g.getReleaseAssetID(release, fileName)
+	if assetID == nil {
+		return nil, errors.Errorf("failed to get file %q from %q release", fileName, *release.TagName)
+	}
+
+	var content []byte
+	err = g.retryOptions.retry(func() error {
+		var err error
+		content, err = g.downloadReleaseAsset(client, *release.TagName, fileName, *assetID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFiles[cacheID] = content
+	return content, nil
+}
+
+// getHTTPClient returns the HTTP client to be used for requests not going through the GitHub API client.
+func (g *gitHubRepository) getHTTPClient() *http.Client {
+	if g.httpClient != nil {
+		return g.httpClient
+	}
+	return http.DefaultClient
+}
+
+// getReleaseAssetID returns the id of the release asset for a file, if any.
+func (g *gitHubRepository) getReleaseAssetID(release *github.RepositoryRelease, fileName string) *int64 {
+	absoluteFileName := filepath.Join(g.rootPath, fileName)
 	for _, a := range release.Assets {
 		if a.Name != nil && *a.Name == absoluteFileName {
-			assetID = a.ID
-			break
+			return a.ID
 		}
 	}
-	if assetID == nil {
-		return nil, errors.Errorf("failed to get file %q from %q release", fileName, *release.TagName)
-	}
+	return nil
+}
 
-	reader, redirect, err := client.Repositories.DownloadReleaseAsset(context.TODO(), g.owner, g.repository, *assetID, http.DefaultClient)
+// downloadReleaseAsset downloads the content of a release asset, following the redirect location if required.
+func (g *gitHubRepository) downloadReleaseAsset(client *github.Client, tag, fileName string, assetID int64) ([]byte, error) {
+	reader, redirect, err := client.Repositories.DownloadReleaseAsset(context.TODO(), g.owner, g.repository, assetID, g.getHTTPClient())
 	if err != nil {
-		return nil, g.handleGithubErr(err, "failed to download file %q from %q release", *release.TagName, fileName)
+		return nil, g.handleGithubErr(err, "failed to download file %q from %q release", tag, fileName)
 	}
 	if redirect != "" {
-		response, err := http.Get(redirect) //nolint:bodyclose,gosec // (NB: The reader is actually closed in a defer)
+		response, err := g.getHTTPClient().Get(redirect) //nolint:bodyclose // (NB: The reader is actually closed in a defer)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to download file %q from %q release via redirect location %q", *release.TagName, fileName, redirect)
+			return nil, errors.Wrapf(err, "failed to download file %q from %q release via redirect location %q", tag, fileName, redirect)
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			err := errors.Errorf("failed to download file %q from %q release via redirect location %q: unexpected status %q", tag, fileName, redirect, response.Status)
+			if !isRetriableStatusCode(response.StatusCode) {
+				return nil, &nonRetriableError{err: err}
+			}
+			return nil, err
 		}
 		reader = response.Body
 	}
@@ -403,17 +578,15 @@ func (g *gitHubRepository) downloadFilesFromRelease(release *github.RepositoryRe
 	// Read contents from the reader (redirect or not), and return.
 	content, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read downloaded file %q from %q release", *release.TagName, fileName)
+		return nil, errors.Wrapf(err, "failed to read downloaded file %q from %q release", tag, fileName)
 	}
-
-	cacheFiles[cacheID] = content
 	return content, nil
 }
 
 // handleGithubErr wraps error messages.
 func (g *gitHubRepository) handleGithubErr(err error, message string, args ...interface{}) error {
 	if _, ok := err.(*github.RateLimitError); ok {
-		return errors.New("rate limit for github api has been reached. Please wait one hour or get a personal API tokens a assign it to the GITHUB_TOKEN environment variable")
+		return &nonRetriableError{err: errors.New("rate limit for github api has been reached. Please wait one hour or get a personal API tokens a assign it to the GITHUB_TOKEN environment variable")}
 	}
 	return errors.Wrapf(err, message, args...)
 }
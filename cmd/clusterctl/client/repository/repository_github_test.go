@@ -50,16 +50,50 @@ func Test_githubRepository_newGitHubRepository(t *testing.T) {
 			want: &gitHubRepository{
 				providerConfig:           config.NewProvider("test", "https://github.com/o/r1/releases/v0.4.1/path", clusterctlv1.CoreProviderType),
 				configVariablesClient:    test.NewFakeVariableClient(),
+				httpClient:               http.DefaultClient,
 				authenticatingHTTPClient: nil,
+				host:                     "github.com",
 				owner:                    "o",
 				repository:               "r1",
 				defaultVersion:           "v0.4.1",
 				rootPath:                 ".",
 				componentsPath:           "path",
+				retryOptions:             defaultRetryOptions(),
 				injectClient:             nil,
 			},
 			wantErr: false,
 		},
+		{
+			name: "can create a new GitHub Enterprise repo",
+			field: field{
+				providerConfig: config.NewProvider("test", "https://ghe.example.com/o/r1/releases/v0.4.1/path", clusterctlv1.CoreProviderType),
+				variableClient: test.NewFakeVariableClient().WithVar(config.GitHubEnterpriseHostsVariable, "foo.example.com, ghe.example.com"),
+			},
+			want: &gitHubRepository{
+				providerConfig:           config.NewProvider("test", "https://ghe.example.com/o/r1/releases/v0.4.1/path", clusterctlv1.CoreProviderType),
+				configVariablesClient:    test.NewFakeVariableClient().WithVar(config.GitHubEnterpriseHostsVariable, "foo.example.com, ghe.example.com"),
+				httpClient:               http.DefaultClient,
+				authenticatingHTTPClient: nil,
+				host:                     "ghe.example.com",
+				owner:                    "o",
+				repository:               "r1",
+				defaultVersion:           "v0.4.1",
+				rootPath:                 ".",
+				componentsPath:           "path",
+				retryOptions:             defaultRetryOptions(),
+				injectClient:             nil,
+			},
+			wantErr: false,
+		},
+		{
+			name: "provider url should be in github or in one of the GitHub Enterprise hosts",
+			field: field{
+				providerConfig: config.NewProvider("test", "https://ghe.example.com/o/r1/releases/v0.4.1/path", clusterctlv1.CoreProviderType),
+				variableClient: test.NewFakeVariableClient().WithVar(config.GitHubEnterpriseHostsVariable, "foo.example.com"),
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "missing variableClient",
 			field: field{
@@ -124,6 +158,58 @@ func Test_githubRepository_newGitHubRepository(t *testing.T) {
 	}
 }
 
+func Test_getGitHubToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		variables map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "github.com uses the github-token variable",
+			host:      "github.com",
+			variables: map[string]string{config.GitHubTokenVariable: "token", "github-token-github-com": "other"},
+			want:      "token",
+		},
+		{
+			name:      "GitHub Enterprise hosts use the host specific variable",
+			host:      "ghe.example.com",
+			variables: map[string]string{config.GitHubTokenVariable: "token", "github-token-ghe-example-com": "ghe-token"},
+			want:      "ghe-token",
+		},
+		{
+			name:      "GitHub Enterprise hosts fall back to the github-token variable",
+			host:      "ghe.example.com:8443",
+			variables: map[string]string{config.GitHubTokenVariable: "token", "github-token-ghe-example-com": "ghe-token"},
+			want:      "token",
+		},
+		{
+			name:    "fails if no token is set",
+			host:    "ghe.example.com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			got, err := getGitHubToken(tt.host, variableClient)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
 func Test_githubRepository_getComponentsPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -227,6 +313,73 @@ func Test_githubRepository_getFile(t *testing.T) {
 	}
 }
 
+func Test_githubRepository_getFile_Checksum(t *testing.T) {
+	client, mux, teardown := test.NewFakeGitHub()
+	defer teardown()
+
+	providerConfig := config.NewProvider("test", "https://github.com/o/r/releases/v0.4.1/file.yaml", clusterctlv1.CoreProviderType)
+
+	// test.NewFakeGitHub and handler for returning a fake release, with the checksum of file.yaml only
+	mux.HandleFunc("/repos/o/r/releases/tags/v0.4.1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":13, "tag_name": "v0.4.1", "assets": [{"id": 1, "name": "file.yaml"}, {"id": 2, "name": "file.yaml.sha256"}, {"id": 3, "name": "other.yaml"}] }`)
+	})
+
+	// test.NewFakeGitHub an handler for returning the fake release assets
+	assets := map[string]string{"1": "content", "2": testChecksum + "  file.yaml", "3": "other"}
+	for id, content := range assets {
+		content := content
+		mux.HandleFunc("/repos/o/r/releases/assets/"+id, func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, "GET")
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, content)
+		})
+	}
+
+	tests := []struct {
+		name     string
+		required bool
+		fileName string
+		wantErr  bool
+	}{
+		{
+			name:     "pass with a matching checksum",
+			fileName: "file.yaml",
+		},
+		{
+			name:     "pass without checksum if verification is not required",
+			fileName: "other.yaml",
+		},
+		{
+			name:     "fails without checksum if verification is required",
+			required: true,
+			fileName: "other.yaml",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			resetCaches()
+
+			configVariablesClient := test.NewFakeVariableClient()
+			if tt.required {
+				configVariablesClient.WithVar(config.RepositoryVerifyChecksumsVariable, "true")
+			}
+
+			gitHub, err := newGitHubRepository(providerConfig, configVariablesClient, injectGithubClient(client))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			_, err = gitHub.GetFile("v0.4.1", tt.fileName)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
 func Test_gitHubRepository_getVersions(t *testing.T) {
 	client, mux, teardown := test.NewFakeGitHub()
 	defer teardown()
@@ -603,6 +756,65 @@ func Test_gitHubRepository_getReleaseByTag(t *testing.T) {
 	}
 }
 
+func Test_gitHubRepository_GetReleaseNotes(t *testing.T) {
+	client, mux, teardown := test.NewFakeGitHub()
+	defer teardown()
+
+	providerConfig := config.NewProvider("test", "https://github.com/o/r/releases/v0.4.1/path", clusterctlv1.CoreProviderType)
+
+	// setup and handler for returning fake releases, with and without release notes
+	mux.HandleFunc("/repos/o/r/releases/tags/v0.4.1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":13, "tag_name": "v0.4.1", "body": "release notes"}`)
+	})
+	mux.HandleFunc("/repos/o/r/releases/tags/v0.4.2", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":14, "tag_name": "v0.4.2"}`)
+	})
+
+	configVariablesClient := test.NewFakeVariableClient()
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Return the body of the release",
+			version: "v0.4.1",
+			want:    "release notes",
+		},
+		{
+			name:    "Fails if the release has no body",
+			version: "v0.4.2",
+			wantErr: true,
+		},
+		{
+			name:    "Fails if the release does not exist",
+			version: "v0.4.3",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			resetCaches()
+
+			gRepo, err := newGitHubRepository(providerConfig, configVariablesClient, injectGithubClient(client))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := gRepo.GetReleaseNotes(tt.version)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
 func Test_gitHubRepository_downloadFilesFromRelease(t *testing.T) {
 	client, mux, teardown := test.NewFakeGitHub()
 	defer teardown()
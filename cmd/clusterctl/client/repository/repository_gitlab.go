@@ -0,0 +1,509 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+)
+
+const (
+	gitlabDomain = "gitlab.com"
+
+	// gitlabPathSeparator separates the project path from the release path in GitLab web URLs, e.g.
+	// https://gitlab.com/group/subgroup/project/-/releases/v1.0.0/components.yaml.
+	gitlabPathSeparator = "-"
+
+	gitlabReleases        = "releases"
+	gitlabAPIPath         = "api/v4/projects"
+	gitlabTokenHeader     = "PRIVATE-TOKEN"
+	gitlabNextPageHeader  = "X-Next-Page"
+	gitlabReleasesPerPage = 100
+)
+
+// gitLabRepository provides support for providers hosted on GitLab, either on gitlab.com or on a self-managed instance.
+//
+// We support GitLab projects that use the release feature to publish artifacts and versions; the files of
+// each provider version must be attached to the release as asset links, named after the file, e.g. by uploading
+// the files to the generic package registry of the project and linking them to the release.
+// The repository URL should be in the form https://{host}/{project-path}/-/releases/{latest|version-tag}/{components.yaml},
+// or https://{host}/api/v4/projects/{url-encoded-project-path}/releases/{latest|version-tag}/{components.yaml}.
+type gitLabRepository struct {
+	providerConfig        config.Provider
+	configVariablesClient config.VariablesClient
+	httpClient            *http.Client
+	host                  string
+	project               string
+	defaultVersion        string
+	rootPath              string
+	componentsPath        string
+	token                 string
+	retryOptions          retryOptions
+	verifyChecksums       bool
+	includePrereleases    bool
+	versions              []string
+	releases              map[string]*gitlabRelease
+	files                 map[string][]byte
+}
+
+var _ Repository = &gitLabRepository{}
+var _ fileChecksumGetter = &gitLabRepository{}
+var _ fileLister = &gitLabRepository{}
+
+type gitlabRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []gitlabReleaseLink `json:"links"`
+	} `json:"assets"`
+}
+
+type gitlabReleaseLink struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+type gitlabRepositoryOption func(*gitLabRepository)
+
+func injectGitLabHTTPClient(c *http.Client) gitlabRepositoryOption {
+	return func(g *gitLabRepository) {
+		g.httpClient = c
+	}
+}
+
+func injectGitLabContext(ctx context.Context) gitlabRepositoryOption {
+	return func(g *gitLabRepository) {
+		g.httpClient = util.NewContextHTTPClient(ctx, g.httpClient)
+	}
+}
+
+func injectGitLabIncludePrereleases() gitlabRepositoryOption {
+	return func(g *gitLabRepository) {
+		g.includePrereleases = true
+	}
+}
+
+// DefaultVersion returns defaultVersion field of gitLabRepository struct.
+func (g *gitLabRepository) DefaultVersion() string {
+	return g.defaultVersion
+}
+
+// GetVersions returns the list of versions that are available in a provider repository, derived from the
+// release tags that are valid semantic versions.
+func (g *gitLabRepository) GetVersions() ([]string, error) {
+	if g.versions != nil {
+		return g.versions, nil
+	}
+
+	versions := []string{}
+	page := "1"
+	for page != "" {
+		var releases []*gitlabRelease
+		nextPage, err := g.getJSON(fmt.Sprintf("%s?per_page=%d&page=%s", gitlabReleases, gitlabReleasesPerPage, page), &releases)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the list of releases for %q", g.project)
+		}
+		for _, r := range releases {
+			if _, err := version.ParseSemantic(r.TagName); err != nil {
+				// Discard releases with tags that are not a valid semantic versions (the user can point explicitly to such releases).
+				continue
+			}
+			versions = append(versions, r.TagName)
+			g.releases[r.TagName] = r
+		}
+		page = nextPage
+	}
+
+	g.versions = versions
+	return versions, nil
+}
+
+// RootPath returns rootPath field of gitLabRepository struct.
+func (g *gitLabRepository) RootPath() string {
+	return g.rootPath
+}
+
+// ComponentsPath returns componentsPath field of gitLabRepository struct.
+func (g *gitLabRepository) ComponentsPath() string {
+	return g.componentsPath
+}
+
+// GetFile returns a file for a given provider version.
+func (g *gitLabRepository) GetFile(version, path string) ([]byte, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get GitLab release %s", version)
+	}
+
+	link := g.getReleaseLink(release, path)
+	if link == nil {
+		return nil, errors.Errorf("failed to get file %q from %q release", path, release.TagName)
+	}
+
+	content, err := g.downloadReleaseLink(release, link)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download files from GitLab release %s", version)
+	}
+
+	if err := verifyFileChecksum(g, version, path, content, g.verifyChecksums); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify files from GitLab release %s", version)
+	}
+	return content, nil
+}
+
+// ListFiles returns the files in a release for a given provider version, using the names of the release asset links.
+func (g *gitLabRepository) ListFiles(version string) ([]string, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get GitLab release %s", version)
+	}
+
+	names := []string{}
+	for _, l := range release.Assets.Links {
+		names = append(names, l.Name)
+	}
+	return relativeFileNames(g.rootPath, names), nil
+}
+
+// GetFileChecksum returns the checksum of a file for a given provider version, reading it from the
+// {file}.sha256 asset of the release.
+func (g *gitLabRepository) GetFileChecksum(version, path string) (string, error) {
+	release, err := g.getReleaseByTag(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get GitLab release %s", version)
+	}
+
+	checksumFile := path + checksumFileSuffix
+	link := g.getReleaseLink(release, checksumFile)
+	if link == nil {
+		return "", errors.Wrapf(errChecksumNotPublished, "failed to get file %q from %q release", checksumFile, release.TagName)
+	}
+
+	content, err := g.downloadReleaseLink(release, link)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download files from GitLab release %s", version)
+	}
+	return parseChecksumFile(content)
+}
+
+// newGitLabRepository returns a gitLabRepository implementation.
+func newGitLabRepository(providerConfig config.Provider, configVariablesClient config.VariablesClient, opts ...gitlabRepositoryOption) (*gitLabRepository, error) {
+	if configVariablesClient == nil {
+		return nil, errors.New("invalid arguments: configVariablesClient can't be nil")
+	}
+
+	rURL, err := url.Parse(providerConfig.URL())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+
+	// Check if the url is a gitlab repository
+	if rURL.Scheme != httpsScheme || !isGitLabHost(rURL.Host, configVariablesClient) {
+		return nil, errors.Errorf("invalid url: a GitLab repository url should start with https://gitlab.com or with https:// followed by one of the hosts listed in the %s variable", config.GitLabHostsVariable)
+	}
+
+	project, defaultVersion, path, err := parseGitLabPath(rURL.EscapedPath())
+	if err != nil {
+		return nil, err
+	}
+
+	// use path's directory as a rootPath
+	rootPath := filepath.Dir(path)
+	// use the file name (if any) as componentsPath
+	componentsPath := getComponentsPath(path, rootPath)
+
+	repo := &gitLabRepository{
+		providerConfig:        providerConfig,
+		configVariablesClient: configVariablesClient,
+		host:                  rURL.Host,
+		project:               project,
+		defaultVersion:        defaultVersion,
+		rootPath:              rootPath,
+		componentsPath:        componentsPath,
+		releases:              map[string]*gitlabRelease{},
+		files:                 map[string][]byte{},
+	}
+
+	repo.httpClient, err = NewHTTPClient(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.retryOptions, err = newRetryOptions(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.verifyChecksums, err = isChecksumVerificationRequired(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// process gitlabRepositoryOptions
+	for _, o := range opts {
+		o(repo)
+	}
+
+	// The token is optional, so only public projects can be accessed if it is not defined.
+	if token, err := getHostToken(rURL.Host, gitlabDomain, config.GitLabTokenVariable, configVariablesClient); err == nil {
+		repo.token = token
+	}
+
+	if defaultVersion == latestVersionTag {
+		repo.defaultVersion, err = repo.getLatestContractRelease(clusterv1.GroupVersion.Version)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get GitLab latest version")
+		}
+	}
+
+	return repo, nil
+}
+
+// parseGitLabPath extracts the project path, the version and the file path from the escaped path of a GitLab
+// repository url, supporting both the web url and the API url, where the project path is url-encoded.
+func parseGitLabPath(escapedPath string) (string, string, string, error) {
+	invalidURLErr := errors.Errorf(
+		"invalid url: a GitLab repository url should be in the form https://{host}/{project-path}/-/%[1]s/{latest|version-tag}/{components.yaml} or https://{host}/%[2]s/{url-encoded-project-path}/%[1]s/{latest|version-tag}/{components.yaml}",
+		gitlabReleases, gitlabAPIPath,
+	)
+
+	// Url's path has an extra leading slash which we need to clean up before splitting.
+	urlSplit := strings.Split(strings.TrimPrefix(escapedPath, "/"), "/")
+
+	var projectSplit, releaseSplit []string
+	if strings.HasPrefix(strings.Join(urlSplit, "/"), gitlabAPIPath+"/") {
+		apiPathLen := len(strings.Split(gitlabAPIPath, "/"))
+		projectSplit = urlSplit[apiPathLen : apiPathLen+1]
+		releaseSplit = urlSplit[apiPathLen+1:]
+	} else {
+		i := 0
+		for i < len(urlSplit) && urlSplit[i] != gitlabPathSeparator {
+			i++
+		}
+		// NB. Projects always belong to a group or to a user namespace.
+		if i < 2 || i == len(urlSplit) {
+			return "", "", "", invalidURLErr
+		}
+		projectSplit = urlSplit[:i]
+		releaseSplit = urlSplit[i+1:]
+	}
+
+	if len(releaseSplit) < 3 || releaseSplit[0] != gitlabReleases {
+		return "", "", "", invalidURLErr
+	}
+
+	segments := make([]string, 0, len(projectSplit)+len(releaseSplit))
+	for _, s := range append(append([]string{}, projectSplit...), releaseSplit...) {
+		unescaped, err := url.PathUnescape(s)
+		if err != nil {
+			return "", "", "", errors.Wrapf(err, "invalid url: failed to decode %q", s)
+		}
+		segments = append(segments, unescaped)
+	}
+
+	project := strings.Join(segments[:len(projectSplit)], "/")
+	if project == "" || strings.Contains(project, "//") {
+		return "", "", "", invalidURLErr
+	}
+	releaseSegments := segments[len(projectSplit):]
+	return project, releaseSegments[1], strings.Join(releaseSegments[2:], "/"), nil
+}
+
+// isGitLabHost returns true if the host is gitlab.com or one of the self-managed GitLab hosts
+// listed in the gitlab-hosts variable.
+func isGitLabHost(host string, configVariablesClient config.VariablesClient) bool {
+	return host == gitlabDomain || isListedHost(host, config.GitLabHostsVariable, configVariablesClient)
+}
+
+// getReleaseByTag returns the GitLab release with a specific tag name.
+func (g *gitLabRepository) getReleaseByTag(tag string) (*gitlabRelease, error) {
+	if release, ok := g.releases[tag]; ok {
+		return release, nil
+	}
+
+	release := &gitlabRelease{}
+	if _, err := g.getJSON(fmt.Sprintf("%s/%s", gitlabReleases, url.PathEscape(tag)), release); err != nil {
+		return nil, errors.Wrapf(err, "failed to read release %q", tag)
+	}
+
+	g.releases[tag] = release
+	return release, nil
+}
+
+// getReleaseLink returns the asset link of the release for a file, if any.
+func (g *gitLabRepository) getReleaseLink(release *gitlabRelease, fileName string) *gitlabReleaseLink {
+	absoluteFileName := filepath.Join(g.rootPath, fileName)
+	for i := range release.Assets.Links {
+		if release.Assets.Links[i].Name == absoluteFileName {
+			return &release.Assets.Links[i]
+		}
+	}
+	return nil
+}
+
+// downloadReleaseLink downloads the content of a release asset link.
+func (g *gitLabRepository) downloadReleaseLink(release *gitlabRelease, link *gitlabReleaseLink) ([]byte, error) {
+	cacheID := fmt.Sprintf("%s:%s", release.TagName, link.Name)
+	if content, ok := g.files[cacheID]; ok {
+		return content, nil
+	}
+
+	linkURL := link.DirectAssetURL
+	if linkURL == "" {
+		linkURL = link.URL
+	}
+
+	var content []byte
+	err := g.retryOptions.retry(func() error {
+		var err error
+		content, _, err = g.getOnce(linkURL)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download file %q from %q release", link.Name, release.TagName)
+	}
+
+	g.files[cacheID] = content
+	return content, nil
+}
+
+// getJSON reads a resource of the project from the GitLab API, retrying in case of transient errors, and returns
+// the next page of the results, if any.
+func (g *gitLabRepository) getJSON(path string, into interface{}) (string, error) {
+	// NB. the project path must be url-encoded, e.g. group%2Fproject, when used as a project ID.
+	resourceURL := fmt.Sprintf("https://%s/%s/%s/%s", g.host, gitlabAPIPath, url.PathEscape(g.project), path)
+
+	var nextPage string
+	err := g.retryOptions.retry(func() error {
+		content, header, err := g.getOnce(resourceURL)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(content, into); err != nil {
+			return &nonRetriableError{err: errors.Wrapf(err, "failed to parse the response from %q", resourceURL)}
+		}
+		nextPage = header.Get(gitlabNextPageHeader)
+		return nil
+	})
+	return nextPage, err
+}
+
+// getOnce reads a resource from GitLab; the access token, if any, is sent only to the GitLab host, so it is not
+// leaked to asset links pointing to external hosts.
+func (g *gitLabRepository) getOnce(resourceURL string) ([]byte, http.Header, error) {
+	request, err := http.NewRequest(http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return nil, nil, &nonRetriableError{err: errors.Wrapf(err, "failed to create the request for %q", resourceURL)}
+	}
+	if g.token != "" && request.URL.Host == g.host {
+		request.Header.Set(gitlabTokenHeader, g.token)
+	}
+
+	response, err := g.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get %q", resourceURL)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err := errors.Errorf("failed to get %q: unexpected status %q", resourceURL, response.Status)
+		if !isRetriableStatusCode(response.StatusCode) {
+			if response.StatusCode == http.StatusUnauthorized || (response.StatusCode == http.StatusNotFound && g.token == "") {
+				// NB. GitLab returns not found for private projects when the request is not authenticated.
+				err = errors.Wrapf(err, "if the project is private, please set the %s variable", config.GitLabTokenVariable)
+			}
+			return nil, nil, &nonRetriableError{err: err}
+		}
+		return nil, nil, err
+	}
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read %q", resourceURL)
+	}
+	return content, response.Header, nil
+}
+
+// getLatestContractRelease returns the latest patch release for a GitLab project for the current API contract, according to
+// semantic version order of the release tag name.
+func (g *gitLabRepository) getLatestContractRelease(contract string) (string, error) {
+	latest, err := g.getLatestRelease()
+	if err != nil {
+		return latest, err
+	}
+	// Attempt to check if the latest release satisfies the API Contract
+	// This is a best-effort attempt to find the latest release for an older API contract if it's not the latest release.
+	// If an error occurs, we just return the latest release.
+	file, err := g.GetFile(latest, metadataFile)
+	if err != nil {
+		// if we can't get the metadata file from the release, we return latest.
+		return latest, nil // nolint:nilerr
+	}
+	latestMetadata := &clusterctlv1.Metadata{}
+	codecFactory := serializer.NewCodecFactory(scheme.Scheme)
+	if err := apiruntime.DecodeInto(codecFactory.UniversalDecoder(), file, latestMetadata); err != nil {
+		return latest, nil // nolint:nilerr
+	}
+
+	releaseSeries := latestMetadata.GetReleaseSeriesForContract(contract)
+	if releaseSeries == nil {
+		return latest, nil
+	}
+
+	sv, err := version.ParseSemantic(latest)
+	if err != nil {
+		return latest, nil // nolint:nilerr
+	}
+
+	// If the Major or Minor version of the latest release doesn't match the release series for the current contract,
+	// return the latest patch release of the desired Major/Minor version.
+	if sv.Major() != releaseSeries.Major || sv.Minor() != releaseSeries.Minor {
+		return g.getLatestPatchRelease(&releaseSeries.Major, &releaseSeries.Minor)
+	}
+	return latest, nil
+}
+
+// getLatestRelease returns the latest release for a GitLab project.
+func (g *gitLabRepository) getLatestRelease() (string, error) {
+	return g.getLatestPatchRelease(nil, nil)
+}
+
+// getLatestPatchRelease returns the latest patch release for a given Major and Minor version.
+func (g *gitLabRepository) getLatestPatchRelease(major, minor *uint) (string, error) {
+	versions, err := g.GetVersions()
+	if err != nil {
+		return "", err
+	}
+
+	return latestVersion(versions, major, minor, g.includePrereleases)
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+// fakeGitLab is a minimal GitLab instance serving the releases of a project, one release per page;
+// if token is set, the project is private and the API returns not found for unauthenticated requests.
+type fakeGitLab struct {
+	project  string
+	releases map[string]map[string]string
+	token    string
+	order    []string
+}
+
+func (f *fakeGitLab) handler(server **httptest.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if f.token != "" && req.Header.Get(gitlabTokenHeader) != f.token {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		release := func(tag string) gitlabRelease {
+			r := gitlabRelease{TagName: tag}
+			for name := range f.releases[tag] {
+				r.Assets.Links = append(r.Assets.Links, gitlabReleaseLink{
+					Name:           name,
+					DirectAssetURL: fmt.Sprintf("%s/files/%s/%s", (*server).URL, tag, name),
+				})
+			}
+			return r
+		}
+
+		// NB. the project path is url-encoded in the API urls.
+		releasesPath := fmt.Sprintf("/api/v4/projects/%s/releases", strings.ReplaceAll(f.project, "/", "%2F"))
+		path := req.URL.EscapedPath()
+		switch {
+		case path == releasesPath:
+			page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+			if page < 1 || page > len(f.order) {
+				_ = json.NewEncoder(w).Encode([]gitlabRelease{})
+				return
+			}
+			if page < len(f.order) {
+				w.Header().Set(gitlabNextPageHeader, strconv.Itoa(page+1))
+			}
+			_ = json.NewEncoder(w).Encode([]gitlabRelease{release(f.order[page-1])})
+		case strings.HasPrefix(path, releasesPath+"/"):
+			tag := strings.TrimPrefix(path, releasesPath+"/")
+			if _, ok := f.releases[tag]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(release(tag))
+		case strings.HasPrefix(path, "/files/"):
+			parts := strings.SplitN(strings.TrimPrefix(path, "/files/"), "/", 2)
+			content, ok := f.releases[parts[0]][parts[1]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(content))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return mux
+}
+
+func newFakeGitLabServer(gitlab *fakeGitLab) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(gitlab.handler(&server))
+	return server
+}
+
+func Test_parseGitLabPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantProject string
+		wantVersion string
+		wantPath    string
+		wantErr     bool
+	}{
+		{
+			name:        "web url",
+			path:        "/group/project/-/releases/v1.0.0/components.yaml",
+			wantProject: "group/project",
+			wantVersion: "v1.0.0",
+			wantPath:    "components.yaml",
+		},
+		{
+			name:        "web url with subgroups",
+			path:        "/group/subgroup/project/-/releases/latest/path/components.yaml",
+			wantProject: "group/subgroup/project",
+			wantVersion: "latest",
+			wantPath:    "path/components.yaml",
+		},
+		{
+			name:        "api url with url-encoded project path",
+			path:        "/api/v4/projects/group%2Fsubgroup%2Fproject/releases/v1.0.0/components.yaml",
+			wantProject: "group/subgroup/project",
+			wantVersion: "v1.0.0",
+			wantPath:    "components.yaml",
+		},
+		{
+			name:        "api url with project id",
+			path:        "/api/v4/projects/42/releases/v1.0.0/components.yaml",
+			wantProject: "42",
+			wantVersion: "v1.0.0",
+			wantPath:    "components.yaml",
+		},
+		{
+			name:    "fails without the namespace",
+			path:    "/project/-/releases/v1.0.0/components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "fails without the separator",
+			path:    "/group/project/releases/v1.0.0/components.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "fails without the components path",
+			path:    "/group/project/-/releases/v1.0.0",
+			wantErr: true,
+		},
+		{
+			name:    "fails with an api url without releases",
+			path:    "/api/v4/projects/group%2Fproject/packages/generic/v1.0.0/components.yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			project, version, path, err := parseGitLabPath(tt.path)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(project).To(Equal(tt.wantProject))
+			g.Expect(version).To(Equal(tt.wantVersion))
+			g.Expect(path).To(Equal(tt.wantPath))
+		})
+	}
+}
+
+func Test_gitLabRepository_newGitLabRepository(t *testing.T) {
+	gitlab := &fakeGitLab{
+		project: "group/subgroup/infra",
+		releases: map[string]map[string]string{
+			"v1.0.0":        {"components.yaml": "v1.0.0-components"},
+			"v1.1.0":        {"components.yaml": "v1.1.0-components"},
+			"v1.2.0-beta.0": {"components.yaml": "v1.2.0-beta.0-components"},
+			"foo":           {"components.yaml": "foo-components"},
+		},
+		order: []string{"foo", "v1.2.0-beta.0", "v1.1.0", "v1.0.0"},
+	}
+	server := newFakeGitLabServer(gitlab)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name               string
+		url                string
+		variables          map[string]string
+		wantDefaultVersion string
+		wantRootPath       string
+		wantComponentsPath string
+		wantErr            bool
+	}{
+		{
+			name:               "pass with a version",
+			url:                fmt.Sprintf("https://%s/group/subgroup/infra/-/releases/v1.0.0/components.yaml", host),
+			variables:          map[string]string{config.GitLabHostsVariable: host},
+			wantDefaultVersion: "v1.0.0",
+			wantRootPath:       ".",
+			wantComponentsPath: "components.yaml",
+		},
+		{
+			name:               "pass with latest, reading all the pages of releases",
+			url:                fmt.Sprintf("https://%s/api/v4/projects/group%%2Fsubgroup%%2Finfra/releases/latest/path/components.yaml", host),
+			variables:          map[string]string{config.GitLabHostsVariable: "gitlab.example.com, " + host},
+			wantDefaultVersion: "v1.1.0",
+			wantRootPath:       "path",
+			wantComponentsPath: "components.yaml",
+		},
+		{
+			name:    "fails if the host is not a GitLab host",
+			url:     fmt.Sprintf("https://%s/group/subgroup/infra/-/releases/v1.0.0/components.yaml", host),
+			wantErr: true,
+		},
+		{
+			name:      "fails with an invalid path",
+			url:       fmt.Sprintf("https://%s/group/subgroup/infra/releases/v1.0.0/components.yaml", host),
+			variables: map[string]string{config.GitLabHostsVariable: host},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			providerConfig := config.NewProvider("infra", tt.url, clusterctlv1.InfrastructureProviderType)
+			got, err := newGitLabRepository(providerConfig, variableClient, injectGitLabHTTPClient(server.Client()))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got.host).To(Equal(host))
+			g.Expect(got.project).To(Equal(gitlab.project))
+			g.Expect(got.DefaultVersion()).To(Equal(tt.wantDefaultVersion))
+			g.Expect(got.RootPath()).To(Equal(tt.wantRootPath))
+			g.Expect(got.ComponentsPath()).To(Equal(tt.wantComponentsPath))
+		})
+	}
+}
+
+func Test_gitLabRepository_GetFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitlab    *fakeGitLab
+		variables map[string]string
+		path      string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "pass with a public project",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+			},
+			path: "components.yaml",
+			want: "components",
+		},
+		{
+			name: "pass with a private project and the token",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+				token:    "secret",
+			},
+			variables: map[string]string{config.GitLabTokenVariable: "secret"},
+			path:      "components.yaml",
+			want:      "components",
+		},
+		{
+			name: "fails with a private project without the token",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+				token:    "secret",
+			},
+			path:    "components.yaml",
+			wantErr: true,
+		},
+		{
+			name: "pass with a valid checksum",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {
+					"components.yaml":        "components",
+					"components.yaml.sha256": strings.TrimPrefix(fileChecksum([]byte("components")), "sha256:") + "  components.yaml",
+				}},
+			},
+			variables: map[string]string{config.RepositoryVerifyChecksumsVariable: "true"},
+			path:      "components.yaml",
+			want:      "components",
+		},
+		{
+			name: "fails with an invalid checksum",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {
+					"components.yaml":        "components",
+					"components.yaml.sha256": strings.TrimPrefix(fileChecksum([]byte("other")), "sha256:") + "  components.yaml",
+				}},
+			},
+			path:    "components.yaml",
+			wantErr: true,
+		},
+		{
+			name: "fails if the checksum is required but not published",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+			},
+			variables: map[string]string{config.RepositoryVerifyChecksumsVariable: "true"},
+			path:      "components.yaml",
+			wantErr:   true,
+		},
+		{
+			name: "fails if the file does not exist",
+			gitlab: &fakeGitLab{
+				releases: map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+			},
+			path:    "metadata.yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tt.gitlab.project = "group/infra"
+			server := newFakeGitLabServer(tt.gitlab)
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "https://")
+
+			variableClient := test.NewFakeVariableClient().WithVar(config.GitLabHostsVariable, host)
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			providerConfig := config.NewProvider("infra", fmt.Sprintf("https://%s/group/infra/-/releases/v1.0.0/components.yaml", host), clusterctlv1.InfrastructureProviderType)
+			repo, err := newGitLabRepository(providerConfig, variableClient, injectGitLabHTTPClient(server.Client()))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := repo.GetFile("v1.0.0", tt.path)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(got)).To(Equal(tt.want))
+		})
+	}
+}
@@ -70,9 +70,21 @@ type localRepository struct {
 	providerLabel         string
 	defaultVersion        string
 	componentsPath        string
+	verifyChecksums       bool
+	includePrereleases    bool
+}
+
+type localRepositoryOption func(*localRepository)
+
+func injectLocalIncludePrereleases() localRepositoryOption {
+	return func(r *localRepository) {
+		r.includePrereleases = true
+	}
 }
 
 var _ Repository = &localRepository{}
+var _ fileChecksumGetter = &localRepository{}
+var _ fileLister = &localRepository{}
 
 // DefaultVersion returns the default version for the local repository.
 func (r *localRepository) DefaultVersion() string {
@@ -91,17 +103,79 @@ func (r *localRepository) ComponentsPath() string {
 
 // GetFile returns a file for a given provider version.
 func (r *localRepository) GetFile(version, fileName string) ([]byte, error) {
-	var err error
+	version, err := r.resolveVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := r.getFile(version, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyFileChecksum(r, version, fileName, content, r.verifyChecksums); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify file %q from local release %s", fileName, version)
+	}
+	return content, nil
+}
+
+// GetFileChecksum returns the checksum of a file for a given provider version, reading it from the
+// {file}.sha256 file stored beside the file.
+func (r *localRepository) GetFileChecksum(version, fileName string) (string, error) {
+	version, err := r.resolveVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := r.getFile(version, fileName+checksumFileSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrapf(errChecksumNotPublished, "failed to read checksum file for %q from local release %s", fileName, version)
+		}
+		return "", err
+	}
+	return parseChecksumFile(content)
+}
 
-	if version == latestVersionTag {
-		version, err = r.getLatestRelease()
+// ListFiles returns the files stored in the root path of the folder for a given provider version.
+func (r *localRepository) ListFiles(version string) ([]string, error) {
+	version, err := r.resolveVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	absolutePath := filepath.Join(r.basepath, r.providerLabel, version, r.RootPath())
+	entries, err := os.ReadDir(absolutePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the files of local release %s", version)
+	}
+	files := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, e.Name())
+		}
+	}
+	return files, nil
+}
+
+// resolveVersion returns the version to use for reading files, resolving the latest and the empty version.
+func (r *localRepository) resolveVersion(version string) (string, error) {
+	switch version {
+	case latestVersionTag:
+		latest, err := r.getLatestRelease()
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get the latest release")
+			return "", errors.Wrapf(err, "failed to get the latest release")
 		}
-	} else if version == "" {
-		version = r.defaultVersion
+		return latest, nil
+	case "":
+		return r.defaultVersion, nil
+	default:
+		return version, nil
 	}
+}
 
+// getFile reads a file for a given provider version from the local filesystem.
+func (r *localRepository) getFile(version, fileName string) ([]byte, error) {
 	absolutePath := filepath.Join(r.basepath, r.providerLabel, version, r.RootPath(), fileName)
 
 	f, err := os.Stat(absolutePath)
@@ -143,7 +217,7 @@ func (r *localRepository) GetVersions() ([]string, error) {
 }
 
 // newLocalRepository returns a new localRepository.
-func newLocalRepository(providerConfig config.Provider, configVariablesClient config.VariablesClient) (*localRepository, error) {
+func newLocalRepository(providerConfig config.Provider, configVariablesClient config.VariablesClient, opts ...localRepositoryOption) (*localRepository, error) {
 	url, err := url.Parse(providerConfig.URL())
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid url")
@@ -197,6 +271,16 @@ func newLocalRepository(providerConfig config.Provider, configVariablesClient co
 		componentsPath:        componentsPath,
 	}
 
+	repo.verifyChecksums, err = isChecksumVerificationRequired(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// process localRepositoryOptions
+	for _, o := range opts {
+		o(repo)
+	}
+
 	if defaultVersion == latestVersionTag {
 		repo.defaultVersion, err = repo.getLatestContractRelease(clusterv1.GroupVersion.Version)
 		if err != nil {
@@ -256,45 +340,5 @@ func (r *localRepository) getLatestPatchRelease(major, minor *uint) (string, err
 		return "", errors.Wrapf(err, "failed to get local repository versions")
 	}
 
-	var latestTag string
-	var latestPrereleaseTag string
-
-	var latestReleaseVersion *version.Version
-	var latestPrereleaseVersion *version.Version
-
-	for _, v := range versions {
-		sv, err := version.ParseSemantic(v)
-		if err != nil {
-			continue
-		}
-
-		if (major != nil && sv.Major() != *major) || (minor != nil && sv.Minor() != *minor) {
-			// skip versions that don't match the desired Major.Minor version.
-			continue
-		}
-
-		// track prereleases separately
-		if sv.PreRelease() != "" {
-			if latestPrereleaseVersion == nil || latestPrereleaseVersion.LessThan(sv) {
-				latestPrereleaseTag = v
-				latestPrereleaseVersion = sv
-			}
-			continue
-		}
-
-		if latestReleaseVersion == nil || latestReleaseVersion.LessThan(sv) {
-			latestTag = v
-			latestReleaseVersion = sv
-		}
-	}
-
-	// Fall back to returning latest prereleases if no release has been cut or bail if it's also empty
-	if latestTag == "" {
-		if latestPrereleaseTag == "" {
-			return "", errors.New("failed to find releases tagged with a valid semantic version number")
-		}
-
-		return latestPrereleaseTag, nil
-	}
-	return latestTag, nil
+	return latestVersion(versions, major, minor, r.includePrereleases)
 }
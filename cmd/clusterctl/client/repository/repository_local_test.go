@@ -380,3 +380,66 @@ func Test_localRepository_GetVersions(t *testing.T) {
 		})
 	}
 }
+
+func Test_localRepository_GetFile_Checksum(t *testing.T) {
+	tmpDir := createTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	dst := createLocalTestProviderFile(t, tmpDir, "bootstrap-foo/v1.0.0/bootstrap-components.yaml", "content")
+	createLocalTestProviderFile(t, tmpDir, "bootstrap-foo/v1.0.0/metadata.yaml", "content")
+	createLocalTestProviderFile(t, tmpDir, "bootstrap-foo/v1.0.0/metadata.yaml.sha256", testChecksum+"  metadata.yaml")
+	createLocalTestProviderFile(t, tmpDir, "bootstrap-foo/v1.0.0/corrupted.yaml", "corrupted")
+	createLocalTestProviderFile(t, tmpDir, "bootstrap-foo/v1.0.0/corrupted.yaml.sha256", testChecksum)
+
+	tests := []struct {
+		name     string
+		required bool
+		fileName string
+		wantErr  bool
+	}{
+		{
+			name:     "pass with a matching checksum",
+			fileName: "metadata.yaml",
+		},
+		{
+			name:     "pass without checksum if verification is not required",
+			fileName: "bootstrap-components.yaml",
+		},
+		{
+			name:     "fails with a mismatched checksum",
+			fileName: "corrupted.yaml",
+			wantErr:  true,
+		},
+		{
+			name:     "pass with a matching checksum if verification is required",
+			required: true,
+			fileName: "metadata.yaml",
+		},
+		{
+			name:     "fails without checksum if verification is required",
+			required: true,
+			fileName: "bootstrap-components.yaml",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			if tt.required {
+				variableClient.WithVar(config.RepositoryVerifyChecksumsVariable, "true")
+			}
+
+			r, err := newLocalRepository(config.NewProvider("foo", dst, clusterctlv1.BootstrapProviderType), variableClient)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			_, err = r.GetFile("v1.0.0", tt.fileName)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
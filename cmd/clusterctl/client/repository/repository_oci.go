@@ -0,0 +1,530 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+)
+
+const (
+	ociScheme = "oci"
+
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+	// ociTitleAnnotation is the annotation used for storing the file name of an artifact layer.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// ociChallengeParamRegexp matches the parameters of a WWW-Authenticate challenge, e.g. realm="https://auth.example.com/token".
+var ociChallengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ociRepository provides support for providers published as OCI artifacts in an OCI registry.
+//
+// Each provider version is an OCI artifact tagged with the version, and each file of the provider version
+// (components YAML, metadata.yaml, cluster templates) is stored in a layer of the artifact; the file name is stored
+// in the org.opencontainers.image.title annotation of the layer, like e.g. oras does when pushing files.
+// The repository URL should be in the form oci://{registry}/{repository}:{latest|version-tag}/{components.yaml}.
+type ociRepository struct {
+	providerConfig        config.Provider
+	configVariablesClient config.VariablesClient
+	httpClient            *http.Client
+	registry              string
+	repository            string
+	defaultVersion        string
+	rootPath              string
+	componentsPath        string
+	username              string
+	password              string
+	useBasicAuth          bool
+	token                 string
+	retryOptions          retryOptions
+	includePrereleases    bool
+	manifests             map[string]*ociManifest
+}
+
+var _ Repository = &ociRepository{}
+var _ fileChecksumGetter = &ociRepository{}
+var _ fileLister = &ociRepository{}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType,omitempty"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociRepositoryOption func(*ociRepository)
+
+func injectOCIHTTPClient(c *http.Client) ociRepositoryOption {
+	return func(o *ociRepository) {
+		o.httpClient = c
+	}
+}
+
+func injectOCIContext(ctx context.Context) ociRepositoryOption {
+	return func(o *ociRepository) {
+		o.httpClient = util.NewContextHTTPClient(ctx, o.httpClient)
+	}
+}
+
+func injectOCIIncludePrereleases() ociRepositoryOption {
+	return func(o *ociRepository) {
+		o.includePrereleases = true
+	}
+}
+
+// DefaultVersion returns defaultVersion field of ociRepository struct.
+func (o *ociRepository) DefaultVersion() string {
+	return o.defaultVersion
+}
+
+// GetVersions returns the list of versions that are available in a provider repository, derived from the
+// artifact tags that are valid semantic versions.
+func (o *ociRepository) GetVersions() ([]string, error) {
+	content, err := o.get(fmt.Sprintf("%s/tags/list", o.repository), "application/json")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the list of tags for %q", o.repository)
+	}
+
+	tagList := struct {
+		Tags []string `json:"tags"`
+	}{}
+	if err := json.Unmarshal(content, &tagList); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the list of tags for %q", o.repository)
+	}
+
+	versions := []string{}
+	for _, tag := range tagList.Tags {
+		if _, err := version.ParseSemantic(tag); err != nil {
+			// Discard tags that are not a valid semantic versions (the user can point explicitly to such tags).
+			continue
+		}
+		versions = append(versions, tag)
+	}
+	return versions, nil
+}
+
+// RootPath returns rootPath field of ociRepository struct.
+func (o *ociRepository) RootPath() string {
+	return o.rootPath
+}
+
+// ComponentsPath returns componentsPath field of ociRepository struct.
+func (o *ociRepository) ComponentsPath() string {
+	return o.componentsPath
+}
+
+// GetFile returns a file for a given provider version.
+func (o *ociRepository) GetFile(version, path string) ([]byte, error) {
+	layer, err := o.getLayer(version, path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := o.get(fmt.Sprintf("%s/blobs/%s", o.repository, layer.Digest), "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download file %q from the OCI artifact %s:%s", path, o.repository, version)
+	}
+	if err := verifyOCIDigest(content, layer.Digest); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify file %q from the OCI artifact %s:%s", path, o.repository, version)
+	}
+	return content, nil
+}
+
+// GetFileChecksum returns the checksum of a file for a given provider version, that is the digest of the
+// corresponding layer in the OCI artifact; NB. the content of all the files is verified against the digest
+// by GetFile, so OCI repositories always comply with the repository-verify-checksums variable.
+func (o *ociRepository) GetFileChecksum(version, path string) (string, error) {
+	layer, err := o.getLayer(version, path)
+	if err != nil {
+		return "", err
+	}
+	return layer.Digest, nil
+}
+
+// ListFiles returns the files in the OCI artifact for a given provider version, using the title of the layers.
+func (o *ociRepository) ListFiles(version string) ([]string, error) {
+	manifest, err := o.getManifest(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the OCI artifact %s:%s", o.repository, version)
+	}
+
+	names := []string{}
+	for i := range manifest.Layers {
+		if title := manifest.Layers[i].Annotations[ociTitleAnnotation]; title != "" {
+			names = append(names, title)
+		}
+	}
+	return relativeFileNames(o.rootPath, names), nil
+}
+
+// getLayer returns the layer of the OCI artifact for a given provider version hosting a file.
+func (o *ociRepository) getLayer(version, path string) (*ociDescriptor, error) {
+	manifest, err := o.getManifest(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the OCI artifact %s:%s", o.repository, version)
+	}
+
+	absoluteFileName := filepath.Join(o.rootPath, path)
+	for i := range manifest.Layers {
+		if manifest.Layers[i].Annotations[ociTitleAnnotation] == absoluteFileName {
+			return &manifest.Layers[i], nil
+		}
+	}
+	return nil, errors.Errorf("failed to get file %q from the OCI artifact %s:%s", path, o.repository, version)
+}
+
+// newOCIRepository returns an ociRepository implementation.
+func newOCIRepository(providerConfig config.Provider, configVariablesClient config.VariablesClient, opts ...ociRepositoryOption) (*ociRepository, error) {
+	if configVariablesClient == nil {
+		return nil, errors.New("invalid arguments: configVariablesClient can't be nil")
+	}
+
+	rURL, err := url.Parse(providerConfig.URL())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+
+	if rURL.Scheme != ociScheme || rURL.Host == "" {
+		return nil, errors.New("invalid url: an OCI repository url should start with oci://{registry}")
+	}
+
+	// Check if the path is in the expected format, {repository}:{version}/{components.yaml}.
+	// NB. the url's path has a leading slash we need to clean up before splitting.
+	reference := strings.TrimPrefix(rURL.Path, "/")
+	i := strings.Index(reference, ":")
+	j := strings.Index(reference[i+1:], "/")
+	if i < 1 || j < 1 || j == len(reference[i+1:])-1 {
+		return nil, errors.New("invalid url: an OCI repository url should be in the form oci://{registry}/{repository}:{latest|version-tag}/{components.yaml}")
+	}
+
+	repository := reference[:i]
+	defaultVersion := reference[i+1 : i+1+j]
+	path := reference[i+1+j+1:]
+
+	// use path's directory as a rootPath
+	rootPath := filepath.Dir(path)
+	// use the file name (if any) as componentsPath
+	componentsPath := getComponentsPath(path, rootPath)
+
+	repo := &ociRepository{
+		providerConfig:        providerConfig,
+		configVariablesClient: configVariablesClient,
+		registry:              rURL.Host,
+		repository:            repository,
+		defaultVersion:        defaultVersion,
+		rootPath:              rootPath,
+		componentsPath:        componentsPath,
+		manifests:             map[string]*ociManifest{},
+	}
+
+	repo.httpClient, err = NewHTTPClient(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.retryOptions, err = newRetryOptions(configVariablesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// process ociRepositoryOptions
+	for _, o := range opts {
+		o(repo)
+	}
+
+	// Credentials are optional, so anonymous pulls are used if they are not defined.
+	if username, err := configVariablesClient.Get(config.OCIUsernameVariable); err == nil {
+		repo.username = username
+	}
+	if password, err := configVariablesClient.Get(config.OCIPasswordVariable); err == nil {
+		repo.password = password
+	}
+
+	if defaultVersion == latestVersionTag {
+		repo.defaultVersion, err = repo.getLatestContractRelease(clusterv1.GroupVersion.Version)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get OCI latest version")
+		}
+	}
+
+	return repo, nil
+}
+
+// getManifest returns the manifest of the OCI artifact with the given tag.
+func (o *ociRepository) getManifest(tag string) (*ociManifest, error) {
+	if manifest, ok := o.manifests[tag]; ok {
+		return manifest, nil
+	}
+
+	content, err := o.get(fmt.Sprintf("%s/manifests/%s", o.repository, tag), ociManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the OCI manifest")
+	}
+	o.manifests[tag] = manifest
+	return manifest, nil
+}
+
+// get reads a resource from the registry API, retrying in case of transient errors.
+func (o *ociRepository) get(path string, accept string) ([]byte, error) {
+	var content []byte
+	err := o.retryOptions.retry(func() error {
+		var err error
+		content, err = o.getOnce(path, accept)
+		return err
+	})
+	return content, err
+}
+
+// getOnce reads a resource from the registry API, authenticating if required by the registry.
+func (o *ociRepository) getOnce(path string, accept string) ([]byte, error) {
+	resourceURL := fmt.Sprintf("https://%s/v2/%s", o.registry, path)
+
+	response, err := o.do(resourceURL, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// If the registry requires authentication, authenticate and try again.
+	if response.StatusCode == http.StatusUnauthorized {
+		if err := o.authenticate(response.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+
+		response, err = o.do(resourceURL, accept)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := errors.Errorf("failed to get %q: unexpected status %q", resourceURL, response.Status)
+		if !isRetriableStatusCode(response.StatusCode) {
+			return nil, &nonRetriableError{err: err}
+		}
+		return nil, err
+	}
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", resourceURL)
+	}
+	return content, nil
+}
+
+func (o *ociRepository) do(resourceURL string, accept string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the request for %q", resourceURL)
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	switch {
+	case o.token != "":
+		request.Header.Set("Authorization", "Bearer "+o.token)
+	case o.useBasicAuth:
+		request.SetBasicAuth(o.username, o.password)
+	}
+
+	response, err := o.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %q", resourceURL)
+	}
+	return response, nil
+}
+
+// authenticate handles the WWW-Authenticate challenge returned by the registry, supporting both the Basic and
+// the Bearer token authentication schemes.
+func (o *ociRepository) authenticate(challenge string) error {
+	challengeScheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	params := map[string]string{}
+	for _, m := range ociChallengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+
+	switch challengeScheme {
+	case "basic":
+		if o.username == "" {
+			return &nonRetriableError{err: errors.Errorf("the registry %s requires authentication; please set the %s and %s variables", o.registry, config.OCIUsernameVariable, config.OCIPasswordVariable)}
+		}
+		o.useBasicAuth = true
+		return nil
+	case "bearer":
+		return o.getToken(params["realm"], params["service"], params["scope"])
+	default:
+		return &nonRetriableError{err: errors.Errorf("the registry %s requires an unsupported authentication scheme %q", o.registry, challengeScheme)}
+	}
+}
+
+// getToken gets a Bearer token from the authorization service of the registry; the credentials, if any, are used
+// for getting the token, otherwise an anonymous token is requested.
+func (o *ociRepository) getToken(realm, service, scope string) error {
+	if realm == "" {
+		return errors.Errorf("invalid authentication challenge from the registry %s: the realm is missing", o.registry)
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return errors.Wrapf(err, "invalid authentication realm %q", realm)
+	}
+	query := tokenURL.Query()
+	if service != "" {
+		query.Set("service", service)
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the token request for %q", realm)
+	}
+	if o.username != "" {
+		request.SetBasicAuth(o.username, o.password)
+	}
+
+	response, err := o.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get a token from %q", realm)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err := errors.Errorf("failed to get a token from %q: unexpected status %q", realm, response.Status)
+		if !isRetriableStatusCode(response.StatusCode) {
+			return &nonRetriableError{err: err}
+		}
+		return err
+	}
+
+	tokenResponse := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return errors.Wrapf(err, "failed to parse the token from %q", realm)
+	}
+
+	o.token = tokenResponse.Token
+	if o.token == "" {
+		o.token = tokenResponse.AccessToken
+	}
+	if o.token == "" {
+		return errors.Errorf("failed to get a token from %q: the token is empty", realm)
+	}
+	return nil
+}
+
+// verifyOCIDigest checks the content matches a sha256 digest.
+func verifyOCIDigest(content []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return errors.Errorf("unsupported digest algorithm for %q", digest)
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != strings.TrimPrefix(digest, "sha256:") {
+		return errors.Errorf("the content does not match the digest %q", digest)
+	}
+	return nil
+}
+
+// getLatestContractRelease returns the latest patch release for an OCI repository for the current API contract, according to
+// semantic version order of the artifact tags.
+func (o *ociRepository) getLatestContractRelease(contract string) (string, error) {
+	latest, err := o.getLatestRelease()
+	if err != nil {
+		return latest, err
+	}
+	// Attempt to check if the latest release satisfies the API Contract
+	// This is a best-effort attempt to find the latest release for an older API contract if it's not the latest release.
+	// If an error occurs, we just return the latest release.
+	file, err := o.GetFile(latest, metadataFile)
+	if err != nil {
+		// if we can't get the metadata file from the release, we return latest.
+		return latest, nil // nolint:nilerr
+	}
+	latestMetadata := &clusterctlv1.Metadata{}
+	codecFactory := serializer.NewCodecFactory(scheme.Scheme)
+	if err := apiruntime.DecodeInto(codecFactory.UniversalDecoder(), file, latestMetadata); err != nil {
+		return latest, nil // nolint:nilerr
+	}
+
+	releaseSeries := latestMetadata.GetReleaseSeriesForContract(contract)
+	if releaseSeries == nil {
+		return latest, nil
+	}
+
+	sv, err := version.ParseSemantic(latest)
+	if err != nil {
+		return latest, nil // nolint:nilerr
+	}
+
+	// If the Major or Minor version of the latest release doesn't match the release series for the current contract,
+	// return the latest patch release of the desired Major/Minor version.
+	if sv.Major() != releaseSeries.Major || sv.Minor() != releaseSeries.Minor {
+		return o.getLatestPatchRelease(&releaseSeries.Major, &releaseSeries.Minor)
+	}
+	return latest, nil
+}
+
+// getLatestRelease returns the latest release for an OCI repository.
+func (o *ociRepository) getLatestRelease() (string, error) {
+	return o.getLatestPatchRelease(nil, nil)
+}
+
+// getLatestPatchRelease returns the latest patch release for a given Major and Minor version.
+func (o *ociRepository) getLatestPatchRelease(major, minor *uint) (string, error) {
+	versions, err := o.GetVersions()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get OCI repository versions")
+	}
+
+	return latestVersion(versions, major, minor, o.includePrereleases)
+}
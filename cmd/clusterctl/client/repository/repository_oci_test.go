@@ -0,0 +1,295 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+// fakeOCIRegistry is a minimal OCI registry serving the artifacts of a provider repository; if token is set,
+// the registry requires Bearer token authentication, with the token issued for the given username and password.
+type fakeOCIRegistry struct {
+	repository string
+	artifacts  map[string]map[string]string
+	username   string
+	password   string
+	token      string
+	// corruptBlobs makes the registry serve blobs not matching their digest.
+	corruptBlobs bool
+}
+
+func ociDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (r *fakeOCIRegistry) handler(server **httptest.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != r.username || password != r.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="repository:%s:pull"`, (*server).URL, r.repository))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		path := strings.TrimPrefix(req.URL.Path, "/v2/"+r.repository+"/")
+		switch {
+		case path == "tags/list":
+			tags := []string{}
+			for tag := range r.artifacts {
+				tags = append(tags, tag)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": r.repository, "tags": tags})
+		case strings.HasPrefix(path, "manifests/"):
+			files, ok := r.artifacts[strings.TrimPrefix(path, "manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			manifest := ociManifest{MediaType: ociManifestMediaType}
+			for name, content := range files {
+				manifest.Layers = append(manifest.Layers, ociDescriptor{
+					MediaType:   "application/vnd.oci.image.layer.v1.tar",
+					Digest:      ociDigest(content),
+					Size:        int64(len(content)),
+					Annotations: map[string]string{ociTitleAnnotation: name},
+				})
+			}
+			_ = json.NewEncoder(w).Encode(manifest)
+		case strings.HasPrefix(path, "blobs/"):
+			digest := strings.TrimPrefix(path, "blobs/")
+			for _, files := range r.artifacts {
+				for _, content := range files {
+					if ociDigest(content) == digest {
+						if r.corruptBlobs {
+							content += "corrupted"
+						}
+						_, _ = w.Write([]byte(content))
+						return
+					}
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return mux
+}
+
+func newFakeOCIServer(registry *fakeOCIRegistry) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(registry.handler(&server))
+	return server
+}
+
+func Test_ociRepository_newOCIRepository(t *testing.T) {
+	registry := &fakeOCIRegistry{
+		repository: "capi/infra",
+		artifacts: map[string]map[string]string{
+			"v1.0.0": {"components.yaml": "v1.0.0-components"},
+			"v1.1.0": {"components.yaml": "v1.1.0-components"},
+			"foo":    {"components.yaml": "foo-components"},
+		},
+	}
+	server := newFakeOCIServer(registry)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name               string
+		url                string
+		wantDefaultVersion string
+		wantRepository     string
+		wantRootPath       string
+		wantComponentsPath string
+		wantErr            bool
+	}{
+		{
+			name:               "pass with a version",
+			url:                fmt.Sprintf("oci://%s/capi/infra:v1.0.0/components.yaml", host),
+			wantRepository:     "capi/infra",
+			wantDefaultVersion: "v1.0.0",
+			wantRootPath:       ".",
+			wantComponentsPath: "components.yaml",
+		},
+		{
+			name:               "pass with latest",
+			url:                fmt.Sprintf("oci://%s/capi/infra:latest/path/components.yaml", host),
+			wantRepository:     "capi/infra",
+			wantDefaultVersion: "v1.1.0",
+			wantRootPath:       "path",
+			wantComponentsPath: "components.yaml",
+		},
+		{
+			name:    "fails without the tag",
+			url:     fmt.Sprintf("oci://%s/capi/infra/components.yaml", host),
+			wantErr: true,
+		},
+		{
+			name:    "fails without the components path",
+			url:     fmt.Sprintf("oci://%s/capi/infra:v1.0.0", host),
+			wantErr: true,
+		},
+		{
+			name:    "fails without the registry",
+			url:     "oci:///capi/infra:v1.0.0/components.yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			providerConfig := config.NewProvider("infra", tt.url, clusterctlv1.InfrastructureProviderType)
+			got, err := newOCIRepository(providerConfig, test.NewFakeVariableClient(), injectOCIHTTPClient(server.Client()))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got.registry).To(Equal(host))
+			g.Expect(got.repository).To(Equal(tt.wantRepository))
+			g.Expect(got.DefaultVersion()).To(Equal(tt.wantDefaultVersion))
+			g.Expect(got.RootPath()).To(Equal(tt.wantRootPath))
+			g.Expect(got.ComponentsPath()).To(Equal(tt.wantComponentsPath))
+		})
+	}
+}
+
+func Test_ociRepository_GetFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		registry     *fakeOCIRegistry
+		variables    map[string]string
+		version      string
+		path         string
+		want         string
+		wantVersions []string
+		wantErr      bool
+	}{
+		{
+			name: "pass with anonymous pulls",
+			registry: &fakeOCIRegistry{
+				repository: "capi/infra",
+				artifacts:  map[string]map[string]string{"v1.0.0": {"components.yaml": "components", "metadata.yaml": "metadata"}},
+			},
+			version:      "v1.0.0",
+			path:         "metadata.yaml",
+			want:         "metadata",
+			wantVersions: []string{"v1.0.0"},
+		},
+		{
+			name: "pass with credentials",
+			registry: &fakeOCIRegistry{
+				repository: "capi/infra",
+				artifacts:  map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+				username:   "user",
+				password:   "pass",
+				token:      "token",
+			},
+			variables:    map[string]string{config.OCIUsernameVariable: "user", config.OCIPasswordVariable: "pass"},
+			version:      "v1.0.0",
+			path:         "components.yaml",
+			want:         "components",
+			wantVersions: []string{"v1.0.0"},
+		},
+		{
+			name: "fails with wrong credentials",
+			registry: &fakeOCIRegistry{
+				repository: "capi/infra",
+				artifacts:  map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+				username:   "user",
+				password:   "pass",
+				token:      "token",
+			},
+			variables: map[string]string{config.OCIUsernameVariable: "user", config.OCIPasswordVariable: "wrong"},
+			version:   "v1.0.0",
+			path:      "components.yaml",
+			wantErr:   true,
+		},
+		{
+			name: "fails if the file does not exist",
+			registry: &fakeOCIRegistry{
+				repository: "capi/infra",
+				artifacts:  map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+			},
+			version: "v1.0.0",
+			path:    "metadata.yaml",
+			wantErr: true,
+		},
+		{
+			name: "fails if the file does not match the digest",
+			registry: &fakeOCIRegistry{
+				repository:   "capi/infra",
+				artifacts:    map[string]map[string]string{"v1.0.0": {"components.yaml": "components"}},
+				corruptBlobs: true,
+			},
+			version: "v1.0.0",
+			path:    "components.yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			server := newFakeOCIServer(tt.registry)
+			defer server.Close()
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			providerConfig := config.NewProvider("infra", fmt.Sprintf("oci://%s/capi/infra:v1.0.0/components.yaml", strings.TrimPrefix(server.URL, "https://")), clusterctlv1.InfrastructureProviderType)
+			repo, err := newOCIRepository(providerConfig, variableClient, injectOCIHTTPClient(server.Client()))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := repo.GetFile(tt.version, tt.path)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(got)).To(Equal(tt.want))
+
+			versions, err := repo.GetVersions()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(versions).To(Equal(tt.wantVersions))
+		})
+	}
+}
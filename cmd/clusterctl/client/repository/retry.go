@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/util"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+const (
+	// defaultRepositoryRetries is the number of times a failed repository fetch operation is retried by default.
+	defaultRepositoryRetries = 3
+
+	// defaultRepositoryRetryTimeout is the maximum time spent retrying a repository fetch operation by default.
+	defaultRepositoryRetryTimeout = 30 * time.Second
+)
+
+// retryOptions defines how repository fetch operations are retried in case of transient errors.
+type retryOptions struct {
+	backoff wait.Backoff
+	timeout time.Duration
+}
+
+// defaultRetryOptions returns the default retry options for repository fetch operations.
+func defaultRetryOptions() retryOptions {
+	// Return a exponential backoff configuration which returns durations for a total time of ~3.5s with the default retries.
+	// Example: 0, .5s, 1s, 2s
+	// Jitter is added as a random fraction of the duration multiplied by the jitter factor.
+	return retryOptions{
+		backoff: wait.Backoff{
+			Duration: 500 * time.Millisecond,
+			Factor:   2,
+			Steps:    defaultRepositoryRetries + 1,
+			Jitter:   0.1,
+		},
+		timeout: defaultRepositoryRetryTimeout,
+	}
+}
+
+// newRetryOptions returns the retry options for repository fetch operations; the defaults can be changed
+// using the repository-retries and repository-retry-timeout variables.
+func newRetryOptions(configVariablesClient config.VariablesClient) (retryOptions, error) {
+	opts := defaultRetryOptions()
+	if configVariablesClient == nil {
+		return opts, nil
+	}
+
+	if v, err := configVariablesClient.Get(config.RepositoryRetriesVariable); err == nil {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			return retryOptions{}, errors.Errorf("invalid value %q for the %s variable: it must be a non negative integer", v, config.RepositoryRetriesVariable)
+		}
+		opts.backoff.Steps = retries + 1
+	}
+
+	if v, err := configVariablesClient.Get(config.RepositoryRetryTimeoutVariable); err == nil {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return retryOptions{}, errors.Errorf("invalid value %q for the %s variable: it must be a positive duration, e.g. 30s", v, config.RepositoryRetryTimeoutVariable)
+		}
+		opts.timeout = timeout
+	}
+
+	return opts, nil
+}
+
+// retry runs the operation, retrying with exponential backoff in case of errors until the retries are exhausted
+// or the timeout expires; non retriable errors, e.g. not found or authentication failures, are returned immediately.
+func (o retryOptions) retry(operation func() error) error {
+	log := logf.Log
+
+	backoff := o.backoff
+	if backoff.Steps < 1 {
+		backoff.Steps = 1
+	}
+
+	start := time.Now()
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		lastErr = operation()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !isRetriableError(lastErr) || (o.timeout > 0 && time.Since(start) >= o.timeout) {
+			return false, lastErr
+		}
+		log.V(5).Info("Repository operation failed, retrying with backoff", "Cause", lastErr.Error())
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+	return err
+}
+
+// nonRetriableError wraps errors that should not be retried.
+type nonRetriableError struct {
+	err error
+}
+
+func (e *nonRetriableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetriableError) Unwrap() error {
+	return e.err
+}
+
+// isRetriableStatusCode returns false for HTTP status codes that are not going to change by retrying,
+// e.g. 404 NotFound or 401 Unauthorized.
+func isRetriableStatusCode(statusCode int) bool {
+	if statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode < 400 || statusCode >= 500
+}
+
+// isRetriableError returns true if an error returned by a repository fetch operation is possibly transient.
+func isRetriableError(err error) bool {
+	var nonRetriable *nonRetriableError
+	if errors.As(err, &nonRetriable) {
+		return false
+	}
+
+	if util.IsContextError(err) {
+		return false
+	}
+
+	// NB. waiting for the GitHub rate limit to reset takes up to one hour, so it is not worth retrying.
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return false
+	}
+
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) && githubErr.Response != nil {
+		return isRetriableStatusCode(githubErr.Response.StatusCode)
+	}
+
+	return true
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_newRetryOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		variables   map[string]string
+		wantSteps   int
+		wantTimeout time.Duration
+		wantErr     bool
+	}{
+		{
+			name:        "defaults",
+			wantSteps:   defaultRepositoryRetries + 1,
+			wantTimeout: defaultRepositoryRetryTimeout,
+		},
+		{
+			name:        "custom retries and timeout",
+			variables:   map[string]string{config.RepositoryRetriesVariable: "5", config.RepositoryRetryTimeoutVariable: "2m"},
+			wantSteps:   6,
+			wantTimeout: 2 * time.Minute,
+		},
+		{
+			name:        "retries can be disabled",
+			variables:   map[string]string{config.RepositoryRetriesVariable: "0"},
+			wantSteps:   1,
+			wantTimeout: defaultRepositoryRetryTimeout,
+		},
+		{
+			name:      "fails for invalid retries",
+			variables: map[string]string{config.RepositoryRetriesVariable: "-1"},
+			wantErr:   true,
+		},
+		{
+			name:      "fails for invalid timeout",
+			variables: map[string]string{config.RepositoryRetryTimeoutVariable: "foo"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			variableClient := test.NewFakeVariableClient()
+			for k, v := range tt.variables {
+				variableClient.WithVar(k, v)
+			}
+
+			got, err := newRetryOptions(variableClient)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got.backoff.Steps).To(Equal(tt.wantSteps))
+			g.Expect(got.timeout).To(Equal(tt.wantTimeout))
+		})
+	}
+}
+
+func Test_retryOptions_retry(t *testing.T) {
+	transientErr := errors.New("connection reset by peer")
+	notFoundErr := &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "succeeds at the first attempt",
+			errs:         nil,
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after transient errors",
+			errs:         []error{transientErr, transientErr},
+			wantAttempts: 3,
+		},
+		{
+			name:         "fails when retries are exhausted",
+			errs:         []error{transientErr, transientErr, transientErr, transientErr},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "does not retry non retriable errors",
+			errs:         []error{&nonRetriableError{err: transientErr}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "does not retry GitHub not found errors",
+			errs:         []error{errors.Wrap(notFoundErr, "failed to get release")},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "does not retry GitHub rate limit errors",
+			errs:         []error{&github.RateLimitError{}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "does not retry errors caused by an expired deadline",
+			errs:         []error{errors.Wrap(context.DeadlineExceeded, "failed to get release")},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			opts := retryOptions{
+				backoff: wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3},
+				timeout: time.Minute,
+			}
+
+			attempts := 0
+			err := opts.retry(func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(attempts).To(Equal(tt.wantAttempts))
+		})
+	}
+}
@@ -17,6 +17,11 @@ limitations under the License.
 package repository
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
@@ -46,8 +51,15 @@ type Template interface {
 	// Yaml returns yaml defining all the cluster template objects as a byte array.
 	Yaml() ([]byte, error)
 
-	// Objs returns the cluster template as a list of Unstructured objects.
+	// Objs returns the cluster template as a list of Unstructured objects, in the same order they are
+	// defined in the YAML. Callers can modify the objects (e.g. to inject labels) before applying them;
+	// changes are reflected by Yaml.
+	// The list is empty when the template was created with SkipTemplateProcess.
 	Objs() []unstructured.Unstructured
+
+	// SortedObjs returns the cluster template objects in the order they should be applied, e.g. Namespaces and
+	// Secrets first, then the Cluster, then MachineDeployments; see SortObjs for the ordering rules.
+	SortedObjs() ([]unstructured.Unstructured, error)
 }
 
 // template implements Template.
@@ -77,6 +89,10 @@ func (t *template) Objs() []unstructured.Unstructured {
 	return t.objs
 }
 
+func (t *template) SortedObjs() ([]unstructured.Unstructured, error) {
+	return SortObjs(t.objs)
+}
+
 func (t *template) Yaml() ([]byte, error) {
 	return utilyaml.FromUnstructured(t.objs)
 }
@@ -133,3 +149,68 @@ func NewTemplate(input TemplateInput) (Template, error) {
 		objs:            objs,
 	}, nil
 }
+
+// MergeTemplates merges the provided Templates into one Template; this allows to compose a workload cluster
+// definition by layering templates, e.g. a base cluster template with an add-on overlay.
+// Notes on the merge operation:
+//   - All the templates must have the same TargetNamespace.
+//   - The Variables of the resulting template are the union of the Variables of all the templates.
+//   - The default value of a variable is picked from the first template defining it.
+//   - The Objs of the resulting template are the union of the Objs of all the templates, in order; objects
+//     with the same GroupVersionKind, namespace and name are de-duplicated if identical, while an error is
+//     returned if they are defined with different content.
+func MergeTemplates(templates ...Template) (Template, error) {
+	if len(templates) == 0 {
+		return nil, errors.New("at least one template is required for merging")
+	}
+
+	merged := &template{
+		variables:       []string{},
+		variableMap:     map[string]*string{},
+		targetNamespace: templates[0].TargetNamespace(),
+		objs:            []unstructured.Unstructured{},
+	}
+
+	// objIndex keeps track of the template defining each object, using the GroupVersionKind, namespace and name as a key.
+	type objSource struct {
+		template int
+		obj      unstructured.Unstructured
+	}
+	objIndex := map[string]objSource{}
+	conflicts := []string{}
+
+	for i, t := range templates {
+		if t.TargetNamespace() != merged.targetNamespace {
+			return nil, errors.Errorf("unable to merge templates with different target namespaces: template %d targets %q, template 0 targets %q", i, t.TargetNamespace(), merged.targetNamespace)
+		}
+
+		for key, val := range t.VariableMap() {
+			if v, ok := merged.variableMap[key]; !ok || v == nil {
+				merged.variableMap[key] = val
+			}
+		}
+
+		for _, o := range t.Objs() {
+			key := fmt.Sprintf("%s, %s/%s", o.GroupVersionKind(), o.GetNamespace(), o.GetName())
+			if existing, ok := objIndex[key]; ok {
+				if !reflect.DeepEqual(existing.obj.Object, o.Object) {
+					conflicts = append(conflicts, fmt.Sprintf("%s is defined with different content in template %d and template %d", key, existing.template, i))
+				}
+				continue
+			}
+			objIndex[key] = objSource{template: i, obj: o}
+			merged.objs = append(merged.objs, o)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, errors.Errorf("unable to merge templates, conflicting objects found: %s", strings.Join(conflicts, "; "))
+	}
+
+	for key := range merged.variableMap {
+		merged.variables = append(merged.variables, key)
+	}
+	sort.Strings(merged.variables)
+
+	return merged, nil
+}
@@ -36,6 +36,7 @@ type templateClient struct {
 	repository            Repository
 	configVariablesClient config.VariablesClient
 	processor             yaml.Processor
+	includePrereleases    bool
 }
 
 // TemplateClientInput is an input strict for newTemplateClient.
@@ -45,6 +46,7 @@ type TemplateClientInput struct {
 	repository            Repository
 	configVariablesClient config.VariablesClient
 	processor             yaml.Processor
+	includePrereleases    bool
 }
 
 // Ensure templateClient implements the TemplateClient interface.
@@ -59,6 +61,7 @@ func newTemplateClient(input TemplateClientInput) *templateClient {
 		repository:            input.repository,
 		configVariablesClient: input.configVariablesClient,
 		processor:             input.processor,
+		includePrereleases:    input.includePrereleases,
 	}
 }
 
@@ -72,7 +75,10 @@ func (c *templateClient) Get(flavor, targetNamespace string, skipTemplateProcess
 		return nil, errors.New("invalid arguments: please provide a targetNamespace")
 	}
 
-	version := c.version
+	version, err := resolveVersion(c.repository, c.version, c.includePrereleases)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the version for provider %q", c.provider.ManifestLabel())
+	}
 	name := c.processor.GetTemplateName(version, flavor)
 
 	// read the component YAML, reading the local override file if it exists, otherwise read from the provider repository
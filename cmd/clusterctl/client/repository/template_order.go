@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// kindOrder defines the order used for creating well known Kubernetes and Cluster API kinds; kinds not listed here
+// are ranked using their API group (see objectRank).
+var kindOrder = []string{
+	// Namespaces go first because all the namespaced objects depend on them.
+	"Namespace",
+	// Custom Resource Definitions go before the corresponding Custom Resources.
+	"CustomResourceDefinition",
+	// Secrets, ConfigMaps and ServiceAccounts are referenced by many other objects, e.g. by ClusterResourceSets
+	// or by identity references in infrastructure clusters.
+	"Secret",
+	"ConfigMap",
+	"ServiceAccount",
+	// ClusterClasses go before the Clusters using them.
+	"ClusterClass",
+}
+
+// capiKindOrder defines the order used for creating the Cluster API kinds that depend on a Cluster; these objects
+// are created after the Cluster and its infrastructure, bootstrap and control plane objects.
+var capiKindOrder = []string{
+	"MachineDeployment",
+	"MachineSet",
+	"MachinePool",
+	"Machine",
+	"MachineHealthCheck",
+}
+
+var (
+	rankTemplate       = len(kindOrder)
+	rankCluster        = rankTemplate + 1
+	rankInfrastructure = rankTemplate + 2
+	rankBootstrap      = rankTemplate + 3
+	rankControlPlane   = rankTemplate + 4
+	rankCAPIKinds      = rankTemplate + 5
+	rankOther          = rankCAPIKinds + len(capiKindOrder)
+)
+
+// objectRank returns the rank of an object in the creation order; objects with a lower rank should be created first.
+func objectRank(o unstructured.Unstructured) int {
+	gvk := o.GroupVersionKind()
+	for i, k := range kindOrder {
+		if gvk.Kind == k {
+			return i
+		}
+	}
+
+	// Templates (e.g. KubeadmConfigTemplate or the infrastructure machine templates) are referenced by
+	// control planes, MachineDeployments and MachinePools, so they go before them.
+	if strings.HasSuffix(gvk.Kind, "Template") {
+		return rankTemplate
+	}
+
+	switch {
+	case gvk.Group == "cluster.x-k8s.io" && gvk.Kind == "Cluster":
+		return rankCluster
+	case strings.HasPrefix(gvk.Group, "infrastructure."):
+		return rankInfrastructure
+	case strings.HasPrefix(gvk.Group, "bootstrap."):
+		return rankBootstrap
+	case strings.HasPrefix(gvk.Group, "controlplane."):
+		return rankControlPlane
+	case gvk.Group == "cluster.x-k8s.io" || strings.HasSuffix(gvk.Group, ".cluster.x-k8s.io"):
+		for i, k := range capiKindOrder {
+			if gvk.Kind == k {
+				return rankCAPIKinds + i
+			}
+		}
+	}
+	return rankOther
+}
+
+// SortObjs returns the objects sorted in the order they should be created, so callers applying the objects one by one
+// do not hit transient errors from webhooks or validations due to missing dependencies.
+// The following rules are used:
+//   - Objects owned by other objects in the list (via ownerReferences) come after their owners; an error is
+//     returned if the ownerReferences define a cycle.
+//   - Then objects are sorted by kind: Namespaces, CustomResourceDefinitions, Secrets, ConfigMaps, ServiceAccounts,
+//     ClusterClasses, the *Template kinds, Clusters, the objects in the infrastructure, bootstrap and control plane
+//     API groups, MachineDeployments, MachineSets, MachinePools, Machines, MachineHealthChecks, and then all the
+//     other objects.
+//   - Objects with the same rank keep the order they have in the input list.
+func SortObjs(objs []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	objKey := func(gk schema.GroupKind, namespace, name string) string {
+		return fmt.Sprintf("%s, %s/%s", gk, namespace, name)
+	}
+
+	index := make(map[string]int, len(objs))
+	for i, o := range objs {
+		index[objKey(o.GroupVersionKind().GroupKind(), o.GetNamespace(), o.GetName())] = i
+	}
+
+	// dependencies[i] counts the owners of objs[i] not yet sorted; dependents[i] lists the objects owned by objs[i].
+	dependencies := make([]int, len(objs))
+	dependents := make([][]int, len(objs))
+	for i, o := range objs {
+		for _, ref := range o.GetOwnerReferences() {
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse the owner reference apiVersion of %s %s/%s", o.GetKind(), o.GetNamespace(), o.GetName())
+			}
+			// NB. Owner references are always referencing objects in the same namespace or cluster scoped objects.
+			owner, ok := index[objKey(gv.WithKind(ref.Kind).GroupKind(), o.GetNamespace(), ref.Name)]
+			if !ok {
+				owner, ok = index[objKey(gv.WithKind(ref.Kind).GroupKind(), "", ref.Name)]
+			}
+			if !ok || owner == i {
+				continue
+			}
+			dependencies[i]++
+			dependents[owner] = append(dependents[owner], i)
+		}
+	}
+
+	ranks := make([]int, len(objs))
+	for i, o := range objs {
+		ranks[i] = objectRank(o)
+	}
+
+	sorted := make([]unstructured.Unstructured, 0, len(objs))
+	done := make([]bool, len(objs))
+	for len(sorted) < len(objs) {
+		// Pick the object with the lowest rank among the ones without pending owners, preserving input order on ties.
+		next := -1
+		for i := range objs {
+			if done[i] || dependencies[i] > 0 {
+				continue
+			}
+			if next == -1 || ranks[i] < ranks[next] {
+				next = i
+			}
+		}
+		if next == -1 {
+			cycle := []string{}
+			for i, o := range objs {
+				if !done[i] {
+					cycle = append(cycle, fmt.Sprintf("%s %s/%s", o.GetKind(), o.GetNamespace(), o.GetName()))
+				}
+			}
+			return nil, errors.Errorf("failed to sort objects, owner references define a cycle between: %s", strings.Join(cycle, ", "))
+		}
+
+		done[next] = true
+		sorted = append(sorted, objs[next])
+		for _, d := range dependents[next] {
+			dependencies[d]--
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_SortObjs(t *testing.T) {
+	obj := func(apiVersion, kind, name string, owners ...metav1.OwnerReference) unstructured.Unstructured {
+		o := unstructured.Unstructured{}
+		o.SetAPIVersion(apiVersion)
+		o.SetKind(kind)
+		o.SetName(name)
+		o.SetNamespace("ns1")
+		o.SetOwnerReferences(owners)
+		return o
+	}
+	ownedBy := func(apiVersion, kind, name string) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name}
+	}
+
+	tests := []struct {
+		name    string
+		objs    []unstructured.Unstructured
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "sort by kind",
+			objs: []unstructured.Unstructured{
+				obj("cluster.x-k8s.io/v1alpha4", "MachineHealthCheck", "mhc"),
+				obj("cluster.x-k8s.io/v1alpha4", "MachineDeployment", "md"),
+				obj("bootstrap.cluster.x-k8s.io/v1alpha4", "KubeadmConfigTemplate", "kct"),
+				obj("controlplane.cluster.x-k8s.io/v1alpha4", "KubeadmControlPlane", "kcp"),
+				obj("infrastructure.cluster.x-k8s.io/v1alpha4", "DockerMachineTemplate", "dmt"),
+				obj("infrastructure.cluster.x-k8s.io/v1alpha4", "DockerCluster", "dc"),
+				obj("cluster.x-k8s.io/v1alpha4", "Cluster", "c"),
+				obj("v1", "Secret", "s"),
+				obj("v1", "Namespace", "ns"),
+				obj("foo.io/v1", "Foo", "foo"),
+			},
+			want: []string{"Namespace", "Secret", "KubeadmConfigTemplate", "DockerMachineTemplate", "Cluster", "DockerCluster", "KubeadmControlPlane", "MachineDeployment", "MachineHealthCheck", "Foo"},
+		},
+		{
+			name: "objects with the same rank keep the input order",
+			objs: []unstructured.Unstructured{
+				obj("v1", "ConfigMap", "b"),
+				obj("foo.io/v1", "Foo", "c"),
+				obj("v1", "ConfigMap", "a"),
+			},
+			want: []string{"ConfigMap", "ConfigMap", "Foo"},
+		},
+		{
+			name: "owners go before owned objects",
+			objs: []unstructured.Unstructured{
+				obj("v1", "Secret", "s", ownedBy("cluster.x-k8s.io/v1alpha4", "Cluster", "c")),
+				obj("cluster.x-k8s.io/v1alpha4", "Cluster", "c"),
+				obj("v1", "ConfigMap", "cm"),
+			},
+			want: []string{"ConfigMap", "Cluster", "Secret"},
+		},
+		{
+			name: "owner references cycles are reported",
+			objs: []unstructured.Unstructured{
+				obj("v1", "Secret", "s", ownedBy("v1", "ConfigMap", "cm")),
+				obj("v1", "ConfigMap", "cm", ownedBy("v1", "Secret", "s")),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := SortObjs(tt.objs)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			kinds := []string{}
+			for _, o := range got {
+				kinds = append(kinds, o.GetKind())
+			}
+			g.Expect(kinds).To(Equal(tt.want))
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
 
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
@@ -116,3 +117,105 @@ func Test_newTemplate(t *testing.T) {
 		})
 	}
 }
+
+func Test_MergeTemplates(t *testing.T) {
+	newTemplate := func(g *WithT, targetNamespace string, rawYaml string) Template {
+		template, err := NewTemplate(TemplateInput{
+			RawArtifact:           []byte(rawYaml),
+			ConfigVariablesClient: test.NewFakeVariableClient().WithVar("CLUSTER_NAME", "foo").WithVar("ADDON", "bar"),
+			Processor:             yaml.NewSimpleProcessor(),
+			TargetNamespace:       targetNamespace,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		return template
+	}
+
+	baseYaml := "apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"metadata:\n" +
+		"  name: ${CLUSTER_NAME}\n" +
+		"data:\n" +
+		"  size: ${SIZE:=small}\n"
+	addonYaml := "apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"metadata:\n" +
+		"  name: ${CLUSTER_NAME}\n" +
+		"data:\n" +
+		"  size: ${SIZE:=small}\n" +
+		"---\n" +
+		"apiVersion: v1\n" +
+		"kind: Secret\n" +
+		"metadata:\n" +
+		"  name: ${ADDON}\n" +
+		"stringData:\n" +
+		"  size: ${SIZE}\n"
+	conflictYaml := "apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"metadata:\n" +
+		"  name: ${CLUSTER_NAME}\n" +
+		"data:\n" +
+		"  size: large\n"
+
+	t.Run("merges templates de-duplicating identical objects", func(t *testing.T) {
+		g := NewWithT(t)
+
+		got, err := MergeTemplates(newTemplate(g, "ns1", baseYaml), newTemplate(g, "ns1", addonYaml))
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Expect(got.TargetNamespace()).To(Equal("ns1"))
+		g.Expect(got.Variables()).To(Equal([]string{"ADDON", "CLUSTER_NAME", "SIZE"}))
+		g.Expect(got.VariableMap()).To(HaveKeyWithValue("SIZE", pointer.StringPtr("small")))
+
+		g.Expect(got.Objs()).To(HaveLen(2))
+		g.Expect(got.Objs()[0].GetKind()).To(Equal("ConfigMap"))
+		g.Expect(got.Objs()[1].GetKind()).To(Equal("Secret"))
+	})
+	t.Run("fails for objects defined with different content", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := MergeTemplates(newTemplate(g, "ns1", baseYaml), newTemplate(g, "ns1", addonYaml), newTemplate(g, "ns1", conflictYaml))
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("/v1, Kind=ConfigMap, ns1/foo is defined with different content in template 0 and template 2"))
+	})
+	t.Run("fails for templates with different target namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := MergeTemplates(newTemplate(g, "ns1", baseYaml), newTemplate(g, "ns2", addonYaml))
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func Test_template_Objs(t *testing.T) {
+	g := NewWithT(t)
+
+	rawYaml := []byte("apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"metadata:\n" +
+		"  name: b\n" +
+		"---\n" +
+		"apiVersion: v1\n" +
+		"kind: Secret\n" +
+		"metadata:\n" +
+		"  name: a\n")
+
+	template, err := NewTemplate(TemplateInput{
+		RawArtifact:           rawYaml,
+		ConfigVariablesClient: test.NewFakeVariableClient(),
+		Processor:             yaml.NewSimpleProcessor(),
+		TargetNamespace:       "ns1",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Objects are returned in the same order they are defined in the YAML.
+	objs := template.Objs()
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetName()).To(Equal("b"))
+	g.Expect(objs[1].GetName()).To(Equal("a"))
+
+	// Changes to the objects are reflected in the YAML.
+	objs[1].SetLabels(map[string]string{"foo": "bar"})
+
+	yml, err := template.Yaml()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(yml)).To(MatchRegexp(`(?s)kind: ConfigMap.*name: b.*---.*kind: Secret.*foo: bar`))
+}
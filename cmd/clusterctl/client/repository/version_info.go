@@ -0,0 +1,220 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
+)
+
+// errFileListNotSupported is returned when a repository does not support listing the files in a release.
+var errFileListNotSupported = errors.New("listing the files of a release is not supported")
+
+// fileLister is implemented by repositories able to list the files in a release without downloading them, e.g.
+// using the names of the GitHub release assets.
+type fileLister interface {
+	// ListFiles returns the path of the files in a release for a given provider version, relative to the root path.
+	ListFiles(version string) ([]string, error)
+}
+
+// VersionInfo defines the information about a provider version that are available without downloading
+// the provider components.
+type VersionInfo struct {
+	// Version is the provider version the information refer to.
+	Version string `json:"version"`
+
+	// Contract is the Cluster API contract declared in the provider's metadata for this version, e.g. v1alpha4.
+	Contract string `json:"contract"`
+
+	// DefaultTemplate is true if the version publishes the cluster template used when no flavor is requested.
+	DefaultTemplate bool `json:"defaultTemplate"`
+
+	// Flavors lists the cluster template flavors published by the version, sorted by name.
+	Flavors []string `json:"flavors"`
+}
+
+// getVersionInfo returns the contract and the cluster template flavors of a provider version, reading the metadata
+// and the list of files in the release.
+func getVersionInfo(c *repositoryClient, v string) (*VersionInfo, error) {
+	if v == "" {
+		v = c.repository.DefaultVersion()
+	}
+	resolvedVersion, err := resolveVersion(c.repository, v, c.includePrereleases)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the version for provider %q", c.ManifestLabel())
+	}
+
+	metadata, err := c.Metadata(resolvedVersion).Get()
+	if err != nil {
+		return nil, err
+	}
+	semVersion, err := version.ParseSemantic(resolvedVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse version %q", resolvedVersion)
+	}
+	releaseSeries := metadata.GetReleaseSeriesForVersion(semVersion)
+	if releaseSeries == nil {
+		return nil, errors.Errorf("version %s is not defined in %q for provider %q, release series v%d.%d is missing", resolvedVersion, metadataFile, c.ManifestLabel(), semVersion.Major(), semVersion.Minor())
+	}
+
+	lister, ok := c.repository.(fileLister)
+	if !ok {
+		return nil, errors.Wrapf(errFileListNotSupported, "failed to get the cluster template flavors for provider %q", c.ManifestLabel())
+	}
+	files, err := lister.ListFiles(resolvedVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the files of version %s for provider %q", resolvedVersion, c.ManifestLabel())
+	}
+
+	hasDefault, flavors := getTemplateFlavors(c.processor, resolvedVersion, files)
+	return &VersionInfo{
+		Version:         resolvedVersion,
+		Contract:        releaseSeries.Contract,
+		DefaultTemplate: hasDefault,
+		Flavors:         flavors,
+	}, nil
+}
+
+// ProviderVersion defines a version published by a provider, together with the Cluster API contract it supports.
+type ProviderVersion struct {
+	// Version is the provider version, e.g. v0.4.0.
+	Version string `json:"version"`
+
+	// Contract is the Cluster API contract declared in the provider's metadata for this version, e.g. v1alpha4;
+	// it is empty if the provider's metadata does not define the release series of the version.
+	Contract string `json:"contract"`
+}
+
+// getPublishedVersions returns the versions published by a provider, sorted from the oldest to the newest, with the
+// contract of each version; prerelease versions are included only if c.includePrereleases is set.
+func getPublishedVersions(c *repositoryClient) ([]ProviderVersion, error) {
+	log := logf.Log
+
+	tags, err := c.repository.GetVersions()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the versions for provider %q", c.ManifestLabel())
+	}
+
+	type versionTag struct {
+		tag     string
+		version *version.Version
+	}
+	versions := []versionTag{}
+	skippedPrereleases := 0
+	for _, tag := range tags {
+		sv, err := version.ParseSemantic(tag)
+		if err != nil {
+			continue
+		}
+		if sv.PreRelease() != "" && !c.includePrereleases {
+			skippedPrereleases++
+			continue
+		}
+		versions = append(versions, versionTag{tag: tag, version: sv})
+	}
+	if len(versions) == 0 {
+		if skippedPrereleases > 0 {
+			return nil, errors.Errorf("provider %q has no published releases, excluding %d prerelease versions", c.ManifestLabel(), skippedPrereleases)
+		}
+		return nil, errors.Errorf("provider %q has no published releases", c.ManifestLabel())
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].version.LessThan(versions[j].version)
+	})
+
+	// NB. The metadata of a version usually defines the release series of all the previous versions, so the metadata of
+	// the newest version is read first, and the metadata of older versions is read only for the missing release series.
+	metadataByTag := map[string]*clusterctlv1.Metadata{}
+	getMetadata := func(tag string) *clusterctlv1.Metadata {
+		if m, ok := metadataByTag[tag]; ok {
+			return m
+		}
+		m, err := c.Metadata(tag).Get()
+		if err != nil {
+			log.V(5).Info("Failed to read the provider metadata", "Provider", c.ManifestLabel(), "Version", tag, "Reason", err.Error())
+			metadataByTag[tag] = nil
+			return nil
+		}
+		metadataByTag[tag] = m
+		return m
+	}
+
+	newest := versions[len(versions)-1].tag
+	ret := make([]ProviderVersion, 0, len(versions))
+	for _, v := range versions {
+		published := ProviderVersion{Version: v.tag}
+		for _, tag := range []string{newest, v.tag} {
+			if m := getMetadata(tag); m != nil {
+				if releaseSeries := m.GetReleaseSeriesForVersion(v.version); releaseSeries != nil {
+					published.Contract = releaseSeries.Contract
+					break
+				}
+			}
+		}
+		ret = append(ret, published)
+	}
+	return ret, nil
+}
+
+// getTemplateFlavors returns the cluster template flavors matching a list of files; template names are derived
+// from the name of the default template, e.g. cluster-template.yaml for the SimpleProcessor, that is expanded
+// into cluster-template-{flavor}.yaml for the other flavors.
+func getTemplateFlavors(processor yaml.Processor, version string, files []string) (bool, []string) {
+	defaultName := processor.GetTemplateName(version, "")
+	ext := filepath.Ext(defaultName)
+	prefix := strings.TrimSuffix(defaultName, ext) + "-"
+
+	hasDefault := false
+	flavors := []string{}
+	for _, f := range files {
+		if f == defaultName {
+			hasDefault = true
+			continue
+		}
+		if !strings.HasPrefix(f, prefix) || !strings.HasSuffix(f, ext) {
+			continue
+		}
+		flavor := strings.TrimSuffix(strings.TrimPrefix(f, prefix), ext)
+		// NB. checking the full name protects against processors not following the naming convention above.
+		if flavor == "" || processor.GetTemplateName(version, flavor) != f {
+			continue
+		}
+		flavors = append(flavors, flavor)
+	}
+	sort.Strings(flavors)
+	return hasDefault, flavors
+}
+
+// relativeFileNames returns the names of the files under a root path, relative to the root path.
+func relativeFileNames(rootPath string, names []string) []string {
+	files := []string{}
+	for _, name := range names {
+		rel, err := filepath.Rel(filepath.Join("/", rootPath), filepath.Join("/", name))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		files = append(files, rel)
+	}
+	return files
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_repositoryClient_GetVersionInfo(t *testing.T) {
+	metadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 1, Minor: 0, Contract: "v1alpha3"},
+			{Major: 1, Minor: 1, Contract: "v1alpha4"},
+		},
+	}
+
+	repository := test.NewFakeRepository().
+		WithDefaultVersion("v1.1.0").
+		WithMetadata("v1.0.0", metadata).
+		WithFile("v1.0.0", "components.yaml", []byte("components")).
+		WithFile("v1.0.0", "cluster-template-foo.yaml", []byte("template")).
+		WithMetadata("v1.1.0", metadata).
+		WithFile("v1.1.0", "components.yaml", []byte("components")).
+		WithFile("v1.1.0", "cluster-template.yaml", []byte("template")).
+		WithFile("v1.1.0", "cluster-template.yaml.sha256", []byte("checksum")).
+		WithFile("v1.1.0", "cluster-template-foo.yaml", []byte("template")).
+		WithFile("v1.1.0", "cluster-template-bar.yaml", []byte("template")).
+		WithFile("v1.1.0", "cluster-template-.yaml", []byte("template")).
+		WithMetadata("v2.0.0", metadata)
+
+	tests := []struct {
+		name    string
+		version string
+		want    *VersionInfo
+		wantErr bool
+	}{
+		{
+			name:    "returns the contract and the flavors of a version",
+			version: "v1.0.0",
+			want: &VersionInfo{
+				Version:         "v1.0.0",
+				Contract:        "v1alpha3",
+				DefaultTemplate: false,
+				Flavors:         []string{"foo"},
+			},
+		},
+		{
+			name:    "returns the info of the default version if no version is requested",
+			version: "",
+			want: &VersionInfo{
+				Version:         "v1.1.0",
+				Contract:        "v1alpha4",
+				DefaultTemplate: true,
+				Flavors:         []string{"bar", "foo"},
+			},
+		},
+		{
+			name:    "fails if the version is not defined in the metadata",
+			version: "v2.0.0",
+			wantErr: true,
+		},
+		{
+			name:    "fails if the version does not exist",
+			version: "v3.0.0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			repoClient, err := newRepositoryClient(
+				config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+				configClient,
+				InjectRepository(repository),
+			)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := repoClient.GetVersionInfo(tt.version)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_repositoryClient_GetPublishedVersions(t *testing.T) {
+	metadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 1, Minor: 0, Contract: "v1alpha3"},
+			{Major: 1, Minor: 1, Contract: "v1alpha4"},
+		},
+	}
+	// NB. the release series of the oldest version is defined only in its own metadata.
+	oldMetadata := &clusterctlv1.Metadata{
+		ReleaseSeries: []clusterctlv1.ReleaseSeries{
+			{Major: 0, Minor: 9, Contract: "v1alpha2"},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		repository         *test.FakeRepository
+		includePrereleases bool
+		want               []ProviderVersion
+		wantErr            bool
+	}{
+		{
+			name: "returns the versions sorted, with their contract",
+			repository: test.NewFakeRepository().
+				WithDefaultVersion("v1.1.0").
+				WithMetadata("v1.1.0", metadata).
+				WithMetadata("v1.1.0-rc.0", metadata).
+				WithMetadata("v1.0.0", metadata).
+				WithMetadata("v0.9.0", oldMetadata).
+				WithVersions("not-a-version"),
+			want: []ProviderVersion{
+				{Version: "v0.9.0", Contract: "v1alpha2"},
+				{Version: "v1.0.0", Contract: "v1alpha3"},
+				{Version: "v1.1.0", Contract: "v1alpha4"},
+			},
+		},
+		{
+			name: "returns prerelease versions if prereleases are included",
+			repository: test.NewFakeRepository().
+				WithDefaultVersion("v1.1.0").
+				WithMetadata("v1.1.0", metadata).
+				WithMetadata("v1.1.0-rc.0", metadata).
+				WithMetadata("v1.0.0", metadata),
+			includePrereleases: true,
+			want: []ProviderVersion{
+				{Version: "v1.0.0", Contract: "v1alpha3"},
+				{Version: "v1.1.0-rc.0", Contract: "v1alpha4"},
+				{Version: "v1.1.0", Contract: "v1alpha4"},
+			},
+		},
+		{
+			name: "returns an empty contract if the release series is not defined",
+			repository: test.NewFakeRepository().
+				WithDefaultVersion("v1.0.0").
+				WithMetadata("v1.0.0", metadata).
+				WithVersions("v0.8.0"),
+			want: []ProviderVersion{
+				{Version: "v0.8.0", Contract: ""},
+				{Version: "v1.0.0", Contract: "v1alpha3"},
+			},
+		},
+		{
+			name: "fails if there are no published releases",
+			repository: test.NewFakeRepository().
+				WithDefaultVersion("v1.1.0-rc.0").
+				WithMetadata("v1.1.0-rc.0", metadata),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			configClient, err := config.New("", config.InjectReader(test.NewFakeReader()))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			repoClient, err := newRepositoryClient(
+				config.NewProvider("infra", "", clusterctlv1.InfrastructureProviderType),
+				configClient,
+				InjectRepository(tt.repository),
+				InjectIncludePrereleases(tt.includePrereleases),
+			)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := repoClient.GetPublishedVersions()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_relativeFileNames(t *testing.T) {
+	names := []string{"components.yaml", "foo/cluster-template.yaml", "foo/bar/cluster-template-bar.yaml", "foobar/metadata.yaml"}
+
+	tests := []struct {
+		name     string
+		rootPath string
+		want     []string
+	}{
+		{
+			name:     "returns all the files when the root path is empty",
+			rootPath: "",
+			want:     names,
+		},
+		{
+			name:     "returns all the files when the root path is the current folder",
+			rootPath: ".",
+			want:     names,
+		},
+		{
+			name:     "returns only the files under the root path",
+			rootPath: "foo",
+			want:     []string{"cluster-template.yaml", "bar/cluster-template-bar.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(relativeFileNames(tt.rootPath, names)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+// versionRangeOperators are the characters identifying a version range, e.g. ">=1.2.0 <2.0.0", as opposed to
+// an exact version or to the latest meta version.
+const versionRangeOperators = "<>=!"
+
+// versionRangePrefixRegexp matches the v prefix of the versions in a version range, e.g. the v in ">=v1.2.0".
+var versionRangePrefixRegexp = regexp.MustCompile(`(^|[\s<>=!|]+)v(\d)`)
+
+// isVersionRange returns true if a version is a semantic version range, e.g. ">=1.2.0 <2.0.0".
+func isVersionRange(v string) bool {
+	return strings.ContainsAny(v, versionRangeOperators)
+}
+
+// IsPrereleaseIncluded returns true if the repository-include-prereleases variable requires prereleases to be
+// considered when resolving the latest version or a version range.
+func IsPrereleaseIncluded(configVariablesClient config.VariablesClient) (bool, error) {
+	v, err := configVariablesClient.Get(config.RepositoryIncludePrereleasesVariable)
+	if err != nil {
+		return false, nil // nolint:nilerr
+	}
+	included, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Errorf("invalid value %q for the %s variable: it must be a boolean", v, config.RepositoryIncludePrereleasesVariable)
+	}
+	return included, nil
+}
+
+// latestVersion returns the latest version according to semantic version ordering, optionally restricted to a given
+// Major and Minor version; versions that are not valid semantic versions are ignored.
+// Prereleases are considered only if includePrereleases is true, or if there are no releases.
+func latestVersion(versions []string, major, minor *uint, includePrereleases bool) (string, error) {
+	var latestTag string
+	var latestPrereleaseTag string
+
+	var latestReleaseVersion *version.Version
+	var latestPrereleaseVersion *version.Version
+
+	for _, v := range versions {
+		sv, err := version.ParseSemantic(v)
+		if err != nil {
+			// discard releases with tags that are not a valid semantic versions (the user can point explicitly to such releases)
+			continue
+		}
+
+		if (major != nil && sv.Major() != *major) || (minor != nil && sv.Minor() != *minor) {
+			// skip versions that don't match the desired Major.Minor version.
+			continue
+		}
+
+		// track prereleases separately, unless they are included
+		if sv.PreRelease() != "" && !includePrereleases {
+			if latestPrereleaseVersion == nil || latestPrereleaseVersion.LessThan(sv) {
+				latestPrereleaseTag = v
+				latestPrereleaseVersion = sv
+			}
+			continue
+		}
+
+		if latestReleaseVersion == nil || latestReleaseVersion.LessThan(sv) {
+			latestTag = v
+			latestReleaseVersion = sv
+		}
+	}
+
+	// Fall back to returning latest prereleases if no release has been cut or bail if it's also empty
+	if latestTag == "" {
+		if latestPrereleaseTag == "" {
+			return "", errors.New("failed to find releases tagged with a valid semantic version number")
+		}
+
+		return latestPrereleaseTag, nil
+	}
+	return latestTag, nil
+}
+
+// resolveVersion returns the highest release of a repository satisfying a version range, e.g. ">=1.2.0 <2.0.0"
+// or ">=1.2.0 <1.3.0 || >=1.4.0"; versions that are not ranges are returned as they are.
+// Prereleases are considered only if includePrereleases is true.
+func resolveVersion(repository Repository, v string, includePrereleases bool) (string, error) {
+	if !isVersionRange(v) {
+		return v, nil
+	}
+
+	// NB. semver ranges do not allow the v prefix, that is instead commonly used in release tags.
+	versionRange, err := semver.ParseRange(versionRangePrefixRegexp.ReplaceAllString(strings.TrimSpace(v), "${1}${2}"))
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid version range %q", v)
+	}
+
+	versions, err := repository.GetVersions()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the versions for resolving the version range %q", v)
+	}
+
+	var latestTag string
+	var latestVersion *version.Version
+	for _, tag := range versions {
+		sv, err := version.ParseSemantic(tag)
+		if err != nil || (sv.PreRelease() != "" && !includePrereleases) {
+			continue
+		}
+		rangeVersion, err := semver.ParseTolerant(tag)
+		if err != nil || !versionRange(rangeVersion) {
+			continue
+		}
+		if latestVersion == nil || latestVersion.LessThan(sv) {
+			latestTag = tag
+			latestVersion = sv
+		}
+	}
+
+	if latestTag == "" {
+		return "", errors.Errorf("no release satisfies the version range %q, available versions are %q", v, versions)
+	}
+	return latestTag, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+)
+
+func Test_resolveVersion(t *testing.T) {
+	repository := test.NewFakeRepository().
+		WithVersions("v1.1.0", "v1.2.0", "v1.2.3", "v1.3.0-rc.0", "v2.0.0", "foo")
+
+	tests := []struct {
+		name               string
+		version            string
+		includePrereleases bool
+		want               string
+		wantErr            bool
+	}{
+		{
+			name:    "exact versions are not resolved",
+			version: "v1.2.0",
+			want:    "v1.2.0",
+		},
+		{
+			name:    "latest is not resolved",
+			version: "latest",
+			want:    "latest",
+		},
+		{
+			name:    "range picks the highest matching release, skipping prereleases",
+			version: ">=1.2.0 <2.0.0",
+			want:    "v1.2.3",
+		},
+		{
+			name:    "range with the v prefix",
+			version: ">=v1.1.0 <v1.2.0",
+			want:    "v1.1.0",
+		},
+		{
+			name:    "range with alternatives",
+			version: "<1.2.0 || >=2.0.0",
+			want:    "v2.0.0",
+		},
+		{
+			name:    "fails if no release satisfies the range",
+			version: ">=3.0.0",
+			wantErr: true,
+		},
+		{
+			name:    "fails if only prereleases satisfy the range",
+			version: ">1.2.3 <2.0.0",
+			wantErr: true,
+		},
+		{
+			name:               "range picks prereleases if included",
+			version:            ">1.2.3 <2.0.0",
+			includePrereleases: true,
+			want:               "v1.3.0-rc.0",
+		},
+		{
+			name:    "fails with an invalid range",
+			version: ">=foo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := resolveVersion(repository, tt.version, tt.includePrereleases)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_latestVersion(t *testing.T) {
+	versions := []string{"v1.1.0", "v1.2.0-rc.0", "v1.1.1", "v2.0.0-beta.0", "v2.0.0-beta.1", "foo"}
+	one := uint(1)
+	two := uint(2)
+
+	tests := []struct {
+		name               string
+		major              *uint
+		minor              *uint
+		includePrereleases bool
+		want               string
+		wantErr            bool
+	}{
+		{
+			name: "prereleases are skipped by default",
+			want: "v1.1.1",
+		},
+		{
+			name:               "prereleases are ordered according to semantic versioning if included",
+			includePrereleases: true,
+			want:               "v2.0.0-beta.1",
+		},
+		{
+			name:               "prereleases are included for the given Major and Minor version",
+			major:              &one,
+			minor:              &two,
+			includePrereleases: true,
+			want:               "v1.2.0-rc.0",
+		},
+		{
+			name:  "fall back to prereleases if there are no releases",
+			major: &two,
+			want:  "v2.0.0-beta.1",
+		},
+		{
+			name:    "fails if there are no versions",
+			major:   &two,
+			minor:   &two,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := latestVersion(versions, tt.major, tt.minor, tt.includePrereleases)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/scheme"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+// ValidateClusterTemplateOptions carries the options supported by ValidateClusterTemplate.
+type ValidateClusterTemplateOptions struct {
+	// Template to be validated, e.g. a template returned by GetClusterTemplate or by ProcessYAML.
+	Template Template
+
+	// CustomResourceDefinitions is the YAML defining the CRDs used for validating the template, e.g. CRDs bundled
+	// with a CI job.
+	CustomResourceDefinitions []byte
+
+	// CoreProvider, BootstrapProviders, ControlPlaneProviders and InfrastructureProviders define the providers whose
+	// CRDs are used for validating the template, in the name[:version] format; the CRDs are read from the provider
+	// repositories, so a local repository or the overrides layer should be used for working offline.
+	CoreProvider            string
+	BootstrapProviders      []string
+	ControlPlaneProviders   []string
+	InfrastructureProviders []string
+}
+
+// TemplateValidationError reports the invalid fields of an object in a cluster template.
+type TemplateValidationError struct {
+	// Object is the invalid object.
+	Object corev1.ObjectReference
+
+	// Errors lists the invalid fields, with their path.
+	Errors field.ErrorList
+}
+
+// Error returns the invalid fields of the object.
+func (e TemplateValidationError) Error() string {
+	return fmt.Sprintf("%s %s/%s is invalid: %s", e.Object.Kind, e.Object.Namespace, e.Object.Name, e.Errors.ToAggregate().Error())
+}
+
+func (c *clusterctlClient) ValidateClusterTemplate(options ValidateClusterTemplateOptions) ([]TemplateValidationError, error) {
+	if options.Template == nil {
+		return nil, errors.New("please specify the template to be validated")
+	}
+
+	crds, err := c.getTemplateValidationCRDs(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(crds) == 0 {
+		return nil, errors.New("please specify the CRDs or the providers used for validating the template")
+	}
+
+	validator := newTemplateValidator(crds)
+	validationErrors := []TemplateValidationError{}
+	for _, obj := range options.Template.Objs() {
+		errs, err := validator.validate(obj)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) == 0 {
+			continue
+		}
+		validationErrors = append(validationErrors, TemplateValidationError{
+			Object: corev1.ObjectReference{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+			},
+			Errors: errs,
+		})
+	}
+	return validationErrors, nil
+}
+
+// getTemplateValidationCRDs returns the CRDs defined in the options and the CRDs of the providers in the options.
+func (c *clusterctlClient) getTemplateValidationCRDs(options ValidateClusterTemplateOptions) ([]apiextensionsv1.CustomResourceDefinition, error) {
+	objs := []unstructured.Unstructured{}
+	if len(options.CustomResourceDefinitions) > 0 {
+		crdObjs, err := utilyaml.ToUnstructured(options.CustomResourceDefinitions)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the CRDs used for validating the template")
+		}
+		objs = append(objs, crdObjs...)
+	}
+
+	providers := map[clusterctlv1.ProviderType][]string{
+		clusterctlv1.BootstrapProviderType:      options.BootstrapProviders,
+		clusterctlv1.ControlPlaneProviderType:   options.ControlPlaneProviders,
+		clusterctlv1.InfrastructureProviderType: options.InfrastructureProviders,
+	}
+	if options.CoreProvider != "" {
+		providers[clusterctlv1.CoreProviderType] = []string{options.CoreProvider}
+	}
+	for providerType, names := range providers {
+		for _, provider := range names {
+			name, version, err := parseProviderName(provider)
+			if err != nil {
+				return nil, err
+			}
+			// NB. Variables are not replaced, because the CRDs are not expected to use them and because the values of the
+			// variables are not required for validating a template.
+			components, err := c.GetProviderComponents(name, providerType, ComponentsOptions{
+				Version:             version,
+				TargetNamespace:     "validation",
+				SkipTemplateProcess: true,
+				Categories:          []repository.ComponentsCategory{repository.CRDsComponentsCategory},
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get the CRDs of provider %q", provider)
+			}
+			objs = append(objs, components.Objs()...)
+		}
+	}
+
+	crds := []apiextensionsv1.CustomResourceDefinition{}
+	for i := range objs {
+		if objs[i].GroupVersionKind() != apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition") {
+			continue
+		}
+		crd := apiextensionsv1.CustomResourceDefinition{}
+		if err := scheme.Scheme.Convert(&objs[i], &crd, nil); err != nil {
+			return nil, errors.Wrapf(err, "failed to convert CRD %q", objs[i].GetName())
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// templateValidator validates objects against the OpenAPI schemas of a set of CRDs.
+type templateValidator struct {
+	// versions maps each group/version/kind to the corresponding CRD version.
+	versions map[schema.GroupVersionKind]*apiextensionsv1.CustomResourceDefinitionVersion
+
+	// groups lists the groups defined by the CRDs.
+	groups map[string]bool
+}
+
+func newTemplateValidator(crds []apiextensionsv1.CustomResourceDefinition) *templateValidator {
+	v := &templateValidator{
+		versions: map[schema.GroupVersionKind]*apiextensionsv1.CustomResourceDefinitionVersion{},
+		groups:   map[string]bool{},
+	}
+	for i := range crds {
+		crd := &crds[i]
+		v.groups[crd.Spec.Group] = true
+		for j := range crd.Spec.Versions {
+			version := &crd.Spec.Versions[j]
+			v.versions[schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}] = version
+		}
+	}
+	return v
+}
+
+// validate returns the invalid fields of an object, including the fields not defined in the schema.
+// Objects in groups not defined by the CRDs, e.g. Secrets or ConfigMaps, are not validated.
+func (v *templateValidator) validate(obj unstructured.Unstructured) (field.ErrorList, error) {
+	gvk := obj.GroupVersionKind()
+	if !v.groups[gvk.Group] {
+		return nil, nil
+	}
+
+	version, ok := v.versions[gvk]
+	if !ok {
+		return field.ErrorList{field.Invalid(field.NewPath("apiVersion"), obj.GetAPIVersion(), fmt.Sprintf("kind %s is not defined in this version", gvk.Kind))}, nil
+	}
+	if !version.Served {
+		return field.ErrorList{field.Invalid(field.NewPath("apiVersion"), obj.GetAPIVersion(), "version is not served")}, nil
+	}
+	if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+		return nil, nil
+	}
+
+	internalSchema := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(version.Schema.OpenAPIV3Schema, internalSchema, nil); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert the schema for %s", gvk)
+	}
+	schemaValidator, _, err := apiservervalidation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internalSchema})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the schema validator for %s", gvk)
+	}
+	errs := apiservervalidation.ValidateCustomResource(nil, obj.UnstructuredContent(), schemaValidator)
+
+	// Detects the fields not defined in the schema, that are silently dropped by the API server.
+	for k, value := range obj.UnstructuredContent() {
+		// NB. apiVersion, kind and metadata are defined by the API server for all the resources.
+		if k == "apiVersion" || k == "kind" || k == "metadata" {
+			continue
+		}
+		errs = append(errs, unknownFields(field.NewPath(k), value, propertySchema(version.Schema.OpenAPIV3Schema, k))...)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs, nil
+}
+
+// propertySchema returns the schema of a property of an object, or nil if the property is not defined.
+func propertySchema(s *apiextensionsv1.JSONSchemaProps, name string) *apiextensionsv1.JSONSchemaProps {
+	if p, ok := s.Properties[name]; ok {
+		return &p
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		return s.AdditionalProperties.Schema
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Allows {
+		return &apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: &s.AdditionalProperties.Allows}
+	}
+	return nil
+}
+
+// unknownFields returns the fields of a value not defined in its schema.
+func unknownFields(path *field.Path, value interface{}, s *apiextensionsv1.JSONSchemaProps) field.ErrorList {
+	if s == nil {
+		return field.ErrorList{field.Forbidden(path, "unknown field")}
+	}
+	if s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			// NB. apiVersion, kind and metadata are always allowed in embedded resources.
+			if s.XEmbeddedResource && (k == "apiVersion" || k == "kind" || k == "metadata") {
+				continue
+			}
+			errs = append(errs, unknownFields(path.Child(k), child, propertySchema(s, k))...)
+		}
+	case []interface{}:
+		if s.Items == nil || s.Items.Schema == nil {
+			return errs
+		}
+		for i := range v {
+			errs = append(errs, unknownFields(path.Index(i), v[i], s.Items.Schema)...)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+)
+
+func Test_clusterctlClient_ValidateClusterTemplate(t *testing.T) {
+	crds := []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: infraclusters.infrastructure.cluster.x-k8s.io
+spec:
+  group: infrastructure.cluster.x-k8s.io
+  names:
+    kind: InfraCluster
+  versions:
+  - name: v1alpha4
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          apiVersion:
+            type: string
+          kind:
+            type: string
+          metadata:
+            type: object
+          spec:
+            type: object
+            required:
+            - region
+            properties:
+              region:
+                type: string
+              replicas:
+                type: integer
+                minimum: 1
+              tags:
+                type: object
+                additionalProperties:
+                  type: string
+`)
+
+	tests := []struct {
+		name       string
+		template   string
+		wantFields []string
+	}{
+		{
+			name: "valid objects",
+			template: `apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+kind: InfraCluster
+metadata:
+  name: cluster1
+spec:
+  region: us-east-1
+  tags:
+    owner: team1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret1
+`,
+			wantFields: []string{},
+		},
+		{
+			name: "invalid values, missing fields and unknown fields are reported with their path",
+			template: `apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+kind: InfraCluster
+metadata:
+  name: cluster1
+spec:
+  replicas: 0
+  regoin: us-east-1
+`,
+			wantFields: []string{"spec.region", "spec.regoin", "spec.replicas"},
+		},
+		{
+			name: "versions not defined by the CRDs are reported",
+			template: `apiVersion: infrastructure.cluster.x-k8s.io/v1alpha3
+kind: InfraCluster
+metadata:
+  name: cluster1
+`,
+			wantFields: []string{"apiVersion"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig()
+			client := newFakeClient(config1)
+
+			template, err := repository.NewTemplate(repository.TemplateInput{
+				RawArtifact:           []byte(tt.template),
+				ConfigVariablesClient: config1.Variables(),
+				Processor:             yaml.NewSimpleProcessor(),
+				TargetNamespace:       "ns1",
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			got, err := client.ValidateClusterTemplate(ValidateClusterTemplateOptions{
+				Template:                  template,
+				CustomResourceDefinitions: crds,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			gotFields := []string{}
+			for _, e := range got {
+				g.Expect(e.Object.Kind).To(Equal("InfraCluster"))
+				g.Expect(e.Object.Name).To(Equal("cluster1"))
+				for _, fieldErr := range e.Errors {
+					gotFields = append(gotFields, fieldErr.Field)
+				}
+			}
+			g.Expect(gotFields).To(Equal(tt.wantFields))
+		})
+	}
+
+	t.Run("fails if no CRDs are specified", func(t *testing.T) {
+		g := NewWithT(t)
+
+		config1 := newFakeConfig()
+		template, err := repository.NewTemplate(repository.TemplateInput{
+			RawArtifact:           []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret1\n"),
+			ConfigVariablesClient: config1.Variables(),
+			Processor:             yaml.NewSimpleProcessor(),
+			TargetNamespace:       "ns1",
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		_, err = newFakeClient(config1).ValidateClusterTemplate(ValidateClusterTemplateOptions{Template: template})
+		g.Expect(err).To(HaveOccurred())
+	})
+}
@@ -17,37 +17,62 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterv1old "sigs.k8s.io/cluster-api/api/v1alpha3"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
+	logf "sigs.k8s.io/cluster-api/cmd/clusterctl/log"
 )
 
 // PlanUpgradeOptions carries the options supported by upgrade plan.
 type PlanUpgradeOptions struct {
 	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty, default discovery rules apply.
 	Kubeconfig Kubeconfig
+
+	// CertManagerVersion pins the cert-manager version to upgrade to, overriding the version defined in the clusterctl
+	// configuration or the default version embedded in clusterctl. This is used only by PlanCertManagerUpgrade.
+	CertManagerVersion string
+
+	// OnlyUpgradable, if true, omits from the upgrade plans the providers already at the latest version available
+	// for the plan's contract; upgrade plans without any provider left are omitted as well. This is used only by PlanUpgrade.
+	OnlyUpgradable bool
+
+	// IncludePrereleases makes prerelease versions, e.g. release candidates, eligible as upgrade targets. This is used only by PlanUpgrade.
+	IncludePrereleases bool
+
+	// IncludeChangelog, if true, adds to each upgrade item the release notes of the versions between the current version
+	// and the next version; providers not publishing release notes get an empty changelog. This is used only by PlanUpgrade.
+	IncludeChangelog bool
 }
 
 func (c *clusterctlClient) PlanCertManagerUpgrade(options PlanUpgradeOptions) (CertManagerUpgradePlan, error) {
 	// Get the client for interacting with the management cluster.
-	cluster, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
 	if err != nil {
 		return CertManagerUpgradePlan{}, err
 	}
 
-	certManager := cluster.CertManager()
+	certManager := clusterClient.CertManager(cluster.WithCertManagerVersion(options.CertManagerVersion))
 	plan, err := certManager.PlanUpgrade()
 	return CertManagerUpgradePlan(plan), err
 }
 
 func (c *clusterctlClient) PlanUpgrade(options PlanUpgradeOptions) ([]UpgradePlan, error) {
+	return c.PlanUpgradeContext(context.Background(), options)
+}
+
+func (c *clusterctlClient) PlanUpgradeContext(ctx context.Context, options PlanUpgradeOptions) ([]UpgradePlan, error) {
+	c = c.withContext(ctx)
+
 	// Get the client for interacting with the management cluster.
-	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig, IncludePrereleases: options.IncludePrereleases})
 	if err != nil {
 		return nil, err
 	}
@@ -68,17 +93,101 @@ func (c *clusterctlClient) PlanUpgrade(options PlanUpgradeOptions) ([]UpgradePla
 	}
 
 	// UpgradePlan is an alias for cluster.UpgradePlan; this makes the conversion
-	aliasUpgradePlan := make([]UpgradePlan, len(upgradePlans))
-	for i, plan := range upgradePlans {
-		aliasUpgradePlan[i] = UpgradePlan{
-			Contract:  plan.Contract,
-			Providers: plan.Providers,
+	aliasUpgradePlan := make([]UpgradePlan, 0, len(upgradePlans))
+	for _, plan := range upgradePlans {
+		providers := plan.Providers
+		if options.OnlyUpgradable {
+			providers = filterUpgradableItems(providers)
+			if len(providers) == 0 {
+				continue
+			}
 		}
+		if options.IncludeChangelog {
+			providers, err = c.addChangelog(providers, options.IncludePrereleases)
+			if err != nil {
+				return nil, err
+			}
+		}
+		aliasUpgradePlan = append(aliasUpgradePlan, UpgradePlan{
+			Contract:        plan.Contract,
+			Providers:       providers,
+			CrossesContract: plan.CrossesContract,
+		})
 	}
 
 	return aliasUpgradePlan, nil
 }
 
+// addChangelog returns a copy of the upgrade items with the release notes of the versions between the current version
+// and the next version.
+func (c *clusterctlClient) addChangelog(items []cluster.UpgradeItem, includePrereleases bool) ([]cluster.UpgradeItem, error) {
+	withChangelog := make([]cluster.UpgradeItem, 0, len(items))
+	for _, item := range items {
+		if item.NextVersion != "" && item.NextVersion != item.Version {
+			providerConfig, err := c.configClient.Providers().Get(item.ProviderName, item.GetProviderType())
+			if err != nil {
+				return nil, err
+			}
+			repo, err := c.repositoryClientFactory(RepositoryClientFactoryInput{Provider: providerConfig, IncludePrereleases: includePrereleases})
+			if err != nil {
+				return nil, err
+			}
+			item.Changelog, err = repo.GetChangelog(item.Version, item.NextVersion)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get the changelog for the provider %s", item.InstanceName())
+			}
+		}
+		withChangelog = append(withChangelog, item)
+	}
+	return withChangelog, nil
+}
+
+// CheckVersionSkewOptions carries the options supported by CheckVersionSkew.
+type CheckVersionSkewOptions struct {
+	// Kubeconfig defines the kubeconfig to use for accessing the management cluster. If empty, default discovery rules apply.
+	Kubeconfig Kubeconfig
+}
+
+func (c *clusterctlClient) CheckVersionSkew(options CheckVersionSkewOptions) ([]VersionSkew, error) {
+	// Get the client for interacting with the management cluster.
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return nil, err
+	}
+
+	// NB. The check is read-only, so it does not install the inventory CRDs if they are missing.
+	installed, err := clusterClient.ProviderInventory().HasCustomResourceDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	if !installed {
+		return nil, ErrInventoryNotInstalled
+	}
+
+	skews, err := clusterClient.ProviderUpgrader().CheckVersionSkew()
+	if err != nil {
+		return nil, err
+	}
+
+	// VersionSkew is an alias for cluster.VersionSkew; this makes the conversion
+	aliasSkews := make([]VersionSkew, 0, len(skews))
+	for _, skew := range skews {
+		aliasSkews = append(aliasSkews, VersionSkew(skew))
+	}
+	return aliasSkews, nil
+}
+
+// filterUpgradableItems returns the upgrade items with a next version available.
+func filterUpgradableItems(upgradeItems []cluster.UpgradeItem) []cluster.UpgradeItem {
+	ret := []cluster.UpgradeItem{}
+	for _, upgradeItem := range upgradeItems {
+		if upgradeItem.NextVersion != "" {
+			ret = append(ret, upgradeItem)
+		}
+	}
+	return ret
+}
+
 // ApplyUpgradeOptions carries the options supported by upgrade apply.
 type ApplyUpgradeOptions struct {
 	// Kubeconfig to use for accessing the management cluster. If empty, default discovery rules apply.
@@ -90,36 +199,84 @@ type ApplyUpgradeOptions struct {
 	Contract string
 
 	// CoreProvider instance and version (e.g. capi-system/cluster-api:v0.3.0) to upgrade to. This field can be used as alternative to Contract.
+	// If the version is omitted (e.g. capi-system/cluster-api), the latest version available for the current contract is used.
 	CoreProvider string
 
 	// BootstrapProviders instance and versions (e.g. capi-kubeadm-bootstrap-system/kubeadm:v0.3.0) to upgrade to. This field can be used as alternative to Contract.
+	// If the version is omitted, the latest version available for the contract of the management cluster is used.
 	BootstrapProviders []string
 
 	// ControlPlaneProviders instance and versions (e.g. capi-kubeadm-control-plane-system/kubeadm:v0.3.0) to upgrade to. This field can be used as alternative to Contract.
+	// If the version is omitted, the latest version available for the contract of the management cluster is used.
 	ControlPlaneProviders []string
 
 	// InfrastructureProviders instance and versions (e.g. capa-system/aws:v0.5.0) to upgrade to. This field can be used as alternative to Contract.
+	// If the version is omitted (e.g. capa-system/aws), the latest version available for the contract of the management cluster is used;
+	// this allows to upgrade a single provider independently from the others. In any case, if the resulting combination of providers
+	// does not satisfy the contract of the management cluster, an error listing the conflicting providers is returned.
 	InfrastructureProviders []string
+
+	// CertManagerVersion pins the cert-manager version to upgrade to, overriding the version defined in the clusterctl
+	// configuration or the default version embedded in clusterctl. NB. cert-manager is never downgraded.
+	CertManagerVersion string
+
+	// CertManagerImageRegistry, if set, replaces the registry of the cert-manager images when upgrading cert-manager,
+	// e.g. for air-gapped installations; see InitOptions.CertManagerImageRegistry.
+	CertManagerImageRegistry string
+
+	// DryRun means the upgrade action is a dry run, no real action will be performed; instead, the changes the upgrade
+	// is going to apply to the CustomResourceDefinitions and Deployments of the providers are computed and logged.
+	// NB. cert-manager is not upgraded during a dry run. Use ApplyUpgradeDryRun for getting the changes programmatically.
+	DryRun bool
+
+	// Timeout defines the maximum duration of the ApplyUpgrade operation, including upgrading cert-manager; in-flight
+	// requests are interrupted when the timeout expires. Zero means no timeout.
+	Timeout time.Duration
+
+	// WaitForReady instructs ApplyUpgrade to wait, after upgrading the providers, until the controllers of the upgraded
+	// providers are available, their CRDs are established and their webhooks are serving, for at most WaitForReadyTimeout
+	// (5 minutes if unspecified); a ProvidersNotReadyError with the readiness of each provider is returned if the time expires.
+	WaitForReady        bool
+	WaitForReadyTimeout time.Duration
+
+	// IncludePrereleases makes prerelease versions, e.g. release candidates, eligible as upgrade targets when upgrading
+	// to the latest versions for Contract or when the next version of a provider is not specified.
+	IncludePrereleases bool
+
+	// EventFunc, if set, is invoked for streaming the steps of the ApplyUpgrade operation, e.g. fetching the components
+	// of the next version of a provider, applying them or waiting for the provider to be ready.
+	// EventFunc is never invoked concurrently, and it is never invoked after ApplyUpgrade returns.
+	EventFunc EventFunc
 }
 
 func (c *clusterctlClient) ApplyUpgrade(options ApplyUpgradeOptions) error {
-	if options.Contract != "" && options.Contract != clusterv1.GroupVersion.Version {
-		return errors.Errorf("current version of clusterctl could only upgrade to %s contract, requested %s", clusterv1.GroupVersion.Version, options.Contract)
-	}
+	return c.ApplyUpgradeContext(context.Background(), options)
+}
 
-	// Get the client for interacting with the management cluster.
-	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
-	if err != nil {
-		return err
-	}
+func (c *clusterctlClient) ApplyUpgradeContext(ctx context.Context, options ApplyUpgradeOptions) (retErr error) {
+	ctx, cancel := newOperationContext(ctx, options.Timeout)
+	defer cancel()
+	c = c.withContext(ctx)
 
-	// Ensure this command only runs against management clusters with the current Cluster API contract (default) or the previous one.
-	if err := clusterClient.ProviderInventory().CheckCAPIContract(cluster.AllowCAPIContract{Contract: clusterv1old.GroupVersion.Version}); err != nil {
-		return err
+	if options.DryRun {
+		diff, err := c.ApplyUpgradeDryRun(options)
+		if err != nil {
+			return err
+		}
+
+		logUpgradeDiff(diff)
+		return nil
 	}
 
-	// Ensures the custom resource definitions required by clusterctl are in place.
-	if err := clusterClient.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
+	op := c.startOperation(ApplyUpgradeOperation)
+	defer func() { op.done(retErr) }()
+
+	eventFunc, closeEvents := cluster.NewEventStream(options.EventFunc)
+	defer closeEvents()
+	options.EventFunc = eventFunc
+
+	clusterClient, err := c.getUpgradeCluster(options)
+	if err != nil {
 		return err
 	}
 
@@ -127,47 +284,183 @@ func (c *clusterctlClient) ApplyUpgrade(options ApplyUpgradeOptions) error {
 	// NOTE: it is safe to upgrade to latest version of cert-manager given that it provides
 	// conversion web-hooks around Issuer/Certificate kinds, so installing an older versions of providers
 	// should continue to work with the latest cert-manager.
-	certManager := clusterClient.CertManager()
+	certManager := clusterClient.CertManager(
+		cluster.WithCertManagerVersion(options.CertManagerVersion),
+		cluster.WithCertManagerImageRegistry(options.CertManagerImageRegistry),
+	)
+	options.EventFunc.Notify(cluster.InstallingCertManagerEvent, "", "Ensuring the latest version of cert-manager is installed")
 	if err := certManager.EnsureLatestVersion(); err != nil {
 		return err
 	}
 
-	// Check if the user want a custom upgrade
-	isCustomUpgrade := options.CoreProvider != "" ||
-		len(options.BootstrapProviders) > 0 ||
-		len(options.ControlPlaneProviders) > 0 ||
-		len(options.InfrastructureProviders) > 0
+	upgradeItems, err := getUpgradeItems(options)
+	if err != nil {
+		return err
+	}
+	for _, item := range upgradeItems {
+		op.providers = append(op.providers, item.ManifestLabel())
+	}
 
-	// If we are upgrading a specific set of providers only, process the providers and call ApplyCustomPlan.
-	if isCustomUpgrade {
-		// Converts upgrade references back into an UpgradeItem.
-		upgradeItems := []cluster.UpgradeItem{}
+	// If we are upgrading a specific set of providers only, execute the upgrade using the custom upgrade items.
+	// Otherwise we are upgrading a whole management cluster according to a clusterctl generated upgrade plan.
+	if len(upgradeItems) > 0 {
+		err = clusterClient.ProviderUpgrader().ApplyCustomPlan(upgradeItems...)
+	} else {
+		err = clusterClient.ProviderUpgrader().ApplyPlan(options.Contract)
+	}
+	if err != nil || !options.WaitForReady {
+		return err
+	}
 
-		if options.CoreProvider != "" {
-			upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.CoreProviderType, options.CoreProvider)
-			if err != nil {
-				return err
-			}
+	providers, err := getUpgradedProviders(clusterClient, upgradeItems)
+	if err != nil {
+		return err
+	}
+	return waitForProvidersReady(ctx, clusterClient, providers, options.WaitForReadyTimeout, options.EventFunc)
+}
+
+// getUpgradedProviders returns the inventory objects of the providers targeted by the upgrade items, or of all the
+// providers if the upgrade items are empty, i.e. when upgrading the whole management cluster.
+func getUpgradedProviders(clusterClient cluster.Client, upgradeItems []cluster.UpgradeItem) ([]clusterctlv1.Provider, error) {
+	providerList, err := clusterClient.ProviderInventory().List()
+	if err != nil {
+		return nil, err
+	}
+	if len(upgradeItems) == 0 {
+		return providerList.Items, nil
+	}
+
+	upgraded := sets.NewString()
+	for _, item := range upgradeItems {
+		upgraded.Insert(item.InstanceName())
+	}
+	providers := []clusterctlv1.Provider{}
+	for _, provider := range providerList.Items {
+		if upgraded.Has(provider.InstanceName()) {
+			providers = append(providers, provider)
 		}
-		upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.BootstrapProviderType, options.BootstrapProviders...)
+	}
+	return providers, nil
+}
+
+func (c *clusterctlClient) ApplyUpgradeDryRun(options ApplyUpgradeOptions) (*UpgradeDiff, error) {
+	clusterClient, err := c.getUpgradeCluster(options)
+	if err != nil {
+		return nil, err
+	}
+
+	upgradeItems, err := getUpgradeItems(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff *cluster.UpgradeDiff
+	if len(upgradeItems) > 0 {
+		diff, err = clusterClient.ProviderUpgrader().DryRunCustomPlan(upgradeItems...)
+	} else {
+		diff, err = clusterClient.ProviderUpgrader().DryRunPlan(options.Contract)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return (*UpgradeDiff)(diff), nil
+}
+
+// getUpgradeCluster returns the client for the management cluster to be upgraded, after checking the management
+// cluster is compatible with the current version of clusterctl.
+func (c *clusterctlClient) getUpgradeCluster(options ApplyUpgradeOptions) (cluster.Client, error) {
+	if options.Contract != "" && options.Contract != clusterv1.GroupVersion.Version {
+		return nil, errors.Errorf("current version of clusterctl could only upgrade to %s contract, requested %s", clusterv1.GroupVersion.Version, options.Contract)
+	}
+
+	// Get the client for interacting with the management cluster.
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig, IncludePrereleases: options.IncludePrereleases, EventFunc: options.EventFunc})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure this command only runs against management clusters with the current Cluster API contract (default) or the previous one.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(cluster.AllowCAPIContract{Contract: clusterv1old.GroupVersion.Version}); err != nil {
+		return nil, err
+	}
+
+	// Ensures the custom resource definitions required by clusterctl are in place.
+	if err := clusterClient.ProviderInventory().EnsureCustomResourceDefinitions(); err != nil {
+		return nil, err
+	}
+	return clusterClient, nil
+}
+
+// getUpgradeItems converts the upgrade references in the options into UpgradeItems; the returned list is empty
+// unless the user wants a custom upgrade of a specific set of providers.
+func getUpgradeItems(options ApplyUpgradeOptions) ([]cluster.UpgradeItem, error) {
+	upgradeItems := []cluster.UpgradeItem{}
+
+	var err error
+	if options.CoreProvider != "" {
+		upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.CoreProviderType, options.CoreProvider)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.ControlPlaneProviderType, options.ControlPlaneProviders...)
-		if err != nil {
-			return err
+	}
+	upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.BootstrapProviderType, options.BootstrapProviders...)
+	if err != nil {
+		return nil, err
+	}
+	upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.ControlPlaneProviderType, options.ControlPlaneProviders...)
+	if err != nil {
+		return nil, err
+	}
+	upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.InfrastructureProviderType, options.InfrastructureProviders...)
+	if err != nil {
+		return nil, err
+	}
+	return upgradeItems, nil
+}
+
+// logUpgradeDiff logs the changes an upgrade is going to apply to the provider components.
+func logUpgradeDiff(diff *UpgradeDiff) {
+	log := logf.Log
+
+	if len(diff.Changes) == 0 {
+		log.Info("No changes to the provider components")
+		return
+	}
+
+	for _, c := range diff.Changes {
+		log.Info("Component change", "Provider", c.Provider, "Type", c.Type, c.Object.Kind, c.Object.Name, "Namespace", c.Object.Namespace)
+		if c.StorageVersionChange != nil {
+			log.Info("Warning: the storage version of the CustomResourceDefinition changes", "Name", c.Object.Name, "From", c.StorageVersionChange.From, "To", c.StorageVersionChange.To)
 		}
-		upgradeItems, err = addUpgradeItems(upgradeItems, clusterctlv1.InfrastructureProviderType, options.InfrastructureProviders...)
-		if err != nil {
-			return err
+		if c.Diff != "" {
+			log.V(1).Info(c.Diff)
 		}
+	}
+}
 
-		// Execute the upgrade using the custom upgrade items
-		return clusterClient.ProviderUpgrader().ApplyCustomPlan(upgradeItems...)
+// RollbackUpgradeOptions carries the options supported by upgrade rollback.
+type RollbackUpgradeOptions struct {
+	// Kubeconfig to use for accessing the management cluster. If empty, default discovery rules apply.
+	Kubeconfig Kubeconfig
+
+	// AttemptID is the ID of the upgrade attempt to rollback, as reported by ApplyUpgrade.
+	// If empty, the latest upgrade attempt is rolled back.
+	AttemptID string
+}
+
+func (c *clusterctlClient) RollbackUpgrade(options RollbackUpgradeOptions) error {
+	// Get the client for interacting with the management cluster.
+	clusterClient, err := c.clusterClientFactory(ClusterClientFactoryInput{Kubeconfig: options.Kubeconfig})
+	if err != nil {
+		return err
 	}
 
-	// Otherwise we are upgrading a whole management cluster according to a clusterctl generated upgrade plan.
-	return clusterClient.ProviderUpgrader().ApplyPlan(options.Contract)
+	// Ensure this command only runs against management clusters with the current Cluster API contract (default) or the previous one.
+	if err := clusterClient.ProviderInventory().CheckCAPIContract(cluster.AllowCAPIContract{Contract: clusterv1old.GroupVersion.Version}); err != nil {
+		return err
+	}
+
+	return clusterClient.ProviderUpgrader().Rollback(options.AttemptID)
 }
 
 func addUpgradeItems(upgradeItems []cluster.UpgradeItem, providerType clusterctlv1.ProviderType, providers ...string) ([]cluster.UpgradeItem, error) {
@@ -176,9 +469,6 @@ func addUpgradeItems(upgradeItems []cluster.UpgradeItem, providerType clusterctl
 		if err != nil {
 			return nil, err
 		}
-		if providerUpgradeItem.NextVersion == "" {
-			return nil, errors.Errorf("invalid provider name %q. Provider name should be in the form namespace/name:version and version cannot be empty", upgradeReference)
-		}
 		upgradeItems = append(upgradeItems, *providerUpgradeItem)
 	}
 	return upgradeItems, nil
@@ -23,12 +23,14 @@ import (
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/cluster"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/internal/test"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func Test_clusterctlClient_PlanCertUpgrade(t *testing.T) {
@@ -143,6 +145,169 @@ func Test_clusterctlClient_PlanUpgrade(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_PlanUpgrade_OnlyUpgradable(t *testing.T) {
+	core := config.NewProvider("cluster-api", "https://somewhere.com", clusterctlv1.CoreProviderType)
+	infra := config.NewProvider("infra", "https://somewhere.com", clusterctlv1.InfrastructureProviderType)
+
+	config1 := newFakeConfig().
+		WithProvider(core).
+		WithProvider(infra)
+
+	repository1 := newFakeRepository(core, config1).
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion("v1.0.1").
+		WithVersions("v1.0.0", "v1.0.1").
+		WithMetadata("v1.0.1", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+	repository2 := newFakeRepository(infra, config1).
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion("v2.0.1").
+		WithVersions("v2.0.0", "v2.0.1").
+		WithMetadata("v2.0.1", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+
+	tests := []struct {
+		name           string
+		infraVersion   string
+		onlyUpgradable bool
+		want           []string
+	}{
+		{
+			name:           "returns all the providers if OnlyUpgradable is false",
+			infraVersion:   "v2.0.1", // infra is already at the latest version
+			onlyUpgradable: false,
+			want:           []string{"capi-system/cluster-api", "infra-system/infrastructure-infra"},
+		},
+		{
+			name:           "omits providers already at the latest version if OnlyUpgradable is true",
+			infraVersion:   "v2.0.1", // infra is already at the latest version
+			onlyUpgradable: true,
+			want:           []string{"capi-system/cluster-api"},
+		},
+		{
+			name:           "returns all the providers if all of them can be upgraded",
+			infraVersion:   "v2.0.0",
+			onlyUpgradable: true,
+			want:           []string{"capi-system/cluster-api", "infra-system/infrastructure-infra"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kubeconfig := cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+			cluster1 := newFakeCluster(kubeconfig, config1).
+				WithRepository(repository1).
+				WithRepository(repository2).
+				WithProviderInventory(core.Name(), core.Type(), "v1.0.0", "capi-system").
+				WithProviderInventory(infra.Name(), infra.Type(), tt.infraVersion, "infra-system").
+				WithObjs(test.FakeCAPISetupObjects()...)
+
+			client := newFakeClient(config1).
+				WithRepository(repository1).
+				WithRepository(repository2).
+				WithCluster(cluster1)
+
+			got, err := client.PlanUpgrade(PlanUpgradeOptions{
+				Kubeconfig:     Kubeconfig(kubeconfig),
+				OnlyUpgradable: tt.onlyUpgradable,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(HaveLen(1))
+			g.Expect(got[0].Contract).To(Equal(test.CurrentCAPIContract))
+			g.Expect(got[0].CrossesContract).To(BeFalse())
+
+			providers := []string{}
+			for _, p := range got[0].Providers {
+				providers = append(providers, p.InstanceName())
+			}
+			g.Expect(providers).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func Test_clusterctlClient_PlanUpgrade_IncludeChangelog(t *testing.T) {
+	g := NewWithT(t)
+
+	core := config.NewProvider("cluster-api", "https://somewhere.com", clusterctlv1.CoreProviderType)
+	infra := config.NewProvider("infra", "https://somewhere.com", clusterctlv1.InfrastructureProviderType)
+
+	config1 := newFakeConfig().
+		WithProvider(core).
+		WithProvider(infra)
+
+	repository1 := newFakeRepository(core, config1).
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion("v1.0.2").
+		WithVersions("v1.0.0", "v1.0.1", "v1.0.2").
+		WithFile("v1.0.1", "CHANGELOG.md", []byte("changelog v1.0.1")).
+		WithFile("v1.0.2", "CHANGELOG.md", []byte("changelog v1.0.2")).
+		WithMetadata("v1.0.2", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 1, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+	// infra does not publish a changelog
+	repository2 := newFakeRepository(infra, config1).
+		WithPaths("root", "components.yaml").
+		WithDefaultVersion("v2.0.1").
+		WithVersions("v2.0.0", "v2.0.1").
+		WithMetadata("v2.0.1", &clusterctlv1.Metadata{
+			ReleaseSeries: []clusterctlv1.ReleaseSeries{
+				{Major: 2, Minor: 0, Contract: test.CurrentCAPIContract},
+			},
+		})
+
+	kubeconfig := cluster.Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+	cluster1 := newFakeCluster(kubeconfig, config1).
+		WithRepository(repository1).
+		WithRepository(repository2).
+		WithProviderInventory(core.Name(), core.Type(), "v1.0.0", "capi-system").
+		WithProviderInventory(infra.Name(), infra.Type(), "v2.0.0", "infra-system").
+		WithObjs(test.FakeCAPISetupObjects()...)
+
+	client := newFakeClient(config1).
+		WithRepository(repository1).
+		WithRepository(repository2).
+		WithCluster(cluster1)
+
+	got, err := client.PlanUpgrade(PlanUpgradeOptions{
+		Kubeconfig:       Kubeconfig(kubeconfig),
+		IncludeChangelog: true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(HaveLen(1))
+
+	changelogs := map[string][]ReleaseNotes{}
+	for _, p := range got[0].Providers {
+		changelogs[p.InstanceName()] = p.Changelog
+	}
+	g.Expect(changelogs).To(Equal(map[string][]ReleaseNotes{
+		"capi-system/cluster-api": {
+			{Version: "v1.0.1", Notes: "changelog v1.0.1"},
+			{Version: "v1.0.2", Notes: "changelog v1.0.2"},
+		},
+		"infra-system/infrastructure-infra": {},
+	}))
+}
+
+func Test_filterUpgradableItems(t *testing.T) {
+	g := NewWithT(t)
+
+	upgradeItems := []cluster.UpgradeItem{
+		{Provider: fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"), NextVersion: "v1.0.1"},
+		{Provider: fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.1", "infra-system"), NextVersion: ""},
+	}
+	g.Expect(filterUpgradableItems(upgradeItems)).To(Equal(upgradeItems[:1]))
+	g.Expect(filterUpgradableItems(upgradeItems[1:])).To(BeEmpty())
+}
+
 func Test_clusterctlClient_ApplyUpgrade(t *testing.T) {
 	type fields struct {
 		client *fakeClient
@@ -269,6 +434,55 @@ func Test_clusterctlClient_ApplyUpgrade(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "apply a custom plan - infra provider only, without a target version",
+			fields: fields{
+				client: fakeClientForUpgrade(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: ApplyUpgradeOptions{
+					Kubeconfig:              Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					InfrastructureProviders: []string{"infra-system/infra"},
+				},
+			},
+			wantProviders: &clusterctlv1.ProviderList{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: clusterctlv1.GroupVersion.String(),
+					Kind:       "ProviderList",
+				},
+				ListMeta: metav1.ListMeta{},
+				Items: []clusterctlv1.Provider{
+					fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"),
+					fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.1", "infra-system"),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "dry run a plan",
+			fields: fields{
+				client: fakeClientForUpgrade(), // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+			},
+			args: args{
+				options: ApplyUpgradeOptions{
+					Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+					Contract:   test.CurrentCAPIContract,
+					DryRun:     true,
+				},
+			},
+			wantProviders: &clusterctlv1.ProviderList{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: clusterctlv1.GroupVersion.String(),
+					Kind:       "ProviderList",
+				},
+				ListMeta: metav1.ListMeta{},
+				Items: []clusterctlv1.Provider{ // providers should not be upgraded
+					fakeProvider("cluster-api", clusterctlv1.CoreProviderType, "v1.0.0", "cluster-api-system"),
+					fakeProvider("infra", clusterctlv1.InfrastructureProviderType, "v2.0.0", "infra-system"),
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -305,6 +519,115 @@ func Test_clusterctlClient_ApplyUpgrade(t *testing.T) {
 	}
 }
 
+func Test_clusterctlClient_ApplyUpgradeDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ApplyUpgradeOptions
+		wantErr bool
+	}{
+		{
+			name: "dry run a plan",
+			options: ApplyUpgradeOptions{
+				Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				Contract:   test.CurrentCAPIContract,
+			},
+			wantErr: false,
+		},
+		{
+			name: "dry run a custom plan",
+			options: ApplyUpgradeOptions{
+				Kubeconfig:   Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				CoreProvider: "cluster-api-system/cluster-api:v1.0.1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "fails for an unsupported contract",
+			options: ApplyUpgradeOptions{
+				Kubeconfig: Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"},
+				Contract:   test.NextCAPIContractNotSupported,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakeClientForUpgrade()
+			diff, err := client.ApplyUpgradeDryRun(tt.options)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// NB. the provider components used in this test do not include CRDs or Deployments.
+			g.Expect(diff).ToNot(BeNil())
+			g.Expect(diff.Changes).To(BeEmpty())
+		})
+	}
+}
+
+func Test_clusterctlClient_RollbackUpgrade(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakeClientForUpgrade() // core v1.0.0 (v1.0.1 available), infra v2.0.0 (v2.0.1 available)
+	kubeconfig := Kubeconfig{Path: "kubeconfig", Context: "mgmt-context"}
+
+	// Fails if there are no upgrade attempts to rollback.
+	err := client.RollbackUpgrade(RollbackUpgradeOptions{Kubeconfig: kubeconfig})
+	g.Expect(err).To(HaveOccurred())
+
+	// Adds the snapshot of a previous upgrade attempt.
+	proxy := client.clusters[cluster.Kubeconfig(kubeconfig)].Proxy()
+	c, err := proxy.NewClient()
+	g.Expect(err).NotTo(HaveOccurred())
+	previousSnapshot := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "cluster-api-system",
+			Name:      "clusterctl-upgrade-20200101-000000-cluster-api",
+			Labels: map[string]string{
+				clusterctlv1.ClusterctlUpgradeSnapshotLabelName: "20200101-000000",
+			},
+		},
+	}
+	g.Expect(c.Create(ctx, previousSnapshot)).To(Succeed())
+
+	err = client.ApplyUpgrade(ApplyUpgradeOptions{
+		Kubeconfig: kubeconfig,
+		Contract:   test.CurrentCAPIContract,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// After a successful upgrade, only the snapshots of the latest upgrade attempt are preserved.
+	snapshots := &corev1.SecretList{}
+	g.Expect(c.List(ctx, snapshots, ctrlclient.HasLabels{clusterctlv1.ClusterctlUpgradeSnapshotLabelName})).To(Succeed())
+	g.Expect(snapshots.Items).To(HaveLen(2))
+	for _, s := range snapshots.Items {
+		g.Expect(s.Labels[clusterctlv1.ClusterctlUpgradeSnapshotLabelName]).ToNot(Equal("20200101-000000"))
+	}
+
+	// Rollbacks the latest upgrade attempt.
+	err = client.RollbackUpgrade(RollbackUpgradeOptions{Kubeconfig: kubeconfig})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	gotProviders := &clusterctlv1.ProviderList{}
+	g.Expect(c.List(ctx, gotProviders)).To(Succeed())
+	gotVersions := map[string]string{}
+	for _, p := range gotProviders.Items {
+		gotVersions[p.InstanceName()] = p.Version
+	}
+	g.Expect(gotVersions).To(Equal(map[string]string{
+		"cluster-api-system/cluster-api":    "v1.0.0",
+		"infra-system/infrastructure-infra": "v2.0.0",
+	}))
+
+	// The snapshots are deleted after the rollback.
+	err = client.RollbackUpgrade(RollbackUpgradeOptions{Kubeconfig: kubeconfig})
+	g.Expect(err).To(HaveOccurred())
+}
+
 func fakeClientForUpgrade() *fakeClient {
 	core := config.NewProvider("cluster-api", "https://somewhere.com", clusterctlv1.CoreProviderType)
 	infra := config.NewProvider("infra", "https://somewhere.com", clusterctlv1.InfrastructureProviderType)
@@ -316,6 +639,7 @@ func fakeClientForUpgrade() *fakeClient {
 	repository1 := newFakeRepository(core, config1).
 		WithPaths("root", "components.yaml").
 		WithDefaultVersion("v1.0.1").
+		WithFile("v1.0.0", "components.yaml", componentsYAML("ns2")).
 		WithFile("v1.0.1", "components.yaml", componentsYAML("ns2")).
 		WithVersions("v1.0.0", "v1.0.1").
 		WithMetadata("v1.0.1", &clusterctlv1.Metadata{
@@ -326,6 +650,7 @@ func fakeClientForUpgrade() *fakeClient {
 	repository2 := newFakeRepository(infra, config1).
 		WithPaths("root", "components.yaml").
 		WithDefaultVersion("v2.0.0").
+		WithFile("v2.0.0", "components.yaml", componentsYAML("ns2")).
 		WithFile("v2.0.1", "components.yaml", componentsYAML("ns2")).
 		WithVersions("v2.0.0", "v2.0.1").
 		WithMetadata("v2.0.1", &clusterctlv1.Metadata{
@@ -0,0 +1,282 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	apiyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	yaml "sigs.k8s.io/cluster-api/cmd/clusterctl/client/yamlprocessor"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+// WorkerPool defines a group of worker machines to be added to a workload cluster.
+// For each worker pool, GetClusterTemplate generates a copy of the MachineDeployments defined in the template, and
+// of the machine templates they reference, appending the name of the pool to the name of each object.
+// Variables used only by these objects, e.g. WORKER_MACHINE_COUNT or the instance type, are namespaced using the
+// name of the pool, uppercased, as a prefix, e.g. GPU_WORKER_MACHINE_COUNT for the pool "gpu", so each pool
+// can use different values; variables used also by other objects, e.g. CLUSTER_NAME, are shared by all the pools.
+type WorkerPool struct {
+	// Name of the worker pool; it must be a valid DNS-1123 label.
+	Name string
+
+	// MachineCount defines the number of worker machines in the pool. If unspecified, the value of the pool
+	// variable, e.g. GPU_WORKER_MACHINE_COUNT, or the WorkerMachineCount will be used.
+	MachineCount *int64
+
+	// Variables defines the values of the variables for the pool, using the variable names as defined in the
+	// template, without the pool prefix, e.g. {"AWS_NODE_MACHINE_TYPE": "p3.2xlarge"}.
+	Variables map[string]string
+}
+
+// variablePrefix returns the prefix used for the variables of the worker pool.
+func (p WorkerPool) variablePrefix() string {
+	return strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_")) + "_"
+}
+
+// workerPoolsToVariables validates the worker pools and injects the values for the pool variables into the configClient.
+func (c *clusterctlClient) workerPoolsToVariables(options GetClusterTemplateOptions) error {
+	names := sets.NewString()
+	for _, pool := range options.WorkerPools {
+		if err := validateDNS1123Label(pool.Name); err != nil {
+			return errors.Wrapf(err, "invalid worker pool name %q", pool.Name)
+		}
+		if names.Has(pool.Name) {
+			return errors.Errorf("invalid worker pools: the worker pool %q is defined more than once", pool.Name)
+		}
+		names.Insert(pool.Name)
+
+		prefix := pool.variablePrefix()
+		for name, value := range pool.Variables {
+			c.configClient.Variables().Set(prefix+name, value)
+		}
+
+		// NB. the WorkerMachineCount is already validated and set by templateOptionsToVariables.
+		if pool.MachineCount == nil {
+			if _, err := c.configClient.Variables().Get(prefix + "WORKER_MACHINE_COUNT"); err == nil {
+				continue
+			}
+			workerMachineCount, err := c.configClient.Variables().Get("WORKER_MACHINE_COUNT")
+			if err != nil {
+				return err
+			}
+			c.configClient.Variables().Set(prefix+"WORKER_MACHINE_COUNT", workerMachineCount)
+			continue
+		}
+		if *pool.MachineCount < 0 {
+			return errors.Errorf("invalid MachineCount for the worker pool %q. Please use a number greater or equal than 0", pool.Name)
+		}
+		c.configClient.Variables().Set(prefix+"WORKER_MACHINE_COUNT", strconv.FormatInt(*pool.MachineCount, 10))
+	}
+	return nil
+}
+
+// workerPoolsProcessor is a yaml.Processor expanding the template into a copy of the pool objects for each worker pool
+// before delegating to the underlying processor, so both the processed template and its variables reflect the pools.
+// NB. Pool variables are namespaced by rewriting the ${VAR} references in the template, so the underlying
+// processor is expected to use the same syntax of the SimpleProcessor.
+type workerPoolsProcessor struct {
+	yaml.Processor
+	pools []WorkerPool
+}
+
+var _ yaml.Processor = &workerPoolsProcessor{}
+
+func newWorkerPoolsProcessor(processor yaml.Processor, pools []WorkerPool) *workerPoolsProcessor {
+	if processor == nil {
+		processor = yaml.NewSimpleProcessor()
+	}
+	return &workerPoolsProcessor{
+		Processor: processor,
+		pools:     pools,
+	}
+}
+
+func (p *workerPoolsProcessor) GetVariables(rawArtifact []byte) ([]string, error) {
+	expanded, err := p.expand(rawArtifact)
+	if err != nil {
+		return nil, err
+	}
+	return p.Processor.GetVariables(expanded)
+}
+
+func (p *workerPoolsProcessor) GetVariableMap(rawArtifact []byte) (map[string]*string, error) {
+	expanded, err := p.expand(rawArtifact)
+	if err != nil {
+		return nil, err
+	}
+	return p.Processor.GetVariableMap(expanded)
+}
+
+func (p *workerPoolsProcessor) Process(rawArtifact []byte, variablesClient func(string) (string, error)) ([]byte, error) {
+	expanded, err := p.expand(rawArtifact)
+	if err != nil {
+		return rawArtifact, err
+	}
+	return p.Processor.Process(expanded, variablesClient)
+}
+
+// templateDocument is a YAML document of a template, not yet processed.
+type templateDocument struct {
+	raw  []byte
+	gvk  schema.GroupVersionKind
+	name string
+}
+
+// isMachineDeployment returns true if the document defines a Cluster API MachineDeployment.
+func (d templateDocument) isMachineDeployment() bool {
+	return d.gvk.Group == clusterv1.GroupVersion.Group && d.gvk.Kind == "MachineDeployment"
+}
+
+// expand replaces the pool objects in the template, i.e. the MachineDeployments and the templates they reference,
+// with a copy for each worker pool.
+func (p *workerPoolsProcessor) expand(rawArtifact []byte) ([]byte, error) {
+	docs, err := splitTemplateDocuments(rawArtifact)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the template for generating the worker pools")
+	}
+
+	// Gets the MachineDeployments and the templates they reference.
+	poolRefs := sets.NewString()
+	for _, d := range docs {
+		if !d.isMachineDeployment() {
+			continue
+		}
+		poolRefs.Insert(d.gvk.Kind + "/" + d.name)
+
+		objs, err := utilyaml.ToUnstructured(d.raw)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the template for generating the worker pools")
+		}
+		for _, path := range [][]string{
+			{"spec", "template", "spec", "infrastructureRef"},
+			{"spec", "template", "spec", "bootstrap", "configRef"},
+		} {
+			ref, ok, _ := unstructured.NestedStringMap(objs[0].Object, path...)
+			if ok {
+				poolRefs.Insert(ref["kind"] + "/" + ref["name"])
+			}
+		}
+	}
+	if poolRefs.Len() == 0 {
+		return nil, errors.New("worker pools require a template defining at least one MachineDeployment")
+	}
+
+	var poolDocs, otherDocs [][]byte
+	poolNames := sets.NewString()
+	for _, d := range docs {
+		if poolRefs.Has(d.gvk.Kind + "/" + d.name) {
+			poolDocs = append(poolDocs, d.raw)
+			poolNames.Insert(d.name)
+			continue
+		}
+		otherDocs = append(otherDocs, d.raw)
+	}
+
+	// Gets the variables used only by the pool objects; those variables are namespaced for each pool.
+	poolVariables, err := p.Processor.GetVariables(utilyaml.JoinYaml(poolDocs...))
+	if err != nil {
+		return nil, err
+	}
+	sharedVariables := []string{}
+	if len(otherDocs) > 0 {
+		sharedVariables, err = p.Processor.GetVariables(utilyaml.JoinYaml(otherDocs...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	namespacedVariables := sets.NewString(poolVariables...).Difference(sets.NewString(sharedVariables...))
+
+	namesRegex := alternationRegexp("", poolNames.List(), "")
+	var variablesRegex *regexp.Regexp
+	if namespacedVariables.Len() > 0 {
+		variablesRegex = alternationRegexp(`\$\{\s*`, namespacedVariables.List(), `\b`)
+	}
+
+	poolsYaml := utilyaml.JoinYaml(poolDocs...)
+	expanded := otherDocs
+	for _, pool := range p.pools {
+		pool := pool
+		poolYaml := namesRegex.ReplaceAllStringFunc(string(poolsYaml), func(name string) string {
+			return name + "-" + pool.Name
+		})
+		if variablesRegex != nil {
+			poolYaml = variablesRegex.ReplaceAllStringFunc(poolYaml, func(v string) string {
+				name := strings.TrimLeft(v[2:], " \t")
+				return v[:len(v)-len(name)] + pool.variablePrefix() + name
+			})
+		}
+		expanded = append(expanded, []byte(poolYaml))
+	}
+	return utilyaml.JoinYaml(expanded...), nil
+}
+
+// splitTemplateDocuments splits a template into YAML documents, reading the kind and the name of each object
+// without processing the template.
+func splitTemplateDocuments(rawArtifact []byte) ([]templateDocument, error) {
+	reader := apiyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(rawArtifact)))
+	docs := []templateDocument{}
+	for {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+
+		objs, err := utilyaml.ToUnstructured(raw)
+		if err != nil {
+			return nil, err
+		}
+		doc := templateDocument{raw: bytes.TrimSuffix(raw, []byte("\n"))}
+		if len(objs) > 0 {
+			doc.gvk = objs[0].GroupVersionKind()
+			doc.name = objs[0].GetName()
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// alternationRegexp returns a regular expression matching any of the values, with the given prefix and suffix; longer
+// values are matched first, so a value is never matched as a part of a longer one.
+func alternationRegexp(prefix string, values []string, suffix string) *regexp.Regexp {
+	sorted := append([]string{}, values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	quoted := make([]string, 0, len(sorted))
+	for _, v := range sorted {
+		quoted = append(quoted, regexp.QuoteMeta(v))
+	}
+	return regexp.MustCompile(prefix + "(" + strings.Join(quoted, "|") + ")" + suffix)
+}
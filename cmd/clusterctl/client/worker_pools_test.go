@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+)
+
+var workerPoolsTemplate = []byte(`apiVersion: cluster.x-k8s.io/v1alpha4
+kind: Cluster
+metadata:
+  name: ${CLUSTER_NAME}
+---
+apiVersion: cluster.x-k8s.io/v1alpha4
+kind: MachineDeployment
+metadata:
+  name: ${CLUSTER_NAME}-md-0
+spec:
+  clusterName: ${CLUSTER_NAME}
+  replicas: ${ WORKER_MACHINE_COUNT }
+  template:
+    spec:
+      clusterName: ${CLUSTER_NAME}
+      version: ${KUBERNETES_VERSION}
+      bootstrap:
+        configRef:
+          apiVersion: bootstrap.cluster.x-k8s.io/v1alpha4
+          kind: KubeadmConfigTemplate
+          name: ${CLUSTER_NAME}-md-0
+      infrastructureRef:
+        apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+        kind: DockerMachineTemplate
+        name: ${CLUSTER_NAME}-md-0
+---
+apiVersion: infrastructure.cluster.x-k8s.io/v1alpha4
+kind: DockerMachineTemplate
+metadata:
+  name: ${CLUSTER_NAME}-md-0
+spec:
+  template:
+    spec:
+      customImage: ${WORKER_IMAGE:=kindest/node}
+---
+apiVersion: controlplane.cluster.x-k8s.io/v1alpha4
+kind: KubeadmControlPlane
+metadata:
+  name: ${CLUSTER_NAME}-control-plane
+spec:
+  version: ${KUBERNETES_VERSION}
+---
+apiVersion: bootstrap.cluster.x-k8s.io/v1alpha4
+kind: KubeadmConfigTemplate
+metadata:
+  name: ${CLUSTER_NAME}-md-0
+`)
+
+func Test_workerPoolsProcessor(t *testing.T) {
+	g := NewWithT(t)
+
+	p := newWorkerPoolsProcessor(nil, []WorkerPool{{Name: "default"}, {Name: "gpu-large"}})
+
+	variables, err := p.GetVariables(workerPoolsTemplate)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(variables).To(Equal([]string{
+		"CLUSTER_NAME",
+		"DEFAULT_WORKER_IMAGE",
+		"DEFAULT_WORKER_MACHINE_COUNT",
+		"GPU_LARGE_WORKER_IMAGE",
+		"GPU_LARGE_WORKER_MACHINE_COUNT",
+		"KUBERNETES_VERSION",
+	}))
+
+	variableMap, err := p.GetVariableMap(workerPoolsTemplate)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(variableMap).To(HaveKeyWithValue("GPU_LARGE_WORKER_IMAGE", pointer.StringPtr("kindest/node")))
+	g.Expect(variableMap).To(HaveKeyWithValue("GPU_LARGE_WORKER_MACHINE_COUNT", BeNil()))
+
+	values := map[string]string{
+		"CLUSTER_NAME":                   "test",
+		"KUBERNETES_VERSION":             "v1.21.2",
+		"DEFAULT_WORKER_MACHINE_COUNT":   "3",
+		"GPU_LARGE_WORKER_MACHINE_COUNT": "1",
+		"GPU_LARGE_WORKER_IMAGE":         "gpu/node",
+	}
+	processed, err := p.Process(workerPoolsTemplate, func(name string) (string, error) {
+		return values[name], nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	objs, err := utilyaml.ToUnstructured(processed)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	got := map[string]string{}
+	for _, o := range objs {
+		got[o.GetKind()+"/"+o.GetName()] = o.GetName()
+	}
+	g.Expect(got).To(HaveLen(8))
+	g.Expect(got).To(HaveKey("Cluster/test"))
+	g.Expect(got).To(HaveKey("KubeadmControlPlane/test-control-plane"))
+	for _, pool := range []string{"default", "gpu-large"} {
+		g.Expect(got).To(HaveKey("MachineDeployment/test-md-0-" + pool))
+		g.Expect(got).To(HaveKey("KubeadmConfigTemplate/test-md-0-" + pool))
+		g.Expect(got).To(HaveKey("DockerMachineTemplate/test-md-0-" + pool))
+	}
+
+	for _, o := range objs {
+		switch o.GetName() {
+		case "test-md-0-gpu-large":
+			if o.GetKind() == "MachineDeployment" {
+				g.Expect(o.Object["spec"]).To(HaveKeyWithValue("replicas", BeNumerically("==", 1)))
+				ref, _, _ := unstructured.NestedString(o.Object, "spec", "template", "spec", "infrastructureRef", "name")
+				g.Expect(ref).To(Equal("test-md-0-gpu-large"))
+			}
+			if o.GetKind() == "DockerMachineTemplate" {
+				image, _, _ := unstructured.NestedString(o.Object, "spec", "template", "spec", "customImage")
+				g.Expect(image).To(Equal("gpu/node"))
+			}
+		case "test-md-0-default":
+			if o.GetKind() == "MachineDeployment" {
+				g.Expect(o.Object["spec"]).To(HaveKeyWithValue("replicas", BeNumerically("==", 3)))
+			}
+			if o.GetKind() == "DockerMachineTemplate" {
+				image, _, _ := unstructured.NestedString(o.Object, "spec", "template", "spec", "customImage")
+				g.Expect(image).To(Equal("kindest/node"))
+			}
+		}
+	}
+}
+
+func Test_workerPoolsProcessor_withoutMachineDeployments(t *testing.T) {
+	g := NewWithT(t)
+
+	p := newWorkerPoolsProcessor(nil, []WorkerPool{{Name: "gpu"}})
+	_, err := p.GetVariables([]byte("apiVersion: cluster.x-k8s.io/v1alpha4\nkind: Cluster\nmetadata:\n  name: ${CLUSTER_NAME}\n"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_clusterctlClient_workerPoolsToVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		pools    []WorkerPool
+		wantVars map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "pool variables are set with the pool prefix",
+			pools: []WorkerPool{
+				{Name: "gpu", MachineCount: pointer.Int64Ptr(2), Variables: map[string]string{"INSTANCE_TYPE": "p3.2xlarge"}},
+				{Name: "spot"},
+			},
+			wantVars: map[string]string{
+				"GPU_WORKER_MACHINE_COUNT":  "2",
+				"GPU_INSTANCE_TYPE":         "p3.2xlarge",
+				"SPOT_WORKER_MACHINE_COUNT": "5",
+			},
+		},
+		{
+			name:    "fails for invalid pool names",
+			pools:   []WorkerPool{{Name: "GPU"}},
+			wantErr: true,
+		},
+		{
+			name:    "fails for duplicated pool names",
+			pools:   []WorkerPool{{Name: "gpu"}, {Name: "gpu"}},
+			wantErr: true,
+		},
+		{
+			name:    "fails for negative machine counts",
+			pools:   []WorkerPool{{Name: "gpu", MachineCount: pointer.Int64Ptr(-1)}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config1 := newFakeConfig().WithVar("WORKER_MACHINE_COUNT", "5")
+			client := newFakeClient(config1)
+
+			err := client.internalClient.workerPoolsToVariables(GetClusterTemplateOptions{WorkerPools: tt.pools})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for name, value := range tt.wantVars {
+				got, err := config1.Variables().Get(name)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(got).To(Equal(value))
+			}
+		})
+	}
+}
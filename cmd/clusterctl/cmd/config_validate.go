@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/config"
+)
+
+type configValidateOptions struct {
+	skipRepositoryChecks bool
+}
+
+var cvo = &configValidateOptions{}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Args:  cobra.NoArgs,
+	Short: "Validate the clusterctl configuration.",
+	Long: LongDesc(`
+		Validate the clusterctl configuration.
+
+		Checks the provider configurations, the provider aliases, the cert-manager configuration,
+		the image overrides and the overrides layer, reporting invalid values as errors and
+		unknown or duplicated keys as warnings; it also checks that the provider repositories
+		are reachable.
+
+		The command fails if at least one error is found.`),
+
+	Example: Examples(`
+		# Validate the clusterctl configuration.
+		clusterctl config validate
+
+		# Validate the clusterctl configuration without accessing the provider repositories.
+		clusterctl config validate --skip-repository-checks`),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runValidateConfig(cfgFile, os.Stdout)
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&cvo.skipRepositoryChecks, "skip-repository-checks", false,
+		"Skip checking that the provider repositories are reachable.")
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runValidateConfig(cfgFile string, out io.Writer) error {
+	c, err := client.New(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	findings, err := c.ValidateConfig(client.ValidateConfigOptions{
+		SkipRepositoryChecks: cvo.skipRepositoryChecks,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "The clusterctl configuration is valid.")
+		return nil
+	}
+
+	errorCount := 0
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tKEY\tMESSAGE")
+	for _, f := range findings {
+		if f.Severity == config.ErrorValidationSeverity {
+			errorCount++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Severity, f.Key, f.Message)
+	}
+	w.Flush()
+
+	if errorCount > 0 {
+		return errors.Errorf("the clusterctl configuration is not valid: %d error(s) found", errorCount)
+	}
+	return nil
+}
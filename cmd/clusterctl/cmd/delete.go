@@ -17,9 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/yaml"
 )
 
 type deleteOptions struct {
@@ -31,7 +35,10 @@ type deleteOptions struct {
 	infrastructureProviders []string
 	includeNamespace        bool
 	includeCRDs             bool
+	preserveNamespace       bool
 	deleteAll               bool
+	force                   bool
+	dryRun                  bool
 }
 
 var dd = &deleteOptions{}
@@ -66,6 +73,10 @@ var deleteCmd = &cobra.Command{
 		# the AWS infrastructure provider are orphaned and there might be ongoing costs incurred as a result of this.
 		clusterctl delete --infrastructure aws --include-crd
 
+		# Delete the AWS infrastructure provider and related CRDs, while preserving its hosting Namespace and
+		# the objects in it not belonging to the provider, e.g. when the namespace is shared with other tools.
+		clusterctl delete --infrastructure aws --preserve-namespace
+
 		# Delete the AWS infrastructure provider and its hosting Namespace. Please note that this forces deletion of
 		# all objects existing in the namespace.
 		# Important! As a consequence of this operation, all the corresponding resources managed by
@@ -75,7 +86,16 @@ var deleteCmd = &cobra.Command{
 		# Reset the management cluster to its original state
 		# Important! As a consequence of this operation all the corresponding resources on target clouds
 		# are "orphaned" and thus there may be ongoing costs incurred as a result of this.
-		clusterctl delete --all --include-crd  --include-namespace`),
+		clusterctl delete --all --include-crd  --include-namespace
+
+		# Prints the objects that are going to be deleted and the workload Clusters depending on the
+		# providers being deleted, without deleting anything.
+		clusterctl delete --all --include-crd  --include-namespace --dry-run
+
+		# Deletes the AWS infrastructure provider even if workload Clusters depending on it still exist.
+		# Important! As a consequence of this operation the machines of those Clusters are left running
+		# without a controller reconciling them.
+		clusterctl delete --infrastructure aws --force`),
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runDelete()
@@ -92,6 +112,8 @@ func init() {
 		"Forces the deletion of the namespace where the providers are hosted (and of all the contained objects)")
 	deleteCmd.Flags().BoolVar(&dd.includeCRDs, "include-crd", false,
 		"Forces the deletion of the provider's CRDs (and of all the related objects)")
+	deleteCmd.Flags().BoolVar(&dd.preserveNamespace, "preserve-namespace", false,
+		"Forces the deletion of the provider's CRDs (and of all the related objects) while preserving the namespace where the providers are hosted and the objects in it not belonging to the providers")
 
 	deleteCmd.Flags().StringVar(&dd.coreProvider, "core", "",
 		"Core provider version (e.g. cluster-api:v0.3.0) to delete from the management cluster")
@@ -104,6 +126,10 @@ func init() {
 
 	deleteCmd.Flags().BoolVar(&dd.deleteAll, "all", false,
 		"Force deletion of all the providers")
+	deleteCmd.Flags().BoolVar(&dd.force, "force", false,
+		"Force deletion of the providers even if workload Clusters depending on them still exist in the management cluster")
+	deleteCmd.Flags().BoolVar(&dd.dryRun, "dry-run", false,
+		"Print in yaml format the objects that are going to be deleted and the workload Clusters depending on the providers, without deleting anything.")
 
 	RootCmd.AddCommand(deleteCmd)
 }
@@ -127,14 +153,34 @@ func runDelete() error {
 		return errors.New("At least one of --core, --bootstrap, --control-plane, --infrastructure should be specified or the --all flag should be set")
 	}
 
-	return c.Delete(client.DeleteOptions{
+	options := client.DeleteOptions{
 		Kubeconfig:              client.Kubeconfig{Path: dd.kubeconfig, Context: dd.kubeconfigContext},
 		IncludeNamespace:        dd.includeNamespace,
 		IncludeCRDs:             dd.includeCRDs,
+		PreserveNamespace:       dd.preserveNamespace,
 		CoreProvider:            dd.coreProvider,
 		BootstrapProviders:      dd.bootstrapProviders,
 		InfrastructureProviders: dd.infrastructureProviders,
 		ControlPlaneProviders:   dd.controlPlaneProviders,
 		DeleteAll:               dd.deleteAll,
-	})
+		Force:                   dd.force,
+	}
+
+	if dd.dryRun {
+		report, err := c.DeleteDryRun(options)
+		if err != nil {
+			return err
+		}
+		y, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(y))
+		if len(report.DependentClusters) > 0 {
+			fmt.Fprintf(os.Stderr, "\nWARNING: %d workload Clusters depend on the providers being deleted and they will not be reconciled anymore; see dependentClusters for details.\n", len(report.DependentClusters))
+		}
+		return nil
+	}
+
+	return c.Delete(options)
 }
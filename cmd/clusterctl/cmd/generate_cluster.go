@@ -18,6 +18,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
@@ -33,13 +36,16 @@ type generateClusterOptions struct {
 	kubernetesVersion        string
 	controlPlaneMachineCount int64
 	workerMachineCount       int64
+	workerPools              []string
+	clusterClass             string
 
 	url                string
 	configMapNamespace string
 	configMapName      string
 	configMapDataKey   string
 
-	listVariables bool
+	listVariables      bool
+	includePrereleases bool
 }
 
 var gc = &generateClusterOptions{}
@@ -84,6 +90,9 @@ var generateClusterClusterCmd = &cobra.Command{
 		# Generates a yaml file for creating workload clusters using a template stored locally.
 		clusterctl generate cluster my-cluster --from ~/workspace/cluster-template.yaml
 
+		# Generates a yaml file for creating workload clusters using a template read from stdin.
+		cat ~/workspace/cluster-template.yaml | clusterctl generate cluster my-cluster --from -
+
 		# Prints the list of variables required by the yaml file for creating workload cluster.
 		clusterctl generate cluster my-cluster --list-variables`),
 
@@ -108,6 +117,11 @@ func init() {
 		"The number of control plane machines for the workload cluster.")
 	generateClusterClusterCmd.Flags().Int64Var(&gc.workerMachineCount, "worker-machine-count", 0,
 		"The number of worker machines for the workload cluster.")
+	generateClusterClusterCmd.Flags().StringSliceVar(&gc.workerPools, "worker-pool", nil,
+		"A worker pool to add to the workload cluster, in the format name[=machine-count]; can be repeated for adding many pools. Each pool gets a copy of the MachineDeployments in the template, using variables prefixed with the uppercased pool name.")
+
+	generateClusterClusterCmd.Flags().StringVar(&gc.clusterClass, "cluster-class", "",
+		"The ClusterClass existing in the target namespace to be used by the workload cluster. If set, only the Cluster with a managed topology defined by the template is generated, instead of all the objects defined by the template.")
 
 	// flags for the repository source
 	generateClusterClusterCmd.Flags().StringVarP(&gc.infrastructureProvider, "infrastructure", "i", "",
@@ -117,7 +131,7 @@ func init() {
 
 	// flags for the url source
 	generateClusterClusterCmd.Flags().StringVar(&gc.url, "from", "",
-		"The URL to read the workload cluster template from. If unspecified, the infrastructure provider repository URL will be used")
+		"The URL to read the workload cluster template from, or - to read it from stdin. If unspecified, the infrastructure provider repository URL will be used")
 
 	// flags for the config map source
 	generateClusterClusterCmd.Flags().StringVar(&gc.configMapName, "from-config-map", "",
@@ -130,6 +144,8 @@ func init() {
 	// other flags
 	generateClusterClusterCmd.Flags().BoolVar(&gc.listVariables, "list-variables", false,
 		"Returns the list of variables expected by the template instead of the template yaml")
+	generateClusterClusterCmd.Flags().BoolVar(&gc.includePrereleases, "include-prereleases", false,
+		"Consider prerelease versions of the infrastructure provider (e.g. release candidates) when the version is a version range.")
 
 	generateCmd.AddCommand(generateClusterClusterCmd)
 }
@@ -141,11 +157,13 @@ func runGenerateClusterTemplate(cmd *cobra.Command, name string) error {
 	}
 
 	templateOptions := client.GetClusterTemplateOptions{
-		Kubeconfig:        client.Kubeconfig{Path: gc.kubeconfig, Context: gc.kubeconfigContext},
-		ClusterName:       name,
-		TargetNamespace:   gc.targetNamespace,
-		KubernetesVersion: gc.kubernetesVersion,
-		ListVariablesOnly: gc.listVariables,
+		Kubeconfig:         client.Kubeconfig{Path: gc.kubeconfig, Context: gc.kubeconfigContext},
+		ClusterName:        name,
+		TargetNamespace:    gc.targetNamespace,
+		KubernetesVersion:  gc.kubernetesVersion,
+		ListVariablesOnly:  gc.listVariables,
+		IncludePrereleases: gc.includePrereleases,
+		ClusterClass:       gc.clusterClass,
 	}
 
 	if cmd.Flags().Changed("control-plane-machine-count") {
@@ -154,8 +172,19 @@ func runGenerateClusterTemplate(cmd *cobra.Command, name string) error {
 	if cmd.Flags().Changed("worker-machine-count") {
 		templateOptions.WorkerMachineCount = &gc.workerMachineCount
 	}
+	for _, p := range gc.workerPools {
+		pool, err := parseWorkerPool(p)
+		if err != nil {
+			return err
+		}
+		templateOptions.WorkerPools = append(templateOptions.WorkerPools, pool)
+	}
 
-	if gc.url != "" {
+	if gc.url == "-" {
+		templateOptions.RawSource = &client.RawSourceOptions{
+			Reader: os.Stdin,
+		}
+	} else if gc.url != "" {
 		templateOptions.URLSource = &client.URLSourceOptions{
 			URL: gc.url,
 		}
@@ -187,3 +216,21 @@ func runGenerateClusterTemplate(cmd *cobra.Command, name string) error {
 
 	return printYamlOutput(template)
 }
+
+// parseWorkerPool parses a worker pool in the format name[=machine-count].
+func parseWorkerPool(value string) (client.WorkerPool, error) {
+	name, count := value, ""
+	if i := strings.Index(value, "="); i >= 0 {
+		name, count = value[:i], value[i+1:]
+	}
+
+	pool := client.WorkerPool{Name: name}
+	if count != "" {
+		i, err := strconv.ParseInt(count, 10, 64)
+		if err != nil {
+			return client.WorkerPool{}, fmt.Errorf("invalid machine count %q for the worker pool %q", count, name)
+		}
+		pool.MachineCount = &i
+	}
+	return pool, nil
+}
@@ -17,11 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client/repository"
 )
 
 type generateProvidersOptions struct {
@@ -32,6 +35,10 @@ type generateProvidersOptions struct {
 	targetNamespace        string
 	textOutput             bool
 	raw                    bool
+	listVariables          bool
+	categories             []string
+	extraLabels            map[string]string
+	extraAnnotations       map[string]string
 }
 
 var gpo = &generateProvidersOptions{}
@@ -66,7 +73,14 @@ var generateProviderCmd = &cobra.Command{
 
 		# Generates a yaml file for creating provider for a specific version.
 		# No variables will be processed and substituted using this flag
-		clusterctl generate provider --infrastructure aws:v0.4.1 --raw`),
+		clusterctl generate provider --infrastructure aws:v0.4.1 --raw
+
+		# Generates a yaml file for creating provider without the CRDs, e.g. for managing
+		# CRDs and controllers in separate GitOps sync waves.
+		clusterctl generate provider --infrastructure aws --category namespace,rbac,controller
+
+		# Prints the list of variables required by the provider components, e.g. for setting them before clusterctl init.
+		clusterctl generate provider --infrastructure aws --list-variables`),
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runGenerateProviderComponents()
@@ -88,6 +102,15 @@ func init() {
 		"Generate configuration without variable substitution.")
 	generateProviderCmd.Flags().BoolVar(&gpo.raw, "raw", false,
 		"Generate configuration without variable substitution in a yaml format.")
+	generateProviderCmd.Flags().BoolVar(&gpo.listVariables, "list-variables", false,
+		"Returns the list of variables expected by the provider components, distinguishing required variables from optional ones having a default value.")
+	generateProviderCmd.Flags().StringSliceVar(&gpo.categories, "category", nil,
+		"Generate only the components belonging to the given categories (crds, namespace, rbac, controller). If unspecified, all the components are generated.")
+
+	generateProviderCmd.Flags().StringToStringVar(&gpo.extraLabels, "extra-labels", nil,
+		"Labels to be applied to all the provider components (e.g. cost-center=123). Labels reserved for clusterctl can't be used.")
+	generateProviderCmd.Flags().StringToStringVar(&gpo.extraAnnotations, "extra-annotations", nil,
+		"Annotations to be applied to all the provider components (e.g. owner=team-a). Annotations reserved for clusterctl can't be used.")
 
 	generateCmd.AddCommand(generateProviderCmd)
 }
@@ -105,6 +128,24 @@ func runGenerateProviderComponents() error {
 	options := client.ComponentsOptions{
 		TargetNamespace:     gpo.targetNamespace,
 		SkipTemplateProcess: gpo.raw,
+		ExtraLabels:         gpo.extraLabels,
+		ExtraAnnotations:    gpo.extraAnnotations,
+	}
+	for _, category := range gpo.categories {
+		options.Categories = append(options.Categories, repository.ComponentsCategory(category))
+	}
+
+	if gpo.listVariables {
+		variables, err := c.GetProviderVariables(providerName, providerType, options)
+		if err != nil {
+			return err
+		}
+		variableMap := make(map[string]*string, len(variables))
+		for _, v := range variables {
+			variableMap[v.Name] = v.Default
+		}
+		printVariables(os.Stdout, quoteVariableDefaults(variableMap))
+		return nil
 	}
 
 	components, err := c.GetProviderComponents(providerName, providerType, options)
@@ -20,14 +20,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
 )
 
 type generateYAMLOptions struct {
-	url           string
-	listVariables bool
+	url              string
+	variablesFile    string
+	listVariables    bool
+	resolveVariables bool
+	showSecrets      bool
 }
 
 var gyOpts = &generateYAMLOptions{}
@@ -58,6 +62,9 @@ var generateYamlCmd = &cobra.Command{
 
 		# Prints list of variables from template passed in via stdin
 		cat ~/workspace/cluster-template.yaml | clusterctl generate yaml --list-variables
+
+		# Prints the value and the source of each variable used in the local template
+		clusterctl generate yaml --from ~/workspace/cluster-template.yaml --resolve-variables
 `),
 
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -70,9 +77,16 @@ func init() {
 	generateYamlCmd.Flags().StringVar(&gyOpts.url, "from", "-",
 		"The URL to read the template from. It defaults to '-' which reads from stdin.")
 
+	generateYamlCmd.Flags().StringVar(&gyOpts.variablesFile, "variables-file", "",
+		"Path to a file defining values for the template variables, using the dotenv format or a YAML map. Environment variables take precedence over values from this file, which take precedence over values from the clusterctl config file.")
+
 	// other flags
 	generateYamlCmd.Flags().BoolVar(&gyOpts.listVariables, "list-variables", false,
 		"Returns the list of variables expected by the template instead of the template yaml")
+	generateYamlCmd.Flags().BoolVar(&gyOpts.resolveVariables, "resolve-variables", false,
+		"Returns the value and the source of each variable expected by the template instead of the template yaml")
+	generateYamlCmd.Flags().BoolVar(&gyOpts.showSecrets, "show-secrets", false,
+		"Shows the value of sensitive variables, e.g. passwords or tokens, when used with --resolve-variables")
 
 	generateCmd.AddCommand(generateYamlCmd)
 }
@@ -84,6 +98,7 @@ func generateYAML(r io.Reader, w io.Writer) error {
 	}
 	options := client.ProcessYAMLOptions{
 		SkipTemplateProcess: gyOpts.listVariables,
+		VariablesFile:       gyOpts.variablesFile,
 	}
 	if gyOpts.url != "" {
 		if gyOpts.url == "-" {
@@ -96,19 +111,21 @@ func generateYAML(r io.Reader, w io.Writer) error {
 			}
 		}
 	}
+	if gyOpts.resolveVariables {
+		options.ShowSecrets = gyOpts.showSecrets
+		variables, err := c.ResolveYAMLVariables(options)
+		if err != nil {
+			return err
+		}
+		printResolvedVariables(w, variables)
+		return nil
+	}
 	printer, err := c.ProcessYAML(options)
 	if err != nil {
 		return err
 	}
 	if gyOpts.listVariables {
-		if len(printer.Variables()) > 0 {
-			fmt.Fprintln(w, "Variables:")
-			for _, v := range printer.Variables() {
-				fmt.Fprintf(w, "  - %s\n", v)
-			}
-		} else {
-			fmt.Fprintln(w)
-		}
+		printVariables(w, quoteVariableDefaults(printer.VariableMap()))
 		return nil
 	}
 	out, err := printer.Yaml()
@@ -118,3 +135,17 @@ func generateYAML(r io.Reader, w io.Writer) error {
 	_, err = fmt.Fprintln(w, string(out))
 	return err
 }
+
+// printResolvedVariables prints the value and the source of each variable.
+func printResolvedVariables(w io.Writer, variables []client.ResolvedVariable) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE")
+	for _, v := range variables {
+		source := string(v.Source)
+		if !v.IsSet {
+			source = "not set"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, v.Value, source)
+	}
+	tw.Flush()
+}
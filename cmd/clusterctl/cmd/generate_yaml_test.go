@@ -40,6 +40,10 @@ v3: ${VAR3:-default3}`
 v2: bazfoo`)
 	defer cleanup2()
 
+	templateWithRequiredVars, cleanup3 := createTempFile(g, `v1: ${VAR1:=default1}
+v2: ${VAR2}`)
+	defer cleanup3()
+
 	inputReader := strings.NewReader(contents)
 
 	tests := []struct {
@@ -62,12 +66,39 @@ v3: default3
 			name:      "prints variables using --list-variables flag",
 			options:   &generateYAMLOptions{url: template, listVariables: true},
 			expectErr: false,
-			expectedOutput: `Variables:
-  - VAR1
+			expectedOutput: `Optional Variables:
+  - VAR1  (defaults to "default1")
+  - VAR2  (defaults to "default2")
+  - VAR3  (defaults to "default3")
+
+`,
+		},
+		{
+			name:      "prints required and optional variables using --list-variables flag",
+			options:   &generateYAMLOptions{url: templateWithRequiredVars, listVariables: true},
+			expectErr: false,
+			expectedOutput: `Required Variables:
   - VAR2
-  - VAR3
+
+Optional Variables:
+  - VAR1  (defaults to "default1")
+
 `,
 		},
+		{
+			name:      "prints variables values and sources using --resolve-variables flag",
+			options:   &generateYAMLOptions{url: templateWithRequiredVars, resolveVariables: true},
+			expectErr: false,
+			expectedOutput: `NAME  VALUE     SOURCE
+VAR1  default1  default
+VAR2            not set
+`,
+		},
+		{
+			name:      "returns error if a required variable is not set",
+			options:   &generateYAMLOptions{url: templateWithRequiredVars},
+			expectErr: true,
+		},
 		{
 			name:      "returns error for bad templateFile path",
 			options:   &generateYAMLOptions{url: "/tmp/do-not-exist", listVariables: true},
@@ -27,6 +27,12 @@ type getKubeconfigOptions struct {
 	kubeconfig        string
 	kubeconfigContext string
 	namespace         string
+	contextName       string
+	merge             bool
+	overwrite         bool
+	secretName        string
+	secretNamespace   string
+	secretKey         string
 }
 
 var gk = &getKubeconfigOptions{}
@@ -42,7 +48,13 @@ var getKubeconfigCmd = &cobra.Command{
 		clusterctl get kubeconfig <name of workload cluster>
 
 		# Get the workload cluster's kubeconfig in a particular namespace.
-		clusterctl get kubeconfig <name of workload cluster> --namespace foo`),
+		clusterctl get kubeconfig <name of workload cluster> --namespace foo
+
+		# Merge the workload cluster's kubeconfig into the default kubeconfig file, using a custom context name.
+		clusterctl get kubeconfig <name of workload cluster> --context-name foo --merge
+
+		# Get the workload cluster's kubeconfig from a Secret with a non-conventional name, e.g. published by a managed control plane provider.
+		clusterctl get kubeconfig <name of workload cluster> --secret-name foo-user-kubeconfig --secret-key value`),
 
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -57,6 +69,18 @@ func init() {
 		"Path to the kubeconfig file to use for accessing the management cluster. If unspecified, default discovery rules apply.")
 	getKubeconfigCmd.Flags().StringVar(&gk.kubeconfigContext, "kubeconfig-context", "",
 		"Context to be used within the kubeconfig file. If empty, current context will be used.")
+	getKubeconfigCmd.Flags().StringVar(&gk.contextName, "context-name", "",
+		"Name to be used for the context, the cluster and the user in the workload cluster's kubeconfig. If empty, the names generated by Cluster API will be used.")
+	getKubeconfigCmd.Flags().BoolVar(&gk.merge, "merge", false,
+		"Merge the workload cluster's kubeconfig into the kubeconfig file used for accessing the management cluster instead of printing it.")
+	getKubeconfigCmd.Flags().BoolVar(&gk.overwrite, "overwrite", false,
+		"Replace the clusters, users and contexts with the same name already existing in the kubeconfig file, if --merge is set. If not set, name collisions are an error.")
+	getKubeconfigCmd.Flags().StringVar(&gk.secretName, "secret-name", "",
+		"Name of the Secret storing the workload cluster's kubeconfig. If empty, the <name of workload cluster>-kubeconfig Secret will be used.")
+	getKubeconfigCmd.Flags().StringVar(&gk.secretNamespace, "secret-namespace", "",
+		"Namespace of the Secret storing the workload cluster's kubeconfig, if --secret-name is set. If empty, the namespace of the workload cluster will be used.")
+	getKubeconfigCmd.Flags().StringVar(&gk.secretKey, "secret-key", "",
+		"Key of the Secret storing the workload cluster's kubeconfig, if --secret-name is set. If empty, the \"value\" key will be used.")
 	getCmd.AddCommand(getKubeconfigCmd)
 }
 
@@ -70,12 +94,22 @@ func runGetKubeconfig(workloadClusterName string) error {
 		Kubeconfig:          client.Kubeconfig{Path: gk.kubeconfig, Context: gk.kubeconfigContext},
 		WorkloadClusterName: workloadClusterName,
 		Namespace:           gk.namespace,
+		ContextName:         gk.contextName,
+		Merge:               gk.merge,
+		Overwrite:           gk.overwrite,
+		SecretName:          gk.secretName,
+		SecretNamespace:     gk.secretNamespace,
+		SecretKey:           gk.secretKey,
 	}
 
 	out, err := c.GetKubeconfig(options)
 	if err != nil {
 		return err
 	}
+	if gk.merge {
+		fmt.Printf("Kubeconfig of the %q workload cluster merged into %q\n", workloadClusterName, out)
+		return nil
+	}
 	fmt.Println(out)
 	return nil
 }
@@ -18,8 +18,10 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
 )
 
@@ -31,7 +33,18 @@ type initOptions struct {
 	controlPlaneProviders   []string
 	infrastructureProviders []string
 	targetNamespace         string
+	specFile                string
+	localProviderPaths      map[string]string
+	extraLabels             map[string]string
+	extraAnnotations        map[string]string
+	skipCertManager         bool
+	certManagerVersion      string
 	listImages              bool
+	listImagesProviderTypes []string
+	listImagesProviders     []string
+	waitProviders           bool
+	waitProviderTimeout     time.Duration
+	includePrereleases      bool
 }
 
 var initOpts = &initOptions{}
@@ -75,6 +88,9 @@ var initCmd = &cobra.Command{
 		# Initialize a management cluster with a custom target namespace for the provider resources.
 		clusterctl init --infrastructure aws --target-namespace foo
 
+		# Initialize a management cluster with the providers, versions and variables defined in a spec file.
+		clusterctl init --spec-file init.yaml
+
 		# Lists the container images required for initializing the management cluster.
 		#
 		# Note: This command is a dry-run; it won't perform any action other than printing to screen.
@@ -100,10 +116,33 @@ func init() {
 		"Control plane providers and versions (e.g. kubeadm:v0.3.0) to add to the management cluster. If unspecified, the Kubeadm control plane provider's latest release is used.")
 	initCmd.Flags().StringVar(&initOpts.targetNamespace, "target-namespace", "",
 		"The target namespace where the providers should be deployed. If unspecified, the provider components' default namespace is used.")
+	initCmd.Flags().StringVar(&initOpts.specFile, "spec-file", "",
+		"Path of a YAML file defining the providers to add to the management cluster, with their versions, target namespaces and variables. It can't be used together with the flags defining providers or the target namespace.")
+	initCmd.Flags().StringToStringVar(&initOpts.localProviderPaths, "local-provider-path", nil,
+		"Path of the components YAML to be used instead of the provider repository, indexed by provider label (e.g. infrastructure-aws=/home/user/repo/infrastructure-aws/v0.5.2/infrastructure-components.yaml).")
+	initCmd.Flags().StringToStringVar(&initOpts.extraLabels, "extra-labels", nil,
+		"Labels to be applied to all the provider components (e.g. cost-center=123). Labels reserved for clusterctl can't be used.")
+	initCmd.Flags().StringToStringVar(&initOpts.extraAnnotations, "extra-annotations", nil,
+		"Annotations to be applied to all the provider components (e.g. owner=team-a). Annotations reserved for clusterctl can't be used.")
+	initCmd.Flags().BoolVar(&initOpts.skipCertManager, "skip-cert-manager", false,
+		"Skip the installation of cert-manager, e.g. because it is managed outside of clusterctl. A working cert-manager must be installed in the cluster.")
+	initCmd.Flags().StringVar(&initOpts.certManagerVersion, "cert-manager-version", "",
+		"The cert-manager version (e.g. v1.1.0) to install, if cert-manager is not already installed. If empty, the version defined in the clusterctl configuration or the clusterctl default is used.")
+
+	initCmd.Flags().BoolVar(&initOpts.waitProviders, "wait-providers", false,
+		"Wait for the provider controllers to be available, the provider CRDs to be established and the provider webhooks to be serving.")
+	initCmd.Flags().DurationVar(&initOpts.waitProviderTimeout, "wait-provider-timeout", 5*time.Minute,
+		"Time to wait for the providers to be ready, if --wait-providers is set.")
+	initCmd.Flags().BoolVar(&initOpts.includePrereleases, "include-prereleases", false,
+		"Consider prerelease provider versions (e.g. release candidates) when resolving the latest version or a version range. Intended for testing upcoming releases only.")
 
 	// TODO: Move this to a sub-command or similar, it shouldn't really be a flag.
 	initCmd.Flags().BoolVar(&initOpts.listImages, "list-images", false,
 		"Lists the container images required for initializing the management cluster (without actually installing the providers)")
+	initCmd.Flags().StringSliceVar(&initOpts.listImagesProviderTypes, "list-images-provider-type", nil,
+		"Lists only the images of the providers of the given types (e.g. InfrastructureProvider), if --list-images is set.")
+	initCmd.Flags().StringSliceVar(&initOpts.listImagesProviders, "list-images-provider", nil,
+		"Lists only the images of the given providers (e.g. aws or infrastructure-aws, or cert-manager for the cert-manager images), if --list-images is set.")
 
 	RootCmd.AddCommand(initCmd)
 }
@@ -121,10 +160,24 @@ func runInit() error {
 		ControlPlaneProviders:   initOpts.controlPlaneProviders,
 		InfrastructureProviders: initOpts.infrastructureProviders,
 		TargetNamespace:         initOpts.targetNamespace,
+		SpecFile:                initOpts.specFile,
+		LocalProviderPaths:      initOpts.localProviderPaths,
+		ExtraLabels:             initOpts.extraLabels,
+		ExtraAnnotations:        initOpts.extraAnnotations,
+		SkipCertManager:         initOpts.skipCertManager,
+		CertManagerVersion:      initOpts.certManagerVersion,
+		WaitForReady:            initOpts.waitProviders,
+		WaitForReadyTimeout:     initOpts.waitProviderTimeout,
+		IncludePrereleases:      initOpts.includePrereleases,
 		LogUsageInstructions:    true,
 	}
 
 	if initOpts.listImages {
+		for _, t := range initOpts.listImagesProviderTypes {
+			options.ImagesProviderTypes = append(options.ImagesProviderTypes, clusterctlv1.ProviderType(t))
+		}
+		options.ImagesProviders = initOpts.listImagesProviders
+
 		images, err := c.InitImages(options)
 		if err != nil {
 			return err
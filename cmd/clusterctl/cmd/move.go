@@ -17,8 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+	"regexp"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
 )
 
@@ -29,6 +34,17 @@ type moveOptions struct {
 	toKubeconfigContext   string
 	namespace             string
 	dryRun                bool
+	resume                bool
+	selector              string
+	excludeKinds          []string
+	controlPlaneOnly      bool
+	preserveStatus        bool
+	toNamespace           string
+	apiGroupMappings      map[string]string
+	toDirectory           string
+	fromDirectory         string
+	redactSecrets         bool
+	encryptionKeyFile     string
 }
 
 var mo = &moveOptions{}
@@ -43,7 +59,25 @@ var moveCmd = &cobra.Command{
 
 	Example: Examples(`
 		Move Cluster API objects and all dependencies between management clusters.
-		clusterctl move --to-kubeconfig=target-kubeconfig.yaml`),
+		clusterctl move --to-kubeconfig=target-kubeconfig.yaml
+
+		Move only the Clusters labeled env=staging and all their dependencies between management clusters.
+		clusterctl move --to-kubeconfig=target-kubeconfig.yaml --selector env=staging
+
+		Move Cluster API objects and all dependencies, except the ClusterResourceSets and their bindings.
+		clusterctl move --to-kubeconfig=target-kubeconfig.yaml --exclude-kind ClusterResourceSet.addons.cluster.x-k8s.io --exclude-kind ClusterResourceSetBinding.addons.cluster.x-k8s.io
+
+		Move Cluster API objects and all dependencies, creating the objects of a renamed API group using the new API group.
+		clusterctl move --to-kubeconfig=target-kubeconfig.yaml --api-group-mapping infrastructure.example.com=infrastructure.cluster.x-k8s.io
+
+		Move only the control plane objects of the Clusters, leaving the other objects paused in the source management cluster.
+		clusterctl move --to-kubeconfig=target-kubeconfig.yaml --control-plane-only
+
+		Save Cluster API objects and all dependencies to a directory.
+		clusterctl move --to-directory=/tmp/backup
+
+		Restore Cluster API objects and all dependencies from a directory.
+		clusterctl move --from-directory=/tmp/backup --to-kubeconfig=target-kubeconfig.yaml`),
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runMove()
@@ -62,26 +96,114 @@ func init() {
 	moveCmd.Flags().StringVarP(&mo.namespace, "namespace", "n", "",
 		"The namespace where the workload cluster is hosted. If unspecified, the current context's namespace is used.")
 	moveCmd.Flags().BoolVar(&mo.dryRun, "dry-run", false,
-		"Enable dry run, don't really perform the move actions")
+		"Enable dry run, don't really perform the move actions. If --to-kubeconfig is specified, conflicts with the objects existing in the destination management cluster are reported.")
+	moveCmd.Flags().BoolVar(&mo.resume, "resume", false,
+		"Resume a move operation previously failed, skipping the objects already moved to the destination management cluster.")
+	moveCmd.Flags().StringVarP(&mo.selector, "selector", "l", "",
+		"Label selector for restricting the move to the matching Cluster API objects (e.g. Clusters) and all the objects belonging to them. If unspecified, all the objects in the namespace are moved.")
+
+	moveCmd.Flags().StringSliceVar(&mo.excludeKinds, "exclude-kind", nil,
+		"Kind of the objects not to be moved, in the Kind.version.group or Kind.group format (e.g. ClusterResourceSet.addons.cluster.x-k8s.io). The move fails if an excluded object owns any of the objects to be moved.")
+	moveCmd.Flags().BoolVar(&mo.controlPlaneOnly, "control-plane-only", false,
+		"Move only the control plane objects of the Clusters, copying the Clusters and leaving them paused, together with the other objects, in the source management cluster. Intended for disaster recovery only.")
+	moveCmd.Flags().BoolVar(&mo.preserveStatus, "preserve-status", false,
+		"Restore the status of the Cluster API objects after creating them in the destination management cluster, so the controllers can resume from the known state.")
+	moveCmd.Flags().StringVar(&mo.toNamespace, "to-namespace", "",
+		"The namespace where the Cluster API objects are moved in the destination management cluster. If unspecified, objects keep the namespace they have in the source management cluster.")
+	moveCmd.Flags().StringToStringVar(&mo.apiGroupMappings, "api-group-mapping", nil,
+		"Mapping of an API group of the source management cluster to an equivalent API group of the destination management cluster, in the source=target format (e.g. infrastructure.example.com=infrastructure.cluster.x-k8s.io). Objects of the mapped API groups, and the references to them, are moved using the target API group.")
+	moveCmd.Flags().StringVar(&mo.toDirectory, "to-directory", "",
+		"Save the Cluster API objects to a directory instead of moving them to the destination management cluster.")
+	moveCmd.Flags().StringVar(&mo.fromDirectory, "from-directory", "",
+		"Restore the Cluster API objects previously saved to a directory into the destination management cluster.")
+	moveCmd.Flags().BoolVar(&mo.redactSecrets, "redact-secrets", false,
+		"Remove the data of the Secrets when saving the Cluster API objects to a directory. Redacted Secrets are not restored.")
+	moveCmd.Flags().StringVar(&mo.encryptionKeyFile, "encryption-key-file", "",
+		"Path to a file containing the key used for encrypting the data of the Secrets when saving the Cluster API objects to a directory, and for decrypting them when restoring.")
 
 	RootCmd.AddCommand(moveCmd)
 }
 
 func runMove() error {
-	// if no to kubeconfig provided and it's not a dry run, return error
-	if mo.toKubeconfig == "" && !mo.dryRun {
+	if mo.toDirectory != "" && mo.fromDirectory != "" {
+		return errors.New("please specify only one of the --to-directory and --from-directory flags")
+	}
+
+	// if no to kubeconfig provided and it's not a dry run or a backup, return error
+	if mo.toKubeconfig == "" && !mo.dryRun && mo.toDirectory == "" {
 		return errors.New("please specify a target cluster using the --to-kubeconfig flag")
 	}
 
+	encryptionKey := ""
+	if mo.encryptionKeyFile != "" {
+		key, err := os.ReadFile(mo.encryptionKeyFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the encryption key from %q", mo.encryptionKeyFile)
+		}
+		encryptionKey = strings.TrimSpace(string(key))
+	}
+
+	excludedKinds := []schema.GroupVersionKind{}
+	for _, k := range mo.excludeKinds {
+		gvk, err := parseExcludedKind(k)
+		if err != nil {
+			return err
+		}
+		excludedKinds = append(excludedKinds, gvk)
+	}
+
+	scope := client.AllMoveScope
+	if mo.controlPlaneOnly {
+		scope = client.ControlPlaneMoveScope
+	}
+
 	c, err := client.New(cfgFile)
 	if err != nil {
 		return err
 	}
 
 	return c.Move(client.MoveOptions{
-		FromKubeconfig: client.Kubeconfig{Path: mo.fromKubeconfig, Context: mo.fromKubeconfigContext},
-		ToKubeconfig:   client.Kubeconfig{Path: mo.toKubeconfig, Context: mo.toKubeconfigContext},
-		Namespace:      mo.namespace,
-		DryRun:         mo.dryRun,
+		FromKubeconfig:   client.Kubeconfig{Path: mo.fromKubeconfig, Context: mo.fromKubeconfigContext},
+		ToKubeconfig:     client.Kubeconfig{Path: mo.toKubeconfig, Context: mo.toKubeconfigContext},
+		Namespace:        mo.namespace,
+		DryRun:           mo.dryRun,
+		Resume:           mo.resume,
+		LabelSelector:    mo.selector,
+		ExcludedKinds:    excludedKinds,
+		Scope:            scope,
+		PreserveStatus:   mo.preserveStatus,
+		ToNamespace:      mo.toNamespace,
+		APIGroupMappings: mo.apiGroupMappings,
+		ToDirectory:      mo.toDirectory,
+		FromDirectory:    mo.fromDirectory,
+		RedactSecrets:    mo.redactSecrets,
+		EncryptionKey:    encryptionKey,
 	})
 }
+
+// kindVersionRegexp matches Kubernetes API versions, e.g. v1 or v1alpha4.
+var kindVersionRegexp = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// parseExcludedKind parses a kind in the Kind.version.group or Kind.group format, e.g. ClusterResourceSet.addons.cluster.x-k8s.io;
+// kinds of the core group are specified as Kind or Kind.v1.
+func parseExcludedKind(s string) (schema.GroupVersionKind, error) {
+	parts := strings.SplitN(s, ".", 2)
+	gvk := schema.GroupVersionKind{Kind: parts[0]}
+	if gvk.Kind == "" {
+		return schema.GroupVersionKind{}, errors.Errorf("invalid kind %q", s)
+	}
+	if len(parts) == 1 {
+		return gvk, nil
+	}
+
+	groupParts := strings.SplitN(parts[1], ".", 2)
+	if kindVersionRegexp.MatchString(groupParts[0]) {
+		gvk.Version = groupParts[0]
+		if len(groupParts) == 2 {
+			gvk.Group = groupParts[1]
+		}
+		return gvk, nil
+	}
+	gvk.Group = parts[1]
+	return gvk, nil
+}
@@ -75,15 +75,6 @@ var RootCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "\033[33m%s\033[0m", output)
 		}
 
-		// clean the downloaded config if was fetched from remote
-		downloadConfigFile := filepath.Join(homedir.HomeDir(), config.ConfigFolder, config.DownloadConfigFile)
-		if _, err := os.Stat(downloadConfigFile); err == nil {
-			if verbosity != nil && *verbosity >= 5 {
-				fmt.Fprintf(os.Stdout, "Removing downloaded clusterctl config file: %s\n", config.DownloadConfigFile)
-			}
-			_ = os.Remove(downloadConfigFile)
-		}
-
 		return nil
 	},
 }
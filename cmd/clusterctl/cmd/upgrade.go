@@ -35,6 +35,7 @@ var upgradeCmd = &cobra.Command{
 func init() {
 	upgradeCmd.AddCommand(upgradePlanCmd)
 	upgradeCmd.AddCommand(upgradeApplyCmd)
+	upgradeCmd.AddCommand(upgradeRollbackCmd)
 	RootCmd.AddCommand(upgradeCmd)
 }
 
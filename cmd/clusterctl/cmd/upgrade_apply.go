@@ -17,10 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+	"sigs.k8s.io/yaml"
 )
 
 type upgradeApplyOptions struct {
@@ -31,6 +35,11 @@ type upgradeApplyOptions struct {
 	bootstrapProviders      []string
 	controlPlaneProviders   []string
 	infrastructureProviders []string
+	certManagerVersion      string
+	dryRun                  bool
+	waitProviders           bool
+	waitProviderTimeout     time.Duration
+	includePrereleases      bool
 }
 
 var ua = &upgradeApplyOptions{}
@@ -50,7 +59,13 @@ var upgradeApplyCmd = &cobra.Command{
 		clusterctl upgrade apply --contract v1alpha4
 
 		# Upgrades only the capa-system/aws provider to the v0.5.0 version.
-		clusterctl upgrade apply --infrastructure capa-system/aws:v0.5.0`),
+		clusterctl upgrade apply --infrastructure capa-system/aws:v0.5.0
+
+		# Upgrades only the capa-system/aws provider to the latest version available for the current API Version of Cluster API (contract).
+		clusterctl upgrade apply --infrastructure capa-system/aws
+
+		# Prints the changes the upgrade is going to apply to the provider CRDs and Deployments, without applying them.
+		clusterctl upgrade apply --contract v1alpha4 --dry-run`),
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runUpgradeApply()
@@ -66,13 +81,23 @@ func init() {
 		"The API Version of Cluster API (contract, e.g. v1alpha4) the management cluster should upgrade to")
 
 	upgradeApplyCmd.Flags().StringVar(&ua.coreProvider, "core", "",
-		"Core provider instance version (e.g. capi-system/cluster-api:v0.3.0) to upgrade to. This flag can be used as alternative to --contract.")
+		"Core provider instance version (e.g. capi-system/cluster-api:v0.3.0) to upgrade to. If the version is omitted, the latest version for the current contract is used. This flag can be used as alternative to --contract.")
 	upgradeApplyCmd.Flags().StringSliceVarP(&ua.infrastructureProviders, "infrastructure", "i", nil,
-		"Infrastructure providers instance and versions (e.g. capa-system/aws:v0.5.0) to upgrade to. This flag can be used as alternative to --contract.")
+		"Infrastructure providers instance and versions (e.g. capa-system/aws:v0.5.0) to upgrade to. If the version is omitted, the latest version for the current contract is used. This flag can be used as alternative to --contract.")
 	upgradeApplyCmd.Flags().StringSliceVarP(&ua.bootstrapProviders, "bootstrap", "b", nil,
-		"Bootstrap providers instance and versions (e.g. capi-kubeadm-bootstrap-system/kubeadm:v0.3.0) to upgrade to. This flag can be used as alternative to --contract.")
+		"Bootstrap providers instance and versions (e.g. capi-kubeadm-bootstrap-system/kubeadm:v0.3.0) to upgrade to. If the version is omitted, the latest version for the current contract is used. This flag can be used as alternative to --contract.")
 	upgradeApplyCmd.Flags().StringSliceVarP(&ua.controlPlaneProviders, "control-plane", "c", nil,
-		"ControlPlane providers instance and versions (e.g. capi-kubeadm-control-plane-system/kubeadm:v0.3.0) to upgrade to. This flag can be used as alternative to --contract.")
+		"ControlPlane providers instance and versions (e.g. capi-kubeadm-control-plane-system/kubeadm:v0.3.0) to upgrade to. If the version is omitted, the latest version for the current contract is used. This flag can be used as alternative to --contract.")
+	upgradeApplyCmd.Flags().StringVar(&ua.certManagerVersion, "cert-manager-version", "",
+		"The cert-manager version (e.g. v1.1.0) to upgrade to, if cert-manager is managed by clusterctl. If empty, the version defined in the clusterctl configuration or the clusterctl default is used.")
+	upgradeApplyCmd.Flags().BoolVar(&ua.dryRun, "dry-run", false,
+		"Print in yaml format the changes to the provider CRDs and Deployments, without applying them.")
+	upgradeApplyCmd.Flags().BoolVar(&ua.waitProviders, "wait-providers", false,
+		"Wait for the controllers of the upgraded providers to be available, their CRDs to be established and their webhooks to be serving.")
+	upgradeApplyCmd.Flags().DurationVar(&ua.waitProviderTimeout, "wait-provider-timeout", 5*time.Minute,
+		"Time to wait for the upgraded providers to be ready, if --wait-providers is set.")
+	upgradeApplyCmd.Flags().BoolVar(&ua.includePrereleases, "include-prereleases", false,
+		"Consider prerelease provider versions (e.g. release candidates) as upgrade targets when a target version is not specified. Intended for testing upcoming releases only.")
 }
 
 func runUpgradeApply() error {
@@ -90,12 +115,31 @@ func runUpgradeApply() error {
 		return errors.New("The --contract flag can't be used in combination with --core, --bootstrap, --control-plane, --infrastructure")
 	}
 
-	return c.ApplyUpgrade(client.ApplyUpgradeOptions{
+	options := client.ApplyUpgradeOptions{
 		Kubeconfig:              client.Kubeconfig{Path: ua.kubeconfig, Context: ua.kubeconfigContext},
 		Contract:                ua.contract,
 		CoreProvider:            ua.coreProvider,
 		BootstrapProviders:      ua.bootstrapProviders,
 		ControlPlaneProviders:   ua.controlPlaneProviders,
 		InfrastructureProviders: ua.infrastructureProviders,
-	})
+		CertManagerVersion:      ua.certManagerVersion,
+		WaitForReady:            ua.waitProviders,
+		WaitForReadyTimeout:     ua.waitProviderTimeout,
+		IncludePrereleases:      ua.includePrereleases,
+	}
+
+	if ua.dryRun {
+		diff, err := c.ApplyUpgradeDryRun(options)
+		if err != nil {
+			return err
+		}
+		y, err := yaml.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(y))
+		return nil
+	}
+
+	return c.ApplyUpgrade(options)
 }
@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -27,8 +28,12 @@ import (
 )
 
 type upgradePlanOptions struct {
-	kubeconfig        string
-	kubeconfigContext string
+	kubeconfig         string
+	kubeconfigContext  string
+	certManagerVersion string
+	onlyUpgradable     bool
+	includePrereleases bool
+	showChangelog      bool
 }
 
 var up = &upgradePlanOptions{}
@@ -61,6 +66,14 @@ func init() {
 		"Path to the kubeconfig file to use for accessing the management cluster. If empty, default discovery rules apply.")
 	upgradePlanCmd.Flags().StringVar(&up.kubeconfigContext, "kubeconfig-context", "",
 		"Context to be used within the kubeconfig file. If empty, current context will be used.")
+	upgradePlanCmd.Flags().StringVar(&up.certManagerVersion, "cert-manager-version", "",
+		"The cert-manager version (e.g. v1.1.0) to plan the upgrade to. If empty, the version defined in the clusterctl configuration or the clusterctl default is used.")
+	upgradePlanCmd.Flags().BoolVar(&up.onlyUpgradable, "only-upgradable", false,
+		"If true, only the providers with a new release available are listed.")
+	upgradePlanCmd.Flags().BoolVar(&up.includePrereleases, "include-prereleases", false,
+		"Consider prerelease provider versions (e.g. release candidates) as upgrade targets. Intended for testing upcoming releases only.")
+	upgradePlanCmd.Flags().BoolVar(&up.showChangelog, "show-changelog", false,
+		"If true, the release notes of the versions between the current and the next version are printed for each provider.")
 }
 
 func runUpgradePlan() error {
@@ -70,7 +83,8 @@ func runUpgradePlan() error {
 	}
 
 	certManUpgradePlan, err := c.PlanCertManagerUpgrade(client.PlanUpgradeOptions{
-		Kubeconfig: client.Kubeconfig{Path: up.kubeconfig, Context: up.kubeconfigContext},
+		Kubeconfig:         client.Kubeconfig{Path: up.kubeconfig, Context: up.kubeconfigContext},
+		CertManagerVersion: up.certManagerVersion,
 	})
 	if err != nil {
 		return err
@@ -84,7 +98,10 @@ func runUpgradePlan() error {
 	}
 
 	upgradePlans, err := c.PlanUpgrade(client.PlanUpgradeOptions{
-		Kubeconfig: client.Kubeconfig{Path: up.kubeconfig, Context: up.kubeconfigContext},
+		Kubeconfig:         client.Kubeconfig{Path: up.kubeconfig, Context: up.kubeconfigContext},
+		OnlyUpgradable:     up.onlyUpgradable,
+		IncludePrereleases: up.includePrereleases,
+		IncludeChangelog:   up.showChangelog,
 	})
 
 	if err != nil {
@@ -92,6 +109,10 @@ func runUpgradePlan() error {
 	}
 
 	if len(upgradePlans) == 0 {
+		if up.onlyUpgradable {
+			fmt.Println("You are already up to date!")
+			return nil
+		}
 		fmt.Println("There are no providers in the cluster. Please use clusterctl init to initialize a Cluster API management cluster.")
 		return nil
 	}
@@ -107,6 +128,9 @@ func runUpgradePlan() error {
 
 		fmt.Println("")
 		fmt.Printf("Latest release available for the %s API Version of Cluster API (contract):\n", plan.Contract)
+		if plan.CrossesContract {
+			fmt.Println("NOTE: this upgrade changes the API Version of Cluster API (contract) of the management cluster.")
+		}
 		fmt.Println("")
 		w := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
 		fmt.Fprintln(w, "NAME\tNAMESPACE\tTYPE\tCURRENT VERSION\tNEXT VERSION")
@@ -119,6 +143,10 @@ func runUpgradePlan() error {
 		w.Flush()
 		fmt.Println("")
 
+		if up.showChangelog {
+			printChangelog(plan)
+		}
+
 		if upgradeAvailable {
 			if plan.Contract == clusterv1.GroupVersion.Version {
 				fmt.Println("You can now apply the upgrade by executing the following command:")
@@ -135,3 +163,21 @@ func runUpgradePlan() error {
 
 	return nil
 }
+
+// printChangelog prints the release notes for the providers in an upgrade plan.
+func printChangelog(plan client.UpgradePlan) {
+	for _, upgradeItem := range plan.Providers {
+		if upgradeItem.NextVersion == "" || upgradeItem.NextVersion == upgradeItem.Version {
+			continue
+		}
+		fmt.Printf("Changelog for %s from %s to %s:\n", upgradeItem.InstanceName(), upgradeItem.Version, upgradeItem.NextVersion)
+		if len(upgradeItem.Changelog) == 0 {
+			fmt.Printf("No release notes published\n\n")
+			continue
+		}
+		for _, notes := range upgradeItem.Changelog {
+			fmt.Printf("\n## %s\n\n%s\n", notes.Version, strings.TrimSpace(notes.Notes))
+		}
+		fmt.Println("")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cluster-api/cmd/clusterctl/client"
+)
+
+type upgradeRollbackOptions struct {
+	kubeconfig        string
+	kubeconfigContext string
+	attemptID         string
+}
+
+var ur = &upgradeRollbackOptions{}
+
+var upgradeRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore the versions of Cluster API core and providers in a management cluster before an upgrade",
+	Long: LongDesc(`
+		The upgrade rollback command restores the provider components as they were before an upgrade attempt,
+		e.g. in case the upgrade failed after partially applying the new provider versions.
+
+		Rollback is refused if restoring the previous CRDs would make unreadable objects already stored using
+		a version only supported by the new CRDs.`),
+
+	Example: Examples(`
+		# Restores the provider components as they were before the latest upgrade attempt.
+		clusterctl upgrade rollback
+
+		# Restores the provider components as they were before a specific upgrade attempt.
+		clusterctl upgrade rollback --attempt-id 20210701-120000`),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUpgradeRollback()
+	},
+}
+
+func init() {
+	upgradeRollbackCmd.Flags().StringVar(&ur.kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig file to use for accessing the management cluster. If unspecified, default discovery rules apply.")
+	upgradeRollbackCmd.Flags().StringVar(&ur.kubeconfigContext, "kubeconfig-context", "",
+		"Context to be used within the kubeconfig file. If empty, current context will be used.")
+	upgradeRollbackCmd.Flags().StringVar(&ur.attemptID, "attempt-id", "",
+		"The ID of the upgrade attempt to rollback, as reported by clusterctl upgrade apply. If empty, the latest upgrade attempt is rolled back.")
+}
+
+func runUpgradeRollback() error {
+	c, err := client.New(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	return c.RollbackUpgrade(client.RollbackUpgradeOptions{
+		Kubeconfig: client.Kubeconfig{Path: ur.kubeconfig, Context: ur.kubeconfigContext},
+		AttemptID:  ur.attemptID,
+	})
+}
@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -50,18 +51,8 @@ func stringPtr(s string) *string {
 // printVariablesOutput prints the expected variables in the template to stdout.
 func printVariablesOutput(template client.Template, options client.GetClusterTemplateOptions) error {
 	// Decorate the variable map for printing
-	variableMap := template.VariableMap()
-	var requiredVariables []string
-	var optionalVariables []string
+	variableMap := quoteVariableDefaults(template.VariableMap())
 	for name := range variableMap {
-		if variableMap[name] != nil {
-			v := *variableMap[name]
-			// Add quotes around any unquoted strings
-			if len(v) > 0 && !strings.HasPrefix(v, "\"") {
-				v = fmt.Sprintf("\"%s\"", v)
-				variableMap[name] = &v
-			}
-		}
 
 		// Fix up default for well-know variables that have a special logic implemented in clusterctl.
 		// NOTE: this logic mimics the defaulting rules implemented in client.GetClusterTemplate;
@@ -107,6 +98,30 @@ func printVariablesOutput(template client.Template, options client.GetClusterTem
 			}
 		}
 
+	}
+
+	printVariables(os.Stdout, variableMap)
+	return nil
+}
+
+// quoteVariableDefaults returns a copy of the variable map with quotes added around any unquoted default value.
+func quoteVariableDefaults(variableMap map[string]*string) map[string]*string {
+	ret := make(map[string]*string, len(variableMap))
+	for name, value := range variableMap {
+		if value != nil && len(*value) > 0 && !strings.HasPrefix(*value, "\"") {
+			value = stringPtr(fmt.Sprintf("\"%s\"", *value))
+		}
+		ret[name] = value
+	}
+	return ret
+}
+
+// printVariables prints the variables in a variable map, distinguishing required variables
+// from optional variables, having a default value.
+func printVariables(w io.Writer, variableMap map[string]*string) {
+	var requiredVariables []string
+	var optionalVariables []string
+	for name := range variableMap {
 		if variableMap[name] != nil {
 			optionalVariables = append(optionalVariables, name)
 		} else {
@@ -117,23 +132,25 @@ func printVariablesOutput(template client.Template, options client.GetClusterTem
 	sort.Strings(optionalVariables)
 
 	if len(requiredVariables) > 0 {
-		fmt.Println("Required Variables:")
+		fmt.Fprintln(w, "Required Variables:")
 		for _, v := range requiredVariables {
-			fmt.Printf("  - %s\n", v)
+			fmt.Fprintf(w, "  - %s\n", v)
 		}
 	}
 
 	if len(optionalVariables) > 0 {
-		fmt.Println("\nOptional Variables:")
-		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.FilterHTML)
+		if len(requiredVariables) > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "Optional Variables:")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.FilterHTML)
 		for _, v := range optionalVariables {
-			fmt.Fprintf(w, "  - %s\t(defaults to %s)\n", v, *variableMap[v])
+			fmt.Fprintf(tw, "  - %s\t(defaults to %s)\n", v, *variableMap[v])
 		}
-		w.Flush()
+		tw.Flush()
 	}
 
-	fmt.Println()
-	return nil
+	fmt.Fprintln(w)
 }
 
 // printComponentsAsText prints information about the components to stdout.
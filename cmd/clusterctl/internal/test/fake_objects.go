@@ -46,6 +46,7 @@ type FakeCluster struct {
 	machines              []*FakeMachine
 	withCloudConfigSecret bool
 	withCredentialSecret  bool
+	labels                map[string]string
 }
 
 // NewFakeCluster return a FakeCluster that can generate a cluster object, all its own ancillary objects:
@@ -96,6 +97,11 @@ func (f *FakeCluster) WithMachines(fakeMachine ...*FakeMachine) *FakeCluster {
 	return f
 }
 
+func (f *FakeCluster) WithLabels(labels map[string]string) *FakeCluster {
+	f.labels = labels
+	return f
+}
+
 func (f *FakeCluster) Objs() []client.Object {
 	clusterInfrastructure := &fakeinfrastructure.GenericInfrastructureCluster{
 		TypeMeta: metav1.TypeMeta{
@@ -118,6 +124,7 @@ func (f *FakeCluster) Objs() []client.Object {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      f.name,
 			Namespace: f.namespace,
+			Labels:    f.labels,
 			// Labels: cluster.x-k8s.io/cluster-name=cluster MISSING??
 		},
 		Spec: clusterv1.ClusterSpec{
@@ -1328,7 +1335,7 @@ func fakeCRD(group string, kind string, versions []string) *apiextensionsv1.Cust
 
 	for i, version := range versions {
 		// set the first version as a storage version
-		versionObj := apiextensionsv1.CustomResourceDefinitionVersion{Name: version}
+		versionObj := apiextensionsv1.CustomResourceDefinitionVersion{Name: version, Served: true}
 		if i == 0 {
 			versionObj.Storage = true
 		}
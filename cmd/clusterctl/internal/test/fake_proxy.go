@@ -17,6 +17,8 @@ limitations under the License.
 package test
 
 import (
+	"sync"
+
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -39,6 +41,8 @@ type FakeProxy struct {
 	cs        client.Client
 	namespace string
 	objs      []client.Object
+	// lock protects cs, that is lazily created by NewClient, e.g. by concurrent move workers.
+	lock sync.Mutex
 }
 
 var (
@@ -72,6 +76,8 @@ func (f *FakeProxy) GetConfig() (*rest.Config, error) {
 }
 
 func (f *FakeProxy) NewClient() (client.Client, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	if f.cs != nil {
 		return f.cs, nil
 	}
@@ -112,7 +118,7 @@ func (f *FakeProxy) ListResources(labels map[string]string, namespaces ...string
 				}
 			}
 		}
-		if !haslabel {
+		if len(labels) > 0 && !haslabel {
 			continue
 		}
 
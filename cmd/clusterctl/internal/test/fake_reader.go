@@ -52,6 +52,7 @@ type configCertManager struct {
 type imageMeta struct {
 	Repository string `json:"repository,omitempty"`
 	Tag        string `json:"tag,omitempty"`
+	Registry   string `json:"registry,omitempty"`
 }
 
 func (f *FakeReader) Init(config string) error {
@@ -103,6 +104,17 @@ func (f *FakeReader) WithProvider(name string, ttype clusterctlv1.ProviderType,
 	return f
 }
 
+func (f *FakeReader) WithProviderAlias(alias, provider string) *FakeReader {
+	aliases := map[string]string{}
+	_ = yaml.Unmarshal([]byte(f.variables["provider-aliases"]), &aliases)
+	aliases[alias] = provider
+
+	yaml, _ := yaml.Marshal(aliases)
+	f.variables["provider-aliases"] = string(yaml)
+
+	return f
+}
+
 func (f *FakeReader) WithCertManager(url, version, timeout string) *FakeReader {
 	f.certManager = configCertManager{
 		URL:     url,
@@ -127,3 +139,14 @@ func (f *FakeReader) WithImageMeta(component, repository, tag string) *FakeReade
 
 	return f
 }
+
+func (f *FakeReader) WithImageRegistry(component, registry string) *FakeReader {
+	meta := f.imageMetas[component]
+	meta.Registry = registry
+	f.imageMetas[component] = meta
+
+	yaml, _ := yaml.Marshal(f.imageMetas)
+	f.variables["images"] = string(yaml)
+
+	return f
+}
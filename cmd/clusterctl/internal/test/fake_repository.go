@@ -18,6 +18,8 @@ package test
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -59,6 +61,22 @@ func (f FakeRepository) GetFile(version string, path string) ([]byte, error) {
 	return nil, errors.Errorf("unable to get file %s for version %s", path, version)
 }
 
+func (f *FakeRepository) ListFiles(version string) ([]string, error) {
+	if _, ok := f.versions[version]; !ok {
+		return nil, errors.Errorf("unable to list files for version %s", version)
+	}
+
+	files := []string{}
+	prefix := vpath(version, "")
+	for p := range f.files {
+		if strings.HasPrefix(p, prefix) {
+			files = append(files, strings.TrimPrefix(p, prefix))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 func (f *FakeRepository) GetVersions() ([]string, error) {
 	v := make([]string, 0, len(f.versions))
 	for k := range f.versions {
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// NewContextRoundTripper returns a RoundTripper binding all the requests to ctx, in addition to the request's own
+// context; in-flight requests are interrupted, and new requests fail, when ctx is cancelled or its deadline expires.
+func NewContextRoundTripper(ctx context.Context, rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &contextRoundTripper{ctx: ctx, rt: rt}
+}
+
+// NewContextHTTPClient returns a copy of an HTTP client whose requests are bound to ctx.
+func NewContextHTTPClient(ctx context.Context, c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	contextClient := *c
+	contextClient.Transport = NewContextRoundTripper(ctx, c.Transport)
+	return &contextClient
+}
+
+// IsContextError returns true if an error is caused by a cancelled context or by an expired deadline.
+func IsContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+type contextRoundTripper struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+func (c *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// NB. The request context is cancelled when ctx is done, or when the response body is closed, so the goroutine
+	// watching ctx does not leak.
+	reqCtx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+
+	resp, err := c.rt.RoundTrip(req.WithContext(reqCtx))
+	if err != nil {
+		cancel()
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return nil, errors.Wrap(ctxErr, err.Error())
+		}
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody cancels the request context when the response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_NewContextHTTPClient(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Run("requests succeed while the context is not done", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := NewContextHTTPClient(context.Background(), server.Client())
+		resp, err := c.Get(server.URL)
+		g.Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(string(body)).To(Equal("ok"))
+	})
+
+	t.Run("in-flight requests are interrupted when the context expires", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		c := NewContextHTTPClient(ctx, server.Client())
+		_, err := c.Get(server.URL + "/hang")
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(IsContextError(err)).To(BeTrue())
+	})
+
+	t.Run("requests fail when the context is already cancelled", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c := NewContextHTTPClient(ctx, server.Client())
+		_, err := c.Get(server.URL)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(IsContextError(err)).To(BeTrue())
+	})
+}
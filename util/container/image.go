@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"path"
 	"regexp"
+	"strings"
 
 	//  Import the crypto sha256 algorithm for the docker image parser to work
 	_ "crypto/sha256"
@@ -103,6 +104,35 @@ func ModifyImageRepository(imageName, repositoryName string) (string, error) {
 	return "", errors.New("image must be tagged")
 }
 
+// ModifyImageRegistry takes an imageName (e.g., registry/repository/image:tag), and returns an image name with updated registry,
+// preserving the repository path, the tag and the digest. Images without an explicit registry are considered hosted on
+// Docker Hub, e.g. the registry of image:tag is updated as in registry/library/image:tag.
+func ModifyImageRegistry(imageName, registry string) (string, error) {
+	namedRef, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image name")
+	}
+
+	var updated reference.Named
+	updated, err = reference.WithName(path.Join(strings.TrimSuffix(registry, "/"), reference.Path(namedRef)))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to update registry name")
+	}
+	if tagged, ok := namedRef.(reference.Tagged); ok {
+		updated, err = reference.WithTag(updated, tagged.Tag())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse image tag")
+		}
+	}
+	if digested, ok := namedRef.(reference.Digested); ok {
+		updated, err = reference.WithDigest(updated, digested.Digest())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse image digest")
+		}
+	}
+	return updated.String(), nil
+}
+
 // ModifyImageTag takes an imageName (e.g., repository/image:tag), and returns an image name with updated tag.
 func ModifyImageTag(imageName, tagName string) (string, error) {
 	normalisedTagName := SemverToOCIImageTag(tagName)
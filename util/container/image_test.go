@@ -208,6 +208,65 @@ func TestModifyImageRepository(t *testing.T) {
 	}
 }
 
+func TestModifyImageRegistry(t *testing.T) {
+	const testRegistry = "mirror.example.com:5000"
+
+	testCases := []struct {
+		name           string
+		image          string
+		registry       string
+		want           string
+		wantError      bool
+		wantErrMessage string
+	}{
+		{
+			name:     "updates the registry of the image",
+			image:    "k8s.gcr.io/cluster-api/cluster-api-controller:v0.4.0",
+			registry: testRegistry,
+			want:     "mirror.example.com:5000/cluster-api/cluster-api-controller:v0.4.0",
+		},
+		{
+			name:     "updates the registry of an image without an explicit registry",
+			image:    "image:1.17.3",
+			registry: testRegistry,
+			want:     "mirror.example.com:5000/library/image:1.17.3",
+		},
+		{
+			name:     "updates the registry of an image without a tag",
+			image:    "example.com/image",
+			registry: testRegistry + "/",
+			want:     "mirror.example.com:5000/image",
+		},
+		{
+			name:     "preserves the tag and the digest",
+			image:    "example.com/image:1.17.3@sha256:b4abe825d29d10a3106a8f18526a1831a1a90cfb7dfd0aa38b115c4a5505553f",
+			registry: testRegistry,
+			want:     "mirror.example.com:5000/image:1.17.3@sha256:b4abe825d29d10a3106a8f18526a1831a1a90cfb7dfd0aa38b115c4a5505553f",
+		},
+		{
+			name:           "errors if the image name is not valid",
+			image:          "example.com/image:$@$(*",
+			registry:       testRegistry,
+			wantError:      true,
+			wantErrMessage: "failed to parse image name",
+		},
+	}
+	for _, tc := range testCases {
+		g := NewWithT(t)
+
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ModifyImageRegistry(tc.image, tc.registry)
+			if tc.wantError {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErrMessage)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(res).To(Equal(tc.want))
+			}
+		})
+	}
+}
+
 func TestModifyImageTag(t *testing.T) {
 	g := NewWithT(t)
 	t.Run("should ensure image is a docker compatible tag", func(t *testing.T) {